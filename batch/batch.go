@@ -0,0 +1,72 @@
+// Package batch implements storage for the Anthropic Message Batches API.
+// Entries are processed by the regular /v1/messages pipeline; this package
+// only defines the wire types and persists batches and their results.
+package batch
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Processing statuses reported for a batch
+const (
+	StatusInProgress = "in_progress"
+	StatusEnded      = "ended"
+)
+
+// Result types for individual entries
+const (
+	ResultSucceeded = "succeeded"
+	ResultErrored   = "errored"
+)
+
+// RequestCounts tallies entries by outcome
+type RequestCounts struct {
+	Processing int `json:"processing"`
+	Succeeded  int `json:"succeeded"`
+	Errored    int `json:"errored"`
+	Canceled   int `json:"canceled"`
+	Expired    int `json:"expired"`
+}
+
+// Batch is the message_batch object returned by the API
+type Batch struct {
+	ID                string        `json:"id"`
+	Type              string        `json:"type"` // Always "message_batch"
+	ProcessingStatus  string        `json:"processing_status"`
+	RequestCounts     RequestCounts `json:"request_counts"`
+	CreatedAt         time.Time     `json:"created_at"`
+	EndedAt           *time.Time    `json:"ended_at"`
+	ExpiresAt         time.Time     `json:"expires_at"`
+	ArchivedAt        *time.Time    `json:"archived_at"`
+	CancelInitiatedAt *time.Time    `json:"cancel_initiated_at"`
+	ResultsURL        *string       `json:"results_url"`
+}
+
+// Record is the persisted form of a batch. Headers holds the request headers
+// (anthropic-version, anthropic-beta) replayed for every entry, ClientKey the
+// hashed ID of the client key every entry is charged to and limited as.
+type Record struct {
+	Batch
+	Headers   map[string][]string `json:"headers,omitempty"`
+	ClientKey string              `json:"client_key,omitempty"`
+}
+
+// Entry is one request of a batch as submitted by the client
+type Entry struct {
+	CustomID string          `json:"custom_id"`
+	Params   json.RawMessage `json:"params"`
+}
+
+// Result is one line of the batch results JSONL
+type Result struct {
+	CustomID string      `json:"custom_id"`
+	Result   EntryResult `json:"result"`
+}
+
+// EntryResult holds either the message or the error body for an entry
+type EntryResult struct {
+	Type    string          `json:"type"`
+	Message json.RawMessage `json:"message,omitempty"`
+	Error   json.RawMessage `json:"error,omitempty"`
+}
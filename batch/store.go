@@ -0,0 +1,219 @@
+package batch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	batchesBucket  = []byte("batches")
+	requestsBucket = []byte("requests")
+	resultsBucket  = []byte("results")
+)
+
+// Store persists batches, their request entries and per-entry results in an
+// embedded bbolt database so batches survive restarts and results stay
+// retrievable after processing ends
+type Store struct {
+	db *bolt.DB
+}
+
+// OpenStore opens (or creates) the batch database at path
+func OpenStore(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 2 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open batch store %s: %v", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{batchesBucket, requestsBucket, resultsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize batch store: %v", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close closes the underlying database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// entryKey orders entries of a batch by their position in the create request
+func entryKey(batchID string, index int) []byte {
+	return []byte(fmt.Sprintf("%s/%08d", batchID, index))
+}
+
+// Create persists a new batch together with its request entries
+func (s *Store) Create(record Record, entries []Entry) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := putJSON(tx.Bucket(batchesBucket), []byte(record.ID), record); err != nil {
+			return err
+		}
+		requests := tx.Bucket(requestsBucket)
+		for i, entry := range entries {
+			if err := putJSON(requests, entryKey(record.ID, i), entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Get returns a batch record; ok is false when the batch does not exist
+func (s *Store) Get(id string) (record Record, ok bool, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(batchesBucket).Get([]byte(id))
+		if data == nil {
+			return nil
+		}
+		ok = true
+		return json.Unmarshal(data, &record)
+	})
+	return record, ok, err
+}
+
+// List returns all batches, most recently created first
+func (s *Store) List() ([]Record, error) {
+	var records []Record
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(batchesBucket).ForEach(func(_, data []byte) error {
+			var record Record
+			if err := json.Unmarshal(data, &record); err != nil {
+				return err
+			}
+			records = append(records, record)
+			return nil
+		})
+	})
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].CreatedAt.After(records[j].CreatedAt)
+	})
+	return records, err
+}
+
+// Update overwrites a batch record
+func (s *Store) Update(record Record) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx.Bucket(batchesBucket), []byte(record.ID), record)
+	})
+}
+
+// Entries returns the request entries of a batch in submission order
+func (s *Store) Entries(batchID string) ([]Entry, error) {
+	var entries []Entry
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return forEachEntry(tx.Bucket(requestsBucket), batchID, func(data []byte) error {
+			var entry Entry
+			if err := json.Unmarshal(data, &entry); err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+			return nil
+		})
+	})
+	return entries, err
+}
+
+// HasResult reports whether the entry at index already has a stored result
+func (s *Store) HasResult(batchID string, index int) bool {
+	found := false
+	s.db.View(func(tx *bolt.Tx) error {
+		found = tx.Bucket(resultsBucket).Get(entryKey(batchID, index)) != nil
+		return nil
+	})
+	return found
+}
+
+// PutResult stores the result of one entry
+func (s *Store) PutResult(batchID string, index int, result Result) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx.Bucket(resultsBucket), entryKey(batchID, index), result)
+	})
+}
+
+// Results calls fn with each stored result line in submission order
+func (s *Store) Results(batchID string, fn func(line []byte) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return forEachEntry(tx.Bucket(resultsBucket), batchID, fn)
+	})
+}
+
+// PurgeEnded deletes batches that finished processing before the given time,
+// with their request entries and results. Returns the number of batches deleted.
+func (s *Store) PurgeEnded(before time.Time) (int, error) {
+	deleted := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var ids []string
+		err := tx.Bucket(batchesBucket).ForEach(func(_, data []byte) error {
+			var record Record
+			if err := json.Unmarshal(data, &record); err != nil {
+				return err
+			}
+			if record.EndedAt != nil && record.EndedAt.Before(before) {
+				ids = append(ids, record.ID)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			for _, bucket := range []*bolt.Bucket{tx.Bucket(requestsBucket), tx.Bucket(resultsBucket)} {
+				if err := deleteEntries(bucket, id); err != nil {
+					return err
+				}
+			}
+			if err := tx.Bucket(batchesBucket).Delete([]byte(id)); err != nil {
+				return err
+			}
+			deleted++
+		}
+		return nil
+	})
+	return deleted, err
+}
+
+// deleteEntries removes keys prefixed with "<batchID>/"
+func deleteEntries(bucket *bolt.Bucket, batchID string) error {
+	prefix := []byte(batchID + "/")
+	cursor := bucket.Cursor()
+	for key, _ := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Seek(prefix) {
+		if err := bucket.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// forEachEntry iterates keys prefixed with "<batchID>/"
+func forEachEntry(bucket *bolt.Bucket, batchID string, fn func(data []byte) error) error {
+	prefix := []byte(batchID + "/")
+	cursor := bucket.Cursor()
+	for key, data := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, data = cursor.Next() {
+		if err := fn(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func putJSON(bucket *bolt.Bucket, key []byte, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return bucket.Put(key, data)
+}
@@ -0,0 +1,242 @@
+package circuitbreaker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Alert types emitted by the health manager
+const (
+	AlertCircuitOpened      = "circuit_opened"       // A single endpoint's circuit transitioned to open
+	AlertAllEndpointsFailed = "all_endpoints_failed" // Every endpoint registered for a role is unhealthy
+	AlertBudgetExceeded     = "budget_exceeded"      // A session used up its token or cost budget
+)
+
+// Alert describes a circuit breaker transition worth notifying an operator about
+type Alert struct {
+	Type               string    `json:"type"`
+	Role               string    `json:"role,omitempty"`
+	Endpoint           string    `json:"endpoint,omitempty"`
+	FailureCount       int       `json:"failure_count"`
+	TotalRequests      int       `json:"total_requests"`
+	LastError          string    `json:"last_error,omitempty"`
+	NextRetryTime      time.Time `json:"next_retry_time,omitempty"`
+	UnhealthyEndpoints []string  `json:"unhealthy_endpoints,omitempty"`
+	Session            string    `json:"session,omitempty"`
+	Message            string    `json:"message,omitempty"`
+	Timestamp          time.Time `json:"timestamp"`
+}
+
+// Summary returns a single-line human readable description of the alert
+func (a Alert) Summary() string {
+	switch a.Type {
+	case AlertAllEndpointsFailed:
+		return fmt.Sprintf("🚨 All %s endpoints are unhealthy (%s)", a.Role, strings.Join(a.UnhealthyEndpoints, ", "))
+	case AlertBudgetExceeded:
+		return fmt.Sprintf("💸 Session %s stopped: %s", a.Session, a.Message)
+	default:
+		summary := fmt.Sprintf("⚠️ Circuit opened for %s endpoint %s after %d failures", a.Role, a.Endpoint, a.FailureCount)
+		if a.LastError != "" {
+			summary += ": " + a.LastError
+		}
+		return summary
+	}
+}
+
+// AlertSink receives circuit breaker alerts
+type AlertSink interface {
+	SendAlert(alert Alert) error
+}
+
+// WebhookAlertSink posts alerts as JSON to a generic webhook or Slack incoming webhook URL
+type WebhookAlertSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookAlertSink creates a sink that posts alerts to the given URL
+func NewWebhookAlertSink(url string) *WebhookAlertSink {
+	return &WebhookAlertSink{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// SendAlert posts the alert. The payload carries a Slack-compatible "text" field
+// alongside the structured alert so the same URL format works for both.
+func (s *WebhookAlertSink) SendAlert(alert Alert) error {
+	payload := struct {
+		Text string `json:"text"`
+		Alert
+	}{
+		Text:  alert.Summary(),
+		Alert: alert,
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert: %v", err)
+	}
+
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("alert webhook request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("alert webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SetAlertSink configures where circuit breaker alerts are delivered (nil disables alerting)
+func (hm *HealthManager) SetAlertSink(sink AlertSink) {
+	hm.healthMutex.Lock()
+	defer hm.healthMutex.Unlock()
+	hm.alertSink = sink
+}
+
+// StateChangeListener is notified whenever an endpoint's circuit opens or closes
+type StateChangeListener func(endpoint string, open bool)
+
+// AddStateChangeListener registers a listener for circuit transitions alongside
+// any already added, so that independent subscribers (metrics, endpoint warm-up)
+// all observe them. Listeners are invoked synchronously outside the health lock
+// and must not block.
+func (hm *HealthManager) AddStateChangeListener(listener StateChangeListener) {
+	if listener == nil {
+		return
+	}
+	hm.healthMutex.Lock()
+	defer hm.healthMutex.Unlock()
+	previous := hm.onStateChange
+	if previous == nil {
+		hm.onStateChange = listener
+		return
+	}
+	hm.onStateChange = func(endpoint string, open bool) {
+		previous(endpoint, open)
+		listener(endpoint, open)
+	}
+}
+
+// RegisterRole associates a set of endpoints with a role (e.g. "small_model") so that
+// an alert can be raised when every endpoint serving that role is unhealthy
+func (hm *HealthManager) RegisterRole(role string, endpoints []string) {
+	hm.healthMutex.Lock()
+	defer hm.healthMutex.Unlock()
+
+	if hm.roles == nil {
+		hm.roles = make(map[string][]string)
+	}
+	hm.roles[role] = append([]string(nil), endpoints...)
+}
+
+// rolesForEndpointLocked returns the roles an endpoint is registered under.
+// Caller must hold healthMutex.
+func (hm *HealthManager) rolesForEndpointLocked(endpoint string) []string {
+	var roles []string
+	for role, endpoints := range hm.roles {
+		for _, e := range endpoints {
+			if e == endpoint {
+				roles = append(roles, role)
+				break
+			}
+		}
+	}
+	return roles
+}
+
+// isHealthyLocked mirrors IsHealthy for callers already holding healthMutex
+func (hm *HealthManager) isHealthyLocked(endpoint string, now time.Time) bool {
+	health, exists := hm.healthMap[endpoint]
+	if !exists || !health.CircuitOpen {
+		return true
+	}
+	return now.After(health.NextRetryTime)
+}
+
+// collectOpenAlertsLocked builds the alerts for an endpoint whose circuit just opened.
+// Caller must hold healthMutex.
+func (hm *HealthManager) collectOpenAlertsLocked(health *EndpointHealth) []Alert {
+	if hm.alertSink == nil {
+		return nil
+	}
+
+	now := time.Now()
+	roles := hm.rolesForEndpointLocked(health.URL)
+	alerts := []Alert{{
+		Type:          AlertCircuitOpened,
+		Role:          strings.Join(roles, ","),
+		Endpoint:      health.URL,
+		FailureCount:  health.FailureCount,
+		TotalRequests: health.TotalRequests,
+		LastError:     health.LastError,
+		NextRetryTime: health.NextRetryTime,
+		Timestamp:     now,
+	}}
+
+	if hm.roleAlerted == nil {
+		hm.roleAlerted = make(map[string]bool)
+	}
+	for _, role := range roles {
+		if hm.roleAlerted[role] {
+			continue
+		}
+		allUnhealthy := true
+		for _, endpoint := range hm.roles[role] {
+			if hm.isHealthyLocked(endpoint, now) {
+				allUnhealthy = false
+				break
+			}
+		}
+		if !allUnhealthy {
+			continue
+		}
+		hm.roleAlerted[role] = true
+		alerts = append(alerts, Alert{
+			Type:               AlertAllEndpointsFailed,
+			Role:               role,
+			Endpoint:           health.URL,
+			FailureCount:       health.FailureCount,
+			TotalRequests:      health.TotalRequests,
+			LastError:          health.LastError,
+			NextRetryTime:      health.NextRetryTime,
+			UnhealthyEndpoints: append([]string(nil), hm.roles[role]...),
+			Timestamp:          now,
+		})
+	}
+	return alerts
+}
+
+// clearRoleAlertsLocked re-arms the all-endpoints alert for every role the endpoint
+// belongs to once it recovers. Caller must hold healthMutex.
+func (hm *HealthManager) clearRoleAlertsLocked(endpoint string) {
+	for _, role := range hm.rolesForEndpointLocked(endpoint) {
+		delete(hm.roleAlerted, role)
+	}
+}
+
+// dispatchAlerts delivers alerts asynchronously so request paths never block on the webhook
+func (hm *HealthManager) dispatchAlerts(sink AlertSink, alerts []Alert) {
+	if sink == nil || len(alerts) == 0 {
+		return
+	}
+	obsLogger := hm.obsLogger
+	go func() {
+		for _, alert := range alerts {
+			if err := sink.SendAlert(alert); err != nil && obsLogger != nil {
+				obsLogger.Warn("circuit_breaker", "warning", "", "Failed to deliver circuit breaker alert", map[string]interface{}{
+					"alert_type": alert.Type,
+					"endpoint":   alert.Endpoint,
+					"error":      err.Error(),
+				})
+			}
+		}
+	}()
+}
@@ -4,10 +4,51 @@ import (
 	"time"
 )
 
+// SetRoleConfig applies separate breaker settings to the endpoints registered
+// under role, e.g. a higher failure threshold for slow big model backends
+func (hm *HealthManager) SetRoleConfig(role string, config Config) {
+	hm.healthMutex.Lock()
+	defer hm.healthMutex.Unlock()
+
+	if hm.roleConfigs == nil {
+		hm.roleConfigs = make(map[string]Config)
+	}
+	hm.roleConfigs[role] = config
+}
+
+// configForLocked returns the breaker settings for an endpoint: those of the
+// first of its roles with an override, otherwise the default config.
+// Caller must hold healthMutex.
+func (hm *HealthManager) configForLocked(endpoint string) Config {
+	if len(hm.roleConfigs) > 0 {
+		for _, role := range hm.rolesForEndpointLocked(endpoint) {
+			if config, exists := hm.roleConfigs[role]; exists {
+				return config
+			}
+		}
+	}
+	return hm.config
+}
+
 // RecordFailure marks an endpoint as failed and potentially opens its circuit
 func (hm *HealthManager) RecordFailure(endpoint string) {
+	hm.RecordFailureWithError(endpoint, nil)
+}
+
+// RecordFailureWithError marks an endpoint as failed, remembering the error for alerts
+func (hm *HealthManager) RecordFailureWithError(endpoint string, failureErr error) {
 	hm.healthMutex.Lock()
-	defer hm.healthMutex.Unlock()
+	var alerts []Alert
+	opened := false
+	sink := hm.alertSink
+	listener := hm.onStateChange
+	defer func() {
+		hm.healthMutex.Unlock()
+		if opened && listener != nil {
+			listener(endpoint, true)
+		}
+		hm.dispatchAlerts(sink, alerts)
+	}()
 
 	health, exists := hm.healthMap[endpoint]
 	if !exists {
@@ -18,19 +59,25 @@ func (hm *HealthManager) RecordFailure(endpoint string) {
 	health.FailureCount++
 	health.TotalRequests++
 	health.LastFailureTime = time.Now()
+	hm.updateEWMALocked(health, false, 0)
+	if failureErr != nil {
+		health.LastError = failureErr.Error()
+	}
 
 	// Open circuit if failure threshold exceeded
-	if health.FailureCount >= hm.config.FailureThreshold {
+	config := hm.configForLocked(endpoint)
+	if health.FailureCount >= config.FailureThreshold {
+		wasOpen := health.CircuitOpen
 		health.CircuitOpen = true
 
 		// Calculate backoff time with exponential backoff capped at max
-		failuresOverThreshold := health.FailureCount - hm.config.FailureThreshold + 1
+		failuresOverThreshold := health.FailureCount - config.FailureThreshold + 1
 		if failuresOverThreshold < 1 {
 			failuresOverThreshold = 1
 		}
-		backoff := time.Duration(int64(hm.config.BackoffDuration) * int64(failuresOverThreshold))
-		if backoff > hm.config.MaxBackoffDuration {
-			backoff = hm.config.MaxBackoffDuration
+		backoff := time.Duration(int64(config.BackoffDuration) * int64(failuresOverThreshold))
+		if backoff > config.MaxBackoffDuration {
+			backoff = config.MaxBackoffDuration
 		}
 
 		now := time.Now()
@@ -44,12 +91,18 @@ func (hm *HealthManager) RecordFailure(endpoint string) {
 				"next_retry_time": health.NextRetryTime.Format(time.RFC3339),
 			})
 		}
+
+		// Only alert on the closed -> open transition, not on every failure while open
+		if !wasOpen {
+			opened = true
+			alerts = hm.collectOpenAlertsLocked(health)
+		}
 	} else {
 		if hm.obsLogger != nil {
 			hm.obsLogger.Warn("circuit_breaker", "warning", "", "Endpoint failure recorded", map[string]interface{}{
 				"endpoint": endpoint,
 				"failure_count": health.FailureCount,
-				"failure_threshold": hm.config.FailureThreshold,
+				"failure_threshold": config.FailureThreshold,
 			})
 		}
 	}
@@ -57,8 +110,21 @@ func (hm *HealthManager) RecordFailure(endpoint string) {
 
 // RecordSuccess marks an endpoint as successful and potentially closes its circuit
 func (hm *HealthManager) RecordSuccess(endpoint string) {
+	hm.RecordSuccessWithLatency(endpoint, 0)
+}
+
+// RecordSuccessWithLatency records a success along with how long the endpoint
+// took to respond, which feeds the endpoint's latency score
+func (hm *HealthManager) RecordSuccessWithLatency(endpoint string, latency time.Duration) {
 	hm.healthMutex.Lock()
-	defer hm.healthMutex.Unlock()
+	closed := false
+	listener := hm.onStateChange
+	defer func() {
+		hm.healthMutex.Unlock()
+		if closed && listener != nil {
+			listener(endpoint, false)
+		}
+	}()
 
 	health, exists := hm.healthMap[endpoint]
 	if !exists {
@@ -70,12 +136,15 @@ func (hm *HealthManager) RecordSuccess(endpoint string) {
 	health.SuccessCount++
 	health.TotalRequests++
 	health.LastSuccessTime = time.Now()
+	hm.updateEWMALocked(health, true, latency)
 
 	// If circuit was open, close it and reset
 	if health.CircuitOpen {
 		health.CircuitOpen = false
 		health.FailureCount = 0
 		health.NextRetryTime = time.Time{}
+		closed = true
+		hm.clearRoleAlertsLocked(endpoint)
 		if hm.obsLogger != nil {
 			hm.obsLogger.Info("circuit_breaker", "health", "", "Circuit breaker closed for endpoint", map[string]interface{}{
 				"endpoint": endpoint,
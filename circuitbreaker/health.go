@@ -1,6 +1,7 @@
 package circuitbreaker
 
 import (
+	"sort"
 	"sync"
 	"time"
 )
@@ -16,6 +17,10 @@ type EndpointHealth struct {
 	CircuitOpen       bool      `json:"circuit_open"`
 	NextRetryTime     time.Time `json:"next_retry_time"`
 	LastReorderCheck  time.Time `json:"last_reorder_check"`
+	LastError         string    `json:"last_error,omitempty"`
+	SuccessEWMA       float64   `json:"success_ewma"`    // Exponentially weighted success rate, 1 = every recent request succeeded
+	LatencyEWMAMs     float64   `json:"latency_ewma_ms"` // Exponentially weighted time to response headers, 0 until measured
+	Score             float64   `json:"score"`           // Routing score derived from both, see HealthManager.Score
 }
 
 // Config controls circuit breaker behavior
@@ -24,6 +29,9 @@ type Config struct {
 	BackoffDuration    time.Duration `json:"backoff_duration"`     // How long to wait before retrying failed endpoint
 	MaxBackoffDuration time.Duration `json:"max_backoff_duration"` // Maximum backoff time
 	ResetTimeout       time.Duration `json:"reset_timeout"`        // Time to reset failure count after success
+	EWMAAlpha          float64       `json:"ewma_alpha"`           // Weight of the latest request in the success and latency averages
+	LatencyReference   time.Duration `json:"latency_reference"`    // Latency at which an endpoint's score is halved
+	ReorderInterval    time.Duration `json:"reorder_interval"`     // Minimum time between endpoint reorders
 }
 
 // DefaultConfig returns sensible defaults for circuit breaker
@@ -33,15 +41,23 @@ func DefaultConfig() Config {
 		BackoffDuration:    30 * time.Second, // Initial 30s backoff
 		MaxBackoffDuration: 5 * time.Minute,  // Max 5min backoff
 		ResetTimeout:       1 * time.Minute,  // Reset failure count after 1min of success
+		EWMAAlpha:          0.2,              // Roughly the last 10 requests dominate the score
+		LatencyReference:   5 * time.Second,  // A 5s endpoint scores half of an instant one
+		ReorderInterval:    30 * time.Second, // Scores adapt quickly, so reorder often
 	}
 }
 
 // HealthManager manages endpoint health tracking
 type HealthManager struct {
-	config      Config
-	healthMap   map[string]*EndpointHealth
-	healthMutex sync.RWMutex
-	obsLogger   interface {
+	config        Config
+	healthMap     map[string]*EndpointHealth
+	healthMutex   sync.RWMutex
+	roles         map[string][]string // Role name -> endpoints, used for all-endpoints-down alerts
+	roleAlerted   map[string]bool     // Roles that already raised an all-endpoints-down alert
+	roleConfigs   map[string]Config   // Role name -> breaker settings overriding config for its endpoints
+	alertSink     AlertSink
+	onStateChange StateChangeListener
+	obsLogger     interface {
 		Info(component, category, requestID, message string, fields map[string]interface{})
 		Warn(component, category, requestID, message string, fields map[string]interface{})
 		Error(component, category, requestID, message string, fields map[string]interface{})
@@ -115,7 +131,7 @@ func (hm *HealthManager) GetHealthDebug(endpoint string) (failureCount int, circ
 	return health.FailureCount, health.CircuitOpen, health.NextRetryTime, true
 }
 
-// CalculateSuccessRate calculates the success rate for an endpoint
+// CalculateSuccessRate returns the exponentially weighted success rate of an endpoint
 func (hm *HealthManager) CalculateSuccessRate(endpoint string) float64 {
 	hm.healthMutex.RLock()
 	defer hm.healthMutex.RUnlock()
@@ -125,5 +141,70 @@ func (hm *HealthManager) CalculateSuccessRate(endpoint string) float64 {
 		return 0.5 // Default neutral rate for new endpoints
 	}
 
-	return float64(health.SuccessCount) / float64(health.TotalRequests)
-}
\ No newline at end of file
+	return health.SuccessEWMA
+}
+
+// Score rates an endpoint for routing: its weighted success rate, scaled down
+// by its weighted latency so that a LatencyReference endpoint scores half of an
+// instant one. Endpoints without requests get a neutral 0.5.
+func (hm *HealthManager) Score(endpoint string) float64 {
+	hm.healthMutex.RLock()
+	defer hm.healthMutex.RUnlock()
+
+	health, exists := hm.healthMap[endpoint]
+	if !exists {
+		return 0.5
+	}
+	return hm.scoreLocked(health)
+}
+
+// scoreLocked computes Score; callers hold healthMutex
+func (hm *HealthManager) scoreLocked(health *EndpointHealth) float64 {
+	if health.TotalRequests == 0 {
+		return 0.5
+	}
+	score := health.SuccessEWMA
+	if reference := float64(hm.config.LatencyReference.Milliseconds()); reference > 0 && health.LatencyEWMAMs > 0 {
+		score *= reference / (reference + health.LatencyEWMAMs)
+	}
+	return score
+}
+
+// updateEWMALocked folds one request outcome into the weighted averages.
+// latency <= 0 leaves the latency average unchanged. Callers hold healthMutex
+// and have already counted the request in TotalRequests.
+func (hm *HealthManager) updateEWMALocked(health *EndpointHealth, success bool, latency time.Duration) {
+	outcome := 0.0
+	if success {
+		outcome = 1.0
+	}
+	alpha := hm.config.EWMAAlpha
+	if health.TotalRequests <= 1 {
+		health.SuccessEWMA = outcome // First sample seeds the average
+	} else {
+		health.SuccessEWMA = alpha*outcome + (1-alpha)*health.SuccessEWMA
+	}
+
+	if latency > 0 {
+		latencyMs := float64(latency) / float64(time.Millisecond)
+		if health.LatencyEWMAMs == 0 {
+			health.LatencyEWMAMs = latencyMs
+		} else {
+			health.LatencyEWMAMs = alpha*latencyMs + (1-alpha)*health.LatencyEWMAMs
+		}
+	}
+}
+// Snapshot returns a copy of the health state of every tracked endpoint, sorted by URL
+func (hm *HealthManager) Snapshot() []EndpointHealth {
+	hm.healthMutex.RLock()
+	defer hm.healthMutex.RUnlock()
+
+	snapshot := make([]EndpointHealth, 0, len(hm.healthMap))
+	for _, health := range hm.healthMap {
+		entry := *health
+		entry.Score = hm.scoreLocked(health)
+		snapshot = append(snapshot, entry)
+	}
+	sort.Slice(snapshot, func(i, j int) bool { return snapshot[i].URL < snapshot[j].URL })
+	return snapshot
+}
@@ -0,0 +1,115 @@
+package circuitbreaker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// persistedState is the on-disk form of the health map
+type persistedState struct {
+	SavedAt   time.Time        `json:"saved_at"`
+	Endpoints []EndpointHealth `json:"endpoints"`
+}
+
+// SaveState writes the health of every tracked endpoint to path. The file is
+// replaced atomically so a crash mid-write never leaves a truncated state.
+func (hm *HealthManager) SaveState(path string) error {
+	data, err := json.MarshalIndent(persistedState{SavedAt: time.Now(), Endpoints: hm.Snapshot()}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write circuit breaker state: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write circuit breaker state: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write circuit breaker state: %v", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadState restores endpoint health saved by SaveState. State older than ttl
+// is ignored, as is state of endpoints that are no longer configured, so only
+// failures that are still likely to matter survive a restart. A missing file
+// is not an error. Returns the number of endpoints restored.
+func (hm *HealthManager) LoadState(path string, ttl time.Duration) (int, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read circuit breaker state: %v", err)
+	}
+
+	var state persistedState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return 0, fmt.Errorf("failed to parse circuit breaker state %s: %v", path, err)
+	}
+	if time.Since(state.SavedAt) > ttl {
+		return 0, nil
+	}
+
+	hm.healthMutex.Lock()
+	defer hm.healthMutex.Unlock()
+
+	restored := 0
+	for _, saved := range state.Endpoints {
+		if _, exists := hm.healthMap[saved.URL]; !exists {
+			continue
+		}
+		health := saved
+		hm.healthMap[saved.URL] = &health
+		restored++
+
+		if health.CircuitOpen && hm.obsLogger != nil {
+			hm.obsLogger.Warn("circuit_breaker", "warning", "", "Restored open circuit for endpoint", map[string]interface{}{
+				"endpoint":        health.URL,
+				"failure_count":   health.FailureCount,
+				"next_retry_time": health.NextRetryTime.Format(time.RFC3339),
+			})
+		}
+	}
+	return restored, nil
+}
+
+// StartPersistence restores state from path, then saves it every interval
+// until the returned function is called, which saves a final time
+func (hm *HealthManager) StartPersistence(path string, ttl, interval time.Duration, onError func(error)) (func() error, int, error) {
+	restored, err := hm.LoadState(path, ttl)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := hm.SaveState(path); err != nil && onError != nil {
+					onError(err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return func() error {
+		close(stop)
+		<-done
+		return hm.SaveState(path)
+	}, restored, nil
+}
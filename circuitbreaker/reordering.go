@@ -8,13 +8,15 @@ import (
 type endpointScore struct {
 	url         string
 	successRate float64
+	latencyMs   float64
+	score       float64
 	isHealthy   bool
 }
 
-// ReorderBySuccess reorders endpoint slices based on success rates
+// ReorderBySuccess reorders endpoint slices by their EWMA success/latency score
 func (hm *HealthManager) ReorderBySuccess(endpoints []string, endpointType string) bool {
 	now := time.Now()
-	reorderInterval := 5 * time.Minute // Reorder every 5 minutes
+	reorderInterval := hm.config.ReorderInterval
 
 	// Check if enough time has passed since last reorder
 	hm.healthMutex.RLock()
@@ -37,11 +39,17 @@ func (hm *HealthManager) ReorderBySuccess(endpoints []string, endpointType strin
 		scores[i] = endpointScore{
 			url:         endpoint,
 			successRate: hm.CalculateSuccessRate(endpoint),
+			score:       hm.Score(endpoint),
 			isHealthy:   hm.IsHealthy(endpoint),
 		}
+		hm.healthMutex.RLock()
+		if health, exists := hm.healthMap[endpoint]; exists {
+			scores[i].latencyMs = health.LatencyEWMAMs
+		}
+		hm.healthMutex.RUnlock()
 	}
 
-	// Sort by: 1) healthy status (healthy first), 2) success rate (highest first)
+	// Sort by: 1) healthy status (healthy first), 2) score (highest first)
 	for i := 0; i < len(scores); i++ {
 		for j := i + 1; j < len(scores); j++ {
 			// Prioritize healthy endpoints
@@ -51,8 +59,8 @@ func (hm *HealthManager) ReorderBySuccess(endpoints []string, endpointType strin
 				}
 				continue
 			}
-			// Among same health status, prioritize higher success rate
-			if scores[j].successRate > scores[i].successRate {
+			// Among same health status, prioritize higher score
+			if scores[j].score > scores[i].score {
 				scores[i], scores[j] = scores[j], scores[i]
 			}
 		}
@@ -83,11 +91,13 @@ func (hm *HealthManager) ReorderBySuccess(endpoints []string, endpointType strin
 				endpointDetails[i] = map[string]interface{}{
 					"position": i + 1,
 					"endpoint": score.url,
+					"score": score.score,
 					"success_rate": score.successRate,
+					"latency_ewma_ms": score.latencyMs,
 					"is_healthy": score.isHealthy,
 				}
 			}
-			hm.obsLogger.Info("circuit_breaker", "health", "", "Reordered endpoints by success rate", map[string]interface{}{
+			hm.obsLogger.Info("circuit_breaker", "health", "", "Reordered endpoints by score", map[string]interface{}{
 				"endpoint_type": endpointType,
 				"endpoint_details": endpointDetails,
 				"total_endpoints": len(scores),
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"claude-proxy/config"
+	"claude-proxy/types"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// command is a simple-proxy subcommand. run receives the arguments after the
+// command name and returns the process exit code.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}
+
+// commands lists the subcommands in the order the usage shows them
+var commands = []command{
+	{"serve", "Run the proxy (the default when no command is given)", runServe},
+	{"validate", "Load and check the configuration, then exit", runValidate},
+	{"selftest", "Send a test request for each model role through the full pipeline", runSelftest},
+	{"replay", "Send the requests of an exported conversation to a running proxy", runReplay},
+	{"loadtest", "Send concurrent requests to a running proxy and report latency", runLoadtest},
+	{"version", "Print build information", runVersion},
+	{"mine-corrections", "Suggest tool_validators.yaml rules from correction logs", runMineCorrections},
+}
+
+// defaultProxyURL is where replay and loadtest send requests unless -url is given
+const defaultProxyURL = "http://localhost:3456/v1/messages"
+
+// run dispatches to a subcommand. Without one, or when the first argument is
+// a flag, the proxy is served as before subcommands existed.
+func run(args []string) int {
+	if len(args) == 0 {
+		return runServe(nil)
+	}
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		printUsage(os.Stdout)
+		return 0
+	}
+	if strings.HasPrefix(args[0], "-") {
+		return runServe(args)
+	}
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:])
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", args[0])
+	printUsage(os.Stderr)
+	return 2
+}
+
+// printUsage lists the subcommands
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: simple-proxy [command] [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-18s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Run "simple-proxy <command> -h" for the flags of a command.`)
+}
+
+// newFlagSet creates the flag set of a subcommand with its usage line
+func newFlagSet(name, usage string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: simple-proxy "+usage)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// configDirFlag adds -config-dir, the directory holding .env and the YAML
+// override files, which are all read relative to the working directory
+func configDirFlag(flags *flag.FlagSet) *string {
+	return flags.String("config-dir", "", "Directory containing .env and the override files (default: current directory)")
+}
+
+// loadConfig loads the configuration from dir, or the working directory
+func loadConfig(dir string) (*config.Config, error) {
+	if dir != "" {
+		if err := os.Chdir(dir); err != nil {
+			return nil, fmt.Errorf("failed to use config directory: %v", err)
+		}
+	}
+	return config.LoadConfigWithEnv()
+}
+
+// runVersion implements "simple-proxy version [-short]"
+func runVersion(args []string) int {
+	flags := newFlagSet("version", "version [-short]")
+	short := flags.Bool("short", false, "Print only the version")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *short {
+		fmt.Println(Version)
+		return 0
+	}
+	fmt.Println(GetBuildInfo())
+	return 0
+}
+
+// runValidate implements "simple-proxy validate [-config-dir DIR] [-json]": it
+// loads the configuration the way serve does and reports the first error
+func runValidate(args []string) int {
+	flags := newFlagSet("validate", "validate [-config-dir DIR] [-json]")
+	configDir := configDirFlag(flags)
+	asJSON := flags.Bool("json", false, "Print the effective configuration with secrets masked")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := loadConfig(*configDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration: %v\n", err)
+		return 1
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(cfg.Sanitized()); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to encode configuration: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	fmt.Println("✅ Configuration is valid")
+	fmt.Printf("   BIG_MODEL:        %s (%d endpoints)\n", cfg.BigModel, len(cfg.BigModelEndpoints))
+	fmt.Printf("   SMALL_MODEL:      %s (%d endpoints)\n", cfg.SmallModel, len(cfg.SmallModelEndpoints))
+	fmt.Printf("   CORRECTION_MODEL: %s (%d endpoints)\n", cfg.CorrectionModel, len(cfg.ToolCorrectionEndpoints))
+	for _, file := range cfg.Sanitized().OverrideFiles {
+		fmt.Printf("   %s: %s\n", file.Path, file.Status)
+	}
+	fmt.Printf("   Config hash:      %s\n", cfg.Hash())
+	return 0
+}
+
+// messageResult is the outcome of one request sent to a running proxy
+type messageResult struct {
+	Status   int
+	Latency  time.Duration
+	Response *types.AnthropicResponse // Nil unless the status is 200
+	Error    string                   // Transport failure or error body
+}
+
+// postMessage sends a non-streaming /v1/messages request to url
+func postMessage(client *http.Client, url, apiKey string, req types.AnthropicRequest) messageResult {
+	req.Stream = false
+	body, err := json.Marshal(req)
+	if err != nil {
+		return messageResult{Error: err.Error()}
+	}
+	httpReq, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return messageResult{Error: err.Error()}
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		httpReq.Header.Set("x-api-key", apiKey)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return messageResult{Latency: time.Since(start), Error: err.Error()}
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	result := messageResult{Status: resp.StatusCode, Latency: time.Since(start)}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if resp.StatusCode != http.StatusOK {
+		result.Error = strings.TrimSpace(string(data))
+		return result
+	}
+	var anthropicResp types.AnthropicResponse
+	if err := json.Unmarshal(data, &anthropicResp); err != nil {
+		result.Error = fmt.Sprintf("invalid response: %v", err)
+		return result
+	}
+	result.Response = &anthropicResp
+	return result
+}
+
+// toolCallNames lists the tools a response calls, in order
+func toolCallNames(resp *types.AnthropicResponse) []string {
+	var names []string
+	if resp == nil {
+		return names
+	}
+	for _, content := range resp.Content {
+		if content.Type == "tool_use" {
+			names = append(names, content.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Access log outputs (ACCESS_LOG)
+const (
+	AccessLogOff  = ""     // No access log
+	AccessLogFile = "file" // JSON lines appended to ACCESS_LOG_FILE
+	AccessLogLoki = "loki" // Loki entries with component access_log
+)
+
+// AccessLogFields are the fields an access log entry can carry, in the order
+// they are documented. ACCESS_LOG_FIELDS selects a subset.
+var AccessLogFields = []string{
+	"method",
+	"path",
+	"model",
+	"status",
+	"duration_ms",
+	"bytes",
+	"request_id",
+	"client_key",
+}
+
+// parseAccessLogFields validates a comma-separated ACCESS_LOG_FIELDS value
+func parseAccessLogFields(value string) ([]string, error) {
+	fields := splitList(strings.ToLower(value))
+	for _, field := range fields {
+		known := false
+		for _, name := range AccessLogFields {
+			if field == name {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown access log field %q, must be one of: %s", field, strings.Join(AccessLogFields, ", "))
+		}
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("at least one access log field is required")
+	}
+	return fields, nil
+}
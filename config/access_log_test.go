@@ -0,0 +1,26 @@
+package config
+
+import "testing"
+
+// TestParseAccessLogFields tests ACCESS_LOG_FIELDS validation
+func TestParseAccessLogFields(t *testing.T) {
+	fields, err := parseAccessLogFields("Method, path,status,,client_key")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{"method", "path", "status", "client_key"}
+	if len(fields) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, fields)
+	}
+	for i := range expected {
+		if fields[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, fields)
+		}
+	}
+
+	for _, value := range []string{"method,user_agent", " , "} {
+		if _, err := parseAccessLogFields(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
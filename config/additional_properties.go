@@ -0,0 +1,35 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"claude-proxy/types"
+)
+
+// parseAdditionalProperties applies ADDITIONAL_PROPERTIES_POLICY and
+// ADDITIONAL_PROPERTIES_TOOLS to policy. Tool entries take the form
+// Tool:policy, comma-separated, and override the defaults tool by tool.
+func parseAdditionalProperties(policy types.AdditionalPropertiesPolicy, defaultPolicy, toolPolicies string) (types.AdditionalPropertiesPolicy, error) {
+	result := types.AdditionalPropertiesPolicy{Default: policy.Default, Tools: make(map[string]string, len(policy.Tools))}
+	for tool, toolPolicy := range policy.Tools {
+		result.Tools[tool] = toolPolicy
+	}
+
+	if defaultPolicy != "" {
+		result.Default = strings.ToLower(strings.TrimSpace(defaultPolicy))
+	}
+	for _, entry := range splitList(toolPolicies) {
+		tool, toolPolicy, found := strings.Cut(entry, ":")
+		tool = strings.TrimSpace(tool)
+		if !found || tool == "" {
+			return policy, fmt.Errorf("entry %q must be tool:policy", entry)
+		}
+		result.Tools[tool] = strings.ToLower(strings.TrimSpace(toolPolicy))
+	}
+
+	if err := result.Validate(); err != nil {
+		return policy, err
+	}
+	return result, nil
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"testing"
+
+	"claude-proxy/types"
+)
+
+// TestParseAdditionalProperties tests ADDITIONAL_PROPERTIES_POLICY and
+// ADDITIONAL_PROPERTIES_TOOLS parsing on top of the defaults
+func TestParseAdditionalProperties(t *testing.T) {
+	defaults := types.DefaultAdditionalPropertiesPolicy()
+	policy, err := parseAdditionalProperties(defaults, "Strip", "mcp__github__create_issue:error, Bash:allow")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := map[string]string{
+		"Read":                      types.AdditionalPropertiesStrip, // Global policy
+		"Task":                      types.AdditionalPropertiesAllow, // Default kept
+		"Bash":                      types.AdditionalPropertiesAllow,
+		"mcp__github__create_issue": types.AdditionalPropertiesError,
+	}
+	for tool, expected := range tests {
+		if got := policy.For(tool); got != expected {
+			t.Errorf("%s: expected %s, got %s", tool, expected, got)
+		}
+	}
+	if _, overridden := defaults.Tools["Bash"]; overridden {
+		t.Error("Parsing must not modify the defaults")
+	}
+
+	for _, value := range [][2]string{{"ignore", ""}, {"", "Task"}, {"", "Task:keep"}, {"", ":strip"}} {
+		if _, err := parseAdditionalProperties(defaults, value[0], value[1]); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
@@ -4,11 +4,17 @@ import (
 	"bufio"
 	"claude-proxy/circuitbreaker"
 	"claude-proxy/internal"
+	"claude-proxy/parser"
+	"claude-proxy/types"
 	"context"
+	"crypto/sha256"
 	"fmt"
-	"log"
+	"net"
+	"net/http"
 	"os"
+	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -38,21 +44,82 @@ import (
 // The Config struct is thread-safe for read operations and includes mutex
 // protection for endpoint rotation and health management operations.
 type Config struct {
-	Port string `json:"port"`
+	Port            string          `json:"port"`
+	ListenAddresses []ListenAddress `json:"listen_addresses"` // LISTEN sockets; empty serves TCP on Port
 
 	// Tool correction settings
-	ToolCorrectionEnabled bool `json:"tool_correction_enabled"`
+	ToolCorrectionEnabled       bool `json:"tool_correction_enabled"`
+	DisableSmallModelCorrection bool `json:"disable_small_model_correction"` // SMALL_MODEL requests skip correction and tool necessity detection
 
 	// Empty message handling
 	HandleEmptyToolResults  bool `json:"handle_empty_tool_results"`  // Replace empty tool results with descriptive messages
 	HandleEmptyUserMessages bool `json:"handle_empty_user_messages"` // Replace empty user messages with placeholder content
 
+	// Tool result normalization before forwarding to the backend
+	ToolResultRepair    bool `json:"tool_result_repair"`     // Decode nested JSON, close truncated JSON and pretty-print
+	ToolResultMaxTokens int  `json:"tool_result_max_tokens"` // Cut longer tool results in the middle (0 = no limit)
+
+	// Extended thinking round-trip
+	ForwardThinkingBlocks bool `json:"forward_thinking_blocks"` // Send prior-turn thinking as reasoning_content instead of stripping it
+
+	// Upstream responses with n>1 or unexpected choice indices: "first", "merge" or "error"
+	MultiChoicePolicy string `json:"multi_choice_policy"`
+
+	// Tool calls identical to the one before them in a response: "drop", "warn" or "keep"
+	DuplicateToolCallPolicy string `json:"duplicate_tool_call_policy"`
+
+	// Tool-call loop detection: identical calls in a row that make a loop, and
+	// the reaction: "respond", "nudge" or "tool_result"
+	LoopDetectionThreshold int    `json:"loop_detection_threshold"`
+	LoopDetectionAction    string `json:"loop_detection_action"`
+
+	// Consecutive tool-only assistant turns after which the next upstream
+	// call is sent with tool_choice "none" (0 = unlimited)
+	MaxToolIterations int `json:"max_tool_iterations"`
+
+	// finish_reason -> stop_reason entries that extend or replace DefaultStopReasons
+	StopReasons map[string]string `json:"stop_reasons,omitempty"`
+
 	// Tool filtering settings
 	SkipTools []string `json:"skip_tools"` // Tools to skip/filter out from requests
 
 	// Tool description overrides (loaded from tools_override.yaml)
 	ToolDescriptions map[string]string `json:"tool_descriptions"`
 
+	// Tool schema minification (shrinks descriptions sent upstream; validation keeps full schemas)
+	ToolSchemaMinifyEnabled  bool           `json:"tool_schema_minify_enabled"`   // Strip examples and truncate tool/parameter descriptions
+	ToolSchemaMinifyMaxChars int            `json:"tool_schema_minify_max_chars"` // Default description budget per tool
+	ToolSchemaMinifyLimits   map[string]int `json:"tool_schema_minify_limits"`    // Per-tool budgets, 0 keeps the full description
+	ToolSchemaMinifyMode     string         `json:"tool_schema_minify_mode"`      // "truncate" or "summarize" (LLM summaries via the correction model)
+	ToolSummaryCachePath     string         `json:"tool_summary_cache_path"`      // On-disk cache of summarized descriptions
+
+	// Warn when a known tool's input_schema changes between Claude Code releases
+	ToolSchemaDriftEnabled bool   `json:"tool_schema_drift_enabled"`
+	ToolSchemaRegistryPath string `json:"tool_schema_registry_path"` // Last known schema fingerprint per tool
+
+	// Request stream_options.include_usage so streamed responses report real token usage
+	StreamIncludeUsage bool `json:"stream_include_usage"`
+
+	// Validate the proxy's own SSE event sequence and log ordering violations (debug)
+	SSEVerifyEnabled bool `json:"sse_verify_enabled"`
+
+	// Honor the x-proxy-debug request header by returning a proxy_debug trace
+	ProxyDebugHeaderEnabled bool `json:"proxy_debug_header_enabled"`
+
+	// Honor the x-proxy-no-correction request header by skipping tool correction for that request
+	NoCorrectionHeaderEnabled bool `json:"no_correction_header_enabled"`
+
+	// Mapped model globs translated only at the protocol level: no correction, Harmony
+	// parsing, system overrides or tool filtering
+	PassthroughModels []string `json:"passthrough_models,omitempty"`
+
+	// Coalesce streamed SSE events into fewer flushes (0 disables each trigger)
+	SSECoalesceIntervalMs int `json:"sse_coalesce_interval_ms"` // Flush at most every N ms
+	SSECoalesceBytes      int `json:"sse_coalesce_bytes"`       // Flush once M bytes are buffered
+
+	// Minify tool schemas for requests that send the token-efficient-tools anthropic-beta
+	BetaMinifyTools bool `json:"beta_minify_tools"`
+
 	// Debug settings
 	PrintSystemMessage           bool `json:"print_system_message"`            // Print system messages to logs
 	PrintToolSchemas             bool `json:"print_tool_schemas"`              // Print tool schemas from Claude Code for debugging
@@ -62,24 +129,183 @@ type Config struct {
 	// Conversation logging settings
 	ConversationLoggingEnabled bool   `json:"conversation_logging_enabled"` // Enable full conversation logging
 	ConversationLogLevel       string `json:"conversation_log_level"`       // Log level for conversation logs (DEBUG, INFO, WARN, ERROR)
+	ConversationLogFilter      ConversationLogFilter `json:"conversation_log_filter"` // Models and paths conversation logging covers
 	ConversationMaskSensitive  bool   `json:"conversation_mask_sensitive"`  // Mask sensitive data in conversation logs
+	ConversationMaskDetectors  []string `json:"conversation_mask_detectors"` // Built-in detectors applied when masking
+	RedactionPatterns          []RedactionPattern `json:"redaction_patterns"` // Custom detectors (loaded from redaction_patterns.yaml)
 	ConversationLogFullTools   bool   `json:"conversation_log_full_tools"`  // Log full tool definitions vs tool names only
 	ConversationTruncation     int    `json:"conversation_truncation"`      // Maximum message length (0 = disabled)
+	ConversationTruncationTokens int  `json:"conversation_truncation_tokens"` // Maximum message length in estimated tokens, overrides ConversationTruncation (0 = disabled)
+
+	// Runtime log levels (initial values; adjustable via /admin/log-level)
+	LogLevel           string `json:"log_level"`            // Default minimum log level (DEBUG, INFO, WARN, ERROR)
+	ComponentLogLevels string `json:"component_log_levels"` // Per-component overrides, e.g. "correction=DEBUG,harmony=DEBUG"
 
 	// Connection timeout settings
 	DefaultConnectionTimeout int `json:"default_connection_timeout"` // Connection timeout in seconds for all endpoints
 
+	// HTTP server timeouts in seconds, 0 disables the timeout
+	ServerReadTimeout  int `json:"server_read_timeout"`  // Reading a whole client request, including large conversation uploads
+	ServerWriteTimeout int `json:"server_write_timeout"` // Writing a whole response, including long big model streams
+	ServerIdleTimeout  int `json:"server_idle_timeout"`  // Keep-alive connections waiting for the next request
+
+	// Statistics persistence and cost estimation
+	StatsPersistenceEnabled bool                  `json:"stats_persistence_enabled"` // Persist cumulative usage/cost/correction stats across restarts
+	StatsDBPath             string                `json:"stats_db_path"`             // Path of the embedded stats database
+	ModelPricing            map[string]ModelPrice `json:"model_pricing"`             // USD per million tokens, keyed by provider model name
+
+	// Per-session spend limits; a session is metadata.user_id, else the client API key (0 = unlimited)
+	SessionBudgetTokens int     `json:"session_budget_tokens"` // Input plus output tokens
+	SessionBudgetUSD    float64 `json:"session_budget_usd"`    // Estimated cost from MODEL_PRICING
+
+	// Daily and monthly spend limits per client API key, reloadable at runtime
+	SpendLimits SpendLimits `json:"spend_limits"`
+
+	// Streaming speed per client API key, enforced while writing SSE events (0 = unlimited)
+	StreamRateLimits StreamRateLimits `json:"stream_rate_limits"`
+
+	// Mapped models each client API key may use (empty = no restrictions)
+	ClientModelAccess ModelAccess `json:"client_model_access"`
+
+	// Upstream calls in progress at once; interactive requests are queued ahead of background ones (0 = unlimited)
+	MaxConcurrentRequests int `json:"max_concurrent_requests"`
+
+	// Send a one-token generation to every endpoint at startup and after circuit recovery
+	WarmupEnabled bool `json:"warmup_enabled"`
+
+	// Recent requests kept in memory for GET /admin/requests (0 = off)
+	RequestHistorySize int `json:"request_history_size"`
+
+	// File the SIGUSR1 diagnostic dump is appended to ("" = the process log)
+	DiagnosticDumpFile string `json:"diagnostic_dump_file"`
+
+	// Message Batches API (/v1/messages/batches)
+	BatchesEnabled   bool   `json:"batches_enabled"`   // Serve the batches endpoints
+	BatchDBPath      string `json:"batch_db_path"`     // Path of the embedded batch database
+	BatchConcurrency int    `json:"batch_concurrency"` // Entries processed in parallel across all batches
+
+	// Conversation search (GET /admin/conversations)
+	ConversationSearchEnabled bool   `json:"conversation_search_enabled"` // Keep logged conversations in an indexed local store
+	ConversationDBPath        string `json:"conversation_db_path"`        // Path of the embedded conversation database
+
+	// Crash-safe journal of accepted requests (GET /admin/journal)
+	RequestJournalEnabled bool   `json:"request_journal_enabled"` // Journal full request bodies until they are answered
+	RequestJournalPath    string `json:"request_journal_path"`    // JSON lines file holding the journal
+
+	// Retention of stored data, 0 keeps it forever (purged on demand via POST /admin/purge)
+	ConversationRetentionDays int `json:"conversation_retention_days"` // Delete stored conversations older than this
+	ConversationMaxMB         int `json:"conversation_max_mb"`         // Delete the oldest stored conversations beyond this size
+	BatchRetentionDays        int `json:"batch_retention_days"`        // Delete batches this long after they ended
+	JournalRetentionDays      int `json:"journal_retention_days"`      // Dismiss interrupted journal entries older than this
+	StatsRetentionDays        int `json:"stats_retention_days"`        // Reset cumulative stats once they span this many days
+	RetentionIntervalMinutes  int `json:"retention_interval_minutes"`  // How often retention is enforced
+
+	// Circuit breaker alerting
+	AlertWebhookURL string `json:"alert_webhook_url"` // Webhook/Slack URL notified when circuits open or a role loses all endpoints
+
+	// Circuit breaking for big model endpoints (bypassed by default for 30+ minute generations)
+	BigModelCircuitBreaker   bool `json:"big_model_circuit_breaker"`   // Skip big model endpoints with an open circuit
+	BigModelFailureThreshold int  `json:"big_model_failure_threshold"` // Consecutive failures before a big model circuit opens
+
+	// Circuit breaker state persistence
+	CircuitStatePath       string `json:"circuit_state_path"`        // JSON file holding endpoint health across restarts
+	CircuitStateTTLSeconds int    `json:"circuit_state_ttl_seconds"` // Saved state older than this is ignored, 0 disables persistence
+
+	// StatsD/Datadog metrics
+	MetricsStatsDAddr   string `json:"metrics_statsd_addr"`   // host:port of a StatsD agent, empty disables the emitter
+	MetricsStatsDPrefix string `json:"metrics_statsd_prefix"` // Prefix prepended to every metric name
+
+	// gRPC control plane
+	AdminGRPCAddr  string `json:"admin_grpc_addr"` // host:port of the gRPC admin service, empty disables it
+	AdminGRPCToken string `json:"-"`               // Bearer token required by the gRPC admin service, empty allows all callers
+
+	// HTTP admin routes
+	AdminToken string `json:"-"` // Bearer token required by /admin routes; routes that change state are not served without it
+
+	// CORS for browser clients on /v1/messages
+	CORS CORSConfig `json:"cors"`
+
+	// HTTP access log, separate from conversation logging
+	AccessLog       string   `json:"access_log"`        // "", "file" or "loki"
+	AccessLogPath   string   `json:"access_log_path"`   // File written when AccessLog is "file"
+	AccessLogFields []string `json:"access_log_fields"` // Subset of AccessLogFields included in each entry
+
+	// Tool correction backend: "llm", "rules" (no correction model) or "remote"
+	CorrectionBackend              string `json:"correction_backend"`
+	CorrectionRemoteURL            string `json:"correction_remote_url"`             // Endpoint of the external correction service
+	CorrectionRemoteTimeoutSeconds int    `json:"correction_remote_timeout_seconds"` // Per-request timeout for the remote backend
+
+	// Correction latency budget per request: calls still invalid when it runs out are forwarded or blocked
+	CorrectionBudgetMs     int    `json:"correction_budget_ms"`     // 0 disables the budget
+	CorrectionBudgetPolicy string `json:"correction_budget_policy"` // "forward" or "block"
+
+	// Correction attempts per tool call and the fate of calls still invalid after them
+	CorrectionMaxRetries      int    `json:"correction_max_retries"`
+	CorrectionExhaustedPolicy string `json:"correction_exhausted_policy"` // "original", "drop" or "block"
+
+	// Parameter renames learned from LLM corrections and whether they join the rule-based mappings
+	CorrectionLearnThreshold int  `json:"correction_learn_threshold"` // Observations before a rename counts as learned
+	CorrectionAutoPromote    bool `json:"correction_auto_promote"`    // Apply learned renames without calling the correction model
+
+	// Conversation tail included in correction prompts
+	CorrectionContextMessages int `json:"correction_context_messages"` // 0 leaves the conversation out
+	CorrectionContextTokens   int `json:"correction_context_tokens"`   // Estimated token budget of the tail
+
+	// Rounds of returning invalid tool calls to the model as failed tool_results before correction
+	ValidationFeedbackRounds int `json:"validation_feedback_rounds"` // 0 disables validation feedback
+
+	// Severity per validation rule (warn, fix or block), unlisted rules are fixed
+	ValidationSeverity map[string]string `json:"validation_severity"`
+
+	// Handling of tool parameters the schema does not define (error, allow or strip), globally and per tool
+	AdditionalProperties types.AdditionalPropertiesPolicy `json:"additional_properties"`
+
+	// Directory of correction model prompt templates overriding the built-in ones
+	PromptsDir string `json:"prompts_dir"`
+
 	// Tool choice correction and necessity detection
 	EnableToolChoiceCorrection bool `json:"enable_tool_choice_correction"` // Enable tool choice correction and necessity detection
 
 	// System message overrides (loaded from system_overrides.yaml)
 	SystemMessageOverrides SystemMessageOverrides `json:"system_message_overrides"`
 
+	// Per-model system instructions (loaded from model_prompts.yaml), keyed by backend model
+	ModelSystemPrompts map[string]string `json:"model_system_prompts"`
+
+	// Per-model request profiles (loaded from model_profiles.yaml), keyed by backend model
+	ModelProfiles map[string]ModelProfile `json:"model_profiles"`
+
+	// Per-endpoint transport settings (loaded from endpoints.yaml), keyed by URL prefix
+	EndpointSettings map[string]EndpointSettings `json:"endpoint_settings"`
+
+	// Custom provider quirk profiles (loaded from provider_profiles.yaml), selected per endpoint
+	ProviderProfiles map[string]ProviderProfile `json:"provider_profiles"`
+
+	// Custom tool name/parameter normalization (loaded from tool_validators.yaml)
+	CustomTools []types.CustomTool `json:"custom_tools"`
+
+	// Tool-call governance rules (loaded from tool_policies.yaml), first match wins
+	ToolPolicies []ToolPolicy `json:"tool_policies"`
+
+	// Seconds a tool call held for approval waits before it is denied
+	ApprovalTimeoutSeconds int `json:"approval_timeout_seconds"`
+
 	// Harmony parsing settings
 	HarmonyParsingEnabled bool `json:"harmony_parsing_enabled"` // Enable Harmony format parsing
 	HarmonyDebug          bool `json:"harmony_debug"`           // Enable detailed Harmony debug logging
 	HarmonyStrictMode     bool `json:"harmony_strict_mode"`     // Strict error handling for malformed Harmony content
 
+	HarmonyModels        []string `json:"harmony_models,omitempty"` // Mapped model globs Harmony parsing applies to (e.g. gpt-oss*); empty is every model
+	HarmonyDeveloperRole bool     `json:"harmony_developer_role"`   // Send the system prompt to HARMONY_MODELS as a developer message
+
+	// Harmony system message preamble for HARMONY_MODELS
+	HarmonySystemPreamble  bool   `json:"harmony_system_preamble"`  // Inject identity, knowledge cutoff, date, reasoning level and channels
+	HarmonyReasoningLevel  string `json:"harmony_reasoning_level"`  // "low", "medium" (default) or "high"
+	HarmonyKnowledgeCutoff string `json:"harmony_knowledge_cutoff"` // Knowledge cutoff stated in the preamble (default 2024-06)
+
+	// Harmony token dialect recognized in responses (HARMONY_*_TOKEN, defaults to gpt-oss tokens)
+	HarmonyTokens parser.TokenSet `json:"harmony_tokens"`
+
 	// Model configuration (.env configurable)
 	BigModel        string `json:"big_model"`        // For Claude Sonnet requests
 	SmallModel      string `json:"small_model"`      // For Claude Haiku requests
@@ -90,6 +316,11 @@ type Config struct {
 	SmallModelEndpoints     []string `json:"small_model_endpoints"`     // Endpoints for SMALL_MODEL (comma-separated)
 	ToolCorrectionEndpoints []string `json:"tool_correction_endpoints"` // Endpoints for TOOL_CORRECTION_LLM (comma-separated)
 
+	// Endpoint lists as configured, for roles using dns+, srv+ or k8s+ specs that are expanded at runtime
+	EndpointSpecs                  map[string][]string `json:"endpoint_specs,omitempty"`
+	EndpointResolveIntervalSeconds int                 `json:"endpoint_resolve_interval_seconds"` // How often dns+/srv+/k8s+ specs are resolved again
+	KubernetesAPIURL               string              `json:"kubernetes_api_url,omitempty"`      // API server for k8s+ specs; in-cluster service account when empty
+
 	// API Key configuration (.env configurable)
 	BigModelAPIKey       string `json:"big_model_api_key"`       // API Key for BIG_MODEL
 	SmallModelAPIKey     string `json:"small_model_api_key"`     // API Key for SMALL_MODEL
@@ -103,6 +334,15 @@ type Config struct {
 
 	// Circuit breaker health manager
 	HealthManager *circuitbreaker.HealthManager `json:"-"`
+
+	// HTTP transports shared by endpoints with the same settings
+	transports     map[transportKey]*http.Transport `json:"-"`
+	transportMutex sync.Mutex                       `json:"-"`
+
+	// Load provenance for introspection (not serialized)
+	envFilePath   string               `json:"-"`
+	loadedAt      time.Time            `json:"-"`
+	overrideFiles []OverrideFileStatus `json:"-"`
 	
 	// Observability logger (optional, can be nil during initial config loading)
 	obsLogger interface {
@@ -200,15 +440,74 @@ func GetDefaultConfig() *Config {
 		PrintSystemMessage:           false,                    // Disabled by default
 		PrintToolSchemas:             false,                    // Disabled by default
 		DisableSmallModelLogging:     false,                    // Enabled by default (normal logging)
+		DisableSmallModelCorrection:  false,                    // Small model requests are corrected like any other
 		DisableToolCorrectionLogging: false,                    // Enabled by default (normal logging)
 		ConversationLoggingEnabled:   false,                    // Disabled by default
 		ConversationLogLevel:         "INFO",                   // Default to INFO level
 		ConversationMaskSensitive:    true,                     // Enable sensitive data masking by default
+		ConversationMaskDetectors:    append([]string(nil), redactionDetectors...), // Every built-in detector
+		LogLevel:                     "INFO",                   // Default to INFO level
+		CORS:                         DefaultCORSConfig(),      // No origins allowed until CORS_ALLOWED_ORIGINS is set
+		AccessLog:                    AccessLogOff,             // No access log by default
+		AccessLogPath:                "access.log",             // Default access log file
+		AccessLogFields:              AccessLogFields,          // Every field by default
+		CorrectionBackend:            CorrectionBackendLLM,     // Correction model after rule-based fixes
+		MultiChoicePolicy:            MultiChoiceFirst,         // Keep the first choice of multi-choice responses
+		DuplicateToolCallPolicy:      DuplicateToolCallDrop,    // Never let the client run the same call twice in a row
+		LoopDetectionThreshold:       3,                        // Identical tool calls in a row that make a loop
+		LoopDetectionAction:          LoopActionRespond,        // Break loops with a message to the client
+		CorrectionRemoteTimeoutSeconds: 30,                     // Remote correction service timeout
+		CorrectionBudgetPolicy:       CorrectionBudgetForward,  // Forward calls left uncorrected when the budget runs out
+		PromptsDir:                   "prompts",                // Built-in prompts are used for files not found here
+		CorrectionMaxRetries:         3,                        // Correction attempts per tool call
+		CorrectionExhaustedPolicy:    CorrectionExhaustedOriginal, // Return calls that could not be fixed unchanged
+		AdditionalProperties:         types.DefaultAdditionalPropertiesPolicy(), // Undefined parameters are invalid, except for Task
+		CorrectionLearnThreshold:     3,                        // Learn a rename after three identical LLM fixes
+		CorrectionContextTokens:      1000,                     // Conversation tail budget once CORRECTION_CONTEXT_MESSAGES is set
+		ApprovalTimeoutSeconds:       300,                         // Deny held tool calls after 5 minutes
+		RetentionIntervalMinutes:     60,                          // Enforce retention hourly
+		StatsPersistenceEnabled:      false,                    // Stateless by default for tests
+		ToolSchemaDriftEnabled:       false,                    // Stateless by default for tests
+		StatsDBPath:                  "stats.db",               // Default stats database path
+		CircuitStatePath:             "circuit_state.json",     // Default circuit breaker state file
+		CircuitStateTTLSeconds:       0,                        // Stateless by default for tests
+		BigModelFailureThreshold:     5,                        // Tolerate more failures from slow big models
+		BatchesEnabled:               false,                    // Stateless by default for tests
+		BatchDBPath:                  "batches.db",             // Default batch database path
+		BatchConcurrency:             4,                        // Bounded fan-out to the pipeline
+		ServerReadTimeout:            30,                       // Client request read timeout
+		ServerWriteTimeout:           300,                      // Long enough for most streamed responses
+		ServerIdleTimeout:            60,                       // Keep-alive idle timeout
+		EndpointResolveIntervalSeconds: 30,                     // Re-resolve dns+/srv+ endpoint specs
+		ConversationSearchEnabled:    false,                    // Opt-in: stores full conversation content
+		ConversationDBPath:           "conversations.db",       // Default conversation database path
+		RequestJournalEnabled:        false,                    // Opt-in: stores full request bodies
+		RequestJournalPath:           "request_journal.jsonl",  // Default request journal file
+		MetricsStatsDPrefix:          "simple_proxy",           // Default metric prefix
+		ModelPricing:                 make(map[string]ModelPrice), // No pricing by default
 		EnableToolChoiceCorrection:   false,                    // Disable tool choice correction by default
 		SystemMessageOverrides:       SystemMessageOverrides{}, // Empty by default
+		ModelSystemPrompts:           make(map[string]string),  // No per-model prompts by default
+		ModelProfiles:                make(map[string]ModelProfile), // No per-model profiles by default
+		ProviderProfiles:             make(map[string]ProviderProfile), // Built-in provider profiles only
+		ToolSchemaMinifyMaxChars:     DefaultToolDescriptionMaxChars, // Used only when minification is enabled
+		ToolSchemaMinifyMode:         ToolMinifyTruncate,       // Deterministic truncation by default
+		ToolSummaryCachePath:         "tool_summaries.json",    // Stored next to .env by default
+		ToolSchemaRegistryPath:       "tool_schemas.json",      // Stored next to .env by default
+		StreamIncludeUsage:           true,                     // Disable per model with dropParams: [stream_options]
+		SSEVerifyEnabled:             false,                    // Debug-only self-check
+		ProxyDebugHeaderEnabled:      false,                    // Traces expose internals, opt-in only
+		NoCorrectionHeaderEnabled:    false,                    // Clients cannot switch correction off unless allowed
+		SSECoalesceIntervalMs:        0,                        // Flush every event by default
+		SSECoalesceBytes:             0,                        // Flush every event by default
+		BetaMinifyTools:              false,                    // Betas never change tool schemas unless enabled
 		HarmonyParsingEnabled:        true,                      // Enable by default
 		HarmonyDebug:                 false,                     // Disabled by default
 		HarmonyStrictMode:            false,                     // Lenient by default
+		HarmonyReasoningLevel:        HarmonyReasoningMedium,
+		HarmonyKnowledgeCutoff:       "2024-06",
+		HarmonyTokens:                parser.DefaultTokenSet(),
+		RequestHistorySize:           100,
 		BigModel:                     "",                       // Will be set from .env
 		SmallModel:                   "",                       // Will be set from .env
 		CorrectionModel:              "",                       // Will be set from .env
@@ -237,7 +536,7 @@ func GetDefaultConfig() *Config {
 //   - BIG_MODEL, SMALL_MODEL, CORRECTION_MODEL: Model identifiers
 //   - BIG_MODEL_ENDPOINT, SMALL_MODEL_ENDPOINT, TOOL_CORRECTION_ENDPOINT: Provider URLs
 //   - BIG_MODEL_API_KEY, SMALL_MODEL_API_KEY, TOOL_CORRECTION_API_KEY: Authentication
-//   - LOG_FULL_TOOLS, CONVERSATION_TRUNCATION: Logging configuration
+//   - LOG_FULL_TOOLS, CONVERSATION_TRUNCATION, CONVERSATION_TRUNCATION_TOKENS: Logging configuration
 //
 // Optional configurations:
 //   - Feature flags: HARMONY_PARSING_ENABLED, PRINT_SYSTEM_MESSAGE
@@ -280,14 +579,73 @@ func LoadConfigWithEnv() (*Config, error) {
 		ConversationLoggingEnabled: false,                    // Disabled by default
 		ConversationLogLevel:       "INFO",                   // Default to INFO level
 		ConversationMaskSensitive:  true,                     // Enable sensitive data masking by default
+		ConversationMaskDetectors:  append([]string(nil), redactionDetectors...), // Every built-in detector
 		ConversationLogFullTools:     false,                    // Log tool names only by default
 		ConversationTruncation:       0,                        // No truncation by default
+		LogLevel:                     "INFO",                   // Default to INFO level
+		CORS:                         DefaultCORSConfig(),      // No origins allowed until CORS_ALLOWED_ORIGINS is set
+		AccessLog:                    AccessLogOff,             // No access log by default
+		AccessLogPath:                "access.log",             // Default access log file
+		AccessLogFields:              AccessLogFields,          // Every field by default
+		CorrectionBackend:            CorrectionBackendLLM,     // Correction model after rule-based fixes
+		MultiChoicePolicy:            MultiChoiceFirst,         // Keep the first choice of multi-choice responses
+		DuplicateToolCallPolicy:      DuplicateToolCallDrop,    // Never let the client run the same call twice in a row
+		LoopDetectionThreshold:       3,                        // Identical tool calls in a row that make a loop
+		LoopDetectionAction:          LoopActionRespond,        // Break loops with a message to the client
+		CorrectionRemoteTimeoutSeconds: 30,                     // Remote correction service timeout
+		CorrectionBudgetPolicy:       CorrectionBudgetForward,  // Forward calls left uncorrected when the budget runs out
+		PromptsDir:                   "prompts",                // Built-in prompts are used for files not found here
+		CorrectionMaxRetries:         3,                        // Correction attempts per tool call
+		CorrectionExhaustedPolicy:    CorrectionExhaustedOriginal, // Return calls that could not be fixed unchanged
+		AdditionalProperties:         types.DefaultAdditionalPropertiesPolicy(), // Undefined parameters are invalid, except for Task
+		CorrectionLearnThreshold:     3,                        // Learn a rename after three identical LLM fixes
+		CorrectionContextTokens:      1000,                     // Conversation tail budget once CORRECTION_CONTEXT_MESSAGES is set
+		ApprovalTimeoutSeconds:       300,                         // Deny held tool calls after 5 minutes
+		RetentionIntervalMinutes:     60,                          // Enforce retention hourly
+		StatsPersistenceEnabled:      true,                     // Persist cumulative stats by default
+		ToolSchemaDriftEnabled:       true,                     // Track tool schemas across releases by default
+		StatsDBPath:                  "stats.db",               // Stored next to .env by default
+		CircuitStatePath:             "circuit_state.json",     // Stored next to .env by default
+		CircuitStateTTLSeconds:       900,                      // Remember failing endpoints for 15 minutes
+		BigModelFailureThreshold:     5,                        // Tolerate more failures from slow big models
+		BatchesEnabled:               true,                     // Batches API available by default
+		BatchDBPath:                  "batches.db",             // Stored next to .env by default
+		BatchConcurrency:             4,                        // Bounded fan-out to the pipeline
+		ServerReadTimeout:            30,                       // Client request read timeout
+		ServerWriteTimeout:           300,                      // Long enough for most streamed responses
+		ServerIdleTimeout:            60,                       // Keep-alive idle timeout
+		EndpointResolveIntervalSeconds: 30,                     // Re-resolve dns+/srv+ endpoint specs
+		ConversationSearchEnabled:    false,                    // Opt-in: stores full conversation content
+		ConversationDBPath:           "conversations.db",       // Default conversation database path
+		RequestJournalEnabled:        false,                    // Opt-in: stores full request bodies
+		RequestJournalPath:           "request_journal.jsonl",  // Default request journal file
+		MetricsStatsDPrefix:          "simple_proxy",           // Default metric prefix
+		ModelPricing:                 make(map[string]ModelPrice), // No pricing, cost reported as 0
 		DefaultConnectionTimeout:     30,                       // 30 seconds default connection timeout
 		EnableToolChoiceCorrection:   false,                    // Disable tool choice correction by default
 		SystemMessageOverrides:       SystemMessageOverrides{}, // Empty by default
+		ModelSystemPrompts:           make(map[string]string),  // No per-model prompts by default
+		ModelProfiles:                make(map[string]ModelProfile), // No per-model profiles by default
+		ProviderProfiles:             make(map[string]ProviderProfile), // Built-in provider profiles only
+		ToolSchemaMinifyMaxChars:     DefaultToolDescriptionMaxChars, // Used only when minification is enabled
+		ToolSchemaMinifyMode:         ToolMinifyTruncate,       // Deterministic truncation by default
+		ToolSummaryCachePath:         "tool_summaries.json",    // Stored next to .env by default
+		ToolSchemaRegistryPath:       "tool_schemas.json",      // Stored next to .env by default
+		StreamIncludeUsage:           true,                     // Disable per model with dropParams: [stream_options]
+		SSEVerifyEnabled:             false,                    // Debug-only self-check
+		ProxyDebugHeaderEnabled:      false,                    // Traces expose internals, opt-in only
+		NoCorrectionHeaderEnabled:    false,                    // Clients cannot switch correction off unless allowed
+		DisableSmallModelCorrection:  false,                    // Small model requests are corrected like any other
+		SSECoalesceIntervalMs:        0,                        // Flush every event by default
+		SSECoalesceBytes:             0,                        // Flush every event by default
+		BetaMinifyTools:              false,                    // Betas never change tool schemas unless enabled
 		HarmonyParsingEnabled:        true,                      // Enable by default
 		HarmonyDebug:                 false,                     // Disabled by default
 		HarmonyStrictMode:            false,                     // Lenient by default
+		HarmonyReasoningLevel:        HarmonyReasoningMedium,
+		HarmonyKnowledgeCutoff:       "2024-06",
+		HarmonyTokens:                parser.DefaultTokenSet(),
+		RequestHistorySize:           100,
 		HealthManager:              circuitbreaker.NewHealthManager(circuitbreaker.DefaultConfig()),
 	}
 
@@ -402,6 +760,7 @@ func LoadConfigWithEnv() (*Config, error) {
 	} else {
 		return nil, fmt.Errorf("TOOL_CORRECTION_ENDPOINT must be set in .env file")
 	}
+	cfg.recordEndpointSpecs()
 
 	if toolCorrectionAPIKey, exists := envVars["TOOL_CORRECTION_API_KEY"]; exists && toolCorrectionAPIKey != "" {
 		cfg.ToolCorrectionAPIKey = toolCorrectionAPIKey
@@ -432,6 +791,338 @@ func LoadConfigWithEnv() (*Config, error) {
 		})
 	}
 
+	// Parse TOOL_SCHEMA_MINIFY (optional, defaults to false)
+	if minify, exists := envVars["TOOL_SCHEMA_MINIFY"]; exists {
+		cfg.ToolSchemaMinifyEnabled = minify == "true" || minify == "1"
+		cfg.logInfo("configuration", "request", "", "Configured TOOL_SCHEMA_MINIFY", map[string]interface{}{
+			"enabled": cfg.ToolSchemaMinifyEnabled,
+		})
+	}
+
+	// Parse TOOL_SCHEMA_MINIFY_MAX_CHARS (optional, defaults to DefaultToolDescriptionMaxChars)
+	if maxChars, exists := envVars["TOOL_SCHEMA_MINIFY_MAX_CHARS"]; exists && maxChars != "" {
+		var maxCharsValue int
+		if n, err := fmt.Sscanf(maxChars, "%d", &maxCharsValue); n != 1 || err != nil || maxCharsValue < 1 {
+			return nil, fmt.Errorf("TOOL_SCHEMA_MINIFY_MAX_CHARS must be a positive number, got: %s", maxChars)
+		}
+		cfg.ToolSchemaMinifyMaxChars = maxCharsValue
+		cfg.logInfo("configuration", "request", "", "Configured TOOL_SCHEMA_MINIFY_MAX_CHARS", map[string]interface{}{
+			"max_chars": maxCharsValue,
+		})
+	}
+
+	// Parse TOOL_SCHEMA_MINIFY_LIMITS (optional, Tool=chars per-tool budgets)
+	if minifyLimits, exists := envVars["TOOL_SCHEMA_MINIFY_LIMITS"]; exists && minifyLimits != "" {
+		limits, err := parseToolMinifyLimits(minifyLimits)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ToolSchemaMinifyLimits = limits
+		cfg.logInfo("configuration", "request", "", "Configured TOOL_SCHEMA_MINIFY_LIMITS", map[string]interface{}{
+			"limits": limits,
+		})
+	}
+
+	// Parse TOOL_SCHEMA_MINIFY_MODE (optional, truncate or summarize)
+	if minifyMode, exists := envVars["TOOL_SCHEMA_MINIFY_MODE"]; exists && minifyMode != "" {
+		if minifyMode != ToolMinifyTruncate && minifyMode != ToolMinifySummarize {
+			return nil, fmt.Errorf("TOOL_SCHEMA_MINIFY_MODE must be %q or %q, got: %s", ToolMinifyTruncate, ToolMinifySummarize, minifyMode)
+		}
+		cfg.ToolSchemaMinifyMode = minifyMode
+		cfg.logInfo("configuration", "request", "", "Configured TOOL_SCHEMA_MINIFY_MODE", map[string]interface{}{
+			"mode": minifyMode,
+		})
+	}
+
+	// Parse TOOL_SUMMARY_CACHE_PATH (optional, defaults to tool_summaries.json)
+	if summaryCachePath, exists := envVars["TOOL_SUMMARY_CACHE_PATH"]; exists && summaryCachePath != "" {
+		cfg.ToolSummaryCachePath = summaryCachePath
+		cfg.logInfo("configuration", "request", "", "Configured TOOL_SUMMARY_CACHE_PATH", map[string]interface{}{
+			"path": summaryCachePath,
+		})
+	}
+
+	// Parse CORRECTION_REMOTE_URL (required for CORRECTION_BACKEND=remote)
+	if remoteURL, exists := envVars["CORRECTION_REMOTE_URL"]; exists && remoteURL != "" {
+		cfg.CorrectionRemoteURL = remoteURL
+		cfg.logInfo("configuration", "request", "", "Configured CORRECTION_REMOTE_URL", map[string]interface{}{
+			"url": remoteURL,
+		})
+	}
+
+	// Parse CORRECTION_REMOTE_TIMEOUT_SECONDS (optional, defaults to 30)
+	if remoteTimeout, exists := envVars["CORRECTION_REMOTE_TIMEOUT_SECONDS"]; exists && remoteTimeout != "" {
+		var seconds int
+		if _, err := fmt.Sscanf(remoteTimeout, "%d", &seconds); err != nil || seconds <= 0 {
+			return nil, fmt.Errorf("CORRECTION_REMOTE_TIMEOUT_SECONDS must be a positive integer, got: %s", remoteTimeout)
+		}
+		cfg.CorrectionRemoteTimeoutSeconds = seconds
+		cfg.logInfo("configuration", "request", "", "Configured CORRECTION_REMOTE_TIMEOUT_SECONDS", map[string]interface{}{
+			"seconds": seconds,
+		})
+	}
+
+	// Parse CORRECTION_BACKEND (optional, defaults to llm)
+	if backend, exists := envVars["CORRECTION_BACKEND"]; exists && backend != "" {
+		switch backend {
+		case CorrectionBackendLLM, CorrectionBackendRules:
+		case CorrectionBackendRemote:
+			if cfg.CorrectionRemoteURL == "" {
+				return nil, fmt.Errorf("CORRECTION_REMOTE_URL must be set when CORRECTION_BACKEND is %q", CorrectionBackendRemote)
+			}
+		default:
+			return nil, fmt.Errorf("CORRECTION_BACKEND must be %q, %q or %q, got: %s",
+				CorrectionBackendLLM, CorrectionBackendRules, CorrectionBackendRemote, backend)
+		}
+		cfg.CorrectionBackend = backend
+		cfg.logInfo("configuration", "request", "", "Configured CORRECTION_BACKEND", map[string]interface{}{
+			"backend": backend,
+		})
+	}
+
+	// Parse CORRECTION_BUDGET_MS (optional, defaults to 0 = unlimited)
+	if budget, exists := envVars["CORRECTION_BUDGET_MS"]; exists && budget != "" {
+		budgetMs, err := strconv.Atoi(budget)
+		if err != nil || budgetMs < 0 {
+			return nil, fmt.Errorf("CORRECTION_BUDGET_MS must be a non-negative integer, got: %s", budget)
+		}
+		cfg.CorrectionBudgetMs = budgetMs
+		cfg.logInfo("configuration", "request", "", "Configured CORRECTION_BUDGET_MS", map[string]interface{}{
+			"budget_ms": budgetMs,
+		})
+	}
+
+	// Parse CORRECTION_BUDGET_POLICY (optional, defaults to forward)
+	if policy, exists := envVars["CORRECTION_BUDGET_POLICY"]; exists && policy != "" {
+		if policy != CorrectionBudgetForward && policy != CorrectionBudgetBlock {
+			return nil, fmt.Errorf("CORRECTION_BUDGET_POLICY must be %q or %q, got: %s", CorrectionBudgetForward, CorrectionBudgetBlock, policy)
+		}
+		cfg.CorrectionBudgetPolicy = policy
+		cfg.logInfo("configuration", "request", "", "Configured CORRECTION_BUDGET_POLICY", map[string]interface{}{
+			"policy": policy,
+		})
+	}
+
+	// Parse CORRECTION_MAX_RETRIES (optional, defaults to 3)
+	if retries, exists := envVars["CORRECTION_MAX_RETRIES"]; exists && retries != "" {
+		maxRetries, err := strconv.Atoi(retries)
+		if err != nil || maxRetries < 1 {
+			return nil, fmt.Errorf("CORRECTION_MAX_RETRIES must be a positive integer, got: %s", retries)
+		}
+		cfg.CorrectionMaxRetries = maxRetries
+		cfg.logInfo("configuration", "request", "", "Configured CORRECTION_MAX_RETRIES", map[string]interface{}{
+			"max_retries": maxRetries,
+		})
+	}
+
+	// Parse CORRECTION_EXHAUSTED_POLICY (optional, defaults to original)
+	if policy, exists := envVars["CORRECTION_EXHAUSTED_POLICY"]; exists && policy != "" {
+		switch policy {
+		case CorrectionExhaustedOriginal, CorrectionExhaustedDrop, CorrectionExhaustedBlock:
+			cfg.CorrectionExhaustedPolicy = policy
+		default:
+			return nil, fmt.Errorf("CORRECTION_EXHAUSTED_POLICY must be %q, %q or %q, got: %s",
+				CorrectionExhaustedOriginal, CorrectionExhaustedDrop, CorrectionExhaustedBlock, policy)
+		}
+		cfg.logInfo("configuration", "request", "", "Configured CORRECTION_EXHAUSTED_POLICY", map[string]interface{}{
+			"policy": policy,
+		})
+	}
+
+	// Parse CORRECTION_LEARN_THRESHOLD (optional, defaults to 3)
+	if threshold, exists := envVars["CORRECTION_LEARN_THRESHOLD"]; exists && threshold != "" {
+		learnThreshold, err := strconv.Atoi(threshold)
+		if err != nil || learnThreshold < 1 {
+			return nil, fmt.Errorf("CORRECTION_LEARN_THRESHOLD must be a positive integer, got: %s", threshold)
+		}
+		cfg.CorrectionLearnThreshold = learnThreshold
+		cfg.logInfo("configuration", "request", "", "Configured CORRECTION_LEARN_THRESHOLD", map[string]interface{}{
+			"threshold": learnThreshold,
+		})
+	}
+
+	// Parse CORRECTION_AUTO_PROMOTE (optional, defaults to false)
+	if autoPromote, exists := envVars["CORRECTION_AUTO_PROMOTE"]; exists && autoPromote != "" {
+		cfg.CorrectionAutoPromote = autoPromote == "true" || autoPromote == "1"
+		cfg.logInfo("configuration", "request", "", "Configured CORRECTION_AUTO_PROMOTE", map[string]interface{}{
+			"enabled": cfg.CorrectionAutoPromote,
+		})
+	}
+
+	// Parse CORRECTION_CONTEXT_MESSAGES (optional, defaults to 0 = disabled)
+	if messages, exists := envVars["CORRECTION_CONTEXT_MESSAGES"]; exists && messages != "" {
+		contextMessages, err := strconv.Atoi(messages)
+		if err != nil || contextMessages < 0 {
+			return nil, fmt.Errorf("CORRECTION_CONTEXT_MESSAGES must be a non-negative integer, got: %s", messages)
+		}
+		cfg.CorrectionContextMessages = contextMessages
+		cfg.logInfo("configuration", "request", "", "Configured CORRECTION_CONTEXT_MESSAGES", map[string]interface{}{
+			"messages": contextMessages,
+		})
+	}
+
+	// Parse CORRECTION_CONTEXT_TOKENS (optional, defaults to 1000)
+	if tokens, exists := envVars["CORRECTION_CONTEXT_TOKENS"]; exists && tokens != "" {
+		contextTokens, err := strconv.Atoi(tokens)
+		if err != nil || contextTokens < 1 {
+			return nil, fmt.Errorf("CORRECTION_CONTEXT_TOKENS must be a positive integer, got: %s", tokens)
+		}
+		cfg.CorrectionContextTokens = contextTokens
+		cfg.logInfo("configuration", "request", "", "Configured CORRECTION_CONTEXT_TOKENS", map[string]interface{}{
+			"tokens": contextTokens,
+		})
+	}
+
+	// Parse VALIDATION_FEEDBACK_ROUNDS (optional, defaults to 0 = disabled)
+	if rounds, exists := envVars["VALIDATION_FEEDBACK_ROUNDS"]; exists && rounds != "" {
+		feedbackRounds, err := strconv.Atoi(rounds)
+		if err != nil || feedbackRounds < 0 {
+			return nil, fmt.Errorf("VALIDATION_FEEDBACK_ROUNDS must be a non-negative integer, got: %s", rounds)
+		}
+		cfg.ValidationFeedbackRounds = feedbackRounds
+		cfg.logInfo("configuration", "request", "", "Configured VALIDATION_FEEDBACK_ROUNDS", map[string]interface{}{
+			"rounds": feedbackRounds,
+		})
+	}
+
+	// Parse APPROVAL_TIMEOUT_SECONDS (optional, defaults to 300)
+	if timeout, exists := envVars["APPROVAL_TIMEOUT_SECONDS"]; exists && timeout != "" {
+		approvalTimeout, err := strconv.Atoi(timeout)
+		if err != nil || approvalTimeout < 1 {
+			return nil, fmt.Errorf("APPROVAL_TIMEOUT_SECONDS must be a positive integer, got: %s", timeout)
+		}
+		cfg.ApprovalTimeoutSeconds = approvalTimeout
+		cfg.logInfo("configuration", "request", "", "Configured APPROVAL_TIMEOUT_SECONDS", map[string]interface{}{
+			"timeout_seconds": approvalTimeout,
+		})
+	}
+
+	// Parse VALIDATION_SEVERITY (optional, rule:severity pairs)
+	if validationSeverity, exists := envVars["VALIDATION_SEVERITY"]; exists && validationSeverity != "" {
+		severities, err := parseValidationSeverity(validationSeverity)
+		if err != nil {
+			return nil, fmt.Errorf("VALIDATION_SEVERITY: %v", err)
+		}
+		cfg.ValidationSeverity = severities
+		cfg.logInfo("configuration", "request", "", "Configured VALIDATION_SEVERITY", map[string]interface{}{
+			"severities": severities,
+		})
+	}
+
+	// Parse ADDITIONAL_PROPERTIES_POLICY and ADDITIONAL_PROPERTIES_TOOLS (optional, tool:policy pairs)
+	additionalDefault, additionalTools := envVars["ADDITIONAL_PROPERTIES_POLICY"], envVars["ADDITIONAL_PROPERTIES_TOOLS"]
+	if additionalDefault != "" || additionalTools != "" {
+		policy, err := parseAdditionalProperties(cfg.AdditionalProperties, additionalDefault, additionalTools)
+		if err != nil {
+			return nil, fmt.Errorf("ADDITIONAL_PROPERTIES_POLICY/ADDITIONAL_PROPERTIES_TOOLS: %v", err)
+		}
+		cfg.AdditionalProperties = policy
+		cfg.logInfo("configuration", "request", "", "Configured additional properties policy", map[string]interface{}{
+			"default": policy.Default,
+			"tools":   policy.Tools,
+		})
+	}
+
+	// Parse PROMPTS_DIR (optional, defaults to prompts)
+	if promptsDir, exists := envVars["PROMPTS_DIR"]; exists && promptsDir != "" {
+		cfg.PromptsDir = promptsDir
+		cfg.logInfo("configuration", "request", "", "Configured PROMPTS_DIR", map[string]interface{}{
+			"path": promptsDir,
+		})
+	}
+
+	// Parse TOOL_SCHEMA_DRIFT_DETECTION (optional, defaults to true)
+	if driftDetection, exists := envVars["TOOL_SCHEMA_DRIFT_DETECTION"]; exists {
+		cfg.ToolSchemaDriftEnabled = !(driftDetection == "false" || driftDetection == "0")
+		cfg.logInfo("configuration", "request", "", "Configured TOOL_SCHEMA_DRIFT_DETECTION", map[string]interface{}{
+			"enabled": cfg.ToolSchemaDriftEnabled,
+		})
+	}
+
+	// Parse TOOL_SCHEMA_REGISTRY_PATH (optional, defaults to tool_schemas.json)
+	if registryPath, exists := envVars["TOOL_SCHEMA_REGISTRY_PATH"]; exists && registryPath != "" {
+		cfg.ToolSchemaRegistryPath = registryPath
+		cfg.logInfo("configuration", "request", "", "Configured TOOL_SCHEMA_REGISTRY_PATH", map[string]interface{}{
+			"path": registryPath,
+		})
+	}
+
+	// Parse STREAM_INCLUDE_USAGE (optional, defaults to true)
+	if includeUsage, exists := envVars["STREAM_INCLUDE_USAGE"]; exists {
+		cfg.StreamIncludeUsage = !(includeUsage == "false" || includeUsage == "0")
+		cfg.logInfo("configuration", "request", "", "Configured STREAM_INCLUDE_USAGE", map[string]interface{}{
+			"enabled": cfg.StreamIncludeUsage,
+		})
+	}
+
+	// Parse ANTHROPIC_BETA_MINIFY_TOOLS (optional, defaults to false)
+	if betaMinify, exists := envVars["ANTHROPIC_BETA_MINIFY_TOOLS"]; exists {
+		cfg.BetaMinifyTools = betaMinify == "true" || betaMinify == "1"
+		cfg.logInfo("configuration", "request", "", "Configured ANTHROPIC_BETA_MINIFY_TOOLS", map[string]interface{}{
+			"enabled": cfg.BetaMinifyTools,
+		})
+	}
+
+	// Parse SSE_COALESCE_INTERVAL_MS (optional, defaults to 0 = flush every event)
+	if coalesceInterval, exists := envVars["SSE_COALESCE_INTERVAL_MS"]; exists && coalesceInterval != "" {
+		var intervalMs int
+		if n, err := fmt.Sscanf(coalesceInterval, "%d", &intervalMs); n != 1 || err != nil || intervalMs < 0 {
+			return nil, fmt.Errorf("SSE_COALESCE_INTERVAL_MS must be a non-negative number, got: %s", coalesceInterval)
+		}
+		cfg.SSECoalesceIntervalMs = intervalMs
+		cfg.logInfo("configuration", "request", "", "Configured SSE_COALESCE_INTERVAL_MS", map[string]interface{}{
+			"interval_ms": intervalMs,
+		})
+	}
+
+	// Parse SSE_COALESCE_BYTES (optional, defaults to 0 = flush every event)
+	if coalesceBytes, exists := envVars["SSE_COALESCE_BYTES"]; exists && coalesceBytes != "" {
+		var maxBytes int
+		if n, err := fmt.Sscanf(coalesceBytes, "%d", &maxBytes); n != 1 || err != nil || maxBytes < 0 {
+			return nil, fmt.Errorf("SSE_COALESCE_BYTES must be a non-negative number, got: %s", coalesceBytes)
+		}
+		cfg.SSECoalesceBytes = maxBytes
+		cfg.logInfo("configuration", "request", "", "Configured SSE_COALESCE_BYTES", map[string]interface{}{
+			"bytes": maxBytes,
+		})
+	}
+
+	// Parse PROXY_DEBUG_HEADER_ENABLED (optional, defaults to false)
+	if debugHeader, exists := envVars["PROXY_DEBUG_HEADER_ENABLED"]; exists {
+		cfg.ProxyDebugHeaderEnabled = debugHeader == "true" || debugHeader == "1"
+		cfg.logInfo("configuration", "request", "", "Configured PROXY_DEBUG_HEADER_ENABLED", map[string]interface{}{
+			"enabled": cfg.ProxyDebugHeaderEnabled,
+		})
+	}
+
+	// Parse NO_CORRECTION_HEADER_ENABLED (optional, defaults to false)
+	if noCorrectionHeader, exists := envVars["NO_CORRECTION_HEADER_ENABLED"]; exists {
+		cfg.NoCorrectionHeaderEnabled = noCorrectionHeader == "true" || noCorrectionHeader == "1"
+		cfg.logInfo("configuration", "request", "", "Configured NO_CORRECTION_HEADER_ENABLED", map[string]interface{}{
+			"enabled": cfg.NoCorrectionHeaderEnabled,
+		})
+	}
+
+	// Parse PASSTHROUGH_MODELS (optional, defaults to none)
+	if passthroughModels, exists := envVars["PASSTHROUGH_MODELS"]; exists && passthroughModels != "" {
+		patterns, err := parseGlobList(passthroughModels, true)
+		if err != nil {
+			return nil, fmt.Errorf("PASSTHROUGH_MODELS: %v", err)
+		}
+		cfg.PassthroughModels = patterns
+		cfg.logInfo("configuration", "request", "", "Configured PASSTHROUGH_MODELS", map[string]interface{}{
+			"patterns": patterns,
+		})
+	}
+
+	// Parse SSE_VERIFY (optional, defaults to false)
+	if sseVerify, exists := envVars["SSE_VERIFY"]; exists {
+		cfg.SSEVerifyEnabled = sseVerify == "true" || sseVerify == "1"
+		cfg.logInfo("configuration", "request", "", "Configured SSE_VERIFY", map[string]interface{}{
+			"enabled": cfg.SSEVerifyEnabled,
+		})
+	}
+
 	// Parse PRINT_SYSTEM_MESSAGE (optional, defaults to false)
 	if printSystemMessage, exists := envVars["PRINT_SYSTEM_MESSAGE"]; exists {
 		if printSystemMessage == "true" || printSystemMessage == "1" {
@@ -479,6 +1170,14 @@ func LoadConfigWithEnv() (*Config, error) {
 		}
 	}
 
+	// Parse DISABLE_SMALL_MODEL_CORRECTION (optional, defaults to false)
+	if disableSmallCorrection, exists := envVars["DISABLE_SMALL_MODEL_CORRECTION"]; exists {
+		cfg.DisableSmallModelCorrection = disableSmallCorrection == "true" || disableSmallCorrection == "1"
+		cfg.logInfo("configuration", "request", "", "Configured DISABLE_SMALL_MODEL_CORRECTION", map[string]interface{}{
+			"enabled": cfg.DisableSmallModelCorrection,
+		})
+	}
+
 	// Parse DISABLE_TOOL_CORRECTION_LOGGING (optional, defaults to false)
 	if disableToolCorrectionLogging, exists := envVars["DISABLE_TOOL_CORRECTION_LOGGING"]; exists {
 		if disableToolCorrectionLogging == "true" || disableToolCorrectionLogging == "1" {
@@ -511,6 +1210,26 @@ func LoadConfigWithEnv() (*Config, error) {
 		}
 	}
 
+	// Parse TOOL_RESULT_REPAIR (optional, defaults to false)
+	if repair, exists := envVars["TOOL_RESULT_REPAIR"]; exists && repair != "" {
+		cfg.ToolResultRepair = repair == "true" || repair == "1"
+		cfg.logInfo("configuration", "request", "", "Configured TOOL_RESULT_REPAIR", map[string]interface{}{
+			"enabled": cfg.ToolResultRepair,
+		})
+	}
+
+	// Parse TOOL_RESULT_MAX_TOKENS (optional, defaults to 0 = no limit)
+	if maxTokens, exists := envVars["TOOL_RESULT_MAX_TOKENS"]; exists && maxTokens != "" {
+		limit, err := strconv.Atoi(maxTokens)
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("TOOL_RESULT_MAX_TOKENS must be a non-negative integer, got: %s", maxTokens)
+		}
+		cfg.ToolResultMaxTokens = limit
+		cfg.logInfo("configuration", "request", "", "Configured TOOL_RESULT_MAX_TOKENS", map[string]interface{}{
+			"max_tokens": limit,
+		})
+	}
+
 	// Parse HANDLE_EMPTY_USER_MESSAGES (optional, defaults to false)
 	if handleEmptyUser, exists := envVars["HANDLE_EMPTY_USER_MESSAGES"]; exists {
 		if handleEmptyUser == "true" || handleEmptyUser == "1" {
@@ -526,6 +1245,14 @@ func LoadConfigWithEnv() (*Config, error) {
 		}
 	}
 
+	// Parse FORWARD_THINKING_BLOCKS (optional, defaults to false)
+	if forwardThinking, exists := envVars["FORWARD_THINKING_BLOCKS"]; exists {
+		cfg.ForwardThinkingBlocks = forwardThinking == "true" || forwardThinking == "1"
+		cfg.logInfo("configuration", "request", "", "Configured FORWARD_THINKING_BLOCKS", map[string]interface{}{
+			"enabled": cfg.ForwardThinkingBlocks,
+		})
+	}
+
 	// Parse CONVERSATION_LOGGING_ENABLED (optional, defaults to false)
 	if conversationLogging, exists := envVars["CONVERSATION_LOGGING_ENABLED"]; exists {
 		if conversationLogging == "true" || conversationLogging == "1" {
@@ -541,175 +1268,940 @@ func LoadConfigWithEnv() (*Config, error) {
 		}
 	}
 
-	// Parse CONVERSATION_LOG_LEVEL (optional, defaults to INFO)
-	if logLevel, exists := envVars["CONVERSATION_LOG_LEVEL"]; exists {
-		validLevels := map[string]bool{"DEBUG": true, "INFO": true, "WARN": true, "ERROR": true}
-		if validLevels[strings.ToUpper(logLevel)] {
-			cfg.ConversationLogLevel = strings.ToUpper(logLevel)
-			cfg.logInfo("configuration", "request", "", "Configured CONVERSATION_LOG_LEVEL", map[string]interface{}{
-				"log_level": cfg.ConversationLogLevel,
-			})
-		} else {
-			cfg.logWarn("configuration", "warning", "", "Invalid CONVERSATION_LOG_LEVEL, using default", map[string]interface{}{
-				"invalid_level": logLevel,
-				"default_level": "INFO",
-			})
-			cfg.ConversationLogLevel = "INFO"
-		}
+	// Parse CONVERSATION_LOG_LEVEL (optional, defaults to INFO)
+	if logLevel, exists := envVars["CONVERSATION_LOG_LEVEL"]; exists {
+		validLevels := map[string]bool{"DEBUG": true, "INFO": true, "WARN": true, "ERROR": true}
+		if validLevels[strings.ToUpper(logLevel)] {
+			cfg.ConversationLogLevel = strings.ToUpper(logLevel)
+			cfg.logInfo("configuration", "request", "", "Configured CONVERSATION_LOG_LEVEL", map[string]interface{}{
+				"log_level": cfg.ConversationLogLevel,
+			})
+		} else {
+			cfg.logWarn("configuration", "warning", "", "Invalid CONVERSATION_LOG_LEVEL, using default", map[string]interface{}{
+				"invalid_level": logLevel,
+				"default_level": "INFO",
+			})
+			cfg.ConversationLogLevel = "INFO"
+		}
+	}
+
+	// Parse LOG_LEVEL (optional, defaults to INFO)
+	if logLevel, exists := envVars["LOG_LEVEL"]; exists {
+		validLevels := map[string]bool{"DEBUG": true, "INFO": true, "WARN": true, "ERROR": true}
+		if validLevels[strings.ToUpper(logLevel)] {
+			cfg.LogLevel = strings.ToUpper(logLevel)
+			cfg.logInfo("configuration", "request", "", "Configured LOG_LEVEL", map[string]interface{}{
+				"log_level": cfg.LogLevel,
+			})
+		} else {
+			cfg.logWarn("configuration", "warning", "", "Invalid LOG_LEVEL, using default", map[string]interface{}{
+				"invalid_level": logLevel,
+				"default_level": "INFO",
+			})
+			cfg.LogLevel = "INFO"
+		}
+	}
+
+	// Parse LOG_LEVELS (optional, comma-separated component=LEVEL overrides)
+	if componentLevels, exists := envVars["LOG_LEVELS"]; exists && componentLevels != "" {
+		validLevels := map[string]bool{"DEBUG": true, "INFO": true, "WARN": true, "ERROR": true}
+		for _, entry := range strings.Split(componentLevels, ",") {
+			parts := strings.SplitN(strings.TrimSpace(entry), "=", 2)
+			if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || !validLevels[strings.ToUpper(strings.TrimSpace(parts[1]))] {
+				return nil, fmt.Errorf("LOG_LEVELS must be comma-separated component=LEVEL pairs, got: %s", componentLevels)
+			}
+		}
+		cfg.ComponentLogLevels = componentLevels
+		cfg.logInfo("configuration", "request", "", "Configured LOG_LEVELS", map[string]interface{}{
+			"component_levels": componentLevels,
+		})
+	}
+
+	// Parse CONVERSATION_LOG_{INCLUDE,EXCLUDE}_{MODELS,PATHS} (optional glob lists)
+	conversationFilters := []struct {
+		name     string
+		models   bool
+		patterns *[]string
+	}{
+		{"CONVERSATION_LOG_INCLUDE_MODELS", true, &cfg.ConversationLogFilter.IncludeModels},
+		{"CONVERSATION_LOG_EXCLUDE_MODELS", true, &cfg.ConversationLogFilter.ExcludeModels},
+		{"CONVERSATION_LOG_INCLUDE_PATHS", false, &cfg.ConversationLogFilter.IncludePaths},
+		{"CONVERSATION_LOG_EXCLUDE_PATHS", false, &cfg.ConversationLogFilter.ExcludePaths},
+	}
+	for _, filter := range conversationFilters {
+		if value, exists := envVars[filter.name]; exists && value != "" {
+			patterns, err := parseGlobList(value, filter.models)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", filter.name, err)
+			}
+			*filter.patterns = patterns
+			cfg.logInfo("configuration", "request", "", "Configured "+filter.name, map[string]interface{}{
+				"patterns": patterns,
+			})
+		}
+	}
+
+	// Parse CONVERSATION_MASK_SENSITIVE (optional, defaults to true)
+	if maskSensitive, exists := envVars["CONVERSATION_MASK_SENSITIVE"]; exists {
+		if maskSensitive == "false" || maskSensitive == "0" {
+			cfg.ConversationMaskSensitive = false
+			cfg.logInfo("configuration", "request", "", "Configured CONVERSATION_MASK_SENSITIVE", map[string]interface{}{
+				"enabled": false,
+			})
+		} else {
+			cfg.ConversationMaskSensitive = true
+			cfg.logInfo("configuration", "request", "", "Configured CONVERSATION_MASK_SENSITIVE", map[string]interface{}{
+				"enabled": true,
+			})
+		}
+	}
+
+	// Parse CONVERSATION_MASK_DETECTORS (optional, defaults to all built-in detectors)
+	if maskDetectors, exists := envVars["CONVERSATION_MASK_DETECTORS"]; exists && maskDetectors != "" {
+		detectors, err := parseRedactionDetectors(maskDetectors)
+		if err != nil {
+			return nil, fmt.Errorf("CONVERSATION_MASK_DETECTORS: %v", err)
+		}
+		cfg.ConversationMaskDetectors = detectors
+		cfg.logInfo("configuration", "request", "", "Configured CONVERSATION_MASK_DETECTORS", map[string]interface{}{
+			"detectors": detectors,
+		})
+	}
+
+	// Parse LOG_FULL_TOOLS (required)
+	if logFullTools, exists := envVars["LOG_FULL_TOOLS"]; exists {
+		if logFullTools == "true" || logFullTools == "1" {
+			cfg.ConversationLogFullTools = true
+			cfg.logInfo("configuration", "request", "", "Configured LOG_FULL_TOOLS", map[string]interface{}{
+				"enabled": true,
+				"description": "full tool definitions",
+			})
+		} else if logFullTools == "false" || logFullTools == "0" {
+			cfg.ConversationLogFullTools = false
+			cfg.logInfo("configuration", "request", "", "Configured LOG_FULL_TOOLS", map[string]interface{}{
+				"enabled": false,
+				"description": "tool names only",
+			})
+		} else {
+			return nil, fmt.Errorf("LOG_FULL_TOOLS must be 'true' or 'false', got: %s", logFullTools)
+		}
+	} else {
+		return nil, fmt.Errorf("LOG_FULL_TOOLS must be set in .env file")
+	}
+
+	// Parse CONVERSATION_TRUNCATION (required)
+	if truncation, exists := envVars["CONVERSATION_TRUNCATION"]; exists {
+		if truncation == "false" || truncation == "0" {
+			cfg.ConversationTruncation = 0
+			cfg.logInfo("configuration", "request", "", "Configured CONVERSATION_TRUNCATION", map[string]interface{}{
+				"enabled": false,
+			})
+		} else {
+			// Try to parse as integer
+			var truncationValue int
+			if n, err := fmt.Sscanf(truncation, "%d", &truncationValue); n != 1 || err != nil {
+				return nil, fmt.Errorf("CONVERSATION_TRUNCATION must be 'false' or a positive number, got: %s", truncation)
+			}
+			if truncationValue < 0 {
+				return nil, fmt.Errorf("CONVERSATION_TRUNCATION must be a positive number, got: %d", truncationValue)
+			}
+			cfg.ConversationTruncation = truncationValue
+			cfg.logInfo("configuration", "request", "", "Configured CONVERSATION_TRUNCATION", map[string]interface{}{
+				"enabled": true,
+				"max_characters": truncationValue,
+			})
+		}
+	} else {
+		return nil, fmt.Errorf("CONVERSATION_TRUNCATION must be set in .env file")
+	}
+
+	// Parse CONVERSATION_TRUNCATION_TOKENS (optional, defaults to 0 = disabled)
+	if truncationTokens, exists := envVars["CONVERSATION_TRUNCATION_TOKENS"]; exists && truncationTokens != "" {
+		maxTokens, err := strconv.Atoi(truncationTokens)
+		if err != nil || maxTokens < 0 {
+			return nil, fmt.Errorf("CONVERSATION_TRUNCATION_TOKENS must be a non-negative integer, got: %s", truncationTokens)
+		}
+		cfg.ConversationTruncationTokens = maxTokens
+		cfg.logInfo("configuration", "request", "", "Configured CONVERSATION_TRUNCATION_TOKENS", map[string]interface{}{
+			"max_tokens": maxTokens,
+		})
+	}
+
+	// Parse DEFAULT_CONNECTION_TIMEOUT (optional, defaults to 30 seconds)
+	if connectionTimeout, exists := envVars["DEFAULT_CONNECTION_TIMEOUT"]; exists {
+		var timeoutValue int
+		if n, err := fmt.Sscanf(connectionTimeout, "%d", &timeoutValue); n != 1 || err != nil {
+			return nil, fmt.Errorf("DEFAULT_CONNECTION_TIMEOUT must be a positive number, got: %s", connectionTimeout)
+		}
+		if timeoutValue < 1 {
+			return nil, fmt.Errorf("DEFAULT_CONNECTION_TIMEOUT must be a positive number, got: %d", timeoutValue)
+		}
+		cfg.DefaultConnectionTimeout = timeoutValue
+		cfg.logInfo("configuration", "request", "", "Configured DEFAULT_CONNECTION_TIMEOUT", map[string]interface{}{
+			"timeout_seconds": timeoutValue,
+		})
+	} else {
+		cfg.DefaultConnectionTimeout = 30 // Default to 30 seconds if not specified
+		cfg.logInfo("configuration", "request", "", "Using default DEFAULT_CONNECTION_TIMEOUT", map[string]interface{}{
+			"timeout_seconds": 30,
+		})
+	}
+
+	// Parse SERVER_READ_TIMEOUT (optional, defaults to 30, 0 disables)
+	if timeoutStr, exists := envVars["SERVER_READ_TIMEOUT"]; exists && timeoutStr != "" {
+		timeoutValue, err := strconv.Atoi(timeoutStr)
+		if err != nil || timeoutValue < 0 {
+			return nil, fmt.Errorf("SERVER_READ_TIMEOUT must be a non-negative number of seconds, got: %s", timeoutStr)
+		}
+		cfg.ServerReadTimeout = timeoutValue
+		cfg.logInfo("configuration", "request", "", "Configured SERVER_READ_TIMEOUT", map[string]interface{}{
+			"timeout_seconds": timeoutValue,
+		})
+	}
+
+	// Parse SERVER_WRITE_TIMEOUT (optional, defaults to 300, 0 disables)
+	if timeoutStr, exists := envVars["SERVER_WRITE_TIMEOUT"]; exists && timeoutStr != "" {
+		timeoutValue, err := strconv.Atoi(timeoutStr)
+		if err != nil || timeoutValue < 0 {
+			return nil, fmt.Errorf("SERVER_WRITE_TIMEOUT must be a non-negative number of seconds, got: %s", timeoutStr)
+		}
+		cfg.ServerWriteTimeout = timeoutValue
+		cfg.logInfo("configuration", "request", "", "Configured SERVER_WRITE_TIMEOUT", map[string]interface{}{
+			"timeout_seconds": timeoutValue,
+		})
+	}
+
+	// Parse SERVER_IDLE_TIMEOUT (optional, defaults to 60, 0 uses the read timeout)
+	if timeoutStr, exists := envVars["SERVER_IDLE_TIMEOUT"]; exists && timeoutStr != "" {
+		timeoutValue, err := strconv.Atoi(timeoutStr)
+		if err != nil || timeoutValue < 0 {
+			return nil, fmt.Errorf("SERVER_IDLE_TIMEOUT must be a non-negative number of seconds, got: %s", timeoutStr)
+		}
+		cfg.ServerIdleTimeout = timeoutValue
+		cfg.logInfo("configuration", "request", "", "Configured SERVER_IDLE_TIMEOUT", map[string]interface{}{
+			"timeout_seconds": timeoutValue,
+		})
+	}
+
+	// Parse ENABLE_TOOL_CHOICE_CORRECTION (optional, defaults to false)
+	if enableToolChoiceCorrection, exists := envVars["ENABLE_TOOL_CHOICE_CORRECTION"]; exists {
+		if enableToolChoiceCorrection == "true" || enableToolChoiceCorrection == "1" {
+			cfg.EnableToolChoiceCorrection = true
+			cfg.logInfo("configuration", "request", "", "Configured ENABLE_TOOL_CHOICE_CORRECTION", map[string]interface{}{
+				"enabled": true,
+				"description": "tool choice correction enabled",
+			})
+		} else {
+			cfg.EnableToolChoiceCorrection = false
+			cfg.logInfo("configuration", "request", "", "Configured ENABLE_TOOL_CHOICE_CORRECTION", map[string]interface{}{
+				"enabled": false,
+				"description": "tool choice correction disabled",
+			})
+		}
+	}
+
+	// Parse HARMONY_PARSING_ENABLED (optional, defaults to true)
+	if harmonyParsingEnabled, exists := envVars["HARMONY_PARSING_ENABLED"]; exists {
+		if harmonyParsingEnabled == "false" || harmonyParsingEnabled == "0" {
+			cfg.HarmonyParsingEnabled = false
+			cfg.logInfo("configuration", "request", "", "Configured HARMONY_PARSING_ENABLED", map[string]interface{}{
+				"enabled": false,
+				"description": "Harmony parsing disabled",
+			})
+		} else {
+			cfg.HarmonyParsingEnabled = true
+			cfg.logInfo("configuration", "request", "", "Configured HARMONY_PARSING_ENABLED", map[string]interface{}{
+				"enabled": true,
+				"description": "Harmony parsing enabled",
+			})
+		}
+	}
+
+	// Parse HARMONY_DEBUG (optional, defaults to false)
+	if harmonyDebug, exists := envVars["HARMONY_DEBUG"]; exists {
+		if harmonyDebug == "true" || harmonyDebug == "1" {
+			cfg.HarmonyDebug = true
+			cfg.logInfo("configuration", "request", "", "Configured HARMONY_DEBUG", map[string]interface{}{
+				"enabled": true,
+				"description": "Harmony debug logging enabled",
+			})
+		} else {
+			cfg.HarmonyDebug = false
+			cfg.logInfo("configuration", "request", "", "Configured HARMONY_DEBUG", map[string]interface{}{
+				"enabled": false,
+				"description": "Harmony debug logging disabled",
+			})
+		}
+	}
+
+	// Parse HARMONY_STRICT_MODE (optional, defaults to false)
+	if harmonyStrictMode, exists := envVars["HARMONY_STRICT_MODE"]; exists {
+		if harmonyStrictMode == "true" || harmonyStrictMode == "1" {
+			cfg.HarmonyStrictMode = true
+			cfg.logInfo("configuration", "request", "", "Configured HARMONY_STRICT_MODE", map[string]interface{}{
+				"enabled": true,
+				"description": "Harmony strict error handling enabled",
+			})
+		} else {
+			cfg.HarmonyStrictMode = false
+			cfg.logInfo("configuration", "request", "", "Configured HARMONY_STRICT_MODE", map[string]interface{}{
+				"enabled": false,
+				"description": "Harmony lenient error handling (default)",
+			})
+		}
+	}
+
+	// Parse HARMONY_MODELS (optional, defaults to every model)
+	if harmonyModels, exists := envVars["HARMONY_MODELS"]; exists && harmonyModels != "" {
+		patterns, err := parseGlobList(harmonyModels, true)
+		if err != nil {
+			return nil, fmt.Errorf("HARMONY_MODELS: %v", err)
+		}
+		cfg.HarmonyModels = patterns
+		cfg.logInfo("configuration", "request", "", "Configured HARMONY_MODELS", map[string]interface{}{
+			"patterns": patterns,
+		})
+	}
+
+	// Parse HARMONY_SYSTEM_PREAMBLE (optional, defaults to false)
+	if preamble, exists := envVars["HARMONY_SYSTEM_PREAMBLE"]; exists && preamble != "" {
+		cfg.HarmonySystemPreamble = preamble == "true" || preamble == "1"
+		cfg.logInfo("configuration", "request", "", "Configured HARMONY_SYSTEM_PREAMBLE", map[string]interface{}{
+			"enabled": cfg.HarmonySystemPreamble,
+		})
+	}
+
+	// Parse HARMONY_REASONING_LEVEL (optional, defaults to medium)
+	if level, exists := envVars["HARMONY_REASONING_LEVEL"]; exists && level != "" {
+		level = strings.ToLower(strings.TrimSpace(level))
+		if level != HarmonyReasoningLow && level != HarmonyReasoningMedium && level != HarmonyReasoningHigh {
+			return nil, fmt.Errorf("HARMONY_REASONING_LEVEL must be %q, %q or %q, got: %s",
+				HarmonyReasoningLow, HarmonyReasoningMedium, HarmonyReasoningHigh, level)
+		}
+		cfg.HarmonyReasoningLevel = level
+		cfg.logInfo("configuration", "request", "", "Configured HARMONY_REASONING_LEVEL", map[string]interface{}{
+			"level": level,
+		})
+	}
+
+	// Parse HARMONY_KNOWLEDGE_CUTOFF (optional, defaults to 2024-06)
+	if cutoff, exists := envVars["HARMONY_KNOWLEDGE_CUTOFF"]; exists && cutoff != "" {
+		cfg.HarmonyKnowledgeCutoff = strings.TrimSpace(cutoff)
+		cfg.logInfo("configuration", "request", "", "Configured HARMONY_KNOWLEDGE_CUTOFF", map[string]interface{}{
+			"cutoff": cfg.HarmonyKnowledgeCutoff,
+		})
+	}
+
+	// Parse HARMONY_DEVELOPER_ROLE (optional, defaults to false)
+	if developerRole, exists := envVars["HARMONY_DEVELOPER_ROLE"]; exists && developerRole != "" {
+		cfg.HarmonyDeveloperRole = developerRole == "true" || developerRole == "1"
+		cfg.logInfo("configuration", "request", "", "Configured HARMONY_DEVELOPER_ROLE", map[string]interface{}{
+			"enabled": cfg.HarmonyDeveloperRole,
+		})
+	}
+
+	// Parse HARMONY_START_TOKEN, HARMONY_END_TOKENS, HARMONY_CHANNEL_TOKEN and
+	// HARMONY_MESSAGE_TOKEN (optional, default to the gpt-oss tokens)
+	customTokens := false
+	if startToken, exists := envVars["HARMONY_START_TOKEN"]; exists && startToken != "" {
+		cfg.HarmonyTokens.Start = strings.TrimSpace(startToken)
+		customTokens = true
+	}
+	if endTokens, exists := envVars["HARMONY_END_TOKENS"]; exists && endTokens != "" {
+		cfg.HarmonyTokens.End = splitList(endTokens)
+		customTokens = true
+	}
+	if channelToken, exists := envVars["HARMONY_CHANNEL_TOKEN"]; exists && channelToken != "" {
+		cfg.HarmonyTokens.Channel = strings.TrimSpace(channelToken)
+		customTokens = true
+	}
+	if messageToken, exists := envVars["HARMONY_MESSAGE_TOKEN"]; exists && messageToken != "" {
+		cfg.HarmonyTokens.Message = strings.TrimSpace(messageToken)
+		customTokens = true
+	}
+	if customTokens {
+		if _, err := parser.NewTokenRecognizerWithTokens(cfg.HarmonyTokens); err != nil {
+			return nil, fmt.Errorf("HARMONY_*_TOKEN: %v", err)
+		}
+		cfg.logInfo("configuration", "request", "", "Configured Harmony tokens", map[string]interface{}{
+			"start":   cfg.HarmonyTokens.Start,
+			"end":     cfg.HarmonyTokens.End,
+			"channel": cfg.HarmonyTokens.Channel,
+			"message": cfg.HarmonyTokens.Message,
+		})
+	}
+
+	// Parse STATS_PERSISTENCE_ENABLED (optional, defaults to true)
+	if statsPersistence, exists := envVars["STATS_PERSISTENCE_ENABLED"]; exists {
+		if statsPersistence == "false" || statsPersistence == "0" {
+			cfg.StatsPersistenceEnabled = false
+			cfg.logInfo("configuration", "request", "", "Configured STATS_PERSISTENCE_ENABLED", map[string]interface{}{
+				"enabled": false,
+				"description": "stateless mode, stats reset on restart",
+			})
+		} else {
+			cfg.StatsPersistenceEnabled = true
+			cfg.logInfo("configuration", "request", "", "Configured STATS_PERSISTENCE_ENABLED", map[string]interface{}{
+				"enabled": true,
+				"description": "cumulative stats persisted across restarts",
+			})
+		}
+	}
+
+	// Parse STATS_DB_PATH (optional, defaults to stats.db)
+	if statsDBPath, exists := envVars["STATS_DB_PATH"]; exists && statsDBPath != "" {
+		cfg.StatsDBPath = statsDBPath
+		cfg.logInfo("configuration", "request", "", "Configured STATS_DB_PATH", map[string]interface{}{
+			"path": statsDBPath,
+		})
+	}
+
+	// Parse BATCHES_ENABLED (optional, defaults to true)
+	if batchesEnabled, exists := envVars["BATCHES_ENABLED"]; exists {
+		cfg.BatchesEnabled = !(batchesEnabled == "false" || batchesEnabled == "0")
+		cfg.logInfo("configuration", "request", "", "Configured BATCHES_ENABLED", map[string]interface{}{
+			"enabled": cfg.BatchesEnabled,
+		})
+	}
+
+	// Parse BATCH_DB_PATH (optional, defaults to batches.db)
+	if batchDBPath, exists := envVars["BATCH_DB_PATH"]; exists && batchDBPath != "" {
+		cfg.BatchDBPath = batchDBPath
+		cfg.logInfo("configuration", "request", "", "Configured BATCH_DB_PATH", map[string]interface{}{
+			"path": batchDBPath,
+		})
+	}
+
+	// Parse CONVERSATION_SEARCH_ENABLED (optional, defaults to false)
+	if searchEnabled, exists := envVars["CONVERSATION_SEARCH_ENABLED"]; exists {
+		cfg.ConversationSearchEnabled = searchEnabled == "true" || searchEnabled == "1"
+		cfg.logInfo("configuration", "request", "", "Configured CONVERSATION_SEARCH_ENABLED", map[string]interface{}{
+			"enabled": cfg.ConversationSearchEnabled,
+		})
+	}
+
+	// Parse CONVERSATION_DB_PATH (optional, defaults to conversations.db)
+	if conversationDBPath, exists := envVars["CONVERSATION_DB_PATH"]; exists && conversationDBPath != "" {
+		cfg.ConversationDBPath = conversationDBPath
+		cfg.logInfo("configuration", "request", "", "Configured CONVERSATION_DB_PATH", map[string]interface{}{
+			"path": conversationDBPath,
+		})
+	}
+
+	// Parse REQUEST_JOURNAL_ENABLED (optional, defaults to false)
+	if journalEnabled, exists := envVars["REQUEST_JOURNAL_ENABLED"]; exists {
+		cfg.RequestJournalEnabled = journalEnabled == "true" || journalEnabled == "1"
+		cfg.logInfo("configuration", "request", "", "Configured REQUEST_JOURNAL_ENABLED", map[string]interface{}{
+			"enabled": cfg.RequestJournalEnabled,
+		})
+	}
+
+	// Parse REQUEST_JOURNAL_PATH (optional, defaults to request_journal.jsonl)
+	if journalPath, exists := envVars["REQUEST_JOURNAL_PATH"]; exists && journalPath != "" {
+		cfg.RequestJournalPath = journalPath
+		cfg.logInfo("configuration", "request", "", "Configured REQUEST_JOURNAL_PATH", map[string]interface{}{
+			"path": journalPath,
+		})
+	}
+
+	// Parse ENDPOINT_RESOLVE_INTERVAL_SECONDS (optional, defaults to 30)
+	if resolveInterval, exists := envVars["ENDPOINT_RESOLVE_INTERVAL_SECONDS"]; exists && resolveInterval != "" {
+		var seconds int
+		if n, err := fmt.Sscanf(resolveInterval, "%d", &seconds); n != 1 || err != nil || seconds < 1 {
+			return nil, fmt.Errorf("ENDPOINT_RESOLVE_INTERVAL_SECONDS must be a positive number, got: %s", resolveInterval)
+		}
+		cfg.EndpointResolveIntervalSeconds = seconds
+		cfg.logInfo("configuration", "request", "", "Configured ENDPOINT_RESOLVE_INTERVAL_SECONDS", map[string]interface{}{
+			"seconds": seconds,
+		})
+	}
+
+	// Parse KUBERNETES_API_URL (optional, defaults to the in-cluster API server)
+	if kubernetesAPIURL, exists := envVars["KUBERNETES_API_URL"]; exists && kubernetesAPIURL != "" {
+		cfg.KubernetesAPIURL = kubernetesAPIURL
+		cfg.logInfo("configuration", "request", "", "Configured KUBERNETES_API_URL", map[string]interface{}{
+			"url": kubernetesAPIURL,
+		})
+	}
+
+	// Parse BATCH_CONCURRENCY (optional, defaults to 4)
+	if batchConcurrency, exists := envVars["BATCH_CONCURRENCY"]; exists && batchConcurrency != "" {
+		var concurrency int
+		if n, err := fmt.Sscanf(batchConcurrency, "%d", &concurrency); n != 1 || err != nil || concurrency < 1 {
+			return nil, fmt.Errorf("BATCH_CONCURRENCY must be a positive number, got: %s", batchConcurrency)
+		}
+		cfg.BatchConcurrency = concurrency
+		cfg.logInfo("configuration", "request", "", "Configured BATCH_CONCURRENCY", map[string]interface{}{
+			"concurrency": concurrency,
+		})
+	}
+
+	// Parse retention limits (optional, defaults to 0 = keep forever)
+	retentionLimits := []struct {
+		name  string
+		limit *int
+	}{
+		{"CONVERSATION_RETENTION_DAYS", &cfg.ConversationRetentionDays},
+		{"CONVERSATION_MAX_MB", &cfg.ConversationMaxMB},
+		{"BATCH_RETENTION_DAYS", &cfg.BatchRetentionDays},
+		{"JOURNAL_RETENTION_DAYS", &cfg.JournalRetentionDays},
+		{"STATS_RETENTION_DAYS", &cfg.StatsRetentionDays},
+	}
+	for _, retention := range retentionLimits {
+		if value, exists := envVars[retention.name]; exists && value != "" {
+			limit, err := strconv.Atoi(value)
+			if err != nil || limit < 0 {
+				return nil, fmt.Errorf("%s must be a non-negative integer, got: %s", retention.name, value)
+			}
+			*retention.limit = limit
+			cfg.logInfo("configuration", "request", "", "Configured "+retention.name, map[string]interface{}{
+				"limit": limit,
+			})
+		}
+	}
+
+	// Parse RETENTION_INTERVAL_MINUTES (optional, defaults to 60)
+	if interval, exists := envVars["RETENTION_INTERVAL_MINUTES"]; exists && interval != "" {
+		minutes, err := strconv.Atoi(interval)
+		if err != nil || minutes < 1 {
+			return nil, fmt.Errorf("RETENTION_INTERVAL_MINUTES must be a positive integer, got: %s", interval)
+		}
+		cfg.RetentionIntervalMinutes = minutes
+		cfg.logInfo("configuration", "request", "", "Configured RETENTION_INTERVAL_MINUTES", map[string]interface{}{
+			"minutes": minutes,
+		})
+	}
+
+	// Parse MODEL_PRICING (optional, model=input/output USD per million tokens)
+	if modelPricing, exists := envVars["MODEL_PRICING"]; exists && modelPricing != "" {
+		pricing, err := parseModelPricing(modelPricing)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ModelPricing = pricing
+		cfg.logInfo("configuration", "request", "", "Configured MODEL_PRICING", map[string]interface{}{
+			"models": len(pricing),
+		})
+	}
+
+	// Parse SESSION_BUDGET_TOKENS (optional, 0 = unlimited)
+	if budgetStr, exists := envVars["SESSION_BUDGET_TOKENS"]; exists && budgetStr != "" {
+		budget, err := strconv.Atoi(budgetStr)
+		if err != nil || budget < 0 {
+			return nil, fmt.Errorf("SESSION_BUDGET_TOKENS must be a non-negative integer, got: %s", budgetStr)
+		}
+		cfg.SessionBudgetTokens = budget
+		cfg.logInfo("configuration", "request", "", "Configured SESSION_BUDGET_TOKENS", map[string]interface{}{
+			"tokens": budget,
+		})
+	}
+
+	// Parse SESSION_BUDGET_USD (optional, 0 = unlimited)
+	if budgetStr, exists := envVars["SESSION_BUDGET_USD"]; exists && budgetStr != "" {
+		budget, err := strconv.ParseFloat(budgetStr, 64)
+		if err != nil || budget < 0 {
+			return nil, fmt.Errorf("SESSION_BUDGET_USD must be a non-negative number, got: %s", budgetStr)
+		}
+		cfg.SessionBudgetUSD = budget
+		cfg.logInfo("configuration", "request", "", "Configured SESSION_BUDGET_USD", map[string]interface{}{
+			"usd": budget,
+		})
+	}
+
+	// Parse CLIENT_DAILY_LIMIT_USD and CLIENT_MONTHLY_LIMIT_USD (optional, 0 = unlimited)
+	for name, limit := range map[string]*float64{
+		"CLIENT_DAILY_LIMIT_USD":   &cfg.SpendLimits.Default.DailyUSD,
+		"CLIENT_MONTHLY_LIMIT_USD": &cfg.SpendLimits.Default.MonthlyUSD,
+	} {
+		limitStr, exists := envVars[name]
+		if !exists || limitStr == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(limitStr, 64)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("%s must be a non-negative number, got: %s", name, limitStr)
+		}
+		*limit = value
+		cfg.logInfo("configuration", "request", "", "Configured "+name, map[string]interface{}{
+			"usd": value,
+		})
+	}
+
+	// Parse CLIENT_SPEND_LIMITS (optional, keyid=daily/monthly overrides)
+	if spendLimits, exists := envVars["CLIENT_SPEND_LIMITS"]; exists && spendLimits != "" {
+		keys, err := parseSpendLimits(spendLimits)
+		if err != nil {
+			return nil, err
+		}
+		cfg.SpendLimits.Keys = keys
+		cfg.logInfo("configuration", "request", "", "Configured CLIENT_SPEND_LIMITS", map[string]interface{}{
+			"keys": len(keys),
+		})
+	}
+
+	// Parse STREAM_RATE_LIMIT_TOKENS_PER_SEC and STREAM_RATE_LIMIT_BYTES_PER_SEC (optional, 0 = unlimited)
+	for name, limit := range map[string]*float64{
+		"STREAM_RATE_LIMIT_TOKENS_PER_SEC": &cfg.StreamRateLimits.Default.TokensPerSecond,
+		"STREAM_RATE_LIMIT_BYTES_PER_SEC":  &cfg.StreamRateLimits.Default.BytesPerSecond,
+	} {
+		limitStr, exists := envVars[name]
+		if !exists || limitStr == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(limitStr, 64)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("%s must be a non-negative number, got: %s", name, limitStr)
+		}
+		*limit = value
+		cfg.logInfo("configuration", "request", "", "Configured "+name, map[string]interface{}{
+			"per_second": value,
+		})
+	}
+
+	// Parse CLIENT_STREAM_RATE_LIMITS (optional, keyid=tokens/bytes overrides)
+	if streamRateLimits, exists := envVars["CLIENT_STREAM_RATE_LIMITS"]; exists && streamRateLimits != "" {
+		keys, err := parseStreamRateLimits(streamRateLimits)
+		if err != nil {
+			return nil, err
+		}
+		cfg.StreamRateLimits.Keys = keys
+		cfg.logInfo("configuration", "request", "", "Configured CLIENT_STREAM_RATE_LIMITS", map[string]interface{}{
+			"keys": len(keys),
+		})
+	}
+
+	// Parse CLIENT_MODEL_ACCESS (optional, keyid=model|model entries)
+	if modelAccess, exists := envVars["CLIENT_MODEL_ACCESS"]; exists && modelAccess != "" {
+		access, err := parseModelAccess(modelAccess)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ClientModelAccess = access
+		cfg.logInfo("configuration", "request", "", "Configured CLIENT_MODEL_ACCESS", map[string]interface{}{
+			"keys": len(access),
+		})
+	}
+
+	// Parse MAX_CONCURRENT_REQUESTS (optional, 0 = unlimited)
+	if concurrencyStr, exists := envVars["MAX_CONCURRENT_REQUESTS"]; exists && concurrencyStr != "" {
+		concurrency, err := strconv.Atoi(concurrencyStr)
+		if err != nil || concurrency < 0 {
+			return nil, fmt.Errorf("MAX_CONCURRENT_REQUESTS must be a non-negative integer, got: %s", concurrencyStr)
+		}
+		cfg.MaxConcurrentRequests = concurrency
+		cfg.logInfo("configuration", "request", "", "Configured MAX_CONCURRENT_REQUESTS", map[string]interface{}{
+			"max_concurrent_requests": concurrency,
+		})
+	}
+
+	// Parse REQUEST_HISTORY_SIZE (optional, defaults to 100, 0 disables)
+	if historyStr, exists := envVars["REQUEST_HISTORY_SIZE"]; exists && historyStr != "" {
+		size, err := strconv.Atoi(historyStr)
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("REQUEST_HISTORY_SIZE must be a non-negative integer, got: %s", historyStr)
+		}
+		cfg.RequestHistorySize = size
+		cfg.logInfo("configuration", "request", "", "Configured REQUEST_HISTORY_SIZE", map[string]interface{}{
+			"request_history_size": size,
+		})
+	}
+
+	// Parse DIAGNOSTIC_DUMP_FILE (optional, defaults to the process log)
+	if dumpFile, exists := envVars["DIAGNOSTIC_DUMP_FILE"]; exists && dumpFile != "" {
+		cfg.DiagnosticDumpFile = dumpFile
+		cfg.logInfo("configuration", "request", "", "Configured DIAGNOSTIC_DUMP_FILE", map[string]interface{}{
+			"path": dumpFile,
+		})
+	}
+
+	// Parse WARMUP_ENABLED (optional, defaults to false)
+	if warmup, exists := envVars["WARMUP_ENABLED"]; exists {
+		cfg.WarmupEnabled = warmup == "true" || warmup == "1"
+		cfg.logInfo("configuration", "request", "", "Configured WARMUP_ENABLED", map[string]interface{}{
+			"enabled": cfg.WarmupEnabled,
+		})
+	}
+
+	// Parse ALERT_WEBHOOK_URL (optional, disabled when empty)
+	if alertWebhookURL, exists := envVars["ALERT_WEBHOOK_URL"]; exists && alertWebhookURL != "" {
+		if !strings.HasPrefix(alertWebhookURL, "http://") && !strings.HasPrefix(alertWebhookURL, "https://") {
+			return nil, fmt.Errorf("ALERT_WEBHOOK_URL must be an http(s) URL, got: %s", alertWebhookURL)
+		}
+		cfg.AlertWebhookURL = alertWebhookURL
+		cfg.logInfo("configuration", "request", "", "Configured ALERT_WEBHOOK_URL", map[string]interface{}{
+			"enabled": true,
+			"description": "circuit breaker alerts enabled",
+		})
+	}
+
+	// Parse BIG_MODEL_CIRCUIT_BREAKER (optional, defaults to false)
+	if bigModelBreaker, exists := envVars["BIG_MODEL_CIRCUIT_BREAKER"]; exists {
+		cfg.BigModelCircuitBreaker = bigModelBreaker == "true" || bigModelBreaker == "1"
+		cfg.logInfo("configuration", "request", "", "Configured BIG_MODEL_CIRCUIT_BREAKER", map[string]interface{}{
+			"enabled": cfg.BigModelCircuitBreaker,
+		})
+	}
+
+	// Parse BIG_MODEL_FAILURE_THRESHOLD (optional, defaults to 5)
+	if thresholdStr, exists := envVars["BIG_MODEL_FAILURE_THRESHOLD"]; exists && thresholdStr != "" {
+		var threshold int
+		if _, err := fmt.Sscanf(thresholdStr, "%d", &threshold); err != nil || threshold < 1 {
+			return nil, fmt.Errorf("BIG_MODEL_FAILURE_THRESHOLD must be a positive integer, got: %s", thresholdStr)
+		}
+		cfg.BigModelFailureThreshold = threshold
+		cfg.logInfo("configuration", "request", "", "Configured BIG_MODEL_FAILURE_THRESHOLD", map[string]interface{}{
+			"failure_threshold": threshold,
+		})
+	}
+
+	// Parse CIRCUIT_STATE_PATH (optional, defaults to circuit_state.json)
+	if circuitStatePath, exists := envVars["CIRCUIT_STATE_PATH"]; exists && circuitStatePath != "" {
+		cfg.CircuitStatePath = circuitStatePath
+		cfg.logInfo("configuration", "request", "", "Configured CIRCUIT_STATE_PATH", map[string]interface{}{
+			"path": circuitStatePath,
+		})
+	}
+
+	// Parse CIRCUIT_STATE_TTL_SECONDS (optional, defaults to 900, 0 disables persistence)
+	if ttlStr, exists := envVars["CIRCUIT_STATE_TTL_SECONDS"]; exists && ttlStr != "" {
+		var ttl int
+		if _, err := fmt.Sscanf(ttlStr, "%d", &ttl); err != nil || ttl < 0 {
+			return nil, fmt.Errorf("CIRCUIT_STATE_TTL_SECONDS must be a non-negative integer, got: %s", ttlStr)
+		}
+		cfg.CircuitStateTTLSeconds = ttl
+		cfg.logInfo("configuration", "request", "", "Configured CIRCUIT_STATE_TTL_SECONDS", map[string]interface{}{
+			"ttl_seconds": ttl,
+			"enabled": ttl > 0,
+		})
+	}
+
+	// Parse METRICS_STATSD_ADDR (optional, disabled when empty)
+	if statsdAddr, exists := envVars["METRICS_STATSD_ADDR"]; exists && statsdAddr != "" {
+		if _, _, err := net.SplitHostPort(statsdAddr); err != nil {
+			return nil, fmt.Errorf("METRICS_STATSD_ADDR must be host:port, got: %s", statsdAddr)
+		}
+		cfg.MetricsStatsDAddr = statsdAddr
+		cfg.logInfo("configuration", "request", "", "Configured METRICS_STATSD_ADDR", map[string]interface{}{
+			"address": statsdAddr,
+			"description": "StatsD metrics emitter enabled",
+		})
+	}
+
+	// Parse METRICS_STATSD_PREFIX (optional, defaults to simple_proxy)
+	if statsdPrefix, exists := envVars["METRICS_STATSD_PREFIX"]; exists {
+		cfg.MetricsStatsDPrefix = statsdPrefix
+		cfg.logInfo("configuration", "request", "", "Configured METRICS_STATSD_PREFIX", map[string]interface{}{
+			"prefix": statsdPrefix,
+		})
+	}
+
+	// Parse ADMIN_GRPC_ADDR (optional, disabled when empty)
+	if grpcAddr, exists := envVars["ADMIN_GRPC_ADDR"]; exists && grpcAddr != "" {
+		if _, _, err := net.SplitHostPort(grpcAddr); err != nil {
+			return nil, fmt.Errorf("ADMIN_GRPC_ADDR must be host:port, got: %s", grpcAddr)
+		}
+		cfg.AdminGRPCAddr = grpcAddr
+		cfg.logInfo("configuration", "request", "", "Configured ADMIN_GRPC_ADDR", map[string]interface{}{
+			"address": grpcAddr,
+			"description": "gRPC control plane enabled",
+		})
+	}
+
+	// Parse ADMIN_GRPC_TOKEN (optional)
+	if grpcToken, exists := envVars["ADMIN_GRPC_TOKEN"]; exists && grpcToken != "" {
+		cfg.AdminGRPCToken = grpcToken
+		cfg.logInfo("configuration", "request", "", "Configured ADMIN_GRPC_TOKEN", map[string]interface{}{
+			"token": maskAPIKey(grpcToken),
+		})
+	}
+
+	// Parse ADMIN_TOKEN (optional, state-changing admin routes are disabled without it)
+	if adminToken, exists := envVars["ADMIN_TOKEN"]; exists && adminToken != "" {
+		cfg.AdminToken = adminToken
+		cfg.logInfo("configuration", "request", "", "Configured ADMIN_TOKEN", map[string]interface{}{
+			"token": maskAPIKey(adminToken),
+		})
+	}
+
+	// Parse CORS_ALLOWED_ORIGINS (optional, CORS disabled when empty)
+	if origins, exists := envVars["CORS_ALLOWED_ORIGINS"]; exists && origins != "" {
+		cfg.CORS.AllowedOrigins = splitList(origins)
+		cfg.logInfo("configuration", "request", "", "Configured CORS_ALLOWED_ORIGINS", map[string]interface{}{
+			"origins": cfg.CORS.AllowedOrigins,
+		})
+	}
+
+	// Parse CORS_ALLOWED_METHODS (optional, defaults to POST, OPTIONS)
+	if methods, exists := envVars["CORS_ALLOWED_METHODS"]; exists && methods != "" {
+		cfg.CORS.AllowedMethods = splitList(strings.ToUpper(methods))
+		cfg.logInfo("configuration", "request", "", "Configured CORS_ALLOWED_METHODS", map[string]interface{}{
+			"methods": cfg.CORS.AllowedMethods,
+		})
 	}
 
-	// Parse CONVERSATION_MASK_SENSITIVE (optional, defaults to true)
-	if maskSensitive, exists := envVars["CONVERSATION_MASK_SENSITIVE"]; exists {
-		if maskSensitive == "false" || maskSensitive == "0" {
-			cfg.ConversationMaskSensitive = false
-			cfg.logInfo("configuration", "request", "", "Configured CONVERSATION_MASK_SENSITIVE", map[string]interface{}{
-				"enabled": false,
-			})
-		} else {
-			cfg.ConversationMaskSensitive = true
-			cfg.logInfo("configuration", "request", "", "Configured CONVERSATION_MASK_SENSITIVE", map[string]interface{}{
-				"enabled": true,
-			})
+	// Parse CORS_ALLOWED_HEADERS (optional, defaults to the Anthropic SDK headers)
+	if headers, exists := envVars["CORS_ALLOWED_HEADERS"]; exists && headers != "" {
+		cfg.CORS.AllowedHeaders = splitList(headers)
+		cfg.logInfo("configuration", "request", "", "Configured CORS_ALLOWED_HEADERS", map[string]interface{}{
+			"headers": cfg.CORS.AllowedHeaders,
+		})
+	}
+
+	// Parse CORS_MAX_AGE_SECONDS (optional, defaults to 600)
+	if maxAge, exists := envVars["CORS_MAX_AGE_SECONDS"]; exists && maxAge != "" {
+		var seconds int
+		if _, err := fmt.Sscanf(maxAge, "%d", &seconds); err != nil || seconds < 0 {
+			return nil, fmt.Errorf("CORS_MAX_AGE_SECONDS must be a non-negative integer, got: %s", maxAge)
 		}
+		cfg.CORS.MaxAgeSeconds = seconds
+		cfg.logInfo("configuration", "request", "", "Configured CORS_MAX_AGE_SECONDS", map[string]interface{}{
+			"seconds": seconds,
+		})
 	}
 
-	// Parse LOG_FULL_TOOLS (required)
-	if logFullTools, exists := envVars["LOG_FULL_TOOLS"]; exists {
-		if logFullTools == "true" || logFullTools == "1" {
-			cfg.ConversationLogFullTools = true
-			cfg.logInfo("configuration", "request", "", "Configured LOG_FULL_TOOLS", map[string]interface{}{
-				"enabled": true,
-				"description": "full tool definitions",
-			})
-		} else if logFullTools == "false" || logFullTools == "0" {
-			cfg.ConversationLogFullTools = false
-			cfg.logInfo("configuration", "request", "", "Configured LOG_FULL_TOOLS", map[string]interface{}{
-				"enabled": false,
-				"description": "tool names only",
-			})
-		} else {
-			return nil, fmt.Errorf("LOG_FULL_TOOLS must be 'true' or 'false', got: %s", logFullTools)
+	// Parse ACCESS_LOG (optional, off by default)
+	if accessLog, exists := envVars["ACCESS_LOG"]; exists && accessLog != "" {
+		accessLog = strings.ToLower(accessLog)
+		switch accessLog {
+		case "off", "false":
+			accessLog = AccessLogOff
+		case AccessLogFile, AccessLogLoki:
+		default:
+			return nil, fmt.Errorf("ACCESS_LOG must be off, file or loki, got: %s", accessLog)
 		}
-	} else {
-		return nil, fmt.Errorf("LOG_FULL_TOOLS must be set in .env file")
+		cfg.AccessLog = accessLog
+		cfg.logInfo("configuration", "request", "", "Configured ACCESS_LOG", map[string]interface{}{
+			"output": accessLog,
+		})
 	}
 
-	// Parse CONVERSATION_TRUNCATION (required)
-	if truncation, exists := envVars["CONVERSATION_TRUNCATION"]; exists {
-		if truncation == "false" || truncation == "0" {
-			cfg.ConversationTruncation = 0
-			cfg.logInfo("configuration", "request", "", "Configured CONVERSATION_TRUNCATION", map[string]interface{}{
-				"enabled": false,
-			})
-		} else {
-			// Try to parse as integer
-			var truncationValue int
-			if n, err := fmt.Sscanf(truncation, "%d", &truncationValue); n != 1 || err != nil {
-				return nil, fmt.Errorf("CONVERSATION_TRUNCATION must be 'false' or a positive number, got: %s", truncation)
-			}
-			if truncationValue < 0 {
-				return nil, fmt.Errorf("CONVERSATION_TRUNCATION must be a positive number, got: %d", truncationValue)
-			}
-			cfg.ConversationTruncation = truncationValue
-			cfg.logInfo("configuration", "request", "", "Configured CONVERSATION_TRUNCATION", map[string]interface{}{
-				"enabled": true,
-				"max_characters": truncationValue,
-			})
+	// Parse ACCESS_LOG_FILE (optional, defaults to access.log)
+	if accessLogPath, exists := envVars["ACCESS_LOG_FILE"]; exists && accessLogPath != "" {
+		cfg.AccessLogPath = accessLogPath
+		cfg.logInfo("configuration", "request", "", "Configured ACCESS_LOG_FILE", map[string]interface{}{
+			"path": accessLogPath,
+		})
+	}
+
+	// Parse ACCESS_LOG_FIELDS (optional, defaults to every field)
+	if accessLogFields, exists := envVars["ACCESS_LOG_FIELDS"]; exists && accessLogFields != "" {
+		fields, err := parseAccessLogFields(accessLogFields)
+		if err != nil {
+			return nil, fmt.Errorf("ACCESS_LOG_FIELDS: %v", err)
 		}
-	} else {
-		return nil, fmt.Errorf("CONVERSATION_TRUNCATION must be set in .env file")
+		cfg.AccessLogFields = fields
+		cfg.logInfo("configuration", "request", "", "Configured ACCESS_LOG_FIELDS", map[string]interface{}{
+			"fields": fields,
+		})
 	}
 
-	// Parse DEFAULT_CONNECTION_TIMEOUT (optional, defaults to 30 seconds)
-	if connectionTimeout, exists := envVars["DEFAULT_CONNECTION_TIMEOUT"]; exists {
-		var timeoutValue int
-		if n, err := fmt.Sscanf(connectionTimeout, "%d", &timeoutValue); n != 1 || err != nil {
-			return nil, fmt.Errorf("DEFAULT_CONNECTION_TIMEOUT must be a positive number, got: %s", connectionTimeout)
+	// Parse MULTI_CHOICE_POLICY (optional, defaults to first)
+	if policy, exists := envVars["MULTI_CHOICE_POLICY"]; exists && policy != "" {
+		switch policy {
+		case MultiChoiceFirst, MultiChoiceMerge, MultiChoiceError:
+		default:
+			return nil, fmt.Errorf("MULTI_CHOICE_POLICY must be %q, %q or %q, got: %s",
+				MultiChoiceFirst, MultiChoiceMerge, MultiChoiceError, policy)
 		}
-		if timeoutValue < 1 {
-			return nil, fmt.Errorf("DEFAULT_CONNECTION_TIMEOUT must be a positive number, got: %d", timeoutValue)
+		cfg.MultiChoicePolicy = policy
+		cfg.logInfo("configuration", "request", "", "Configured MULTI_CHOICE_POLICY", map[string]interface{}{
+			"policy": policy,
+		})
+	}
+
+	// Parse DUPLICATE_TOOL_CALL_POLICY (optional, defaults to drop)
+	if policy, exists := envVars["DUPLICATE_TOOL_CALL_POLICY"]; exists && policy != "" {
+		switch policy {
+		case DuplicateToolCallDrop, DuplicateToolCallWarn, DuplicateToolCallKeep:
+		default:
+			return nil, fmt.Errorf("DUPLICATE_TOOL_CALL_POLICY must be %q, %q or %q, got: %s",
+				DuplicateToolCallDrop, DuplicateToolCallWarn, DuplicateToolCallKeep, policy)
 		}
-		cfg.DefaultConnectionTimeout = timeoutValue
-		cfg.logInfo("configuration", "request", "", "Configured DEFAULT_CONNECTION_TIMEOUT", map[string]interface{}{
-			"timeout_seconds": timeoutValue,
+		cfg.DuplicateToolCallPolicy = policy
+		cfg.logInfo("configuration", "request", "", "Configured DUPLICATE_TOOL_CALL_POLICY", map[string]interface{}{
+			"policy": policy,
 		})
-	} else {
-		cfg.DefaultConnectionTimeout = 30 // Default to 30 seconds if not specified
-		cfg.logInfo("configuration", "request", "", "Using default DEFAULT_CONNECTION_TIMEOUT", map[string]interface{}{
-			"timeout_seconds": 30,
+	}
+
+	// Parse LOOP_DETECTION_THRESHOLD (optional, defaults to 3)
+	if thresholdStr, exists := envVars["LOOP_DETECTION_THRESHOLD"]; exists && thresholdStr != "" {
+		threshold, err := strconv.Atoi(thresholdStr)
+		if err != nil || threshold < 2 {
+			return nil, fmt.Errorf("LOOP_DETECTION_THRESHOLD must be an integer of at least 2, got: %s", thresholdStr)
+		}
+		cfg.LoopDetectionThreshold = threshold
+		cfg.logInfo("configuration", "request", "", "Configured LOOP_DETECTION_THRESHOLD", map[string]interface{}{
+			"threshold": threshold,
 		})
 	}
 
-	// Parse ENABLE_TOOL_CHOICE_CORRECTION (optional, defaults to false)
-	if enableToolChoiceCorrection, exists := envVars["ENABLE_TOOL_CHOICE_CORRECTION"]; exists {
-		if enableToolChoiceCorrection == "true" || enableToolChoiceCorrection == "1" {
-			cfg.EnableToolChoiceCorrection = true
-			cfg.logInfo("configuration", "request", "", "Configured ENABLE_TOOL_CHOICE_CORRECTION", map[string]interface{}{
-				"enabled": true,
-				"description": "tool choice correction enabled",
-			})
-		} else {
-			cfg.EnableToolChoiceCorrection = false
-			cfg.logInfo("configuration", "request", "", "Configured ENABLE_TOOL_CHOICE_CORRECTION", map[string]interface{}{
-				"enabled": false,
-				"description": "tool choice correction disabled",
-			})
+	// Parse LOOP_DETECTION_ACTION (optional, defaults to respond)
+	if action, exists := envVars["LOOP_DETECTION_ACTION"]; exists && action != "" {
+		switch action {
+		case LoopActionRespond, LoopActionNudge, LoopActionToolResult:
+		default:
+			return nil, fmt.Errorf("LOOP_DETECTION_ACTION must be %q, %q or %q, got: %s",
+				LoopActionRespond, LoopActionNudge, LoopActionToolResult, action)
 		}
+		cfg.LoopDetectionAction = action
+		cfg.logInfo("configuration", "request", "", "Configured LOOP_DETECTION_ACTION", map[string]interface{}{
+			"action": action,
+		})
 	}
 
-	// Parse HARMONY_PARSING_ENABLED (optional, defaults to true)
-	if harmonyParsingEnabled, exists := envVars["HARMONY_PARSING_ENABLED"]; exists {
-		if harmonyParsingEnabled == "false" || harmonyParsingEnabled == "0" {
-			cfg.HarmonyParsingEnabled = false
-			cfg.logInfo("configuration", "request", "", "Configured HARMONY_PARSING_ENABLED", map[string]interface{}{
-				"enabled": false,
-				"description": "Harmony parsing disabled",
-			})
-		} else {
-			cfg.HarmonyParsingEnabled = true
-			cfg.logInfo("configuration", "request", "", "Configured HARMONY_PARSING_ENABLED", map[string]interface{}{
-				"enabled": true,
-				"description": "Harmony parsing enabled",
-			})
+	// Parse MAX_TOOL_ITERATIONS (optional, 0 = unlimited)
+	if iterationsStr, exists := envVars["MAX_TOOL_ITERATIONS"]; exists && iterationsStr != "" {
+		iterations, err := strconv.Atoi(iterationsStr)
+		if err != nil || iterations < 0 {
+			return nil, fmt.Errorf("MAX_TOOL_ITERATIONS must be a non-negative integer, got: %s", iterationsStr)
 		}
+		cfg.MaxToolIterations = iterations
+		cfg.logInfo("configuration", "request", "", "Configured MAX_TOOL_ITERATIONS", map[string]interface{}{
+			"max_tool_iterations": iterations,
+		})
 	}
 
-	// Parse HARMONY_DEBUG (optional, defaults to false)
-	if harmonyDebug, exists := envVars["HARMONY_DEBUG"]; exists {
-		if harmonyDebug == "true" || harmonyDebug == "1" {
-			cfg.HarmonyDebug = true
-			cfg.logInfo("configuration", "request", "", "Configured HARMONY_DEBUG", map[string]interface{}{
-				"enabled": true,
-				"description": "Harmony debug logging enabled",
-			})
-		} else {
-			cfg.HarmonyDebug = false
-			cfg.logInfo("configuration", "request", "", "Configured HARMONY_DEBUG", map[string]interface{}{
-				"enabled": false,
-				"description": "Harmony debug logging disabled",
-			})
+	// Parse STOP_REASON_MAP (optional, finish_reason:stop_reason pairs)
+	if stopReasonMap, exists := envVars["STOP_REASON_MAP"]; exists && stopReasonMap != "" {
+		stopReasons, err := parseStopReasonMap(stopReasonMap)
+		if err != nil {
+			return nil, fmt.Errorf("STOP_REASON_MAP: %v", err)
 		}
+		cfg.StopReasons = stopReasons
+		cfg.logInfo("configuration", "request", "", "Configured STOP_REASON_MAP", map[string]interface{}{
+			"entries": len(stopReasons),
+		})
 	}
 
-	// Parse HARMONY_STRICT_MODE (optional, defaults to false)
-	if harmonyStrictMode, exists := envVars["HARMONY_STRICT_MODE"]; exists {
-		if harmonyStrictMode == "true" || harmonyStrictMode == "1" {
-			cfg.HarmonyStrictMode = true
-			cfg.logInfo("configuration", "request", "", "Configured HARMONY_STRICT_MODE", map[string]interface{}{
-				"enabled": true,
-				"description": "Harmony strict error handling enabled",
-			})
-		} else {
-			cfg.HarmonyStrictMode = false
-			cfg.logInfo("configuration", "request", "", "Configured HARMONY_STRICT_MODE", map[string]interface{}{
-				"enabled": false,
-				"description": "Harmony lenient error handling (default)",
-			})
+	// Parse LISTEN (optional, comma-separated TCP and unix socket addresses)
+	if listen, exists := envVars["LISTEN"]; exists && listen != "" {
+		var addresses []ListenAddress
+		for _, spec := range strings.Split(listen, ",") {
+			if strings.TrimSpace(spec) == "" {
+				continue
+			}
+			address, err := ParseListenAddress(spec)
+			if err != nil {
+				return nil, fmt.Errorf("LISTEN: %v", err)
+			}
+			addresses = append(addresses, address)
 		}
+		cfg.ListenAddresses = addresses
+		cfg.logInfo("configuration", "request", "", "Configured LISTEN", map[string]interface{}{
+			"addresses": listen,
+		})
+	}
+
+	// Record which .env was loaded for /admin/config
+	cfg.loadedAt = time.Now()
+	cfg.envFilePath = ".env"
+	if absPath, err := filepath.Abs(".env"); err == nil {
+		cfg.envFilePath = absPath
 	}
 
 	// Load tool description overrides from YAML file
 	toolDescriptions, err := LoadToolDescriptions()
+	cfg.overrideFiles = append(cfg.overrideFiles, overrideFileStatusFor("tools_override.yaml", err, len(toolDescriptions)))
 	if err != nil {
 		cfg.logWarn("configuration", "warning", "", "Failed to load tool descriptions from tools_override.yaml", map[string]interface{}{
 			"error": err.Error(),
@@ -721,20 +2213,126 @@ func LoadConfigWithEnv() (*Config, error) {
 
 	// Load system message overrides from YAML file
 	systemOverrides, err := LoadSystemMessageOverrides()
+	systemOverrideEntries := len(systemOverrides.RemovePatterns) + len(systemOverrides.Replacements)
+	if systemOverrides.Prepend != "" {
+		systemOverrideEntries++
+	}
+	if systemOverrides.Append != "" {
+		systemOverrideEntries++
+	}
+	cfg.overrideFiles = append(cfg.overrideFiles, overrideFileStatusFor("system_overrides.yaml", err, systemOverrideEntries))
 	if err != nil {
 		cfg.logWarn("configuration", "warning", "", "Failed to load system message overrides from system_overrides.yaml", map[string]interface{}{
 			"error": err.Error(),
 		})
 		// Continue with empty system overrides instead of failing
+	} else if err := systemOverrides.CompilePatterns(); err != nil {
+		// A broken pattern would silently leave content in every system prompt
+		return nil, fmt.Errorf("system_overrides.yaml: %v", err)
 	} else {
 		cfg.SystemMessageOverrides = systemOverrides
 	}
 
+	// Load per-model system prompts from YAML file
+	modelPrompts, err := LoadModelSystemPrompts()
+	cfg.overrideFiles = append(cfg.overrideFiles, overrideFileStatusFor("model_prompts.yaml", err, len(modelPrompts)))
+	if err != nil {
+		cfg.logWarn("configuration", "warning", "", "Failed to load per-model system prompts from model_prompts.yaml", map[string]interface{}{
+			"error": err.Error(),
+		})
+		// Continue without per-model prompts instead of failing
+	} else {
+		cfg.ModelSystemPrompts = modelPrompts
+	}
+
+	// Load per-model request profiles from YAML file
+	modelProfiles, err := LoadModelProfiles()
+	cfg.overrideFiles = append(cfg.overrideFiles, overrideFileStatusFor("model_profiles.yaml", err, len(modelProfiles)))
+	if err != nil {
+		cfg.logWarn("configuration", "warning", "", "Failed to load model profiles from model_profiles.yaml", map[string]interface{}{
+			"error": err.Error(),
+		})
+		// Continue without profiles instead of failing
+	} else {
+		cfg.ModelProfiles = modelProfiles
+	}
+
+	// Load per-endpoint transport settings from YAML file
+	endpointSettings, err := LoadEndpointSettings()
+	cfg.overrideFiles = append(cfg.overrideFiles, overrideFileStatusFor("endpoints.yaml", err, len(endpointSettings)))
+	if err != nil {
+		cfg.logWarn("configuration", "warning", "", "Failed to load endpoint settings from endpoints.yaml", map[string]interface{}{
+			"error": err.Error(),
+		})
+		// Continue with default transport settings instead of failing
+	} else {
+		cfg.EndpointSettings = endpointSettings
+	}
+
+	// Load custom provider quirk profiles from YAML file
+	providerProfiles, err := LoadProviderProfiles()
+	cfg.overrideFiles = append(cfg.overrideFiles, overrideFileStatusFor("provider_profiles.yaml", err, len(providerProfiles)))
+	if err != nil {
+		cfg.logWarn("configuration", "warning", "", "Failed to load provider profiles from provider_profiles.yaml", map[string]interface{}{
+			"error": err.Error(),
+		})
+		// Continue with the built-in profiles instead of failing
+	} else {
+		cfg.ProviderProfiles = providerProfiles
+	}
+	for _, prefix := range cfg.unknownProviderProfiles() {
+		cfg.logWarn("configuration", "warning", "", "Unknown provider profile in endpoints.yaml, requests are sent unchanged", map[string]interface{}{
+			"endpoint": prefix,
+			"profile":  cfg.EndpointSettings[prefix].Profile,
+		})
+	}
+
+	// Load custom tool validators from YAML file
+	customTools, err := LoadToolValidators()
+	cfg.overrideFiles = append(cfg.overrideFiles, overrideFileStatusFor("tool_validators.yaml", err, len(customTools)))
+	if err != nil {
+		cfg.logWarn("configuration", "warning", "", "Failed to load custom tool validators from tool_validators.yaml", map[string]interface{}{
+			"error": err.Error(),
+		})
+		// Continue with built-in tools only instead of failing
+	} else {
+		cfg.CustomTools = customTools
+	}
+
+	// Load custom redaction patterns from YAML file
+	redactionPatterns, err := LoadRedactionPatterns()
+	cfg.overrideFiles = append(cfg.overrideFiles, overrideFileStatusFor("redaction_patterns.yaml", err, len(redactionPatterns)))
+	if err != nil {
+		// A broken pattern would ship the data it should mask
+		return nil, err
+	}
+	cfg.RedactionPatterns = redactionPatterns
+
+	// Load tool-call policies from YAML file
+	toolPolicies, err := LoadToolPolicies()
+	cfg.overrideFiles = append(cfg.overrideFiles, overrideFileStatusFor("tool_policies.yaml", err, len(toolPolicies)))
+	if err != nil {
+		// A broken policy file would silently let every tool call through
+		return nil, err
+	}
+	cfg.ToolPolicies = toolPolicies
+
 	// Initialize circuit breaker health tracking
 	cfg.HealthManager = circuitbreaker.NewHealthManager(circuitbreaker.DefaultConfig())
 	allEndpoints := append(cfg.BigModelEndpoints, cfg.SmallModelEndpoints...)
 	allEndpoints = append(allEndpoints, cfg.ToolCorrectionEndpoints...)
 	cfg.HealthManager.InitializeEndpoints(allEndpoints)
+	cfg.HealthManager.RegisterRole("big_model", cfg.BigModelEndpoints)
+	cfg.HealthManager.RegisterRole("small_model", cfg.SmallModelEndpoints)
+	cfg.HealthManager.RegisterRole("tool_correction", cfg.ToolCorrectionEndpoints)
+	if cfg.BigModelCircuitBreaker {
+		bigModelBreaker := circuitbreaker.DefaultConfig()
+		bigModelBreaker.FailureThreshold = cfg.BigModelFailureThreshold
+		cfg.HealthManager.SetRoleConfig(EndpointRoleBig, bigModelBreaker)
+	}
+	if cfg.AlertWebhookURL != "" {
+		cfg.HealthManager.SetAlertSink(circuitbreaker.NewWebhookAlertSink(cfg.AlertWebhookURL))
+	}
 
 	return cfg, nil
 }
@@ -1020,6 +2618,33 @@ type SystemMessageOverrides struct {
 	Replacements   []SystemMessageReplacement `yaml:"replacements"`
 	Prepend        string                     `yaml:"prepend"`
 	Append         string                     `yaml:"append"`
+
+	// RemovePatterns compiled once at config load by CompilePatterns
+	compiledPatterns []*regexp.Regexp
+	// Set by CompilePatterns; results of compiled overrides are memoized per version
+	version uint64
+}
+
+// CompilePatterns compiles RemovePatterns so requests reuse them instead of
+// recompiling every pattern per message. An invalid pattern is an error
+// naming the offending entry.
+func (o *SystemMessageOverrides) CompilePatterns() error {
+	compiled := make([]*regexp.Regexp, 0, len(o.RemovePatterns))
+	for i, pattern := range o.RemovePatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid removePatterns[%d] %q: %v", i, pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	o.compiledPatterns = compiled
+	o.version = overridesVersions.Add(1)
+	return nil
+}
+
+// IsEmpty reports whether no override is configured
+func (o SystemMessageOverrides) IsEmpty() bool {
+	return len(o.RemovePatterns) == 0 && len(o.Replacements) == 0 && o.Prepend == "" && o.Append == ""
 }
 
 // SystemMessageOverridesYAML represents the structure of system_overrides.yaml
@@ -1103,7 +2728,9 @@ func LoadSystemMessageOverrides() (SystemMessageOverrides, error) {
 // and monitoring purposes, enabling administrators to verify modification behavior.
 //
 // Error handling:
-//   - Invalid regex patterns: Logged as warnings, pattern skipped
+//   - Patterns are compiled at config load (CompilePatterns), where invalid
+//     ones are rejected; overrides built without it are compiled per call
+//     and invalid patterns are skipped
 //   - Processing continues with remaining valid patterns
 //   - Graceful degradation ensures message processing completion
 //
@@ -1121,23 +2748,62 @@ func LoadSystemMessageOverrides() (SystemMessageOverrides, error) {
 //	modified := ApplySystemMessageOverrides(original, overrides)
 //	// Returns message with all configured transformations applied
 func ApplySystemMessageOverrides(originalMessage string, overrides SystemMessageOverrides) string {
+	return applySystemMessageOverrides(originalMessage, overrides, nil, "")
+}
+
+// ApplySystemOverrides applies the configured system message overrides to
+// message, logging each modification through the ObservabilityLogger.
+// Clients resend the same system prompt every turn, so results of overrides
+// loaded from system_overrides.yaml are memoized by prompt hash and overrides
+// version; modifications are only logged the first time.
+func (c *Config) ApplySystemOverrides(requestID, message string) string {
+	version := c.SystemMessageOverrides.version
+	if version == 0 {
+		return applySystemMessageOverrides(message, c.SystemMessageOverrides, c, requestID)
+	}
+
+	key := systemOverrideKey{version: version, message: sha256.Sum256([]byte(message))}
+	if result, ok := systemOverrideResults.get(key); ok {
+		return result
+	}
+	result := applySystemMessageOverrides(message, c.SystemMessageOverrides, c, requestID)
+	systemOverrideResults.put(key, result)
+	return result
+}
+
+// ApplyToolResultOverrides applies the configured system message overrides to
+// tool result content, logging each modification. Unlike system prompts, tool
+// results rarely repeat, so they are not memoized: a long session would fill
+// the cache and evict the prompts it is meant for.
+func (c *Config) ApplyToolResultOverrides(requestID, content string) string {
+	return applySystemMessageOverrides(content, c.SystemMessageOverrides, c, requestID)
+}
+
+// applySystemMessageOverrides implements ApplySystemMessageOverrides; c may be nil to skip logging
+func applySystemMessageOverrides(originalMessage string, overrides SystemMessageOverrides, c *Config, requestID string) string {
 	message := originalMessage
 
-	// Apply remove patterns (regex-based removal)
-	for _, pattern := range overrides.RemovePatterns {
-		re, err := regexp.Compile(pattern)
-		if err != nil {
-			// Pattern validation error - use fallback logging since this is runtime processing
-			log.Printf("⚠️  Warning: Invalid regex pattern '%s': %v", pattern, err)
-			continue
+	patterns := overrides.compiledPatterns
+	if len(patterns) != len(overrides.RemovePatterns) {
+		// Overrides built without CompilePatterns (e.g. in tests); skip invalid patterns
+		patterns = nil
+		for _, pattern := range overrides.RemovePatterns {
+			if re, err := regexp.Compile(pattern); err == nil {
+				patterns = append(patterns, re)
+			}
 		}
+	}
 
+	// Apply remove patterns (regex-based removal)
+	for _, re := range patterns {
 		// Find matches before removing them
 		matches := re.FindAllString(message, -1)
 		if len(matches) > 0 {
-			for _, match := range matches {
-				// Match removal - use fallback logging for system message processing
-				log.Printf("🔍 removePattern detected, removed '%s' for pattern '%s'", match, pattern)
+			if c != nil {
+				c.logInfo("configuration", "transformation", requestID, "removePattern matched", map[string]interface{}{
+					"pattern": re.String(),
+					"matches": matches,
+				})
 			}
 			message = re.ReplaceAllString(message, "")
 		}
@@ -1146,31 +2812,37 @@ func ApplySystemMessageOverrides(originalMessage string, overrides SystemMessage
 	// Apply replacements
 	for _, replacement := range overrides.Replacements {
 		if strings.Contains(message, replacement.Find) {
-			oldMessage := message
-			message = strings.ReplaceAll(message, replacement.Find, replacement.Replace)
 			// Count occurrences replaced
-			occurrences := strings.Count(oldMessage, replacement.Find)
-			// Replacement applied - use fallback logging for system message processing
-			log.Printf("🔄 replacement applied: '%s' → '%s' (%d occurrences)",
-				replacement.Find, replacement.Replace, occurrences)
+			occurrences := strings.Count(message, replacement.Find)
+			message = strings.ReplaceAll(message, replacement.Find, replacement.Replace)
+			if c != nil {
+				c.logInfo("configuration", "transformation", requestID, "Replacement applied", map[string]interface{}{
+					"find":        replacement.Find,
+					"replace":     replacement.Replace,
+					"occurrences": occurrences,
+				})
+			}
 		}
 	}
 
 	// Apply prepend and append
 	if overrides.Prepend != "" {
 		message = overrides.Prepend + message
-		// Prepend applied - use fallback logging for system message processing
-		log.Printf("➕ prepend applied: '%s'", strings.TrimSpace(overrides.Prepend))
+		if c != nil {
+			c.logInfo("configuration", "transformation", requestID, "Prepend applied", map[string]interface{}{
+				"prepend": strings.TrimSpace(overrides.Prepend),
+			})
+		}
 	}
 	if overrides.Append != "" {
 		message = message + overrides.Append
-		// Append applied - use fallback logging for system message processing
-		log.Printf("➕ append applied: '%s'", strings.TrimSpace(overrides.Append))
+		if c != nil {
+			c.logInfo("configuration", "transformation", requestID, "Append applied", map[string]interface{}{
+				"append": strings.TrimSpace(overrides.Append),
+			})
+		}
 	}
 
-	// Print updated system prompt
-	//log.Printf("Modified system prompt:\n%s", message)
-
 	return message
 }
 
@@ -1180,11 +2852,14 @@ func ApplySystemMessageOverrides(originalMessage string, overrides SystemMessage
 // This method provides endpoint selection for BIG_MODEL requests, which typically
 // involve complex reasoning tasks that may require 30+ minutes of processing time.
 // Due to these extended processing requirements, big model endpoints bypass
-// circuit breaker logic to avoid false failure detection.
+// circuit breaker logic to avoid false failure detection, unless
+// BIG_MODEL_CIRCUIT_BREAKER is enabled.
 //
 // Endpoint selection characteristics:
-//   - Simple round-robin rotation without health checking
-//   - No circuit breaker integration (extended processing time tolerance)
+//   - Simple round-robin rotation without health checking by default
+//   - With BIG_MODEL_CIRCUIT_BREAKER, endpoints with an open circuit are skipped;
+//     circuits open after BIG_MODEL_FAILURE_THRESHOLD failures and requests keep
+//     their 30 minute timeout, so only dead endpoints are excluded
 //   - Thread-safe endpoint index management
 //   - Automatic wraparound for continuous rotation
 //
@@ -1213,6 +2888,11 @@ func (c *Config) GetBigModelEndpoint() string {
 		return ""
 	}
 
+	if c.BigModelCircuitBreaker && c.HealthManager != nil {
+		c.bigModelIndex %= len(c.BigModelEndpoints)
+		return c.HealthManager.SelectHealthyEndpoint(c.BigModelEndpoints, &c.bigModelIndex)
+	}
+
 	// Simple round-robin without circuit breaker for big models
 	// (30+ minute processing time is acceptable for big models)
 	endpoint := c.BigModelEndpoints[c.bigModelIndex%len(c.BigModelEndpoints)]
@@ -1495,6 +3175,22 @@ func (c *Config) GetEnableToolChoiceCorrection() bool {
 	return c.EnableToolChoiceCorrection
 }
 
+// GetCorrectionMaxRetries returns the correction attempts per tool call
+func (c *Config) GetCorrectionMaxRetries() int {
+	return c.CorrectionMaxRetries
+}
+
+// CorrectionContextLimits returns the message and token limits of the
+// conversation tail in correction prompts
+func (c *Config) CorrectionContextLimits() (messages, tokens int) {
+	return c.CorrectionContextMessages, c.CorrectionContextTokens
+}
+
+// GetCorrectionExhaustedPolicy returns what happens to tool calls still invalid after every attempt
+func (c *Config) GetCorrectionExhaustedPolicy() string {
+	return c.CorrectionExhaustedPolicy
+}
+
 // MarkEndpointFailed moves to the next endpoint when the current one fails
 func (c *Config) MarkEndpointFailed(endpointType string) {
 	c.mutex.Lock()
@@ -1563,6 +3259,41 @@ func (c *Config) IsHarmonyParsingEnabled() bool {
 	return c.HarmonyParsingEnabled
 }
 
+// IsHarmonyEnabledForModel reports whether Harmony parsing applies to a mapped
+// backend model. Only gpt-oss models emit Harmony, so HARMONY_MODELS can
+// restrict the detector to them instead of running it on every response;
+// without it every model is parsed while HARMONY_PARSING_ENABLED is set.
+// PASSTHROUGH_MODELS are never parsed.
+func (c *Config) IsHarmonyEnabledForModel(model string) bool {
+	if !c.HarmonyParsingEnabled || c.IsPassthroughModel(model) {
+		return false
+	}
+	return len(c.HarmonyModels) == 0 || matchesAny(c.HarmonyModels, strings.ToLower(model))
+}
+
+// IsHarmonyModel reports whether a mapped backend model is listed in
+// HARMONY_MODELS. Request-side Harmony formatting only applies to listed
+// models, so without the list no model qualifies, and never to
+// PASSTHROUGH_MODELS.
+func (c *Config) IsHarmonyModel(model string) bool {
+	return !c.IsPassthroughModel(model) && matchesAny(c.HarmonyModels, strings.ToLower(model))
+}
+
+// IsPassthroughModel reports whether a mapped backend model is listed in
+// PASSTHROUGH_MODELS. Requests for these models are only translated between
+// the Anthropic and OpenAI formats, so a model suspected of being broken by
+// the pipeline can be run without it.
+func (c *Config) IsPassthroughModel(model string) bool {
+	return matchesAny(c.PassthroughModels, strings.ToLower(model))
+}
+
+// Reasoning levels of the Harmony system message (HARMONY_REASONING_LEVEL)
+const (
+	HarmonyReasoningLow    = "low"
+	HarmonyReasoningMedium = "medium"
+	HarmonyReasoningHigh   = "high"
+)
+
 // IsHarmonyDebugEnabled returns whether detailed Harmony debug logging is
 // currently enabled, controlling the verbosity of Harmony parsing operations.
 //
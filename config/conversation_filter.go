@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ConversationLogFilter narrows conversation logging to the traffic worth
+// keeping, e.g. big-model sessions without Haiku background calls. Patterns
+// are path.Match globs ("*haiku*", "/v1/messages/batches/*"); exclusions win
+// and an empty include list includes everything.
+type ConversationLogFilter struct {
+	IncludeModels []string `json:"include_models,omitempty"`
+	ExcludeModels []string `json:"exclude_models,omitempty"`
+	IncludePaths  []string `json:"include_paths,omitempty"`
+	ExcludePaths  []string `json:"exclude_paths,omitempty"`
+}
+
+// Allows reports whether a request for model on requestPath is logged.
+// Models are matched case-insensitively.
+func (f ConversationLogFilter) Allows(model, requestPath string) bool {
+	model = strings.ToLower(model)
+	if matchesAny(f.ExcludeModels, model) || matchesAny(f.ExcludePaths, requestPath) {
+		return false
+	}
+	if len(f.IncludeModels) > 0 && !matchesAny(f.IncludeModels, model) {
+		return false
+	}
+	if len(f.IncludePaths) > 0 && !matchesAny(f.IncludePaths, requestPath) {
+		return false
+	}
+	return true
+}
+
+// matchesAny reports whether value matches one of the glob patterns
+func matchesAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, value); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// parseGlobList splits a comma-separated list of path.Match patterns,
+// lowercasing them when they match model names
+func parseGlobList(value string, lower bool) ([]string, error) {
+	if lower {
+		value = strings.ToLower(value)
+	}
+	patterns := splitList(value)
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+	}
+	return patterns, nil
+}
@@ -0,0 +1,44 @@
+package config
+
+import "testing"
+
+// TestConversationLogFilter tests include/exclude precedence for models and paths
+func TestConversationLogFilter(t *testing.T) {
+	excludeModels, err := parseGlobList("*HAIKU*", true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	filter := ConversationLogFilter{
+		ExcludeModels: excludeModels,
+		ExcludePaths:  []string{"/v1/messages/batches/*"},
+	}
+
+	tests := []struct {
+		model, path string
+		expected    bool
+	}{
+		{"claude-sonnet-4-20250514", "/v1/messages", true},
+		{"claude-3-5-haiku-20241022", "/v1/messages", false},
+		{"claude-sonnet-4-20250514", "/v1/messages/batches/msgbatch_1", false},
+	}
+	for _, tt := range tests {
+		if got := filter.Allows(tt.model, tt.path); got != tt.expected {
+			t.Errorf("Allows(%q, %q) = %v, expected %v", tt.model, tt.path, got, tt.expected)
+		}
+	}
+
+	filter = ConversationLogFilter{IncludeModels: []string{"claude-sonnet-*", "claude-opus-*"}}
+	if !filter.Allows("Claude-Opus-4-20250514", "/v1/messages") {
+		t.Error("Expected included model to be logged regardless of case")
+	}
+	if filter.Allows("claude-3-5-haiku-20241022", "/v1/messages") {
+		t.Error("Expected models outside the include list to be skipped")
+	}
+	if !(ConversationLogFilter{}).Allows("anything", "/v1/messages") {
+		t.Error("Expected an empty filter to log everything")
+	}
+
+	if _, err := parseGlobList("claude-[", true); err == nil {
+		t.Error("Expected an error for a malformed pattern")
+	}
+}
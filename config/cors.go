@@ -0,0 +1,57 @@
+package config
+
+import "strings"
+
+// CORSConfig controls the CORS headers served on /v1/messages so browser-based
+// Anthropic clients can call the proxy directly. CORS is off while
+// AllowedOrigins is empty.
+type CORSConfig struct {
+	AllowedOrigins []string `json:"allowed_origins"` // Exact origins, or "*" for any
+	AllowedMethods []string `json:"allowed_methods"`
+	AllowedHeaders []string `json:"allowed_headers"`
+	MaxAgeSeconds  int      `json:"max_age_seconds"` // How long browsers may cache a preflight response
+}
+
+// DefaultCORSConfig returns CORS disabled, with the methods and headers the
+// Anthropic SDKs send once origins are allowed
+func DefaultCORSConfig() CORSConfig {
+	return CORSConfig{
+		AllowedMethods: []string{"POST", "OPTIONS"},
+		AllowedHeaders: []string{
+			"Content-Type",
+			"Authorization",
+			"X-Api-Key",
+			"Anthropic-Version",
+			"Anthropic-Beta",
+			"Anthropic-Dangerous-Direct-Browser-Access",
+			"X-Request-ID",
+		},
+		MaxAgeSeconds: 600,
+	}
+}
+
+// Enabled reports whether any origin is allowed
+func (c CORSConfig) Enabled() bool {
+	return len(c.AllowedOrigins) > 0
+}
+
+// AllowsOrigin reports whether a browser origin may call the proxy
+func (c CORSConfig) AllowsOrigin(origin string) bool {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitList splits a comma-separated .env value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,212 @@
+package config
+
+import (
+	"claude-proxy/types"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EndpointSettings overrides transport settings for the endpoints matching a
+// URL prefix in endpoints.yaml. Zero values keep the defaults.
+type EndpointSettings struct {
+	ConnectTimeoutSeconds  int `yaml:"connectTimeoutSeconds" json:"connect_timeout_seconds,omitempty"`   // Dial timeout, defaults to DEFAULT_CONNECTION_TIMEOUT
+	ResponseTimeoutSeconds int `yaml:"responseTimeoutSeconds" json:"response_timeout_seconds,omitempty"` // Whole-request timeout, defaults per role
+	MaxIdleConns           int `yaml:"maxIdleConns" json:"max_idle_conns,omitempty"`                     // Idle keep-alive connections kept per host
+
+	// Extra headers sent with every request, replacing any the proxy sets itself
+	// (so an Authorization entry overrides the Bearer API key)
+	Headers map[string]string `yaml:"headers" json:"headers,omitempty"`
+
+	// Provider quirk profile (groq, together, fireworks or one from provider_profiles.yaml)
+	Profile string `yaml:"profile" json:"profile,omitempty"`
+
+	// OpenRouter provider routing preferences forwarded as the request's "provider" block
+	Provider *types.OpenRouterProvider `yaml:"provider" json:"provider,omitempty"`
+}
+
+// EndpointsYAML represents the structure of endpoints.yaml
+type EndpointsYAML struct {
+	Endpoints map[string]EndpointSettings `yaml:"endpoints"`
+}
+
+// transportKey identifies the transports that can be shared between endpoints;
+// connections are pooled per host inside each transport
+type transportKey struct {
+	connectTimeout time.Duration
+	maxIdleConns   int
+}
+
+// LoadEndpointSettings loads per-endpoint transport settings from endpoints.yaml.
+// Keys are URL prefixes; the longest prefix matching an endpoint wins, so a
+// host entry covers every path and dns+/k8s+ expansions on that host.
+//
+// YAML file structure:
+//
+//	endpoints:
+//	  "http://192.168.0.46:11434":          # LAN GPU box: fail fast, keep connections warm
+//	    connectTimeoutSeconds: 2
+//	    maxIdleConns: 16
+//	  "https://api.groq.com/openai/v1":     # Strip parameters Groq rejects
+//	    profile: groq
+//	  "https://openrouter.ai/api/v1":       # WAN provider: slow handshakes, long generations
+//	    connectTimeoutSeconds: 20
+//	    responseTimeoutSeconds: 600
+//	    headers:                            # OpenRouter app attribution
+//	      HTTP-Referer: "https://github.com/iSevenDays/simple-proxy"
+//	      X-Title: "simple-proxy"
+//	    provider:                           # OpenRouter provider routing
+//	      order: ["deepinfra", "together"]
+//	      allow_fallbacks: false
+//	      quantizations: ["fp8", "bf16"]
+//
+// Returns an empty map (no error) if endpoints.yaml doesn't exist.
+func LoadEndpointSettings() (map[string]EndpointSettings, error) {
+	return loadEndpointSettingsFile("endpoints.yaml")
+}
+
+// loadEndpointSettingsFile loads and validates endpoint settings from the given path
+func loadEndpointSettingsFile(path string) (map[string]EndpointSettings, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return make(map[string]EndpointSettings), nil
+		}
+		return nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	var yamlData EndpointsYAML
+	if err := yaml.NewDecoder(file).Decode(&yamlData); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	for prefix, settings := range yamlData.Endpoints {
+		if !strings.HasPrefix(prefix, "http://") && !strings.HasPrefix(prefix, "https://") {
+			return nil, fmt.Errorf("invalid endpoint %q in %s: must be an http(s) URL prefix", prefix, path)
+		}
+		if settings.ConnectTimeoutSeconds < 0 || settings.ResponseTimeoutSeconds < 0 || settings.MaxIdleConns < 0 {
+			return nil, fmt.Errorf("invalid settings for %s in %s: values must not be negative", prefix, path)
+		}
+		for name, value := range settings.Headers {
+			if name == "" || strings.ContainsAny(name, " \t\r\n:") {
+				return nil, fmt.Errorf("invalid header name %q for %s in %s", name, prefix, path)
+			}
+			if strings.ContainsAny(value, "\r\n") {
+				return nil, fmt.Errorf("invalid value for header %s of %s in %s: must be a single line", name, prefix, path)
+			}
+		}
+		if err := validateOpenRouterProvider(settings.Provider); err != nil {
+			return nil, fmt.Errorf("invalid provider for %s in %s: %v", prefix, path, err)
+		}
+	}
+	if yamlData.Endpoints == nil {
+		return make(map[string]EndpointSettings), nil
+	}
+	return yamlData.Endpoints, nil
+}
+
+// validateOpenRouterProvider checks the enumerated fields of a provider block
+func validateOpenRouterProvider(provider *types.OpenRouterProvider) error {
+	if provider == nil {
+		return nil
+	}
+	switch provider.DataCollection {
+	case "", "allow", "deny":
+	default:
+		return fmt.Errorf("data_collection must be allow or deny, got: %s", provider.DataCollection)
+	}
+	switch provider.Sort {
+	case "", "price", "throughput", "latency":
+	default:
+		return fmt.Errorf("sort must be price, throughput or latency, got: %s", provider.Sort)
+	}
+	return nil
+}
+
+// GetEndpointSettings returns the settings of the longest prefix in
+// endpoints.yaml that matches endpoint
+func (c *Config) GetEndpointSettings(endpoint string) (EndpointSettings, bool) {
+	var best string
+	found := false
+	for prefix := range c.EndpointSettings {
+		if strings.HasPrefix(endpoint, prefix) && (!found || len(prefix) > len(best)) {
+			best = prefix
+			found = true
+		}
+	}
+	return c.EndpointSettings[best], found
+}
+
+// ConnectTimeout returns the dial timeout for an endpoint
+func (c *Config) ConnectTimeout(endpoint string) time.Duration {
+	if settings, ok := c.GetEndpointSettings(endpoint); ok && settings.ConnectTimeoutSeconds > 0 {
+		return time.Duration(settings.ConnectTimeoutSeconds) * time.Second
+	}
+	return time.Duration(c.DefaultConnectionTimeout) * time.Second
+}
+
+// ResponseTimeout returns the whole-request timeout for an endpoint, or
+// fallback when endpoints.yaml does not set one
+func (c *Config) ResponseTimeout(endpoint string, fallback time.Duration) time.Duration {
+	if settings, ok := c.GetEndpointSettings(endpoint); ok && settings.ResponseTimeoutSeconds > 0 {
+		return time.Duration(settings.ResponseTimeoutSeconds) * time.Second
+	}
+	return fallback
+}
+
+// HTTPClient returns a client for requests to endpoint with its connect and
+// response timeouts and extra headers applied. Transports are cached so
+// keep-alive connections are reused across requests.
+//
+// Thread Safety: Safe for concurrent use.
+func (c *Config) HTTPClient(endpoint string, fallbackTimeout time.Duration) *http.Client {
+	settings, _ := c.GetEndpointSettings(endpoint)
+	key := transportKey{connectTimeout: c.ConnectTimeout(endpoint), maxIdleConns: settings.MaxIdleConns}
+
+	c.transportMutex.Lock()
+	transport, exists := c.transports[key]
+	if !exists {
+		transport = &http.Transport{
+			DialContext: (&net.Dialer{Timeout: key.connectTimeout}).DialContext,
+		}
+		if key.maxIdleConns > 0 {
+			transport.MaxIdleConnsPerHost = key.maxIdleConns
+		}
+		if c.transports == nil {
+			c.transports = make(map[transportKey]*http.Transport)
+		}
+		c.transports[key] = transport
+	}
+	c.transportMutex.Unlock()
+
+	var roundTripper http.RoundTripper = transport
+	if len(settings.Headers) > 0 {
+		roundTripper = &headerTransport{base: transport, headers: settings.Headers}
+	}
+	return &http.Client{
+		Timeout:   c.ResponseTimeout(endpoint, fallbackTimeout),
+		Transport: roundTripper,
+	}
+}
+
+// headerTransport adds an endpoint's static headers to each request it sends
+type headerTransport struct {
+	base    http.RoundTripper
+	headers map[string]string
+}
+
+// RoundTrip sets the headers on a copy of req, since a RoundTripper must not
+// modify the request it is given
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for name, value := range t.headers {
+		req.Header.Set(name, value)
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestLoadEndpointSettings tests parsing and validation of endpoints.yaml
+func TestLoadEndpointSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "endpoints.yaml")
+	content := `endpoints:
+  "http://192.168.0.46:11434":
+    connectTimeoutSeconds: 2
+    maxIdleConns: 16
+  "https://openrouter.ai/api/v1":
+    connectTimeoutSeconds: 20
+    responseTimeoutSeconds: 600
+    provider:
+      order: ["deepinfra", "together"]
+      allow_fallbacks: false
+      quantizations: ["fp8"]
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	settings, err := loadEndpointSettingsFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(settings) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(settings))
+	}
+	if got := settings["https://openrouter.ai/api/v1"].ResponseTimeoutSeconds; got != 600 {
+		t.Errorf("Expected response timeout 600, got %d", got)
+	}
+	provider := settings["https://openrouter.ai/api/v1"].Provider
+	if provider == nil || len(provider.Order) != 2 || provider.AllowFallbacks == nil || *provider.AllowFallbacks {
+		t.Errorf("Expected provider order and allow_fallbacks=false, got %+v", provider)
+	}
+
+	invalidSettings := map[string]string{
+		"not a URL":         "endpoints:\n  gpu-box:\n    connectTimeoutSeconds: 2\n",
+		"negative timeout":  "endpoints:\n  \"http://gpu\":\n    responseTimeoutSeconds: -1\n",
+		"bad header name":   "endpoints:\n  \"http://gpu\":\n    headers:\n      \"X Title\": proxy\n",
+		"bad provider sort": "endpoints:\n  \"https://openrouter.ai\":\n    provider:\n      sort: cheapest\n",
+	}
+	for name, invalid := range invalidSettings {
+		if err := os.WriteFile(path, []byte(invalid), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+		if _, err := loadEndpointSettingsFile(path); err == nil {
+			t.Errorf("Expected error for %s", name)
+		}
+	}
+
+	settings, err = loadEndpointSettingsFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil || len(settings) != 0 {
+		t.Errorf("Expected empty settings for missing file, got %v, %v", settings, err)
+	}
+}
+
+// TestEndpointHTTPClient tests prefix matching, fallbacks and transport sharing
+func TestEndpointHTTPClient(t *testing.T) {
+	cfg := &Config{
+		DefaultConnectionTimeout: 30,
+		EndpointSettings: map[string]EndpointSettings{
+			"http://gpu:8000":                 {ConnectTimeoutSeconds: 2, MaxIdleConns: 16},
+			"http://gpu:8000/v1/slow":         {ResponseTimeoutSeconds: 900},
+			"https://openrouter.ai/api/v1":    {ConnectTimeoutSeconds: 20, ResponseTimeoutSeconds: 600},
+			"https://openrouter.ai/api/v1/xx": {ConnectTimeoutSeconds: 20, ResponseTimeoutSeconds: 60},
+		},
+	}
+
+	if got := cfg.ConnectTimeout("http://gpu:8000/v1/chat/completions"); got != 2*time.Second {
+		t.Errorf("Expected 2s connect timeout from host prefix, got %v", got)
+	}
+	if got := cfg.ConnectTimeout("http://other:8000/v1/chat/completions"); got != 30*time.Second {
+		t.Errorf("Expected DEFAULT_CONNECTION_TIMEOUT for unmatched endpoint, got %v", got)
+	}
+
+	// The longest prefix wins, and unset values fall back rather than inherit
+	slow, _ := cfg.GetEndpointSettings("http://gpu:8000/v1/slow/chat/completions")
+	if slow.ResponseTimeoutSeconds != 900 || slow.ConnectTimeoutSeconds != 0 {
+		t.Errorf("Expected the longest prefix to win, got %+v", slow)
+	}
+
+	client := cfg.HTTPClient("https://openrouter.ai/api/v1/chat/completions", 3*time.Minute)
+	if client.Timeout != 600*time.Second {
+		t.Errorf("Expected 600s response timeout, got %v", client.Timeout)
+	}
+	if client := cfg.HTTPClient("http://other:8000/v1", 3*time.Minute); client.Timeout != 3*time.Minute {
+		t.Errorf("Expected fallback response timeout, got %v", client.Timeout)
+	}
+
+	// Endpoints with the same connect timeout and idle pool share a transport
+	other := cfg.HTTPClient("https://openrouter.ai/api/v1/xx/chat/completions", 3*time.Minute)
+	if other.Transport != client.Transport {
+		t.Error("Expected endpoints with equal transport settings to share a transport")
+	}
+	if gpu := cfg.HTTPClient("http://gpu:8000/v1/chat/completions", time.Minute); gpu.Transport == client.Transport {
+		t.Error("Expected a separate transport for different settings")
+	}
+}
+
+// TestEndpointHTTPClientHeaders tests that static headers are added to every
+// request and replace headers set by the caller
+func TestEndpointHTTPClientHeaders(t *testing.T) {
+	var received http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	cfg := &Config{
+		DefaultConnectionTimeout: 30,
+		EndpointSettings: map[string]EndpointSettings{
+			server.URL: {Headers: map[string]string{
+				"HTTP-Referer":  "https://github.com/iSevenDays/simple-proxy",
+				"X-Title":       "simple-proxy",
+				"Authorization": "Token custom-scheme",
+			}},
+		},
+	}
+
+	req, err := http.NewRequest("POST", server.URL+"/v1/chat/completions", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer sk-key")
+	resp, err := cfg.HTTPClient(server.URL+"/v1/chat/completions", time.Minute).Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if received.Get("X-Title") != "simple-proxy" || received.Get("HTTP-Referer") != "https://github.com/iSevenDays/simple-proxy" {
+		t.Errorf("Expected attribution headers, got %v", received)
+	}
+	if got := received.Get("Authorization"); got != "Token custom-scheme" {
+		t.Errorf("Expected configured Authorization to replace the Bearer key, got %q", got)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer sk-key" {
+		t.Errorf("Expected the caller's request to be left unmodified, got %q", got)
+	}
+}
@@ -0,0 +1,119 @@
+package config
+
+import "strings"
+
+// Endpoint roles, matching the circuit breaker role names
+const (
+	EndpointRoleBig            = "big_model"
+	EndpointRoleSmall          = "small_model"
+	EndpointRoleToolCorrection = "tool_correction"
+)
+
+// EndpointRoles lists every endpoint role
+var EndpointRoles = []string{EndpointRoleBig, EndpointRoleSmall, EndpointRoleToolCorrection}
+
+// Endpoint spec prefixes expanded by the discovery package into one endpoint
+// per resolved address:
+//
+//	dns+http://llm.internal:8000/v1/chat/completions   (A/AAAA records)
+//	srv+http://_llm._tcp.internal/v1/chat/completions  (SRV target:port)
+//	k8s+http://vllm.llm:8000/v1/chat/completions       (Kubernetes EndpointSlices)
+const (
+	EndpointSchemeDNS = "dns+"
+	EndpointSchemeSRV = "srv+"
+	EndpointSchemeK8s = "k8s+"
+)
+
+// IsDynamicEndpoint reports whether an endpoint spec is resolved at runtime
+func IsDynamicEndpoint(spec string) bool {
+	return strings.HasPrefix(spec, EndpointSchemeDNS) || strings.HasPrefix(spec, EndpointSchemeSRV) ||
+		strings.HasPrefix(spec, EndpointSchemeK8s)
+}
+
+// UsesKubernetesDiscovery reports whether any endpoint spec is a k8s+ spec
+func (c *Config) UsesKubernetesDiscovery() bool {
+	for _, specs := range c.EndpointSpecs {
+		for _, spec := range specs {
+			if strings.HasPrefix(spec, EndpointSchemeK8s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// endpointsLocked returns a pointer to the endpoint list of a role, or nil
+// for an unknown role. Caller must hold c.mutex.
+func (c *Config) endpointsLocked(role string) *[]string {
+	switch role {
+	case EndpointRoleBig:
+		return &c.BigModelEndpoints
+	case EndpointRoleSmall:
+		return &c.SmallModelEndpoints
+	case EndpointRoleToolCorrection:
+		return &c.ToolCorrectionEndpoints
+	}
+	return nil
+}
+
+// Endpoints returns a copy of the current endpoint list of a role
+func (c *Config) Endpoints(role string) []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if endpoints := c.endpointsLocked(role); endpoints != nil {
+		return append([]string(nil), *endpoints...)
+	}
+	return nil
+}
+
+// HasEndpoint reports whether endpoint currently serves role
+func (c *Config) HasEndpoint(role, endpoint string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if endpoints := c.endpointsLocked(role); endpoints != nil {
+		for _, candidate := range *endpoints {
+			if candidate == endpoint {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SetEndpoints replaces the endpoint list of a role, e.g. after discovery
+// resolved it again. New endpoints start with fresh circuit breaker state;
+// endpoints that remain keep theirs.
+func (c *Config) SetEndpoints(role string, endpoints []string) {
+	c.mutex.Lock()
+	current := c.endpointsLocked(role)
+	if current == nil {
+		c.mutex.Unlock()
+		return
+	}
+	*current = append([]string(nil), endpoints...)
+	c.mutex.Unlock()
+
+	if c.HealthManager != nil {
+		c.HealthManager.InitializeEndpoints(endpoints)
+		c.HealthManager.RegisterRole(role, endpoints)
+	}
+}
+
+// recordEndpointSpecs keeps the configured lists of roles that use dynamic
+// specs, since their endpoint lists are replaced once resolved
+func (c *Config) recordEndpointSpecs() {
+	for _, role := range EndpointRoles {
+		specs := *c.endpointsLocked(role)
+		for _, spec := range specs {
+			if IsDynamicEndpoint(spec) {
+				if c.EndpointSpecs == nil {
+					c.EndpointSpecs = make(map[string][]string)
+				}
+				c.EndpointSpecs[role] = append([]string(nil), specs...)
+				break
+			}
+		}
+	}
+}
@@ -133,4 +133,142 @@ CONVERSATION_TRUNCATION=0
 	if cfg.HarmonyStrictMode {
 		t.Error("Expected HarmonyStrictMode to default to false when not specified")
 	}
-}
\ No newline at end of file
+}
+
+// TestHarmonyModels tests restricting Harmony parsing to matching models
+func TestHarmonyModels(t *testing.T) {
+	envContent := `BIG_MODEL=gpt-oss:120b
+SMALL_MODEL=qwen3:8b
+CORRECTION_MODEL=qwen3:8b
+BIG_MODEL_ENDPOINT=http://localhost:11434/v1/chat/completions
+SMALL_MODEL_ENDPOINT=http://localhost:11434/v1/chat/completions
+TOOL_CORRECTION_ENDPOINT=http://localhost:11434/v1/chat/completions
+BIG_MODEL_API_KEY=test-key
+SMALL_MODEL_API_KEY=test-key
+TOOL_CORRECTION_API_KEY=test-key
+LOG_FULL_TOOLS=false
+CONVERSATION_TRUNCATION=0
+HARMONY_MODELS=GPT-OSS*, openai/gpt-oss-*
+HARMONY_REASONING_LEVEL=High
+`
+	err := os.WriteFile(".env", []byte(envContent), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create test .env file: %v", err)
+	}
+	defer os.Remove(".env")
+
+	cfg, err := LoadConfigWithEnv()
+	if err != nil {
+		t.Fatalf("LoadConfigWithEnv() failed: %v", err)
+	}
+
+	for model, want := range map[string]bool{
+		"gpt-oss:120b":         true,
+		"openai/gpt-oss-20b":   true,
+		"qwen3:8b":             false,
+		"llama-3.1-8b-instant": false,
+	} {
+		if got := cfg.IsHarmonyEnabledForModel(model); got != want {
+			t.Errorf("IsHarmonyEnabledForModel(%q) = %v, want %v", model, got, want)
+		}
+	}
+
+	cfg.HarmonyParsingEnabled = false
+	if cfg.IsHarmonyEnabledForModel("gpt-oss:120b") {
+		t.Error("Expected HARMONY_PARSING_ENABLED=false to disable matching models too")
+	}
+	if !GetDefaultConfig().IsHarmonyEnabledForModel("qwen3:8b") {
+		t.Error("Expected every model to be parsed without HARMONY_MODELS")
+	}
+	if cfg.HarmonyReasoningLevel != HarmonyReasoningHigh {
+		t.Errorf("Expected reasoning level %q, got %q", HarmonyReasoningHigh, cfg.HarmonyReasoningLevel)
+	}
+	if !cfg.IsHarmonyModel("gpt-oss:120b") || cfg.IsHarmonyModel("qwen3:8b") || GetDefaultConfig().IsHarmonyModel("gpt-oss:120b") {
+		t.Error("Expected only models listed in HARMONY_MODELS to be Harmony models")
+	}
+}
+
+// TestPassthroughModels tests that PASSTHROUGH_MODELS are never Harmony models
+func TestPassthroughModels(t *testing.T) {
+	envContent := `BIG_MODEL=gpt-oss:120b
+SMALL_MODEL=qwen3:8b
+CORRECTION_MODEL=qwen3:8b
+BIG_MODEL_ENDPOINT=http://localhost:11434/v1/chat/completions
+SMALL_MODEL_ENDPOINT=http://localhost:11434/v1/chat/completions
+TOOL_CORRECTION_ENDPOINT=http://localhost:11434/v1/chat/completions
+BIG_MODEL_API_KEY=test-key
+SMALL_MODEL_API_KEY=test-key
+TOOL_CORRECTION_API_KEY=test-key
+LOG_FULL_TOOLS=false
+CONVERSATION_TRUNCATION=0
+HARMONY_MODELS=gpt-oss*
+PASSTHROUGH_MODELS=GPT-OSS:20B, qwen3:*
+`
+	err := os.WriteFile(".env", []byte(envContent), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create test .env file: %v", err)
+	}
+	defer os.Remove(".env")
+
+	cfg, err := LoadConfigWithEnv()
+	if err != nil {
+		t.Fatalf("LoadConfigWithEnv() failed: %v", err)
+	}
+
+	for model, want := range map[string]bool{
+		"gpt-oss:20b":  true,
+		"qwen3:8b":     true,
+		"gpt-oss:120b": false,
+	} {
+		if got := cfg.IsPassthroughModel(model); got != want {
+			t.Errorf("IsPassthroughModel(%q) = %v, want %v", model, got, want)
+		}
+	}
+	if cfg.IsHarmonyEnabledForModel("gpt-oss:20b") || cfg.IsHarmonyModel("gpt-oss:20b") {
+		t.Error("Expected passthrough models to skip Harmony even when HARMONY_MODELS matches them")
+	}
+	if !cfg.IsHarmonyEnabledForModel("gpt-oss:120b") || !cfg.IsHarmonyModel("gpt-oss:120b") {
+		t.Error("Expected other HARMONY_MODELS to keep Harmony")
+	}
+}
+
+// TestHarmonyTokens tests that HARMONY_*_TOKEN replace the default tokens and
+// that an unusable set fails configuration loading
+func TestHarmonyTokens(t *testing.T) {
+	envContent := `BIG_MODEL=gpt-oss:120b
+SMALL_MODEL=qwen3:8b
+CORRECTION_MODEL=qwen3:8b
+BIG_MODEL_ENDPOINT=http://localhost:11434/v1/chat/completions
+SMALL_MODEL_ENDPOINT=http://localhost:11434/v1/chat/completions
+TOOL_CORRECTION_ENDPOINT=http://localhost:11434/v1/chat/completions
+BIG_MODEL_API_KEY=test-key
+SMALL_MODEL_API_KEY=test-key
+TOOL_CORRECTION_API_KEY=test-key
+LOG_FULL_TOOLS=false
+CONVERSATION_TRUNCATION=0
+HARMONY_END_TOKENS=<|end|>, <|return|>, <|call|>
+`
+	err := os.WriteFile(".env", []byte(envContent), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create test .env file: %v", err)
+	}
+	defer os.Remove(".env")
+
+	cfg, err := LoadConfigWithEnv()
+	if err != nil {
+		t.Fatalf("LoadConfigWithEnv() failed: %v", err)
+	}
+	if cfg.HarmonyTokens.Start != "<|start|>" || cfg.HarmonyTokens.Message != "<|message|>" {
+		t.Errorf("Expected unset tokens to keep their defaults, got %+v", cfg.HarmonyTokens)
+	}
+	if len(cfg.HarmonyTokens.End) != 3 || cfg.HarmonyTokens.End[2] != "<|call|>" {
+		t.Errorf("Expected three end tokens, got %q", cfg.HarmonyTokens.End)
+	}
+
+	if err := os.WriteFile(".env", []byte(envContent+"HARMONY_END_TOKENS= , \n"), 0644); err != nil {
+		t.Fatalf("Failed to update test .env file: %v", err)
+	}
+	if _, err := LoadConfigWithEnv(); err == nil {
+		t.Error("Expected an empty HARMONY_END_TOKENS list to fail configuration loading")
+	}
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Focused views of Config. Subsystems depend on the narrowest interface that
+// covers what they read, so they can be tested with small fakes instead of a
+// fully loaded Config, and a section can later be swapped without touching
+// consumers of the others. *Config implements all of them.
+
+// RoutingConfig maps Claude model names to backends and selects endpoints
+type RoutingConfig interface {
+	MapModelName(ctx context.Context, claudeModel string) string
+	GetBigModelEndpoint() string
+	GetSmallModelEndpoint() string
+	GetHealthySmallModelEndpoint() string
+	IsEndpointHealthy(endpoint string) bool
+	RecordEndpointFailure(endpoint string)
+	RecordEndpointSuccess(endpoint string)
+}
+
+// LoggingConfig controls which requests are logged
+type LoggingConfig interface {
+	IsSmallModelLoggingDisabled() bool
+	GetSmallModelName() string
+}
+
+// HarmonyConfig controls Harmony format parsing of responses
+type HarmonyConfig interface {
+	IsHarmonyParsingEnabled() bool
+	IsHarmonyEnabledForModel(model string) bool
+	IsHarmonyDebugEnabled() bool
+	IsHarmonyStrictModeEnabled() bool
+	GetHarmonyConfiguration() HarmonyConfiguration
+}
+
+// StopReasonConfig maps upstream finish_reason values to Anthropic stop_reason
+type StopReasonConfig interface {
+	StopReasonFor(finishReason string) (string, bool)
+}
+
+// CorrectionRetryConfig sets how often the correction service retries a tool
+// call and what happens to calls it cannot fix
+type CorrectionRetryConfig interface {
+	GetCorrectionMaxRetries() int
+	GetCorrectionExhaustedPolicy() string
+}
+
+// CorrectionContextConfig bounds the conversation tail added to correction
+// prompts: at most messages messages and tokens estimated tokens
+type CorrectionContextConfig interface {
+	CorrectionContextLimits() (messages, tokens int)
+}
+
+// ValidationSeverityConfig sets whether violations of each validation rule
+// are fixed, only logged, or blocked
+type ValidationSeverityConfig interface {
+	ValidationSeverityFor(rule string) string
+}
+
+// EndpointTransportConfig builds HTTP clients that apply the per-endpoint
+// transport settings of endpoints.yaml
+type EndpointTransportConfig interface {
+	HTTPClient(endpoint string, fallbackTimeout time.Duration) *http.Client
+}
+
+// Handling of upstream responses with several choices (MULTI_CHOICE_POLICY)
+const (
+	MultiChoiceFirst = "first" // Use the choice with the lowest index
+	MultiChoiceMerge = "merge" // Concatenate text and tool calls of every choice
+	MultiChoiceError = "error" // Reject the response as invalid
+)
+
+// Reaction to a detected tool-call loop (LOOP_DETECTION_ACTION)
+const (
+	LoopActionRespond    = "respond"     // Answer with a loop-breaking message instead of calling the backend
+	LoopActionNudge      = "nudge"       // Warn the model in the system message and call the backend
+	LoopActionToolResult = "tool_result" // Replace the repeated call's result with a warning and call the backend
+)
+
+// Handling of a tool call identical to the one before it (DUPLICATE_TOOL_CALL_POLICY)
+const (
+	DuplicateToolCallDrop = "drop" // Remove the repeated call
+	DuplicateToolCallWarn = "warn" // Keep it and log a warning
+	DuplicateToolCallKeep = "keep" // Do not check for duplicates
+)
+
+// Handling of tool calls still invalid when CORRECTION_BUDGET_MS runs out (CORRECTION_BUDGET_POLICY)
+const (
+	CorrectionBudgetForward = "forward" // Send them to the client uncorrected
+	CorrectionBudgetBlock   = "block"   // Replace them with a text note so the client never runs them
+)
+
+// Handling of tool calls still invalid after CORRECTION_MAX_RETRIES attempts (CORRECTION_EXHAUSTED_POLICY)
+const (
+	CorrectionExhaustedOriginal = "original" // Return the call as the model produced it
+	CorrectionExhaustedDrop     = "drop"     // Remove the call from the response
+	CorrectionExhaustedBlock    = "block"    // Replace the call with a text note so the client never runs it
+)
+
+// Tool correction backends (CORRECTION_BACKEND)
+const (
+	CorrectionBackendLLM    = "llm"    // Rule-based stages, then the correction model
+	CorrectionBackendRules  = "rules"  // Rule-based stages only, no network calls
+	CorrectionBackendRemote = "remote" // External correction service at CORRECTION_REMOTE_URL
+)
+
+// CorrectionConfig is what the tool correction service needs: correction
+// endpoints with health reporting and the tool choice correction switch
+type CorrectionConfig interface {
+	GetToolCorrectionEndpoint() string
+	GetHealthyToolCorrectionEndpoint() string
+	RecordEndpointFailure(endpoint string)
+	RecordEndpointSuccess(endpoint string)
+	GetEnableToolChoiceCorrection() bool
+}
+
+var (
+	_ RoutingConfig    = (*Config)(nil)
+	_ LoggingConfig    = (*Config)(nil)
+	_ HarmonyConfig    = (*Config)(nil)
+	_ CorrectionConfig = (*Config)(nil)
+	_ StopReasonConfig = (*Config)(nil)
+
+	_ EndpointTransportConfig = (*Config)(nil)
+	_ CorrectionRetryConfig   = (*Config)(nil)
+	_ CorrectionContextConfig = (*Config)(nil)
+
+	_ ValidationSeverityConfig = (*Config)(nil)
+)
+
+// IsSmallModelLoggingDisabled returns whether small model (Haiku) requests skip logging
+func (c *Config) IsSmallModelLoggingDisabled() bool {
+	return c.DisableSmallModelLogging
+}
+
+// GetSmallModelName returns the backend model serving Haiku requests
+func (c *Config) GetSmallModelName() string {
+	return c.SmallModel
+}
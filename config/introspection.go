@@ -0,0 +1,434 @@
+package config
+
+import (
+	"claude-proxy/parser"
+	"claude-proxy/types"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// OverrideFileStatus describes whether an optional YAML override file was
+// found and applied when the configuration was loaded
+type OverrideFileStatus struct {
+	Path    string `json:"path"`
+	Status  string `json:"status"` // "loaded", "missing" or "error"
+	Error   string `json:"error,omitempty"`
+	Entries int    `json:"entries"`
+}
+
+// EndpointStatus is the sanitized view of a configured endpoint
+type EndpointStatus struct {
+	URL          string `json:"url"`
+	Healthy      bool   `json:"healthy"`
+	FailureCount int    `json:"failure_count"`
+	CircuitOpen  bool   `json:"circuit_open"`
+}
+
+// SanitizedConfig is the effective runtime configuration with all secrets masked.
+// It is intended for operator introspection (GET /admin/config) and never
+// contains raw API keys.
+type SanitizedConfig struct {
+	Source struct {
+		EnvFile  string    `json:"env_file"`
+		LoadedAt time.Time `json:"loaded_at"`
+	} `json:"source"`
+
+	Port   string   `json:"port"`
+	Listen []string `json:"listen"`
+
+	Models struct {
+		Big        string `json:"big"`
+		Small      string `json:"small"`
+		Correction string `json:"correction"`
+	} `json:"models"`
+
+	Endpoints struct {
+		Big            []EndpointStatus `json:"big"`
+		Small          []EndpointStatus `json:"small"`
+		ToolCorrection []EndpointStatus `json:"tool_correction"`
+	} `json:"endpoints"`
+
+	EndpointSpecs map[string][]string `json:"endpoint_specs,omitempty"`
+
+	APIKeys struct {
+		Big            string `json:"big"`
+		Small          string `json:"small"`
+		ToolCorrection string `json:"tool_correction"`
+	} `json:"api_keys"`
+
+	FeatureFlags map[string]bool `json:"feature_flags"`
+
+	Logging struct {
+		LogLevel               string                `json:"log_level"`
+		ComponentLogLevels     string                `json:"component_log_levels,omitempty"`
+		ConversationLogLevel   string                `json:"conversation_log_level"`
+		ConversationTruncation int                   `json:"conversation_truncation"`
+		TruncationTokens       int                   `json:"conversation_truncation_tokens"`
+		ConversationLogFilter  ConversationLogFilter `json:"conversation_log_filter"`
+	} `json:"logging"`
+
+	Retention struct {
+		ConversationDays  int `json:"conversation_days"`
+		ConversationMaxMB int `json:"conversation_max_mb"`
+		BatchDays         int `json:"batch_days"`
+		JournalDays       int `json:"journal_days"`
+		StatsDays         int `json:"stats_days"`
+		IntervalMinutes   int `json:"interval_minutes"`
+	} `json:"retention"`
+
+	DefaultConnectionTimeout int        `json:"default_connection_timeout"`
+	ServerReadTimeout        int        `json:"server_read_timeout"`
+	ServerWriteTimeout       int        `json:"server_write_timeout"`
+	ServerIdleTimeout        int        `json:"server_idle_timeout"`
+	AlertWebhook             string     `json:"alert_webhook,omitempty"`
+	StatsDBPath              string     `json:"stats_db_path,omitempty"`
+	CircuitStatePath         string     `json:"circuit_state_path,omitempty"`
+	MetricsStatsDAddr        string     `json:"metrics_statsd_addr,omitempty"`
+	AdminGRPCAddr            string     `json:"admin_grpc_addr,omitempty"`
+	CorrectionBackend        string     `json:"correction_backend"`
+	CorrectionBudgetMs       int        `json:"correction_budget_ms"`
+	CorrectionBudgetPolicy   string     `json:"correction_budget_policy"`
+	PromptsDir               string     `json:"prompts_dir"`
+	CorrectionMaxRetries     int        `json:"correction_max_retries"`
+	CorrectionExhausted      string     `json:"correction_exhausted_policy"`
+	CorrectionLearnThreshold int        `json:"correction_learn_threshold"`
+	CorrectionContextMsgs    int        `json:"correction_context_messages"`
+	CorrectionContextTokens  int        `json:"correction_context_tokens"`
+	ToolResultMaxTokens      int        `json:"tool_result_max_tokens"`
+	ValidationFeedbackRounds int        `json:"validation_feedback_rounds"`
+	ApprovalTimeoutSeconds   int        `json:"approval_timeout_seconds"`
+	MultiChoicePolicy        string     `json:"multi_choice_policy"`
+	DuplicateToolCallPolicy  string     `json:"duplicate_tool_call_policy"`
+	LoopDetectionThreshold   int        `json:"loop_detection_threshold"`
+	LoopDetectionAction      string     `json:"loop_detection_action"`
+	MaxToolIterations        int        `json:"max_tool_iterations"`
+	CORS                     CORSConfig `json:"cors"`
+	AccessLog                string     `json:"access_log,omitempty"`
+
+	StopReasons          map[string]string                `json:"stop_reasons,omitempty"`
+	ValidationSeverity   map[string]string                `json:"validation_severity,omitempty"`
+	AdditionalProperties types.AdditionalPropertiesPolicy `json:"additional_properties"`
+
+	ModelPricing        map[string]ModelPrice `json:"model_pricing"`
+	SessionBudgetTokens int                   `json:"session_budget_tokens"`
+	SessionBudgetUSD    float64               `json:"session_budget_usd"`
+	SpendLimits         SpendLimits           `json:"spend_limits"`
+	StreamRateLimits    StreamRateLimits      `json:"stream_rate_limits"`
+	ClientModelAccess   ModelAccess           `json:"client_model_access,omitempty"`
+	MaxConcurrentReqs   int                   `json:"max_concurrent_requests"`
+	WarmupEnabled       bool                  `json:"warmup_enabled"`
+	RequestHistorySize  int                   `json:"request_history_size"`
+	DiagnosticDumpFile  string                `json:"diagnostic_dump_file,omitempty"`
+
+	ConversationMaskDetectors []string        `json:"conversation_mask_detectors"`
+	RedactionPatterns         int             `json:"redaction_patterns"` // Custom patterns may name what they mask, so only the count
+	HarmonyModels             []string        `json:"harmony_models,omitempty"`
+	PassthroughModels         []string        `json:"passthrough_models,omitempty"`
+	HarmonyReasoningLevel     string          `json:"harmony_reasoning_level"`
+	HarmonyKnowledgeCutoff    string          `json:"harmony_knowledge_cutoff"`
+	HarmonyTokens             parser.TokenSet `json:"harmony_tokens"`
+
+	SkipTools                []string                    `json:"skip_tools"`
+	ToolDescriptionOverrides int                         `json:"tool_description_overrides"`
+	ModelSystemPrompts       []string                    `json:"model_system_prompts"` // Models with injected instructions
+	ModelProfiles            map[string]ModelProfile     `json:"model_profiles"`
+	ProviderProfiles         map[string]ProviderProfile  `json:"provider_profiles"`
+	EndpointSettings         map[string]EndpointSettings `json:"endpoint_settings,omitempty"`
+	ToolPolicies             []ToolPolicy                `json:"tool_policies,omitempty"`
+	OverrideFiles            []OverrideFileStatus        `json:"override_files"`
+}
+
+// Sanitized returns a snapshot of the effective configuration that is safe to
+// expose over HTTP: API keys are masked with maskAPIKey and URLs carrying
+// credentials (such as Slack webhooks) are reduced to scheme and host.
+//
+// Endpoint lists are returned in their current (possibly health-reordered)
+// order together with circuit breaker state.
+//
+// Thread Safety: Endpoint slices are copied under the rotation mutex.
+func (c *Config) Sanitized() SanitizedConfig {
+	var s SanitizedConfig
+
+	s.Source.EnvFile = c.envFilePath
+	s.Source.LoadedAt = c.loadedAt
+	s.Port = c.Port
+	for _, address := range c.GetListenAddresses() {
+		s.Listen = append(s.Listen, address.String())
+	}
+
+	s.Models.Big = c.BigModel
+	s.Models.Small = c.SmallModel
+	s.Models.Correction = c.CorrectionModel
+
+	c.mutex.Lock()
+	bigEndpoints := append([]string(nil), c.BigModelEndpoints...)
+	smallEndpoints := append([]string(nil), c.SmallModelEndpoints...)
+	correctionEndpoints := append([]string(nil), c.ToolCorrectionEndpoints...)
+	c.mutex.Unlock()
+
+	for role, specs := range c.EndpointSpecs {
+		if s.EndpointSpecs == nil {
+			s.EndpointSpecs = make(map[string][]string)
+		}
+		s.EndpointSpecs[role] = append([]string(nil), specs...)
+	}
+	s.Endpoints.Big = c.endpointStatuses(bigEndpoints)
+	s.Endpoints.Small = c.endpointStatuses(smallEndpoints)
+	s.Endpoints.ToolCorrection = c.endpointStatuses(correctionEndpoints)
+
+	s.APIKeys.Big = maskOptionalAPIKey(c.BigModelAPIKey)
+	s.APIKeys.Small = maskOptionalAPIKey(c.SmallModelAPIKey)
+	s.APIKeys.ToolCorrection = maskOptionalAPIKey(c.ToolCorrectionAPIKey)
+
+	s.FeatureFlags = map[string]bool{
+		"tool_correction_enabled":         c.ToolCorrectionEnabled,
+		"disable_small_model_correction":  c.DisableSmallModelCorrection,
+		"enable_tool_choice_correction":   c.EnableToolChoiceCorrection,
+		"handle_empty_tool_results":       c.HandleEmptyToolResults,
+		"handle_empty_user_messages":      c.HandleEmptyUserMessages,
+		"tool_result_repair":              c.ToolResultRepair,
+		"forward_thinking_blocks":         c.ForwardThinkingBlocks,
+		"big_model_circuit_breaker":       c.BigModelCircuitBreaker,
+		"print_system_message":            c.PrintSystemMessage,
+		"print_tool_schemas":              c.PrintToolSchemas,
+		"disable_small_model_logging":     c.DisableSmallModelLogging,
+		"disable_tool_correction_logging": c.DisableToolCorrectionLogging,
+		"conversation_logging_enabled":    c.ConversationLoggingEnabled,
+		"conversation_mask_sensitive":     c.ConversationMaskSensitive,
+		"conversation_log_full_tools":     c.ConversationLogFullTools,
+		"harmony_parsing_enabled":         c.HarmonyParsingEnabled,
+		"harmony_debug":                   c.HarmonyDebug,
+		"harmony_strict_mode":             c.HarmonyStrictMode,
+		"harmony_developer_role":          c.HarmonyDeveloperRole,
+		"harmony_system_preamble":         c.HarmonySystemPreamble,
+		"stats_persistence_enabled":       c.StatsPersistenceEnabled,
+		"tool_schema_minify_enabled":      c.ToolSchemaMinifyEnabled,
+		"tool_schema_drift_enabled":       c.ToolSchemaDriftEnabled,
+		"stream_include_usage":            c.StreamIncludeUsage,
+		"sse_verify_enabled":              c.SSEVerifyEnabled,
+		"proxy_debug_header_enabled":      c.ProxyDebugHeaderEnabled,
+		"no_correction_header_enabled":    c.NoCorrectionHeaderEnabled,
+		"beta_minify_tools":               c.BetaMinifyTools,
+		"batches_enabled":                 c.BatchesEnabled,
+		"conversation_search_enabled":     c.ConversationSearchEnabled,
+		"request_journal_enabled":         c.RequestJournalEnabled,
+		"correction_auto_promote":         c.CorrectionAutoPromote,
+	}
+
+	s.Logging.LogLevel = c.LogLevel
+	s.Logging.ComponentLogLevels = c.ComponentLogLevels
+	s.Logging.ConversationLogLevel = c.ConversationLogLevel
+	s.Logging.ConversationTruncation = c.ConversationTruncation
+	s.Logging.TruncationTokens = c.ConversationTruncationTokens
+	s.Logging.ConversationLogFilter = c.ConversationLogFilter
+
+	s.Retention.ConversationDays = c.ConversationRetentionDays
+	s.Retention.ConversationMaxMB = c.ConversationMaxMB
+	s.Retention.BatchDays = c.BatchRetentionDays
+	s.Retention.JournalDays = c.JournalRetentionDays
+	s.Retention.StatsDays = c.StatsRetentionDays
+	s.Retention.IntervalMinutes = c.RetentionIntervalMinutes
+
+	s.DefaultConnectionTimeout = c.DefaultConnectionTimeout
+	s.ServerReadTimeout = c.ServerReadTimeout
+	s.ServerWriteTimeout = c.ServerWriteTimeout
+	s.ServerIdleTimeout = c.ServerIdleTimeout
+	s.AlertWebhook = maskURL(c.AlertWebhookURL)
+	if c.StatsPersistenceEnabled {
+		s.StatsDBPath = c.StatsDBPath
+	}
+	if c.CircuitStateTTLSeconds > 0 {
+		s.CircuitStatePath = c.CircuitStatePath
+	}
+	s.MetricsStatsDAddr = c.MetricsStatsDAddr
+	s.AdminGRPCAddr = c.AdminGRPCAddr
+	s.CORS = c.CORS
+	s.AccessLog = c.AccessLog
+	s.StopReasons = c.StopReasons
+	s.CorrectionBackend = c.CorrectionBackend
+	s.CorrectionBudgetMs = c.CorrectionBudgetMs
+	s.CorrectionBudgetPolicy = c.CorrectionBudgetPolicy
+	s.PromptsDir = c.PromptsDir
+	s.CorrectionMaxRetries = c.CorrectionMaxRetries
+	s.CorrectionExhausted = c.CorrectionExhaustedPolicy
+	s.CorrectionLearnThreshold = c.CorrectionLearnThreshold
+	s.CorrectionContextMsgs = c.CorrectionContextMessages
+	s.CorrectionContextTokens = c.CorrectionContextTokens
+	s.ToolResultMaxTokens = c.ToolResultMaxTokens
+	s.ValidationFeedbackRounds = c.ValidationFeedbackRounds
+	s.ApprovalTimeoutSeconds = c.ApprovalTimeoutSeconds
+	s.ValidationSeverity = c.ValidationSeverity
+	s.AdditionalProperties = c.AdditionalProperties
+	s.MultiChoicePolicy = c.MultiChoicePolicy
+	s.DuplicateToolCallPolicy = c.DuplicateToolCallPolicy
+	s.LoopDetectionThreshold = c.LoopDetectionThreshold
+	s.LoopDetectionAction = c.LoopDetectionAction
+	s.MaxToolIterations = c.MaxToolIterations
+
+	s.SessionBudgetTokens = c.SessionBudgetTokens
+	s.SessionBudgetUSD = c.SessionBudgetUSD
+	if len(c.ClientModelAccess) > 0 {
+		s.ClientModelAccess = make(ModelAccess, len(c.ClientModelAccess))
+		for key, models := range c.ClientModelAccess {
+			s.ClientModelAccess[key] = append([]string(nil), models...)
+		}
+	}
+	s.MaxConcurrentReqs = c.MaxConcurrentRequests
+	s.WarmupEnabled = c.WarmupEnabled
+	s.RequestHistorySize = c.RequestHistorySize
+	s.DiagnosticDumpFile = c.DiagnosticDumpFile
+	s.HarmonyModels = c.HarmonyModels
+	s.PassthroughModels = c.PassthroughModels
+	s.HarmonyReasoningLevel = c.HarmonyReasoningLevel
+	s.HarmonyKnowledgeCutoff = c.HarmonyKnowledgeCutoff
+	s.HarmonyTokens = c.HarmonyTokens
+	s.StreamRateLimits = c.StreamRateLimits
+	s.SpendLimits = SpendLimits{Default: c.SpendLimits.Default}
+	if len(c.SpendLimits.Keys) > 0 {
+		s.SpendLimits.Keys = make(map[string]SpendLimit, len(c.SpendLimits.Keys))
+		for key, limit := range c.SpendLimits.Keys {
+			s.SpendLimits.Keys[key] = limit
+		}
+	}
+	s.ModelPricing = make(map[string]ModelPrice, len(c.ModelPricing))
+	for model, price := range c.ModelPricing {
+		s.ModelPricing[model] = price
+	}
+
+	s.ConversationMaskDetectors = append([]string{}, c.ConversationMaskDetectors...)
+	s.RedactionPatterns = len(c.RedactionPatterns)
+
+	s.SkipTools = append([]string{}, c.SkipTools...)
+	s.ToolDescriptionOverrides = len(c.ToolDescriptions)
+	s.ModelSystemPrompts = make([]string, 0, len(c.ModelSystemPrompts))
+	for model := range c.ModelSystemPrompts {
+		s.ModelSystemPrompts = append(s.ModelSystemPrompts, model)
+	}
+	sort.Strings(s.ModelSystemPrompts)
+	s.ModelProfiles = make(map[string]ModelProfile, len(c.ModelProfiles))
+	for model, profile := range c.ModelProfiles {
+		s.ModelProfiles[model] = profile
+	}
+	s.ProviderProfiles = make(map[string]ProviderProfile, len(builtinProviderProfiles)+len(c.ProviderProfiles))
+	for name, profile := range builtinProviderProfiles {
+		s.ProviderProfiles[name] = profile
+	}
+	for name, profile := range c.ProviderProfiles {
+		s.ProviderProfiles[name] = profile
+	}
+	if len(c.EndpointSettings) > 0 {
+		s.EndpointSettings = make(map[string]EndpointSettings, len(c.EndpointSettings))
+		for prefix, settings := range c.EndpointSettings {
+			// Header values may carry credentials (custom auth schemes, Helicone keys)
+			if len(settings.Headers) > 0 {
+				headers := make(map[string]string, len(settings.Headers))
+				for name, value := range settings.Headers {
+					headers[name] = maskAPIKey(value)
+				}
+				settings.Headers = headers
+			}
+			s.EndpointSettings[prefix] = settings
+		}
+	}
+	s.ToolPolicies = append([]ToolPolicy(nil), c.ToolPolicies...)
+	s.OverrideFiles = append([]OverrideFileStatus{}, c.overrideFiles...)
+
+	return s
+}
+
+// Hash fingerprints the effective configuration so deployments can tell
+// whether two instances run with the same settings. It covers the sanitized
+// view, so secrets only count through their masked form, and leaves out
+// what changes at runtime: load time, circuit states and endpoint order.
+func (c *Config) Hash() string {
+	s := c.Sanitized()
+	s.Source.LoadedAt = time.Time{}
+	for _, statuses := range [][]EndpointStatus{s.Endpoints.Big, s.Endpoints.Small, s.Endpoints.ToolCorrection} {
+		for i := range statuses {
+			statuses[i] = EndpointStatus{URL: statuses[i].URL}
+		}
+		sort.Slice(statuses, func(i, j int) bool { return statuses[i].URL < statuses[j].URL })
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// endpointStatuses pairs each endpoint with its circuit breaker state
+func (c *Config) endpointStatuses(endpoints []string) []EndpointStatus {
+	statuses := make([]EndpointStatus, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		status := EndpointStatus{URL: maskURLCredentials(endpoint), Healthy: true}
+		if c.HealthManager != nil {
+			status.Healthy = c.HealthManager.IsHealthy(endpoint)
+			status.FailureCount, status.CircuitOpen, _, _ = c.HealthManager.GetHealthDebug(endpoint)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// maskOptionalAPIKey masks a key but keeps "unset" distinguishable from a short key
+func maskOptionalAPIKey(apiKey string) string {
+	if apiKey == "" {
+		return ""
+	}
+	return maskAPIKey(apiKey)
+}
+
+// maskURL reduces a URL that may embed a secret in its path to scheme://host/***
+func maskURL(rawURL string) string {
+	if rawURL == "" {
+		return ""
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return "***"
+	}
+	return parsed.Scheme + "://" + parsed.Host + "/***"
+}
+
+// maskURLCredentials strips user:password from an endpoint URL while keeping the path
+func maskURLCredentials(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.User == nil {
+		return rawURL
+	}
+	parsed.User = url.User("***")
+	return parsed.String()
+}
+
+// overrideFileStatusFor builds the load status for an optional override file
+func overrideFileStatusFor(path string, loadErr error, entries int) OverrideFileStatus {
+	status := OverrideFileStatus{Path: path, Entries: entries}
+	if absPath, err := filepath.Abs(path); err == nil {
+		status.Path = absPath
+	}
+
+	switch {
+	case loadErr != nil:
+		status.Status = "error"
+		status.Error = loadErr.Error()
+	case fileMissing(path):
+		status.Status = "missing"
+	default:
+		status.Status = "loaded"
+	}
+	return status
+}
+
+// fileMissing reports whether path does not exist
+func fileMissing(path string) bool {
+	_, err := os.Stat(path)
+	return os.IsNotExist(err)
+}
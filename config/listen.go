@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// unixSocketMode lets the proxy's user and group connect to a unix listener
+const unixSocketMode = 0660
+
+// ListenAddress is a socket the HTTP server accepts connections on
+type ListenAddress struct {
+	Network string // "tcp" or "unix"
+	Address string // host:port, or the socket path for unix
+}
+
+// String formats the address the way LISTEN accepts it
+func (a ListenAddress) String() string {
+	return a.Network + ":" + a.Address
+}
+
+// ParseListenAddress parses one LISTEN entry: "unix:/path/to.sock",
+// "tcp:host:port" or a bare "host:port" / ":port"
+func ParseListenAddress(spec string) (ListenAddress, error) {
+	spec = strings.TrimSpace(spec)
+	switch {
+	case strings.HasPrefix(spec, "unix:"):
+		path := strings.TrimPrefix(spec, "unix:")
+		if path == "" {
+			return ListenAddress{}, fmt.Errorf("unix listen address needs a socket path, got: %s", spec)
+		}
+		return ListenAddress{Network: "unix", Address: path}, nil
+	case strings.HasPrefix(spec, "tcp:"):
+		spec = strings.TrimPrefix(spec, "tcp:")
+	}
+	if _, _, err := net.SplitHostPort(spec); err != nil {
+		return ListenAddress{}, fmt.Errorf("listen address must be host:port, tcp:host:port or unix:/path, got: %s", spec)
+	}
+	return ListenAddress{Network: "tcp", Address: spec}, nil
+}
+
+// Listen opens the socket. A unix socket left behind by an unclean exit is
+// removed first; any other file at the path is an error. The socket file is
+// removed again when the listener is closed.
+func (a ListenAddress) Listen() (net.Listener, error) {
+	if a.Network != "unix" {
+		return net.Listen(a.Network, a.Address)
+	}
+
+	if info, err := os.Lstat(a.Address); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", a.Address)
+		}
+		if err := os.Remove(a.Address); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket %s: %v", a.Address, err)
+		}
+	}
+	listener, err := net.Listen("unix", a.Address)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(a.Address, unixSocketMode); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to set permissions on %s: %v", a.Address, err)
+	}
+	return listener, nil
+}
+
+// GetListenAddresses returns the sockets to serve on: the LISTEN entries, or
+// TCP on Port when LISTEN is not set
+func (c *Config) GetListenAddresses() []ListenAddress {
+	if len(c.ListenAddresses) == 0 {
+		return []ListenAddress{{Network: "tcp", Address: ":" + c.Port}}
+	}
+	return c.ListenAddresses
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestParseListenAddress tests the LISTEN entry formats
+func TestParseListenAddress(t *testing.T) {
+	valid := map[string]ListenAddress{
+		":3456":                        {Network: "tcp", Address: ":3456"},
+		"127.0.0.1:3456":               {Network: "tcp", Address: "127.0.0.1:3456"},
+		"tcp:[::1]:3456":               {Network: "tcp", Address: "[::1]:3456"},
+		" unix:/run/simple-proxy.sock": {Network: "unix", Address: "/run/simple-proxy.sock"},
+	}
+	for spec, expected := range valid {
+		address, err := ParseListenAddress(spec)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", spec, err)
+			continue
+		}
+		if address != expected {
+			t.Errorf("%q: expected %+v, got %+v", spec, expected, address)
+		}
+	}
+
+	for _, spec := range []string{"3456", "unix:", "tcp:localhost", "http://localhost:3456"} {
+		if _, err := ParseListenAddress(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+
+	cfg := &Config{Port: "3456"}
+	if addresses := cfg.GetListenAddresses(); len(addresses) != 1 || addresses[0].String() != "tcp::3456" {
+		t.Errorf("Expected TCP on Port by default, got %+v", addresses)
+	}
+}
+
+// TestListenUnixSocket tests serving HTTP on a unix socket, including stale socket cleanup
+func TestListenUnixSocket(t *testing.T) {
+	dir, err := os.MkdirTemp("", "listen")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	// Kept short: unix socket paths are limited to about 100 bytes
+	path := filepath.Join(dir, "p.sock")
+	address := ListenAddress{Network: "unix", Address: path}
+
+	// Leave a stale socket behind, as a crashed proxy would
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Failed to create stale socket: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	listener, err := address.Listen()
+	if err != nil {
+		t.Fatalf("Failed to listen on %s: %v", path, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != unixSocketMode {
+		t.Errorf("Expected socket mode %o, got %v (%v)", unixSocketMode, info, err)
+	}
+
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})}
+	go server.Serve(listener)
+
+	client := &http.Client{Transport: &http.Transport{
+		Dial: func(_, _ string) (net.Conn, error) { return net.Dial("unix", path) },
+	}}
+	resp, err := client.Get("http://unix/health")
+	if err != nil {
+		t.Fatalf("Request over unix socket failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected 204, got %d", resp.StatusCode)
+	}
+
+	server.Close()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected socket file removed on close, got %v", err)
+	}
+
+	// Regular files are never removed
+	if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, err := address.Listen(); err == nil {
+		t.Error("Expected an error for a non-socket file")
+	}
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultToolDescriptionMaxChars is the description budget used when
+// TOOL_SCHEMA_MINIFY is enabled without TOOL_SCHEMA_MINIFY_MAX_CHARS
+const DefaultToolDescriptionMaxChars = 400
+
+// Tool schema minification modes
+const (
+	ToolMinifyTruncate  = "truncate"  // Strip examples and cut at a sentence boundary
+	ToolMinifySummarize = "summarize" // Use cached correction-model summaries, truncating until cached
+)
+
+// parseToolMinifyLimits parses "Tool=chars,..." per-tool description budgets.
+// A limit of 0 keeps that tool's description unminified.
+func parseToolMinifyLimits(spec string) (map[string]int, error) {
+	limits := make(map[string]int)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("TOOL_SCHEMA_MINIFY_LIMITS entry must be Tool=chars, got: %s", entry)
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("TOOL_SCHEMA_MINIFY_LIMITS limit must be a non-negative number, got: %s", entry)
+		}
+		limits[strings.TrimSpace(parts[0])] = limit
+	}
+	return limits, nil
+}
+
+// ToolDescriptionLimit returns the description budget for a tool when schema
+// minification is enabled. ok is false when the tool must be sent unminified.
+func (c *Config) ToolDescriptionLimit(toolName string) (limit int, ok bool) {
+	if !c.ToolSchemaMinifyEnabled {
+		return 0, false
+	}
+	return c.ToolDescriptionBudget(toolName)
+}
+
+// ToolDescriptionBudget returns the configured description budget for a tool
+// regardless of TOOL_SCHEMA_MINIFY, for callers that enable minification per
+// request. ok is false when the tool is configured to keep its full description.
+func (c *Config) ToolDescriptionBudget(toolName string) (limit int, ok bool) {
+	if toolLimit, exists := c.ToolSchemaMinifyLimits[toolName]; exists {
+		return toolLimit, toolLimit > 0
+	}
+	if c.ToolSchemaMinifyMaxChars > 0 {
+		return c.ToolSchemaMinifyMaxChars, true
+	}
+	return DefaultToolDescriptionMaxChars, true
+}
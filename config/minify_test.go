@@ -0,0 +1,40 @@
+package config
+
+import "testing"
+
+// TestParseToolMinifyLimits tests per-tool description budget parsing
+func TestParseToolMinifyLimits(t *testing.T) {
+	limits, err := parseToolMinifyLimits("Bash=800, Task=0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if limits["Bash"] != 800 || limits["Task"] != 0 || len(limits) != 2 {
+		t.Errorf("Unexpected limits: %v", limits)
+	}
+
+	for _, invalid := range []string{"Bash", "Bash=long", "=100", "Bash=-1"} {
+		if _, err := parseToolMinifyLimits(invalid); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
+
+// TestToolDescriptionLimit tests default, per-tool and opt-out budgets
+func TestToolDescriptionLimit(t *testing.T) {
+	cfg := GetDefaultConfig()
+	if _, ok := cfg.ToolDescriptionLimit("Bash"); ok {
+		t.Error("Expected no minification when TOOL_SCHEMA_MINIFY is disabled")
+	}
+
+	cfg.ToolSchemaMinifyEnabled = true
+	cfg.ToolSchemaMinifyLimits = map[string]int{"Bash": 800, "Task": 0}
+	if limit, ok := cfg.ToolDescriptionLimit("Read"); !ok || limit != DefaultToolDescriptionMaxChars {
+		t.Errorf("Expected default limit, got %d (%v)", limit, ok)
+	}
+	if limit, ok := cfg.ToolDescriptionLimit("Bash"); !ok || limit != 800 {
+		t.Errorf("Expected per-tool limit 800, got %d (%v)", limit, ok)
+	}
+	if _, ok := cfg.ToolDescriptionLimit("Task"); ok {
+		t.Error("Expected Task to be excluded from minification")
+	}
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Special entries of CLIENT_MODEL_ACCESS
+const (
+	ModelAccessBigModel   = "BIG_MODEL"   // Stands for the configured BIG_MODEL
+	ModelAccessSmallModel = "SMALL_MODEL" // Stands for the configured SMALL_MODEL
+	ModelAccessOtherKeys  = "*"           // Keys not listed, and requests without a key
+)
+
+// ModelAccess lists the mapped models each client key ID may use. Keys
+// without an entry, and without a "*" entry to fall back to, may use any model.
+type ModelAccess map[string][]string
+
+// parseModelAccess parses CLIENT_MODEL_ACCESS entries of the form
+// "keyid=model|model" separated by commas, e.g. "3f2a9c0d1e4b5a6f=SMALL_MODEL".
+func parseModelAccess(value string) (ModelAccess, error) {
+	access := make(ModelAccess)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("CLIENT_MODEL_ACCESS entries must be keyid=model|model, got: %s", entry)
+		}
+		var models []string
+		for _, model := range strings.Split(parts[1], "|") {
+			if model = strings.TrimSpace(model); model != "" {
+				models = append(models, model)
+			}
+		}
+		if len(models) == 0 {
+			return nil, fmt.Errorf("CLIENT_MODEL_ACCESS entry lists no models: %s", entry)
+		}
+		access[strings.TrimSpace(parts[0])] = models
+	}
+	return access, nil
+}
+
+// AllowsModel reports whether a client key ID ("" for requests without a
+// key) may send requests to a mapped model
+func (c *Config) AllowsModel(clientKey, mappedModel string) bool {
+	models, exists := c.ClientModelAccess[clientKey]
+	if !exists || clientKey == "" {
+		models, exists = c.ClientModelAccess[ModelAccessOtherKeys]
+	}
+	if !exists {
+		return true
+	}
+	for _, model := range models {
+		switch model {
+		case ModelAccessBigModel:
+			model = c.BigModel
+		case ModelAccessSmallModel:
+			model = c.SmallModel
+		}
+		if model == mappedModel {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package config
+
+import "testing"
+
+// TestModelAccess tests CLIENT_MODEL_ACCESS parsing and per-key model checks
+func TestModelAccess(t *testing.T) {
+	access, err := parseModelAccess("intern=SMALL_MODEL, lead=BIG_MODEL|SMALL_MODEL|gpt-4o, *=qwen2.5-coder:latest")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cfg := GetDefaultConfig()
+	cfg.BigModel = "big-model"
+	cfg.SmallModel = "small-model"
+	cfg.ClientModelAccess = access
+
+	tests := []struct {
+		key, model string
+		allowed    bool
+	}{
+		{"intern", "small-model", true},
+		{"intern", "big-model", false},
+		{"lead", "big-model", true},
+		{"lead", "gpt-4o", true},
+		{"other", "qwen2.5-coder:latest", true},
+		{"other", "big-model", false},
+		{"", "qwen2.5-coder:latest", true},
+		{"", "small-model", false},
+	}
+	for _, tt := range tests {
+		if allowed := cfg.AllowsModel(tt.key, tt.model); allowed != tt.allowed {
+			t.Errorf("AllowsModel(%q, %q) = %v, expected %v", tt.key, tt.model, allowed, tt.allowed)
+		}
+	}
+
+	// Without a "*" entry unlisted keys may use any model
+	delete(cfg.ClientModelAccess, ModelAccessOtherKeys)
+	if !cfg.AllowsModel("other", "big-model") || !cfg.AllowsModel("", "big-model") {
+		t.Error("Expected unlisted keys to be unrestricted")
+	}
+
+	for _, invalid := range []string{"intern", "=SMALL_MODEL", "intern=", "intern=|"} {
+		if _, err := parseModelAccess(invalid); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
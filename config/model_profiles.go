@@ -0,0 +1,238 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ParamRange constrains a numeric sampling parameter for a backend model.
+// Nil bounds are unconstrained; Default is used when the client sends no value.
+type ParamRange struct {
+	Min     *float64 `yaml:"min" json:"min,omitempty"`
+	Max     *float64 `yaml:"max" json:"max,omitempty"`
+	Default *float64 `yaml:"default" json:"default,omitempty"`
+}
+
+// ModelProfile holds per-backend request adjustments, keyed by provider model
+// name in model_profiles.yaml
+type ModelProfile struct {
+	Temperature       *ParamRange `yaml:"temperature" json:"temperature,omitempty"`
+	TopP              *ParamRange `yaml:"topP" json:"top_p,omitempty"`
+	DropParams        []string    `yaml:"dropParams" json:"drop_params,omitempty"`               // Parameters the backend rejects (e.g. temperature, top_p)
+	PassthroughParams []string    `yaml:"passthroughParams" json:"passthrough_params,omitempty"` // Anthropic-only parameters the backend accepts (e.g. top_k on Ollama/vLLM)
+
+	MaxOutputTokens int    `yaml:"maxOutputTokens" json:"max_output_tokens,omitempty"` // Clamp for max_tokens, 0 is unlimited
+	MaxTokensField  string `yaml:"maxTokensField" json:"max_tokens_field,omitempty"`   // "max_tokens" (default) or "max_completion_tokens"
+
+	// What the backend model can handle; unset capabilities are assumed supported
+	Capabilities ModelCapabilities `yaml:"capabilities" json:"capabilities"`
+}
+
+// ModelCapabilities lets requests degrade gracefully for backend models that
+// lack a feature instead of failing upstream. Nil flags mean supported.
+type ModelCapabilities struct {
+	Tools            *bool `yaml:"tools" json:"tools,omitempty"`                         // Native function calling; without it tool definitions are inlined into the system prompt
+	Vision           *bool `yaml:"vision" json:"vision,omitempty"`                       // Image and document input; without it such blocks are rejected
+	JSONMode         *bool `yaml:"jsonMode" json:"json_mode,omitempty"`                  // response_format JSON output; true makes emulated tool calls schema-constrained JSON
+	SystemRole       *bool `yaml:"systemRole" json:"system_role,omitempty"`              // System messages; without it the system prompt is merged into the first user message
+	MaxContextTokens int   `yaml:"maxContextTokens" json:"max_context_tokens,omitempty"` // Prompts estimated above this are rejected as too long, 0 is unlimited
+	ToolEmulation    bool  `yaml:"toolEmulation" json:"tool_emulation,omitempty"`        // Call tools through prompt instructions and calls parsed from the text; implies no native tools
+
+	// Text format emulated tool calls are written in: "tool_call", "react" or "json";
+	// the default is "json" for models declaring jsonMode, "tool_call" otherwise
+	ToolEmulationFormat string `yaml:"toolEmulationFormat" json:"tool_emulation_format,omitempty"`
+}
+
+// Text formats for emulated tool calls
+const (
+	ToolEmulationFormatToolCall = "tool_call" // <tool_call> JSON blocks (Hermes, Qwen)
+	ToolEmulationFormatReAct    = "react"     // Thought/Action/Action Input lines
+	ToolEmulationFormatJSON     = "json"      // One JSON object enforced with response_format json_schema
+)
+
+// SupportsTools reports whether the model accepts native tool definitions
+func (c ModelCapabilities) SupportsTools() bool {
+	return !c.ToolEmulation && (c.Tools == nil || *c.Tools)
+}
+
+// EmulatedToolFormat returns the text format tool calls are emulated in, ""
+// when the model does not emulate tools
+func (c ModelCapabilities) EmulatedToolFormat() string {
+	if !c.ToolEmulation {
+		return ""
+	}
+	if c.ToolEmulationFormat == "" {
+		// A schema the backend enforces beats asking for the format in the prompt
+		if c.JSONMode != nil && *c.JSONMode {
+			return ToolEmulationFormatJSON
+		}
+		return ToolEmulationFormatToolCall
+	}
+	return c.ToolEmulationFormat
+}
+
+// SupportsVision reports whether the model accepts image and document input
+func (c ModelCapabilities) SupportsVision() bool { return c.Vision == nil || *c.Vision }
+
+// SupportsJSONMode reports whether the model honors response_format json_object
+func (c ModelCapabilities) SupportsJSONMode() bool { return c.JSONMode == nil || *c.JSONMode }
+
+// SupportsSystemRole reports whether the model accepts system messages
+func (c ModelCapabilities) SupportsSystemRole() bool { return c.SystemRole == nil || *c.SystemRole }
+
+// Upstream field names for the output token limit
+const (
+	MaxTokensFieldDefault    = "max_tokens"
+	MaxTokensFieldCompletion = "max_completion_tokens"
+)
+
+// ModelProfilesYAML represents the structure of model_profiles.yaml
+type ModelProfilesYAML struct {
+	ModelProfiles map[string]ModelProfile `yaml:"modelProfiles"`
+}
+
+// LoadModelProfiles loads per-model request profiles from model_profiles.yaml.
+//
+// YAML file structure:
+//
+//	modelProfiles:
+//	  "gpt-oss:20b":
+//	    temperature: {min: 0.0, max: 1.0, default: 0.6}
+//	    topP: {max: 0.95}
+//	    maxOutputTokens: 8192
+//	    passthroughParams: [top_k]
+//	  "Qwen/Qwen3-32B":
+//	    passthroughParams: [continue_final_message] # vLLM assistant prefill
+//	  "o3-mini":
+//	    dropParams: [temperature, top_p]
+//	    maxTokensField: max_completion_tokens
+//	  "gemma-2-9b-it":
+//	    capabilities: {tools: false, vision: false, systemRole: false, maxContextTokens: 8192}
+//	  "mistral-7b-instruct-v0.1":
+//	    capabilities: {toolEmulation: true}
+//	  "react-agent-7b":
+//	    capabilities: {toolEmulation: true, toolEmulationFormat: react}
+//	  "phi-3-mini-4k-instruct":
+//	    capabilities: {toolEmulation: true, jsonMode: true} # json format
+//
+// Returns an empty map (no error) if model_profiles.yaml doesn't exist.
+func LoadModelProfiles() (map[string]ModelProfile, error) {
+	return loadModelProfilesFile("model_profiles.yaml")
+}
+
+// loadModelProfilesFile loads and validates model profiles from the given path
+func loadModelProfilesFile(path string) (map[string]ModelProfile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return make(map[string]ModelProfile), nil
+		}
+		return nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	var yamlData ModelProfilesYAML
+	if err := yaml.NewDecoder(file).Decode(&yamlData); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	for model, profile := range yamlData.ModelProfiles {
+		if err := profile.Temperature.validate(); err != nil {
+			return nil, fmt.Errorf("invalid temperature range for %s in %s: %v", model, path, err)
+		}
+		if err := profile.TopP.validate(); err != nil {
+			return nil, fmt.Errorf("invalid topP range for %s in %s: %v", model, path, err)
+		}
+		if profile.MaxOutputTokens < 0 {
+			return nil, fmt.Errorf("invalid maxOutputTokens for %s in %s: must not be negative", model, path)
+		}
+		if profile.Capabilities.MaxContextTokens < 0 {
+			return nil, fmt.Errorf("invalid capabilities.maxContextTokens for %s in %s: must not be negative", model, path)
+		}
+		switch format := profile.Capabilities.ToolEmulationFormat; format {
+		case "", ToolEmulationFormatToolCall, ToolEmulationFormatReAct:
+		case ToolEmulationFormatJSON:
+			if !profile.Capabilities.SupportsJSONMode() {
+				return nil, fmt.Errorf("invalid capabilities.toolEmulationFormat for %s in %s: %q requires jsonMode", model, path, format)
+			}
+		default:
+			return nil, fmt.Errorf("invalid capabilities.toolEmulationFormat for %s in %s: must be %q, %q or %q, got: %s",
+				model, path, ToolEmulationFormatToolCall, ToolEmulationFormatReAct, ToolEmulationFormatJSON, format)
+		}
+		if profile.MaxTokensField != "" && profile.MaxTokensField != MaxTokensFieldDefault && profile.MaxTokensField != MaxTokensFieldCompletion {
+			return nil, fmt.Errorf("invalid maxTokensField for %s in %s: must be %q or %q, got: %s",
+				model, path, MaxTokensFieldDefault, MaxTokensFieldCompletion, profile.MaxTokensField)
+		}
+	}
+	if yamlData.ModelProfiles == nil {
+		return make(map[string]ModelProfile), nil
+	}
+	return yamlData.ModelProfiles, nil
+}
+
+// GetModelProfile returns the profile configured for a backend model
+func (c *Config) GetModelProfile(model string) (ModelProfile, bool) {
+	profile, exists := c.ModelProfiles[model]
+	return profile, exists
+}
+
+// GetModelCapabilities returns what a backend model supports, everything for
+// models without a profile
+func (c *Config) GetModelCapabilities(model string) ModelCapabilities {
+	return c.ModelProfiles[model].Capabilities
+}
+
+// Drops reports whether the backend rejects the named request parameter
+func (p ModelProfile) Drops(param string) bool {
+	for _, dropped := range p.DropParams {
+		if dropped == param {
+			return true
+		}
+	}
+	return false
+}
+
+// Passes reports whether an Anthropic-only parameter should be forwarded to the backend
+func (p ModelProfile) Passes(param string) bool {
+	for _, passed := range p.PassthroughParams {
+		if passed == param {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply returns the value to send upstream: the default when value is nil,
+// otherwise value clamped to [Min, Max]. A nil range passes value through.
+func (r *ParamRange) Apply(value *float64) *float64 {
+	if r == nil {
+		return value
+	}
+	if value == nil {
+		if r.Default == nil {
+			return nil
+		}
+		value = r.Default
+	}
+	clamped := *value
+	if r.Min != nil && clamped < *r.Min {
+		clamped = *r.Min
+	}
+	if r.Max != nil && clamped > *r.Max {
+		clamped = *r.Max
+	}
+	return &clamped
+}
+
+// validate checks that the bounds are ordered
+func (r *ParamRange) validate() error {
+	if r == nil {
+		return nil
+	}
+	if r.Min != nil && r.Max != nil && *r.Min > *r.Max {
+		return fmt.Errorf("min %v is greater than max %v", *r.Min, *r.Max)
+	}
+	return nil
+}
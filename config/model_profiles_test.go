@@ -0,0 +1,112 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestLoadModelProfiles tests profile parsing and range validation
+func TestLoadModelProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model_profiles.yaml")
+	content := `modelProfiles:
+  "gpt-oss:20b":
+    temperature: {min: 0.0, max: 1.0, default: 0.6}
+  "o3-mini":
+    dropParams: [temperature, top_p]
+  "gemma-2-9b-it":
+    capabilities: {tools: false, systemRole: false, maxContextTokens: 8192}
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	profiles, err := loadModelProfilesFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(profiles) != 3 {
+		t.Fatalf("Expected 3 profiles, got %d", len(profiles))
+	}
+	if max := profiles["gpt-oss:20b"].Temperature.Max; max == nil || *max != 1.0 {
+		t.Errorf("Unexpected temperature max: %v", max)
+	}
+	if !profiles["o3-mini"].Drops("top_p") || profiles["o3-mini"].Drops("max_tokens") {
+		t.Errorf("Unexpected drop params: %v", profiles["o3-mini"].DropParams)
+	}
+
+	invalidProfiles := map[string]string{
+		"inverted range":       "modelProfiles:\n  bad:\n    topP: {min: 0.9, max: 0.1}\n",
+		"negative max tokens":  "modelProfiles:\n  bad:\n    maxOutputTokens: -1\n",
+		"unknown tokens field": "modelProfiles:\n  bad:\n    maxTokensField: max_output\n",
+		"negative context":     "modelProfiles:\n  bad:\n    capabilities: {maxContextTokens: -1}\n",
+		"unknown format":       "modelProfiles:\n  bad:\n    capabilities: {toolEmulation: true, toolEmulationFormat: xml}\n",
+		"json without mode":    "modelProfiles:\n  bad:\n    capabilities: {jsonMode: false, toolEmulationFormat: json}\n",
+	}
+	for name, invalid := range invalidProfiles {
+		if err := os.WriteFile(path, []byte(invalid), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+		if _, err := loadModelProfilesFile(path); err == nil {
+			t.Errorf("Expected error for %s", name)
+		}
+	}
+
+	profiles, err = loadModelProfilesFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil || len(profiles) != 0 {
+		t.Errorf("Expected empty profiles for missing file, got %v, %v", profiles, err)
+	}
+}
+
+// TestModelCapabilities tests that unset capabilities default to supported
+func TestModelCapabilities(t *testing.T) {
+	no := false
+	cfg := &Config{ModelProfiles: map[string]ModelProfile{
+		"gemma-2-9b-it": {Capabilities: ModelCapabilities{Tools: &no, SystemRole: &no, MaxContextTokens: 8192}},
+	}}
+
+	gemma := cfg.GetModelCapabilities("gemma-2-9b-it")
+	if gemma.SupportsTools() || gemma.SupportsSystemRole() {
+		t.Errorf("Expected tools and system role to be unsupported, got %+v", gemma)
+	}
+	if !gemma.SupportsVision() || !gemma.SupportsJSONMode() {
+		t.Errorf("Expected unset capabilities to be supported, got %+v", gemma)
+	}
+	other := cfg.GetModelCapabilities("unprofiled-model")
+	if !other.SupportsTools() || !other.SupportsVision() || !other.SupportsSystemRole() || other.MaxContextTokens != 0 {
+		t.Errorf("Expected everything supported for a model without a profile, got %+v", other)
+	}
+	if format := other.EmulatedToolFormat(); format != "" {
+		t.Errorf("Expected no emulated tool format without emulation, got %q", format)
+	}
+	emulated := ModelCapabilities{ToolEmulation: true}
+	if format := emulated.EmulatedToolFormat(); format != ToolEmulationFormatToolCall {
+		t.Errorf("Expected %q by default, got %q", ToolEmulationFormatToolCall, format)
+	}
+	yes := true
+	emulated.JSONMode = &yes
+	if format := emulated.EmulatedToolFormat(); format != ToolEmulationFormatJSON {
+		t.Errorf("Expected %q for a model declaring JSON mode, got %q", ToolEmulationFormatJSON, format)
+	}
+}
+
+// TestParamRangeApply tests defaults and clamping
+func TestParamRangeApply(t *testing.T) {
+	min, max, def := 0.0, 1.0, 0.6
+	r := &ParamRange{Min: &min, Max: &max, Default: &def}
+
+	if got := r.Apply(nil); got == nil || *got != 0.6 {
+		t.Errorf("Expected default 0.6, got %v", got)
+	}
+	high := 1.7
+	if got := r.Apply(&high); got == nil || *got != 1.0 {
+		t.Errorf("Expected clamp to 1.0, got %v", got)
+	}
+	var nilRange *ParamRange
+	if got := nilRange.Apply(&high); got != &high {
+		t.Errorf("Expected nil range to pass value through")
+	}
+	if got := (&ParamRange{Max: &max}).Apply(nil); got != nil {
+		t.Errorf("Expected nil without default, got %v", *got)
+	}
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ModelSystemPromptsYAML represents the structure of model_prompts.yaml
+type ModelSystemPromptsYAML struct {
+	ModelSystemPrompts map[string]string `yaml:"modelSystemPrompts"`
+}
+
+// LoadModelSystemPrompts loads per-model system instructions from model_prompts.yaml.
+//
+// Keys are backend (provider) model names as configured in BIG_MODEL/SMALL_MODEL,
+// values are appended after the Claude Code system prompt for requests routed
+// to that model. This is independent of system_overrides.yaml, which applies
+// the same edits to every model.
+//
+// YAML file structure:
+//
+//	modelSystemPrompts:
+//	  "gpt-oss:20b": "Reasoning: high"
+//	  "qwen3-coder": |
+//	    Always call tools with every required parameter.
+//
+// Returns an empty map (no error) if model_prompts.yaml doesn't exist.
+func LoadModelSystemPrompts() (map[string]string, error) {
+	return loadModelSystemPromptsFile("model_prompts.yaml")
+}
+
+// loadModelSystemPromptsFile loads per-model system prompts from the given path
+func loadModelSystemPromptsFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return make(map[string]string), nil
+		}
+		return nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	var yamlData ModelSystemPromptsYAML
+	if err := yaml.NewDecoder(file).Decode(&yamlData); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	prompts := make(map[string]string, len(yamlData.ModelSystemPrompts))
+	for model, prompt := range yamlData.ModelSystemPrompts {
+		if strings.TrimSpace(prompt) != "" {
+			prompts[model] = prompt
+		}
+	}
+	return prompts, nil
+}
+
+// GetModelSystemPrompt returns the extra system instructions configured for a
+// backend model, or "" when none are configured
+func (c *Config) GetModelSystemPrompt(model string) string {
+	return c.ModelSystemPrompts[model]
+}
+
+// InjectModelSystemPrompt appends the model-specific instructions to a system
+// message. An empty system message yields the instructions alone.
+func InjectModelSystemPrompt(systemContent, modelPrompt string) string {
+	modelPrompt = strings.TrimSpace(modelPrompt)
+	if modelPrompt == "" {
+		return systemContent
+	}
+	if strings.TrimSpace(systemContent) == "" {
+		return modelPrompt
+	}
+	return strings.TrimRight(systemContent, "\n") + "\n\n" + modelPrompt
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestLoadModelSystemPrompts tests loading, blank filtering and missing-file handling
+func TestLoadModelSystemPrompts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model_prompts.yaml")
+	content := `modelSystemPrompts:
+  "gpt-oss:20b": "Reasoning: high"
+  "qwen3-coder": |
+    Always call tools with every required parameter.
+  "blank-model": "   "
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	prompts, err := loadModelSystemPromptsFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(prompts) != 2 {
+		t.Errorf("Expected 2 prompts (blank entry dropped), got %d: %v", len(prompts), prompts)
+	}
+	if prompts["gpt-oss:20b"] != "Reasoning: high" {
+		t.Errorf("Unexpected gpt-oss prompt: %q", prompts["gpt-oss:20b"])
+	}
+
+	prompts, err = loadModelSystemPromptsFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil || len(prompts) != 0 {
+		t.Errorf("Expected empty map without error for missing file, got %v, %v", prompts, err)
+	}
+
+	if err := os.WriteFile(path, []byte("modelSystemPrompts: [not, a, map"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if _, err := loadModelSystemPromptsFile(path); err == nil {
+		t.Error("Expected parse error for malformed YAML")
+	}
+}
+
+// TestInjectModelSystemPrompt tests separator handling when appending instructions
+func TestInjectModelSystemPrompt(t *testing.T) {
+	if got := InjectModelSystemPrompt("Base prompt\n", " Reasoning: high\n"); got != "Base prompt\n\nReasoning: high" {
+		t.Errorf("Unexpected injected prompt: %q", got)
+	}
+	if got := InjectModelSystemPrompt("", "Reasoning: high"); got != "Reasoning: high" {
+		t.Errorf("Expected prompt alone for empty system message, got %q", got)
+	}
+	if got := InjectModelSystemPrompt("Base prompt", ""); got != "Base prompt" {
+		t.Errorf("Expected unchanged system message, got %q", got)
+	}
+}
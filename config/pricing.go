@@ -0,0 +1,50 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ModelPrice is the price of a provider model in USD per million tokens
+type ModelPrice struct {
+	InputPerMTok  float64 `json:"input_per_mtok"`
+	OutputPerMTok float64 `json:"output_per_mtok"`
+}
+
+// parseModelPricing parses MODEL_PRICING entries of the form
+// "model=input/output" separated by commas, e.g. "gpt-4o=2.50/10.00".
+// "=" is used as separator because model names such as qwen2.5-coder:latest contain colons.
+func parseModelPricing(value string) (map[string]ModelPrice, error) {
+	pricing := make(map[string]ModelPrice)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("MODEL_PRICING entries must be model=input/output, got: %s", entry)
+		}
+
+		var price ModelPrice
+		if n, err := fmt.Sscanf(strings.TrimSpace(parts[1]), "%f/%f", &price.InputPerMTok, &price.OutputPerMTok); n != 2 || err != nil {
+			return nil, fmt.Errorf("MODEL_PRICING price must be input/output USD per million tokens, got: %s", parts[1])
+		}
+		if price.InputPerMTok < 0 || price.OutputPerMTok < 0 {
+			return nil, fmt.Errorf("MODEL_PRICING prices must not be negative, got: %s", parts[1])
+		}
+		pricing[strings.TrimSpace(parts[0])] = price
+	}
+	return pricing, nil
+}
+
+// EstimateCost returns the estimated USD cost of a request to a provider model.
+// Models without configured pricing cost 0.
+func (c *Config) EstimateCost(model string, inputTokens, outputTokens int) float64 {
+	price, exists := c.ModelPricing[model]
+	if !exists {
+		return 0
+	}
+	return float64(inputTokens)*price.InputPerMTok/1e6 + float64(outputTokens)*price.OutputPerMTok/1e6
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"math"
+	"testing"
+)
+
+// TestParseModelPricing tests MODEL_PRICING parsing including colon-containing model names
+func TestParseModelPricing(t *testing.T) {
+	pricing, err := parseModelPricing("gpt-4o=2.50/10.00, qwen2.5-coder:latest=0/0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if pricing["gpt-4o"].InputPerMTok != 2.5 || pricing["gpt-4o"].OutputPerMTok != 10 {
+		t.Errorf("Unexpected gpt-4o pricing: %+v", pricing["gpt-4o"])
+	}
+	if _, exists := pricing["qwen2.5-coder:latest"]; !exists {
+		t.Error("Expected pricing for qwen2.5-coder:latest")
+	}
+
+	for _, invalid := range []string{"gpt-4o", "gpt-4o=cheap", "=1/2", "gpt-4o=-1/2"} {
+		if _, err := parseModelPricing(invalid); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
+
+// TestEstimateCost tests cost estimation with and without configured pricing
+func TestEstimateCost(t *testing.T) {
+	cfg := GetDefaultConfig()
+	cfg.ModelPricing = map[string]ModelPrice{"gpt-4o": {InputPerMTok: 2.5, OutputPerMTok: 10}}
+
+	if cost := cfg.EstimateCost("gpt-4o", 1000000, 500000); math.Abs(cost-7.5) > 1e-9 {
+		t.Errorf("Expected cost 7.5, got %f", cost)
+	}
+	if cost := cfg.EstimateCost("unpriced-model", 1000, 1000); cost != 0 {
+		t.Errorf("Expected zero cost for unpriced model, got %f", cost)
+	}
+}
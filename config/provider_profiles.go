@@ -0,0 +1,132 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProviderProfile captures the known quirks of a hosted provider. Endpoints
+// select one by name with the profile key of endpoints.yaml.
+type ProviderProfile struct {
+	StripParams          []string `yaml:"stripParams" json:"strip_params,omitempty"`                     // Request fields the provider rejects (e.g. cache_prompt, top_k)
+	MaxParallelToolCalls int      `yaml:"maxParallelToolCalls" json:"max_parallel_tool_calls,omitempty"` // Tool calls kept per response, 1 also sends parallel_tool_calls=false; 0 is unlimited
+	FillToolCallIDs      bool     `yaml:"fillToolCallIDs" json:"fill_tool_call_ids,omitempty"`           // Generate ids for tool calls returned without one
+	NoToolStreaming      bool     `yaml:"noToolStreaming" json:"no_tool_streaming,omitempty"`            // Request responses to tool-enabled requests unstreamed
+	TrimStopTokens       []string `yaml:"trimStopTokens" json:"trim_stop_tokens,omitempty"`              // Chat template end tokens removed when they leak into response text
+}
+
+// Built-in provider profile names
+const (
+	ProviderGroq      = "groq"
+	ProviderTogether  = "together"
+	ProviderFireworks = "fireworks"
+	ProviderLMStudio  = "lmstudio"
+)
+
+// localBackendParams are extensions only local backends (llama.cpp, vLLM) accept
+var localBackendParams = []string{"cache_prompt", "continue_final_message", "add_generation_prompt"}
+
+// builtinProviderProfiles are available without a provider_profiles.yaml
+var builtinProviderProfiles = map[string]ProviderProfile{
+	// Groq returns 400 for fields it does not know and only supports n=1
+	ProviderGroq: {StripParams: append([]string{"top_k", "n"}, localBackendParams...)},
+	// Together may omit tool call ids on some open models
+	ProviderTogether: {StripParams: localBackendParams, FillToolCallIDs: true},
+	// Fireworks function-calling models are tuned for one call per turn
+	ProviderFireworks: {StripParams: localBackendParams, MaxParallelToolCalls: 1},
+	// LM Studio does not stream tool calls, and models loaded with a mismatched
+	// chat template leave their end-of-turn token in the text
+	ProviderLMStudio: {
+		StripParams:     localBackendParams,
+		FillToolCallIDs: true,
+		NoToolStreaming: true,
+		TrimStopTokens:  []string{"<|im_end|>", "<|eot_id|>", "<|end|>", "<end_of_turn>", "</s>"},
+	},
+}
+
+// ProviderProfilesYAML represents the structure of provider_profiles.yaml
+type ProviderProfilesYAML struct {
+	ProviderProfiles map[string]ProviderProfile `yaml:"providerProfiles"`
+}
+
+// LoadProviderProfiles loads custom provider profiles from provider_profiles.yaml.
+// A custom profile replaces the built-in profile of the same name.
+//
+// YAML file structure:
+//
+//	providerProfiles:
+//	  deepinfra:
+//	    stripParams: [cache_prompt, top_k]
+//	    maxParallelToolCalls: 4
+//	  groq:                     # replaces the built-in groq profile
+//	    stripParams: [cache_prompt, top_k, n]
+//	    fillToolCallIDs: true
+//
+// Returns an empty map (no error) if provider_profiles.yaml doesn't exist.
+func LoadProviderProfiles() (map[string]ProviderProfile, error) {
+	return loadProviderProfilesFile("provider_profiles.yaml")
+}
+
+// loadProviderProfilesFile loads and validates provider profiles from the given path
+func loadProviderProfilesFile(path string) (map[string]ProviderProfile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return make(map[string]ProviderProfile), nil
+		}
+		return nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	var yamlData ProviderProfilesYAML
+	if err := yaml.NewDecoder(file).Decode(&yamlData); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	for name, profile := range yamlData.ProviderProfiles {
+		if profile.MaxParallelToolCalls < 0 {
+			return nil, fmt.Errorf("invalid maxParallelToolCalls for %s in %s: must not be negative", name, path)
+		}
+	}
+	if yamlData.ProviderProfiles == nil {
+		return make(map[string]ProviderProfile), nil
+	}
+	return yamlData.ProviderProfiles, nil
+}
+
+// GetProviderProfile returns the provider profile named name, custom
+// profiles taking precedence over built-in ones
+func (c *Config) GetProviderProfile(name string) (ProviderProfile, bool) {
+	if profile, exists := c.ProviderProfiles[name]; exists {
+		return profile, true
+	}
+	profile, exists := builtinProviderProfiles[name]
+	return profile, exists
+}
+
+// EndpointProviderProfile returns the provider profile selected for endpoint
+// in endpoints.yaml
+func (c *Config) EndpointProviderProfile(endpoint string) (ProviderProfile, bool) {
+	settings, ok := c.GetEndpointSettings(endpoint)
+	if !ok || settings.Profile == "" {
+		return ProviderProfile{}, false
+	}
+	return c.GetProviderProfile(settings.Profile)
+}
+
+// unknownProviderProfiles returns the endpoint prefixes whose profile names
+// neither a built-in nor a custom provider profile
+func (c *Config) unknownProviderProfiles() []string {
+	var unknown []string
+	for prefix, settings := range c.EndpointSettings {
+		if settings.Profile == "" {
+			continue
+		}
+		if _, exists := c.GetProviderProfile(settings.Profile); !exists {
+			unknown = append(unknown, prefix)
+		}
+	}
+	return unknown
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestLoadProviderProfiles tests profile parsing and validation
+func TestLoadProviderProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "provider_profiles.yaml")
+	content := `providerProfiles:
+  deepinfra:
+    stripParams: [cache_prompt, top_k]
+    maxParallelToolCalls: 4
+  groq:
+    fillToolCallIDs: true
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	profiles, err := loadProviderProfilesFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(profiles) != 2 || profiles["deepinfra"].MaxParallelToolCalls != 4 {
+		t.Fatalf("Unexpected profiles: %+v", profiles)
+	}
+
+	if err := os.WriteFile(path, []byte("providerProfiles:\n  bad:\n    maxParallelToolCalls: -1\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if _, err := loadProviderProfilesFile(path); err == nil {
+		t.Error("Expected error for negative maxParallelToolCalls")
+	}
+
+	profiles, err = loadProviderProfilesFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil || len(profiles) != 0 {
+		t.Errorf("Expected empty profiles for missing file, got %v, %v", profiles, err)
+	}
+}
+
+// TestEndpointProviderProfile tests profile selection per endpoint and that
+// custom profiles replace built-in ones
+func TestEndpointProviderProfile(t *testing.T) {
+	cfg := &Config{
+		EndpointSettings: map[string]EndpointSettings{
+			"https://api.groq.com":            {Profile: ProviderGroq},
+			"https://api.fireworks.ai":        {Profile: ProviderFireworks},
+			"https://api.deepinfra.com":       {Profile: "deepinfra"},
+			"http://gpu:8000":                 {ConnectTimeoutSeconds: 2},
+			"https://api.together.xyz/v1/bad": {Profile: "togther"},
+		},
+		ProviderProfiles: map[string]ProviderProfile{
+			ProviderFireworks: {MaxParallelToolCalls: 2},
+			"deepinfra":       {StripParams: []string{"top_k"}},
+		},
+	}
+
+	groq, ok := cfg.EndpointProviderProfile("https://api.groq.com/openai/v1/chat/completions")
+	if !ok || len(groq.StripParams) == 0 {
+		t.Errorf("Expected the built-in groq profile, got %+v, %v", groq, ok)
+	}
+	if fireworks, _ := cfg.EndpointProviderProfile("https://api.fireworks.ai/inference/v1/chat/completions"); fireworks.MaxParallelToolCalls != 2 {
+		t.Errorf("Expected the custom fireworks profile to replace the built-in one, got %+v", fireworks)
+	}
+	if _, ok := cfg.EndpointProviderProfile("https://api.deepinfra.com/v1/openai/chat/completions"); !ok {
+		t.Error("Expected the custom deepinfra profile")
+	}
+	if _, ok := cfg.EndpointProviderProfile("http://gpu:8000/v1/chat/completions"); ok {
+		t.Error("Expected no profile for an endpoint without one")
+	}
+
+	unknown := cfg.unknownProviderProfiles()
+	if len(unknown) != 1 || unknown[0] != "https://api.together.xyz/v1/bad" {
+		t.Errorf("Expected the misspelled profile to be reported, got %v", unknown)
+	}
+}
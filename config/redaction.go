@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Built-in detectors CONVERSATION_MASK_DETECTORS can enable
+const (
+	RedactionDetectorAPIKey = "api_key" // Provider API keys and bearer tokens
+	RedactionDetectorEmail  = "email"   // Email addresses
+	RedactionDetectorPhone  = "phone"   // Phone numbers written with separators or a country code
+	RedactionDetectorIP     = "ip"      // IPv4 and full-form IPv6 addresses
+)
+
+// redactionDetectors lists the values CONVERSATION_MASK_DETECTORS accepts
+var redactionDetectors = []string{RedactionDetectorAPIKey, RedactionDetectorEmail, RedactionDetectorPhone, RedactionDetectorIP}
+
+// RedactionPattern is a custom detector from redaction_patterns.yaml. Matches
+// are replaced with [REDACTED_<NAME>].
+type RedactionPattern struct {
+	Name    string `yaml:"name" json:"name"`
+	Pattern string `yaml:"pattern" json:"pattern"`
+
+	compiled *regexp.Regexp
+}
+
+// Regexp returns the compiled pattern. Patterns not loaded from
+// redaction_patterns.yaml are compiled on demand; nil if invalid.
+func (p RedactionPattern) Regexp() *regexp.Regexp {
+	if p.compiled == nil {
+		p.compiled, _ = regexp.Compile(p.Pattern)
+	}
+	return p.compiled
+}
+
+// RedactionPatternsYAML represents the structure of redaction_patterns.yaml
+type RedactionPatternsYAML struct {
+	Patterns []RedactionPattern `yaml:"patterns"`
+}
+
+// LoadRedactionPatterns loads custom conversation log detectors from
+// redaction_patterns.yaml.
+//
+// YAML file structure:
+//
+//	patterns:
+//	  - name: employee_id
+//	    pattern: 'EMP-\d{6}'
+//	  - name: internal_host
+//	    pattern: '[a-z0-9-]+\.corp\.example\.com'
+//
+// Returns no patterns (no error) if redaction_patterns.yaml doesn't exist.
+func LoadRedactionPatterns() ([]RedactionPattern, error) {
+	return loadRedactionPatternsFile("redaction_patterns.yaml")
+}
+
+// loadRedactionPatternsFile loads and compiles custom detectors from the given path
+func loadRedactionPatternsFile(path string) ([]RedactionPattern, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	var yamlData RedactionPatternsYAML
+	if err := yaml.NewDecoder(file).Decode(&yamlData); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	for i := range yamlData.Patterns {
+		pattern := &yamlData.Patterns[i]
+		if pattern.Name == "" || pattern.Pattern == "" {
+			return nil, fmt.Errorf("pattern %d in %s needs a name and a pattern", i+1, path)
+		}
+		compiled, err := regexp.Compile(pattern.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %s in %s: %v", pattern.Name, path, err)
+		}
+		pattern.compiled = compiled
+	}
+	return yamlData.Patterns, nil
+}
+
+// parseRedactionDetectors parses CONVERSATION_MASK_DETECTORS, a
+// comma-separated list of built-in detectors
+func parseRedactionDetectors(value string) ([]string, error) {
+	var detectors []string
+	for _, detector := range splitList(value) {
+		detector = strings.ToLower(detector)
+		if !containsString(redactionDetectors, detector) {
+			return nil, fmt.Errorf("unknown detector %q, must be one of: %s", detector, strings.Join(redactionDetectors, ", "))
+		}
+		detectors = append(detectors, detector)
+	}
+	return detectors, nil
+}
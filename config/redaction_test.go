@@ -0,0 +1,54 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestLoadRedactionPatterns tests custom detector parsing and validation
+func TestLoadRedactionPatterns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "redaction_patterns.yaml")
+	content := `patterns:
+  - name: employee_id
+    pattern: 'EMP-\d{6}'
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	patterns, err := loadRedactionPatternsFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(patterns) != 1 || !patterns[0].Regexp().MatchString("EMP-004211") {
+		t.Errorf("Unexpected patterns: %+v", patterns)
+	}
+
+	invalidPatterns := map[string]string{
+		"missing name":  "patterns:\n  - pattern: 'x'\n",
+		"invalid regex": "patterns:\n  - name: bad\n    pattern: '('\n",
+	}
+	for name, invalid := range invalidPatterns {
+		if err := os.WriteFile(path, []byte(invalid), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+		if _, err := loadRedactionPatternsFile(path); err == nil {
+			t.Errorf("Expected error for %s", name)
+		}
+	}
+}
+
+// TestParseRedactionDetectors tests CONVERSATION_MASK_DETECTORS parsing
+func TestParseRedactionDetectors(t *testing.T) {
+	detectors, err := parseRedactionDetectors("Email, ip")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(detectors) != 2 || detectors[0] != RedactionDetectorEmail || detectors[1] != RedactionDetectorIP {
+		t.Errorf("Unexpected detectors: %v", detectors)
+	}
+	if _, err := parseRedactionDetectors("email,ssn"); err == nil {
+		t.Error("Expected error for unknown detector")
+	}
+}
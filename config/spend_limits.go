@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SpendLimit caps the estimated cost a client key may spend in USD per UTC
+// day and calendar month (0 = unlimited)
+type SpendLimit struct {
+	DailyUSD   float64 `json:"daily_usd"`
+	MonthlyUSD float64 `json:"monthly_usd"`
+}
+
+// SpendLimits are the limits of every client key plus per-key overrides,
+// keyed by the client key ID /stats reports spend under
+type SpendLimits struct {
+	Default SpendLimit            `json:"default"`
+	Keys    map[string]SpendLimit `json:"keys,omitempty"`
+}
+
+// For returns the limit of a client key
+func (l SpendLimits) For(clientKey string) SpendLimit {
+	if limit, exists := l.Keys[clientKey]; exists {
+		return limit
+	}
+	return l.Default
+}
+
+// IsEmpty reports whether no client key is limited
+func (l SpendLimits) IsEmpty() bool {
+	if l.Default != (SpendLimit{}) {
+		return false
+	}
+	for _, limit := range l.Keys {
+		if limit != (SpendLimit{}) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseSpendLimits parses CLIENT_SPEND_LIMITS entries of the form
+// "keyid=daily/monthly" separated by commas, e.g. "3f2a9c0d1e4b5a6f=5/50".
+// A limit of 0 leaves that period unlimited for the key.
+func parseSpendLimits(value string) (map[string]SpendLimit, error) {
+	limits := make(map[string]SpendLimit)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("CLIENT_SPEND_LIMITS entries must be keyid=daily/monthly, got: %s", entry)
+		}
+
+		var limit SpendLimit
+		if n, err := fmt.Sscanf(strings.TrimSpace(parts[1]), "%f/%f", &limit.DailyUSD, &limit.MonthlyUSD); n != 2 || err != nil {
+			return nil, fmt.Errorf("CLIENT_SPEND_LIMITS limits must be daily/monthly USD, got: %s", parts[1])
+		}
+		if limit.DailyUSD < 0 || limit.MonthlyUSD < 0 {
+			return nil, fmt.Errorf("CLIENT_SPEND_LIMITS limits must not be negative, got: %s", parts[1])
+		}
+		limits[strings.TrimSpace(parts[0])] = limit
+	}
+	return limits, nil
+}
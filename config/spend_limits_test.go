@@ -0,0 +1,30 @@
+package config
+
+import "testing"
+
+// TestParseSpendLimits tests CLIENT_SPEND_LIMITS parsing and per-key lookup
+func TestParseSpendLimits(t *testing.T) {
+	keys, err := parseSpendLimits("3f2a9c0d1e4b5a6f=5/50, 0011223344556677=0/10")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	limits := SpendLimits{Default: SpendLimit{DailyUSD: 1, MonthlyUSD: 20}, Keys: keys}
+	if limit := limits.For("3f2a9c0d1e4b5a6f"); limit.DailyUSD != 5 || limit.MonthlyUSD != 50 {
+		t.Errorf("Unexpected override: %+v", limit)
+	}
+	if limit := limits.For("0011223344556677"); limit.DailyUSD != 0 || limit.MonthlyUSD != 10 {
+		t.Errorf("Unexpected override: %+v", limit)
+	}
+	if limit := limits.For("unknown"); limit != limits.Default {
+		t.Errorf("Expected the default limit, got %+v", limit)
+	}
+	if limits.IsEmpty() || !(SpendLimits{Keys: map[string]SpendLimit{"a": {}}}).IsEmpty() {
+		t.Error("Unexpected IsEmpty result")
+	}
+
+	for _, invalid := range []string{"3f2a", "3f2a=5", "=5/50", "3f2a=-1/50", "3f2a=five/50"} {
+		if _, err := parseSpendLimits(invalid); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
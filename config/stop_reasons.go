@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Anthropic stop_reason values
+const (
+	StopReasonEndTurn      = "end_turn"
+	StopReasonMaxTokens    = "max_tokens"
+	StopReasonStopSequence = "stop_sequence"
+	StopReasonToolUse      = "tool_use"
+	StopReasonPauseTurn    = "pause_turn"
+	StopReasonRefusal      = "refusal"
+)
+
+// stopReasonValues lists the stop_reason values STOP_REASON_MAP may map to
+var stopReasonValues = []string{
+	StopReasonEndTurn,
+	StopReasonMaxTokens,
+	StopReasonStopSequence,
+	StopReasonToolUse,
+	StopReasonPauseTurn,
+	StopReasonRefusal,
+}
+
+// DefaultStopReasons maps OpenAI finish_reason values, and the non-standard
+// values some OpenAI-compatible providers return, to Anthropic stop_reason.
+// STOP_REASON_MAP entries are consulted first.
+var DefaultStopReasons = map[string]string{
+	// OpenAI
+	"stop":           StopReasonEndTurn,
+	"length":         StopReasonMaxTokens,
+	"tool_calls":     StopReasonToolUse,
+	"function_call":  StopReasonToolUse, // Legacy function calling
+	"content_filter": StopReasonRefusal,
+
+	// Provider-specific
+	"eos":           StopReasonEndTurn,   // Hugging Face TGI
+	"eos_token":     StopReasonEndTurn,   // Hugging Face TGI
+	"end_turn":      StopReasonEndTurn,   // Anthropic-style passthrough (LiteLLM, OpenRouter)
+	"max_tokens":    StopReasonMaxTokens, // Anthropic-style passthrough
+	"model_length":  StopReasonMaxTokens, // Context window exhausted
+	"stop_sequence": StopReasonStopSequence,
+	"tool_use":      StopReasonToolUse,
+}
+
+// StopReasonFor maps an upstream finish_reason to an Anthropic stop_reason,
+// preferring STOP_REASON_MAP over the defaults. Matching is case-insensitive.
+// ok is false for values neither table knows.
+func (c *Config) StopReasonFor(finishReason string) (string, bool) {
+	key := strings.ToLower(strings.TrimSpace(finishReason))
+	if stopReason, exists := c.StopReasons[key]; exists {
+		return stopReason, true
+	}
+	stopReason, exists := DefaultStopReasons[key]
+	return stopReason, exists
+}
+
+// parseStopReasonMap parses STOP_REASON_MAP entries of the form
+// finish_reason:stop_reason, comma-separated
+func parseStopReasonMap(value string) (map[string]string, error) {
+	stopReasons := make(map[string]string)
+	for _, entry := range splitList(value) {
+		finishReason, stopReason, found := strings.Cut(entry, ":")
+		finishReason = strings.ToLower(strings.TrimSpace(finishReason))
+		stopReason = strings.TrimSpace(stopReason)
+		if !found || finishReason == "" {
+			return nil, fmt.Errorf("entry %q must be finish_reason:stop_reason", entry)
+		}
+		known := false
+		for _, value := range stopReasonValues {
+			if stopReason == value {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown stop_reason %q for %s, must be one of: %s", stopReason, finishReason, strings.Join(stopReasonValues, ", "))
+		}
+		stopReasons[finishReason] = stopReason
+	}
+	return stopReasons, nil
+}
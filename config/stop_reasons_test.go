@@ -0,0 +1,39 @@
+package config
+
+import "testing"
+
+// TestStopReasonFor tests STOP_REASON_MAP parsing and lookups that prefer it
+// over DefaultStopReasons
+func TestStopReasonFor(t *testing.T) {
+	stopReasons, err := parseStopReasonMap("content_filter:end_turn, RECITATION:refusal")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cfg := &Config{StopReasons: stopReasons}
+
+	tests := map[string]string{
+		"stop":           StopReasonEndTurn,
+		"length":         StopReasonMaxTokens,
+		"tool_calls":     StopReasonToolUse,
+		"function_call":  StopReasonToolUse,
+		"content_filter": StopReasonEndTurn, // Overridden
+		"recitation":     StopReasonRefusal, // Added
+		"Length":         StopReasonMaxTokens,
+	}
+	for finishReason, expected := range tests {
+		stopReason, ok := cfg.StopReasonFor(finishReason)
+		if !ok || stopReason != expected {
+			t.Errorf("%s: expected %s, got %q (ok=%v)", finishReason, expected, stopReason, ok)
+		}
+	}
+
+	if _, ok := cfg.StopReasonFor("provider_hiccup"); ok {
+		t.Error("Expected unknown finish_reason to be unmapped")
+	}
+
+	for _, value := range []string{"stop", "stop:done", ":end_turn"} {
+		if _, err := parseStopReasonMap(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StreamRateLimit caps how fast streamed responses are sent to a client, in
+// estimated output tokens and in bytes per second (0 = unlimited)
+type StreamRateLimit struct {
+	TokensPerSecond float64 `json:"tokens_per_second"`
+	BytesPerSecond  float64 `json:"bytes_per_second"`
+}
+
+// IsZero reports whether the limit leaves streams unthrottled
+func (l StreamRateLimit) IsZero() bool {
+	return l.TokensPerSecond <= 0 && l.BytesPerSecond <= 0
+}
+
+// StreamRateLimits are the streaming rate limits of every client key plus
+// per-key overrides, keyed by the client key ID /stats reports spend under
+type StreamRateLimits struct {
+	Default StreamRateLimit            `json:"default"`
+	Keys    map[string]StreamRateLimit `json:"keys,omitempty"`
+}
+
+// For returns the limit of a client key
+func (l StreamRateLimits) For(clientKey string) StreamRateLimit {
+	if limit, exists := l.Keys[clientKey]; exists {
+		return limit
+	}
+	return l.Default
+}
+
+// IsEmpty reports whether no client key is throttled
+func (l StreamRateLimits) IsEmpty() bool {
+	if !l.Default.IsZero() {
+		return false
+	}
+	for _, limit := range l.Keys {
+		if !limit.IsZero() {
+			return false
+		}
+	}
+	return true
+}
+
+// parseStreamRateLimits parses CLIENT_STREAM_RATE_LIMITS entries of the form
+// "keyid=tokens/bytes" separated by commas, e.g. "3f2a9c0d1e4b5a6f=50/0".
+// A limit of 0 leaves that unit unlimited for the key.
+func parseStreamRateLimits(value string) (map[string]StreamRateLimit, error) {
+	limits := make(map[string]StreamRateLimit)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("CLIENT_STREAM_RATE_LIMITS entries must be keyid=tokens/bytes, got: %s", entry)
+		}
+
+		var limit StreamRateLimit
+		if n, err := fmt.Sscanf(strings.TrimSpace(parts[1]), "%f/%f", &limit.TokensPerSecond, &limit.BytesPerSecond); n != 2 || err != nil {
+			return nil, fmt.Errorf("CLIENT_STREAM_RATE_LIMITS limits must be tokens/bytes per second, got: %s", parts[1])
+		}
+		if limit.TokensPerSecond < 0 || limit.BytesPerSecond < 0 {
+			return nil, fmt.Errorf("CLIENT_STREAM_RATE_LIMITS limits must not be negative, got: %s", parts[1])
+		}
+		limits[strings.TrimSpace(parts[0])] = limit
+	}
+	return limits, nil
+}
@@ -0,0 +1,30 @@
+package config
+
+import "testing"
+
+// TestParseStreamRateLimits tests CLIENT_STREAM_RATE_LIMITS parsing and per-key lookup
+func TestParseStreamRateLimits(t *testing.T) {
+	keys, err := parseStreamRateLimits("3f2a9c0d1e4b5a6f=50/0, 0011223344556677=0/65536")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	limits := StreamRateLimits{Default: StreamRateLimit{TokensPerSecond: 200}, Keys: keys}
+	if limit := limits.For("3f2a9c0d1e4b5a6f"); limit.TokensPerSecond != 50 || limit.BytesPerSecond != 0 {
+		t.Errorf("Unexpected override: %+v", limit)
+	}
+	if limit := limits.For("0011223344556677"); limit.TokensPerSecond != 0 || limit.BytesPerSecond != 65536 {
+		t.Errorf("Unexpected override: %+v", limit)
+	}
+	if limit := limits.For("unknown"); limit != limits.Default {
+		t.Errorf("Expected the default limit, got %+v", limit)
+	}
+	if limits.IsEmpty() || !(StreamRateLimits{Keys: map[string]StreamRateLimit{"a": {}}}).IsEmpty() {
+		t.Error("Unexpected IsEmpty result")
+	}
+
+	for _, invalid := range []string{"3f2a", "3f2a=5", "=5/50", "3f2a=-1/50", "3f2a=fast/50"} {
+		if _, err := parseStreamRateLimits(invalid); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"crypto/sha256"
+	"sync"
+	"sync/atomic"
+)
+
+// systemOverrideCacheSize bounds the memoized system prompts. A session sends
+// the same prompt every turn and few prompts are live at once, so a full
+// cache is simply cleared.
+const systemOverrideCacheSize = 256
+
+// overridesVersions numbers each compiled set of system message overrides, so
+// results computed with an earlier set are never served after a reload
+var overridesVersions atomic.Uint64
+
+// systemOverrideKey identifies a system prompt under one set of overrides
+type systemOverrideKey struct {
+	version uint64
+	message [sha256.Size]byte
+}
+
+// systemOverrideCache memoizes ApplySystemOverrides for the newest overrides
+// version it has seen
+type systemOverrideCache struct {
+	mu      sync.RWMutex
+	version uint64
+	results map[systemOverrideKey]string
+}
+
+// systemOverrideResults is shared by every Config, keys carry the version
+var systemOverrideResults = &systemOverrideCache{results: make(map[systemOverrideKey]string)}
+
+// get returns the cached result for key
+func (s *systemOverrideCache) get(key systemOverrideKey) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result, ok := s.results[key]
+	return result, ok
+}
+
+// put caches a result. A newer overrides version drops every entry of the
+// older ones; results for an older version are not cached.
+func (s *systemOverrideCache) put(key systemOverrideKey, result string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key.version < s.version {
+		return
+	}
+	if key.version > s.version || len(s.results) >= systemOverrideCacheSize {
+		s.version = key.version
+		s.results = make(map[systemOverrideKey]string)
+	}
+	s.results[key] = result
+}
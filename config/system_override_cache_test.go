@@ -0,0 +1,88 @@
+package config
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+)
+
+// TestApplySystemOverridesMemoized tests that results are cached per overrides
+// version and never served for reloaded overrides
+func TestApplySystemOverridesMemoized(t *testing.T) {
+	message := "You are Claude Code. IMPORTANT: Never do X. Help users."
+	key := func(o SystemMessageOverrides) systemOverrideKey {
+		return systemOverrideKey{version: o.version, message: sha256.Sum256([]byte(message))}
+	}
+
+	first := SystemMessageOverrides{Replacements: []SystemMessageReplacement{{Find: "Claude Code", Replace: "an assistant"}}}
+	if err := first.CompilePatterns(); err != nil {
+		t.Fatalf("CompilePatterns failed: %v", err)
+	}
+	cfg := &Config{SystemMessageOverrides: first}
+	want := "You are an assistant. IMPORTANT: Never do X. Help users."
+	if got := cfg.ApplySystemOverrides("req-1", message); got != want {
+		t.Fatalf("Expected %q, got %q", want, got)
+	}
+	if cached, ok := systemOverrideResults.get(key(first)); !ok || cached != want {
+		t.Errorf("Expected the result to be cached, got %q, %v", cached, ok)
+	}
+	if got := cfg.ApplySystemOverrides("req-2", message); got != want {
+		t.Errorf("Expected the cached result %q, got %q", want, got)
+	}
+
+	// Reloading system_overrides.yaml compiles a new version
+	reloaded := SystemMessageOverrides{RemovePatterns: []string{`IMPORTANT:[^.]*\.\s*`}}
+	if err := reloaded.CompilePatterns(); err != nil {
+		t.Fatalf("CompilePatterns failed: %v", err)
+	}
+	cfg.SystemMessageOverrides = reloaded
+	want = "You are Claude Code. Help users."
+	if got := cfg.ApplySystemOverrides("req-3", message); got != want {
+		t.Errorf("Expected %q after reload, got %q", want, got)
+	}
+	if _, ok := systemOverrideResults.get(key(first)); ok {
+		t.Error("Expected results of the previous overrides to be dropped")
+	}
+
+	// Overrides that were never compiled have no version and are not cached
+	adHoc := &Config{SystemMessageOverrides: SystemMessageOverrides{Append: " Bye."}}
+	if got := adHoc.ApplySystemOverrides("req-4", message); got != message+" Bye." {
+		t.Errorf("Unexpected result for uncompiled overrides: %q", got)
+	}
+	if _, ok := systemOverrideResults.get(key(adHoc.SystemMessageOverrides)); ok {
+		t.Error("Expected uncompiled overrides not to be cached")
+	}
+}
+
+// TestSystemOverrideCacheBounded tests that a full cache starts over
+func TestSystemOverrideCacheBounded(t *testing.T) {
+	cache := &systemOverrideCache{results: make(map[systemOverrideKey]string)}
+	for i := 0; i <= systemOverrideCacheSize; i++ {
+		cache.put(systemOverrideKey{version: 1, message: sha256.Sum256([]byte(fmt.Sprint(i)))}, "result")
+	}
+	if len(cache.results) != 1 {
+		t.Errorf("Expected the full cache to be cleared, got %d entries", len(cache.results))
+	}
+	cache.put(systemOverrideKey{version: 0}, "stale")
+	if _, ok := cache.get(systemOverrideKey{version: 0}); ok {
+		t.Error("Expected results of an older version not to be cached")
+	}
+}
+
+// TestApplyToolResultOverridesUncached tests that tool results get the
+// overrides without taking cache entries from system prompts
+func TestApplyToolResultOverridesUncached(t *testing.T) {
+	overrides := SystemMessageOverrides{Replacements: []SystemMessageReplacement{{Find: "Claude Code", Replace: "an assistant"}}}
+	if err := overrides.CompilePatterns(); err != nil {
+		t.Fatalf("CompilePatterns failed: %v", err)
+	}
+	cfg := &Config{SystemMessageOverrides: overrides}
+	content := "Output of a tool run by Claude Code"
+	if got := cfg.ApplyToolResultOverrides("req-1", content); got != "Output of a tool run by an assistant" {
+		t.Errorf("Unexpected tool result %q", got)
+	}
+	key := systemOverrideKey{version: overrides.version, message: sha256.Sum256([]byte(content))}
+	if _, ok := systemOverrideResults.get(key); ok {
+		t.Error("Expected tool results not to be cached")
+	}
+}
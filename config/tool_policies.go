@@ -0,0 +1,203 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// What the proxy does with a tool call matched by a policy
+const (
+	ToolPolicyAllow           = "allow"            // Return the call to the client
+	ToolPolicyDeny            = "deny"             // Replace the call with a text note so the client never runs it
+	ToolPolicyRequireApproval = "require_approval" // Hold the response until an admin approves or denies the call
+)
+
+// ToolPolicy is one rule of tool_policies.yaml. Every condition that is set
+// must match; unset conditions match anything.
+type ToolPolicy struct {
+	Name    string            `yaml:"name" json:"name"`
+	Tool    string            `yaml:"tool" json:"tool,omitempty"`       // Tool name or glob (e.g. "mcp__*")
+	Params  map[string]string `yaml:"params" json:"params,omitempty"`   // Parameter name -> regexp on its value
+	Session string            `yaml:"session" json:"session,omitempty"` // Regexp on the request's metadata.user_id
+	Hours   string            `yaml:"hours" json:"hours,omitempty"`     // Local time window "HH:MM-HH:MM", may wrap midnight
+	Action  string            `yaml:"action" json:"action"`
+
+	params  map[string]*regexp.Regexp
+	session *regexp.Regexp
+	from    int // Minutes after midnight the hours window opens
+	until   int // Minutes after midnight the hours window closes
+}
+
+// ToolPoliciesYAML represents the structure of tool_policies.yaml
+type ToolPoliciesYAML struct {
+	Policies []ToolPolicy `yaml:"policies"`
+}
+
+// LoadToolPolicies loads tool-call policies from tool_policies.yaml.
+//
+// YAML file structure:
+//
+//	policies:
+//	  - name: no-force-push
+//	    tool: Bash
+//	    params: {command: 'git push .*(-f|--force)'}
+//	    action: deny
+//	  - name: night-writes
+//	    tool: "Write"
+//	    hours: "22:00-06:00"
+//	    action: require_approval
+//
+// Policies are evaluated in file order and the first match wins; calls no
+// policy matches are allowed. Returns no policies (no error) if
+// tool_policies.yaml doesn't exist.
+func LoadToolPolicies() ([]ToolPolicy, error) {
+	return loadToolPoliciesFile("tool_policies.yaml")
+}
+
+// loadToolPoliciesFile loads and compiles tool-call policies from the given path
+func loadToolPoliciesFile(path string) ([]ToolPolicy, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	var yamlData ToolPoliciesYAML
+	if err := yaml.NewDecoder(file).Decode(&yamlData); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	for i := range yamlData.Policies {
+		policy := &yamlData.Policies[i]
+		if policy.Name == "" {
+			policy.Name = fmt.Sprintf("policy-%d", i+1)
+		}
+		if err := policy.compile(); err != nil {
+			return nil, fmt.Errorf("invalid policy %s in %s: %v", policy.Name, path, err)
+		}
+	}
+	return yamlData.Policies, nil
+}
+
+// compile validates the policy and prepares its conditions for matching
+func (p *ToolPolicy) compile() error {
+	switch p.Action {
+	case ToolPolicyAllow, ToolPolicyDeny, ToolPolicyRequireApproval:
+	default:
+		return fmt.Errorf("action must be %s, %s or %s, got: %q", ToolPolicyAllow, ToolPolicyDeny, ToolPolicyRequireApproval, p.Action)
+	}
+	if p.Tool != "" {
+		if _, err := path.Match(p.Tool, ""); err != nil {
+			return fmt.Errorf("tool pattern %q: %v", p.Tool, err)
+		}
+	}
+
+	p.params = make(map[string]*regexp.Regexp, len(p.Params))
+	for param, pattern := range p.Params {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("pattern for parameter %s: %v", param, err)
+		}
+		p.params[param] = re
+	}
+	if p.Session != "" {
+		re, err := regexp.Compile(p.Session)
+		if err != nil {
+			return fmt.Errorf("session pattern: %v", err)
+		}
+		p.session = re
+	}
+	if p.Hours != "" {
+		from, until, found := strings.Cut(p.Hours, "-")
+		var err error
+		if !found {
+			return fmt.Errorf("hours must be HH:MM-HH:MM, got: %q", p.Hours)
+		}
+		if p.from, err = parseClock(from); err != nil {
+			return fmt.Errorf("hours: %v", err)
+		}
+		if p.until, err = parseClock(until); err != nil {
+			return fmt.Errorf("hours: %v", err)
+		}
+	}
+	return nil
+}
+
+// parseClock parses HH:MM (or HH) into minutes after midnight
+func parseClock(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	hours, minutes, _ := strings.Cut(value, ":")
+	h, err := strconv.Atoi(hours)
+	if err != nil || h < 0 || h > 23 {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+	m := 0
+	if minutes != "" {
+		if m, err = strconv.Atoi(minutes); err != nil || m < 0 || m > 59 {
+			return 0, fmt.Errorf("invalid time %q", value)
+		}
+	}
+	return h*60 + m, nil
+}
+
+// Matches reports whether a tool call made at the given time in the given
+// session meets every condition of the policy
+func (p *ToolPolicy) Matches(tool string, input map[string]interface{}, session string, at time.Time) bool {
+	if p.Tool != "" {
+		if matched, _ := path.Match(p.Tool, tool); !matched {
+			return false
+		}
+	}
+	for param, re := range p.params {
+		value, exists := input[param]
+		if !exists || !re.MatchString(policyValueString(value)) {
+			return false
+		}
+	}
+	if p.session != nil && !p.session.MatchString(session) {
+		return false
+	}
+	if p.Hours != "" {
+		minute := at.Hour()*60 + at.Minute()
+		if p.from <= p.until {
+			if minute < p.from || minute >= p.until {
+				return false
+			}
+		} else if minute < p.from && minute >= p.until {
+			return false
+		}
+	}
+	return true
+}
+
+// policyValueString returns the form of a parameter value policy patterns
+// match: strings as is, anything else as JSON
+func policyValueString(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
+
+// ToolPolicyFor returns the first policy matching a tool call, or false when
+// none does and the call is allowed
+func (c *Config) ToolPolicyFor(tool string, input map[string]interface{}, session string, at time.Time) (ToolPolicy, bool) {
+	for _, policy := range c.ToolPolicies {
+		if policy.Matches(tool, input, session, at) {
+			return policy, true
+		}
+	}
+	return ToolPolicy{}, false
+}
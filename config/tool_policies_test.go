@@ -0,0 +1,90 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestLoadToolPolicies tests policy parsing, validation and first-match evaluation
+func TestLoadToolPolicies(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tool_policies.yaml")
+	content := `policies:
+  - name: no-force-push
+    tool: Bash
+    params: {command: 'git push .*--force'}
+    action: deny
+  - name: mcp-review
+    tool: "mcp__*"
+    session: "_session_abc"
+    action: require_approval
+  - name: night-writes
+    tool: Write
+    hours: "22:00-06:00"
+    action: require_approval
+  - tool: Bash
+    action: allow
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	policies, err := loadToolPoliciesFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(policies) != 4 {
+		t.Fatalf("Expected 4 policies, got %d", len(policies))
+	}
+	if policies[3].Name != "policy-4" {
+		t.Errorf("Expected unnamed policy to be named policy-4, got %s", policies[3].Name)
+	}
+
+	cfg := &Config{ToolPolicies: policies}
+	noon := time.Date(2025, 1, 1, 12, 0, 0, 0, time.Local)
+	midnight := time.Date(2025, 1, 1, 0, 30, 0, 0, time.Local)
+	tests := []struct {
+		name     string
+		tool     string
+		input    map[string]interface{}
+		session  string
+		at       time.Time
+		expected string // Matching policy, empty for none
+	}{
+		{"force push", "Bash", map[string]interface{}{"command": "git push origin main --force"}, "", noon, "no-force-push"},
+		{"plain push", "Bash", map[string]interface{}{"command": "git push origin main"}, "", noon, "policy-4"},
+		{"mcp in session", "mcp__github__create_issue", nil, "user_1_session_abc", noon, "mcp-review"},
+		{"mcp other session", "mcp__github__create_issue", nil, "user_1_session_xyz", noon, ""},
+		{"write at night", "Write", nil, "", midnight, "night-writes"},
+		{"write at noon", "Write", nil, "", noon, ""},
+	}
+	for _, tt := range tests {
+		policy, matched := cfg.ToolPolicyFor(tt.tool, tt.input, tt.session, tt.at)
+		if tt.expected == "" && matched {
+			t.Errorf("%s: expected no policy, got %s", tt.name, policy.Name)
+		} else if tt.expected != "" && policy.Name != tt.expected {
+			t.Errorf("%s: expected policy %s, got %q", tt.name, tt.expected, policy.Name)
+		}
+	}
+
+	invalidPolicies := map[string]string{
+		"unknown action":  "policies:\n  - tool: Bash\n    action: ask\n",
+		"bad param regex": "policies:\n  - params: {command: '('}\n    action: deny\n",
+		"bad hours":       "policies:\n  - hours: '25:00-06:00'\n    action: deny\n",
+		"bad tool glob":   "policies:\n  - tool: '['\n    action: deny\n",
+	}
+	for name, invalid := range invalidPolicies {
+		if err := os.WriteFile(path, []byte(invalid), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+		if _, err := loadToolPoliciesFile(path); err == nil {
+			t.Errorf("Expected error for %s", name)
+		}
+	}
+
+	policies, err = loadToolPoliciesFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil || len(policies) != 0 {
+		t.Errorf("Expected no policies for missing file, got %v, %v", policies, err)
+	}
+}
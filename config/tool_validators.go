@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"claude-proxy/types"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ToolValidatorsYAML represents the structure of tool_validators.yaml
+type ToolValidatorsYAML struct {
+	CustomTools []types.CustomTool `yaml:"customTools"`
+}
+
+// LoadToolValidators loads custom tool definitions from tool_validators.yaml,
+// giving MCP and other non-built-in tools the name and parameter
+// normalization the built-in Claude Code tools get.
+//
+// YAML file structure:
+//
+//	customTools:
+//	  - name: mcp__github__create_issue
+//	    aliases: [create_issue, github_create_issue]
+//	    parameterAliases:
+//	      repository: repo
+//	      body_text: body
+//	    normalizers:
+//	      labels: array
+//	      title: trim
+//
+// Normalizers: trim, lowercase, uppercase, string, number, boolean, array.
+// Returns an empty slice (no error) if tool_validators.yaml doesn't exist.
+func LoadToolValidators() ([]types.CustomTool, error) {
+	return loadToolValidatorsFile("tool_validators.yaml")
+}
+
+// loadToolValidatorsFile loads and validates custom tools from the given path
+func loadToolValidatorsFile(path string) ([]types.CustomTool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []types.CustomTool{}, nil
+		}
+		return nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	var yamlData ToolValidatorsYAML
+	if err := yaml.NewDecoder(file).Decode(&yamlData); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	seen := make(map[string]bool, len(yamlData.CustomTools))
+	for i, tool := range yamlData.CustomTools {
+		if err := tool.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid customTools[%d] in %s: %v", i, path, err)
+		}
+		if seen[tool.Name] {
+			return nil, fmt.Errorf("duplicate custom tool %s in %s", tool.Name, path)
+		}
+		seen[tool.Name] = true
+	}
+	if yamlData.CustomTools == nil {
+		return []types.CustomTool{}, nil
+	}
+	return yamlData.CustomTools, nil
+}
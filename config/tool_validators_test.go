@@ -0,0 +1,54 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestLoadToolValidators tests custom tool parsing and validation
+func TestLoadToolValidators(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tool_validators.yaml")
+	content := `customTools:
+  - name: mcp__github__create_issue
+    aliases: [create_issue]
+    parameterAliases:
+      repository: repo
+    normalizers:
+      labels: array
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	tools, err := loadToolValidatorsFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(tools) != 1 || tools[0].Name != "mcp__github__create_issue" {
+		t.Fatalf("Unexpected tools: %+v", tools)
+	}
+	if tools[0].ParameterAliases["repository"] != "repo" || tools[0].Normalizers["labels"] != "array" {
+		t.Errorf("Unexpected normalization: %+v", tools[0])
+	}
+
+	invalidFiles := map[string]string{
+		"missing name":       "customTools:\n  - aliases: [x]\n",
+		"unknown normalizer": "customTools:\n  - name: t\n    normalizers:\n      a: reverse\n",
+		"duplicate tool":     "customTools:\n  - name: t\n  - name: t\n",
+	}
+	for name, content := range invalidFiles {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+		if _, err := loadToolValidatorsFile(path); err == nil || !strings.Contains(err.Error(), path) {
+			t.Errorf("%s: expected error naming %s, got %v", name, path, err)
+		}
+	}
+
+	missing, err := loadToolValidatorsFile(filepath.Join(t.TempDir(), "absent.yaml"))
+	if err != nil || len(missing) != 0 {
+		t.Errorf("Missing file should yield no tools, got %v, %v", missing, err)
+	}
+}
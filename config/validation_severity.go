@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tool call validation rules VALIDATION_SEVERITY can configure
+const (
+	ValidationRuleMissingParam = "missing_param" // A required parameter is absent
+	ValidationRuleInvalidParam = "invalid_param" // A parameter the schema does not define
+	ValidationRuleStructural   = "structural"    // Parameters valid by name but wrongly shaped (e.g. TodoWrite items)
+	ValidationRuleSemantic     = "semantic"      // A tool used against the architecture (e.g. WebFetch of file:// URLs)
+)
+
+// What the correction service does with a call that breaks a rule
+const (
+	ValidationSeverityWarn  = "warn"  // Log the violation and return the call unchanged
+	ValidationSeverityFix   = "fix"   // Correct the call (default)
+	ValidationSeverityBlock = "block" // Replace the call with a text note so the client never runs it
+)
+
+// validationRules and validationSeverities list the values VALIDATION_SEVERITY accepts
+var (
+	validationRules      = []string{ValidationRuleMissingParam, ValidationRuleInvalidParam, ValidationRuleStructural, ValidationRuleSemantic}
+	validationSeverities = []string{ValidationSeverityWarn, ValidationSeverityFix, ValidationSeverityBlock}
+)
+
+// ValidationSeverityFor returns the severity configured for a validation
+// rule, ValidationSeverityFix when VALIDATION_SEVERITY does not name it
+func (c *Config) ValidationSeverityFor(rule string) string {
+	if severity, exists := c.ValidationSeverity[rule]; exists {
+		return severity
+	}
+	return ValidationSeverityFix
+}
+
+// parseValidationSeverity parses VALIDATION_SEVERITY entries of the form
+// rule:severity, comma-separated
+func parseValidationSeverity(value string) (map[string]string, error) {
+	severities := make(map[string]string)
+	for _, entry := range splitList(value) {
+		rule, severity, found := strings.Cut(entry, ":")
+		rule = strings.ToLower(strings.TrimSpace(rule))
+		severity = strings.ToLower(strings.TrimSpace(severity))
+		if !found || rule == "" {
+			return nil, fmt.Errorf("entry %q must be rule:severity", entry)
+		}
+		if !containsString(validationRules, rule) {
+			return nil, fmt.Errorf("unknown rule %q, must be one of: %s", rule, strings.Join(validationRules, ", "))
+		}
+		if !containsString(validationSeverities, severity) {
+			return nil, fmt.Errorf("unknown severity %q for %s, must be one of: %s", severity, rule, strings.Join(validationSeverities, ", "))
+		}
+		severities[rule] = severity
+	}
+	return severities, nil
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package config
+
+import "testing"
+
+// TestValidationSeverityFor tests VALIDATION_SEVERITY parsing and the fix default
+func TestValidationSeverityFor(t *testing.T) {
+	severities, err := parseValidationSeverity("missing_param:warn, SEMANTIC:Block")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cfg := &Config{ValidationSeverity: severities}
+
+	tests := map[string]string{
+		ValidationRuleMissingParam: ValidationSeverityWarn,
+		ValidationRuleSemantic:     ValidationSeverityBlock,
+		ValidationRuleInvalidParam: ValidationSeverityFix, // Unlisted
+		ValidationRuleStructural:   ValidationSeverityFix,
+	}
+	for rule, expected := range tests {
+		if got := cfg.ValidationSeverityFor(rule); got != expected {
+			t.Errorf("%s: expected %s, got %s", rule, expected, got)
+		}
+	}
+
+	for _, value := range []string{"missing_param", "typo:warn", "semantic:ignore", ":block"} {
+		if _, err := parseValidationSeverity(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
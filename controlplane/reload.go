@@ -0,0 +1,118 @@
+package controlplane
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/logger"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// ReloadResult reports what a reload applied and what still needs a restart
+type ReloadResult struct {
+	LoadedAt           time.Time          `json:"loaded_at"`
+	LogLevel           string             `json:"log_level"`
+	ComponentLogLevels map[string]string  `json:"component_log_levels"`
+	SpendLimits        config.SpendLimits `json:"spend_limits"`
+	RestartRequired    []string           `json:"restart_required"` // Changed settings the running proxy keeps until restarted
+}
+
+// SpendLimitSetter applies client spend limits to a running handler
+type SpendLimitSetter interface {
+	SetSpendLimits(limits config.SpendLimits)
+}
+
+// EnvReloader returns a Reloader that loads the configuration again from .env
+// and the override files. A configuration that fails to load changes nothing.
+// Log levels are applied to levels and spend limits to limits (nil skips
+// them); other differences from current are only reported, since handlers,
+// clients and circuit breakers were built from it.
+func EnvReloader(current *config.Config, levels *logger.LevelController, limits SpendLimitSetter) Reloader {
+	return func() (ReloadResult, error) {
+		fresh, err := config.LoadConfigWithEnv()
+		if err != nil {
+			return ReloadResult{}, err
+		}
+
+		defaultLevel, err := logger.ParseLevel(fresh.LogLevel)
+		if err != nil {
+			return ReloadResult{}, err
+		}
+		if err := levels.Replace(defaultLevel, fresh.ComponentLogLevels); err != nil {
+			return ReloadResult{}, err
+		}
+
+		result := ReloadResult{LoadedAt: time.Now(), SpendLimits: current.SpendLimits}
+		result.LogLevel, result.ComponentLogLevels = levels.Snapshot()
+		if limits != nil {
+			limits.SetSpendLimits(fresh.SpendLimits)
+			result.SpendLimits = fresh.SpendLimits
+		}
+		for _, name := range changedSettings(current.Sanitized(), fresh.Sanitized()) {
+			if name == "spend_limits" && limits != nil {
+				continue // Applied above
+			}
+			result.RestartRequired = append(result.RestartRequired, name)
+		}
+		if result.RestartRequired == nil {
+			result.RestartRequired = []string{}
+		}
+		return result, nil
+	}
+}
+
+// changedSettings lists the top-level settings that differ between two
+// sanitized configurations, ignoring load metadata, runtime endpoint health
+// and the log levels a reload applies
+func changedSettings(current, fresh config.SanitizedConfig) []string {
+	changed := []string{}
+	if !reflect.DeepEqual(endpointURLs(current), endpointURLs(fresh)) {
+		changed = append(changed, "endpoints")
+	}
+	if current.Logging.ConversationLogLevel != fresh.Logging.ConversationLogLevel ||
+		current.Logging.ConversationTruncation != fresh.Logging.ConversationTruncation ||
+		current.Logging.TruncationTokens != fresh.Logging.TruncationTokens ||
+		!reflect.DeepEqual(current.Logging.ConversationLogFilter, fresh.Logging.ConversationLogFilter) {
+		changed = append(changed, "logging")
+	}
+
+	currentFields, freshFields := jsonFields(current), jsonFields(fresh)
+	for name, value := range freshFields {
+		switch name {
+		case "source", "endpoints", "endpoint_specs", "logging", "override_files":
+			continue
+		}
+		if !reflect.DeepEqual(currentFields[name], value) {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// endpointURLs returns the configured endpoint URLs of each role, sorted,
+// since health reordering changes their order at runtime. Roles configured
+// with dns+/srv+ specs are compared by spec, not by the resolved addresses.
+func endpointURLs(s config.SanitizedConfig) [3][]string {
+	var urls [3][]string
+	for i, statuses := range [][]config.EndpointStatus{s.Endpoints.Big, s.Endpoints.Small, s.Endpoints.ToolCorrection} {
+		if specs, exists := s.EndpointSpecs[config.EndpointRoles[i]]; exists {
+			urls[i] = append(urls[i], specs...)
+		} else {
+			for _, status := range statuses {
+				urls[i] = append(urls[i], status.URL)
+			}
+		}
+		sort.Strings(urls[i])
+	}
+	return urls
+}
+
+// jsonFields decodes s into its top-level JSON fields
+func jsonFields(s config.SanitizedConfig) map[string]interface{} {
+	data, _ := json.Marshal(s)
+	fields := map[string]interface{}{}
+	json.Unmarshal(data, &fields)
+	return fields
+}
@@ -0,0 +1,172 @@
+// Package controlplane serves the gRPC admin API used for programmatic fleet
+// management: configuration dump, endpoint health, live requests and reload.
+// The service is described in controlplane.proto.
+package controlplane
+
+import (
+	"claude-proxy/circuitbreaker"
+	"claude-proxy/config"
+	"claude-proxy/proxy"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// ServiceName is the fully qualified gRPC service name
+const ServiceName = "simpleproxy.admin.v1.ControlPlane"
+
+// RequestLister lists the requests currently being served
+type RequestLister interface {
+	InFlight() []proxy.InFlightRequest
+}
+
+// Reloader re-reads configuration and applies what can change at runtime
+type Reloader func() (ReloadResult, error)
+
+// Server implements the ControlPlane service
+type Server struct {
+	cfg      *config.Config
+	requests RequestLister
+	reload   Reloader
+}
+
+// NewServer creates a control plane over the running proxy's configuration
+// and request handler. A nil reload makes Reload return Unimplemented.
+func NewServer(cfg *config.Config, requests RequestLister, reload Reloader) *Server {
+	return &Server{cfg: cfg, requests: requests, reload: reload}
+}
+
+// NewGRPCServer returns a gRPC server with the control plane registered.
+// When token is set every call must carry "authorization: Bearer <token>".
+func NewGRPCServer(s *Server, token string) *grpc.Server {
+	var opts []grpc.ServerOption
+	if token != "" {
+		opts = append(opts, grpc.UnaryInterceptor(bearerTokenInterceptor(token)))
+	}
+	server := grpc.NewServer(opts...)
+	server.RegisterService(&serviceDesc, s)
+	return server
+}
+
+// GetConfig returns the effective configuration with secrets masked
+func (s *Server) GetConfig(ctx context.Context, _ *emptypb.Empty) (*structpb.Struct, error) {
+	return toStruct(s.cfg.Sanitized())
+}
+
+// GetEndpointHealth returns the endpoints of each role and their circuit state
+func (s *Server) GetEndpointHealth(ctx context.Context, _ *emptypb.Empty) (*structpb.Struct, error) {
+	health := struct {
+		Endpoints interface{}                     `json:"endpoints"`
+		Circuits  []circuitbreaker.EndpointHealth `json:"circuits"`
+	}{
+		Endpoints: s.cfg.Sanitized().Endpoints,
+		Circuits:  []circuitbreaker.EndpointHealth{},
+	}
+	if s.cfg.HealthManager != nil {
+		health.Circuits = s.cfg.HealthManager.Snapshot()
+	}
+	return toStruct(health)
+}
+
+// ListRequests returns the requests currently being served, oldest first
+func (s *Server) ListRequests(ctx context.Context, _ *emptypb.Empty) (*structpb.Struct, error) {
+	return toStruct(struct {
+		Requests []proxy.InFlightRequest `json:"requests"`
+	}{Requests: s.requests.InFlight()})
+}
+
+// Reload re-reads the configuration and applies the runtime-changeable parts
+func (s *Server) Reload(ctx context.Context, _ *emptypb.Empty) (*structpb.Struct, error) {
+	if s.reload == nil {
+		return nil, status.Error(codes.Unimplemented, "reload is not configured")
+	}
+	result, err := s.reload()
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "reload failed: %v", err)
+	}
+	return toStruct(result)
+}
+
+// toStruct converts v to a Struct through its JSON encoding, so responses
+// match the HTTP admin endpoints field for field
+func toStruct(v interface{}) (*structpb.Struct, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode response: %v", err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode response: %v", err)
+	}
+	result, err := structpb.NewStruct(fields)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode response: %v", err)
+	}
+	return result, nil
+}
+
+// bearerTokenInterceptor rejects calls without the expected bearer token
+func bearerTokenInterceptor(token string) grpc.UnaryServerInterceptor {
+	expected := []byte("Bearer " + token)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		for _, value := range md.Get("authorization") {
+			if subtle.ConstantTimeCompare([]byte(strings.TrimSpace(value)), expected) == 1 {
+				return handler(ctx, req)
+			}
+		}
+		return nil, status.Error(codes.Unauthenticated, "missing or invalid bearer token")
+	}
+}
+
+// controlPlaneServer is the handler type serviceDesc dispatches to
+type controlPlaneServer interface {
+	GetConfig(context.Context, *emptypb.Empty) (*structpb.Struct, error)
+	GetEndpointHealth(context.Context, *emptypb.Empty) (*structpb.Struct, error)
+	ListRequests(context.Context, *emptypb.Empty) (*structpb.Struct, error)
+	Reload(context.Context, *emptypb.Empty) (*structpb.Struct, error)
+}
+
+// unaryMethod adapts a controlPlaneServer method to a grpc.MethodDesc
+func unaryMethod(name string, call func(controlPlaneServer, context.Context, *emptypb.Empty) (*structpb.Struct, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			in := new(emptypb.Empty)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			server := srv.(controlPlaneServer)
+			if interceptor == nil {
+				return call(server, ctx, in)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: fmt.Sprintf("/%s/%s", ServiceName, name)}
+			return interceptor(ctx, in, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return call(server, ctx, req.(*emptypb.Empty))
+			})
+		},
+	}
+}
+
+// serviceDesc is written by hand in place of protoc-gen-go-grpc output; it
+// must stay in sync with controlplane.proto
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*controlPlaneServer)(nil),
+	Methods: []grpc.MethodDesc{
+		unaryMethod("GetConfig", controlPlaneServer.GetConfig),
+		unaryMethod("GetEndpointHealth", controlPlaneServer.GetEndpointHealth),
+		unaryMethod("ListRequests", controlPlaneServer.ListRequests),
+		unaryMethod("Reload", controlPlaneServer.Reload),
+	},
+	Metadata: "controlplane/controlplane.proto",
+}
@@ -0,0 +1,137 @@
+// Package conversation keeps logged conversations in an embedded, indexed
+// store so past exchanges can be found by content, tool name or request ID
+// without querying Loki.
+//
+// The index is an inverted term index in bbolt rather than SQLite FTS: bbolt
+// already backs the stats and batch stores, and SQLite would need cgo
+// (mattn/go-sqlite3) or a large transpiled driver (modernc.org/sqlite) in an
+// otherwise pure Go, single-binary proxy. The cost is simpler matching: terms
+// match exactly or by prefix, and results come newest first, not ranked.
+package conversation
+
+import (
+	"claude-proxy/types"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Exchange is one logged request/response pair of a session
+type Exchange struct {
+	RequestID string          `json:"request_id"`
+	SessionID string          `json:"session_id"`
+	Model     string          `json:"model"` // Model the client requested
+	Time      time.Time       `json:"time"`
+	ToolNames []string        `json:"tool_names,omitempty"` // Tools called in the response
+	Text      string          `json:"text"`                 // Searchable text: the new turn and the response
+	Request   json.RawMessage `json:"request"`
+	Response  json.RawMessage `json:"response,omitempty"`
+}
+
+// NewExchange builds an exchange from a request and its response. Requests
+// repeat the whole conversation, so only the messages after the last
+// assistant turn are indexed together with the response.
+func NewExchange(requestID, sessionID, model string, req types.AnthropicRequest, resp *types.AnthropicResponse) (Exchange, error) {
+	exchange := Exchange{
+		RequestID: requestID,
+		SessionID: sessionID,
+		Model:     model,
+		Time:      time.Now().UTC(),
+	}
+
+	var err error
+	if exchange.Request, err = json.Marshal(req); err != nil {
+		return exchange, err
+	}
+
+	var text []string
+	newTurn := len(req.Messages)
+	for newTurn > 0 && req.Messages[newTurn-1].Role != "assistant" {
+		newTurn--
+	}
+	for _, msg := range req.Messages[newTurn:] {
+		text = appendStrings(text, msg.Content)
+	}
+
+	if resp != nil {
+		if exchange.Response, err = json.Marshal(resp); err != nil {
+			return exchange, err
+		}
+		for _, content := range resp.Content {
+			switch content.Type {
+			case "text":
+				text = append(text, content.Text)
+			case "tool_use":
+				exchange.ToolNames = append(exchange.ToolNames, content.Name)
+				text = append(text, content.Name)
+				text = appendStrings(text, content.Input)
+			}
+		}
+	}
+
+	exchange.Text = strings.Join(text, "\n")
+	return exchange, nil
+}
+
+// appendStrings appends every string in a decoded JSON value except block
+// metadata such as types and IDs
+func appendStrings(text []string, value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		if v != "" {
+			text = append(text, v)
+		}
+	case []interface{}:
+		for _, item := range v {
+			text = appendStrings(text, item)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			switch key {
+			case "type", "id", "tool_use_id", "signature", "cache_control":
+				continue
+			}
+			text = appendStrings(text, v[key])
+		}
+	case []types.Content:
+		for _, content := range v {
+			text = append(text, content.Text, content.Name)
+			text = appendStrings(text, content.Input)
+		}
+	}
+	return text
+}
+
+// Maximum indexed term length; longer tokens are base64 blobs and the like
+const maxTermLength = 64
+
+// terms splits text into lowercase index terms of letters, digits and
+// underscores, so request IDs (req_1234) and tool names (mcp__github__x)
+// stay whole
+func terms(text string) []string {
+	seen := map[string]bool{}
+	var result []string
+	for _, field := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}) {
+		if len(field) < 2 || len(field) > maxTermLength || seen[field] {
+			continue
+		}
+		seen[field] = true
+		result = append(result, field)
+	}
+	return result
+}
+
+// toolTerm is the index term of a called tool. The # keeps it apart from
+// text terms, so text prefixes never match tool names.
+func toolTerm(name string) string {
+	return "#tool:" + strings.ToLower(name)
+}
@@ -0,0 +1,371 @@
+package conversation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	exchangesBucket  = []byte("exchanges")
+	termsBucket      = []byte("terms")
+	requestIDsBucket = []byte("request_ids")
+)
+
+// Store persists exchanges in an embedded bbolt database with an inverted
+// index of their terms
+type Store struct {
+	db *bolt.DB
+}
+
+// Query selects exchanges. Text terms must all match; a trailing * matches
+// any term with that prefix. Empty fields do not filter.
+type Query struct {
+	Text      string
+	Tool      string
+	RequestID string
+	SessionID string
+	Limit     int
+}
+
+// SearchResult is an exchange matching a query, without its full bodies
+type SearchResult struct {
+	RequestID string    `json:"request_id"`
+	SessionID string    `json:"session_id"`
+	Model     string    `json:"model"`
+	Time      time.Time `json:"time"`
+	ToolNames []string  `json:"tool_names,omitempty"`
+	Snippet   string    `json:"snippet"`
+}
+
+// DefaultSearchLimit bounds results when a query sets no limit
+const DefaultSearchLimit = 50
+
+// OpenStore opens (or creates) the conversation database at path
+func OpenStore(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 2 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open conversation store %s: %v", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{exchangesBucket, termsBucket, requestIDsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize conversation store: %v", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close closes the underlying database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// exchangeKey orders exchanges by time
+func exchangeKey(exchange Exchange) []byte {
+	return []byte(fmt.Sprintf("%020d/%s", exchange.Time.UnixNano(), exchange.RequestID))
+}
+
+// termKey is an index entry: the term, a separator and the exchange key
+func termKey(term string, key []byte) []byte {
+	return append([]byte(term+"\x00"), key...)
+}
+
+// Add stores an exchange and indexes its text, tool names and request ID
+func (s *Store) Add(exchange Exchange) error {
+	data, err := json.Marshal(exchange)
+	if err != nil {
+		return err
+	}
+	key := exchangeKey(exchange)
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(exchangesBucket).Put(key, data); err != nil {
+			return err
+		}
+		index := tx.Bucket(termsBucket)
+		for _, term := range indexTerms(exchange) {
+			if err := index.Put(termKey(term, key), nil); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(requestIDsBucket).Put([]byte(exchange.RequestID), key)
+	})
+}
+
+// indexTerms returns the index terms of an exchange: its text, tool names and request ID
+func indexTerms(exchange Exchange) []string {
+	result := terms(exchange.Text + " " + exchange.RequestID)
+	for _, name := range exchange.ToolNames {
+		result = append(result, toolTerm(name))
+	}
+	return result
+}
+
+// Purge deletes exchanges logged before the given time (zero keeps all ages),
+// then the oldest exchanges until the stored ones take at most maxBytes
+// (0 is unlimited). Returns the number of exchanges deleted.
+func (s *Store) Purge(before time.Time, maxBytes int64) (int, error) {
+	deleted := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var size int64
+		var keys [][]byte
+		var sizes []int64
+		cursor := tx.Bucket(exchangesBucket).Cursor()
+		for key, data := cursor.First(); key != nil; key, data = cursor.Next() {
+			keys = append(keys, append([]byte(nil), key...))
+			sizes = append(sizes, int64(len(data)))
+			size += int64(len(data))
+		}
+
+		// Keys sort by time, so expired and oversized exchanges are a prefix
+		cutoff := []byte(fmt.Sprintf("%020d/", before.UnixNano()))
+		for i, key := range keys {
+			expired := !before.IsZero() && bytes.Compare(key, cutoff) < 0
+			oversized := maxBytes > 0 && size > maxBytes
+			if !expired && !oversized {
+				break
+			}
+			if err := deleteExchange(tx, key); err != nil {
+				return err
+			}
+			size -= sizes[i]
+			deleted++
+		}
+		return nil
+	})
+	return deleted, err
+}
+
+// Delete removes every exchange of a session and/or request ID, for data
+// deletion requests. At least one of them must be set.
+func (s *Store) Delete(sessionID, requestID string) (int, error) {
+	if sessionID == "" && requestID == "" {
+		return 0, fmt.Errorf("a session or request ID is required")
+	}
+	deleted := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var keys [][]byte
+		err := tx.Bucket(exchangesBucket).ForEach(func(key, data []byte) error {
+			var exchange Exchange
+			if err := json.Unmarshal(data, &exchange); err != nil {
+				return err
+			}
+			if (sessionID == "" || exchange.SessionID == sessionID) && (requestID == "" || exchange.RequestID == requestID) {
+				keys = append(keys, append([]byte(nil), key...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if err := deleteExchange(tx, key); err != nil {
+				return err
+			}
+			deleted++
+		}
+		return nil
+	})
+	return deleted, err
+}
+
+// deleteExchange removes an exchange together with its index entries
+func deleteExchange(tx *bolt.Tx, key []byte) error {
+	exchanges := tx.Bucket(exchangesBucket)
+	var exchange Exchange
+	if err := json.Unmarshal(exchanges.Get(key), &exchange); err != nil {
+		return err
+	}
+	index := tx.Bucket(termsBucket)
+	for _, term := range indexTerms(exchange) {
+		if err := index.Delete(termKey(term, key)); err != nil {
+			return err
+		}
+	}
+	requestIDs := tx.Bucket(requestIDsBucket)
+	if bytes.Equal(requestIDs.Get([]byte(exchange.RequestID)), key) {
+		if err := requestIDs.Delete([]byte(exchange.RequestID)); err != nil {
+			return err
+		}
+	}
+	return exchanges.Delete(key)
+}
+
+// Get returns the latest exchange logged for a request ID
+func (s *Store) Get(requestID string) (exchange Exchange, ok bool, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		key := tx.Bucket(requestIDsBucket).Get([]byte(requestID))
+		if key == nil {
+			return nil
+		}
+		data := tx.Bucket(exchangesBucket).Get(key)
+		if data == nil {
+			return nil
+		}
+		ok = true
+		return json.Unmarshal(data, &exchange)
+	})
+	return exchange, ok, err
+}
+
+// Session returns the exchanges of a session, oldest first
+func (s *Store) Session(sessionID string) ([]Exchange, error) {
+	var exchanges []Exchange
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(exchangesBucket).ForEach(func(_, data []byte) error {
+			var exchange Exchange
+			if err := json.Unmarshal(data, &exchange); err != nil {
+				return err
+			}
+			if exchange.SessionID == sessionID {
+				exchanges = append(exchanges, exchange)
+			}
+			return nil
+		})
+	})
+	return exchanges, err
+}
+
+// Search returns the exchanges matching q, newest first
+func (s *Store) Search(q Query) ([]SearchResult, error) {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+
+	var wanted []string
+	for _, word := range strings.Fields(q.Text) {
+		prefix := strings.HasSuffix(word, "*")
+		for _, term := range terms(word) {
+			if prefix {
+				term += "*"
+			}
+			wanted = append(wanted, term)
+		}
+		if prefix && len(terms(word)) == 0 {
+			return nil, fmt.Errorf("prefix %q is too short", word)
+		}
+	}
+	if q.Tool != "" {
+		wanted = append(wanted, toolTerm(q.Tool))
+	}
+
+	results := []SearchResult{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		exchanges := tx.Bucket(exchangesBucket)
+
+		var candidates map[string]bool
+		if q.RequestID != "" {
+			candidates = map[string]bool{}
+			if key := tx.Bucket(requestIDsBucket).Get([]byte(q.RequestID)); key != nil {
+				candidates[string(key)] = true
+			}
+		}
+		for _, term := range wanted {
+			matches := matchTerm(tx.Bucket(termsBucket), term)
+			if candidates == nil {
+				candidates = matches
+				continue
+			}
+			for key := range candidates {
+				if !matches[key] {
+					delete(candidates, key)
+				}
+			}
+		}
+
+		// Newest first: walk exchange keys backwards
+		cursor := exchanges.Cursor()
+		for key, data := cursor.Last(); key != nil && len(results) < limit; key, data = cursor.Prev() {
+			if candidates != nil && !candidates[string(key)] {
+				continue
+			}
+			var exchange Exchange
+			if err := json.Unmarshal(data, &exchange); err != nil {
+				return err
+			}
+			if q.SessionID != "" && exchange.SessionID != q.SessionID {
+				continue
+			}
+			results = append(results, SearchResult{
+				RequestID: exchange.RequestID,
+				SessionID: exchange.SessionID,
+				Model:     exchange.Model,
+				Time:      exchange.Time,
+				ToolNames: exchange.ToolNames,
+				Snippet:   snippet(exchange.Text, wanted),
+			})
+		}
+		return nil
+	})
+	return results, err
+}
+
+// matchTerm returns the exchange keys indexed under term, or under any term
+// starting with it when it ends in *
+func matchTerm(index *bolt.Bucket, term string) map[string]bool {
+	prefix := []byte(term + "\x00")
+	if strings.HasSuffix(term, "*") {
+		prefix = []byte(strings.TrimSuffix(term, "*"))
+	}
+
+	matches := map[string]bool{}
+	cursor := index.Cursor()
+	for key, _ := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Next() {
+		if separator := bytes.IndexByte(key, 0); separator >= 0 {
+			matches[string(key[separator+1:])] = true
+		}
+	}
+	return matches
+}
+
+// snippetRadius is how much text around the first match a result shows
+const snippetRadius = 80
+
+// snippet returns the text around the first matched term
+func snippet(text string, wanted []string) string {
+	lower := strings.ToLower(text)
+	start := 0
+	for _, term := range wanted {
+		if strings.HasPrefix(term, "#") {
+			continue
+		}
+		if i := strings.Index(lower, strings.TrimSuffix(term, "*")); i >= 0 {
+			start = i
+			break
+		}
+	}
+
+	from, to := start-snippetRadius, start+snippetRadius
+	if from < 0 {
+		from = 0
+	}
+	if to > len(text) {
+		to = len(text)
+	}
+	// Keep cuts on UTF-8 boundaries
+	for from > 0 && !isRuneStart(text[from]) {
+		from--
+	}
+	for to < len(text) && !isRuneStart(text[to]) {
+		to++
+	}
+	return strings.Join(strings.Fields(text[from:to]), " ")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
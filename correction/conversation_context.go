@@ -0,0 +1,79 @@
+package correction
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/tokenizer"
+	"claude-proxy/types"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// conversationKey stores the request's conversation in its context
+type conversationKey struct{}
+
+// WithConversation returns a context carrying the request's conversation so
+// correction prompts can include its most recent messages
+func WithConversation(ctx context.Context, messages []types.OpenAIMessage) context.Context {
+	return context.WithValue(ctx, conversationKey{}, messages)
+}
+
+// conversationFromContext returns the conversation stored by WithConversation
+func conversationFromContext(ctx context.Context) []types.OpenAIMessage {
+	messages, _ := ctx.Value(conversationKey{}).([]types.OpenAIMessage)
+	return messages
+}
+
+// conversationTail renders the last messages of the request's conversation
+// for the correction prompt, within CORRECTION_CONTEXT_MESSAGES and
+// CORRECTION_CONTEXT_TOKENS. Each message gets an equal share of the token
+// budget so one long message cannot crowd out the others. System messages
+// are skipped. Returns "" when the tail is disabled or there is no conversation.
+func (s *Service) conversationTail(ctx context.Context) string {
+	limits, ok := s.config.(config.CorrectionContextConfig)
+	if !ok {
+		return ""
+	}
+	maxMessages, maxTokens := limits.CorrectionContextLimits()
+	messages := conversationFromContext(ctx)
+	if maxMessages <= 0 || maxTokens <= 0 || len(messages) == 0 {
+		return ""
+	}
+
+	perMessage := maxTokens / maxMessages
+	if perMessage < 1 {
+		perMessage = 1
+	}
+
+	// Newest messages first, then restored to conversation order
+	var lines []string
+	for i := len(messages) - 1; i >= 0 && len(lines) < maxMessages; i-- {
+		if line := conversationLine(messages[i]); line != "" {
+			lines = append(lines, tokenizer.Truncate(line, perMessage, " ... [truncated] ... "))
+		}
+	}
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// conversationLine renders one message as "ROLE: content", naming the tools
+// an assistant message called
+func conversationLine(msg types.OpenAIMessage) string {
+	if msg.Role == "system" {
+		return ""
+	}
+	content := strings.TrimSpace(msg.Content)
+	if len(msg.ToolCalls) > 0 {
+		var calls []string
+		for _, tc := range msg.ToolCalls {
+			calls = append(calls, fmt.Sprintf("%s(%s)", tc.Function.Name, tc.Function.Arguments))
+		}
+		content = strings.TrimSpace(content + " [Used tools: " + strings.Join(calls, ", ") + "]")
+	}
+	if content == "" {
+		return ""
+	}
+	return strings.ToUpper(msg.Role) + ": " + content
+}
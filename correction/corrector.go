@@ -0,0 +1,140 @@
+package correction
+
+import (
+	"bytes"
+	"claude-proxy/config"
+	"claude-proxy/internal"
+	"claude-proxy/types"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Correction backends selectable with CORRECTION_BACKEND
+const (
+	BackendLLM    = config.CorrectionBackendLLM
+	BackendRules  = config.CorrectionBackendRules
+	BackendRemote = config.CorrectionBackendRemote
+)
+
+// Corrector repairs invalid tool calls in a model response. Calls it cannot
+// fix are returned unchanged.
+type Corrector interface {
+	CorrectToolCalls(ctx context.Context, toolCalls []types.Content, availableTools []types.Tool) ([]types.Content, error)
+}
+
+var (
+	_ Corrector = (*Service)(nil)
+	_ Corrector = (*RulesCorrector)(nil)
+	_ Corrector = (*RemoteCorrector)(nil)
+)
+
+// NewCorrector returns the Corrector for a backend. service backs the llm and
+// rules backends; remoteURL and apiKey configure the remote backend.
+func NewCorrector(backend string, service *Service, remoteURL, apiKey string, timeout time.Duration) (Corrector, error) {
+	switch backend {
+	case "", BackendLLM:
+		return service, nil
+	case BackendRules:
+		return NewRulesCorrector(service), nil
+	case BackendRemote:
+		if remoteURL == "" {
+			return nil, fmt.Errorf("remote correction backend requires a URL")
+		}
+		return NewRemoteCorrector(remoteURL, apiKey, timeout), nil
+	default:
+		return nil, fmt.Errorf("unknown correction backend %q, must be %q, %q or %q", backend, BackendLLM, BackendRules, BackendRemote)
+	}
+}
+
+// RulesCorrector applies only the rule-based stages of Service (case and
+// alias fixes, slash commands, semantic and parameter rules), for
+// deployments that cannot run a correction model.
+type RulesCorrector struct {
+	service *Service
+}
+
+// NewRulesCorrector creates a rules-only corrector sharing service's validator and rules
+func NewRulesCorrector(service *Service) *RulesCorrector {
+	return &RulesCorrector{service: service}
+}
+
+// CorrectToolCalls corrects tool calls without calling the correction model
+func (r *RulesCorrector) CorrectToolCalls(ctx context.Context, toolCalls []types.Content, availableTools []types.Tool) ([]types.Content, error) {
+	return r.service.correctToolCalls(ctx, toolCalls, availableTools, false)
+}
+
+// RemoteCorrector delegates correction to an external service. It POSTs
+//
+//	{"tool_calls": [...], "tools": [...]}
+//
+// using the Anthropic tool_use and tool shapes and expects
+//
+//	{"tool_calls": [...]}
+//
+// with one corrected call per input call, in order.
+type RemoteCorrector struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+// remoteCorrectionRequest is the body sent to a remote correction service
+type remoteCorrectionRequest struct {
+	ToolCalls []types.Content `json:"tool_calls"`
+	Tools     []types.Tool    `json:"tools"`
+}
+
+// remoteCorrectionResponse is the body a remote correction service returns
+type remoteCorrectionResponse struct {
+	ToolCalls []types.Content `json:"tool_calls"`
+}
+
+// NewRemoteCorrector creates a corrector calling the service at url
+func NewRemoteCorrector(url, apiKey string, timeout time.Duration) *RemoteCorrector {
+	return &RemoteCorrector{url: url, apiKey: apiKey, client: &http.Client{Timeout: timeout}}
+}
+
+// CorrectToolCalls sends the calls to the remote service. On any failure the
+// original calls are returned together with the error.
+func (r *RemoteCorrector) CorrectToolCalls(ctx context.Context, toolCalls []types.Content, availableTools []types.Tool) ([]types.Content, error) {
+	body, err := json.Marshal(remoteCorrectionRequest{ToolCalls: toolCalls, Tools: availableTools})
+	if err != nil {
+		return toolCalls, fmt.Errorf("failed to marshal remote correction request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return toolCalls, fmt.Errorf("failed to create remote correction request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if r.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+r.apiKey)
+	}
+	if requestID := getRequestID(ctx); requestID != "unknown" {
+		req.Header.Set(internal.RequestIDHeader, requestID)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return toolCalls, fmt.Errorf("remote correction request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return toolCalls, fmt.Errorf("remote correction service returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var corrected remoteCorrectionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&corrected); err != nil {
+		return toolCalls, fmt.Errorf("failed to parse remote correction response: %v", err)
+	}
+	if len(corrected.ToolCalls) != len(toolCalls) {
+		return toolCalls, fmt.Errorf("remote correction service returned %d calls for %d", len(corrected.ToolCalls), len(toolCalls))
+	}
+	return corrected.ToolCalls, nil
+}
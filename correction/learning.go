@@ -0,0 +1,176 @@
+package correction
+
+import (
+	"claude-proxy/logger"
+	"claude-proxy/types"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LearnedMapping is a parameter rename the correction model applied to a tool
+type LearnedMapping struct {
+	Tool      string    `json:"tool"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Count     int       `json:"count"`
+	Promoted  bool      `json:"promoted"` // Applied by rule-based correction
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// learnedMappingKey identifies a rename of one parameter of one tool
+type learnedMappingKey struct {
+	tool, from, to string
+}
+
+// RuleLearner records the parameter renames of successful LLM corrections.
+// Once the same rename has been seen threshold times it is learned; with auto
+// promotion on, learned renames join the rule-based parameter mappings so the
+// correction model is no longer needed for them.
+type RuleLearner struct {
+	mutex       sync.Mutex
+	threshold   int
+	autoPromote bool
+	mappings    map[learnedMappingKey]*LearnedMapping
+}
+
+// NewRuleLearner creates a rule learner. Thresholds below 1 are treated as 1.
+func NewRuleLearner(threshold int, autoPromote bool) *RuleLearner {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &RuleLearner{
+		threshold:   threshold,
+		autoPromote: autoPromote,
+		mappings:    make(map[learnedMappingKey]*LearnedMapping),
+	}
+}
+
+// parameterRenames returns the parameters renamed between two inputs, old
+// name to new. A rename is a parameter that disappeared while a new one with
+// the same value appeared; values shared by several removed or added
+// parameters are ambiguous and skipped.
+func parameterRenames(original, corrected map[string]interface{}) map[string]string {
+	removed := make(map[string][]string)
+	for key, value := range original {
+		if _, exists := corrected[key]; !exists {
+			text := fmt.Sprintf("%v", value)
+			removed[text] = append(removed[text], key)
+		}
+	}
+	added := make(map[string][]string)
+	for key, value := range corrected {
+		if _, exists := original[key]; !exists {
+			text := fmt.Sprintf("%v", value)
+			added[text] = append(added[text], key)
+		}
+	}
+
+	renames := make(map[string]string)
+	for value, from := range removed {
+		if to := added[value]; len(from) == 1 && len(to) == 1 {
+			renames[from[0]] = to[0]
+		}
+	}
+	return renames
+}
+
+// Observe records the parameter renames between a tool call and its
+// correction and returns the mappings promoted by it
+func (l *RuleLearner) Observe(original, corrected types.Content, now time.Time) []LearnedMapping {
+	if l == nil || original.Name != corrected.Name {
+		return nil
+	}
+	renames := parameterRenames(original.Input, corrected.Input)
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var promoted []LearnedMapping
+	for from, to := range renames {
+		key := learnedMappingKey{tool: original.Name, from: from, to: to}
+		mapping, exists := l.mappings[key]
+		if !exists {
+			mapping = &LearnedMapping{Tool: key.tool, From: key.from, To: key.to, FirstSeen: now}
+			l.mappings[key] = mapping
+		}
+		mapping.Count++
+		mapping.LastSeen = now
+		if l.autoPromote && !mapping.Promoted && mapping.Count >= l.threshold {
+			mapping.Promoted = true
+			promoted = append(promoted, *mapping)
+		}
+	}
+	return promoted
+}
+
+// Promoted returns the promoted renames of a tool, old parameter name to new
+func (l *RuleLearner) Promoted(tool string) map[string]string {
+	if l == nil {
+		return nil
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var mappings map[string]string
+	for key, mapping := range l.mappings {
+		if key.tool != tool || !mapping.Promoted {
+			continue
+		}
+		if mappings == nil {
+			mappings = make(map[string]string)
+		}
+		mappings[key.from] = key.to
+	}
+	return mappings
+}
+
+// Learned returns the renames seen at least threshold times, most frequent first
+func (l *RuleLearner) Learned() []LearnedMapping {
+	if l == nil {
+		return nil
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	learned := []LearnedMapping{}
+	for _, mapping := range l.mappings {
+		if mapping.Count >= l.threshold {
+			learned = append(learned, *mapping)
+		}
+	}
+	sort.Slice(learned, func(i, j int) bool {
+		if learned[i].Count != learned[j].Count {
+			return learned[i].Count > learned[j].Count
+		}
+		if learned[i].Tool != learned[j].Tool {
+			return learned[i].Tool < learned[j].Tool
+		}
+		return learned[i].From < learned[j].From
+	})
+	return learned
+}
+
+// SetRuleLearner records the renames of successful LLM corrections with l
+func (s *Service) SetRuleLearner(l *RuleLearner) {
+	s.learner = l
+}
+
+// RuleLearner returns the service's rule learner, nil when learning is off
+func (s *Service) RuleLearner() *RuleLearner {
+	return s.learner
+}
+
+// learnFromCorrection records the renames of a successful LLM correction
+func (s *Service) learnFromCorrection(requestID string, original, corrected types.Content) {
+	for _, mapping := range s.learner.Observe(original, corrected, time.Now()) {
+		s.logInfo(logger.ComponentToolCorrection, logger.CategoryTransformation, requestID, "Promoted learned parameter correction to rule", map[string]interface{}{
+			"tool_name":       mapping.Tool,
+			"original_param":  mapping.From,
+			"corrected_param": mapping.To,
+			"observations":    mapping.Count,
+		})
+	}
+}
@@ -0,0 +1,306 @@
+package correction
+
+import (
+	"bufio"
+	"claude-proxy/types"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Kinds of invalid patterns found by MineCorrections
+const (
+	PatternToolName     = "tool_name"     // Tool called by a wrong name
+	PatternRenamedParam = "renamed_param" // Parameter passed under a wrong name
+	PatternMissingParam = "missing_param" // Parameter the correction had to add
+	PatternUnknownParam = "unknown_param" // Parameter the correction dropped
+	PatternChangedValue = "changed_value" // Parameter whose value the correction rewrote
+)
+
+// maxLogLineBytes bounds a single line of a correction log export
+const maxLogLineBytes = 16 << 20
+
+// MinedPattern is an invalid pattern and how often corrections fixed it.
+// From and To are tool names for tool_name patterns and parameter names for
+// renamed_param; the other kinds only set From.
+type MinedPattern struct {
+	Kind  string `json:"kind"`
+	From  string `json:"from"`
+	To    string `json:"to,omitempty"`
+	Count int    `json:"count"`
+}
+
+// ToolPatterns lists the invalid patterns of one tool, most frequent first
+type ToolPatterns struct {
+	Tool        string         `json:"tool"`
+	Corrections int            `json:"corrections"` // Corrected calls of this tool
+	Patterns    []MinedPattern `json:"patterns"`
+}
+
+// MiningReport ranks the invalid tool call patterns found in correction logs
+type MiningReport struct {
+	Entries int            `json:"entries"` // Correction log entries read
+	Tools   []ToolPatterns `json:"tools"`   // Tools with the most corrected calls first
+}
+
+// suggestedRules is the tool_validators.yaml document of SuggestedRules
+type suggestedRules struct {
+	CustomTools []suggestedTool `yaml:"customTools"`
+}
+
+// suggestedTool is a tool_validators.yaml entry without the unused fields
+type suggestedTool struct {
+	Name             string            `yaml:"name"`
+	Aliases          []string          `yaml:"aliases,omitempty"`
+	ParameterAliases map[string]string `yaml:"parameterAliases,omitempty"`
+}
+
+// MineCorrections reads exported correction logs and ranks the invalid
+// patterns the corrections fixed, per tool. It accepts the entries
+// LokiLogger.LogCorrection writes in any of the forms they are usually
+// exported in: raw log lines, logcli JSON lines ({"line": ...}) and the Loki
+// push payloads printed when Loki is unavailable. Other lines are skipped.
+func MineCorrections(r io.Reader) (*MiningReport, error) {
+	report := &MiningReport{}
+	counts := make(map[string]map[MinedPattern]int)
+	calls := make(map[string]int)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLogLineBytes)
+	for scanner.Scan() {
+		for _, fields := range correctionLogFields(scanner.Text()) {
+			original, okOriginal := fields["original_data"].(string)
+			corrected, okCorrected := fields["corrected_data"].(string)
+			if fields["event"] != "correction" || fields["correction_method"] != "tool_correction" || !okOriginal || !okCorrected {
+				continue
+			}
+			var originalContent, correctedContent []types.Content
+			if json.Unmarshal([]byte(original), &originalContent) != nil || json.Unmarshal([]byte(corrected), &correctedContent) != nil {
+				continue
+			}
+			report.Entries++
+			for _, pair := range pairToolCalls(originalContent, correctedContent) {
+				patterns := invalidPatterns(pair[0], pair[1])
+				if len(patterns) == 0 {
+					continue
+				}
+				tool := pair[1].Name
+				calls[tool]++
+				if counts[tool] == nil {
+					counts[tool] = make(map[MinedPattern]int)
+				}
+				for _, pattern := range patterns {
+					counts[tool][pattern]++
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read correction logs: %v", err)
+	}
+
+	for tool, patterns := range counts {
+		entry := ToolPatterns{Tool: tool, Corrections: calls[tool]}
+		for pattern, count := range patterns {
+			pattern.Count = count
+			entry.Patterns = append(entry.Patterns, pattern)
+		}
+		sort.Slice(entry.Patterns, func(i, j int) bool {
+			a, b := entry.Patterns[i], entry.Patterns[j]
+			if a.Count != b.Count {
+				return a.Count > b.Count
+			}
+			if a.Kind != b.Kind {
+				return a.Kind < b.Kind
+			}
+			return a.From+"\x00"+a.To < b.From+"\x00"+b.To
+		})
+		report.Tools = append(report.Tools, entry)
+	}
+	sort.Slice(report.Tools, func(i, j int) bool {
+		if report.Tools[i].Corrections != report.Tools[j].Corrections {
+			return report.Tools[i].Corrections > report.Tools[j].Corrections
+		}
+		return report.Tools[i].Tool < report.Tools[j].Tool
+	})
+	return report, nil
+}
+
+// correctionLogFields extracts the structured fields of the log entries on
+// one line of a correction log export
+func correctionLogFields(line string) []map[string]interface{} {
+	start := strings.IndexByte(line, '{')
+	if start < 0 {
+		return nil
+	}
+	var object map[string]interface{}
+	if err := json.Unmarshal([]byte(line[start:]), &object); err != nil {
+		return nil
+	}
+
+	// Loki push payload: {"streams": [{"values": [["<ts>", "<line>"]]}]}
+	if streams, ok := object["streams"].([]interface{}); ok {
+		var entries []map[string]interface{}
+		for _, stream := range streams {
+			values, _ := stream.(map[string]interface{})["values"].([]interface{})
+			for _, value := range values {
+				if pair, ok := value.([]interface{}); ok && len(pair) == 2 {
+					if text, ok := pair[1].(string); ok {
+						entries = append(entries, logLineFields(text)...)
+					}
+				}
+			}
+		}
+		return entries
+	}
+	// logcli JSON lines: {"labels": {...}, "line": "<line>"}
+	if text, ok := object["line"].(string); ok {
+		return logLineFields(text)
+	}
+	return []map[string]interface{}{object}
+}
+
+// logLineFields extracts the structured fields from a log line, which holds
+// a human-readable line followed by the fields as JSON on the next line
+func logLineFields(text string) []map[string]interface{} {
+	var entries []map[string]interface{}
+	for _, part := range strings.Split(text, "\n") {
+		if strings.HasPrefix(part, "{") {
+			entries = append(entries, correctionLogFields(part)...)
+		}
+	}
+	return entries
+}
+
+// pairToolCalls matches the tool calls of a response before and after
+// correction by ID, falling back to their position
+func pairToolCalls(original, corrected []types.Content) [][2]types.Content {
+	var originalCalls, correctedCalls []types.Content
+	for _, item := range original {
+		if item.Type == "tool_use" {
+			originalCalls = append(originalCalls, item)
+		}
+	}
+	for _, item := range corrected {
+		if item.Type == "tool_use" {
+			correctedCalls = append(correctedCalls, item)
+		}
+	}
+
+	var pairs [][2]types.Content
+	for i, call := range originalCalls {
+		match := -1
+		for j, candidate := range correctedCalls {
+			if call.ID != "" && candidate.ID == call.ID {
+				match = j
+				break
+			}
+		}
+		if match < 0 && i < len(correctedCalls) && len(originalCalls) == len(correctedCalls) {
+			match = i
+		}
+		if match >= 0 {
+			pairs = append(pairs, [2]types.Content{call, correctedCalls[match]})
+		}
+	}
+	return pairs
+}
+
+// invalidPatterns returns what a correction changed in a tool call
+func invalidPatterns(original, corrected types.Content) []MinedPattern {
+	var patterns []MinedPattern
+	if original.Name != corrected.Name {
+		patterns = append(patterns, MinedPattern{Kind: PatternToolName, From: original.Name, To: corrected.Name})
+	}
+
+	renames := parameterRenames(original.Input, corrected.Input)
+	renamed := make(map[string]bool, len(renames))
+	for from, to := range renames {
+		patterns = append(patterns, MinedPattern{Kind: PatternRenamedParam, From: from, To: to})
+		renamed[to] = true
+	}
+	for key, value := range original.Input {
+		newValue, exists := corrected.Input[key]
+		switch {
+		case !exists && renames[key] == "":
+			patterns = append(patterns, MinedPattern{Kind: PatternUnknownParam, From: key})
+		case exists && fmt.Sprintf("%v", value) != fmt.Sprintf("%v", newValue):
+			patterns = append(patterns, MinedPattern{Kind: PatternChangedValue, From: key})
+		}
+	}
+	for key := range corrected.Input {
+		if _, exists := original.Input[key]; !exists && !renamed[key] {
+			patterns = append(patterns, MinedPattern{Kind: PatternMissingParam, From: key})
+		}
+	}
+	return patterns
+}
+
+// SuggestedRules returns tool_validators.yaml entries that fix the tool name
+// and parameter name patterns seen at least minCount times without the
+// correction model. Returns nil when no pattern qualifies.
+func (r *MiningReport) SuggestedRules(minCount int) ([]byte, error) {
+	var rules suggestedRules
+	for _, tool := range r.Tools {
+		suggestion := suggestedTool{Name: tool.Tool}
+		for _, pattern := range tool.Patterns {
+			if pattern.Count < minCount {
+				continue
+			}
+			switch pattern.Kind {
+			case PatternToolName:
+				suggestion.Aliases = append(suggestion.Aliases, pattern.From)
+			case PatternRenamedParam:
+				if suggestion.ParameterAliases == nil {
+					suggestion.ParameterAliases = make(map[string]string)
+				}
+				if _, exists := suggestion.ParameterAliases[pattern.From]; !exists {
+					suggestion.ParameterAliases[pattern.From] = pattern.To
+				}
+			}
+		}
+		if len(suggestion.Aliases) > 0 || len(suggestion.ParameterAliases) > 0 {
+			rules.CustomTools = append(rules.CustomTools, suggestion)
+		}
+	}
+	if len(rules.CustomTools) == 0 {
+		return nil, nil
+	}
+	return yaml.Marshal(rules)
+}
+
+// WriteText writes the report as ranked plain text followed by the rules
+// SuggestedRules returns for minCount
+func (r *MiningReport) WriteText(w io.Writer, minCount int) error {
+	fmt.Fprintf(w, "Correction log entries: %d\n", r.Entries)
+	if len(r.Tools) == 0 {
+		_, err := fmt.Fprintln(w, "No corrected tool calls found")
+		return err
+	}
+	for _, tool := range r.Tools {
+		fmt.Fprintf(w, "\n%s (%d corrected calls)\n", tool.Tool, tool.Corrections)
+		for _, pattern := range tool.Patterns {
+			description := pattern.From
+			if pattern.To != "" {
+				description += " -> " + pattern.To
+			}
+			fmt.Fprintf(w, "  %6d  %-14s %s\n", pattern.Count, pattern.Kind, description)
+		}
+	}
+
+	rules, err := r.SuggestedRules(minCount)
+	if err != nil {
+		return err
+	}
+	if rules == nil {
+		_, err = fmt.Fprintf(w, "\nNo pattern seen %d or more times can become a rule\n", minCount)
+		return err
+	}
+	fmt.Fprintf(w, "\nSuggested tool_validators.yaml rules (patterns seen %d or more times):\n\n", minCount)
+	_, err = w.Write(rules)
+	return err
+}
@@ -0,0 +1,170 @@
+package correction
+
+import (
+	"bytes"
+	"claude-proxy/logger"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// Prompt template names. Each is loaded from <name>.tmpl.
+const (
+	PromptCorrection              = "correction"                // Repair of an invalid tool call
+	PromptToolNecessity           = "tool_necessity"            // Full tool necessity analysis
+	PromptToolNecessitySimplified = "tool_necessity_simplified" // Fallback for requests the classifier rules left ambiguous
+	PromptExitPlanModeValidation  = "exit_plan_mode_validation" // ExitPlanMode used as a completion summary
+	PromptExitPlanModeFilter      = "exit_plan_mode_filter"     // Whether ExitPlanMode is offered for a request
+)
+
+var promptNames = []string{
+	PromptCorrection,
+	PromptToolNecessity,
+	PromptToolNecessitySimplified,
+	PromptExitPlanModeValidation,
+	PromptExitPlanModeFilter,
+}
+
+//go:embed prompts/*.tmpl
+var defaultPromptFS embed.FS
+
+// defaultPrompts holds the built-in templates; they are parsed at init so a
+// broken default fails every test rather than a live request
+var defaultPrompts = mustLoadDefaultPrompts()
+
+// Prompts is a set of correction model prompt templates (Go text/template)
+type Prompts struct {
+	templates map[string]*template.Template
+}
+
+// correctionPromptData is passed to the correction template
+type correctionPromptData struct {
+	Call         string // The invalid call as indented JSON
+	Schema       string // The tool's input schema as indented JSON
+	TodoWrite    bool   // The call looks like a TodoWrite call
+	Conversation string // Last conversation messages, "" unless CORRECTION_CONTEXT_MESSAGES is set
+}
+
+// toolNecessityPromptData is passed to both tool necessity templates
+type toolNecessityPromptData struct {
+	Conversation   string // Numbered recent conversation (tool_necessity)
+	Context        string // Last few messages (tool_necessity_simplified)
+	CurrentRequest string // Most recent user message
+	Tools          string // Comma-separated available tool names
+}
+
+// exitPlanModeValidationPromptData is passed to the exit_plan_mode_validation template
+type exitPlanModeValidationPromptData struct {
+	Plan         string
+	RecentTools  string // Comma-separated names of the last tools used
+	MessageCount int
+}
+
+// exitPlanModeFilterPromptData is passed to the exit_plan_mode_filter template
+type exitPlanModeFilterPromptData struct {
+	Request string
+}
+
+func mustLoadDefaultPrompts() *Prompts {
+	prompts := &Prompts{templates: make(map[string]*template.Template)}
+	for _, name := range promptNames {
+		data, err := defaultPromptFS.ReadFile("prompts/" + name + ".tmpl")
+		if err != nil {
+			panic(fmt.Sprintf("missing built-in prompt %s: %v", name, err))
+		}
+		prompts.templates[name] = template.Must(template.New(name).Parse(string(data)))
+	}
+	return prompts
+}
+
+// DefaultPrompts returns the built-in prompt templates
+func DefaultPrompts() *Prompts {
+	return defaultPrompts
+}
+
+// LoadPrompts loads prompt templates from dir, falling back to the built-in
+// template for every <name>.tmpl the directory does not contain. A missing
+// directory yields the built-in prompts. Templates are checked against their
+// data so a typo in a field name fails here rather than on a live request.
+func LoadPrompts(dir string) (*Prompts, error) {
+	prompts := &Prompts{templates: make(map[string]*template.Template)}
+	for _, name := range promptNames {
+		path := filepath.Join(dir, name+".tmpl")
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			prompts.templates[name] = defaultPrompts.templates[name]
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read prompt %s: %v", path, err)
+		}
+		tmpl, err := template.New(name).Parse(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse prompt %s: %v", path, err)
+		}
+		if err := tmpl.Execute(&bytes.Buffer{}, samplePromptData(name)); err != nil {
+			return nil, fmt.Errorf("invalid prompt %s: %v", path, err)
+		}
+		prompts.templates[name] = tmpl
+	}
+	return prompts, nil
+}
+
+// samplePromptData returns zero data of the type each template receives
+func samplePromptData(name string) interface{} {
+	switch name {
+	case PromptCorrection:
+		return correctionPromptData{}
+	case PromptToolNecessity, PromptToolNecessitySimplified:
+		return toolNecessityPromptData{}
+	case PromptExitPlanModeValidation:
+		return exitPlanModeValidationPromptData{}
+	default:
+		return exitPlanModeFilterPromptData{}
+	}
+}
+
+// Render executes the named template. Surrounding whitespace is trimmed so
+// template files may end with a newline.
+func (p *Prompts) Render(name string, data interface{}) (string, error) {
+	tmpl, exists := p.templates[name]
+	if !exists {
+		return "", fmt.Errorf("unknown prompt %s", name)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt %s: %v", name, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// SetPrompts replaces the prompt templates used for correction model requests
+func (s *Service) SetPrompts(prompts *Prompts) {
+	s.prompts = prompts
+}
+
+// renderPrompt renders a prompt with the configured templates, falling back
+// to the built-in template if the configured one fails
+func (s *Service) renderPrompt(name string, data interface{}) string {
+	if s.prompts != nil {
+		prompt, err := s.prompts.Render(name, data)
+		if err == nil {
+			return prompt
+		}
+		s.logWarn(logger.ComponentToolCorrection, logger.CategoryWarning, "", "Custom prompt failed, using built-in prompt", map[string]interface{}{
+			"prompt": name,
+			"error":  err.Error(),
+		})
+	}
+	prompt, err := defaultPrompts.Render(name, data)
+	if err != nil {
+		s.logError(logger.ComponentToolCorrection, logger.CategoryError, "", "Built-in prompt failed", map[string]interface{}{
+			"prompt": name,
+			"error":  err.Error(),
+		})
+	}
+	return prompt
+}
@@ -2,6 +2,7 @@ package correction
 
 import (
 	"bytes"
+	"claude-proxy/config"
 	"claude-proxy/internal"
 	"claude-proxy/logger"
 	"claude-proxy/types"
@@ -37,15 +38,13 @@ func getInputParamNames(input map[string]interface{}) []string {
 	return names
 }
 
-// ConfigProvider provides endpoint configuration for the correction service
-type ConfigProvider interface {
-	GetToolCorrectionEndpoint() string
-	GetHealthyToolCorrectionEndpoint() string
-	RecordEndpointFailure(endpoint string)
-	RecordEndpointSuccess(endpoint string)
-	// GetEnableToolChoiceCorrection returns whether tool choice correction is enabled
-	GetEnableToolChoiceCorrection() bool
-}
+// defaultMaxRetries is the number of correction attempts per tool call when
+// config does not set CORRECTION_MAX_RETRIES
+const defaultMaxRetries = 3
+
+// ConfigProvider provides endpoint configuration for the correction service.
+// It is the correction section of config.Config, so tests can supply a small fake.
+type ConfigProvider = config.CorrectionConfig
 
 // Service handles tool call correction using configurable model
 type Service struct {
@@ -59,6 +58,8 @@ type Service struct {
 	registry                   types.SchemaRegistry        // Injected schema registry
 	classifier                 *HybridClassifier           // Two-stage hybrid classifier for tool necessity
 	obsLogger                  *logger.ObservabilityLogger // Structured logging
+	prompts                    *Prompts                    // Correction model prompt templates, nil for the built-in ones
+	learner                    *RuleLearner                // Parameter renames learned from LLM corrections, nil when not learning
 }
 
 // logInfo logs an info message with structured data if obsLogger is available
@@ -128,6 +129,66 @@ func NewServiceWithComponents(config ConfigProvider, apiKey string, enabled bool
 	}
 }
 
+// RegisterCustomTools adds custom tool definitions to the service's validator
+// so MCP and other non-built-in tools get alias and parameter normalization
+func (s *Service) RegisterCustomTools(tools []types.CustomTool) error {
+	if len(tools) == 0 {
+		return nil
+	}
+	registrar, ok := s.validator.(types.ToolRegistrar)
+	if !ok {
+		return fmt.Errorf("tool validator %T does not support custom tools", s.validator)
+	}
+	for _, tool := range tools {
+		if err := registrar.RegisterTool(tool); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetAdditionalPropertiesPolicy sets what the service's validator does with
+// parameters a tool's schema does not define
+func (s *Service) SetAdditionalPropertiesPolicy(policy types.AdditionalPropertiesPolicy) error {
+	handler, ok := s.validator.(types.AdditionalPropertiesHandler)
+	if !ok {
+		return fmt.Errorf("tool validator %T does not support additional properties policies", s.validator)
+	}
+	return handler.SetAdditionalPropertiesPolicy(policy)
+}
+
+// retryPolicy returns the correction attempts per tool call and what happens
+// to calls still invalid after them, from config when it sets them
+func (s *Service) retryPolicy() (int, string) {
+	maxRetries, exhaustedPolicy := defaultMaxRetries, config.CorrectionExhaustedOriginal
+	if retryConfig, ok := s.config.(config.CorrectionRetryConfig); ok {
+		if retries := retryConfig.GetCorrectionMaxRetries(); retries > 0 {
+			maxRetries = retries
+		}
+		if policy := retryConfig.GetCorrectionExhaustedPolicy(); policy != "" {
+			exhaustedPolicy = policy
+		}
+	}
+	return maxRetries, exhaustedPolicy
+}
+
+// BlockedToolCallNotice returns the text block that replaces a tool call the
+// proxy refuses to forward, naming the call, why, and what was wrong with it
+func BlockedToolCallNotice(toolName, reason string, validation ValidationResult) types.Content {
+	var problems []string
+	if len(validation.MissingParams) > 0 {
+		problems = append(problems, "missing parameters: "+strings.Join(validation.MissingParams, ", "))
+	}
+	if len(validation.InvalidParams) > 0 {
+		problems = append(problems, "invalid parameters: "+strings.Join(validation.InvalidParams, ", "))
+	}
+	text := fmt.Sprintf("[Tool call %s blocked: %s", toolName, reason)
+	if len(problems) > 0 {
+		text += " (" + strings.Join(problems, "; ") + ")"
+	}
+	return types.Content{Type: "text", Text: text + "]"}
+}
+
 // shouldLog determines if logging should be enabled for tool correction
 func (s *Service) shouldLog() bool {
 	return !s.disableLogging
@@ -221,12 +282,23 @@ func (s *Service) logParameterChanges(requestID string, original, corrected type
 
 // CorrectToolCalls validates and corrects tool calls using two-stage approach
 func (s *Service) CorrectToolCalls(ctx context.Context, toolCalls []types.Content, availableTools []types.Tool) ([]types.Content, error) {
+	return s.correctToolCalls(ctx, toolCalls, availableTools, true)
+}
+
+// correctToolCalls runs the correction stages; with allowLLM false it stops
+// after the rule-based stages and returns calls they could not fix unchanged
+func (s *Service) correctToolCalls(ctx context.Context, toolCalls []types.Content, availableTools []types.Tool, allowLLM bool) ([]types.Content, error) {
 	if !s.enabled {
 		return toolCalls, nil
 	}
 	requestID := getRequestID(ctx)
 
+	// Index the request's tools once for every call and retry below
+	ctx = WithToolIndex(ctx, s.toolIndex(ctx, availableTools))
+
 	var correctedCalls []types.Content
+	maxRetries, exhaustedPolicy := s.retryPolicy()
+	severities := s.severityConfig()
 
 	for _, call := range toolCalls {
 		if call.Type != "tool_use" {
@@ -234,8 +306,30 @@ func (s *Service) CorrectToolCalls(ctx context.Context, toolCalls []types.Conten
 			continue
 		}
 
+		// Validation severity: rules set to warn or block are not corrected
+		if severities != nil {
+			severity, rule, validation := s.validationSeverity(ctx, severities, call, availableTools)
+			if severity == config.ValidationSeverityWarn {
+				s.logWarn(logger.ComponentToolCorrection, logger.CategoryValidation, requestID, "Tool call violates validation rule, passing through", map[string]interface{}{
+					"tool_name":      call.Name,
+					"rule":           rule,
+					"missing_params": validation.MissingParams,
+					"invalid_params": validation.InvalidParams,
+				})
+				correctedCalls = append(correctedCalls, call)
+				continue
+			}
+			if severity == config.ValidationSeverityBlock {
+				s.logWarn(logger.ComponentToolCorrection, logger.CategoryValidation, requestID, "Tool call violates validation rule, blocking", map[string]interface{}{
+					"tool_name": call.Name,
+					"rule":      rule,
+				})
+				correctedCalls = append(correctedCalls, BlockedToolCallNotice(call.Name, "it violates the "+rule+" validation rule", validation))
+				continue
+			}
+		}
+
 		// Circuit breaker: Initialize retry tracking for this tool call
-		const maxRetries = 3
 		retryCount := 0
 		var currentCall = call
 
@@ -277,12 +371,21 @@ func (s *Service) CorrectToolCalls(ctx context.Context, toolCalls []types.Conten
 					"max_retries":    maxRetries,
 					"missing_params": validation.MissingParams,
 					"invalid_params": validation.InvalidParams,
+					"policy":         exhaustedPolicy,
 				})
 
 				// Memory management: Reset to original and clear accumulated state
 				currentCall = originalCall
-				correctedCalls = append(correctedCalls, originalCall) // Use original call
-				break                                                 // Exit retry loop
+				switch exhaustedPolicy {
+				case config.CorrectionExhaustedDrop:
+					// Leave the call out of the response
+				case config.CorrectionExhaustedBlock:
+					reason := fmt.Sprintf("it was still invalid after %d correction attempts", maxRetries)
+					correctedCalls = append(correctedCalls, BlockedToolCallNotice(originalCall.Name, reason, validation))
+				default:
+					correctedCalls = append(correctedCalls, originalCall) // Use original call
+				}
+				break // Exit retry loop
 			}
 
 			if s.shouldLog() && retryCount > 0 {
@@ -339,6 +442,22 @@ func (s *Service) CorrectToolCalls(ctx context.Context, toolCalls []types.Conten
 				continue
 			}
 
+			// Stage 1.4: Strip undefined parameters and coerce mistyped and near-miss enum values before LLM
+			if coercedCall, success := s.AttemptSchemaCoercion(ctx, currentCall, availableTools); success {
+				coercedValidation := s.ValidateToolCall(ctx, coercedCall, availableTools)
+				if coercedValidation.IsValid && !s.HasStructuralMismatch(coercedCall, availableTools) {
+					s.logInfo(logger.ComponentToolCorrection, logger.CategorySuccess, requestID, "Type coercion passed validation", map[string]interface{}{
+						"tool_name":         currentCall.Name,
+						"validation_result": "passed",
+					})
+					correctedCalls = append(correctedCalls, coercedCall)
+					break // Exit retry loop - success
+				}
+				// Keep the coerced values for the corrections below
+				currentCall = coercedCall
+				validation = coercedValidation
+			}
+
 			// Stage 1.5: Try rule-based parameter corrections before LLM
 			if ruleBasedCall, success := s.AttemptRuleBasedParameterCorrection(ctx, currentCall); success {
 				s.logInfo(logger.ComponentToolCorrection, logger.CategoryTransformation, requestID, "Rule-based parameter correction successful", map[string]interface{}{
@@ -429,6 +548,31 @@ func (s *Service) CorrectToolCalls(ctx context.Context, toolCalls []types.Conten
 				}
 			}
 
+			// Rules-only backend: nothing left that can be fixed without a model
+			if !allowLLM {
+				if s.shouldLog() {
+					s.logWarn(logger.ComponentToolCorrection, logger.CategoryWarning, requestID, "Rule-based corrections exhausted, LLM correction disabled", map[string]interface{}{
+						"tool_name":      currentCall.Name,
+						"missing_params": validation.MissingParams,
+						"invalid_params": validation.InvalidParams,
+					})
+				}
+				correctedCalls = append(correctedCalls, currentCall)
+				break
+			}
+
+			// Correction budget spent: forward the call as-is rather than waiting on the model
+			if ctx.Err() != nil {
+				s.logWarn(logger.ComponentToolCorrection, logger.CategoryWarning, requestID, "Correction budget exhausted, forwarding tool call uncorrected", map[string]interface{}{
+					"tool_name":      currentCall.Name,
+					"missing_params": validation.MissingParams,
+					"invalid_params": validation.InvalidParams,
+					"retry_count":    retryCount,
+				})
+				correctedCalls = append(correctedCalls, currentCall)
+				break
+			}
+
 			// Stage 2: Fix parameter issues (LLM correction)
 			if len(validation.MissingParams) > 0 || len(validation.InvalidParams) > 0 {
 				s.logInfo(logger.ComponentToolCorrection, logger.CategoryTransformation, requestID, "Starting LLM parameter correction", map[string]interface{}{
@@ -476,6 +620,7 @@ func (s *Service) CorrectToolCalls(ctx context.Context, toolCalls []types.Conten
 
 					// Check if correction was successful
 					if revalidation.IsValid {
+						s.learnFromCorrection(requestID, currentCall, correctedCall)
 						correctedCalls = append(correctedCalls, correctedCall)
 						break // Exit retry loop - success
 					} else {
@@ -512,6 +657,7 @@ func (s *Service) CorrectToolCalls(ctx context.Context, toolCalls []types.Conten
 					// Check if correction was successful
 					fullRevalidation := s.ValidateToolCall(ctx, correctedCall, availableTools)
 					if fullRevalidation.IsValid {
+						s.learnFromCorrection(requestID, currentCall, correctedCall)
 						correctedCalls = append(correctedCalls, correctedCall)
 						break // Exit retry loop - success
 					} else {
@@ -629,7 +775,7 @@ func (s *Service) llmFallbackAnalysis(ctx context.Context, messages []types.Open
 		})
 	}
 	
-	response, err := s.sendCorrectionRequest(req)
+	response, err := s.sendCorrectionRequest(ctx, req)
 	if err != nil {
 		if s.shouldLog() {
 			s.logWarn(logger.ComponentHybridClassifier, logger.CategoryWarning, requestID, "Stage C: LLM request failed", map[string]interface{}{
@@ -694,37 +840,11 @@ func (s *Service) buildExitPlanModeValidationPrompt(planContent string, messages
 	// Get recent tool names for context
 	recentTools := s.getRecentToolNames(messages, 10) // Get last 10 tools used
 
-	return fmt.Sprintf(`Analyze this ExitPlanMode usage and determine if it's appropriate:
-
-PLAN CONTENT:
-"%s"
-
-CONVERSATION CONTEXT:
-- Recent tools used: %s
-- Total messages in conversation: %d
-
-RULES FOR EXITPLANMODE:
-✅ APPROPRIATE USAGE (respond with ALLOW):
-- Planning future implementation steps
-- Outlining approach before starting work
-- Requesting approval for implementation plan
-- Forward-looking language: "I will...", "Here's my plan...", "I propose..."
-
-❌ INAPPROPRIATE USAGE (respond with BLOCK):
-- Summarizing completed work
-- Reporting finished implementation 
-- Using past tense to describe what was done: "I've implemented...", "The implementation included..."
-- Completion language: "successfully completed", "all tasks finished", "ready for production"
-
-ANALYSIS CRITERIA:
-1. Language tense: Future-focused planning vs past-tense completion summary
-2. Content purpose: Outlining upcoming work vs reporting finished work
-3. Context: Is this planning before work or summarizing after work?
-
-Respond with ONLY "BLOCK" or "ALLOW".`,
-		planContent,
-		strings.Join(recentTools, ", "),
-		len(messages))
+	return s.renderPrompt(PromptExitPlanModeValidation, exitPlanModeValidationPromptData{
+		Plan:         planContent,
+		RecentTools:  strings.Join(recentTools, ", "),
+		MessageCount: len(messages),
+	})
 }
 
 // BuildExitPlanModeValidationPrompt is a public wrapper for testing
@@ -799,80 +919,11 @@ func (s *Service) buildToolNecessityPrompt(messages []types.OpenAIMessage, avail
 		}
 	}
 
-	return fmt.Sprintf(`You are analyzing whether a user request requires tools (YES) or can be handled conversationally (NO).
-
-%s
-
-CURRENT REQUEST: "%s"
-AVAILABLE TOOLS: %s
-
-MANDATORY RULE - If request contains ANY of these words, answer YES immediately:
-UPDATE/UPDATING, CREATE/CREATING, EDIT/EDITING, WRITE/WRITING, MODIFY/MODIFYING, FIX/FIXING, CHANGE/CHANGING, MAKE/MAKING, BUILD/BUILDING, ADD/ADDING, IMPLEMENT/IMPLEMENTING, INSTALL/INSTALLING, SETUP, RUN/RUNNING, EXECUTE/EXECUTING, LAUNCH/LAUNCHING, START/STARTING, DELETE/DELETING, REMOVE/REMOVING
-
-OVERRIDE RULE: The phrase "updating CLAUDE.md" MUST return YES regardless of context or politeness.
-
-CONTEXT-AWARE DECISION MATRIX:
-
-SCENARIO 1 - CONTINUATION AFTER RESEARCH (Main failing case):
-Pattern: Research tools used → High token output → User requests implementation
-Example conversation:
-  USER: "gather knowledge about project and update CLAUDE.md"
-  ASSISTANT: [Used Task tool - 23,000 tokens research output]
-  USER: "Please continue with updating CLAUDE.md based on the research"
-DECISION: YES (Research complete, now implementation needed)
-
-SCENARIO 2 - DIRECT FILE OPERATIONS:
-- "create file", "edit config", "update README", "run tests" → YES
-- "write to file", "modify code", "add function" → YES
-- Any action on files/code regardless of politeness → YES
-
-SCENARIO 3 - COMPOUND REQUESTS:
-- "analyze X and create Y" → YES (contains implementation verb "create")
-- "research Z and implement W" → YES (contains implementation verb "implement")
-- "gather info and update file" → YES (contains implementation verb "update")
-
-SCENARIO 4 - PURE RESEARCH/ANALYSIS:
-- "read file X and tell me what it does" → NO
-- "explain the architecture" → NO
-- "what does this code do?" → NO
-
-FEW-SHOT EXAMPLES:
-
-EXAMPLE 1 (Target fix):
-Context: "Task tool used, 23k tokens output, research complete"
-Request: "Please continue with updating CLAUDE.md based on the research"
-Contains: "updating" (implementation verb)
-Phase: Research done, implementation needed
-ANSWER: YES
-
-EXAMPLE 2 (Simple implementation):
-Context: None
-Request: "create a new config file"
-Contains: "create" (implementation verb)  
-ANSWER: YES
-
-EXAMPLE 3 (Pure research):
-Context: None
-Request: "read the architecture docs and explain the design"
-Contains: No implementation verbs, asks for explanation
-ANSWER: NO
-
-EXAMPLE 4 (Compound with implementation):
-Context: None
-Request: "analyze the auth system and implement OAuth"
-Contains: "implement" (implementation verb)
-ANSWER: YES
-
-DECISION ALGORITHM:
-1. Does request contain implementation verbs? → YES
-2. Does conversation show research complete + user wants action? → YES
-3. Is request purely informational/explanatory? → NO
-4. When uncertain about file operations → YES
-
-CRITICAL: File operations (update, create, edit, modify) ALWAYS require tools.
-Be decisive. Prioritize action verbs over polite language.
-
-Answer only: YES or NO`, conversationContext.String(), lastUserMessage, strings.Join(toolNames, ", "))
+	return s.renderPrompt(PromptToolNecessity, toolNecessityPromptData{
+		Conversation:   conversationContext.String(),
+		CurrentRequest: lastUserMessage,
+		Tools:          strings.Join(toolNames, ", "),
+	})
 }
 
 // buildSimplifiedToolNecessityPrompt creates a simplified prompt for LLM fallback
@@ -935,23 +986,11 @@ func (s *Service) buildSimplifiedToolNecessityPrompt(messages []types.OpenAIMess
 		})
 	}
 
-	finalPrompt := fmt.Sprintf(`This is an ambiguous request that needs analysis.
-
-RECENT CONTEXT:
-%s
-
-CURRENT REQUEST: "%s"
-TOOLS: %s
-
-The request was not clearly classified by rules. Analyze if it requires tools:
-- Does it ask for file operations, code changes, or command execution?
-- Is it asking to create, modify, or run something?
-- Or is it asking for explanation, analysis, or information only?
-
-Answer: YES or NO`,
-		strings.Join(contextMessages, "\n"),
-		currentRequest,
-		strings.Join(toolNames, ", "))
+	finalPrompt := s.renderPrompt(PromptToolNecessitySimplified, toolNecessityPromptData{
+		Context:        strings.Join(contextMessages, "\n"),
+		CurrentRequest: currentRequest,
+		Tools:          strings.Join(toolNames, ", "),
+	})
 
 	// Log final prompt details
 	if s.shouldLog() {
@@ -965,13 +1004,8 @@ Answer: YES or NO`,
 }
 
 // findToolByName finds a tool by exact name match
-func (s *Service) findToolByName(name string, availableTools []types.Tool) *types.Tool {
-	for _, t := range availableTools {
-		if t.Name == name {
-			return &t
-		}
-	}
-	return nil
+func (s *Service) findToolByName(name string, index *ToolIndex) *types.Tool {
+	return index.Get(name)
 }
 
 // findToolByCaseInsensitiveName finds a tool by case-insensitive name match
@@ -1011,20 +1045,24 @@ func (s *Service) ValidateToolCall(ctx context.Context, call types.Content, avai
 	}
 
 	// Try exact match first
-	tool := s.findToolByName(call.Name, availableTools)
+	index := s.toolIndex(ctx, availableTools)
+	tool := s.findToolByName(call.Name, index)
 	if tool == nil {
-		// Try case-insensitive match using validator
+		// Try case-insensitive match using validator, then against the request's own tools
 		if normalizedName, found := s.validator.NormalizeToolName(call.Name); found {
-			tool = s.findToolByName(normalizedName, availableTools)
-			if tool != nil {
-				result.HasCaseIssue = true
-				result.CorrectToolName = tool.Name
-				if s.shouldLog() {
-					s.logWarn(logger.ComponentToolCorrection, logger.CategoryValidation, requestID, "Tool name case issue detected", map[string]interface{}{
-						"provided_name": call.Name,
-						"correct_name":  tool.Name,
-					})
-				}
+			tool = s.findToolByName(normalizedName, index)
+		}
+		if tool == nil {
+			tool = index.GetFold(call.Name)
+		}
+		if tool != nil {
+			result.HasCaseIssue = true
+			result.CorrectToolName = tool.Name
+			if s.shouldLog() {
+				s.logWarn(logger.ComponentToolCorrection, logger.CategoryValidation, requestID, "Tool name case issue detected", map[string]interface{}{
+					"provided_name": call.Name,
+					"correct_name":  tool.Name,
+				})
 			}
 		}
 
@@ -1069,20 +1107,6 @@ func (s *Service) ValidateToolCall(ctx context.Context, call types.Content, avai
 	result.MissingParams = validatorResult.MissingParams
 	result.InvalidParams = validatorResult.InvalidParams
 
-	// For Task tools, allow additional parameters (they may come from slash commands)
-	if tool.Name == "Task" && len(result.InvalidParams) > 0 {
-		var filteredInvalid []string
-		for _, invalid := range result.InvalidParams {
-			if s.shouldLog() {
-				s.logInfo(logger.ComponentToolCorrection, logger.CategoryValidation, requestID, "Allowing additional parameter for Task tool", map[string]interface{}{
-					"parameter": invalid,
-				})
-			}
-			// Don't add to filteredInvalid - we're allowing it
-		}
-		result.InvalidParams = filteredInvalid
-	}
-
 	// Enhanced logging: Detailed validation results
 	if len(result.MissingParams) > 0 && s.shouldLog() {
 		s.logWarn(logger.ComponentToolCorrection, logger.CategoryValidation, requestID, "Missing required parameters", map[string]interface{}{
@@ -1224,7 +1248,7 @@ func (s *Service) correctToolCall(ctx context.Context, call types.Content, avail
 	}
 
 	// Build correction prompt
-	prompt := s.buildCorrectionPrompt(call, availableTools)
+	prompt := s.buildCorrectionPrompt(call, availableTools, s.conversationTail(ctx))
 
 	// Enhanced logging: Log prompt details (truncated for security)
 	if s.shouldLog() {
@@ -1265,7 +1289,7 @@ func (s *Service) correctToolCall(ctx context.Context, call types.Content, avail
 	}
 
 	// Send request
-	response, err := s.sendCorrectionRequest(req)
+	response, err := s.sendCorrectionRequest(ctx, req)
 	if err != nil {
 		if s.shouldLog() {
 			s.logError(logger.ComponentToolCorrection, logger.CategoryError, requestID, "LLM correction request failed", map[string]interface{}{
@@ -1329,8 +1353,9 @@ func (s *Service) correctToolCall(ctx context.Context, call types.Content, avail
 	return correctedCall, nil
 }
 
-// buildCorrectionPrompt creates the prompt for qwen2.5-coder
-func (s *Service) buildCorrectionPrompt(call types.Content, availableTools []types.Tool) string {
+// buildCorrectionPrompt creates the prompt for qwen2.5-coder. conversation is
+// the rendered conversation tail, "" to leave it out.
+func (s *Service) buildCorrectionPrompt(call types.Content, availableTools []types.Tool, conversation string) string {
 	// Find the correct tool schema
 	var toolSchema types.Tool
 	for _, tool := range availableTools {
@@ -1348,70 +1373,17 @@ func (s *Service) buildCorrectionPrompt(call types.Content, availableTools []typ
 
 	schemaJson, _ := json.MarshalIndent(toolSchema.InputSchema, "", "  ")
 
-	// Enhanced TodoWrite examples and instructions
-	todoExample := ""
 	callStr := strings.ToLower(string(callJson))
-	if strings.Contains(callStr, "todo") || strings.Contains(strings.ToLower(call.Name), "todo") {
-		todoExample = `
-
-TODOWRITE TRANSFORMATION EXAMPLES:
-
-EXAMPLE 1 - Single todo string:
-INCORRECT: {"name": "TodoWrite", "input": {"todo": "Review code"}}
-CORRECT: {"name": "TodoWrite", "input": {"todos": [{"content": "Review code", "status": "pending", "priority": "medium", "id": "review-code"}]}}
-
-EXAMPLE 2 - Missing parameters:
-INCORRECT: {"name": "TodoWrite", "input": {"task": "Fix bug", "priority": "high"}}
-CORRECT: {"name": "TodoWrite", "input": {"todos": [{"content": "Fix bug", "status": "pending", "priority": "high", "id": "fix-bug"}]}}
-
-EXAMPLE 3 - Multiple items:
-INCORRECT: {"name": "TodoWrite", "input": {"items": ["Task 1", "Task 2"]}}
-CORRECT: {"name": "TodoWrite", "input": {"todos": [{"content": "Task 1", "status": "pending", "priority": "medium", "id": "task-1"}, {"content": "Task 2", "status": "pending", "priority": "medium", "id": "task-2"}]}}
-
-EXAMPLE 4 - No parameters:
-INCORRECT: {"name": "TodoWrite", "input": {}}
-CORRECT: {"name": "TodoWrite", "input": {"todos": [{"content": "New task", "status": "pending", "priority": "medium", "id": "new-task"}]}}
-
-CRITICAL TODOWRITE RULES:
-- ALWAYS use 'todos' parameter (array), never 'todo', 'task', 'items', etc.
-- Each todo object MUST have exactly these 4 fields: content, status, priority, id
-- content: string (preserve original semantic meaning)
-- status: must be "pending", "in_progress", or "completed" (default: "pending")
-- priority: must be "high", "medium", or "low" (default: "medium")
-- id: string (generate from content: lowercase, replace spaces with hyphens)
-- If no meaningful content exists, use "New task" as content
-- Always preserve the user's original intent and information`
-	}
-
-	return fmt.Sprintf(`Fix this invalid tool call to match the required schema:
-
-INVALID TOOL CALL:
-%s
-
-REQUIRED SCHEMA:
-%s
-
-Common fixes needed:
-- 'filename' should be 'file_path'
-- 'path' should be 'file_path' 
-- 'text' should be 'content'
-- 'filter' should be 'glob' (for Grep tool file filtering)
-- 'search' should be 'pattern' (for Grep tool)
-- 'query' should be 'pattern' (for Grep tool)
-- Ensure all required parameters are present%s
-
-Return ONLY the corrected tool call in this exact JSON format:
-{
-  "name": "ToolName",
-  "input": {
-    "parameter1": "value1",
-    "parameter2": "value2"
-  }
-}`, string(callJson), string(schemaJson), todoExample)
+	return s.renderPrompt(PromptCorrection, correctionPromptData{
+		Call:         string(callJson),
+		Schema:       string(schemaJson),
+		TodoWrite:    strings.Contains(callStr, "todo") || strings.Contains(strings.ToLower(call.Name), "todo"),
+		Conversation: conversation,
+	})
 }
 
 // sendCorrectionRequest sends request with automatic failover
-func (s *Service) sendCorrectionRequest(req types.OpenAIRequest) (*types.OpenAIResponse, error) {
+func (s *Service) sendCorrectionRequest(ctx context.Context, req types.OpenAIRequest) (*types.OpenAIResponse, error) {
 	reqBody, err := json.Marshal(req)
 	if err != nil {
 		return nil, err
@@ -1428,21 +1400,31 @@ func (s *Service) sendCorrectionRequest(req types.OpenAIRequest) (*types.OpenAIR
 			return nil, fmt.Errorf("no tool correction endpoints available")
 		}
 
-		httpReq, err := http.NewRequest("POST", endpoint, bytes.NewBuffer(reqBody))
+		httpReq, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(reqBody))
 		if err != nil {
 			return nil, err
 		}
 
 		httpReq.Header.Set("Content-Type", "application/json")
 		httpReq.Header.Set("Authorization", "Bearer "+s.apiKey)
+		if requestID := getRequestID(ctx); requestID != "unknown" {
+			httpReq.Header.Set(internal.RequestIDHeader, requestID)
+		}
 
 		// Use longer timeout for Task agents that need extensive tool usage
 		client := &http.Client{
 			Timeout: 60 * time.Second, // Increased to allow Task agents to complete thorough analysis
 		}
+		if transportConfig, ok := s.config.(config.EndpointTransportConfig); ok {
+			client = transportConfig.HTTPClient(endpoint, client.Timeout)
+		}
 
 		resp, err := client.Do(httpReq)
 		if err != nil {
+			// The caller gave up (budget or client disconnect); the endpoint is not at fault
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("tool correction request cancelled: %v", ctx.Err())
+			}
 			lastErr = err
 			// Record endpoint failure for circuit breaker
 			s.config.RecordEndpointFailure(endpoint)
@@ -1649,10 +1631,15 @@ func (s *Service) validateTodoWriteCorrection(input map[string]interface{}) erro
 	return nil
 }
 
+// Allowed values of the TodoWrite item fields
+var (
+	todoStatuses   = []interface{}{"pending", "in_progress", "completed"}
+	todoPriorities = []interface{}{"high", "medium", "low"}
+)
+
 // isValidStatus checks if a status value is valid
 func isValidStatus(status string) bool {
-	validStatuses := []string{"pending", "in_progress", "completed"}
-	for _, valid := range validStatuses {
+	for _, valid := range todoStatuses {
 		if status == valid {
 			return true
 		}
@@ -1662,8 +1649,7 @@ func isValidStatus(status string) bool {
 
 // isValidPriority checks if a priority value is valid
 func isValidPriority(priority string) bool {
-	validPriorities := []string{"high", "medium", "low"}
-	for _, valid := range validPriorities {
+	for _, valid := range todoPriorities {
 		if priority == valid {
 			return true
 		}
@@ -1704,7 +1690,15 @@ func (s *Service) AttemptRuleBasedTodoWriteCorrection(ctx context.Context, call
 						hasValidStructure = false
 						break
 					}
-					// Allow status and id to be missing (they have defaults) but check names are correct
+					// Missing status and id get defaults and near-miss statuses ("done") are mapped below
+					if status, hasStatus := todoMap["status"].(string); !hasStatus || !isValidStatus(status) {
+						hasValidStructure = false
+						break
+					}
+					if _, hasID := todoMap["id"]; !hasID {
+						hasValidStructure = false
+						break
+					}
 				} else {
 					hasValidStructure = false
 					break
@@ -1762,6 +1756,14 @@ func (s *Service) AttemptRuleBasedTodoWriteCorrection(ctx context.Context, call
 						if statusValue, exists := todoMap["status"]; exists {
 							if sStr, ok := statusValue.(string); ok && isValidStatus(sStr) {
 								status = sStr
+							} else if nearest, found := types.NearestEnumValue(sStr, todoStatuses); ok && found {
+								status = nearest
+								if s.shouldLog() {
+									s.logInfo(logger.ComponentToolCorrection, logger.CategoryTransformation, requestID, "Transformed parameter in todo item", map[string]interface{}{
+										"transformation": sStr + " → " + nearest,
+										"parameter":      "status",
+									})
+								}
 							}
 						} else {
 							if s.shouldLog() {
@@ -1776,6 +1778,8 @@ func (s *Service) AttemptRuleBasedTodoWriteCorrection(ctx context.Context, call
 						if p, exists := todoMap["priority"]; exists {
 							if pStr, ok := p.(string); ok && isValidPriority(pStr) {
 								priority = pStr
+							} else if nearest, found := types.NearestEnumValue(pStr, todoPriorities); ok && found {
+								priority = nearest
 							}
 						} else {
 							if s.shouldLog() {
@@ -2240,7 +2244,7 @@ func (s *Service) correctSlashCommandToTask(ctx context.Context, call types.Cont
 	}
 
 	// Find Task tool in available tools
-	taskTool := s.findToolByName("Task", availableTools)
+	taskTool := s.findToolByName("Task", s.toolIndex(ctx, availableTools))
 	if taskTool == nil {
 		if s.shouldLog() {
 			s.logWarn(logger.ComponentToolCorrection, logger.CategoryWarning, requestID, "Cannot correct slash command - Task tool not available", map[string]interface{}{
@@ -2343,7 +2347,7 @@ func (s *Service) suggestCorrectTool(ctx context.Context, call types.Content, av
 		if url, exists := call.Input["url"]; exists {
 			if urlStr, ok := url.(string); ok && strings.HasPrefix(urlStr, "file://") {
 				// Check if Read tool is available
-				if s.findToolByName("Read", availableTools) != nil {
+				if s.findToolByName("Read", s.toolIndex(ctx, availableTools)) != nil {
 					return "Read"
 				}
 			}
@@ -2365,7 +2369,7 @@ func (s *Service) CorrectSemanticIssue(ctx context.Context, call types.Content,
 		if url, exists := call.Input["url"]; exists {
 			if urlStr, ok := url.(string); ok && strings.HasPrefix(urlStr, "file://") {
 				// Check if Read tool is available
-				if s.findToolByName("Read", availableTools) != nil {
+				if s.findToolByName("Read", s.toolIndex(ctx, availableTools)) != nil {
 					// Extract file path from file:// URL
 					filePath := strings.TrimPrefix(urlStr, "file://")
 
@@ -2412,15 +2416,116 @@ func (s *Service) HasStructuralMismatch(call types.Content, availableTools []typ
 		return false // Unknown tool - let normal validation handle it
 	}
 
-	// Special handling for TodoWrite - this is the only tool we know has structural issues
-	// In the future, this could be expanded to other tools or made more generic
-	if call.Name == "TodoWrite" {
-		return s.checkTodoWriteStructure(call)
+	// Array items and nested objects are checked against the schema's items and properties
+	structureValidator, ok := s.validator.(types.StructureValidator)
+	if !ok {
+		return false
 	}
+	return len(structureValidator.ValidateStructure(call, structureSchema(toolSchema))) > 0
+}
 
-	// For other tools, we could add generic structural checks here
-	// For now, no structural validation for other tools
-	return false
+// structureSchema returns the tool's schema with the array items and nested
+// objects it leaves undescribed taken from the built-in schema of the same
+// tool, so clients sending shallow schemas still get their structure checked
+func structureSchema(tool *types.Tool) types.ToolSchema {
+	schema := tool.InputSchema
+	fallback := types.GetFallbackToolSchema(tool.Name)
+	if fallback == nil || fallback.Name != tool.Name {
+		return schema
+	}
+
+	var properties map[string]types.ToolProperty
+	for name, property := range schema.Properties {
+		builtIn, exists := fallback.InputSchema.Properties[name]
+		if !exists || builtIn.Type != property.Type || describesNested(property) || !describesNested(builtIn) {
+			continue
+		}
+		if properties == nil {
+			properties = make(map[string]types.ToolProperty, len(schema.Properties))
+			for key, original := range schema.Properties {
+				properties[key] = original
+			}
+		}
+		property.Properties, property.Required, property.Items = builtIn.Properties, builtIn.Required, builtIn.Items
+		properties[name] = property
+	}
+	if properties != nil {
+		schema.Properties = properties
+	}
+	return schema
+}
+
+// describesNested reports whether a property describes the fields or
+// elements of its values
+func describesNested(property types.ToolProperty) bool {
+	if len(property.Properties) > 0 {
+		return true
+	}
+	items := property.Items
+	return items != nil && (len(items.Properties) > 0 || len(items.Enum) > 0 || items.Items != nil)
+}
+
+// AttemptSchemaCoercion removes the parameters the tool's schema does not
+// define when its additional properties policy is strip, converts parameter
+// values sent with the wrong JSON type ("50" for a number, "true" for a
+// boolean) to the type the schema declares, then replaces near-miss enum
+// values ("in-progress") with the allowed value they meant, as far as the
+// validator supports each
+func (s *Service) AttemptSchemaCoercion(ctx context.Context, call types.Content, availableTools []types.Tool) (types.Content, bool) {
+	if call.Type != "tool_use" {
+		return call, false
+	}
+	tool := s.findToolByName(call.Name, s.toolIndex(ctx, availableTools))
+	if tool == nil {
+		if registryTool, exists := s.registry.GetSchema(call.Name); exists {
+			tool = registryTool
+		} else {
+			return call, false
+		}
+	}
+
+	schema := structureSchema(tool)
+	changed := false
+	if handler, ok := s.validator.(types.AdditionalPropertiesHandler); ok {
+		var stripped []string
+		if call, stripped = handler.StripAdditionalProperties(call, schema); len(stripped) > 0 {
+			changed = true
+			if s.shouldLog() {
+				s.logInfo(logger.ComponentToolCorrection, logger.CategoryTransformation, getRequestID(ctx), "Additional properties stripped", map[string]interface{}{
+					"tool_name":       call.Name,
+					"stripped_params": stripped,
+					"correction_type": "strip-additional-properties",
+				})
+			}
+		}
+	}
+	if coercer, ok := s.validator.(types.TypeCoercer); ok {
+		var coerced []string
+		if call, coerced = coercer.CoerceTypes(call, schema); len(coerced) > 0 {
+			changed = true
+			if s.shouldLog() {
+				s.logInfo(logger.ComponentToolCorrection, logger.CategoryTransformation, getRequestID(ctx), "Parameter type coercion", map[string]interface{}{
+					"tool_name":       call.Name,
+					"coerced_params":  coerced,
+					"correction_type": "type-coercion",
+				})
+			}
+		}
+	}
+	if corrector, ok := s.validator.(types.EnumCorrector); ok {
+		var corrected []string
+		if call, corrected = corrector.CorrectEnums(call, schema); len(corrected) > 0 {
+			changed = true
+			if s.shouldLog() {
+				s.logInfo(logger.ComponentToolCorrection, logger.CategoryTransformation, getRequestID(ctx), "Enum value correction", map[string]interface{}{
+					"tool_name":        call.Name,
+					"corrected_params": corrected,
+					"correction_type":  "enum-nearest-match",
+				})
+			}
+		}
+	}
+	return call, changed
 }
 
 // AttemptRuleBasedParameterCorrection tries to fix common parameter name issues instantly
@@ -2432,6 +2537,18 @@ func (s *Service) AttemptRuleBasedParameterCorrection(ctx context.Context, call
 		return call, false
 	}
 
+	// Custom tools registered with the validator (e.g. from tool_validators.yaml)
+	if normalizer, ok := s.validator.(types.ParameterNormalizer); ok {
+		if normalizedCall, changed := normalizer.NormalizeParameters(call); changed {
+			if s.shouldLog() {
+				s.logInfo(logger.ComponentToolCorrection, logger.CategoryTransformation, requestID, "Custom tool parameter normalization", map[string]interface{}{
+					"tool_name": call.Name,
+				})
+			}
+			return normalizedCall, true
+		}
+	}
+
 	// Tool-specific parameter mappings based on frequent correction patterns
 	// These are extracted from the actual LLM correction prompt patterns
 	toolSpecificMappings := map[string]map[string]string{
@@ -2449,9 +2566,17 @@ func (s *Service) AttemptRuleBasedParameterCorrection(ctx context.Context, call
 		// Other tools: Add as needed
 	}
 
-	// Get mappings for this specific tool
-	mappings, exists := toolSpecificMappings[call.Name]
-	if !exists {
+	// Get mappings for this specific tool, plus renames promoted by the rule learner
+	mappings := toolSpecificMappings[call.Name]
+	for oldParam, newParam := range s.learner.Promoted(call.Name) {
+		if mappings == nil {
+			mappings = make(map[string]string)
+		}
+		if _, builtIn := mappings[oldParam]; !builtIn {
+			mappings[oldParam] = newParam
+		}
+	}
+	if len(mappings) == 0 {
 		// No specific mappings for this tool, return unchanged
 		return call, false
 	}
@@ -2554,7 +2679,7 @@ func (s *Service) ValidateExitPlanMode(ctx context.Context, call types.Content,
 	}
 
 	// Send request
-	response, err := s.sendCorrectionRequest(req)
+	response, err := s.sendCorrectionRequest(ctx, req)
 	if err != nil {
 		if s.shouldLog() {
 			s.logWarn(logger.ComponentExitPlanMode, logger.CategoryWarning, requestID, "ExitPlanMode LLM validation failed, conservative fallback", map[string]interface{}{
@@ -2598,34 +2723,6 @@ func (s *Service) ValidateExitPlanMode(ctx context.Context, call types.Content,
 	}
 }
 
-// checkTodoWriteStructure validates TodoWrite internal structure
-func (s *Service) checkTodoWriteStructure(call types.Content) bool {
-	if todos, exists := call.Input["todos"]; exists {
-		if todosArray, ok := todos.([]interface{}); ok && len(todosArray) > 0 {
-			// Check if any todo item has wrong field names or missing required fields
-			for _, todoItem := range todosArray {
-				if todoMap, ok := todoItem.(map[string]interface{}); ok {
-					// Check for invalid field names that should be 'content'
-					if _, hasDesc := todoMap["description"]; hasDesc {
-						return true
-					}
-					if _, hasTask := todoMap["task"]; hasTask {
-						return true
-					}
-					// Check for missing required fields
-					if _, hasContent := todoMap["content"]; !hasContent {
-						return true
-					}
-					if _, hasPriority := todoMap["priority"]; !hasPriority {
-						return true
-					}
-				}
-			}
-		}
-	}
-	return false
-}
-
 // AnalyzeRequestContext analyzes the user request to determine if ExitPlanMode should be filtered out
 // Returns (shouldFilter, error) where shouldFilter=true means ExitPlanMode should not be available
 func (s *Service) AnalyzeRequestContext(ctx context.Context, userRequest string) (bool, error) {
@@ -2644,21 +2741,7 @@ func (s *Service) AnalyzeRequestContext(ctx context.Context, userRequest string)
 	}
 
 	// Build analysis prompt
-	prompt := fmt.Sprintf(`Analyze this user request: "%s"
-
-ExitPlanMode creates implementation plans BEFORE starting work.
-
-FILTER ExitPlanMode (respond "FILTER") for:
-- Research: "read X", "analyze Y", "examine Z"  
-- Information: "tell me about", "explain", "what is"
-- Investigation: "check", "review", "investigate"
-
-KEEP ExitPlanMode (respond "KEEP") for:  
-- Implementation: "implement", "create", "build", "develop"
-- Planning: "add feature", "make", "write code"
-
-For mixed requests, consider PRIMARY intent.
-Respond only "FILTER" or "KEEP".`, userRequest)
+	prompt := s.renderPrompt(PromptExitPlanModeFilter, exitPlanModeFilterPromptData{Request: userRequest})
 
 	// Create request to correction model
 	req := types.OpenAIRequest{
@@ -2678,7 +2761,7 @@ Respond only "FILTER" or "KEEP".`, userRequest)
 	}
 
 	// Send request
-	response, err := s.sendCorrectionRequest(req)
+	response, err := s.sendCorrectionRequest(ctx, req)
 	if err != nil {
 		if s.shouldLog() {
 			s.logWarn(logger.ComponentExitPlanMode, logger.CategoryWarning, requestID, "Context analysis LLM failed, conservative fallback", map[string]interface{}{
@@ -0,0 +1,70 @@
+package correction
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/types"
+	"context"
+)
+
+// validationRules lists the rules checked by violatedRules, in reporting order
+var validationRules = []string{
+	config.ValidationRuleSemantic,
+	config.ValidationRuleStructural,
+	config.ValidationRuleMissingParam,
+	config.ValidationRuleInvalidParam,
+}
+
+// severityRank orders severities from most to least lenient
+var severityRank = map[string]int{
+	config.ValidationSeverityWarn:  0,
+	config.ValidationSeverityFix:   1,
+	config.ValidationSeverityBlock: 2,
+}
+
+// severityConfig returns the configured validation severities, or nil when
+// every rule is fixed so callers can skip the extra validation pass
+func (s *Service) severityConfig() config.ValidationSeverityConfig {
+	severities, ok := s.config.(config.ValidationSeverityConfig)
+	if !ok {
+		return nil
+	}
+	for _, rule := range validationRules {
+		if severities.ValidationSeverityFor(rule) != config.ValidationSeverityFix {
+			return severities
+		}
+	}
+	return nil
+}
+
+// violatedRules returns the validation rules a tool call breaks. Tool name
+// issues are not a rule: they are always fixed.
+func (s *Service) violatedRules(ctx context.Context, call types.Content, validation ValidationResult, availableTools []types.Tool) []string {
+	var rules []string
+	if s.DetectSemanticIssue(ctx, call) {
+		rules = append(rules, config.ValidationRuleSemantic)
+	}
+	if validation.IsValid && s.HasStructuralMismatch(call, availableTools) {
+		rules = append(rules, config.ValidationRuleStructural)
+	}
+	if len(validation.MissingParams) > 0 {
+		rules = append(rules, config.ValidationRuleMissingParam)
+	}
+	if len(validation.InvalidParams) > 0 {
+		rules = append(rules, config.ValidationRuleInvalidParam)
+	}
+	return rules
+}
+
+// validationSeverity returns the strictest severity among the rules a tool
+// call breaks and the rule it comes from. Calls that break no rule are fixed.
+func (s *Service) validationSeverity(ctx context.Context, severities config.ValidationSeverityConfig, call types.Content, availableTools []types.Tool) (string, string, ValidationResult) {
+	validation := s.ValidateToolCall(ctx, call, availableTools)
+	severity, violated := config.ValidationSeverityFix, ""
+	for i, rule := range s.violatedRules(ctx, call, validation, availableTools) {
+		ruleSeverity := severities.ValidationSeverityFor(rule)
+		if i == 0 || severityRank[ruleSeverity] > severityRank[severity] {
+			severity, violated = ruleSeverity, rule
+		}
+	}
+	return severity, violated, validation
+}
@@ -0,0 +1,65 @@
+package correction
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"claude-proxy/logger"
+	"claude-proxy/types"
+)
+
+// SummarizeToolDescription asks the correction model to condense a verbose tool
+// description into at most maxChars characters while keeping usage rules the
+// model needs to call the tool correctly. Examples and formatting are dropped.
+func (s *Service) SummarizeToolDescription(ctx context.Context, toolName, description string, maxChars int) (string, error) {
+	requestID := getRequestID(ctx)
+
+	prompt := fmt.Sprintf(`Summarize the description of the tool "%s" in at most %d characters.
+
+Keep: what the tool does, when to use it, hard rules and constraints on its parameters.
+Drop: examples, formatting, repetition and general advice.
+Respond with ONLY the summary text.
+
+Description:
+%s`, toolName, maxChars, description)
+
+	req := types.OpenAIRequest{
+		Model: s.modelName,
+		Messages: []types.OpenAIMessage{
+			{
+				Role:    "system",
+				Content: "You compress tool documentation for small language models. Respond with plain text only.",
+			},
+			{
+				Role:    "user",
+				Content: prompt,
+			},
+		},
+		MaxTokens:   maxChars/2 + 32, // Roughly maxChars worth of tokens with headroom
+		Temperature: 0.1,
+	}
+
+	response, err := s.sendCorrectionRequest(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("tool description summarization failed for %s: %v", toolName, err)
+	}
+	if len(response.Choices) == 0 {
+		return "", fmt.Errorf("tool description summarization returned no choices for %s", toolName)
+	}
+
+	summary := strings.TrimSpace(response.Choices[0].Message.Content)
+	if summary == "" {
+		return "", fmt.Errorf("tool description summarization returned empty text for %s", toolName)
+	}
+
+	if s.shouldLog() {
+		s.logInfo(logger.ComponentToolCorrection, logger.CategoryTransformation, requestID, "Summarized tool description", map[string]interface{}{
+			"tool":         toolName,
+			"original_len": len(description),
+			"summary_len":  len(summary),
+			"max_chars":    maxChars,
+		})
+	}
+	return summary, nil
+}
@@ -0,0 +1,73 @@
+package correction
+
+import (
+	"context"
+	"strings"
+
+	"claude-proxy/types"
+)
+
+// toolIndexKey stores the request's *ToolIndex in its context
+type toolIndexKey struct{}
+
+// ToolIndex looks up a request's tools by name without scanning the tool list.
+// It is built once per request and reused for every tool call and retry
+// during validation and correction.
+type ToolIndex struct {
+	tools  []types.Tool
+	exact  map[string]*types.Tool
+	folded map[string]*types.Tool // Lowercased name -> tool
+}
+
+// NewToolIndex indexes tools by exact and case-insensitive name. When names
+// collide case-insensitively the first tool wins, matching a linear scan.
+func NewToolIndex(tools []types.Tool) *ToolIndex {
+	index := &ToolIndex{
+		tools:  tools,
+		exact:  make(map[string]*types.Tool, len(tools)),
+		folded: make(map[string]*types.Tool, len(tools)),
+	}
+	for i := range tools {
+		tool := &tools[i]
+		if _, exists := index.exact[tool.Name]; !exists {
+			index.exact[tool.Name] = tool
+		}
+		folded := strings.ToLower(tool.Name)
+		if _, exists := index.folded[folded]; !exists {
+			index.folded[folded] = tool
+		}
+	}
+	return index
+}
+
+// WithToolIndex attaches a request's tool index to the context
+func WithToolIndex(ctx context.Context, index *ToolIndex) context.Context {
+	return context.WithValue(ctx, toolIndexKey{}, index)
+}
+
+// Get finds a tool by exact name
+func (i *ToolIndex) Get(name string) *types.Tool {
+	return i.exact[name]
+}
+
+// GetFold finds a tool by case-insensitive name
+func (i *ToolIndex) GetFold(name string) *types.Tool {
+	return i.folded[strings.ToLower(name)]
+}
+
+// indexes reports whether the index was built from exactly this tool slice
+func (i *ToolIndex) indexes(tools []types.Tool) bool {
+	if len(i.tools) != len(tools) {
+		return false
+	}
+	return len(tools) == 0 || &i.tools[0] == &tools[0]
+}
+
+// toolIndex returns the request's index from ctx when it covers availableTools,
+// otherwise indexes availableTools for this call
+func (s *Service) toolIndex(ctx context.Context, availableTools []types.Tool) *ToolIndex {
+	if index, ok := ctx.Value(toolIndexKey{}).(*ToolIndex); ok && index.indexes(availableTools) {
+		return index
+	}
+	return NewToolIndex(availableTools)
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyDiagnosticSignals relays SIGUSR1 to signals
+func notifyDiagnosticSignals(signals chan<- os.Signal) {
+	signal.Notify(signals, syscall.SIGUSR1)
+}
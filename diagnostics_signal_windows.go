@@ -0,0 +1,7 @@
+package main
+
+import "os"
+
+// notifyDiagnosticSignals does nothing: Windows has no SIGUSR1, so the
+// diagnostics dump is not available there
+func notifyDiagnosticSignals(signals chan<- os.Signal) {}
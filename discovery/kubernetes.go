@@ -0,0 +1,232 @@
+package discovery
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"claude-proxy/config"
+)
+
+// In-cluster service account files mounted into every pod
+const (
+	serviceAccountDir  = "/var/run/secrets/kubernetes.io/serviceaccount"
+	serviceNameLabel   = "kubernetes.io/service-name"
+	endpointSlicesPath = "/apis/discovery.k8s.io/v1/namespaces/%s/endpointslices"
+)
+
+// KubernetesClient reads EndpointSlices from the Kubernetes API using plain
+// HTTP, authenticated with the pod's service account when running in-cluster
+type KubernetesClient struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewKubernetesClient creates a client for apiURL. An empty apiURL uses the
+// in-cluster API server and service account; an explicit one (e.g. a
+// "kubectl proxy" address) is used as is without credentials.
+func NewKubernetesClient(apiURL string) (*KubernetesClient, error) {
+	if apiURL != "" {
+		return &KubernetesClient{baseURL: strings.TrimSuffix(apiURL, "/"), httpClient: &http.Client{}}, nil
+	}
+
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("not running in a Kubernetes cluster and KUBERNETES_API_URL is not set")
+	}
+	token, err := os.ReadFile(serviceAccountDir + "/token")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account token: %v", err)
+	}
+	caCert, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account CA: %v", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("invalid service account CA certificate")
+	}
+
+	return &KubernetesClient{
+		baseURL: "https://" + net.JoinHostPort(host, port),
+		token:   strings.TrimSpace(string(token)),
+		httpClient: &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: roots},
+		}},
+	}, nil
+}
+
+// KubernetesTarget is what a k8s+ spec selects: the EndpointSlices of a
+// Service, or of every Service matching a label selector, in one namespace
+//
+//	k8s+http://vllm.llm:8000/v1/chat/completions                Service vllm in namespace llm
+//	k8s+http://llm:8000/v1/chat/completions?selector=app=vllm   label selector app=vllm in namespace llm
+//
+// The URL port is the pod port; without one the slice's first port is used.
+// The selector is a query parameter rather than a fragment because .env
+// files treat # as the start of a comment.
+type KubernetesTarget struct {
+	Namespace     string
+	LabelSelector string
+	Port          int
+	endpointURL   url.URL
+}
+
+// ParseKubernetesTarget parses a k8s+ endpoint spec
+func ParseKubernetesTarget(spec string) (KubernetesTarget, error) {
+	u, err := url.Parse(strings.TrimPrefix(spec, config.EndpointSchemeK8s))
+	if err != nil || !strings.HasPrefix(spec, config.EndpointSchemeK8s) || u.Hostname() == "" {
+		return KubernetesTarget{}, fmt.Errorf("invalid endpoint spec %q", spec)
+	}
+
+	query := u.Query()
+	selector := query.Get("selector")
+	query.Del("selector")
+
+	target := KubernetesTarget{endpointURL: *u}
+	target.endpointURL.RawQuery = query.Encode()
+	if port := u.Port(); port != "" {
+		target.Port, _ = strconv.Atoi(port)
+	}
+
+	if selector != "" {
+		target.Namespace = u.Hostname()
+		target.LabelSelector = selector
+	} else {
+		service, namespace, found := strings.Cut(u.Hostname(), ".")
+		if !found || service == "" || namespace == "" {
+			return KubernetesTarget{}, fmt.Errorf("endpoint spec %q must name <service>.<namespace> or <namespace>?selector=<label selector>", spec)
+		}
+		target.Namespace = namespace
+		target.LabelSelector = serviceNameLabel + "=" + service
+	}
+	return target, nil
+}
+
+// endpointSliceList is the subset of discovery.k8s.io/v1 EndpointSliceList the proxy reads
+type endpointSliceList struct {
+	Metadata struct {
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+	Items []struct {
+		Endpoints []struct {
+			Addresses  []string `json:"addresses"`
+			Conditions struct {
+				Ready *bool `json:"ready"`
+			} `json:"conditions"`
+		} `json:"endpoints"`
+		Ports []struct {
+			Port *int `json:"port"`
+		} `json:"ports"`
+	} `json:"items"`
+}
+
+// Endpoints lists the ready pod addresses of a target as endpoint URLs and
+// returns the list's resourceVersion for watching
+func (k *KubernetesClient) Endpoints(ctx context.Context, target KubernetesTarget) ([]string, string, error) {
+	resp, err := k.get(ctx, target, url.Values{})
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	var list endpointSliceList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, "", fmt.Errorf("failed to decode EndpointSlices: %v", err)
+	}
+
+	var endpoints []string
+	for _, slice := range list.Items {
+		port := target.Port
+		if port == 0 && len(slice.Ports) > 0 && slice.Ports[0].Port != nil {
+			port = *slice.Ports[0].Port
+		}
+		for _, endpoint := range slice.Endpoints {
+			// An unknown ready condition counts as ready
+			if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+				continue
+			}
+			for _, address := range endpoint.Addresses {
+				resolved := target.endpointURL
+				if port > 0 {
+					resolved.Host = net.JoinHostPort(address, strconv.Itoa(port))
+				} else if strings.Contains(address, ":") {
+					resolved.Host = "[" + address + "]"
+				} else {
+					resolved.Host = address
+				}
+				endpoints = append(endpoints, resolved.String())
+			}
+		}
+	}
+	return endpoints, list.Metadata.ResourceVersion, nil
+}
+
+// Watch streams EndpointSlice changes of a target from resourceVersion and
+// calls onEvent for each one until the stream ends or ctx is cancelled
+func (k *KubernetesClient) Watch(ctx context.Context, target KubernetesTarget, resourceVersion string, onEvent func()) error {
+	query := url.Values{"watch": {"true"}, "allowWatchBookmarks": {"false"}}
+	if resourceVersion != "" {
+		query.Set("resourceVersion", resourceVersion)
+	}
+	resp, err := k.get(ctx, target, query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var event struct {
+			Type string `json:"type"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &event) != nil {
+			continue
+		}
+		if event.Type == "ERROR" {
+			return fmt.Errorf("watch error event: %s", scanner.Text())
+		}
+		onEvent()
+	}
+	return scanner.Err()
+}
+
+// get requests the EndpointSlices of a target with extra query parameters
+func (k *KubernetesClient) get(ctx context.Context, target KubernetesTarget, query url.Values) (*http.Response, error) {
+	query.Set("labelSelector", target.LabelSelector)
+	requestURL := k.baseURL + fmt.Sprintf(endpointSlicesPath, url.PathEscape(target.Namespace)) + "?" + query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if k.token != "" {
+		req.Header.Set("Authorization", "Bearer "+k.token)
+	}
+	resp, err := k.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list EndpointSlices: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("kubernetes API returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return resp, nil
+}
+
+// watchRetryDelay is the pause before re-listing after a watch ends
+const watchRetryDelay = 2 * time.Second
@@ -0,0 +1,308 @@
+// Package discovery keeps endpoint lists in sync with service discovery
+// sources. Endpoint specs prefixed with dns+, srv+ or k8s+ expand into one
+// endpoint per resolved address, so each backend instance gets its own
+// circuit breaker.
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"claude-proxy/config"
+)
+
+// Lookup is the subset of *net.Resolver used to expand endpoint specs
+type Lookup interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+// Expand resolves a dns+ or srv+ endpoint spec into concrete endpoint URLs.
+// Other specs are returned unchanged.
+//
+// dns+ replaces the host with each A/AAAA address and keeps the port, so it
+// suits plain HTTP backends; TLS certificates rarely cover bare addresses.
+// srv+ looks up the host as an SRV name and uses each target and port.
+func Expand(ctx context.Context, lookup Lookup, spec string) ([]string, error) {
+	var scheme string
+	switch {
+	case strings.HasPrefix(spec, config.EndpointSchemeDNS):
+		scheme = config.EndpointSchemeDNS
+	case strings.HasPrefix(spec, config.EndpointSchemeSRV):
+		scheme = config.EndpointSchemeSRV
+	default:
+		return []string{spec}, nil
+	}
+
+	u, err := url.Parse(strings.TrimPrefix(spec, scheme))
+	if err != nil || u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid endpoint spec %q", spec)
+	}
+
+	var hostPorts []string
+	if scheme == config.EndpointSchemeDNS {
+		addresses, err := lookup.LookupHost(ctx, u.Hostname())
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %v", u.Hostname(), err)
+		}
+		for _, address := range addresses {
+			if port := u.Port(); port != "" {
+				hostPorts = append(hostPorts, net.JoinHostPort(address, port))
+			} else if strings.Contains(address, ":") {
+				hostPorts = append(hostPorts, "["+address+"]")
+			} else {
+				hostPorts = append(hostPorts, address)
+			}
+		}
+	} else {
+		_, records, err := lookup.LookupSRV(ctx, "", "", u.Hostname())
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up SRV %s: %v", u.Hostname(), err)
+		}
+		for _, record := range records {
+			target := strings.TrimSuffix(record.Target, ".")
+			hostPorts = append(hostPorts, net.JoinHostPort(target, strconv.Itoa(int(record.Port))))
+		}
+	}
+	if len(hostPorts) == 0 {
+		return nil, fmt.Errorf("%s resolved to no addresses", u.Hostname())
+	}
+
+	endpoints := make([]string, 0, len(hostPorts))
+	for _, hostPort := range hostPorts {
+		resolved := *u
+		resolved.Host = hostPort
+		endpoints = append(endpoints, resolved.String())
+	}
+	return endpoints, nil
+}
+
+// ExpandAll expands every spec of a role's list, keeping their order and
+// dropping duplicates
+func ExpandAll(ctx context.Context, lookup Lookup, specs []string) ([]string, error) {
+	var endpoints []string
+	seen := make(map[string]bool)
+	for _, spec := range specs {
+		expanded, err := Expand(ctx, lookup, spec)
+		if err != nil {
+			return nil, err
+		}
+		for _, endpoint := range expanded {
+			if !seen[endpoint] {
+				seen[endpoint] = true
+				endpoints = append(endpoints, endpoint)
+			}
+		}
+	}
+	return endpoints, nil
+}
+
+// Resolver re-resolves the dynamic endpoint specs of a configuration and
+// replaces the endpoint lists when the resolved addresses change
+type Resolver struct {
+	cfg    *config.Config
+	lookup Lookup
+	kube   *KubernetesClient
+	mu     sync.Mutex // Serializes refreshes from the ticker and Kubernetes watches
+}
+
+// NewResolver creates a resolver for cfg's endpoint specs. A nil lookup uses
+// net.DefaultResolver.
+func NewResolver(cfg *config.Config, lookup Lookup) *Resolver {
+	if lookup == nil {
+		lookup = net.DefaultResolver
+	}
+	return &Resolver{cfg: cfg, lookup: lookup}
+}
+
+// SetKubernetesClient enables k8s+ specs
+func (r *Resolver) SetKubernetesClient(kube *KubernetesClient) {
+	r.kube = kube
+}
+
+// expandAll expands a role's specs like ExpandAll, resolving k8s+ specs
+// through the Kubernetes client
+func (r *Resolver) expandAll(ctx context.Context, specs []string) ([]string, error) {
+	var endpoints []string
+	seen := make(map[string]bool)
+	for _, spec := range specs {
+		var expanded []string
+		var err error
+		if strings.HasPrefix(spec, config.EndpointSchemeK8s) {
+			expanded, err = r.expandKubernetes(ctx, spec)
+		} else {
+			expanded, err = Expand(ctx, r.lookup, spec)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, endpoint := range expanded {
+			if !seen[endpoint] {
+				seen[endpoint] = true
+				endpoints = append(endpoints, endpoint)
+			}
+		}
+	}
+	return endpoints, nil
+}
+
+// expandKubernetes lists the ready pods behind a k8s+ spec
+func (r *Resolver) expandKubernetes(ctx context.Context, spec string) ([]string, error) {
+	if r.kube == nil {
+		return nil, fmt.Errorf("endpoint spec %q requires Kubernetes discovery", spec)
+	}
+	target, err := ParseKubernetesTarget(spec)
+	if err != nil {
+		return nil, err
+	}
+	endpoints, _, err := r.kube.Endpoints(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("%s has no ready endpoints", spec)
+	}
+	return endpoints, nil
+}
+
+// Refresh resolves every role configured with dynamic specs. A role whose
+// specs fail to resolve keeps its previous endpoints; the first error is
+// returned after the other roles were refreshed.
+func (r *Resolver) Refresh(ctx context.Context) (changed []string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, role := range config.EndpointRoles {
+		specs, exists := r.cfg.EndpointSpecs[role]
+		if !exists {
+			continue
+		}
+		endpoints, resolveErr := r.expandAll(ctx, specs)
+		if resolveErr != nil {
+			if err == nil {
+				err = fmt.Errorf("%s endpoints: %v", role, resolveErr)
+			}
+			continue
+		}
+		if !sameEndpoints(r.cfg.Endpoints(role), endpoints) {
+			r.cfg.SetEndpoints(role, endpoints)
+			changed = append(changed, role)
+		}
+	}
+	return changed, err
+}
+
+// Start refreshes the endpoints every interval, and whenever a watched
+// Kubernetes target changes, until the returned function is called. onChange
+// receives the roles whose endpoints changed, onError each failed refresh.
+func (r *Resolver) Start(interval time.Duration, onChange func(roles []string), onError func(error)) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+
+	refresh := func() {
+		refreshCtx, refreshCancel := context.WithTimeout(ctx, interval)
+		changed, err := r.Refresh(refreshCtx)
+		refreshCancel()
+		if len(changed) > 0 && onChange != nil {
+			onChange(changed)
+		}
+		if err != nil && ctx.Err() == nil && onError != nil {
+			onError(err)
+		}
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				refresh()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	if r.kube != nil {
+		for _, target := range r.kubernetesTargets() {
+			wg.Add(1)
+			go func(target KubernetesTarget) {
+				defer wg.Done()
+				r.watchKubernetes(ctx, target, refresh, onError)
+			}(target)
+		}
+	}
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// kubernetesTargets returns the distinct targets of every k8s+ spec
+func (r *Resolver) kubernetesTargets() []KubernetesTarget {
+	var targets []KubernetesTarget
+	seen := make(map[string]bool)
+	for _, role := range config.EndpointRoles {
+		for _, spec := range r.cfg.EndpointSpecs[role] {
+			if !strings.HasPrefix(spec, config.EndpointSchemeK8s) {
+				continue
+			}
+			target, err := ParseKubernetesTarget(spec)
+			if err != nil {
+				continue // Reported by Refresh
+			}
+			key := target.Namespace + "/" + target.LabelSelector
+			if !seen[key] {
+				seen[key] = true
+				targets = append(targets, target)
+			}
+		}
+	}
+	return targets
+}
+
+// watchKubernetes refreshes on every EndpointSlice change of target, listing
+// again to resume after the watch ends, until ctx is cancelled
+func (r *Resolver) watchKubernetes(ctx context.Context, target KubernetesTarget, refresh func(), onError func(error)) {
+	for ctx.Err() == nil {
+		_, resourceVersion, err := r.kube.Endpoints(ctx, target)
+		if err == nil {
+			err = r.kube.Watch(ctx, target, resourceVersion, refresh)
+		}
+		if err != nil && ctx.Err() == nil && onError != nil {
+			onError(fmt.Errorf("watch %s/%s: %v", target.Namespace, target.LabelSelector, err))
+		}
+
+		select {
+		case <-time.After(watchRetryDelay):
+		case <-ctx.Done():
+		}
+	}
+}
+
+// sameEndpoints compares endpoint sets regardless of order, since health
+// reordering changes the order of the current list
+func sameEndpoints(current, resolved []string) bool {
+	if len(current) != len(resolved) {
+		return false
+	}
+	set := func(endpoints []string) map[string]bool {
+		m := make(map[string]bool, len(endpoints))
+		for _, endpoint := range endpoints {
+			m[endpoint] = true
+		}
+		return m
+	}
+	return reflect.DeepEqual(set(current), set(resolved))
+}
@@ -6,9 +6,20 @@ import "context"
 type contextKey string
 
 const (
-	RequestIDKey contextKey = "request_id"
+	RequestIDKey        contextKey = "request_id"
+	AnthropicVersionKey contextKey = "anthropic_version"
+	AnthropicBetasKey   contextKey = "anthropic_betas"
 )
 
+// RequestIDHeader is accepted from clients, echoed on responses and forwarded upstream
+const RequestIDHeader = "X-Request-ID"
+
+// AnthropicVersionHeader selects the Anthropic API version a client was written against
+const AnthropicVersionHeader = "anthropic-version"
+
+// AnthropicBetaHeader lists opt-in beta features, comma-separated
+const AnthropicBetaHeader = "anthropic-beta"
+
 // GetRequestID retrieves the request ID from context
 func GetRequestID(ctx context.Context) string {
 	if id, ok := ctx.Value(RequestIDKey).(string); ok {
@@ -20,4 +31,30 @@ func GetRequestID(ctx context.Context) string {
 // WithRequestID adds a request ID to the context
 func WithRequestID(ctx context.Context, requestID string) context.Context {
 	return context.WithValue(ctx, RequestIDKey, requestID)
+}
+
+// GetAnthropicVersion retrieves the validated anthropic-version from context, or "" when unset
+func GetAnthropicVersion(ctx context.Context) string {
+	if version, ok := ctx.Value(AnthropicVersionKey).(string); ok {
+		return version
+	}
+	return ""
+}
+
+// WithAnthropicVersion adds the request's anthropic-version to the context
+func WithAnthropicVersion(ctx context.Context, version string) context.Context {
+	return context.WithValue(ctx, AnthropicVersionKey, version)
+}
+
+// GetAnthropicBetas retrieves the beta features requested by the client
+func GetAnthropicBetas(ctx context.Context) []string {
+	if betas, ok := ctx.Value(AnthropicBetasKey).([]string); ok {
+		return betas
+	}
+	return nil
+}
+
+// WithAnthropicBetas adds the request's anthropic-beta features to the context
+func WithAnthropicBetas(ctx context.Context, betas []string) context.Context {
+	return context.WithValue(ctx, AnthropicBetasKey, betas)
 }
\ No newline at end of file
@@ -0,0 +1,312 @@
+// Package journal keeps an append-only, crash-safe record of the requests the
+// proxy accepted, so requests cut off by a crash or deploy can be found and
+// replayed after the restart.
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Record operations, one JSON line each
+const (
+	opAccepted = "accepted" // Request decoded; written and synced before any upstream call
+	opFinished = "finished" // Response written, or the interrupted entry was replayed or dismissed
+)
+
+// compactEvery rewrites the file after this many finished records, keeping it
+// proportional to the requests still open
+const compactEvery = 1000
+
+// Entry is a journaled request. Entries are keyed by ID, which the journal
+// generates: request IDs come from clients or are short and can repeat.
+type Entry struct {
+	ID         string          `json:"id"`
+	RequestID  string          `json:"request_id"`
+	AcceptedAt time.Time       `json:"accepted_at"`
+	Model      string          `json:"model"`                // As requested by the client
+	Stream     bool            `json:"stream"`               // Streaming requests can only be accounted for, not replayed
+	Request    json.RawMessage `json:"request"`              // Body as received, before any transformation
+	Path       string          `json:"path,omitempty"`       // Request path, for path-based filters on replay
+	ClientKey  string          `json:"client_key,omitempty"` // Hashed client key ID the request and its replay are charged to
+	Status     int             `json:"status,omitempty"`     // Set on finished records
+}
+
+// record is one line of the journal file
+type record struct {
+	Op string    `json:"op"`
+	At time.Time `json:"at"`
+	Entry
+}
+
+// Journal appends accepted and finished records to a JSON lines file. Entries
+// accepted but never finished by an earlier process are interrupted; they are
+// kept across compactions until Resolve is called for them.
+type Journal struct {
+	mu          sync.Mutex
+	path        string
+	file        *os.File
+	open        map[string]Entry // Accepted by this process and not finished yet
+	interrupted map[string]Entry // Left open by an earlier process
+	finished    int              // Finished records written since the last compaction
+}
+
+// Open reads the journal at path (creating it if needed), collects the
+// entries an earlier process left open and compacts the file down to them
+func Open(path string) (*Journal, error) {
+	j := &Journal{
+		path:        path,
+		open:        make(map[string]Entry),
+		interrupted: make(map[string]Entry),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read request journal %s: %v", path, err)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var rec record
+		// A crash can leave a torn last line; everything before it is intact
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue
+		}
+		// Records written before entries had their own ID are keyed by request ID
+		if rec.ID == "" {
+			rec.ID = rec.RequestID
+		}
+		switch rec.Op {
+		case opAccepted:
+			j.interrupted[rec.ID] = rec.Entry
+		case opFinished:
+			delete(j.interrupted, rec.ID)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read request journal %s: %v", path, err)
+	}
+
+	if err := j.compactLocked(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Close closes the journal file. Requests still open stay interrupted for the
+// next Open.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Close()
+	j.file = nil
+	return err
+}
+
+// Accept records a request before it is processed and syncs the file, so the
+// request survives a crash at any later point. Returns the entry's ID.
+func (j *Journal) Accept(entry Entry) (string, error) {
+	entry.ID = newEntryID()
+	entry.Request = append(json.RawMessage(nil), entry.Request...)
+	if entry.AcceptedAt.IsZero() {
+		entry.AcceptedAt = time.Now()
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.appendLocked(record{Op: opAccepted, At: entry.AcceptedAt, Entry: entry}, true); err != nil {
+		return "", err
+	}
+	j.open[entry.ID] = entry
+	return entry.ID, nil
+}
+
+// Finish records that a request accepted by this process got its response
+func (j *Journal) Finish(id string, status int) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	entry, ok := j.open[id]
+	if !ok {
+		return nil
+	}
+	delete(j.open, id)
+	return j.finishLocked(entry, status)
+}
+
+// Resolve marks an interrupted entry as accounted for, after it was replayed
+// (status is the replay's) or dismissed (status 0). Reports whether it existed.
+func (j *Journal) Resolve(id string, status int) (bool, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	entry, ok := j.interrupted[id]
+	if !ok {
+		return false, nil
+	}
+	delete(j.interrupted, id)
+	return true, j.finishLocked(entry, status)
+}
+
+// Purge dismisses the interrupted entries accepted before the given time and
+// rewrites the file without them, so their bodies are gone from disk too.
+// Returns how many were dismissed.
+func (j *Journal) Purge(before time.Time) (int, error) {
+	return j.dismiss(func(entry Entry) bool { return entry.AcceptedAt.Before(before) })
+}
+
+// Delete dismisses the interrupted entries of a request ID like Purge
+func (j *Journal) Delete(requestID string) (int, error) {
+	if requestID == "" {
+		return 0, fmt.Errorf("deleting journal entries needs a request ID")
+	}
+	return j.dismiss(func(entry Entry) bool { return entry.RequestID == requestID })
+}
+
+// dismiss drops the matching interrupted entries and compacts the file
+func (j *Journal) dismiss(match func(Entry) bool) (int, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	dismissed := 0
+	for id, entry := range j.interrupted {
+		if match(entry) {
+			delete(j.interrupted, id)
+			dismissed++
+		}
+	}
+	if dismissed == 0 {
+		return 0, nil
+	}
+	return dismissed, j.compactLocked()
+}
+
+// Interrupted returns the entries left open by an earlier process, oldest first
+func (j *Journal) Interrupted() []Entry {
+	j.mu.Lock()
+	list := make([]Entry, 0, len(j.interrupted))
+	for _, entry := range j.interrupted {
+		list = append(list, entry)
+	}
+	j.mu.Unlock()
+
+	sort.Slice(list, func(a, b int) bool { return list[a].AcceptedAt.Before(list[b].AcceptedAt) })
+	return list
+}
+
+// Get returns an interrupted entry
+func (j *Journal) Get(id string) (Entry, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	entry, ok := j.interrupted[id]
+	return entry, ok
+}
+
+// InFlight returns the number of requests accepted by this process and not finished yet
+func (j *Journal) InFlight() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return len(j.open)
+}
+
+// finishLocked appends a finished record and compacts once enough piled up
+func (j *Journal) finishLocked(entry Entry, status int) error {
+	// Losing a finished record to a crash only makes the request look interrupted
+	err := j.appendLocked(record{Op: opFinished, At: time.Now(), Entry: Entry{ID: entry.ID, RequestID: entry.RequestID, Status: status}}, false)
+	if err != nil {
+		return err
+	}
+	j.finished++
+	if j.finished >= compactEvery {
+		return j.compactLocked()
+	}
+	return nil
+}
+
+// newEntryID returns a random entry ID
+func newEntryID() string {
+	buf := make([]byte, 12)
+	rand.Read(buf)
+	return "jrn_" + hex.EncodeToString(buf)
+}
+
+// appendLocked writes one record, syncing it to disk when sync is set
+func (j *Journal) appendLocked(rec record, sync bool) error {
+	if j.file == nil {
+		return fmt.Errorf("request journal %s is closed", j.path)
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode journal record: %v", err)
+	}
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write request journal: %v", err)
+	}
+	if sync {
+		if err := j.file.Sync(); err != nil {
+			return fmt.Errorf("failed to sync request journal: %v", err)
+		}
+	}
+	return nil
+}
+
+// compactLocked atomically replaces the file with the accepted records of the
+// open and interrupted entries, then reopens it for appending
+func (j *Journal) compactLocked() error {
+	entries := make([]Entry, 0, len(j.interrupted)+len(j.open))
+	for _, entry := range j.interrupted {
+		entries = append(entries, entry)
+	}
+	for _, entry := range j.open {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(a, b int) bool { return entries[a].AcceptedAt.Before(entries[b].AcceptedAt) })
+
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		line, err := json.Marshal(record{Op: opAccepted, At: entry.AcceptedAt, Entry: entry})
+		if err != nil {
+			return fmt.Errorf("failed to encode journal record: %v", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	tmpPath := j.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to compact request journal: %v", err)
+	}
+	if _, err := tmp.Write(buf.Bytes()); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, j.path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to compact request journal: %v", err)
+	}
+
+	if j.file != nil {
+		j.file.Close()
+	}
+	j.file, err = os.OpenFile(j.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		j.file = nil
+		return fmt.Errorf("failed to open request journal %s: %v", j.path, err)
+	}
+	j.finished = 0
+	return nil
+}
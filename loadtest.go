@@ -0,0 +1,106 @@
+package main
+
+import (
+	"claude-proxy/types"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// runLoadtest implements "simple-proxy loadtest [-url URL] [-n N] [-c N] ...":
+// it sends n identical requests to a running proxy, c at a time, and reports
+// throughput, latency percentiles and failures. Returns 1 when any request
+// fails.
+func runLoadtest(args []string) int {
+	flags := newFlagSet("loadtest", "loadtest [-url URL] [-n N] [-c N] [-model MODEL] [-prompt TEXT] [-max-tokens N] [-api-key KEY]")
+	url := flags.String("url", defaultProxyURL, "Messages endpoint of the proxy under test")
+	total := flags.Int("n", 20, "Requests to send")
+	concurrency := flags.Int("c", 4, "Requests in flight at once")
+	model := flags.String("model", "claude-3-5-haiku-20241022", "Client model name to request")
+	prompt := flags.String("prompt", "Reply with the single word OK.", "User message of every request")
+	maxTokens := flags.Int("max-tokens", 32, "max_tokens of every request")
+	apiKey := flags.String("api-key", "", "Sent as x-api-key")
+	timeout := flags.Duration("timeout", 5*time.Minute, "Time allowed for each request")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *total < 1 || *concurrency < 1 {
+		fmt.Fprintln(os.Stderr, "-n and -c must be at least 1")
+		return 2
+	}
+
+	req := types.AnthropicRequest{
+		Model:     *model,
+		MaxTokens: *maxTokens,
+		Messages:  []types.Message{{Role: "user", Content: *prompt}},
+	}
+	client := &http.Client{Timeout: *timeout}
+
+	results := make([]messageResult, *total)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < *concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = postMessage(client, *url, *apiKey, req)
+			}
+		}()
+	}
+	for i := 0; i < *total; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	var latencies []time.Duration
+	var outputTokens int
+	errors := make(map[string]int)
+	for _, result := range results {
+		if result.Response == nil {
+			key := result.Error
+			if result.Status != 0 {
+				key = fmt.Sprintf("HTTP %d", result.Status)
+			}
+			errors[key]++
+			continue
+		}
+		latencies = append(latencies, result.Latency)
+		outputTokens += result.Response.Usage.OutputTokens
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	fmt.Printf("Requests:      %d (%d succeeded, %d failed) at concurrency %d\n", *total, len(latencies), *total-len(latencies), *concurrency)
+	fmt.Printf("Duration:      %s\n", elapsed.Round(time.Millisecond))
+	fmt.Printf("Throughput:    %.2f req/s, %.1f output tokens/s\n", float64(len(latencies))/elapsed.Seconds(), float64(outputTokens)/elapsed.Seconds())
+	if len(latencies) > 0 {
+		fmt.Printf("Latency:       p50 %s, p95 %s, p99 %s, max %s\n",
+			percentile(latencies, 0.50), percentile(latencies, 0.95), percentile(latencies, 0.99), latencies[len(latencies)-1].Round(time.Millisecond))
+	}
+	for reason, count := range errors {
+		fmt.Printf("Failed:        %d × %s\n", count, reason)
+	}
+
+	if len(errors) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(float64(len(sorted))*p+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank].Round(time.Millisecond)
+}
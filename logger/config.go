@@ -5,20 +5,20 @@ import (
 	"context"
 )
 
-// ConfigAdapter adapts the existing config.Config to implement LoggerConfig
+// ConfigAdapter adapts the logging section of config.Config to implement LoggerConfig
 type ConfigAdapter struct {
-	config *config.Config
+	config config.LoggingConfig
 }
 
 // NewConfigAdapter creates a new ConfigAdapter
-func NewConfigAdapter(cfg *config.Config) LoggerConfig {
+func NewConfigAdapter(cfg config.LoggingConfig) LoggerConfig {
 	return &ConfigAdapter{config: cfg}
 }
 
 // ShouldLogForModel determines if logging should be enabled for the given model
 func (c *ConfigAdapter) ShouldLogForModel(model string) bool {
 	// If small model logging is disabled and this is a small model, don't log
-	if c.config.DisableSmallModelLogging && c.isSmallModelSimple(model) {
+	if c.config.IsSmallModelLoggingDisabled() && c.isSmallModelSimple(model) {
 		return false
 	}
 	return true
@@ -28,7 +28,7 @@ func (c *ConfigAdapter) ShouldLogForModel(model string) bool {
 // without requiring context (to avoid [unknown] request IDs in logs)
 func (c *ConfigAdapter) isSmallModelSimple(model string) bool {
 	// Check direct matches first (most common cases)
-	if model == "claude-3-5-haiku-20241022" || model == c.config.SmallModel {
+	if model == "claude-3-5-haiku-20241022" || model == c.config.GetSmallModelName() {
 		return true
 	}
 	
@@ -41,11 +41,14 @@ func (c *ConfigAdapter) isSmallModelSimple(model string) bool {
 	return false
 }
 
-// GetMinLogLevel returns the minimum log level (currently always DEBUG for backwards compatibility)
+// GetMinLogLevel returns the process-wide default level (LOG_LEVEL, adjustable at runtime)
 func (c *ConfigAdapter) GetMinLogLevel() Level {
-	// For now, maintain backwards compatibility by allowing all levels
-	// In the future, this could be configurable via environment variables
-	return DEBUG
+	return Levels().DefaultLevel()
+}
+
+// GetMinLogLevelForComponent returns the runtime level for a component (LOG_LEVELS overrides)
+func (c *ConfigAdapter) GetMinLogLevelForComponent(component string) Level {
+	return Levels().ComponentLevel(component)
 }
 
 // ShouldMaskAPIKeys returns whether API keys should be masked in logs
@@ -58,13 +61,13 @@ func (c *ConfigAdapter) ShouldMaskAPIKeys() bool {
 // This avoids duplicate context key definitions
 
 // NewFromConfig creates a new logger using the existing config
-func NewFromConfig(ctx context.Context, cfg *config.Config) Logger {
+func NewFromConfig(ctx context.Context, cfg config.LoggingConfig) Logger {
 	loggerConfig := NewConfigAdapter(cfg)
 	return New(ctx, loggerConfig)
 }
 
 // ContextLoggerFromConfig creates a logger and stores it in context for easy access
-func ContextLoggerFromConfig(ctx context.Context, cfg *config.Config) (context.Context, Logger) {
+func ContextLoggerFromConfig(ctx context.Context, cfg config.LoggingConfig) (context.Context, Logger) {
 	logger := NewFromConfig(ctx, cfg)
 	newCtx := context.WithValue(ctx, loggerContextKey, logger)
 	return newCtx, logger
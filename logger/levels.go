@@ -0,0 +1,219 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ComponentLevelConfig is an optional LoggerConfig extension that allows the
+// minimum level to vary per component
+type ComponentLevelConfig interface {
+	GetMinLogLevelForComponent(component string) Level
+}
+
+// componentAliases maps short operator-facing names to component labels
+var componentAliases = map[string]string{
+	"proxy":      ComponentProxy,
+	"correction": ComponentToolCorrection,
+	"classifier": ComponentHybridClassifier,
+	"config":     ComponentConfig,
+	"access":     ComponentAccessLog,
+}
+
+// LevelController holds the process-wide minimum log levels. It can be changed
+// at runtime so verbose logging can be enabled without restarting the proxy.
+type LevelController struct {
+	mu           sync.RWMutex
+	defaultLevel Level
+	components   map[string]Level
+}
+
+var levels = NewLevelController(INFO)
+
+// Levels returns the process-wide level controller
+func Levels() *LevelController {
+	return levels
+}
+
+// NewLevelController creates a controller with the given default level
+func NewLevelController(defaultLevel Level) *LevelController {
+	return &LevelController{
+		defaultLevel: defaultLevel,
+		components:   make(map[string]Level),
+	}
+}
+
+// ParseLevel converts a level name (case-insensitive) to a Level
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToUpper(strings.TrimSpace(name)) {
+	case "DEBUG":
+		return DEBUG, nil
+	case "INFO":
+		return INFO, nil
+	case "WARN", "WARNING":
+		return WARN, nil
+	case "ERROR":
+		return ERROR, nil
+	default:
+		return INFO, fmt.Errorf("invalid log level %q (valid: DEBUG, INFO, WARN, ERROR)", name)
+	}
+}
+
+// NormalizeComponent resolves short aliases such as "correction" to component labels
+func NormalizeComponent(component string) string {
+	component = strings.ToLower(strings.TrimSpace(component))
+	if alias, exists := componentAliases[component]; exists {
+		return alias
+	}
+	return component
+}
+
+// DefaultLevel returns the level applied to components without an override
+func (c *LevelController) DefaultLevel() Level {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.defaultLevel
+}
+
+// SetDefaultLevel changes the level applied to components without an override
+func (c *LevelController) SetDefaultLevel(level Level) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.defaultLevel = level
+}
+
+// ComponentLevel returns the effective minimum level for a component
+func (c *LevelController) ComponentLevel(component string) Level {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if level, exists := c.components[NormalizeComponent(component)]; exists {
+		return level
+	}
+	return c.defaultLevel
+}
+
+// SetComponentLevel overrides the minimum level for a single component
+func (c *LevelController) SetComponentLevel(component string, level Level) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.components[NormalizeComponent(component)] = level
+}
+
+// ResetComponentLevel removes a component override so it follows the default level again
+func (c *LevelController) ResetComponentLevel(component string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.components, NormalizeComponent(component))
+}
+
+// ApplySpec applies a comma-separated list of component=LEVEL overrides,
+// e.g. "correction=DEBUG,harmony=DEBUG"
+func (c *LevelController) ApplySpec(spec string) error {
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return fmt.Errorf("invalid component log level %q (expected component=LEVEL)", entry)
+		}
+		level, err := ParseLevel(parts[1])
+		if err != nil {
+			return err
+		}
+		c.SetComponentLevel(parts[0], level)
+	}
+	return nil
+}
+
+// Replace sets the default level and replaces every component override with
+// those in spec. Nothing changes if spec is invalid.
+func (c *LevelController) Replace(defaultLevel Level, spec string) error {
+	parsed := NewLevelController(defaultLevel)
+	if err := parsed.ApplySpec(spec); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.defaultLevel = defaultLevel
+	c.components = parsed.components
+	return nil
+}
+
+// Snapshot returns the current levels keyed by component name
+func (c *LevelController) Snapshot() (string, map[string]string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	components := make(map[string]string, len(c.components))
+	for component, level := range c.components {
+		components[component] = level.String()
+	}
+	return c.defaultLevel.String(), components
+}
+
+// logLevelRequest is the body accepted by HandleLogLevel
+type logLevelRequest struct {
+	Component string `json:"component"` // Empty to change the default level
+	Level     string `json:"level"`
+	Reset     bool   `json:"reset"` // Remove the component override
+}
+
+// HandleLogLevel serves the admin log level endpoint.
+// GET returns the current levels; PUT/POST changes the default or a component level.
+func (c *LevelController) HandleLogLevel(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		var req logLevelRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+
+		if req.Reset {
+			if req.Component == "" {
+				http.Error(w, "component is required for reset", http.StatusBadRequest)
+				return
+			}
+			c.ResetComponentLevel(req.Component)
+		} else {
+			level, err := ParseLevel(req.Level)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if req.Component == "" {
+				c.SetDefaultLevel(level)
+			} else {
+				c.SetComponentLevel(req.Component, level)
+			}
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	defaultLevel, components := c.Snapshot()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"default":    defaultLevel,
+		"components": components,
+	})
+}
+
+// minLevelFor resolves the minimum level for a logger's component using the
+// per-component extension when the config supports it
+func minLevelFor(config LoggerConfig, component string) Level {
+	if component != "" {
+		if componentConfig, ok := config.(ComponentLevelConfig); ok {
+			return componentConfig.GetMinLogLevelForComponent(component)
+		}
+	}
+	return config.GetMinLogLevel()
+}
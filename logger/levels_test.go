@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// componentLevelTestConfig routes level lookups through a LevelController
+type componentLevelTestConfig struct {
+	levels *LevelController
+}
+
+func (c *componentLevelTestConfig) ShouldLogForModel(model string) bool { return true }
+func (c *componentLevelTestConfig) GetMinLogLevel() Level               { return c.levels.DefaultLevel() }
+func (c *componentLevelTestConfig) ShouldMaskAPIKeys() bool             { return true }
+func (c *componentLevelTestConfig) GetMinLogLevelForComponent(component string) Level {
+	return c.levels.ComponentLevel(component)
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("debug")
+	require.NoError(t, err)
+	require.Equal(t, DEBUG, level)
+
+	level, err = ParseLevel(" WARNING ")
+	require.NoError(t, err)
+	require.Equal(t, WARN, level)
+
+	_, err = ParseLevel("verbose")
+	require.Error(t, err)
+}
+
+func TestLevelControllerComponentOverrides(t *testing.T) {
+	levels := NewLevelController(INFO)
+	require.NoError(t, levels.ApplySpec("correction=DEBUG, harmony=error"))
+
+	// Aliases resolve to the component labels used by the loggers
+	require.Equal(t, DEBUG, levels.ComponentLevel(ComponentToolCorrection))
+	require.Equal(t, ERROR, levels.ComponentLevel(ComponentHarmony))
+	require.Equal(t, INFO, levels.ComponentLevel(ComponentProxy))
+
+	levels.ResetComponentLevel("correction")
+	require.Equal(t, INFO, levels.ComponentLevel(ComponentToolCorrection))
+
+	require.Error(t, levels.ApplySpec("harmony"))
+	require.Error(t, levels.ApplySpec("harmony=LOUD"))
+}
+
+func TestLevelControllerReplace(t *testing.T) {
+	levels := NewLevelController(INFO)
+	require.NoError(t, levels.ApplySpec("correction=DEBUG"))
+
+	require.NoError(t, levels.Replace(WARN, "harmony=DEBUG"))
+	require.Equal(t, WARN, levels.DefaultLevel())
+	require.Equal(t, WARN, levels.ComponentLevel(ComponentToolCorrection), "overrides not in the new spec are dropped")
+	require.Equal(t, DEBUG, levels.ComponentLevel(ComponentHarmony))
+
+	// An invalid spec leaves the levels untouched
+	require.Error(t, levels.Replace(ERROR, "harmony=LOUD"))
+	require.Equal(t, WARN, levels.DefaultLevel())
+	require.Equal(t, DEBUG, levels.ComponentLevel(ComponentHarmony))
+}
+
+func TestLokiLoggerRespectsComponentLevel(t *testing.T) {
+	levels := NewLevelController(WARN)
+	levels.SetComponentLevel("harmony", DEBUG)
+	config := &componentLevelTestConfig{levels: levels}
+
+	l, err := NewLokiLogger(context.Background(), config, "http://localhost:3100")
+	require.NoError(t, err)
+	lokiLogger := l.(*LokiLogger)
+
+	require.False(t, lokiLogger.shouldLog(INFO), "default level should filter INFO")
+	require.True(t, lokiLogger.WithComponent(ComponentHarmony).(*LokiLogger).shouldLog(DEBUG))
+	require.True(t, lokiLogger.WithField("component", ComponentHarmony).(*LokiLogger).shouldLog(DEBUG))
+	require.False(t, lokiLogger.WithComponent(ComponentProxy).(*LokiLogger).shouldLog(DEBUG))
+}
+
+func TestHandleLogLevel(t *testing.T) {
+	levels := NewLevelController(INFO)
+
+	body, _ := json.Marshal(map[string]interface{}{"component": "correction", "level": "DEBUG"})
+	rec := httptest.NewRecorder()
+	levels.HandleLogLevel(rec, httptest.NewRequest(http.MethodPut, "/admin/log-level", bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp struct {
+		Default    string            `json:"default"`
+		Components map[string]string `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, "INFO", resp.Default)
+	require.Equal(t, "DEBUG", resp.Components[ComponentToolCorrection])
+
+	body, _ = json.Marshal(map[string]interface{}{"level": "nope"})
+	rec = httptest.NewRecorder()
+	levels.HandleLogLevel(rec, httptest.NewRequest(http.MethodPut, "/admin/log-level", bytes.NewReader(body)))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	levels.HandleLogLevel(rec, httptest.NewRequest(http.MethodDelete, "/admin/log-level", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
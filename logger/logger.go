@@ -155,7 +155,11 @@ func (l *ContextLogger) WithComponent(component string) Logger {
 // shouldLog determines if a message should be logged based on level and model filtering
 func (l *ContextLogger) shouldLog(level Level) bool {
 	// Check minimum log level
-	if level < l.config.GetMinLogLevel() {
+	component := l.component
+	if component == "" {
+		component = l.fields["component"]
+	}
+	if level < minLevelFor(l.config, component) {
 		return false
 	}
 	
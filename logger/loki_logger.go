@@ -22,6 +22,8 @@ const (
 	ComponentSchemaCorrection = "schema_correction"
 	ComponentEndpointManagement = "endpoint_management"
 	ComponentConfig        = "configuration"
+	ComponentHarmony       = "harmony"
+	ComponentAccessLog     = "access_log"
 )
 
 // Category constants for log classification
@@ -48,6 +50,8 @@ type LokiLogger struct {
 	fields    map[string]string
 	model     string
 	component string
+	redactor  *Redactor // Masks conversation data, nil when masking is off
+	truncator *Truncator // Shortens long conversation strings, nil when truncation is off
 }
 
 // LokiLogEntry represents a Loki log entry
@@ -77,6 +81,16 @@ func NewLokiLogger(ctx context.Context, config LoggerConfig, lokiURL string) (Lo
 	}, nil
 }
 
+// SetRedactor masks the data of conversation log entries with r
+func (l *LokiLogger) SetRedactor(r *Redactor) {
+	l.redactor = r
+}
+
+// SetTruncator shortens the strings of conversation log entries with t
+func (l *LokiLogger) SetTruncator(t *Truncator) {
+	l.truncator = t
+}
+
 // Close shuts down the logger
 func (l *LokiLogger) Close() error {
 	l.client.CloseIdleConnections()
@@ -99,6 +113,8 @@ func (l *LokiLogger) WithField(key, value string) Logger {
 		fields:    newFields,
 		model:     l.model,
 		component: l.component,
+		redactor:  l.redactor,
+		truncator: l.truncator,
 	}
 }
 
@@ -117,6 +133,8 @@ func (l *LokiLogger) WithModel(model string) Logger {
 		fields:    newFields,
 		model:     model,
 		component: l.component,
+		redactor:  l.redactor,
+		truncator: l.truncator,
 	}
 }
 
@@ -135,6 +153,8 @@ func (l *LokiLogger) WithComponent(component string) Logger {
 		fields:    newFields,
 		model:     l.model,
 		component: component,
+		redactor:  l.redactor,
+		truncator: l.truncator,
 	}
 }
 
@@ -145,7 +165,11 @@ func (l *LokiLogger) shouldLog(level Level) bool {
 		return true
 	}
 	
-	if level < l.config.GetMinLogLevel() {
+	component := l.component
+	if component == "" {
+		component = l.fields["component"]
+	}
+	if level < minLevelFor(l.config, component) {
 		return false
 	}
 	
@@ -452,6 +476,13 @@ func (l *LokiObservabilityLogger) Error(component, category, requestID, message
 
 // Conversation logging methods - extending LokiLogger for structured conversation data
 
+// redactJSON encodes conversation data for a log entry, masked by the
+// redactor and then shortened by the truncator
+func (l *LokiLogger) redactJSON(data interface{}) []byte {
+	encoded, _ := json.Marshal(data)
+	return l.truncator.JSON(l.redactor.JSON(encoded))
+}
+
 // LogConversationStart logs the beginning of a new conversation
 func (l *LokiLogger) LogConversationStart(ctx context.Context, requestID, sessionID string) {
 	l.WithField("event", "conversation_start").
@@ -463,7 +494,7 @@ func (l *LokiLogger) LogConversationStart(ctx context.Context, requestID, sessio
 
 // LogRequest logs a complete incoming request
 func (l *LokiLogger) LogRequest(ctx context.Context, requestID, sessionID string, request interface{}) {
-	requestJSON, _ := json.Marshal(request)
+	requestJSON := l.redactJSON(request)
 	
 	l.WithField("event", "request").
 		WithField("session_id", sessionID).
@@ -476,7 +507,7 @@ func (l *LokiLogger) LogRequest(ctx context.Context, requestID, sessionID string
 
 // LogResponse logs a complete outgoing response  
 func (l *LokiLogger) LogResponse(ctx context.Context, requestID, sessionID string, response interface{}) {
-	responseJSON, _ := json.Marshal(response)
+	responseJSON := l.redactJSON(response)
 	
 	l.WithField("event", "response").
 		WithField("session_id", sessionID).
@@ -489,8 +520,8 @@ func (l *LokiLogger) LogResponse(ctx context.Context, requestID, sessionID strin
 
 // LogToolCall logs a tool call and its result
 func (l *LokiLogger) LogToolCall(ctx context.Context, requestID, sessionID, toolName string, params, result interface{}) {
-	paramsJSON, _ := json.Marshal(params)
-	resultJSON, _ := json.Marshal(result)
+	paramsJSON := l.redactJSON(params)
+	resultJSON := l.redactJSON(result)
 	
 	l.WithField("event", "tool_call").
 		WithField("session_id", sessionID).
@@ -505,8 +536,8 @@ func (l *LokiLogger) LogToolCall(ctx context.Context, requestID, sessionID, tool
 
 // LogCorrection logs a tool correction attempt
 func (l *LokiLogger) LogCorrection(ctx context.Context, requestID, sessionID string, original, corrected interface{}, method string) {
-	originalJSON, _ := json.Marshal(original)
-	correctedJSON, _ := json.Marshal(corrected)
+	originalJSON := l.redactJSON(original)
+	correctedJSON := l.redactJSON(corrected)
 	
 	l.WithField("event", "correction").
 		WithField("session_id", sessionID).
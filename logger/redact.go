@@ -0,0 +1,157 @@
+package logger
+
+import (
+	"bytes"
+	"claude-proxy/config"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// redactionDetectors holds the expressions behind each built-in detector of
+// CONVERSATION_MASK_DETECTORS
+var redactionDetectors = map[string][]*regexp.Regexp{
+	config.RedactionDetectorAPIKey: {
+		regexp.MustCompile(`\b(?:sk|pk|rk)-[A-Za-z0-9_-]{16,}`),
+		regexp.MustCompile(`\bBearer\s+[A-Za-z0-9._~+/-]{16,}=*`),
+		regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`),
+		regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`),
+	},
+	config.RedactionDetectorEmail: {
+		regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`),
+	},
+	config.RedactionDetectorPhone: {
+		regexp.MustCompile(`\+\d{1,3}[ .-]?\(?\d{1,4}\)?(?:[ .-]?\d{2,4}){2,4}\b`),
+		regexp.MustCompile(`(?:\(\d{3}\) ?|\b\d{3}[ .-])\d{3}[ .-]\d{4}\b`),
+	},
+	config.RedactionDetectorIP: {
+		regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`),
+		regexp.MustCompile(`\b(?:[0-9A-Fa-f]{1,4}:){7}[0-9A-Fa-f]{1,4}\b`),
+	},
+}
+
+// redactionRule replaces every match of an expression with a placeholder
+type redactionRule struct {
+	expr        *regexp.Regexp
+	replacement string
+}
+
+// Redactor masks personal data and secrets in conversation logs before they
+// are shipped to Loki or stored on disk. A nil Redactor leaves data unchanged.
+type Redactor struct {
+	rules []redactionRule
+}
+
+// NewConversationRedactor builds the redactor for CONVERSATION_MASK_SENSITIVE,
+// CONVERSATION_MASK_DETECTORS and redaction_patterns.yaml. Returns nil when
+// masking is disabled.
+func NewConversationRedactor(cfg *config.Config) *Redactor {
+	if !cfg.ConversationMaskSensitive {
+		return nil
+	}
+	return NewRedactor(cfg.ConversationMaskDetectors, cfg.RedactionPatterns)
+}
+
+// NewRedactor builds a redactor from built-in detector names and custom
+// patterns. Built-in detectors run first, API keys before anything else, so a
+// custom pattern never sees half of a masked secret.
+func NewRedactor(detectors []string, patterns []config.RedactionPattern) *Redactor {
+	r := &Redactor{}
+	for _, detector := range []string{config.RedactionDetectorAPIKey, config.RedactionDetectorEmail, config.RedactionDetectorIP, config.RedactionDetectorPhone} {
+		if !containsDetector(detectors, detector) {
+			continue
+		}
+		for _, expr := range redactionDetectors[detector] {
+			r.rules = append(r.rules, redactionRule{expr: expr, replacement: redactionPlaceholder(detector)})
+		}
+	}
+	for _, pattern := range patterns {
+		if expr := pattern.Regexp(); expr != nil {
+			r.rules = append(r.rules, redactionRule{expr: expr, replacement: redactionPlaceholder(pattern.Name)})
+		}
+	}
+	return r
+}
+
+// redactionPlaceholder returns the text that replaces a match, e.g. [REDACTED_EMAIL]
+func redactionPlaceholder(name string) string {
+	return "[REDACTED_" + strings.ToUpper(name) + "]"
+}
+
+// containsDetector reports whether detectors enables the named detector
+func containsDetector(detectors []string, name string) bool {
+	for _, detector := range detectors {
+		if detector == name {
+			return true
+		}
+	}
+	return false
+}
+
+// String masks every match in s
+func (r *Redactor) String(s string) string {
+	if r == nil {
+		return s
+	}
+	for _, rule := range r.rules {
+		s = rule.expr.ReplaceAllLiteralString(s, rule.replacement)
+	}
+	return s
+}
+
+// JSON masks every string value and object key in a JSON document
+func (r *Redactor) JSON(data []byte) []byte {
+	if r == nil {
+		return data
+	}
+	return rewriteJSONStrings(data, func(s string, _ bool) string { return r.String(s) })
+}
+
+// Copy stores a masked copy of src in dst, a pointer to a zero value of the
+// same type, leaving src untouched
+func (r *Redactor) Copy(src, dst interface{}) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(r.JSON(data), dst)
+}
+
+// rewriteJSONStrings applies fn to every string value and object key (key is
+// true) of a JSON document. Rewriting the decoded strings rather than the
+// encoded text keeps escape sequences intact. Data that is not valid JSON is
+// rewritten as a single plain string.
+func rewriteJSONStrings(data []byte, fn func(s string, key bool) string) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber() // Keep large integers exact
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return []byte(fn(string(data), false))
+	}
+	rewritten, err := json.Marshal(rewriteJSONValue(value, fn))
+	if err != nil {
+		return data
+	}
+	return rewritten
+}
+
+// rewriteJSONValue applies fn to the strings of a decoded JSON value
+func rewriteJSONValue(value interface{}, fn func(s string, key bool) string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return fn(v, false)
+	case []interface{}:
+		for i, item := range v {
+			v[i] = rewriteJSONValue(item, fn)
+		}
+		return v
+	case map[string]interface{}:
+		rewritten := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			rewritten[fn(key, true)] = rewriteJSONValue(item, fn)
+		}
+		return rewritten
+	default:
+		return v
+	}
+}
@@ -0,0 +1,70 @@
+package logger
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/tokenizer"
+)
+
+// truncationMarker replaces the middle of a truncated string
+const truncationMarker = " ... "
+
+// Truncator shortens long strings in conversation logs to their beginning and
+// end. A nil Truncator leaves data unchanged.
+type Truncator struct {
+	maxChars  int // Limit in characters (runes), 0 when unset
+	maxTokens int // Limit in estimated tokens, takes precedence over maxChars
+}
+
+// NewConversationTruncator builds the truncator for CONVERSATION_TRUNCATION
+// and CONVERSATION_TRUNCATION_TOKENS. Returns nil when both are disabled.
+func NewConversationTruncator(cfg *config.Config) *Truncator {
+	if cfg.ConversationTruncation <= 0 && cfg.ConversationTruncationTokens <= 0 {
+		return nil
+	}
+	return &Truncator{maxChars: cfg.ConversationTruncation, maxTokens: cfg.ConversationTruncationTokens}
+}
+
+// String shortens s to the configured limit
+func (t *Truncator) String(s string) string {
+	switch {
+	case t == nil:
+		return s
+	case t.maxTokens > 0:
+		return tokenizer.Truncate(s, t.maxTokens, truncationMarker)
+	default:
+		return truncateRunes(s, t.maxChars)
+	}
+}
+
+// JSON shortens every string value of a JSON document; object keys are kept
+func (t *Truncator) JSON(data []byte) []byte {
+	if t == nil {
+		return data
+	}
+	return rewriteJSONStrings(data, func(s string, key bool) string {
+		if key {
+			return s
+		}
+		return t.String(s)
+	})
+}
+
+// truncateRunes shortens s to maxChars characters without splitting
+// multi-byte characters
+func truncateRunes(s string, maxChars int) string {
+	if maxChars <= 0 || len(s) <= maxChars {
+		return s
+	}
+	runes := []rune(s)
+	if len(runes) <= maxChars {
+		return s
+	}
+	if maxChars < len(truncationMarker) {
+		return string(runes[:maxChars])
+	}
+	keep := (maxChars - len(truncationMarker)) / 2
+	if keep < 1 {
+		keep = 1
+	}
+	return string(runes[:keep]) + truncationMarker + string(runes[len(runes)-keep:])
+}
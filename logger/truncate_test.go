@@ -0,0 +1,42 @@
+package logger
+
+import (
+	"claude-proxy/config"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewConversationTruncator(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	require.Nil(t, NewConversationTruncator(cfg), "truncation is off by default")
+
+	var disabled *Truncator
+	require.Equal(t, "unchanged", disabled.String("unchanged"))
+	require.Equal(t, []byte(`{"a":"b"}`), disabled.JSON([]byte(`{"a":"b"}`)))
+}
+
+func TestTruncatorCharacters(t *testing.T) {
+	tr := NewConversationTruncator(&config.Config{ConversationTruncation: 15})
+
+	require.Equal(t, "short", tr.String("short"))
+	truncated := tr.String(strings.Repeat("日本語", 10))
+	require.True(t, utf8.ValidString(truncated), "multi-byte characters are not split")
+	require.Equal(t, "日本語日本 ... 本語日本語", truncated)
+}
+
+func TestTruncatorTokens(t *testing.T) {
+	tr := NewConversationTruncator(&config.Config{ConversationTruncation: 10000, ConversationTruncationTokens: 12})
+
+	long := "Read the file " + strings.Repeat("and then edit it ", 50) + "done."
+	truncated := tr.String(long)
+	require.True(t, strings.HasPrefix(truncated, "Read the"))
+	require.True(t, strings.HasSuffix(truncated, "done."))
+	require.Contains(t, truncated, " ... ", "the token limit takes precedence over characters")
+
+	data := tr.JSON([]byte(`{"content":"` + long + `","tool_names_that_stay_complete":1}`))
+	require.Contains(t, string(data), `"tool_names_that_stay_complete":1`, "object keys are kept")
+	require.Contains(t, string(data), `"content":"Read the`)
+}
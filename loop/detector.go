@@ -14,6 +14,7 @@ type LoopDetector struct {
 	recentCalls []toolCallRecord
 	maxHistory  int
 	timeWindow  time.Duration
+	threshold   int // Consecutive identical calls that make a loop
 }
 
 type toolCallRecord struct {
@@ -25,10 +26,21 @@ type toolCallRecord struct {
 
 // NewLoopDetector creates a new loop detector
 func NewLoopDetector() *LoopDetector {
+	return NewLoopDetectorWithThreshold(3)
+}
+
+// NewLoopDetectorWithThreshold creates a loop detector that reports a loop
+// once the same call has been made threshold times in a row. Thresholds
+// below 2 fall back to the default of 3.
+func NewLoopDetectorWithThreshold(threshold int) *LoopDetector {
+	if threshold < 2 {
+		threshold = 3
+	}
 	return &LoopDetector{
 		recentCalls: make([]toolCallRecord, 0),
 		maxHistory:  20,              // Track last 20 tool calls
 		timeWindow:  5 * time.Minute, // Consider calls within 5 minutes
+		threshold:   threshold,
 	}
 }
 
@@ -41,13 +53,13 @@ func (ld *LoopDetector) DetectLoop(ctx context.Context, messages []types.OpenAIM
 	toolCalls := ld.extractToolCallsFromFilteredMessages(filteredMessages)
 
 	// Look for repetitive patterns in the filtered messages
-	if len(toolCalls) < 3 {
+	if len(toolCalls) < ld.threshold {
 		return &LoopDetection{HasLoop: false}
 	}
 
 	// Check for consecutive identical tool calls
 	consecutiveCount := ld.countConsecutiveIdenticalCalls(toolCalls)
-	if consecutiveCount >= 3 {
+	if consecutiveCount >= ld.threshold {
 		lastCall := toolCalls[len(toolCalls)-1]
 		return &LoopDetection{
 			HasLoop:        true,
@@ -0,0 +1,59 @@
+package loop
+
+import (
+	"claude-proxy/types"
+	"fmt"
+)
+
+// nudgeText is the instruction both interventions give the model
+func nudgeText(detection *LoopDetection) string {
+	return fmt.Sprintf("[Loop Guard] %s Repeating the same call will not produce a different result. Use the results you already have, try a different tool or different arguments, or explain to the user what is blocking you.", detection.Recommendation)
+}
+
+// Nudge adds a loop warning to the system message of an upstream request (the
+// developer message for Harmony models), creating one when the conversation
+// has none. The warning goes to the existing message because many chat
+// templates reject system messages after the first.
+func Nudge(messages []types.OpenAIMessage, detection *LoopDetection) []types.OpenAIMessage {
+	nudge := nudgeText(detection)
+	if len(messages) > 0 && (messages[0].Role == "system" || messages[0].Role == "developer") {
+		result := append([]types.OpenAIMessage(nil), messages...)
+		result[0].Content += "\n\n" + nudge
+		return result
+	}
+	return append([]types.OpenAIMessage{{Role: "system", Content: nudge}}, messages...)
+}
+
+// ReplaceToolResult replaces the results of the looping tool in the trailing
+// tool messages of an upstream request with a synthetic result telling the
+// model to change approach. Reports false when the request does not end with
+// a result of that tool.
+func ReplaceToolResult(messages []types.OpenAIMessage, detection *LoopDetection) ([]types.OpenAIMessage, bool) {
+	// Tool messages answer the calls of the assistant message before them
+	start := len(messages)
+	for start > 0 && messages[start-1].Role == "tool" {
+		start--
+	}
+	if start == 0 || start == len(messages) || messages[start-1].Role != "assistant" {
+		return messages, false
+	}
+	looping := make(map[string]bool)
+	for _, call := range messages[start-1].ToolCalls {
+		if call.Function.Name == detection.ToolName {
+			looping[call.ID] = true
+		}
+	}
+
+	result := append([]types.OpenAIMessage(nil), messages...)
+	replaced := false
+	for i := start; i < len(result); i++ {
+		if looping[result[i].ToolCallID] {
+			result[i].Content = nudgeText(detection)
+			replaced = true
+		}
+	}
+	if !replaced {
+		return messages, false
+	}
+	return result, true
+}
@@ -0,0 +1,25 @@
+package loop
+
+import (
+	"claude-proxy/types"
+	"strings"
+)
+
+// ConsecutiveToolTurns counts the assistant turns at the end of a conversation
+// that consist solely of tool calls. Tool results between them are skipped;
+// any other message, or an assistant turn with text, ends the count.
+func ConsecutiveToolTurns(messages []types.OpenAIMessage) int {
+	count := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		msg := messages[i]
+		switch {
+		case msg.Role == "tool":
+			continue
+		case msg.Role == "assistant" && len(msg.ToolCalls) > 0 && strings.TrimSpace(msg.Content) == "":
+			count++
+		default:
+			return count
+		}
+	}
+	return count
+}
@@ -1,22 +1,32 @@
 package main
 
 import (
+	"claude-proxy/batch"
 	"claude-proxy/config"
+	"claude-proxy/controlplane"
+	"claude-proxy/conversation"
+	"claude-proxy/discovery"
+	"claude-proxy/journal"
 	"claude-proxy/logger"
 	"claude-proxy/proxy"
+	"claude-proxy/stats"
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-// Simple logger config implementation
+// Simple logger config implementation backed by the runtime level controller
 type simpleLoggerConfig struct {
-	minLevel    logger.Level
+	levels      *logger.LevelController
 	maskAPIKeys bool
 }
 
@@ -25,7 +35,11 @@ func (s *simpleLoggerConfig) ShouldLogForModel(model string) bool {
 }
 
 func (s *simpleLoggerConfig) GetMinLogLevel() logger.Level {
-	return s.minLevel
+	return s.levels.DefaultLevel()
+}
+
+func (s *simpleLoggerConfig) GetMinLogLevelForComponent(component string) logger.Level {
+	return s.levels.ComponentLevel(component)
 }
 
 func (s *simpleLoggerConfig) ShouldMaskAPIKeys() bool {
@@ -33,12 +47,26 @@ func (s *simpleLoggerConfig) ShouldMaskAPIKeys() bool {
 }
 
 func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// runServe implements "simple-proxy serve [-config-dir DIR]", running the proxy
+// until it is shut down
+func runServe(args []string) int {
+	flags := newFlagSet("serve", "serve [-config-dir DIR]")
+	configDir := configDirFlag(flags)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	startedAt := time.Now()
+
 	// Print version information
 	fmt.Println(GetBuildInfo())
 	fmt.Println()
 
 	// Load configuration with .env support
-	cfg, err := config.LoadConfigWithEnv()
+	cfg, err := loadConfig(*configDir)
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
@@ -49,9 +77,19 @@ func main() {
 		lokiURL = "http://localhost:3100"
 	}
 	
+	// Apply initial log levels from .env (changeable at runtime via /admin/log-level)
+	minLevel, err := logger.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		log.Fatalf("Invalid LOG_LEVEL: %v", err)
+	}
+	logger.Levels().SetDefaultLevel(minLevel)
+	if err := logger.Levels().ApplySpec(cfg.ComponentLogLevels); err != nil {
+		log.Fatalf("Invalid LOG_LEVELS: %v", err)
+	}
+
 	// Create a simple config adapter
 	loggerCfg := &simpleLoggerConfig{
-		minLevel:    logger.INFO,
+		levels:      logger.Levels(),
 		maskAPIKeys: true,
 	}
 	
@@ -63,6 +101,8 @@ func main() {
 	// Wrap for config interface compatibility
 	obsLogger := &logger.LokiObservabilityLogger{LokiLogger: lokiLogger.(*logger.LokiLogger)}
 	cfg.SetObservabilityLogger(obsLogger)
+	obsLogger.LokiLogger.SetRedactor(logger.NewConversationRedactor(cfg))
+	obsLogger.LokiLogger.SetTruncator(logger.NewConversationTruncator(cfg))
 	fmt.Printf("✅ Direct Loki logging enabled at %s\n", lokiURL)
 
 	if obsLogger != nil {
@@ -117,37 +157,291 @@ func main() {
 		}()
 	}
 
+	// Expand dns+/srv+/k8s+ endpoint specs and keep them resolved
+	if len(cfg.EndpointSpecs) > 0 {
+		resolver := discovery.NewResolver(cfg, nil)
+		if cfg.UsesKubernetesDiscovery() {
+			kube, err := discovery.NewKubernetesClient(cfg.KubernetesAPIURL)
+			if err != nil {
+				log.Fatalf("Failed to set up Kubernetes discovery: %v", err)
+			}
+			resolver.SetKubernetesClient(kube)
+		}
+		resolveCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		_, err := resolver.Refresh(resolveCtx)
+		cancel()
+		if err != nil {
+			log.Fatalf("Failed to resolve endpoints: %v", err)
+		}
+		interval := time.Duration(cfg.EndpointResolveIntervalSeconds) * time.Second
+		stopResolver := resolver.Start(interval, func(roles []string) {
+			for _, role := range roles {
+				obsLogger.Info(logger.ComponentProxy, logger.CategoryRequest, "", "Resolved endpoints changed", map[string]interface{}{
+					"role": role,
+					"endpoints": cfg.Endpoints(role),
+				})
+			}
+		}, func(err error) {
+			obsLogger.Warn(logger.ComponentProxy, logger.CategoryWarning, "", "Failed to re-resolve endpoints, keeping previous addresses", map[string]interface{}{"error": err.Error()})
+		})
+		defer stopResolver()
+		obsLogger.Info(logger.ComponentProxy, logger.CategoryRequest, "", "Endpoint re-resolution enabled", map[string]interface{}{
+			"interval_seconds": cfg.EndpointResolveIntervalSeconds,
+			"roles": len(cfg.EndpointSpecs),
+		})
+	}
+
 	// Create proxy handler  
 	proxyHandler := proxy.NewHandler(cfg, obsLogger, conversationSessionID)
 
+	// Persist cumulative usage, cost and correction stats across restarts unless disabled
+	stopStatsPersistence := func() error { return nil }
+	if cfg.StatsPersistenceEnabled {
+		statsStore, err := stats.OpenStore(cfg.StatsDBPath)
+		if err != nil {
+			log.Fatalf("Failed to open stats store: %v", err)
+		}
+		stopStatsPersistence, err = proxyHandler.Stats().StartPersistence(statsStore, 30*time.Second, func(err error) {
+			obsLogger.Warn(logger.ComponentProxy, logger.CategoryWarning, "", "Failed to persist stats", map[string]interface{}{"error": err.Error()})
+		})
+		if err != nil {
+			log.Fatalf("Failed to load persisted stats: %v", err)
+		}
+		obsLogger.Info(logger.ComponentProxy, logger.CategoryRequest, "", "Stats persistence enabled", map[string]interface{}{
+			"path": cfg.StatsDBPath,
+		})
+	}
+
+	// Remember failing endpoints across restarts so a known-dead backend is not retried at once
+	stopCircuitPersistence := func() error { return nil }
+	if cfg.CircuitStateTTLSeconds > 0 {
+		ttl := time.Duration(cfg.CircuitStateTTLSeconds) * time.Second
+		var restored int
+		stopCircuitPersistence, restored, err = cfg.HealthManager.StartPersistence(cfg.CircuitStatePath, ttl, 10*time.Second, func(err error) {
+			obsLogger.Warn(logger.ComponentProxy, logger.CategoryWarning, "", "Failed to persist circuit breaker state", map[string]interface{}{"error": err.Error()})
+		})
+		if err != nil {
+			log.Fatalf("Failed to load circuit breaker state: %v", err)
+		}
+		obsLogger.Info(logger.ComponentProxy, logger.CategoryRequest, "", "Circuit breaker persistence enabled", map[string]interface{}{
+			"path": cfg.CircuitStatePath,
+			"ttl_seconds": cfg.CircuitStateTTLSeconds,
+			"restored_endpoints": restored,
+		})
+	}
+
+	// Mirror request, correction and circuit metrics to StatsD/Datadog when configured
+	if cfg.MetricsStatsDAddr != "" {
+		emitter, err := stats.NewStatsDEmitter(cfg.MetricsStatsDAddr, cfg.MetricsStatsDPrefix)
+		if err != nil {
+			log.Fatalf("Failed to create StatsD emitter: %v", err)
+		}
+		defer emitter.Close()
+		proxyHandler.Stats().SetEmitter(emitter)
+		cfg.HealthManager.AddStateChangeListener(emitter.CircuitStateChanged)
+		obsLogger.Info(logger.ComponentProxy, logger.CategoryRequest, "", "StatsD metrics enabled", map[string]interface{}{
+			"address": cfg.MetricsStatsDAddr,
+			"prefix": cfg.MetricsStatsDPrefix,
+		})
+	}
+
+	// Load models on every endpoint before the first real request needs them, and
+	// again whenever an endpoint's circuit recovers
+	if cfg.WarmupEnabled {
+		cfg.HealthManager.AddStateChangeListener(proxyHandler.WarmUpOnRecovery())
+		go proxyHandler.WarmUp(context.Background())
+		obsLogger.Info(logger.ComponentProxy, logger.CategoryRequest, "", "Endpoint warm-up enabled", nil)
+	}
+
+	// Message Batches API: entries are fanned out to the regular /v1/messages pipeline
+	var batchStore *batch.Store
+	if cfg.BatchesEnabled {
+		batchStore, err = batch.OpenStore(cfg.BatchDBPath)
+		if err != nil {
+			log.Fatalf("Failed to open batch store: %v", err)
+		}
+		defer batchStore.Close()
+		// Entries run on background goroutines, out of reach of the server's panic recovery
+		batchPipeline := proxy.WithPanicRecovery(obsLogger, proxyHandler.Stats(), http.HandlerFunc(proxyHandler.HandleAnthropicRequest))
+		batches := proxy.NewBatchProcessor(batchStore, batchPipeline, cfg.BatchConcurrency)
+		if err := batches.Resume(); err != nil {
+			log.Fatalf("Failed to resume message batches: %v", err)
+		}
+		http.HandleFunc("/v1/messages/batches", batches.HandleBatches)
+		http.HandleFunc("/v1/messages/batches/", batches.HandleBatch)
+	}
+
+	// Indexed local copy of logged conversations for GET /admin/conversations
+	var conversationStore *conversation.Store
+	if cfg.ConversationSearchEnabled {
+		if conversationSessionID == "" {
+			obsLogger.Warn(logger.ComponentProxy, logger.CategoryWarning, "", "Conversation search needs CONVERSATION_LOGGING_ENABLED=true, nothing will be stored", nil)
+		}
+		conversationStore, err = conversation.OpenStore(cfg.ConversationDBPath)
+		if err != nil {
+			log.Fatalf("Failed to open conversation store: %v", err)
+		}
+		defer conversationStore.Close()
+		proxyHandler.SetConversationStore(conversationStore)
+		handleAdmin(cfg, obsLogger, "/admin/conversations", proxyHandler.HandleConversations)
+		handleAdmin(cfg, obsLogger, "/admin/conversations/export", proxyHandler.HandleConversationExport)
+	}
+
+	// Crash-safe journal of accepted requests; those an earlier process left unanswered are listed at /admin/journal
+	var requestJournal *journal.Journal
+	if cfg.RequestJournalEnabled {
+		requestJournal, err = journal.Open(cfg.RequestJournalPath)
+		if err != nil {
+			log.Fatalf("Failed to open request journal: %v", err)
+		}
+		defer requestJournal.Close()
+		proxyHandler.SetJournal(requestJournal)
+		if interrupted := requestJournal.Interrupted(); len(interrupted) > 0 {
+			requestIDs := make([]string, 0, len(interrupted))
+			for _, entry := range interrupted {
+				requestIDs = append(requestIDs, entry.RequestID)
+			}
+			obsLogger.Warn(logger.ComponentProxy, logger.CategoryWarning, "", "Requests left unanswered by the previous run, see /admin/journal", map[string]interface{}{
+				"count": len(interrupted),
+				"request_ids": requestIDs,
+			})
+		}
+		// Lists raw request bodies and replays them
+		handleAdmin(cfg, obsLogger, "/admin/journal", proxyHandler.HandleJournal)
+		handleAdmin(cfg, obsLogger, "/admin/journal/", proxyHandler.HandleJournal)
+	}
+
+	// Delete stored data past its retention; POST /admin/purge deletes on demand
+	retention := proxy.NewRetentionManager(cfg, conversationStore, batchStore, requestJournal, proxyHandler.Stats())
+	stopRetention := retention.Start(time.Duration(cfg.RetentionIntervalMinutes)*time.Minute, func(result proxy.PurgeResult) {
+		obsLogger.Info(logger.ComponentProxy, logger.CategoryRequest, "", "Retention purged stored data", map[string]interface{}{
+			"conversations": result.Conversations,
+			"batches": result.Batches,
+			"journal": result.Journal,
+			"stats_reset": result.StatsReset,
+		})
+	}, func(err error) {
+		obsLogger.Warn(logger.ComponentProxy, logger.CategoryWarning, "", "Failed to enforce retention", map[string]interface{}{"error": err.Error()})
+	})
+	defer stopRetention()
+
 	// Setup HTTP routes
 	http.HandleFunc("/", handleRoot)
 	http.HandleFunc("/health", handleHealth)
-	http.HandleFunc("/v1/messages", proxyHandler.HandleAnthropicRequest)
+	http.HandleFunc("/version", handleVersion(cfg, startedAt))
+	http.HandleFunc("/v1/messages", proxy.WithCORS(cfg.CORS, proxyHandler.HandleAnthropicRequest))
 	http.Handle("/metrics", promhttp.Handler())
+	handleAdmin(cfg, obsLogger, "/stats", proxyHandler.HandleStats) // Client spend, recent errors and upstream error bodies
+	handleAdmin(cfg, obsLogger, "/admin/dashboard", proxy.HandleDashboard) // Browsers log in with the token as Basic auth password
+	handleAdmin(cfg, obsLogger, "/admin/log-level", logger.Levels().HandleLogLevel)
+	handleAdmin(cfg, obsLogger, "/admin/config", handleAdminConfig(cfg))
+	handleAdmin(cfg, obsLogger, "/admin/approvals", proxyHandler.HandleApprovals)
+	handleAdmin(cfg, obsLogger, "/admin/purge", retention.HandlePurge)
+	handleAdmin(cfg, obsLogger, "/admin/requests", proxyHandler.HandleRequestHistory)
+	handleAdmin(cfg, obsLogger, "/admin/requests/", proxyHandler.HandleRequestHistory)
 
-	// Setup HTTP server with reasonable timeouts
+	// Access log for every route, separate from conversation logging
+	accessLogger, err := proxy.NewAccessLogger(cfg, obsLogger.LokiLogger)
+	if err != nil {
+		log.Fatalf("Failed to create access log: %v", err)
+	}
+	if accessLogger != nil {
+		defer accessLogger.Close()
+	}
+
+	// Setup HTTP server with configurable timeouts (SERVER_*_TIMEOUT, 0 disables)
 	server := &http.Server{
-		Addr:         ":" + cfg.Port,
-		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 300 * time.Second, // Long timeout for streaming responses
-		IdleTimeout:  60 * time.Second,
+		Handler:      proxy.WithAccessLog(accessLogger, cfg.AccessLogFields, proxy.WithPanicRecovery(obsLogger, proxyHandler.Stats(), http.DefaultServeMux)),
+		ReadTimeout:  time.Duration(cfg.ServerReadTimeout) * time.Second,
+		WriteTimeout: time.Duration(cfg.ServerWriteTimeout) * time.Second, // Bounds the longest streaming response
+		IdleTimeout:  time.Duration(cfg.ServerIdleTimeout) * time.Second,
+	}
+
+	// Open every LISTEN socket (TCP on PORT by default) before serving any of them
+	var listeners []net.Listener
+	var listenAddresses []string
+	for _, address := range cfg.GetListenAddresses() {
+		listener, err := address.Listen()
+		if err != nil {
+			if obsLogger != nil {
+				obsLogger.Error(logger.ComponentProxy, logger.CategoryError, "", "Server failed to start", map[string]interface{}{"error": err.Error(), "listen": address.String()})
+			}
+			log.Fatalf("Server failed to listen on %s: %v", address, err)
+		}
+		listeners = append(listeners, listener)
+		listenAddresses = append(listenAddresses, address.String())
 	}
 
 	if obsLogger != nil {
 		obsLogger.Info(logger.ComponentProxy, logger.CategoryRequest, "", "Claude Code Proxy started", map[string]interface{}{
-			"address": fmt.Sprintf("http://localhost:%s", cfg.Port),
-			"endpoint": fmt.Sprintf("http://localhost:%s/v1/messages", cfg.Port),
+			"listen": listenAddresses,
+			"endpoint": "/v1/messages",
+		})
+	}
+
+	// Optional gRPC control plane for programmatic fleet management
+	if cfg.AdminGRPCAddr != "" {
+		listener, err := net.Listen("tcp", cfg.AdminGRPCAddr)
+		if err != nil {
+			log.Fatalf("Failed to listen on ADMIN_GRPC_ADDR: %v", err)
+		}
+		controlPlane := controlplane.NewServer(cfg, proxyHandler, controlplane.EnvReloader(cfg, logger.Levels(), proxyHandler))
+		grpcServer := controlplane.NewGRPCServer(controlPlane, cfg.AdminGRPCToken)
+		go func() {
+			if err := grpcServer.Serve(listener); err != nil {
+				obsLogger.Error(logger.ComponentProxy, logger.CategoryError, "", "gRPC control plane stopped", map[string]interface{}{"error": err.Error()})
+			}
+		}()
+		defer grpcServer.GracefulStop()
+		obsLogger.Info(logger.ComponentProxy, logger.CategoryRequest, "", "gRPC control plane enabled", map[string]interface{}{
+			"address": cfg.AdminGRPCAddr,
+			"authenticated": cfg.AdminGRPCToken != "",
 		})
 	}
 
-	// Start server
-	if err := server.ListenAndServe(); err != nil {
-		if obsLogger != nil {
-			obsLogger.Error(logger.ComponentProxy, logger.CategoryError, "", "Server failed to start", map[string]interface{}{"error": err.Error()})
+	// Shut down gracefully on SIGINT/SIGTERM so persisted stats get a final flush
+	shutdownSignals := make(chan os.Signal, 1)
+	signal.Notify(shutdownSignals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-shutdownSignals
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	// Dump goroutine stacks, in-flight requests and queue depths on SIGUSR1 (not on Windows) to
+	// diagnose hangs without attaching a debugger
+	diagnosticSignals := make(chan os.Signal, 1)
+	notifyDiagnosticSignals(diagnosticSignals)
+	go func() {
+		for range diagnosticSignals {
+			proxyHandler.DumpDiagnostics()
 		}
-		log.Fatalf("Server failed to start: %v", err)
+	}()
+
+	// Start server; Shutdown closes every listener
+	serveErrors := make(chan error, len(listeners))
+	for _, listener := range listeners {
+		go func(listener net.Listener) {
+			serveErrors <- server.Serve(listener)
+		}(listener)
+	}
+	for range listeners {
+		if err := <-serveErrors; err != nil && err != http.ErrServerClosed {
+			if obsLogger != nil {
+				obsLogger.Error(logger.ComponentProxy, logger.CategoryError, "", "Server failed to start", map[string]interface{}{"error": err.Error()})
+			}
+			log.Fatalf("Server failed to start: %v", err)
+		}
+	}
+
+	if err := stopStatsPersistence(); err != nil {
+		log.Printf("Failed to flush stats on shutdown: %v", err)
+	}
+	if err := stopCircuitPersistence(); err != nil {
+		log.Printf("Failed to save circuit breaker state on shutdown: %v", err)
 	}
+	return 0
 }
 
 // handleRoot provides basic information about the proxy
@@ -159,7 +453,20 @@ func handleRoot(w http.ResponseWriter, r *http.Request) {
 	"status": "running",
 	"endpoints": [
 		"GET /health - Health check",
-		"POST /v1/messages - Anthropic-compatible chat completions"
+		"GET /version - Build version, commit, config hash and start time",
+		"POST /v1/messages - Anthropic-compatible chat completions",
+		"POST|GET /v1/messages/batches - Create or list message batches",
+		"GET /v1/messages/batches/{id}[/results] - Batch status or JSONL results",
+		"GET /stats - Aggregate request, correction, Harmony and circuit statistics",
+		"GET /admin/dashboard - Web dashboard of throughput, endpoint health, corrections and errors",
+		"GET|PUT /admin/log-level - View or change runtime log levels",
+		"GET /admin/config - Effective configuration with API keys masked",
+		"GET /admin/conversations?q=... - Search logged conversations (CONVERSATION_SEARCH_ENABLED)",
+		"GET /admin/conversations/export?session=...&format=anthropic|openai - Export a logged session",
+		"GET|POST /admin/approvals - List or decide tool calls held by tool policies",
+		"GET /admin/requests[/{id}] - Recent request summaries and their transformation audit trail",
+		"GET|POST|DELETE /admin/journal[/{id}[/replay]] - Requests interrupted by a crash or restart (REQUEST_JOURNAL_ENABLED)",
+		"POST /admin/purge?target=conversations|batches|journal|stats - Delete stored data"
 	]
 }`)
 }
@@ -172,3 +479,34 @@ func handleHealth(w http.ResponseWriter, r *http.Request) {
 	"timestamp": "%s"
 }`, time.Now().UTC().Format(time.RFC3339))
 }
+
+// handleAdmin registers an admin route that exposes data or changes state
+// behind ADMIN_TOKEN. The server listens on every interface, so without
+// ADMIN_TOKEN the route is not served at all.
+func handleAdmin(cfg *config.Config, obsLogger *logger.LokiObservabilityLogger, pattern string, handler http.HandlerFunc) {
+	if cfg.AdminToken == "" {
+		obsLogger.Info(logger.ComponentProxy, logger.CategoryRequest, "", "Admin route not served, set ADMIN_TOKEN to enable it", map[string]interface{}{
+			"route": pattern,
+		})
+		return
+	}
+	http.HandleFunc(pattern, proxy.WithAdminToken(cfg.AdminToken, handler))
+}
+
+// handleAdminConfig returns the effective runtime configuration with secrets masked
+func handleAdminConfig(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(cfg.Sanitized()); err != nil {
+			http.Error(w, "Failed to encode configuration", http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"claude-proxy/correction"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// runMineCorrections implements "simple-proxy mine-corrections [-min N]
+// [-rules FILE] [LOG_FILE...]": it reads exported correction logs (stdin when
+// no file is given) and prints the most frequent invalid tool call patterns
+// with suggested tool_validators.yaml rules. Returns the process exit code.
+func runMineCorrections(args []string) int {
+	flags := flag.NewFlagSet("mine-corrections", flag.ContinueOnError)
+	minCount := flags.Int("min", 3, "Occurrences a pattern needs before it is suggested as a rule")
+	rulesPath := flags.String("rules", "", "Also write the suggested rules to this YAML file")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: simple-proxy mine-corrections [-min N] [-rules FILE] [LOG_FILE...]")
+		fmt.Fprintln(flags.Output(), "Reads correction logs exported from Loki, e.g. logcli query --output=jsonl '{job=\"simple-proxy\"} |= \"correction\"'")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	var input io.Reader = os.Stdin
+	if flags.NArg() > 0 {
+		var readers []io.Reader
+		for _, path := range flags.Args() {
+			file, err := os.Open(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to open correction log: %v\n", err)
+				return 1
+			}
+			defer file.Close()
+			readers = append(readers, file)
+		}
+		input = io.MultiReader(readers...)
+	}
+
+	report, err := correction.MineCorrections(input)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := report.WriteText(os.Stdout, *minCount); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write report: %v\n", err)
+		return 1
+	}
+
+	if *rulesPath != "" {
+		rules, err := report.SuggestedRules(*minCount)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to build suggested rules: %v\n", err)
+			return 1
+		}
+		if err := os.WriteFile(*rulesPath, rules, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write suggested rules: %v\n", err)
+			return 1
+		}
+	}
+	return 0
+}
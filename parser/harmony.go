@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"sync"
 )
 
 // Role represents the different roles that can appear in Harmony messages.
@@ -533,6 +534,61 @@ type TokenRecognizer struct {
 	messagePattern   *regexp.Regexp
 	fullPattern      *regexp.Regexp
 	partialPattern   *regexp.Regexp
+	defaultTokens    bool // Tokens match DefaultTokenSet, so the fast detection path applies
+}
+
+// TokenSet names the literal tokens a TokenRecognizer looks for. Backends
+// that emit a different Harmony dialect (renamed or extra end tokens) get a
+// recognizer built from their own set instead of the gpt-oss defaults.
+type TokenSet struct {
+	Start   string   `json:"start"`   // Opens a message, followed by the role
+	End     []string `json:"end"`     // Close a message; any of them ends it
+	Channel string   `json:"channel"` // Precedes the channel name
+	Message string   `json:"message"` // Separates the header from the message content
+}
+
+// DefaultTokenSet returns the tokens gpt-oss emits
+func DefaultTokenSet() TokenSet {
+	return TokenSet{
+		Start:   "<|start|>",
+		End:     []string{"<|end|>", "<|return|>"},
+		Channel: "<|channel|>",
+		Message: "<|message|>",
+	}
+}
+
+// isDefault reports whether the set is exactly DefaultTokenSet
+func (s TokenSet) isDefault() bool {
+	d := DefaultTokenSet()
+	if s.Start != d.Start || s.Channel != d.Channel || s.Message != d.Message || len(s.End) != len(d.End) {
+		return false
+	}
+	for i := range s.End {
+		if s.End[i] != d.End[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate reports the first empty token in the set
+func (s TokenSet) Validate() error {
+	switch {
+	case s.Start == "":
+		return fmt.Errorf("start token is empty")
+	case s.Channel == "":
+		return fmt.Errorf("channel token is empty")
+	case s.Message == "":
+		return fmt.Errorf("message token is empty")
+	case len(s.End) == 0:
+		return fmt.Errorf("no end tokens")
+	}
+	for _, end := range s.End {
+		if end == "" {
+			return fmt.Errorf("end token is empty")
+		}
+	}
+	return nil
 }
 
 // NewTokenRecognizer creates a new TokenRecognizer with all necessary
@@ -563,34 +619,54 @@ type TokenRecognizer struct {
 //	}
 //	// Use recognizer for multiple parsing operations
 func NewTokenRecognizer() (*TokenRecognizer, error) {
-	startPattern, err := regexp.Compile(`<\|start\|>(\w+)`)
+	return NewTokenRecognizerWithTokens(DefaultTokenSet())
+}
+
+// NewTokenRecognizerWithTokens creates a TokenRecognizer for a custom token
+// set. Tokens are matched literally; roles and channel names must still be
+// word characters.
+func NewTokenRecognizerWithTokens(tokens TokenSet) (*TokenRecognizer, error) {
+	if err := tokens.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid harmony token set: %w", err)
+	}
+
+	start := regexp.QuoteMeta(tokens.Start)
+	channel := regexp.QuoteMeta(tokens.Channel)
+	message := regexp.QuoteMeta(tokens.Message)
+	ends := make([]string, len(tokens.End))
+	for i, end := range tokens.End {
+		ends[i] = regexp.QuoteMeta(end)
+	}
+	end := `(?:` + strings.Join(ends, "|") + `)`
+
+	startPattern, err := regexp.Compile(start + `(\w+)`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to compile start pattern: %w", err)
 	}
 
-	endPattern, err := regexp.Compile(`<\|(?:end|return)\|>`)
+	endPattern, err := regexp.Compile(end)
 	if err != nil {
 		return nil, fmt.Errorf("failed to compile end pattern: %w", err)
 	}
 
-	channelPattern, err := regexp.Compile(`<\|channel\|>(\w+)`)
+	channelPattern, err := regexp.Compile(channel + `(\w+)`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to compile channel pattern: %w", err)
 	}
 
-	messagePattern, err := regexp.Compile(`<\|message\|>`)
+	messagePattern, err := regexp.Compile(message)
 	if err != nil {
 		return nil, fmt.Errorf("failed to compile message pattern: %w", err)
 	}
 
 	// Full pattern for complete token sequences with start token
-	fullPattern, err := regexp.Compile(`(?s)<\|start\|>(\w+)(?:<\|channel\|>(\w+))?<\|message\|>(.*?)<\|(?:end|return)\|>`)
+	fullPattern, err := regexp.Compile(`(?s)` + start + `(\w+)(?:` + channel + `(\w+))?` + message + `(.*?)` + end)
 	if err != nil {
 		return nil, fmt.Errorf("failed to compile full pattern: %w", err)
 	}
 
 	// Partial pattern for sequences without start token (fallback)
-	partialPattern, err := regexp.Compile(`(?s)<\|channel\|>(\w+)<\|message\|>(.*?)<\|(?:end|return)\|>`)
+	partialPattern, err := regexp.Compile(`(?s)` + channel + `(\w+)` + message + `(.*?)` + end)
 	if err != nil {
 		return nil, fmt.Errorf("failed to compile partial pattern: %w", err)
 	}
@@ -602,6 +678,7 @@ func NewTokenRecognizer() (*TokenRecognizer, error) {
 		messagePattern: messagePattern,
 		fullPattern:    fullPattern,
 		partialPattern: partialPattern,
+		defaultTokens:  tokens.isDefault(),
 	}, nil
 }
 
@@ -622,7 +699,11 @@ func NewTokenRecognizer() (*TokenRecognizer, error) {
 //   - true if any Harmony tokens are found
 //   - false if no Harmony tokens are present
 //
-// Performance: O(n) where n is content length, but optimized for early exit.
+// Performance: O(n) where n is content length, without allocations. Content
+// is scanned for "<|" and only the bytes after each occurrence are checked, so
+// plain text without that marker costs a single strings.Index. The regex
+// patterns are reserved for full extraction, except for custom token sets,
+// which are detected with them.
 //
 // Example:
 //
@@ -632,10 +713,51 @@ func NewTokenRecognizer() (*TokenRecognizer, error) {
 //		// Handle as regular content
 //	}
 func (tr *TokenRecognizer) HasHarmonyTokens(content string) bool {
-	return tr.startPattern.MatchString(content) || 
-		   tr.endPattern.MatchString(content) ||
-		   tr.channelPattern.MatchString(content) || 
-		   tr.messagePattern.MatchString(content)
+	if tr.defaultTokens {
+		return hasHarmonyTokens(content)
+	}
+	return tr.startPattern.MatchString(content) ||
+		tr.endPattern.MatchString(content) ||
+		tr.channelPattern.MatchString(content) ||
+		tr.messagePattern.MatchString(content)
+}
+
+// hasHarmonyTokens reports whether content contains any of the tokens matched
+// by the start, end, channel and message patterns:
+//
+//	<|start|>\w+  <|end|>  <|return|>  <|channel|>\w+  <|message|>
+func hasHarmonyTokens(content string) bool {
+	for {
+		i := strings.Index(content, "<|")
+		if i < 0 {
+			return false
+		}
+		content = content[i+2:]
+
+		switch {
+		case strings.HasPrefix(content, "end|>"),
+			strings.HasPrefix(content, "return|>"),
+			strings.HasPrefix(content, "message|>"):
+			return true
+		case strings.HasPrefix(content, "start|>"):
+			if startsWithWordChar(content[len("start|>"):]) {
+				return true
+			}
+		case strings.HasPrefix(content, "channel|>"):
+			if startsWithWordChar(content[len("channel|>"):]) {
+				return true
+			}
+		}
+	}
+}
+
+// startsWithWordChar reports whether s begins with a regexp \w character
+func startsWithWordChar(s string) bool {
+	if s == "" {
+		return false
+	}
+	c := s[0]
+	return c == '_' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
 }
 
 // ExtractTokens extracts all complete Harmony token sequences from content,
@@ -704,15 +826,22 @@ func (tr *TokenRecognizer) ExtractTokens(content string) [][]string {
 	return allMatches
 }
 
-// Package-level default token recognizer for performance
-var defaultTokenRecognizer *TokenRecognizer
+// Package-level default token recognizer, built on first use. A failure is
+// kept and returned by DefaultTokenRecognizer rather than panicking at
+// package load.
+var (
+	defaultTokenRecognizerOnce sync.Once
+	defaultTokenRecognizer     *TokenRecognizer
+	defaultTokenRecognizerErr  error
+)
 
-func init() {
-	var err error
-	defaultTokenRecognizer, err = NewTokenRecognizer()
-	if err != nil {
-		panic(fmt.Sprintf("failed to initialize default token recognizer: %v", err))
-	}
+// DefaultTokenRecognizer returns the shared recognizer for DefaultTokenSet
+// used by the package-level functions
+func DefaultTokenRecognizer() (*TokenRecognizer, error) {
+	defaultTokenRecognizerOnce.Do(func() {
+		defaultTokenRecognizer, defaultTokenRecognizerErr = NewTokenRecognizer()
+	})
+	return defaultTokenRecognizer, defaultTokenRecognizerErr
 }
 
 // IsHarmonyFormat provides a package-level convenience function for detecting
@@ -720,7 +849,7 @@ func init() {
 //
 // This function offers a simple API for Harmony format detection without
 // requiring explicit TokenRecognizer instantiation, using a shared recognizer
-// instance initialized at package level for optimal performance.
+// instance built on first use. It reports false if that build failed.
 //
 // The function is thread-safe and suitable for concurrent use across
 // multiple goroutines, as the underlying TokenRecognizer uses read-only
@@ -744,7 +873,11 @@ func init() {
 //		// Handle as regular text response
 //	}
 func IsHarmonyFormat(content string) bool {
-	return defaultTokenRecognizer.HasHarmonyTokens(content)
+	tr, err := DefaultTokenRecognizer()
+	if err != nil {
+		return false
+	}
+	return tr.HasHarmonyTokens(content)
 }
 
 // ExtractChannels extracts and parses all valid Harmony channels from content,
@@ -778,9 +911,18 @@ func IsHarmonyFormat(content string) bool {
 //			channel.Role, channel.ChannelType, channel.Content)
 //	}
 func ExtractChannels(content string) []Channel {
+	tr, err := DefaultTokenRecognizer()
+	if err != nil {
+		return nil
+	}
+	return tr.ExtractChannels(content)
+}
+
+// ExtractChannels is ExtractChannels using this recognizer's token set
+func (tr *TokenRecognizer) ExtractChannels(content string) []Channel {
 	var channels []Channel
 	
-	tokens := defaultTokenRecognizer.ExtractTokens(content)
+	tokens := tr.ExtractTokens(content)
 	
 	for _, match := range tokens {
 		if len(match) < 4 {
@@ -888,6 +1030,15 @@ func DetermineContentType(channelType ChannelType) ContentType {
 //		fmt.Printf("Response: %s\n", message.ResponseText)
 //	}
 func ParseHarmonyMessage(content string) (*HarmonyMessage, error) {
+	tr, err := DefaultTokenRecognizer()
+	if err != nil {
+		return nil, err
+	}
+	return tr.ParseHarmonyMessage(content)
+}
+
+// ParseHarmonyMessage is ParseHarmonyMessage using this recognizer's token set
+func (tr *TokenRecognizer) ParseHarmonyMessage(content string) (*HarmonyMessage, error) {
 	if content == "" {
 		return &HarmonyMessage{
 			Channels:     []Channel{},
@@ -900,12 +1051,12 @@ func ParseHarmonyMessage(content string) (*HarmonyMessage, error) {
 		}, nil
 	}
 
-	channels := ExtractChannels(content)
+	channels := tr.ExtractChannels(content)
 	
 	message := &HarmonyMessage{
 		Channels:     channels,
 		RawContent:   content,
-		HasHarmony:   IsHarmonyFormat(content),
+		HasHarmony:   tr.HasHarmonyTokens(content),
 		ParseErrors:  []error{},
 		ThinkingText: "",
 		ResponseText: "",
@@ -973,10 +1124,19 @@ func ParseHarmonyMessage(content string) (*HarmonyMessage, error) {
 //			pos.Type, pos.Start, pos.End, pos.Value)
 //	}
 func FindHarmonyTokens(content string) []TokenPosition {
+	tr, err := DefaultTokenRecognizer()
+	if err != nil {
+		return nil
+	}
+	return tr.FindHarmonyTokens(content)
+}
+
+// FindHarmonyTokens is FindHarmonyTokens using this recognizer's token set
+func (tr *TokenRecognizer) FindHarmonyTokens(content string) []TokenPosition {
 	var positions []TokenPosition
 	
 	// Find start tokens
-	startMatches := defaultTokenRecognizer.startPattern.FindAllStringSubmatchIndex(content, -1)
+	startMatches := tr.startPattern.FindAllStringSubmatchIndex(content, -1)
 	for _, match := range startMatches {
 		positions = append(positions, TokenPosition{
 			Type:     "start",
@@ -988,7 +1148,7 @@ func FindHarmonyTokens(content string) []TokenPosition {
 	}
 	
 	// Find channel tokens
-	channelMatches := defaultTokenRecognizer.channelPattern.FindAllStringSubmatchIndex(content, -1)
+	channelMatches := tr.channelPattern.FindAllStringSubmatchIndex(content, -1)
 	for _, match := range channelMatches {
 		positions = append(positions, TokenPosition{
 			Type:     "channel",
@@ -1000,7 +1160,7 @@ func FindHarmonyTokens(content string) []TokenPosition {
 	}
 	
 	// Find message tokens
-	messageMatches := defaultTokenRecognizer.messagePattern.FindAllStringIndex(content, -1)
+	messageMatches := tr.messagePattern.FindAllStringIndex(content, -1)
 	for _, match := range messageMatches {
 		positions = append(positions, TokenPosition{
 			Type:     "message",
@@ -1011,7 +1171,7 @@ func FindHarmonyTokens(content string) []TokenPosition {
 	}
 	
 	// Find end tokens
-	endMatches := defaultTokenRecognizer.endPattern.FindAllStringIndex(content, -1)
+	endMatches := tr.endPattern.FindAllStringIndex(content, -1)
 	for _, match := range endMatches {
 		positions = append(positions, TokenPosition{
 			Type:     "end",
@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -505,6 +507,155 @@ func BenchmarkParseHarmonyMessage(b *testing.B) {
 	}
 }
 
+// multiChannelResponse builds a response of the given number of analysis,
+// commentary and final message groups, as long agentic gpt-oss turns produce
+func multiChannelResponse(groups int) string {
+	var b strings.Builder
+	for i := 0; i < groups; i++ {
+		b.WriteString("<|start|>assistant<|channel|>analysis<|message|>")
+		b.WriteString(strings.Repeat("Weighing the next step against the test output. ", 20))
+		b.WriteString("<|end|>\n<|start|>assistant<|channel|>commentary<|message|>")
+		b.WriteString(`{"file_path": "/src/config/loader.go", "old_string": "a", "new_string": "b"}`)
+		b.WriteString("<|end|>\n<|start|>assistant<|channel|>final<|message|>")
+		b.WriteString(strings.Repeat("Updated the loader and reran the tests. ", 10))
+		b.WriteString("<|end|>\n")
+	}
+	return b.String()
+}
+
+// Benchmark parsing large responses with many channels
+func BenchmarkParseHarmonyMultiChannel(b *testing.B) {
+	for _, groups := range []int{10, 100, 500} {
+		input := multiChannelResponse(groups)
+		b.Run(fmt.Sprintf("%dChannels/%dKB", groups*3, len(input)>>10), func(b *testing.B) {
+			message, err := ParseHarmonyMessage(input)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if len(message.Channels) != groups*3 {
+				b.Fatalf("parsed %d channels, want %d", len(message.Channels), groups*3)
+			}
+
+			b.SetBytes(int64(len(input)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := ParseHarmonyMessage(input); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// Test that the byte scanner agrees with the token regexes it replaces
+func TestHasHarmonyTokensMatchesPatterns(t *testing.T) {
+	inputs := []string{
+		"",
+		"plain text response",
+		"<|start|>assistant",
+		"<|start|>",
+		"<|start|> assistant",
+		"<|start|>_role",
+		"<|end|>",
+		"text <|return|> more",
+		"<|channel|>final",
+		"<|channel|>",
+		"<|channel|>-final",
+		"<|message|>",
+		"<|constrain|>json",
+		"<<|end|>",
+		"<|<|message|>",
+		"<|ends|>",
+		"a <| b |> c",
+		"trailing <|",
+		"generic Map<|K, V|> notation",
+		"ünïcödé <|start|>é",
+		"ünïcödé <|start|>9",
+	}
+
+	tr, err := DefaultTokenRecognizer()
+	if err != nil {
+		t.Fatalf("DefaultTokenRecognizer() failed: %v", err)
+	}
+
+	for _, input := range inputs {
+		want := tr.startPattern.MatchString(input) ||
+			tr.endPattern.MatchString(input) ||
+			tr.channelPattern.MatchString(input) ||
+			tr.messagePattern.MatchString(input)
+		if got := IsHarmonyFormat(input); got != want {
+			t.Errorf("IsHarmonyFormat(%q) = %v, regex patterns say %v", input, got, want)
+		}
+	}
+}
+
+// Test a recognizer built from a custom token dialect
+func TestTokenRecognizerWithTokens(t *testing.T) {
+	tr, err := NewTokenRecognizerWithTokens(TokenSet{
+		Start:   "[[start]]",
+		End:     []string{"[[end]]", "[[stop]]"},
+		Channel: "[[channel]]",
+		Message: "[[message]]",
+	})
+	if err != nil {
+		t.Fatalf("NewTokenRecognizerWithTokens() failed: %v", err)
+	}
+
+	content := "[[start]]assistant[[channel]]analysis[[message]]Thinking[[end]]" +
+		"[[start]]assistant[[channel]]final[[message]]Answer[[stop]]"
+	if !tr.HasHarmonyTokens(content) {
+		t.Error("Expected custom tokens to be detected")
+	}
+	if tr.HasHarmonyTokens("<|start|>assistant<|message|>Hi<|end|>") {
+		t.Error("Expected default tokens to be ignored by a custom recognizer")
+	}
+
+	msg, err := tr.ParseHarmonyMessage(content)
+	if err != nil {
+		t.Fatalf("ParseHarmonyMessage() failed: %v", err)
+	}
+	if msg.ThinkingText != "Thinking" || msg.ResponseText != "Answer" {
+		t.Errorf("Got thinking %q and response %q", msg.ThinkingText, msg.ResponseText)
+	}
+	if got := len(tr.FindHarmonyTokens(content)); got != 8 {
+		t.Errorf("Expected 8 tokens, got %d", got)
+	}
+
+	if _, err := NewTokenRecognizerWithTokens(TokenSet{Start: "<|start|>", Channel: "<|channel|>", Message: "<|message|>"}); err == nil {
+		t.Error("Expected a token set without end tokens to be rejected")
+	}
+	if tr, err := NewTokenRecognizerWithTokens(DefaultTokenSet()); err != nil || !tr.defaultTokens {
+		t.Errorf("Expected the default token set to use the fast detection path, err=%v", err)
+	}
+}
+
+// Benchmark detection on plain and Harmony content; detection must not allocate
+func BenchmarkIsHarmonyFormat(b *testing.B) {
+	inputs := map[string]string{
+		"Plain":   strings.Repeat("A regular response without any special tokens. ", 200),
+		"Markers": strings.Repeat("Generic Map<|K, V|> notation in prose. ", 200),
+		"Harmony": "<|start|>assistant<|channel|>final<|message|>" + strings.Repeat("Final response. ", 200) + "<|return|>",
+	}
+
+	for name, input := range inputs {
+		b.Run(name+"/Regex", func(b *testing.B) {
+			tr, _ := DefaultTokenRecognizer()
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = tr.startPattern.MatchString(input) || tr.endPattern.MatchString(input) ||
+					tr.channelPattern.MatchString(input) || tr.messagePattern.MatchString(input)
+			}
+		})
+		b.Run(name+"/Scanner", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				IsHarmonyFormat(input)
+			}
+		})
+	}
+}
+
 // Test string representations
 func TestStringRepresentations(t *testing.T) {
 	// Test Role.String()
@@ -0,0 +1,203 @@
+package proxy
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/internal"
+	"claude-proxy/logger"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AccessLogger writes one entry per HTTP request
+type AccessLogger interface {
+	LogAccess(entry map[string]interface{})
+	Close() error
+}
+
+// NewAccessLogger returns the access logger selected by ACCESS_LOG, or nil
+// when access logging is off
+func NewAccessLogger(cfg *config.Config, lokiLogger logger.Logger) (AccessLogger, error) {
+	switch cfg.AccessLog {
+	case config.AccessLogFile:
+		fileLogger, err := NewFileAccessLogger(cfg.AccessLogPath)
+		if err != nil {
+			return nil, err
+		}
+		return fileLogger, nil
+	case config.AccessLogLoki:
+		if lokiLogger == nil {
+			return nil, fmt.Errorf("loki access log needs a Loki logger")
+		}
+		return &lokiAccessLogger{logger: lokiLogger.WithComponent(logger.ComponentAccessLog)}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// FileAccessLogger appends access log entries to a file as JSON lines
+type FileAccessLogger struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileAccessLogger opens path for appending, creating it if needed
+func NewFileAccessLogger(path string) (*FileAccessLogger, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open access log %s: %v", path, err)
+	}
+	return &FileAccessLogger{file: file}, nil
+}
+
+// LogAccess appends the entry as a single JSON line
+func (f *FileAccessLogger) LogAccess(entry map[string]interface{}) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.file.Write(line)
+}
+
+// Close closes the access log file
+func (f *FileAccessLogger) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Close()
+}
+
+// lokiAccessLogger pushes access log entries to Loki under their own component
+// so they can be queried apart from request and conversation logs
+type lokiAccessLogger struct {
+	logger logger.Logger
+}
+
+func (l *lokiAccessLogger) LogAccess(entry map[string]interface{}) {
+	entryLogger := l.logger.WithField("category", "access")
+	for key, value := range entry {
+		entryLogger = entryLogger.WithField(key, fmt.Sprintf("%v", value))
+	}
+	entryLogger.Info("%v %v %v", entry["method"], entry["path"], entry["status"])
+}
+
+func (l *lokiAccessLogger) Close() error {
+	return nil
+}
+
+// accessLogRecord carries what only the proxy handler knows (the requested
+// model) back to the access log middleware
+type accessLogRecord struct {
+	mu    sync.Mutex
+	model string
+}
+
+type accessLogKey struct{}
+
+// setAccessLogModel records the request's model for the access log, if any
+func setAccessLogModel(ctx context.Context, model string) {
+	if record, ok := ctx.Value(accessLogKey{}).(*accessLogRecord); ok {
+		record.mu.Lock()
+		record.model = model
+		record.mu.Unlock()
+	}
+}
+
+// accessRecorder captures status and body size while preserving streaming
+type accessRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *accessRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *accessRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += n
+	return n, err
+}
+
+// Flush forwards to the underlying writer so SSE streaming keeps working
+func (r *accessRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// WithAccessLog logs every request served by next with the selected fields.
+// A nil accessLogger returns next unchanged.
+func WithAccessLog(accessLogger AccessLogger, fields []string, next http.Handler) http.Handler {
+	if accessLogger == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		record := &accessLogRecord{}
+		recorder := &accessRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), accessLogKey{}, record)))
+
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+		record.mu.Lock()
+		model := record.model
+		record.mu.Unlock()
+
+		entry := make(map[string]interface{}, len(fields))
+		for _, field := range fields {
+			switch field {
+			case "method":
+				entry[field] = r.Method
+			case "path":
+				entry[field] = r.URL.Path
+			case "model":
+				entry[field] = model
+			case "status":
+				entry[field] = recorder.status
+			case "duration_ms":
+				entry[field] = time.Since(start).Milliseconds()
+			case "bytes":
+				entry[field] = recorder.bytes
+			case "request_id":
+				entry[field] = recorder.Header().Get(internal.RequestIDHeader)
+			case "client_key":
+				entry[field] = maskClientKey(r.Header)
+			}
+		}
+		accessLogger.LogAccess(entry)
+	})
+}
+
+// maskClientKey identifies the caller's API key (x-api-key or bearer token)
+// by its last four characters
+func maskClientKey(header http.Header) string {
+	key := header.Get("X-Api-Key")
+	if key == "" {
+		key = strings.TrimSpace(strings.TrimPrefix(header.Get("Authorization"), "Bearer "))
+	}
+	if key == "" {
+		return ""
+	}
+	if len(key) <= 8 {
+		return "***"
+	}
+	return "..." + key[len(key)-4:]
+}
@@ -0,0 +1,110 @@
+package proxy
+
+import (
+	"bufio"
+	"claude-proxy/config"
+	"claude-proxy/internal"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// recordingAccessLogger keeps entries in memory
+type recordingAccessLogger struct {
+	entries []map[string]interface{}
+}
+
+func (r *recordingAccessLogger) LogAccess(entry map[string]interface{}) {
+	r.entries = append(r.entries, entry)
+}
+
+func (r *recordingAccessLogger) Close() error { return nil }
+
+// TestWithAccessLogFields tests that entries carry exactly the selected fields
+func TestWithAccessLogFields(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		setAccessLogModel(r.Context(), "claude-sonnet-4-20250514")
+		w.Header().Set(internal.RequestIDHeader, "req_42")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	})
+
+	recorder := &recordingAccessLogger{}
+	handler := WithAccessLog(recorder, config.AccessLogFields, next)
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages?beta=true", nil)
+	req.Header.Set("X-Api-Key", "sk-ant-secret-key-1234")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(recorder.entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(recorder.entries))
+	}
+	entry := recorder.entries[0]
+	expected := map[string]interface{}{
+		"method":     "POST",
+		"path":       "/v1/messages",
+		"model":      "claude-sonnet-4-20250514",
+		"status":     http.StatusCreated,
+		"bytes":      5,
+		"request_id": "req_42",
+		"client_key": "...1234",
+	}
+	for field, value := range expected {
+		if entry[field] != value {
+			t.Errorf("Expected %s=%v, got %v", field, value, entry[field])
+		}
+	}
+	if _, ok := entry["duration_ms"]; !ok {
+		t.Error("Expected duration_ms")
+	}
+
+	recorder.entries = nil
+	WithAccessLog(recorder, []string{"path", "status"}, next).ServeHTTP(httptest.NewRecorder(), req)
+	if len(recorder.entries[0]) != 2 {
+		t.Errorf("Expected only path and status, got %v", recorder.entries[0])
+	}
+}
+
+// TestFileAccessLogger tests JSON lines output and disabled logging
+func TestFileAccessLogger(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	if accessLogger, err := NewAccessLogger(cfg, nil); err != nil || accessLogger != nil {
+		t.Fatalf("Expected no access logger by default, got %v (%v)", accessLogger, err)
+	}
+
+	cfg.AccessLog = config.AccessLogFile
+	cfg.AccessLogPath = filepath.Join(t.TempDir(), "access.log")
+	accessLogger, err := NewAccessLogger(cfg, nil)
+	if err != nil {
+		t.Fatalf("Failed to create file access logger: %v", err)
+	}
+
+	handler := WithAccessLog(accessLogger, []string{"method", "path", "status"}, http.NotFoundHandler())
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+	accessLogger.Close()
+
+	file, err := os.Open(cfg.AccessLogPath)
+	if err != nil {
+		t.Fatalf("Failed to open access log: %v", err)
+	}
+	defer file.Close()
+
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("Invalid JSON line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, entry)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(lines))
+	}
+	if lines[0]["path"] != "/missing" || lines[0]["status"] != float64(http.StatusNotFound) {
+		t.Errorf("Unexpected first entry: %v", lines[0])
+	}
+}
@@ -0,0 +1,32 @@
+package proxy
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// WithAdminToken requires "Authorization: Bearer <token>" on requests before
+// they reach next. Browsers opening the dashboard may instead send the token
+// as the Basic auth password, with any user name. An empty token leaves next
+// open, like ADMIN_GRPC_TOKEN does for the gRPC control plane.
+func WithAdminToken(token string, next http.HandlerFunc) http.HandlerFunc {
+	if token == "" {
+		return next
+	}
+
+	expected := []byte("Bearer " + token)
+	return func(w http.ResponseWriter, r *http.Request) {
+		valid := subtle.ConstantTimeCompare([]byte(strings.TrimSpace(r.Header.Get("Authorization"))), expected) == 1
+		if _, password, ok := r.BasicAuth(); ok {
+			valid = subtle.ConstantTimeCompare([]byte(password), []byte(token)) == 1
+		}
+		if !valid {
+			w.Header().Add("WWW-Authenticate", `Bearer realm="admin"`)
+			w.Header().Add("WWW-Authenticate", `Basic realm="admin", charset="UTF-8"`)
+			http.Error(w, "Missing or invalid admin token", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
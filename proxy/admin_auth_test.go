@@ -0,0 +1,48 @@
+package proxy
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestWithAdminToken tests that admin routes require the token, as a bearer
+// token or Basic auth password, once one is set
+func TestWithAdminToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		expectedCode  int
+	}{
+		{"NoTokenConfigured", "", "", http.StatusOK},
+		{"MissingHeader", "secret", "", http.StatusUnauthorized},
+		{"WrongToken", "secret", "Bearer guess", http.StatusUnauthorized},
+		{"NotBearer", "secret", "secret", http.StatusUnauthorized},
+		{"ValidToken", "secret", "Bearer secret", http.StatusOK},
+		{"ValidBasicPassword", "secret", "Basic " + base64.StdEncoding.EncodeToString([]byte("admin:secret")), http.StatusOK},
+		{"WrongBasicPassword", "secret", "Basic " + base64.StdEncoding.EncodeToString([]byte("secret:guess")), http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := WithAdminToken(tt.token, corsTestHandler(&called))
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/approvals", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			if rec.Code != tt.expectedCode {
+				t.Errorf("Expected %d, got %d", tt.expectedCode, rec.Code)
+			}
+			if called != (tt.expectedCode == http.StatusOK) {
+				t.Errorf("Handler called = %v for status %d", called, rec.Code)
+			}
+		})
+	}
+}
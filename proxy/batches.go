@@ -0,0 +1,393 @@
+package proxy
+
+import (
+	"bytes"
+	"claude-proxy/batch"
+	"claude-proxy/internal"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxBatchRequests matches the Anthropic per-batch limit
+	maxBatchRequests = 100000
+
+	// batchExpiry is reported as expires_at; entries are processed regardless
+	batchExpiry = 24 * time.Hour
+
+	batchesPath = "/v1/messages/batches"
+)
+
+// customIDPattern is the custom_id format accepted by the Anthropic API
+var customIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// batchReplayHeaders are copied from the create request onto every entry
+var batchReplayHeaders = []string{internal.AnthropicVersionHeader, internal.AnthropicBetaHeader}
+
+// BatchProcessor implements the Message Batches API by fanning entries out to
+// the regular /v1/messages pipeline with bounded concurrency. Batches and
+// results are persisted in a batch.Store; batches interrupted by a restart are
+// picked up again by Resume.
+type BatchProcessor struct {
+	store    *batch.Store
+	pipeline http.Handler
+	slots    chan struct{} // Bounds concurrent entries across all batches
+
+	mu sync.Mutex // Serializes request count updates
+	wg sync.WaitGroup
+}
+
+// NewBatchProcessor creates a processor sending entries through pipeline,
+// typically the handler's HandleAnthropicRequest wrapped in WithPanicRecovery:
+// entries run on background goroutines, where a panic would end the process
+func NewBatchProcessor(store *batch.Store, pipeline http.Handler, concurrency int) *BatchProcessor {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &BatchProcessor{
+		store:    store,
+		pipeline: pipeline,
+		slots:    make(chan struct{}, concurrency),
+	}
+}
+
+// Resume restarts processing of batches that were still in progress
+func (p *BatchProcessor) Resume() error {
+	records, err := p.store.List()
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if record.ProcessingStatus == batch.StatusInProgress {
+			p.start(record)
+		}
+	}
+	return nil
+}
+
+// Wait blocks until all running batches have ended
+func (p *BatchProcessor) Wait() {
+	p.wg.Wait()
+}
+
+// createBatchRequest is the body of POST /v1/messages/batches
+type createBatchRequest struct {
+	Requests []batch.Entry `json:"requests"`
+}
+
+// HandleBatches serves POST (create) and GET (list) on /v1/messages/batches
+func (p *BatchProcessor) HandleBatches(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		p.createBatch(w, r)
+	case http.MethodGet:
+		records, err := p.store.List()
+		if err != nil {
+			writeProxyError(w, http.StatusInternalServerError, CodeInternal, "Failed to list batches")
+			return
+		}
+		batches := make([]batch.Batch, 0, len(records))
+		for _, record := range records {
+			batches = append(batches, record.Batch)
+		}
+		writeBatchJSON(w, http.StatusOK, map[string]interface{}{"data": batches, "has_more": false})
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeProxyError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// HandleBatch serves GET /v1/messages/batches/{id} and /v1/messages/batches/{id}/results
+func (p *BatchProcessor) HandleBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeProxyError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, batchesPath+"/")
+	id, wantResults := strings.CutSuffix(path, "/results")
+	if id == "" || strings.Contains(id, "/") {
+		writeProxyError(w, http.StatusNotFound, CodeBatchNotFound, "Unknown batch endpoint")
+		return
+	}
+
+	record, ok, err := p.store.Get(id)
+	if err != nil {
+		writeProxyError(w, http.StatusInternalServerError, CodeInternal, "Failed to load batch")
+		return
+	}
+	if !ok {
+		writeProxyError(w, http.StatusNotFound, CodeBatchNotFound, fmt.Sprintf("Batch %s not found", id))
+		return
+	}
+
+	if !wantResults {
+		writeBatchJSON(w, http.StatusOK, record.Batch)
+		return
+	}
+	if record.ProcessingStatus != batch.StatusEnded {
+		writeProxyError(w, http.StatusConflict, CodeBatchNotEnded, fmt.Sprintf("Batch %s is still processing", id))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-jsonl")
+	p.store.Results(id, func(line []byte) error {
+		w.Write(line)
+		_, err := w.Write([]byte("\n"))
+		return err
+	})
+}
+
+// createBatch validates and persists a new batch, then starts processing it
+func (p *BatchProcessor) createBatch(w http.ResponseWriter, r *http.Request) {
+	if _, _, err := anthropicVersionFromHeader(r.Header); err != nil {
+		writeProxyError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return
+	}
+
+	var req createBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProxyError(w, http.StatusBadRequest, CodeInvalidRequest, "Invalid batch request body")
+		return
+	}
+	if err := validateBatchEntries(req.Requests); err != nil {
+		writeProxyError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return
+	}
+
+	now := time.Now().UTC()
+	record := batch.Record{
+		Batch: batch.Batch{
+			ID:               newBatchID(),
+			Type:             "message_batch",
+			ProcessingStatus: batch.StatusInProgress,
+			RequestCounts:    batch.RequestCounts{Processing: len(req.Requests)},
+			CreatedAt:        now,
+			ExpiresAt:        now.Add(batchExpiry),
+		},
+		Headers:   make(map[string][]string),
+		ClientKey: requestClientKeyID(r),
+	}
+	for _, name := range batchReplayHeaders {
+		if values := r.Header.Values(name); len(values) > 0 {
+			record.Headers[name] = values
+		}
+	}
+
+	if err := p.store.Create(record, req.Requests); err != nil {
+		writeProxyError(w, http.StatusInternalServerError, CodeInternal, "Failed to persist batch")
+		return
+	}
+
+	p.start(record)
+	writeBatchJSON(w, http.StatusOK, record.Batch)
+}
+
+// validateBatchEntries checks entry count, custom_id format and uniqueness
+func validateBatchEntries(entries []batch.Entry) error {
+	if len(entries) == 0 {
+		return fmt.Errorf("requests must contain at least one entry")
+	}
+	if len(entries) > maxBatchRequests {
+		return fmt.Errorf("requests must contain at most %d entries, got: %d", maxBatchRequests, len(entries))
+	}
+
+	seen := make(map[string]bool, len(entries))
+	for i, entry := range entries {
+		if !customIDPattern.MatchString(entry.CustomID) {
+			return fmt.Errorf("requests[%d].custom_id must be 1-64 characters of [a-zA-Z0-9_-]", i)
+		}
+		if seen[entry.CustomID] {
+			return fmt.Errorf("requests[%d].custom_id %q is not unique", i, entry.CustomID)
+		}
+		seen[entry.CustomID] = true
+
+		var params map[string]json.RawMessage
+		if err := json.Unmarshal(entry.Params, &params); err != nil || params == nil {
+			return fmt.Errorf("requests[%d].params must be a JSON object", i)
+		}
+	}
+	return nil
+}
+
+// start processes a batch in the background
+func (p *BatchProcessor) start(record batch.Record) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.process(record)
+	}()
+}
+
+// process runs every entry without a stored result, then marks the batch ended
+func (p *BatchProcessor) process(record batch.Record) {
+	entries, err := p.store.Entries(record.ID)
+	if err != nil {
+		return
+	}
+
+	var entriesWG sync.WaitGroup
+	for i, entry := range entries {
+		if p.store.HasResult(record.ID, i) {
+			continue
+		}
+		p.slots <- struct{}{}
+		entriesWG.Add(1)
+		go func(index int, entry batch.Entry) {
+			defer func() {
+				<-p.slots
+				entriesWG.Done()
+			}()
+			result := p.runEntry(record, entry)
+			if err := p.store.PutResult(record.ID, index, result); err != nil {
+				return
+			}
+			p.recordOutcome(record.ID, result.Result.Type)
+		}(i, entry)
+	}
+	entriesWG.Wait()
+
+	p.finish(record.ID)
+}
+
+// runEntry sends one entry through the pipeline as a non-streaming request
+func (p *BatchProcessor) runEntry(record batch.Record, entry batch.Entry) batch.Result {
+	result := batch.Result{CustomID: entry.CustomID}
+
+	body, err := nonStreamingParams(entry.Params)
+	if err != nil {
+		result.Result = erroredBatchResult(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return result
+	}
+
+	// Served under the batch's path so conversation log and other path filters can tell batch traffic apart
+	// Entries carry no API key headers; they run on behalf of the key that created the batch
+	req, err := http.NewRequestWithContext(withClientKeyID(context.Background(), record.ClientKey), http.MethodPost, "/v1/messages/batches/"+record.ID, bytes.NewReader(body))
+	if err != nil {
+		result.Result = erroredBatchResult(http.StatusInternalServerError, CodeInternal, err.Error())
+		return result
+	}
+	for name, values := range record.Headers {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(internal.RequestIDHeader, record.ID+"_"+entry.CustomID)
+
+	rec := &batchResponseWriter{header: make(http.Header), status: http.StatusOK}
+	p.pipeline.ServeHTTP(rec, req)
+
+	response := bytes.TrimSpace(rec.body.Bytes())
+	switch {
+	case rec.status == http.StatusOK && json.Valid(response):
+		result.Result = batch.EntryResult{Type: batch.ResultSucceeded, Message: response}
+	case json.Valid(response):
+		result.Result = batch.EntryResult{Type: batch.ResultErrored, Error: response}
+	default:
+		result.Result = erroredBatchResult(rec.status, CodeUpstreamInvalidResponse, "Pipeline returned a non-JSON response")
+	}
+	return result
+}
+
+// recordOutcome moves one entry from processing to its outcome count
+func (p *BatchProcessor) recordOutcome(batchID, resultType string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	record, ok, err := p.store.Get(batchID)
+	if err != nil || !ok {
+		return
+	}
+	record.RequestCounts.Processing--
+	if resultType == batch.ResultSucceeded {
+		record.RequestCounts.Succeeded++
+	} else {
+		record.RequestCounts.Errored++
+	}
+	p.store.Update(record)
+}
+
+// finish recounts outcomes from the stored results and marks the batch ended
+func (p *BatchProcessor) finish(batchID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	record, ok, err := p.store.Get(batchID)
+	if err != nil || !ok {
+		return
+	}
+
+	counts := batch.RequestCounts{}
+	p.store.Results(batchID, func(line []byte) error {
+		var result batch.Result
+		if json.Unmarshal(line, &result) == nil && result.Result.Type == batch.ResultSucceeded {
+			counts.Succeeded++
+		} else {
+			counts.Errored++
+		}
+		return nil
+	})
+
+	endedAt := time.Now().UTC()
+	resultsURL := fmt.Sprintf("%s/%s/results", batchesPath, batchID)
+	record.RequestCounts = counts
+	record.ProcessingStatus = batch.StatusEnded
+	record.EndedAt = &endedAt
+	record.ResultsURL = &resultsURL
+	p.store.Update(record)
+}
+
+// nonStreamingParams removes "stream" so the entry returns a single message
+func nonStreamingParams(params json.RawMessage) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(params, &fields); err != nil {
+		return nil, fmt.Errorf("params must be a JSON object: %v", err)
+	}
+	delete(fields, "stream")
+	return json.Marshal(fields)
+}
+
+// erroredBatchResult builds an errored entry carrying the proxy error envelope
+func erroredBatchResult(status int, code ErrorCode, message string) batch.EntryResult {
+	var resp errorResponse
+	resp.Type = "error"
+	resp.Error.Type = anthropicErrorType(status)
+	resp.Error.Code = code
+	resp.Error.Message = message
+	data, _ := json.Marshal(resp)
+	return batch.EntryResult{Type: batch.ResultErrored, Error: data}
+}
+
+// newBatchID returns an identifier in the msgbatch_ format used by Anthropic
+func newBatchID() string {
+	buf := make([]byte, 12)
+	rand.Read(buf)
+	return "msgbatch_" + hex.EncodeToString(buf)
+}
+
+func writeBatchJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+// batchResponseWriter buffers a pipeline response in memory
+type batchResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *batchResponseWriter) Header() http.Header         { return w.header }
+func (w *batchResponseWriter) Write(b []byte) (int, error) { return w.body.Write(b) }
+func (w *batchResponseWriter) WriteHeader(status int)      { w.status = status }
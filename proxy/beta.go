@@ -0,0 +1,93 @@
+package proxy
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/internal"
+	"claude-proxy/logger"
+	"context"
+	"net/http"
+	"strings"
+)
+
+// anthropic-beta features sent by Claude Code
+const (
+	BetaClaudeCode               = "claude-code-20250219"
+	BetaPromptCaching            = "prompt-caching-2024-07-31"
+	BetaTokenEfficientTools      = "token-efficient-tools-2025-02-19"
+	BetaInterleavedThinking      = "interleaved-thinking-2025-05-14"
+	BetaFineGrainedToolStreaming = "fine-grained-tool-streaming-2025-05-14"
+	BetaContext1M                = "context-1m-2025-08-07"
+)
+
+// supportedBetas are betas the proxy honors or that need no translation.
+// Beta-only body fields (cache_control, thinking) are never forwarded to
+// OpenAI-compatible backends, and the anthropic-beta header itself is not sent
+// upstream, so anything else is logged as ignored.
+var supportedBetas = map[string]bool{
+	BetaClaudeCode:          true,
+	BetaPromptCaching:       true, // Mapped to cache_prompt, which is always requested
+	BetaTokenEfficientTools: true, // Optionally minifies tool schemas (ANTHROPIC_BETA_MINIFY_TOOLS)
+}
+
+// parseAnthropicBetas collects beta names from every anthropic-beta header
+// value, splitting comma-separated lists and dropping duplicates
+func parseAnthropicBetas(header http.Header) []string {
+	var betas []string
+	seen := make(map[string]bool)
+	for _, value := range header.Values(internal.AnthropicBetaHeader) {
+		for _, beta := range strings.Split(value, ",") {
+			beta = strings.ToLower(strings.TrimSpace(beta))
+			if beta == "" || seen[beta] {
+				continue
+			}
+			seen[beta] = true
+			betas = append(betas, beta)
+		}
+	}
+	return betas
+}
+
+// HasAnthropicBeta reports whether the request opted into a beta feature
+func HasAnthropicBeta(ctx context.Context, beta string) bool {
+	for _, requested := range internal.GetAnthropicBetas(ctx) {
+		if requested == beta {
+			return true
+		}
+	}
+	return false
+}
+
+// unsupportedBetas returns the requested betas the proxy does not honor
+func unsupportedBetas(betas []string) []string {
+	var unsupported []string
+	for _, beta := range betas {
+		if !supportedBetas[beta] {
+			unsupported = append(unsupported, beta)
+		}
+	}
+	return unsupported
+}
+
+// toolMinifyLimit returns the description budget for a tool in this request.
+// Minification applies when TOOL_SCHEMA_MINIFY is on, or when the client sent
+// token-efficient-tools and ANTHROPIC_BETA_MINIFY_TOOLS allows the beta to opt in.
+func toolMinifyLimit(ctx context.Context, cfg *config.Config, toolName string) (int, bool) {
+	if cfg.ToolSchemaMinifyEnabled {
+		return cfg.ToolDescriptionLimit(toolName)
+	}
+	if cfg.BetaMinifyTools && HasAnthropicBeta(ctx, BetaTokenEfficientTools) {
+		return cfg.ToolDescriptionBudget(toolName)
+	}
+	return 0, false
+}
+
+// logAnthropicBetas records the requested betas and flags ignored ones
+func logAnthropicBetas(betas []string, loggerInstance logger.Logger) {
+	if len(betas) == 0 {
+		return
+	}
+	loggerInstance.Debug("anthropic-beta: %s", strings.Join(betas, ","))
+	if ignored := unsupportedBetas(betas); len(ignored) > 0 {
+		loggerInstance.Debug("Ignoring unsupported anthropic-beta features: %s", strings.Join(ignored, ","))
+	}
+}
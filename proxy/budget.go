@@ -0,0 +1,172 @@
+package proxy
+
+import (
+	"claude-proxy/circuitbreaker"
+	"claude-proxy/config"
+	"claude-proxy/types"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sessionBudgetIdleTimeout is how long the spend of an inactive session is kept
+const sessionBudgetIdleTimeout = 24 * time.Hour
+
+// sessionBudgetSweepInterval is how often record looks for inactive sessions
+const sessionBudgetSweepInterval = time.Hour
+
+// sessionSpend is what one session has used so far
+type sessionSpend struct {
+	tokens   int64
+	costUSD  float64
+	exceeded bool // Budget used up; the alert has been sent
+	lastSeen time.Time
+}
+
+// sessionBudgets tracks the tokens and estimated cost each session spends
+// against SESSION_BUDGET_TOKENS and SESSION_BUDGET_USD
+type sessionBudgets struct {
+	mu        sync.Mutex
+	sessions  map[string]*sessionSpend
+	lastSweep time.Time
+}
+
+// newSessionBudgets creates an empty session budget tracker
+func newSessionBudgets() *sessionBudgets {
+	return &sessionBudgets{sessions: make(map[string]*sessionSpend)}
+}
+
+// budgetSession identifies whose budget a request is charged to: the
+// metadata.user_id session Claude Code sends within the caller's API key.
+// The session ID is client-supplied, so it never stands in for the key:
+// keys sending the same ID keep separate budgets.
+func budgetSession(client string, req types.AnthropicRequest) string {
+	session := requestSession(req)
+	switch {
+	case client != "" && session != "":
+		return "key:" + client + "/" + session
+	case client != "":
+		return "key:" + client
+	}
+	return session
+}
+
+// onBehalfOfKey stores the client key ID an internal request runs for
+type onBehalfOfKey struct{}
+
+// withClientKeyID runs an internal request (batch entry, journal replay) on
+// behalf of the client key that submitted it. Such requests carry no API key
+// headers, so without it they would escape per-key access and spend limits.
+func withClientKeyID(ctx context.Context, client string) context.Context {
+	return context.WithValue(ctx, onBehalfOfKey{}, client)
+}
+
+// requestClientKeyID identifies the client key a request is charged to: the
+// one it runs on behalf of, else the caller's own API key
+func requestClientKeyID(r *http.Request) string {
+	if client, ok := r.Context().Value(onBehalfOfKey{}).(string); ok {
+		return client
+	}
+	return clientKeyID(r.Header)
+}
+
+// clientKeyID identifies the caller's API key (x-api-key or bearer token) by
+// a hash, so spend can be tracked and persisted without storing the key
+func clientKeyID(header http.Header) string {
+	key := header.Get("X-Api-Key")
+	if key == "" {
+		key = strings.TrimSpace(strings.TrimPrefix(header.Get("Authorization"), "Bearer "))
+	}
+	if key == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// budgetExceededMessage describes a used-up budget, "" while spend is within it
+func budgetExceededMessage(cfg *config.Config, spend *sessionSpend) string {
+	switch {
+	case cfg.SessionBudgetTokens > 0 && spend.tokens >= int64(cfg.SessionBudgetTokens):
+		return fmt.Sprintf("session token budget exhausted: %d of %d tokens used", spend.tokens, cfg.SessionBudgetTokens)
+	case cfg.SessionBudgetUSD > 0 && spend.costUSD >= cfg.SessionBudgetUSD:
+		return fmt.Sprintf("session cost budget exhausted: $%.4f of $%.4f spent", spend.costUSD, cfg.SessionBudgetUSD)
+	}
+	return ""
+}
+
+// check returns why a session may not send more requests, "" when it may.
+// Requests without a session are not limited.
+func (b *sessionBudgets) check(cfg *config.Config, session string) string {
+	if session == "" {
+		return ""
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	spend, exists := b.sessions[session]
+	if !exists {
+		return ""
+	}
+	return budgetExceededMessage(cfg, spend)
+}
+
+// record charges a response to a session. Returns the exceeded message the
+// first time the session runs out of budget, "" otherwise.
+func (b *sessionBudgets) record(cfg *config.Config, session string, tokens int, costUSD float64, now time.Time) string {
+	if session == "" {
+		return ""
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if now.Sub(b.lastSweep) >= sessionBudgetSweepInterval {
+		for id, spend := range b.sessions {
+			if now.Sub(spend.lastSeen) > sessionBudgetIdleTimeout {
+				delete(b.sessions, id)
+			}
+		}
+		b.lastSweep = now
+	}
+	spend, exists := b.sessions[session]
+	if !exists {
+		spend = &sessionSpend{}
+		b.sessions[session] = spend
+	}
+	spend.tokens += int64(tokens)
+	spend.costUSD += costUSD
+	spend.lastSeen = now
+
+	if spend.exceeded {
+		return ""
+	}
+	message := budgetExceededMessage(cfg, spend)
+	spend.exceeded = message != ""
+	return message
+}
+
+// sendBudgetAlert notifies ALERT_WEBHOOK_URL that a session used up its
+// budget. Delivery is asynchronous so the response is never delayed.
+func (h *Handler) sendBudgetAlert(session, message string) {
+	if h.alertSink == nil {
+		return
+	}
+	alert := circuitbreaker.Alert{
+		Type:      circuitbreaker.AlertBudgetExceeded,
+		Session:   session,
+		Message:   message,
+		Timestamp: time.Now(),
+	}
+	go func() {
+		if err := h.alertSink.SendAlert(alert); err != nil && h.obsLogger != nil {
+			h.obsLogger.Warn("budget", "warning", "", "Failed to deliver budget alert", map[string]interface{}{
+				"session": session,
+				"error":   err.Error(),
+			})
+		}
+	}()
+}
@@ -0,0 +1,33 @@
+package proxy
+
+import (
+	"testing"
+	"time"
+
+	"claude-proxy/config"
+)
+
+// TestSessionBudgetsEvictIdle tests that inactive sessions are dropped by the
+// periodic sweep rather than on every recorded response
+func TestSessionBudgetsEvictIdle(t *testing.T) {
+	cfg := &config.Config{}
+	budgets := newSessionBudgets()
+	start := time.Now()
+
+	budgets.record(cfg, "idle", 10, 0, start)
+	budgets.record(cfg, "busy", 10, 0, start.Add(sessionBudgetIdleTimeout))
+
+	// Past the idle timeout, but within the sweep interval of the first sweep
+	budgets.record(cfg, "busy", 10, 0, start.Add(sessionBudgetIdleTimeout+time.Minute))
+	if len(budgets.sessions) != 2 {
+		t.Fatalf("Expected no sweep before the interval, got %d sessions", len(budgets.sessions))
+	}
+
+	budgets.record(cfg, "busy", 10, 0, start.Add(sessionBudgetIdleTimeout+sessionBudgetSweepInterval))
+	if _, exists := budgets.sessions["idle"]; exists {
+		t.Error("Expected the idle session to be evicted")
+	}
+	if spend := budgets.sessions["busy"]; spend == nil || spend.tokens != 30 {
+		t.Errorf("Expected the busy session to keep its spend, got %+v", spend)
+	}
+}
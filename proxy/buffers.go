@@ -0,0 +1,63 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// maxPooledBufferSize keeps a single huge conversation from pinning its
+// buffer in the pool for the life of the process
+const maxPooledBufferSize = 8 << 20
+
+// bufferPool recycles the buffers request and response bodies are read and
+// encoded into. Conversations of hundreds of KB are otherwise reallocated,
+// growth step by growth step, on every request.
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// getBuffer returns an empty buffer from the pool
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// putBuffer returns buf to the pool; its bytes must no longer be referenced
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	bufferPool.Put(buf)
+}
+
+// readPooled reads r into a pooled buffer. The caller releases it with
+// putBuffer once decoding is done.
+func readPooled(r io.Reader) (*bytes.Buffer, error) {
+	buf := getBuffer()
+	if _, err := buf.ReadFrom(r); err != nil {
+		putBuffer(buf)
+		return nil, err
+	}
+	return buf, nil
+}
+
+// writeSSEData encodes data straight into a pooled buffer and writes the
+// "data:" line of an SSE event in a single Write. Output is identical to
+// formatting json.Marshal's result.
+func writeSSEData(w io.Writer, data interface{}) (int, error) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	buf.WriteString("data: ")
+	// Encode appends the newline that ends the data line
+	if err := json.NewEncoder(buf).Encode(data); err != nil {
+		// Fallback to empty object if marshaling fails
+		buf.Reset()
+		buf.WriteString("data: {}\n")
+	}
+	buf.WriteByte('\n')
+	return w.Write(buf.Bytes())
+}
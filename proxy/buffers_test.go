@@ -0,0 +1,112 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"claude-proxy/types"
+)
+
+// largeConversationBody builds a request body of roughly the given size
+func largeConversationBody(size int) []byte {
+	req := types.AnthropicRequest{Model: "claude-sonnet-4-20250514", MaxTokens: 1024}
+	chunk := strings.Repeat("Some file content with <tags> & symbols. ", 50)
+	for i := 0; len(chunk)*i < size; i++ {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		req.Messages = append(req.Messages, types.Message{Role: role, Content: chunk})
+	}
+	body, _ := json.Marshal(req)
+	return body
+}
+
+// TestWriteSSEDataMatchesMarshal tests that the pooled encoder emits the same bytes as json.Marshal
+func TestWriteSSEDataMatchesMarshal(t *testing.T) {
+	events := []interface{}{
+		map[string]interface{}{"type": "content_block_delta", "index": 0, "delta": map[string]interface{}{"type": "text_delta", "text": "a <b> & \"c\"\n"}},
+		map[string]interface{}{"type": "message_stop"},
+		make(chan int), // Unencodable values fall back to an empty object
+	}
+	for _, event := range events {
+		dataJSON, err := json.Marshal(event)
+		if err != nil {
+			dataJSON = []byte("{}")
+		}
+		want := fmt.Sprintf("data: %s\n\n", string(dataJSON))
+
+		var got bytes.Buffer
+		n, err := writeSSEData(&got, event)
+		if err != nil || n != len(want) || got.String() != want {
+			t.Errorf("writeSSEData = %q (n=%d, err=%v), want %q", got.String(), n, err, want)
+		}
+	}
+}
+
+// TestReadPooled tests that pooled reads return the full body
+func TestReadPooled(t *testing.T) {
+	body := largeConversationBody(64 << 10)
+	for i := 0; i < 3; i++ {
+		buf, err := readPooled(bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("readPooled failed: %v", err)
+		}
+		if !bytes.Equal(buf.Bytes(), body) {
+			t.Errorf("readPooled returned %d bytes, want %d", buf.Len(), len(body))
+		}
+		putBuffer(buf)
+	}
+}
+
+// BenchmarkReadRequestBody compares io.ReadAll (before) with pooled reads (after)
+func BenchmarkReadRequestBody(b *testing.B) {
+	for _, size := range []int{16 << 10, 256 << 10, 1 << 20} {
+		body := largeConversationBody(size)
+
+		b.Run(fmt.Sprintf("ReadAll/%dKB", size>>10), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				data, _ := io.ReadAll(bytes.NewReader(body))
+				var req types.AnthropicRequest
+				_ = json.Unmarshal(data, &req)
+			}
+		})
+		b.Run(fmt.Sprintf("Pooled/%dKB", size>>10), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				buf, _ := readPooled(bytes.NewReader(body))
+				var req types.AnthropicRequest
+				_ = json.Unmarshal(buf.Bytes(), &req)
+				putBuffer(buf)
+			}
+		})
+	}
+}
+
+// BenchmarkWriteSSEData compares json.Marshal + Fprintf (before) with pooled encoding (after)
+func BenchmarkWriteSSEData(b *testing.B) {
+	event := map[string]interface{}{
+		"type":  "content_block_delta",
+		"index": 0,
+		"delta": map[string]interface{}{"type": "text_delta", "text": strings.Repeat("streamed text ", 20)},
+	}
+
+	b.Run("Marshal", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			dataJSON, _ := json.Marshal(event)
+			fmt.Fprintf(io.Discard, "data: %s\n\n", string(dataJSON))
+		}
+	})
+	b.Run("Pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			writeSSEData(io.Discard, event)
+		}
+	})
+}
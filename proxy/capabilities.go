@@ -0,0 +1,195 @@
+package proxy
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/logger"
+	"claude-proxy/tokenizer"
+	"claude-proxy/types"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// unsupportedContent returns why a request carries content the backend model
+// cannot read (images or documents for a model without vision), "" when it
+// can. Rejecting beats silently dropping the block: the model would answer
+// about an image it never saw.
+func unsupportedContent(req types.AnthropicRequest, caps config.ModelCapabilities) string {
+	if caps.SupportsVision() {
+		return ""
+	}
+	for _, msg := range req.Messages {
+		if blockType := firstMediaBlock(msg.Content); blockType != "" {
+			return fmt.Sprintf("model %s does not accept %s input; remove the %s block or use a vision model", req.Model, blockType, blockType)
+		}
+	}
+	return ""
+}
+
+// firstMediaBlock returns the type of the first image or document block in
+// message content, including blocks nested in tool results
+func firstMediaBlock(content interface{}) string {
+	blocks, ok := content.([]interface{})
+	if !ok {
+		return ""
+	}
+	for _, item := range blocks {
+		block, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch blockType, _ := block["type"].(string); blockType {
+		case "image", "document":
+			return blockType
+		case "tool_result":
+			if nested := firstMediaBlock(block["content"]); nested != "" {
+				return nested
+			}
+		}
+	}
+	return ""
+}
+
+// applyModelCapabilities degrades a transformed request to what the backend
+// model supports
+func applyModelCapabilities(openaiReq *types.OpenAIRequest, caps config.ModelCapabilities, loggerInstance logger.Logger) {
+	if !caps.SupportsTools() && len(openaiReq.Tools) > 0 {
+		format := caps.EmulatedToolFormat()
+		if format == config.ToolEmulationFormatJSON {
+			openaiReq.ResponseFormat = toolCallResponseFormat(openaiReq.Tools)
+		}
+		inlineToolDefinitions(openaiReq, format)
+		loggerInstance.Info("🧩 Inlined %d tool definitions into the system prompt (model has no native tools, emulation=%q)", len(openaiReq.Tools), format)
+		openaiReq.Tools = nil
+		openaiReq.ToolChoice = nil
+		openaiReq.ParallelToolCalls = nil
+	}
+	if !caps.SupportsSystemRole() {
+		if mergeSystemIntoUser(openaiReq) {
+			loggerInstance.Debug("🧩 Merged system prompt into the first user message (model has no system role)")
+		}
+	}
+}
+
+// inlineToolDefinitions describes the request's tools in the system prompt,
+// followed by the tool calling instructions of the emulated format if any, and
+// rewrites earlier tool calls and results as text, which backends without
+// function calling would otherwise reject
+func inlineToolDefinitions(openaiReq *types.OpenAIRequest, format string) {
+	var definitions strings.Builder
+	definitions.WriteString("# Tools\n\nYou can use the following tools:\n")
+	for _, tool := range openaiReq.Tools {
+		parameters, _ := json.Marshal(tool.Function.Parameters)
+		fmt.Fprintf(&definitions, "\n## %s\n%s\nParameters (JSON Schema): %s\n", tool.Function.Name, tool.Function.Description, parameters)
+	}
+	switch format {
+	case config.ToolEmulationFormatToolCall:
+		definitions.WriteString("\n" + toolEmulationInstructions)
+	case config.ToolEmulationFormatReAct:
+		definitions.WriteString("\n" + reactInstructions)
+	case config.ToolEmulationFormatJSON:
+		definitions.WriteString("\n" + jsonToolCallInstructions)
+	}
+	appendSystemText(openaiReq, definitions.String())
+	openaiReq.Messages = toolHistoryAsText(openaiReq.Messages, format)
+}
+
+// toolHistoryAsText returns messages with tool calls and tool results written
+// as text: in the emulated format (<tool_call>/<tool_response> blocks,
+// Action/Observation lines or reply objects), as plain notes when not
+// emulating. Messages without tool content are returned as is.
+func toolHistoryAsText(messages []types.OpenAIMessage, format string) []types.OpenAIMessage {
+	converted := make([]types.OpenAIMessage, len(messages))
+	for i, msg := range messages {
+		switch {
+		case len(msg.ToolCalls) > 0 && format == config.ToolEmulationFormatJSON:
+			msg.Content = renderJSONToolCalls(msg.Content, msg.ToolCalls)
+			msg.ToolCalls = nil
+		case len(msg.ToolCalls) > 0:
+			var parts []string
+			if msg.Content != "" {
+				parts = append(parts, msg.Content)
+			}
+			for _, call := range msg.ToolCalls {
+				switch format {
+				case config.ToolEmulationFormatToolCall:
+					parts = append(parts, renderEmulatedToolCall(call))
+				case config.ToolEmulationFormatReAct:
+					parts = append(parts, renderReActAction(call))
+				default:
+					parts = append(parts, fmt.Sprintf("[Called tool %s with %s]", call.Function.Name, call.Function.Arguments))
+				}
+			}
+			msg.Content = strings.Join(parts, "\n")
+			msg.ToolCalls = nil
+		case msg.Role == "tool":
+			msg.Role = "user"
+			switch format {
+			case config.ToolEmulationFormatToolCall, config.ToolEmulationFormatJSON:
+				msg.Content = fmt.Sprintf("<tool_response>\n%s\n</tool_response>", msg.Content)
+			case config.ToolEmulationFormatReAct:
+				msg.Content = "Observation: " + msg.Content
+			default:
+				msg.Content = fmt.Sprintf("[Tool result for %s]\n%s", msg.ToolCallID, msg.Content)
+			}
+			msg.ToolCallID = ""
+		}
+		converted[i] = msg
+	}
+	return converted
+}
+
+// appendSystemText adds text after the existing system prompt, or as a new
+// system message when there is none
+func appendSystemText(openaiReq *types.OpenAIRequest, text string) {
+	if len(openaiReq.Messages) > 0 && openaiReq.Messages[0].Role == "system" {
+		openaiReq.Messages[0].Content += "\n\n" + text
+		return
+	}
+	openaiReq.Messages = append([]types.OpenAIMessage{{Role: "system", Content: text}}, openaiReq.Messages...)
+}
+
+// mergeSystemIntoUser moves system messages into the first user message,
+// reporting whether there were any
+func mergeSystemIntoUser(openaiReq *types.OpenAIRequest) bool {
+	var system []string
+	messages := openaiReq.Messages[:0:0]
+	for _, msg := range openaiReq.Messages {
+		if msg.Role == "system" {
+			system = append(system, msg.Content)
+			continue
+		}
+		messages = append(messages, msg)
+	}
+	if len(system) == 0 {
+		return false
+	}
+
+	prefix := strings.Join(system, "\n\n")
+	for i := range messages {
+		if messages[i].Role == "user" {
+			messages[i].Content = prefix + "\n\n" + messages[i].Content
+			openaiReq.Messages = messages
+			return true
+		}
+	}
+	openaiReq.Messages = append([]types.OpenAIMessage{{Role: "user", Content: prefix}}, messages...)
+	return true
+}
+
+// estimatePromptTokens approximates the prompt size of a transformed request
+// with the tokenizer heuristic, including tool definitions
+func estimatePromptTokens(openaiReq types.OpenAIRequest) int {
+	tokens := 0
+	for _, msg := range openaiReq.Messages {
+		tokens += tokenizer.Count(msg.Content) + tokenizer.Count(msg.ReasoningContent)
+		for _, call := range msg.ToolCalls {
+			tokens += tokenizer.Count(call.Function.Name) + tokenizer.Count(call.Function.Arguments)
+		}
+	}
+	if len(openaiReq.Tools) > 0 {
+		tools, _ := json.Marshal(openaiReq.Tools)
+		tokens += tokenizer.Count(string(tools))
+	}
+	return tokens
+}
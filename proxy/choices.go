@@ -0,0 +1,67 @@
+package proxy
+
+import (
+	"sort"
+	"strings"
+
+	"claude-proxy/config"
+	"claude-proxy/logger"
+	"claude-proxy/types"
+)
+
+// applyChoicePolicy reduces an upstream response to the single choice the
+// Anthropic format can carry. Responses with one choice at index 0 pass
+// through unchanged; anything else (n>1, duplicate or unexpected indices) is
+// logged and handled by MULTI_CHOICE_POLICY.
+func applyChoicePolicy(resp *types.OpenAIResponse, policy string, loggerInstance logger.Logger) (*types.OpenAIResponse, error) {
+	if len(resp.Choices) == 0 || (len(resp.Choices) == 1 && resp.Choices[0].Index == 0) {
+		return resp, nil
+	}
+
+	indices := make([]int, len(resp.Choices))
+	for i, choice := range resp.Choices {
+		indices[i] = choice.Index
+	}
+	loggerInstance.Warn("⚠️ Upstream returned %d choice(s) with indices %v, applying multi-choice policy %q", len(resp.Choices), indices, policy)
+
+	if policy == config.MultiChoiceError {
+		return nil, newProxyError(CodeUpstreamInvalidResponse, "upstream returned %d choices with indices %v", len(resp.Choices), indices)
+	}
+
+	choices := make([]types.OpenAIChoice, len(resp.Choices))
+	copy(choices, resp.Choices)
+	sort.SliceStable(choices, func(i, j int) bool { return choices[i].Index < choices[j].Index })
+
+	selected := choices[0]
+	if policy == config.MultiChoiceMerge {
+		selected = mergeChoices(choices)
+	}
+	selected.Index = 0
+
+	reduced := *resp
+	reduced.Choices = []types.OpenAIChoice{selected}
+	return &reduced, nil
+}
+
+// mergeChoices concatenates the text and tool calls of every choice in index
+// order. The finish reason is tool_calls when any choice called a tool,
+// otherwise that of the last choice.
+func mergeChoices(choices []types.OpenAIChoice) types.OpenAIChoice {
+	merged := types.OpenAIChoice{
+		Message:      types.OpenAIMessage{Role: "assistant"},
+		FinishReason: choices[len(choices)-1].FinishReason,
+	}
+
+	var text []string
+	for _, choice := range choices {
+		if choice.Message.Content != "" {
+			text = append(text, choice.Message.Content)
+		}
+		merged.Message.ToolCalls = append(merged.Message.ToolCalls, choice.Message.ToolCalls...)
+		if choice.FinishReason != nil && *choice.FinishReason == "tool_calls" {
+			merged.FinishReason = choice.FinishReason
+		}
+	}
+	merged.Message.Content = strings.Join(text, "\n\n")
+	return merged
+}
@@ -4,9 +4,14 @@ import (
 	"claude-proxy/internal"
 	"context"
 	"fmt"
+	"net/http"
+	"strings"
 	"time"
 )
 
+// maxRequestIDLength bounds client-supplied request IDs so they stay log-friendly
+const maxRequestIDLength = 128
+
 // withRequestID adds a request ID to the context (wraps internal function)
 func withRequestID(ctx context.Context, requestID string) context.Context {
 	return internal.WithRequestID(ctx, requestID)
@@ -21,3 +26,19 @@ func GetRequestID(ctx context.Context) string {
 func generateRequestID() string {
 	return fmt.Sprintf("req_%d", time.Now().UnixNano()%10000)
 }
+
+// requestIDFromHeader returns the client-supplied X-Request-ID when it is usable,
+// otherwise a newly generated ID. IDs that are too long or contain characters
+// outside printable ASCII are replaced to prevent log injection.
+func requestIDFromHeader(header http.Header) string {
+	requestID := strings.TrimSpace(header.Get(internal.RequestIDHeader))
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return generateRequestID()
+	}
+	for _, r := range requestID {
+		if r < 0x21 || r > 0x7e {
+			return generateRequestID()
+		}
+	}
+	return requestID
+}
@@ -0,0 +1,176 @@
+package proxy
+
+import (
+	"claude-proxy/conversation"
+	"claude-proxy/types"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Export formats for HandleConversationExport
+const (
+	ExportFormatAnthropic = "anthropic" // /v1/messages request bodies and responses
+	ExportFormatOpenAI    = "openai"    // Chat completions bodies as sent upstream and assistant messages
+)
+
+// ExportedExchange is one request of an exported session in the chosen format
+type ExportedExchange struct {
+	RequestID string      `json:"request_id"`
+	Time      time.Time   `json:"time"`
+	Request   interface{} `json:"request"`
+	Response  interface{} `json:"response,omitempty"`
+}
+
+// ConversationExport is a logged session rendered for replay against a backend
+type ConversationExport struct {
+	Format    string             `json:"format"`
+	SessionID string             `json:"session_id,omitempty"`
+	Exchanges []ExportedExchange `json:"exchanges"`
+}
+
+// ExportConversation renders exchanges in format. The OpenAI rendering runs
+// each request through the same model mapping and transformation the proxy
+// applied when it was served, so it reproduces what the backend received.
+func (h *Handler) ExportConversation(ctx context.Context, exchanges []conversation.Exchange, format string) (ConversationExport, error) {
+	export := ConversationExport{Format: format, Exchanges: []ExportedExchange{}}
+	if len(exchanges) > 0 {
+		export.SessionID = exchanges[0].SessionID
+	}
+
+	for _, exchange := range exchanges {
+		var req types.AnthropicRequest
+		if err := json.Unmarshal(exchange.Request, &req); err != nil {
+			return export, fmt.Errorf("request %s: %v", exchange.RequestID, err)
+		}
+		var resp *types.AnthropicResponse
+		if len(exchange.Response) > 0 {
+			resp = &types.AnthropicResponse{}
+			if err := json.Unmarshal(exchange.Response, resp); err != nil {
+				return export, fmt.Errorf("response %s: %v", exchange.RequestID, err)
+			}
+		}
+
+		exported := ExportedExchange{RequestID: exchange.RequestID, Time: exchange.Time}
+		switch format {
+		case ExportFormatAnthropic:
+			exported.Request = req
+			if resp != nil {
+				exported.Response = resp
+			}
+		case ExportFormatOpenAI:
+			openaiReq, openaiResp, err := h.exportOpenAI(withRequestID(ctx, exchange.RequestID), req, resp)
+			if err != nil {
+				return export, fmt.Errorf("request %s: %v", exchange.RequestID, err)
+			}
+			exported.Request = openaiReq
+			if openaiResp != nil {
+				exported.Response = openaiResp
+			}
+		default:
+			return export, fmt.Errorf("unknown export format %q, must be %q or %q", format, ExportFormatAnthropic, ExportFormatOpenAI)
+		}
+		export.Exchanges = append(export.Exchanges, exported)
+	}
+	return export, nil
+}
+
+// exportOpenAI transforms a request, and its response as the assistant turn
+// that follows it, into OpenAI chat completions form
+func (h *Handler) exportOpenAI(ctx context.Context, req types.AnthropicRequest, resp *types.AnthropicResponse) (types.OpenAIRequest, *types.OpenAIMessage, error) {
+	req.Model = h.config.MapModelName(ctx, req.Model)
+	openaiReq, err := TransformAnthropicToOpenAI(ctx, req, h.config)
+	if err != nil || resp == nil {
+		return openaiReq, nil, err
+	}
+
+	// The assistant turn converts exactly like an assistant message in the
+	// next request of the session would
+	content, err := json.Marshal(resp.Content)
+	if err != nil {
+		return openaiReq, nil, err
+	}
+	var blocks []interface{}
+	if err := json.Unmarshal(content, &blocks); err != nil {
+		return openaiReq, nil, err
+	}
+	withResponse := req
+	withResponse.Messages = append(append([]types.Message{}, req.Messages...), types.Message{Role: "assistant", Content: blocks})
+	transcript, err := TransformAnthropicToOpenAI(ctx, withResponse, h.config)
+	if err != nil || len(transcript.Messages) == 0 {
+		return openaiReq, nil, err
+	}
+	assistant := transcript.Messages[len(transcript.Messages)-1]
+	return openaiReq, &assistant, nil
+}
+
+// HandleConversationExport serves
+// GET /admin/conversations/export?session=...|request_id=...&format=anthropic|openai
+// as a downloadable JSON document; request_id exports a single exchange as a
+// minimal repro case.
+func (h *Handler) HandleConversationExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.conversations == nil {
+		http.Error(w, "Conversation search is disabled", http.StatusNotFound)
+		return
+	}
+
+	params := r.URL.Query()
+	format := params.Get("format")
+	if format == "" {
+		format = ExportFormatAnthropic
+	}
+	if format != ExportFormatAnthropic && format != ExportFormatOpenAI {
+		http.Error(w, "format must be anthropic or openai", http.StatusBadRequest)
+		return
+	}
+
+	var exchanges []conversation.Exchange
+	var name string
+	switch {
+	case params.Get("request_id") != "":
+		name = params.Get("request_id")
+		exchange, ok, err := h.conversations.Get(name)
+		if err != nil {
+			http.Error(w, "Failed to read conversation store", http.StatusInternalServerError)
+			return
+		}
+		if ok {
+			exchanges = append(exchanges, exchange)
+		}
+	case params.Get("session") != "":
+		name = params.Get("session")
+		var err error
+		if exchanges, err = h.conversations.Session(name); err != nil {
+			http.Error(w, "Failed to read conversation store", http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, "session or request_id is required", http.StatusBadRequest)
+		return
+	}
+	if len(exchanges) == 0 {
+		http.Error(w, "No logged exchanges for "+name, http.StatusNotFound)
+		return
+	}
+
+	export, err := h.ExportConversation(r.Context(), exchanges, format)
+	if err != nil {
+		http.Error(w, "Failed to export conversation: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+"."+format+".json"))
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(export); err != nil {
+		http.Error(w, "Failed to encode export", http.StatusInternalServerError)
+	}
+}
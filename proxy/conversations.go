@@ -0,0 +1,95 @@
+package proxy
+
+import (
+	"claude-proxy/conversation"
+	"claude-proxy/logger"
+	"claude-proxy/types"
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// maxConversationSearchLimit bounds the limit parameter of conversation searches
+const maxConversationSearchLimit = 500
+
+// SetConversationStore keeps an indexed copy of logged conversations in store
+func (h *Handler) SetConversationStore(store *conversation.Store) {
+	h.conversations = store
+}
+
+// storeConversation adds a logged exchange to the conversation store, if any.
+// The request is stored with the model the client asked for, masked like the
+// Loki conversation logs.
+func (h *Handler) storeConversation(requestID, model string, req types.AnthropicRequest, resp *types.AnthropicResponse, log logger.Logger) {
+	if h.conversations == nil {
+		return
+	}
+	req.Model = model
+	if h.redactor != nil {
+		var redactedReq types.AnthropicRequest
+		var redactedResp types.AnthropicResponse
+		if err := h.redactor.Copy(req, &redactedReq); err != nil {
+			log.Warn("Failed to mask conversation, not storing it: %v", err)
+			return
+		}
+		if err := h.redactor.Copy(resp, &redactedResp); err != nil {
+			log.Warn("Failed to mask conversation, not storing it: %v", err)
+			return
+		}
+		req, resp = redactedReq, &redactedResp
+	}
+	exchange, err := conversation.NewExchange(requestID, h.conversationSessionID, model, req, resp)
+	if err == nil {
+		err = h.conversations.Add(exchange)
+	}
+	if err != nil {
+		log.Warn("Failed to store conversation for search: %v", err)
+	}
+}
+
+// conversationSearchResponse is the JSON body served by HandleConversations
+type conversationSearchResponse struct {
+	Results []conversation.SearchResult `json:"results"`
+}
+
+// HandleConversations serves GET /admin/conversations?q=...&tool=...&request_id=...&session=...&limit=...
+// q terms must all appear in the exchange (a trailing * matches a prefix);
+// results are newest first.
+func (h *Handler) HandleConversations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.conversations == nil {
+		http.Error(w, "Conversation search is disabled", http.StatusNotFound)
+		return
+	}
+
+	params := r.URL.Query()
+	query := conversation.Query{
+		Text:      params.Get("q"),
+		Tool:      params.Get("tool"),
+		RequestID: params.Get("request_id"),
+		SessionID: params.Get("session"),
+	}
+	if limit := params.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxConversationSearchLimit {
+			http.Error(w, "limit must be between 1 and "+strconv.Itoa(maxConversationSearchLimit), http.StatusBadRequest)
+			return
+		}
+		query.Limit = n
+	}
+
+	results, err := h.conversations.Search(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(conversationSearchResponse{Results: results}); err != nil {
+		http.Error(w, "Failed to encode conversations", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,82 @@
+package proxy
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/correction"
+	"claude-proxy/types"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// NoCorrectionHeader skips tool necessity detection, ExitPlanMode filtering and
+// validation, validation feedback and tool correction for a request when
+// NO_CORRECTION_HEADER_ENABLED allows it, so the model's raw tool calls can be
+// compared with what the proxy makes of them
+const NoCorrectionHeader = "x-proxy-no-correction"
+
+// correctionBypassed reports whether the request asked for, and may have, its
+// tool calls left uncorrected
+func (h *Handler) correctionBypassed(header http.Header) bool {
+	return h.config.NoCorrectionHeaderEnabled && strings.EqualFold(strings.TrimSpace(header.Get(NoCorrectionHeader)), "true")
+}
+
+// noCorrectionKey marks a request context whose correction was bypassed
+type noCorrectionKey struct{}
+
+// withoutCorrection marks ctx so the request transform also skips the
+// correction model's ExitPlanMode analysis
+func withoutCorrection(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCorrectionKey{}, true)
+}
+
+// correctionBypassedIn reports whether ctx was marked by withoutCorrection
+func correctionBypassedIn(ctx context.Context) bool {
+	bypassed, _ := ctx.Value(noCorrectionKey{}).(bool)
+	return bypassed
+}
+
+// correctionContext bounds tool correction by CORRECTION_BUDGET_MS so a slow
+// correction model cannot hold the response for minutes
+func (h *Handler) correctionContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if h.config.CorrectionBudgetMs <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, time.Duration(h.config.CorrectionBudgetMs)*time.Millisecond)
+}
+
+// correctionBudgetExhausted reports whether correction stopped because its
+// budget ran out, as opposed to the client going away
+func correctionBudgetExhausted(requestCtx, correctionCtx context.Context) bool {
+	return requestCtx.Err() == nil && errors.Is(correctionCtx.Err(), context.DeadlineExceeded)
+}
+
+// blockInvalidToolCalls replaces tool calls that still fail validation with a
+// text block so the client never executes them. Returns the new content and
+// the number of calls blocked.
+func (h *Handler) blockInvalidToolCalls(ctx context.Context, content []types.Content, availableTools []types.Tool) ([]types.Content, int) {
+	blocked := 0
+	result := make([]types.Content, 0, len(content))
+	for _, item := range content {
+		if item.Type != "tool_use" {
+			result = append(result, item)
+			continue
+		}
+		validation := h.correctionService.ValidateToolCall(ctx, item, availableTools)
+		if validation.IsValid {
+			result = append(result, item)
+			continue
+		}
+		blocked++
+		result = append(result, correction.BlockedToolCallNotice(item.Name, "it was still invalid when the correction budget ran out", validation))
+	}
+	return result, blocked
+}
+
+// correctionBudgetBlocks reports whether calls left invalid by an exhausted
+// budget should be blocked rather than forwarded
+func (h *Handler) correctionBudgetBlocks() bool {
+	return h.config.CorrectionBudgetPolicy == config.CorrectionBudgetBlock
+}
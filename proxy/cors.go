@@ -0,0 +1,55 @@
+package proxy
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/internal"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// WithCORS adds CORS headers for allowed browser origins and answers
+// preflight requests before they reach next. Requests without an Origin
+// header, and all requests while CORS is disabled, pass through unchanged.
+func WithCORS(cors config.CORSConfig, next http.HandlerFunc) http.HandlerFunc {
+	if !cors.Enabled() {
+		return next
+	}
+
+	allowMethods := strings.Join(cors.AllowedMethods, ", ")
+	allowHeaders := strings.Join(cors.AllowedHeaders, ", ")
+	maxAge := strconv.Itoa(cors.MaxAgeSeconds)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		allowed := cors.AllowsOrigin(origin)
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		if preflight {
+			if !allowed {
+				http.Error(w, "Origin not allowed", http.StatusForbidden)
+				return
+			}
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Methods", allowMethods)
+			w.Header().Set("Access-Control-Allow-Headers", allowHeaders)
+			w.Header().Set("Access-Control-Max-Age", maxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if allowed {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Expose-Headers", internal.RequestIDHeader)
+		}
+		next(w, r)
+	}
+}
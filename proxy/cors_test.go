@@ -0,0 +1,88 @@
+package proxy
+
+import (
+	"claude-proxy/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func corsTestHandler(called *bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		*called = true
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// TestWithCORSPreflight tests preflight answers for allowed and rejected origins
+func TestWithCORSPreflight(t *testing.T) {
+	cors := config.DefaultCORSConfig()
+	cors.AllowedOrigins = []string{"https://app.example.com"}
+
+	called := false
+	handler := WithCORS(cors, corsTestHandler(&called))
+
+	req := httptest.NewRequest(http.MethodOptions, "/v1/messages", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	req.Header.Set("Access-Control-Request-Headers", "content-type,x-api-key")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 for preflight, got %d", rec.Code)
+	}
+	if called {
+		t.Error("Preflight must not reach the proxy handler")
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("Unexpected Access-Control-Allow-Origin: %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Methods"); got != "POST, OPTIONS" {
+		t.Errorf("Unexpected Access-Control-Allow-Methods: %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Max-Age"); got != "600" {
+		t.Errorf("Unexpected Access-Control-Max-Age: %q", got)
+	}
+
+	req.Header.Set("Origin", "https://evil.example.com")
+	rec = httptest.NewRecorder()
+	handler(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a disallowed origin, got %d", rec.Code)
+	}
+	if rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Error("Disallowed origins must not get CORS headers")
+	}
+}
+
+// TestWithCORSRequests tests headers on actual requests and pass-through cases
+func TestWithCORSRequests(t *testing.T) {
+	cors := config.DefaultCORSConfig()
+	cors.AllowedOrigins = []string{"*"}
+
+	called := false
+	handler := WithCORS(cors, corsTestHandler(&called))
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+	req.Header.Set("Origin", "http://localhost:5173")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if !called || rec.Code != http.StatusOK {
+		t.Errorf("Expected the request to reach the handler, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "http://localhost:5173" {
+		t.Errorf("Unexpected Access-Control-Allow-Origin: %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Expose-Headers"); got != "X-Request-ID" {
+		t.Errorf("Unexpected Access-Control-Expose-Headers: %q", got)
+	}
+
+	// Without configured origins the handler is used as is
+	called = false
+	rec = httptest.NewRecorder()
+	WithCORS(config.DefaultCORSConfig(), corsTestHandler(&called))(rec, req)
+	if !called || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Error("Expected no CORS headers while CORS is disabled")
+	}
+}
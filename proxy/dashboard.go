@@ -0,0 +1,26 @@
+package proxy
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// dashboardHTML is the admin dashboard page. It has no dependencies and reads
+// everything from /stats, so it works without a Grafana/Loki stack.
+//
+//go:embed dashboard/index.html
+var dashboardHTML []byte
+
+// HandleDashboard serves the embedded admin dashboard: request throughput,
+// endpoint health, recent corrections and recent errors
+func HandleDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(dashboardHTML)
+}
@@ -0,0 +1,88 @@
+package proxy
+
+import (
+	"claude-proxy/types"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DeadlineHeader lets clients bound the whole pipeline of a request, upstream
+// calls and tool correction included, to a number of milliseconds
+const DeadlineHeader = "X-Proxy-Deadline-Ms"
+
+// maxDeadlineMs is the longest deadline a time.Duration can hold
+const maxDeadlineMs = math.MaxInt64 / int64(time.Millisecond)
+
+// requestDeadline parses DeadlineHeader; zero means the request has none
+func requestDeadline(header http.Header) (time.Duration, error) {
+	value := strings.TrimSpace(header.Get(DeadlineHeader))
+	if value == "" {
+		return 0, nil
+	}
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || ms <= 0 {
+		return 0, fmt.Errorf("%s must be a positive number of milliseconds, got: %s", DeadlineHeader, value)
+	}
+	if ms > maxDeadlineMs {
+		return 0, fmt.Errorf("%s must be at most %d milliseconds, got: %s", DeadlineHeader, maxDeadlineMs, value)
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// deadlineExceeded reports whether ctx ended because of its deadline rather
+// than the client going away
+func deadlineExceeded(ctx context.Context) bool {
+	return errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
+
+// deadlineAccounting is what a request got done before its deadline passed,
+// returned with DEADLINE_EXCEEDED so automation can account for the spend
+type deadlineAccounting struct {
+	DeadlineMs int64        `json:"deadline_ms"`
+	ElapsedMs  int64        `json:"elapsed_ms"`
+	Stage      string       `json:"stage"`           // Pipeline stage that was cut short: "upstream", "correction" or "tool_policies"
+	Completed  []string     `json:"completed"`       // Stages finished in time
+	Usage      *types.Usage `json:"usage,omitempty"` // Tokens the backend already generated, nil when no response arrived
+}
+
+// newDeadlineAccounting describes a request started at start whose deadline
+// passed during stage
+func newDeadlineAccounting(deadline time.Duration, start time.Time, stage string, completed ...string) deadlineAccounting {
+	if completed == nil {
+		completed = []string{}
+	}
+	return deadlineAccounting{
+		DeadlineMs: deadline.Milliseconds(),
+		ElapsedMs:  time.Since(start).Milliseconds(),
+		Stage:      stage,
+		Completed:  completed,
+	}
+}
+
+// writeDeadlineExceeded sends a 504 DEADLINE_EXCEEDED error carrying the accounting
+func writeDeadlineExceeded(w http.ResponseWriter, accounting deadlineAccounting) {
+	message := fmt.Sprintf("request deadline of %dms exceeded during %s", accounting.DeadlineMs, accounting.Stage)
+	var resp struct {
+		errorResponse
+		Accounting deadlineAccounting `json:"accounting"`
+	}
+	resp.Type = "error"
+	resp.Error.Type = anthropicErrorType(http.StatusGatewayTimeout)
+	resp.Error.Code = CodeDeadlineExceeded
+	resp.Error.Message = message
+	resp.Accounting = accounting
+	if recorder, ok := w.(*statusRecorder); ok {
+		recorder.recordError(CodeDeadlineExceeded, message)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusGatewayTimeout)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,150 @@
+package proxy
+
+import (
+	"claude-proxy/types"
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ProxyDebugHeader requests a per-request debug trace in the response when
+// PROXY_DEBUG_HEADER_ENABLED allows it
+const ProxyDebugHeader = "x-proxy-debug"
+
+// debugTraceKey stores the *DebugTrace of a request in its context
+type debugTraceKey struct{}
+
+// DebugTrace is the proxy_debug block returned for requests sent with
+// x-proxy-debug: true. It records what the proxy did to the request: the
+// transformation audit trail, endpoint attempts, tool correction outcome and
+// per-stage timings.
+//
+// All methods are nil-safe so stages can record unconditionally; they are
+// no-ops when tracing was not requested.
+type DebugTrace struct {
+	mu       sync.Mutex
+	start    time.Time
+	returned bool // Sent back to the client; traces kept only for the request history are not
+
+	Steps      []string           `json:"steps"`
+	Endpoint   string             `json:"endpoint,omitempty"` // Endpoint that produced the response
+	Attempts   []EndpointAttempt  `json:"attempts,omitempty"`
+	Correction *CorrectionTrace   `json:"correction,omitempty"`
+	TimingsMs  map[string]float64 `json:"timings_ms"`
+}
+
+// EndpointAttempt is one upstream call made for the request
+type EndpointAttempt struct {
+	Endpoint   string  `json:"endpoint"`
+	DurationMs float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// CorrectionTrace summarizes tool correction for the response
+type CorrectionTrace struct {
+	ToolCalls      int    `json:"tool_calls"`
+	Changed        bool   `json:"changed"`
+	BudgetExceeded bool   `json:"budget_exceeded,omitempty"` // CORRECTION_BUDGET_MS ran out
+	Error          string `json:"error,omitempty"`
+}
+
+// newDebugTrace starts a trace for a request received now; returned traces are
+// added to the response
+func newDebugTrace(returned bool) *DebugTrace {
+	return &DebugTrace{start: time.Now(), returned: returned, Steps: []string{}, TimingsMs: make(map[string]float64)}
+}
+
+// isReturned reports whether the client asked for the trace
+func (t *DebugTrace) isReturned() bool {
+	return t != nil && t.returned
+}
+
+// withDebugTrace attaches a trace to the request context
+func withDebugTrace(ctx context.Context, trace *DebugTrace) context.Context {
+	return context.WithValue(ctx, debugTraceKey{}, trace)
+}
+
+// debugTraceFrom returns the request's trace, or nil when tracing is off
+func debugTraceFrom(ctx context.Context) *DebugTrace {
+	trace, _ := ctx.Value(debugTraceKey{}).(*DebugTrace)
+	return trace
+}
+
+// Step appends an entry to the transformation audit trail
+func (t *DebugTrace) Step(format string, args ...interface{}) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Steps = append(t.Steps, fmt.Sprintf(format, args...))
+}
+
+// Time records how long a named stage took
+func (t *DebugTrace) Time(stage string, since time.Time) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.TimingsMs[stage] = durationMs(time.Since(since))
+}
+
+// Attempt records an upstream call; a successful call becomes the chosen endpoint
+func (t *DebugTrace) Attempt(endpoint string, since time.Time, err error) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	attempt := EndpointAttempt{Endpoint: maskEndpointForTrace(endpoint), DurationMs: durationMs(time.Since(since))}
+	if err != nil {
+		attempt.Error = err.Error()
+	} else {
+		t.Endpoint = attempt.Endpoint
+	}
+	t.Attempts = append(t.Attempts, attempt)
+}
+
+// SetCorrection records the tool correction outcome
+func (t *DebugTrace) SetCorrection(correction CorrectionTrace) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Correction = &correction
+}
+
+// finish stamps the total time; the trace is ready to be serialized
+func (t *DebugTrace) finish() *DebugTrace {
+	t.Time("total", t.start)
+	return t
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// maskEndpointForTrace removes credentials embedded in an endpoint URL
+func maskEndpointForTrace(endpoint string) string {
+	parsed, err := url.Parse(endpoint)
+	if err != nil || parsed.User == nil {
+		return endpoint
+	}
+	parsed.User = url.User("***")
+	return parsed.String()
+}
+
+// countToolCalls counts tool_use items in response content
+func countToolCalls(content []types.Content) int {
+	count := 0
+	for _, item := range content {
+		if item.Type == "tool_use" {
+			count++
+		}
+	}
+	return count
+}
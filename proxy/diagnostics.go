@@ -0,0 +1,110 @@
+package proxy
+
+import (
+	"bytes"
+	"claude-proxy/logger"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"time"
+)
+
+// WriteDiagnostics writes a plain-text report of what the proxy is doing right
+// now: requests in flight and their age, endpoint circuits, scheduler and
+// approval queue depths, and the stack of every goroutine
+func (h *Handler) WriteDiagnostics(w io.Writer) error {
+	now := time.Now()
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "=== simple-proxy diagnostic dump %s ===\n", now.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&buf, "goroutines: %d\n", runtime.NumGoroutine())
+
+	inFlight := h.inFlight.list()
+	fmt.Fprintf(&buf, "\n--- in-flight requests (%d) ---\n", len(inFlight))
+	for _, req := range inFlight {
+		fmt.Fprintf(&buf, "%s model=%s stream=%t tools=%d remote=%s age=%s\n",
+			req.RequestID, req.Model, req.Stream, req.Tools, req.RemoteAddr, now.Sub(req.StartedAt).Round(time.Millisecond))
+	}
+
+	fmt.Fprintf(&buf, "\n--- endpoints ---\n")
+	if h.config.HealthManager != nil {
+		for _, endpoint := range h.config.HealthManager.Snapshot() {
+			state := "closed"
+			if endpoint.CircuitOpen {
+				state = "open until " + endpoint.NextRetryTime.UTC().Format(time.RFC3339)
+			}
+			fmt.Fprintf(&buf, "%s circuit=%s failures=%d requests=%d success_ewma=%.2f latency_ewma_ms=%.0f",
+				endpoint.URL, state, endpoint.FailureCount, endpoint.TotalRequests, endpoint.SuccessEWMA, endpoint.LatencyEWMAMs)
+			if endpoint.LastError != "" {
+				fmt.Fprintf(&buf, " last_error=%q", endpoint.LastError)
+			}
+			buf.WriteString("\n")
+		}
+	}
+
+	scheduler := h.scheduler.snapshot()
+	fmt.Fprintf(&buf, "\n--- queues ---\n")
+	fmt.Fprintf(&buf, "scheduler: active=%d limit=%d queued_interactive=%d queued_background=%d\n",
+		scheduler.Active, scheduler.Limit, scheduler.QueuedInteractive, scheduler.QueuedBackground)
+	fmt.Fprintf(&buf, "pending approvals: %d\n", len(h.approvals.list()))
+
+	fmt.Fprintf(&buf, "\n--- goroutine stacks ---\n")
+	buf.Write(goroutineStacks())
+	buf.WriteString("\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// goroutineStacks returns the stacks of all goroutines, growing the buffer
+// until runtime.Stack no longer fills it
+func goroutineStacks() []byte {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// DumpDiagnostics appends WriteDiagnostics' report to DIAGNOSTIC_DUMP_FILE,
+// or the process log when none is configured. Triggered by SIGUSR1.
+func (h *Handler) DumpDiagnostics() error {
+	destination := "log"
+	var err error
+	if path := h.config.DiagnosticDumpFile; path != "" {
+		destination = path
+		var file *os.File
+		file, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			err = fmt.Errorf("failed to open diagnostic dump file %s: %v", path, err)
+		} else {
+			err = h.WriteDiagnostics(file)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}
+	} else {
+		err = h.WriteDiagnostics(log.Writer())
+	}
+
+	if h.obsLogger != nil {
+		if err != nil {
+			h.obsLogger.Error(logger.ComponentProxy, logger.CategoryError, "", "Failed to write diagnostic dump", map[string]interface{}{
+				"destination": destination,
+				"error":       err.Error(),
+			})
+		} else {
+			h.obsLogger.Info(logger.ComponentProxy, logger.CategoryRequest, "", "Wrote diagnostic dump", map[string]interface{}{
+				"destination": destination,
+				"in_flight":   len(h.inFlight.list()),
+				"goroutines":  runtime.NumGoroutine(),
+			})
+		}
+	}
+	return err
+}
@@ -0,0 +1,53 @@
+package proxy
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/types"
+	"encoding/json"
+)
+
+// duplicateToolCall reports whether call repeats previous with the same name
+// and input. Inputs are compared as JSON, which sorts object keys.
+func duplicateToolCall(previous, call types.Content) bool {
+	if previous.Name != call.Name {
+		return false
+	}
+	previousInput, err := json.Marshal(previous.Input)
+	if err != nil {
+		return false
+	}
+	input, err := json.Marshal(call.Input)
+	return err == nil && string(previousInput) == string(input)
+}
+
+// suppressDuplicateToolCalls applies DUPLICATE_TOOL_CALL_POLICY to tool_use
+// blocks that repeat the tool_use block before them. Returns the content and
+// the names of the duplicates found; with the drop policy they are removed.
+func suppressDuplicateToolCalls(content []types.Content, policy string) ([]types.Content, []string) {
+	if policy == config.DuplicateToolCallKeep {
+		return content, nil
+	}
+
+	var duplicates []string
+	kept := make([]types.Content, 0, len(content))
+	var previous *types.Content
+	for i := range content {
+		item := content[i]
+		if item.Type != "tool_use" {
+			kept = append(kept, item)
+			continue
+		}
+		if previous != nil && duplicateToolCall(*previous, item) {
+			duplicates = append(duplicates, item.Name)
+			if policy == config.DuplicateToolCallDrop {
+				continue
+			}
+		}
+		previous = &content[i]
+		kept = append(kept, item)
+	}
+	if len(duplicates) == 0 || policy != config.DuplicateToolCallDrop {
+		return content, duplicates
+	}
+	return kept, duplicates
+}
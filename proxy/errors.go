@@ -0,0 +1,127 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// ErrorCode is a stable identifier for a class of proxy failure. Codes are
+// returned in error responses and attached to logs so issue reports can cite
+// a code instead of log fragments.
+type ErrorCode string
+
+const (
+	CodeInvalidRequest          ErrorCode = "INVALID_REQUEST"           // Body could not be read or parsed
+	CodeInvalidConversation     ErrorCode = "INVALID_CONVERSATION"      // Conversation structure rejected (e.g. no user message)
+	CodeRequestTransformFailed  ErrorCode = "REQUEST_TRANSFORM_FAILED"  // Anthropic -> OpenAI transformation failed
+	CodeHarmonyStrictReject     ErrorCode = "HARMONY_STRICT_REJECT"     // Malformed Harmony content rejected by HARMONY_STRICT_MODE
+	CodeUpstreamTimeout         ErrorCode = "UPSTREAM_TIMEOUT"          // Provider did not respond within the request timeout
+	CodeUpstreamUnreachable     ErrorCode = "UPSTREAM_UNREACHABLE"      // Connection to the provider failed
+	CodeUpstreamStatus          ErrorCode = "UPSTREAM_ERROR_STATUS"     // Provider returned a non-200 status
+	CodeUpstreamInvalidResponse ErrorCode = "UPSTREAM_INVALID_RESPONSE" // Provider response could not be read or parsed
+	CodeAllEndpointsDown        ErrorCode = "ALL_ENDPOINTS_DOWN"        // Every endpoint for the model failed or is circuit-open
+	CodeResponseTransformFailed ErrorCode = "RESPONSE_TRANSFORM_FAILED" // OpenAI -> Anthropic transformation failed
+	CodeCorrectionFailed        ErrorCode = "CORRECTION_FAILED"         // Tool correction failed; original tool calls were used
+	CodeResponseEncodingFailed  ErrorCode = "RESPONSE_ENCODING_FAILED"  // Response could not be serialized
+	CodeMethodNotAllowed        ErrorCode = "METHOD_NOT_ALLOWED"        // Unsupported HTTP method
+	CodeBatchNotFound           ErrorCode = "BATCH_NOT_FOUND"           // Unknown message batch ID
+	CodeBatchNotEnded           ErrorCode = "BATCH_NOT_ENDED"           // Batch results requested before processing ended
+	CodeBudgetExceeded          ErrorCode = "BUDGET_EXCEEDED"           // Session token or cost budget used up
+	CodeSpendLimitExceeded      ErrorCode = "SPEND_LIMIT_EXCEEDED"      // Client key reached its daily or monthly spend limit
+	CodeModelNotAllowed         ErrorCode = "MODEL_NOT_ALLOWED"         // Client key may not use the mapped model (CLIENT_MODEL_ACCESS)
+	CodeUnsupportedContent      ErrorCode = "UNSUPPORTED_CONTENT"       // Request content the backend model cannot read (e.g. images without vision)
+	CodePromptTooLong           ErrorCode = "PROMPT_TOO_LONG"           // Prompt exceeds the backend model's context window
+	CodeDeadlineExceeded        ErrorCode = "DEADLINE_EXCEEDED"         // X-Proxy-Deadline-Ms passed before the response was ready
+	CodeInternal                ErrorCode = "INTERNAL_ERROR"            // Unclassified failure
+)
+
+// ProxyError is an error tagged with an ErrorCode
+type ProxyError struct {
+	Code ErrorCode
+	Err  error
+}
+
+// newProxyError creates a ProxyError with a formatted message
+func newProxyError(code ErrorCode, format string, args ...interface{}) *ProxyError {
+	return &ProxyError{Code: code, Err: fmt.Errorf(format, args...)}
+}
+
+func (e *ProxyError) Error() string {
+	return fmt.Sprintf("[%s] %v", e.Code, e.Err)
+}
+
+func (e *ProxyError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCodeOf returns the code attached to err, falling back to fallback for
+// untagged errors. Untagged timeouts are always reported as UPSTREAM_TIMEOUT.
+func ErrorCodeOf(err error, fallback ErrorCode) ErrorCode {
+	var proxyErr *ProxyError
+	if errors.As(err, &proxyErr) {
+		return proxyErr.Code
+	}
+	if isTimeout(err) {
+		return CodeUpstreamTimeout
+	}
+	return fallback
+}
+
+// isTimeout reports whether err is a deadline or network timeout
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// errorResponse mirrors the Anthropic error envelope with an added proxy error code
+type errorResponse struct {
+	Type  string `json:"type"`
+	Error struct {
+		Type    string    `json:"type"`
+		Code    ErrorCode `json:"code"`
+		Message string    `json:"message"`
+	} `json:"error"`
+}
+
+// writeProxyError sends an Anthropic-style error body carrying the proxy error code
+func writeProxyError(w http.ResponseWriter, status int, code ErrorCode, message string) {
+	var resp errorResponse
+	resp.Type = "error"
+	resp.Error.Type = anthropicErrorType(status)
+	resp.Error.Code = code
+	resp.Error.Message = message
+	if recorder, ok := w.(*statusRecorder); ok {
+		recorder.recordError(code, message)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// anthropicErrorType maps an HTTP status to the Anthropic error type clients expect
+func anthropicErrorType(status int) string {
+	switch {
+	case status == http.StatusNotFound:
+		return "not_found_error"
+	case status == http.StatusPaymentRequired:
+		return "billing_error"
+	case status == http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case status == http.StatusForbidden:
+		return "permission_error"
+	case status >= 400 && status < 500:
+		return "invalid_request_error"
+	case status == http.StatusServiceUnavailable:
+		return "overloaded_error"
+	default:
+		return "api_error"
+	}
+}
@@ -2,17 +2,23 @@ package proxy
 
 import (
 	"bytes"
+	"claude-proxy/circuitbreaker"
 	"claude-proxy/config"
+	"claude-proxy/conversation"
 	"claude-proxy/correction"
+	"claude-proxy/internal"
+	"claude-proxy/journal"
 	"claude-proxy/logger"
 	"claude-proxy/loop"
+	"claude-proxy/parser"
+	"claude-proxy/stats"
 	"claude-proxy/types"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -21,74 +27,234 @@ import (
 type Handler struct {
 	config                *config.Config
 	correctionService     *correction.Service
+	corrector             correction.Corrector // Backend that repairs invalid tool calls
 	loggerConfig          logger.LoggerConfig
 	conversationSessionID string
 	loopDetector          *loop.LoopDetector
 	obsLogger             *logger.ObservabilityLogger
+	stats                 *stats.Collector
+	inFlight              *inFlightRequests
+	conversations         *conversation.Store      // Indexed copy of logged conversations, nil when search is off
+	approvals             *approvalQueue           // Tool calls held by require_approval policies
+	redactor              *logger.Redactor         // Masks conversations before they are stored, nil when masking is off
+	budgets               *sessionBudgets          // Spend per session against SESSION_BUDGET_TOKENS/USD
+	spendLimits           *spendLimitSet           // Daily/monthly spend limits per client key
+	streamLimiters        *streamRateLimiters      // Streaming rate of each client key, shared by its streams
+	scheduler             *requestScheduler        // Bounds upstream calls, interactive before background
+	alertSink             circuitbreaker.AlertSink // ALERT_WEBHOOK_URL, nil when alerting is off
+	harmonyTokens         *parser.TokenRecognizer  // HARMONY_*_TOKEN dialect, nil falls back to the parser default
+	history               *requestHistory          // Recent requests for GET /admin/requests, nil when REQUEST_HISTORY_SIZE is 0
+	journal               *journal.Journal         // Crash-safe record of accepted requests, nil when REQUEST_JOURNAL_ENABLED is off
 }
 
 // NewHandler creates a new proxy handler
 func NewHandler(cfg *config.Config, obsLogger *logger.ObservabilityLogger, conversationSessionID string) *Handler {
+	correctionService := correction.NewService(
+		cfg,
+		cfg.ToolCorrectionAPIKey,
+		cfg.ToolCorrectionEnabled,
+		cfg.CorrectionModel,
+		cfg.DisableToolCorrectionLogging,
+		obsLogger,
+	)
+	if err := correctionService.RegisterCustomTools(cfg.CustomTools); err != nil && obsLogger != nil {
+		obsLogger.Warn(logger.ComponentToolCorrection, logger.CategoryWarning, "", "Failed to register custom tool validators", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	if err := correctionService.SetAdditionalPropertiesPolicy(cfg.AdditionalProperties); err != nil && obsLogger != nil {
+		obsLogger.Warn(logger.ComponentToolCorrection, logger.CategoryWarning, "", "Failed to apply additional properties policy", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	correctionService.SetRuleLearner(correction.NewRuleLearner(cfg.CorrectionLearnThreshold, cfg.CorrectionAutoPromote))
+
+	if prompts, err := correction.LoadPrompts(cfg.PromptsDir); err != nil {
+		if obsLogger != nil {
+			obsLogger.Warn(logger.ComponentToolCorrection, logger.CategoryWarning, "", "Failed to load prompt templates, using built-in prompts", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+	} else {
+		correctionService.SetPrompts(prompts)
+	}
+
+	corrector, err := correction.NewCorrector(cfg.CorrectionBackend, correctionService, cfg.CorrectionRemoteURL,
+		cfg.ToolCorrectionAPIKey, time.Duration(cfg.CorrectionRemoteTimeoutSeconds)*time.Second)
+	if err != nil {
+		if obsLogger != nil {
+			obsLogger.Warn(logger.ComponentToolCorrection, logger.CategoryWarning, "", "Invalid correction backend, using the correction model", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+		corrector = correctionService
+	}
+
+	var alertSink circuitbreaker.AlertSink
+	if cfg.AlertWebhookURL != "" {
+		alertSink = circuitbreaker.NewWebhookAlertSink(cfg.AlertWebhookURL)
+	}
+
+	harmonyTokens, err := parser.NewTokenRecognizerWithTokens(cfg.HarmonyTokens)
+	if err != nil && obsLogger != nil {
+		obsLogger.Warn(logger.ComponentHarmony, logger.CategoryWarning, "", "Invalid Harmony tokens, using the default tokens", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
 	return &Handler{
-		config: cfg,
-		correctionService: correction.NewService(
-			cfg,
-			cfg.ToolCorrectionAPIKey,
-			cfg.ToolCorrectionEnabled,
-			cfg.CorrectionModel,
-			cfg.DisableToolCorrectionLogging,
-			obsLogger,
-		),
+		config:                cfg,
+		correctionService:     correctionService,
+		corrector:             corrector,
 		loggerConfig:          logger.NewConfigAdapter(cfg),
 		conversationSessionID: conversationSessionID,
-		loopDetector:          loop.NewLoopDetector(),
+		loopDetector:          loop.NewLoopDetectorWithThreshold(cfg.LoopDetectionThreshold),
 		obsLogger:             obsLogger,
+		stats:                 stats.NewCollector(),
+		inFlight:              newInFlightRequests(),
+		approvals:             newApprovalQueue(),
+		redactor:              logger.NewConversationRedactor(cfg),
+		budgets:               newSessionBudgets(),
+		spendLimits:           &spendLimitSet{limits: cfg.SpendLimits},
+		streamLimiters:        newStreamRateLimiters(),
+		scheduler:             newRequestScheduler(cfg.MaxConcurrentRequests),
+		alertSink:             alertSink,
+		harmonyTokens:         harmonyTokens,
+		history:               newRequestHistory(cfg.RequestHistorySize),
 	}
 }
 
 // HandleAnthropicRequest handles incoming Anthropic format requests
 func (h *Handler) HandleAnthropicRequest(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeProxyError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Honor the client's X-Request-ID (or generate one) and echo it so callers can correlate
+	requestID := requestIDFromHeader(r.Header)
+	w.Header().Set(internal.RequestIDHeader, requestID)
+
+	// Record request count, latency and outcome for /stats
+	startTime := time.Now()
+	statsModel := "unknown"
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	w = recorder
+	record := h.history.begin(requestID, startTime)
+	defer func() {
+		failed := recorder.status >= http.StatusBadRequest
+		h.stats.RecordRequest(statsModel, time.Since(startTime), failed)
+		if failed {
+			h.stats.RecordErrorEvent(stats.Event{
+				RequestID: requestID,
+				Model:     statsModel,
+				Kind:      string(recorder.errorCode),
+				Status:    recorder.status,
+				Detail:    recorder.errorMessage,
+			})
+		}
+		if record != nil {
+			record.finish(recorder.status, recorder.errorCode, recorder.errorMessage)
+			h.history.add(record)
+		}
+	}()
+
+	// Validate anthropic-version; behavior switches read it back from the context
+	apiVersion, knownVersion, err := anthropicVersionFromHeader(r.Header)
+	if err != nil {
+		if h.obsLogger != nil {
+			h.obsLogger.Error(logger.ComponentProxy, logger.CategoryError, requestID, "Invalid anthropic-version header", map[string]interface{}{
+				"error":      err.Error(),
+				"error_code": CodeInvalidRequest,
+			})
+		}
+		writeProxyError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return
+	}
+	deadline, err := requestDeadline(r.Header)
+	if err != nil {
+		writeProxyError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
 		return
 	}
 
 	// Read request body
-	body, err := io.ReadAll(r.Body)
+	bodyBuf, err := readPooled(r.Body)
 	if err != nil {
-		// Early error - no context yet
+		// Early error - no logger context yet
 		if h.obsLogger != nil {
-			h.obsLogger.Error(logger.ComponentProxy, logger.CategoryError, "", "Failed to read request body", map[string]interface{}{"error": err.Error()})
+			h.obsLogger.Error(logger.ComponentProxy, logger.CategoryError, requestID, "Failed to read request body", map[string]interface{}{
+				"error":      err.Error(),
+				"error_code": CodeInvalidRequest,
+			})
 		}
-		http.Error(w, "Failed to read request", http.StatusBadRequest)
+		writeProxyError(w, http.StatusBadRequest, CodeInvalidRequest, "Failed to read request")
 		return
 	}
 	defer r.Body.Close()
+	// Decoding copies everything it keeps, so the buffer can be recycled once the request is handled
+	defer putBuffer(bodyBuf)
+
+	// Normalize shapes the typed request rejects and collect fields it does not model
+	body, unknownFields := sanitizeRequestBody(bodyBuf.Bytes())
 
 	// Parse Anthropic request
 	var anthropicReq types.AnthropicRequest
 	if err := json.Unmarshal(body, &anthropicReq); err != nil {
-		// Early error - no context yet
+		// Early error - no logger context yet
 		if h.obsLogger != nil {
-			h.obsLogger.Error(logger.ComponentProxy, logger.CategoryError, "", "Invalid JSON in request", map[string]interface{}{
-				"error":    err.Error(),
-				"raw_body": string(body),
+			h.obsLogger.Error(logger.ComponentProxy, logger.CategoryError, requestID, "Invalid JSON in request", map[string]interface{}{
+				"error":      err.Error(),
+				"error_code": CodeInvalidRequest,
+				"raw_body":   string(body),
 			})
 		}
-		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		writeProxyError(w, http.StatusBadRequest, CodeInvalidRequest, "Invalid request format")
 		return
 	}
 
 	// Create context with request ID for tracing
-	requestID := generateRequestID()
 	ctx := withRequestID(r.Context(), requestID)
+	ctx = internal.WithAnthropicVersion(ctx, apiVersion)
+	betas := parseAnthropicBetas(r.Header)
+	ctx = internal.WithAnthropicBetas(ctx, betas)
+
+	// X-Proxy-Deadline-Ms bounds everything from here on, upstream calls and correction included
+	if deadline > 0 {
+		var cancelDeadline context.CancelFunc
+		ctx, cancelDeadline = context.WithDeadline(ctx, startTime.Add(deadline))
+		defer cancelDeadline()
+	}
+
+	// Collect a debug trace for the response when the client asks and config allows it,
+	// and for the request history
+	record.SetRequest(anthropicReq)
+	debugRequested := h.config.ProxyDebugHeaderEnabled && strings.EqualFold(r.Header.Get(ProxyDebugHeader), "true")
+	var trace *DebugTrace
+	if debugRequested || record != nil {
+		trace = newDebugTrace(debugRequested)
+		ctx = withDebugTrace(ctx, trace)
+		record.SetTrace(trace)
+	}
 
 	// Set up logger context - request ID already set by withRequestID above
 	loggerInstance := logger.New(ctx, h.loggerConfig)
-
-	// Log conversation if enabled
-	if h.obsLogger != nil && h.conversationSessionID != "" {
-		h.obsLogger.LokiLogger.LogRequest(ctx, requestID, h.conversationSessionID, anthropicReq)
+	if !knownVersion {
+		loggerInstance.Warn("Unrecognized anthropic-version %s, using latest known behavior", apiVersion)
+	} else {
+		loggerInstance.Debug("anthropic-version: %s", apiVersion)
+	}
+	logAnthropicBetas(betas, loggerInstance)
+	logUnknownFields(unknownFields, loggerInstance)
+
+	// x-proxy-no-correction leaves the model's tool calls as they are
+	bypassCorrection := h.correctionBypassed(r.Header)
+	correctionEnabled := h.config.ToolCorrectionEnabled && !bypassCorrection
+	if bypassCorrection {
+		ctx = withoutCorrection(ctx)
+		trace.Step("tool correction disabled by %s", NoCorrectionHeader)
+		loggerInstance.Info("🔧 Tool correction disabled for this request by %s", NoCorrectionHeader)
 	}
 
 	originalModel := anthropicReq.Model
@@ -99,8 +265,52 @@ func (h *Handler) HandleAnthropicRequest(w http.ResponseWriter, r *http.Request)
 		loggerInstance.WithModel(originalModel).Warn("Empty model provided, using fallback: %s (server workaround)", originalModel)
 	}
 
+	// Log conversation if enabled and the model and path pass the conversation log filter
+	logConversation := h.obsLogger != nil && h.conversationSessionID != "" &&
+		h.config.ConversationLogFilter.Allows(originalModel, r.URL.Path)
+	if logConversation {
+		h.obsLogger.LokiLogger.LogRequest(ctx, requestID, h.conversationSessionID, anthropicReq)
+	}
+
+	statsModel = originalModel
+	setAccessLogModel(r.Context(), originalModel)
+	defer h.inFlight.add(InFlightRequest{
+		RequestID:  requestID,
+		Model:      originalModel,
+		Stream:     anthropicReq.Stream,
+		Tools:      len(anthropicReq.Tools),
+		RemoteAddr: r.RemoteAddr,
+		StartedAt:  startTime,
+	})()
+
+	// Journal the request before any upstream call; a crash from here on leaves it interrupted
+	client := requestClientKeyID(r)
+	if journalID := h.journalAccept(requestID, r.URL.Path, originalModel, client, anthropicReq.Stream, body, startTime, loggerInstance); journalID != "" {
+		defer func() { h.journalFinish(journalID, recorder.status, loggerInstance) }()
+	}
 	logger.LogRequest(ctx, loggerInstance.WithModel(originalModel), originalModel, len(anthropicReq.Tools))
 
+	// Refuse sessions that used up SESSION_BUDGET_TOKENS or SESSION_BUDGET_USD
+	session := budgetSession(client, anthropicReq)
+	if message := h.budgets.check(h.config, session); message != "" {
+		loggerInstance.Warn("💸 [%s] %s", CodeBudgetExceeded, message)
+		writeProxyError(w, http.StatusPaymentRequired, CodeBudgetExceeded, message)
+		return
+	}
+
+	// Refuse client keys over their daily or monthly spend limit
+	if message, resetIn := h.spendLimitExceeded(client, time.Now()); message != "" {
+		loggerInstance.Warn("💸 [%s] %s", CodeSpendLimitExceeded, message)
+		w.Header().Set("Retry-After", strconv.Itoa(int(resetIn.Seconds())+1))
+		writeProxyError(w, http.StatusTooManyRequests, CodeSpendLimitExceeded, message)
+		return
+	}
+
+	// Warn when Claude Code changed a tool's schema since we last saw it
+	if h.config.ToolSchemaDriftEnabled {
+		checkToolSchemaDrift(h.config.ToolSchemaRegistryPath, anthropicReq.Tools, clientRelease(r.Header.Get("User-Agent")), loggerInstance)
+	}
+
 	// Log available tools for this request
 	if len(anthropicReq.Tools) > 0 {
 		modelLogger := loggerInstance.WithModel(originalModel)
@@ -115,13 +325,61 @@ func (h *Handler) HandleAnthropicRequest(w http.ResponseWriter, r *http.Request)
 
 	// Transform to OpenAI format with mapped model name
 	anthropicReq.Model = mappedModel // Update the request with mapped model
+	trace.Step("model mapped: %s -> %s", originalModel, mappedModel)
+
+	// Client keys restricted by CLIENT_MODEL_ACCESS only reach their models
+	if !h.config.AllowsModel(client, mappedModel) {
+		h.logModelAccessDenied(requestID, r, client, originalModel, mappedModel, loggerInstance)
+		writeProxyError(w, http.StatusForbidden, CodeModelNotAllowed,
+			fmt.Sprintf("this API key may not use model %s (mapped to %s)", originalModel, mappedModel))
+		return
+	}
+
+	// PASSTHROUGH_MODELS are translated between the protocols and nothing else
+	if h.config.IsPassthroughModel(mappedModel) {
+		bypassCorrection, correctionEnabled = true, false
+		ctx = withoutCorrection(ctx)
+		trace.Step("passthrough model: correction, Harmony parsing, system overrides and tool filtering skipped")
+		loggerInstance.Info("🚇 Passthrough model %s: translating the request at the protocol level only", mappedModel)
+	} else if h.config.DisableSmallModelCorrection && mappedModel == h.config.SmallModel {
+		// Background SMALL_MODEL requests (titles, topic detection) never need correction
+		bypassCorrection, correctionEnabled = true, false
+		ctx = withoutCorrection(ctx)
+		trace.Step("small model: correction and tool necessity detection skipped")
+		loggerInstance.Debug("⚡ Skipping tool correction for small model %s", mappedModel)
+	}
+
+	// Refuse images and documents for models without vision rather than silently dropping them
+	capabilities := h.config.GetModelCapabilities(mappedModel)
+	if message := unsupportedContent(anthropicReq, capabilities); message != "" {
+		loggerInstance.Warn("🖼️ [%s] %s", CodeUnsupportedContent, message)
+		writeProxyError(w, http.StatusBadRequest, CodeUnsupportedContent, message)
+		return
+	}
+
+	transformStart := time.Now()
+	ctx = withAssistantPrefill(ctx, assistantPrefill(anthropicReq))
+	ctx = withBackendModel(ctx, mappedModel)
+	ctx = withTokenRecognizer(ctx, h.harmonyTokens)
 	openaiReq, err := TransformAnthropicToOpenAI(ctx, anthropicReq, h.config)
+	trace.Time("request_transform", transformStart)
 	if err != nil {
-		loggerInstance.Error("❌ Failed to transform request: %v", err)
-		http.Error(w, "Request transformation failed", http.StatusInternalServerError)
+		code := ErrorCodeOf(err, CodeRequestTransformFailed)
+		loggerInstance.Error("❌ [%s] Failed to transform request: %v", code, err)
+		writeProxyError(w, http.StatusInternalServerError, code, "Request transformation failed")
 		return
 	}
 
+	// Claude Code offers to compact the conversation when told the prompt is too long
+	if maxContext := capabilities.MaxContextTokens; maxContext > 0 {
+		if tokens := estimatePromptTokens(openaiReq); tokens > maxContext {
+			message := fmt.Sprintf("prompt is too long: %d tokens > %d maximum", tokens, maxContext)
+			loggerInstance.Warn("📏 [%s] %s for model %s", CodePromptTooLong, message, mappedModel)
+			writeProxyError(w, http.StatusBadRequest, CodePromptTooLong, message)
+			return
+		}
+	}
+
 	// Check for loop patterns in the conversation
 	if h.loopDetector != nil {
 		detection := h.loopDetector.DetectLoop(ctx, openaiReq.Messages)
@@ -130,22 +388,38 @@ func (h *Handler) HandleAnthropicRequest(w http.ResponseWriter, r *http.Request)
 			loggerInstance.Info("🔄 Breaking loop with recommendation: %s", detection.Recommendation)
 
 			// Log conversation loop if enabled
-			if h.obsLogger != nil && h.conversationSessionID != "" {
+			if logConversation {
 				h.obsLogger.LokiLogger.LogCorrection(ctx, requestID, h.conversationSessionID, nil, nil, fmt.Sprintf("loop_detection_%s_%s_%d", detection.LoopType, detection.ToolName, detection.Count))
 			}
 
-			// Return loop-breaking response immediately
-			loopBreakResponse := h.loopDetector.CreateLoopBreakingResponse(detection)
-			w.Header().Set("Content-Type", "application/json")
-			if err := json.NewEncoder(w).Encode(loopBreakResponse); err != nil {
-				loggerInstance.Error("❌ Failed to encode loop-breaking response: %v", err)
+			action := h.config.LoopDetectionAction
+			if action == config.LoopActionToolResult {
+				if messages, replaced := loop.ReplaceToolResult(openaiReq.Messages, detection); replaced {
+					openaiReq.Messages = messages
+					trace.Step("loop: replaced %s result with a loop warning", detection.ToolName)
+				} else {
+					action = config.LoopActionNudge // No result of the looping call to replace
+				}
+			}
+			if action == config.LoopActionNudge {
+				openaiReq.Messages = loop.Nudge(openaiReq.Messages, detection)
+				trace.Step("loop: added loop warning to the system message")
+			}
+
+			if action != config.LoopActionNudge && action != config.LoopActionToolResult {
+				// Return loop-breaking response immediately
+				loopBreakResponse := h.loopDetector.CreateLoopBreakingResponse(detection)
+				w.Header().Set("Content-Type", "application/json")
+				if err := json.NewEncoder(w).Encode(loopBreakResponse); err != nil {
+					loggerInstance.Error("❌ Failed to encode loop-breaking response: %v", err)
+				}
+				return
 			}
-			return
 		}
 	}
 
 	// Apply smart tool choice detection if enabled and tools are available
-	if h.config.ToolCorrectionEnabled && len(openaiReq.Tools) > 0 && h.correctionService != nil {
+	if correctionEnabled && len(openaiReq.Tools) > 0 && h.correctionService != nil {
 		// Extract last N messages for context-aware analysis (max 10 messages)
 		const maxContextMessages = 10
 		contextMessages := openaiReq.Messages
@@ -168,12 +442,22 @@ func (h *Handler) HandleAnthropicRequest(w http.ResponseWriter, r *http.Request)
 			loggerInstance.Warn("Tool necessity detection failed: %v", err)
 		} else if shouldRequireTools {
 			openaiReq.ToolChoice = "required"
+			trace.Step("tool_choice set to required by conversation analysis")
 			loggerInstance.Info("🎯 Tool choice set to 'required' based on conversation analysis")
 		} else {
 			loggerInstance.Info("🎯 Tool choice remains optional based on conversation analysis")
 		}
 	}
 
+	// Runaway agent loops end with a text answer once the tool-only turn cap is hit
+	if h.config.MaxToolIterations > 0 && len(openaiReq.Tools) > 0 {
+		if turns := loop.ConsecutiveToolTurns(openaiReq.Messages); turns >= h.config.MaxToolIterations {
+			openaiReq.ToolChoice = "none"
+			trace.Step("tool_choice set to none after %d tool-only turns", turns)
+			loggerInstance.Warn("🛑 %d consecutive tool-only turns (limit %d): forcing a text response", turns, h.config.MaxToolIterations)
+		}
+	}
+
 	// Route to appropriate provider based on mapped model (for endpoint selection)
 	endpoint, apiKey := h.selectProvider(mappedModel)
 	logger.LogModelRouting(ctx, loggerInstance.WithModel(originalModel), mappedModel, endpoint)
@@ -224,7 +508,7 @@ func (h *Handler) HandleAnthropicRequest(w http.ResponseWriter, r *http.Request)
 		loggerInstance.Error("   - Content-Length: %s", r.Header.Get("Content-Length"))
 		loggerInstance.Error("   - Remote-Addr: %s", r.RemoteAddr)
 
-		http.Error(w, "Invalid conversation: missing user message", http.StatusBadRequest)
+		writeProxyError(w, http.StatusBadRequest, CodeInvalidConversation, "Invalid conversation: missing user message")
 		return
 	}
 
@@ -242,8 +526,8 @@ func (h *Handler) HandleAnthropicRequest(w http.ResponseWriter, r *http.Request)
 				loggerInstance.Error("❌ Invalid assistant message %d: empty content and no tool_calls", i)
 				invalidMessages++
 			}
-		case "user", "system":
-			// User and system messages are valid (content field always exists now)
+		case "user", "system", "developer":
+			// User, system and developer messages are valid (content field always exists now)
 			// Note: Server accepts empty content as long as the field is present
 		}
 	}
@@ -257,7 +541,7 @@ func (h *Handler) HandleAnthropicRequest(w http.ResponseWriter, r *http.Request)
 
 	// Validate ExitPlanMode usage before sending to provider
 	for _, msg := range openaiReq.Messages {
-		if msg.ToolCalls != nil {
+		if msg.ToolCalls != nil && !bypassCorrection {
 			for _, toolCall := range msg.ToolCalls {
 				if toolCall.Function.Name == "ExitPlanMode" {
 					// Convert to types.Content for validation
@@ -296,8 +580,8 @@ func (h *Handler) HandleAnthropicRequest(w http.ResponseWriter, r *http.Request)
 						// Send educational response
 						w.Header().Set("Content-Type", "application/json")
 						if err := json.NewEncoder(w).Encode(educationalResponse); err != nil {
-							loggerInstance.Error("❌ Failed to encode educational response: %v", err)
-							http.Error(w, "Response encoding failed", http.StatusInternalServerError)
+							loggerInstance.Error("❌ [%s] Failed to encode educational response: %v", CodeResponseEncodingFailed, err)
+							writeProxyError(w, http.StatusInternalServerError, CodeResponseEncodingFailed, "Response encoding failed")
 						}
 						return
 					}
@@ -307,37 +591,131 @@ func (h *Handler) HandleAnthropicRequest(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Proxy to selected provider with immediate failover for small models
-	var response *types.OpenAIResponse
+	priority := requestPriority(r.Header, mappedModel, h.config)
+	sendUpstream := func(ctx context.Context, req types.OpenAIRequest) (*types.OpenAIResponse, error) {
+		// Wait for a slot while MAX_CONCURRENT_REQUESTS upstream calls are in progress
+		queuedAt := time.Now()
+		release, err := h.scheduler.acquire(ctx, priority)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		if waited := time.Since(queuedAt); waited >= time.Millisecond {
+			trace.Step("waited %s for an upstream slot (%s)", waited.Round(time.Millisecond), priority)
+			loggerInstance.Info("⏳ %s request waited %s for an upstream slot", priority, waited.Round(time.Millisecond))
+		}
 
-	// Check if this is a small model endpoint that supports immediate failover
-	if mappedModel == h.config.SmallModel {
-		response, err = h.proxyWithImmediateFailover(ctx, openaiReq, originalModel, loggerInstance)
-	} else {
-		// Big model endpoints don't use immediate failover (30min timeout acceptable)
-		response, err = h.proxyToProviderEndpoint(ctx, openaiReq, endpoint, apiKey, originalModel)
+		// Follow-up requests (validation feedback) carry native tool calls that an
+		// emulating model reads as text, and its replies carry calls as text
+		emulatedFormat := capabilities.EmulatedToolFormat()
+		emulateTools := emulatedFormat != "" && len(anthropicReq.Tools) > 0
+		if emulateTools {
+			req.Messages = toolHistoryAsText(req.Messages, emulatedFormat)
+		}
+
+		var response *types.OpenAIResponse
+		// Check if this is a small model endpoint that supports immediate failover
+		if mappedModel == h.config.SmallModel {
+			response, err = h.proxyWithImmediateFailover(ctx, req, originalModel, loggerInstance)
+		} else {
+			// Big model endpoints don't use immediate failover (30min timeout acceptable)
+			response, err = h.proxyToProviderEndpoint(ctx, req, endpoint, apiKey, originalModel)
+		}
+		if err == nil && emulateTools {
+			parseEmulatedResponse(response, emulatedFormat, loggerInstance)
+		}
+		return response, err
 	}
 
+	upstreamStart := time.Now()
+	record.SetUpstream(openaiReq)
+	response, err := sendUpstream(ctx, openaiReq)
+	trace.Time("upstream", upstreamStart)
+
 	if err != nil {
-		loggerInstance.Error("❌ Proxy request failed: %v", err)
-		http.Error(w, "Proxy request failed", http.StatusBadGateway)
+		if deadline > 0 && deadlineExceeded(ctx) {
+			loggerInstance.Error("❌ [%s] Request deadline of %s passed before the upstream response: %v", CodeDeadlineExceeded, deadline, err)
+			writeDeadlineExceeded(w, newDeadlineAccounting(deadline, startTime, "upstream", "request_transform"))
+			return
+		}
+		code := ErrorCodeOf(err, CodeUpstreamUnreachable)
+		loggerInstance.Error("❌ [%s] Proxy request failed: %v", code, err)
+		writeProxyError(w, http.StatusBadGateway, code, "Proxy request failed")
+		return
+	}
+
+	// The Anthropic format carries a single choice
+	response, err = applyChoicePolicy(response, h.config.MultiChoicePolicy, loggerInstance)
+	if err != nil {
+		code := ErrorCodeOf(err, CodeUpstreamInvalidResponse)
+		loggerInstance.Error("❌ [%s] Rejected upstream response: %v", code, err)
+		writeProxyError(w, http.StatusBadGateway, code, "Proxy request failed")
 		return
 	}
 
 	// Transform response back to Anthropic format (use original model name)
+	responseTransformStart := time.Now()
 	anthropicResp, err := TransformOpenAIToAnthropic(ctx, response, originalModel, h.config)
+	trace.Time("response_transform", responseTransformStart)
 	if err != nil {
-		loggerInstance.Error("❌ Failed to transform response: %v", err)
-		http.Error(w, "Response transformation failed", http.StatusInternalServerError)
+		code := ErrorCodeOf(err, CodeResponseTransformFailed)
+		loggerInstance.Error("❌ [%s] Failed to transform response: %v", code, err)
+		writeProxyError(w, http.StatusInternalServerError, code, "Response transformation failed")
 		return
 	}
+	if len(anthropicResp.HarmonyChannels) > 0 {
+		h.stats.RecordHarmonyDetection()
+	}
+	cost := h.config.EstimateCost(mappedModel, anthropicResp.Usage.InputTokens, anthropicResp.Usage.OutputTokens)
+	h.stats.RecordUsage(originalModel, anthropicResp.Usage.InputTokens, anthropicResp.Usage.OutputTokens, cost)
+	if client != "" && cost > 0 {
+		h.stats.RecordClientSpend(client, cost, time.Now())
+	}
+	if message := h.budgets.record(h.config, session, anthropicResp.Usage.InputTokens+anthropicResp.Usage.OutputTokens, cost, time.Now()); message != "" {
+		loggerInstance.Warn("💸 %s; further requests of this session are refused", message)
+		h.sendBudgetAlert(session, message)
+	}
 
 	// Apply tool correction if needed - only if there are actual tool calls that need correction
-	if HasToolCalls(anthropicResp.Content) && h.config.ToolCorrectionEnabled && NeedsCorrection(ctx, anthropicResp.Content, anthropicReq.Tools, h.correctionService, h.loggerConfig) {
+	correctionCandidate := HasToolCalls(anthropicResp.Content) && correctionEnabled
+	if correctionCandidate {
+		// Shared by NeedsCorrection and CorrectToolCalls for every tool call and retry
+		ctx = correction.WithToolIndex(ctx, correction.NewToolIndex(anthropicReq.Tools))
+		ctx = correction.WithConversation(ctx, openaiReq.Messages)
+	}
+	if correctionCandidate && h.config.ValidationFeedbackRounds > 0 {
+		// Let the model fix its own invalid calls first, as it would after a failed tool run
+		feedbackStart := time.Now()
+		anthropicResp = h.applyValidationFeedback(ctx, openaiReq, anthropicResp, anthropicReq.Tools, originalModel, sendUpstream, loggerInstance)
+		trace.Time("validation_feedback", feedbackStart)
+	}
+	if correctionCandidate && NeedsCorrection(ctx, anthropicResp.Content, anthropicReq.Tools, h.correctionService, h.loggerConfig) {
 		loggerInstance.Info("🔧 Starting tool correction for %d content items", len(anthropicResp.Content))
 		originalContent := anthropicResp.Content
-		correctedContent, err := h.correctionService.CorrectToolCalls(ctx, anthropicResp.Content, anthropicReq.Tools)
+		correctionStart := time.Now()
+		correctionCtx, cancelCorrection := h.correctionContext(ctx)
+		correctedContent, err := h.corrector.CorrectToolCalls(correctionCtx, anthropicResp.Content, anthropicReq.Tools)
+		budgetExceeded := correctionBudgetExhausted(ctx, correctionCtx)
+		cancelCorrection()
+		trace.Time("tool_correction", correctionStart)
+		if budgetExceeded {
+			// Whatever was corrected in time is kept; the rest is forwarded or blocked
+			if err != nil {
+				correctedContent, err = originalContent, nil
+			}
+			loggerInstance.Warn("⏱️ Tool correction budget of %dms exhausted (policy: %s)", h.config.CorrectionBudgetMs, h.config.CorrectionBudgetPolicy)
+			if h.correctionBudgetBlocks() {
+				var blocked int
+				correctedContent, blocked = h.blockInvalidToolCalls(ctx, correctedContent, anthropicReq.Tools)
+				if blocked > 0 {
+					loggerInstance.Warn("⏱️ Blocked %d tool call(s) still invalid after the correction budget", blocked)
+				}
+			}
+		}
 		if err != nil {
-			loggerInstance.Warn("⚠️ Tool correction failed: %v", err)
+			trace.SetCorrection(CorrectionTrace{ToolCalls: countToolCalls(originalContent), Error: err.Error()})
+			loggerInstance.Warn("⚠️ [%s] Tool correction failed: %v", CodeCorrectionFailed, err)
+			h.recordCorrection(requestID, originalModel, stats.CorrectionFailed, err.Error())
 			// Continue with original content if correction fails
 		} else {
 			// Log if any changes were made
@@ -345,9 +723,13 @@ func (h *Handler) HandleAnthropicRequest(w http.ResponseWriter, r *http.Request)
 				loggerInstance.Info("🔧 Tool correction changed content count: %d -> %d", len(originalContent), len(correctedContent))
 			}
 
-			// Check for actual changes in tool calls
-			changesDetected := false
+			// Check for actual changes in tool calls; dropped or blocked calls change the count or type
+			changesDetected := len(correctedContent) != len(originalContent)
 			for i, corrected := range correctedContent {
+				if i < len(originalContent) && corrected.Type != originalContent[i].Type {
+					loggerInstance.Info("🔧 Tool call %s replaced by %s content", originalContent[i].Name, corrected.Type)
+					changesDetected = true
+				}
 				if i < len(originalContent) && corrected.Type == "tool_use" && originalContent[i].Type == "tool_use" {
 					if corrected.Name != originalContent[i].Name {
 						loggerInstance.Info("🔧 Tool name changed: %s -> %s", originalContent[i].Name, corrected.Name)
@@ -360,16 +742,60 @@ func (h *Handler) HandleAnthropicRequest(w http.ResponseWriter, r *http.Request)
 				}
 			}
 
-			if !changesDetected {
+			trace.SetCorrection(CorrectionTrace{ToolCalls: countToolCalls(originalContent), Changed: changesDetected, BudgetExceeded: budgetExceeded})
+			detail := fmt.Sprintf("%d tool call(s)", countToolCalls(originalContent))
+			if budgetExceeded {
+				h.recordCorrection(requestID, originalModel, stats.CorrectionBudgetExceeded, detail)
+			} else if !changesDetected {
 				loggerInstance.Info("🔧 Tool correction completed - no changes detected")
+				h.recordCorrection(requestID, originalModel, stats.CorrectionUnchanged, detail)
+			} else {
+				h.recordCorrection(requestID, originalModel, stats.CorrectionApplied, detail)
 			}
 
 			// Log conversation correction if enabled
-			if h.obsLogger != nil && h.conversationSessionID != "" && changesDetected {
+			if logConversation && changesDetected {
 				h.obsLogger.LokiLogger.LogCorrection(ctx, requestID, h.conversationSessionID, originalContent, correctedContent, "tool_correction")
 			}
 
 			anthropicResp.Content = correctedContent
+			anthropicResp.StopReason = reconcileStopReason(anthropicResp.StopReason, correctedContent)
+		}
+	}
+
+	// Tokens used upstream are already counted above; the deadline error reports them
+	if deadline > 0 && deadlineExceeded(ctx) {
+		loggerInstance.Error("❌ [%s] Request deadline of %s passed during tool correction", CodeDeadlineExceeded, deadline)
+		accounting := newDeadlineAccounting(deadline, startTime, "correction", "request_transform", "upstream")
+		accounting.Usage = &anthropicResp.Usage
+		writeDeadlineExceeded(w, accounting)
+		return
+	}
+
+	// Identical back-to-back tool calls would make the client run the same command twice
+	if HasToolCalls(anthropicResp.Content) {
+		content, duplicates := suppressDuplicateToolCalls(anthropicResp.Content, h.config.DuplicateToolCallPolicy)
+		if len(duplicates) > 0 {
+			loggerInstance.Warn("🔁 %d duplicate tool call(s) in response (policy: %s): %v", len(duplicates), h.config.DuplicateToolCallPolicy, duplicates)
+			anthropicResp.Content = content
+		}
+	}
+
+	// Tool-call governance runs on the calls the client would execute
+	if HasToolCalls(anthropicResp.Content) {
+		policyStart := time.Now()
+		content, denied := h.applyToolPolicies(ctx, anthropicResp.Content, requestSession(anthropicReq), requestID, loggerInstance)
+		trace.Time("tool_policies", policyStart)
+		if denied {
+			anthropicResp.Content = content
+			anthropicResp.StopReason = reconcileStopReason(anthropicResp.StopReason, content)
+		}
+		if deadline > 0 && deadlineExceeded(ctx) {
+			loggerInstance.Error("❌ [%s] Request deadline of %s passed waiting for tool policies", CodeDeadlineExceeded, deadline)
+			accounting := newDeadlineAccounting(deadline, startTime, "tool_policies", "request_transform", "upstream", "correction")
+			accounting.Usage = &anthropicResp.Usage
+			writeDeadlineExceeded(w, accounting)
+			return
 		}
 	}
 
@@ -385,7 +811,7 @@ func (h *Handler) HandleAnthropicRequest(w http.ResponseWriter, r *http.Request)
 			logger.LogToolUsed(ctx, modelLogger, content.Name, content.ID)
 
 			// Log conversation tool call if enabled
-			if h.obsLogger != nil && h.conversationSessionID != "" {
+			if logConversation {
 				h.obsLogger.LokiLogger.LogToolCall(ctx, requestID, h.conversationSessionID, content.Name, content.Input, nil) // Result will be from next request
 			}
 		}
@@ -393,19 +819,29 @@ func (h *Handler) HandleAnthropicRequest(w http.ResponseWriter, r *http.Request)
 	logger.LogResponseSummary(ctx, modelLogger, textItemCount, toolCallCount, anthropicResp.StopReason)
 
 	// Log conversation response if enabled
-	if h.obsLogger != nil && h.conversationSessionID != "" {
+	if logConversation {
 		h.obsLogger.LokiLogger.LogResponse(ctx, requestID, h.conversationSessionID, anthropicResp)
+		h.storeConversation(requestID, originalModel, anthropicReq, anthropicResp, loggerInstance)
 	}
 
+	record.SetResponse(anthropicResp)
+
 	// Send response - stream if client requested it
 	if anthropicReq.Stream {
 		// Client requested streaming - return Anthropic SSE streaming format
-		h.sendStreamingResponse(w, anthropicResp, loggerInstance)
+		h.sendStreamingResponse(ctx, w, anthropicResp, h.streamLimiters.get(h.config.StreamRateLimits, client, time.Now()), loggerInstance)
 	} else {
 		// Client wants JSON response - return regular JSON
+		var body interface{} = anthropicResp
+		if trace.isReturned() {
+			body = struct {
+				*types.AnthropicResponse
+				ProxyDebug *DebugTrace `json:"proxy_debug"`
+			}{anthropicResp, trace.finish()}
+		}
 		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(anthropicResp); err != nil {
-			loggerInstance.Error("❌ Failed to encode response: %v", err)
+		if err := json.NewEncoder(w).Encode(body); err != nil {
+			loggerInstance.Error("❌ [%s] Failed to encode response: %v", CodeResponseEncodingFailed, err)
 		}
 	}
 }
@@ -413,6 +849,12 @@ func (h *Handler) HandleAnthropicRequest(w http.ResponseWriter, r *http.Request)
 // mapModelName is now handled by config.MapModelName() method
 // This function has been removed in favor of configurable model mapping
 
+// recordCorrection counts a correction outcome and keeps it for the dashboard
+func (h *Handler) recordCorrection(requestID, model, outcome, detail string) {
+	h.stats.RecordCorrection(outcome)
+	h.stats.RecordCorrectionEvent(stats.Event{RequestID: requestID, Model: model, Kind: outcome, Detail: detail})
+}
+
 // selectProvider determines which endpoint to use based on mapped model with failover support
 func (h *Handler) selectProvider(mappedModel string) (endpoint, apiKey string) {
 	// Route based on configured SMALL_MODEL to small model endpoint
@@ -424,14 +866,32 @@ func (h *Handler) selectProvider(mappedModel string) (endpoint, apiKey string) {
 	return h.config.GetBigModelEndpoint(), h.config.BigModelAPIKey
 }
 
-// isBigModelEndpoint checks if an endpoint is a big model endpoint (bypasses circuit breaker)
-func (h *Handler) isBigModelEndpoint(endpoint string) bool {
-	for _, bigEndpoint := range h.config.BigModelEndpoints {
-		if endpoint == bigEndpoint {
-			return true
-		}
+// logModelAccessDenied records a request CLIENT_MODEL_ACCESS refused, for
+// auditing who tried to reach which model
+func (h *Handler) logModelAccessDenied(requestID string, r *http.Request, client, originalModel, mappedModel string, loggerInstance logger.Logger) {
+	loggerInstance.Warn("🚫 [%s] API key %s may not use %s (mapped to %s)", CodeModelNotAllowed, maskClientKey(r.Header), originalModel, mappedModel)
+	if h.obsLogger == nil {
+		return
 	}
-	return false
+	h.obsLogger.Warn(logger.ComponentProxy, logger.CategoryBlocked, requestID, "Model access denied", map[string]interface{}{
+		"client_key_id":   client,
+		"client_key":      maskClientKey(r.Header),
+		"remote_addr":     r.RemoteAddr,
+		"requested_model": originalModel,
+		"mapped_model":    mappedModel,
+		"error_code":      CodeModelNotAllowed,
+	})
+}
+
+// isBigModelEndpoint checks if an endpoint is a big model endpoint
+func (h *Handler) isBigModelEndpoint(endpoint string) bool {
+	return h.config.HasEndpoint(config.EndpointRoleBig, endpoint)
+}
+
+// tracksHealth reports whether results from endpoint feed the circuit breaker.
+// Big model endpoints bypass it unless BIG_MODEL_CIRCUIT_BREAKER is enabled.
+func (h *Handler) tracksHealth(endpoint string) bool {
+	return h.config.BigModelCircuitBreaker || !h.isBigModelEndpoint(endpoint)
 }
 
 // getRequestTimeout returns appropriate request timeout for specific endpoints
@@ -446,93 +906,125 @@ func (h *Handler) getRequestTimeout(endpoint string) time.Duration {
 }
 
 // proxyToProviderEndpoint sends the OpenAI request to a specific provider endpoint
-func (h *Handler) proxyToProviderEndpoint(ctx context.Context, req types.OpenAIRequest, endpoint, apiKey, originalModel string) (*types.OpenAIResponse, error) {
+func (h *Handler) proxyToProviderEndpoint(ctx context.Context, req types.OpenAIRequest, endpoint, apiKey, originalModel string) (_ *types.OpenAIResponse, err error) {
+	attemptStart := time.Now()
+	defer func() {
+		debugTraceFrom(ctx).Attempt(endpoint, attemptStart, err)
+	}()
+
+	// OpenRouter provider routing preferences from endpoints.yaml
+	if settings, ok := h.config.GetEndpointSettings(endpoint); ok && settings.Provider != nil {
+		req.Provider = settings.Provider
+	}
+	// Provider quirk profile (groq, together, fireworks, ...) selected in endpoints.yaml
+	profile, hasProfile := h.config.EndpointProviderProfile(endpoint)
+	if hasProfile {
+		applyProviderRequestQuirks(&req, profile)
+	}
+
 	// Serialize request
 	reqBody, err := json.Marshal(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %v", err)
+		return nil, newProxyError(CodeRequestTransformFailed, "failed to marshal request: %v", err)
+	}
+	if hasProfile {
+		if reqBody, err = stripProviderParams(reqBody, profile.StripParams); err != nil {
+			return nil, newProxyError(CodeRequestTransformFailed, "failed to strip provider parameters: %v", err)
+		}
 	}
 
 	// Create HTTP request with context for timeout/cancellation
 	httpReq, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(reqBody))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
+		return nil, newProxyError(CodeUpstreamUnreachable, "failed to create request: %v", err)
 	}
 
 	// Set headers
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+	if requestID := GetRequestID(ctx); requestID != "unknown" {
+		httpReq.Header.Set(internal.RequestIDHeader, requestID)
+	}
 
 	// Get logger from context and use it for logging
 	proxyLogger := logger.FromContext(ctx, h.loggerConfig).WithModel(originalModel)
 	logger.LogProxyRequest(ctx, proxyLogger, endpoint, req.Stream)
 	// Verbose logging can be added via obsLogger.Debug if needed
 
-	// Create HTTP client with custom connection timeout
-	connectionTimeout := time.Duration(h.config.DefaultConnectionTimeout) * time.Second
-	requestTimeout := h.getRequestTimeout(endpoint)
-
-	client := &http.Client{
-		Timeout: requestTimeout,
-		Transport: &http.Transport{
-			DialContext: (&net.Dialer{
-				Timeout: connectionTimeout,
-			}).DialContext,
-		},
-	}
+	// Create HTTP client with the endpoint's connection and request timeouts (endpoints.yaml)
+	client := h.config.HTTPClient(endpoint, h.getRequestTimeout(endpoint))
+	connectionTimeout := h.config.ConnectTimeout(endpoint)
+	requestTimeout := client.Timeout
 	proxyLogger.Debug("🔗 Using connection timeout %v, request timeout %v for endpoint: %s", connectionTimeout, requestTimeout, endpoint)
 	resp, err := client.Do(httpReq)
+	responseLatency := time.Since(attemptStart) // Time to response headers, i.e. first token when streaming
 	if err != nil {
-		// Record endpoint failure for circuit breaker (skip for big models - 30min timeout acceptable)
-		if !h.isBigModelEndpoint(endpoint) {
-			h.config.HealthManager.RecordFailure(endpoint)
+		// Record endpoint failure for circuit breaker (skipped for big models unless BIG_MODEL_CIRCUIT_BREAKER - 30min timeout acceptable)
+		if h.tracksHealth(endpoint) {
+			h.config.HealthManager.RecordFailureWithError(endpoint, err)
 		}
-		return nil, fmt.Errorf("request failed: %v", err)
+		if isTimeout(err) {
+			return nil, newProxyError(CodeUpstreamTimeout, "request timed out after %v: %v", requestTimeout, err)
+		}
+		return nil, newProxyError(CodeUpstreamUnreachable, "request failed: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		// Record endpoint failure for non-200 status codes (skip for big models)
-		if !h.isBigModelEndpoint(endpoint) {
-			h.config.HealthManager.RecordFailure(endpoint)
-		}
 		// Read error response
 		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("provider returned status %d: %s", resp.StatusCode, string(respBody))
+		statusErr := newProxyError(CodeUpstreamStatus, "provider returned status %d: %s", resp.StatusCode, string(respBody))
+		// Record endpoint failure for non-200 status codes (skipped for big models unless BIG_MODEL_CIRCUIT_BREAKER)
+		if h.tracksHealth(endpoint) {
+			h.config.HealthManager.RecordFailureWithError(endpoint, statusErr)
+		}
+		return nil, statusErr
 	}
 
 	// Handle streaming vs non-streaming responses
 	if req.Stream {
 		logger.LogStreamingResponse(ctx, proxyLogger)
-		result, err := h.ProcessStreamingResponse(ctx, resp)
+		expectUsage := req.StreamOptions != nil && req.StreamOptions.IncludeUsage
+		result, err := h.processStreamingResponse(ctx, resp, expectUsage)
 		if err != nil {
-			// Record endpoint failure for streaming errors (skip for big models)
-			if !h.isBigModelEndpoint(endpoint) {
-				h.config.HealthManager.RecordFailure(endpoint)
+			// Record endpoint failure for streaming errors (skipped for big models unless BIG_MODEL_CIRCUIT_BREAKER)
+			if h.tracksHealth(endpoint) {
+				h.config.HealthManager.RecordFailureWithError(endpoint, err)
 			}
 			return nil, err
 		}
-		// Record endpoint success for successful streaming (skip for big models)
-		if !h.isBigModelEndpoint(endpoint) {
-			h.config.HealthManager.RecordSuccess(endpoint)
+		// Record endpoint success for successful streaming (skipped for big models unless BIG_MODEL_CIRCUIT_BREAKER)
+		if h.tracksHealth(endpoint) {
+			h.config.HealthManager.RecordSuccessWithLatency(endpoint, responseLatency)
+		}
+		if hasProfile {
+			applyProviderResponseQuirks(result, profile, proxyLogger)
 		}
 		return result, nil
 	} else {
 		// Handle non-streaming response (current logic)
-		respBody, err := io.ReadAll(resp.Body)
+		respBuf, err := readPooled(resp.Body)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read response: %v", err)
+			code := CodeUpstreamInvalidResponse
+			if isTimeout(err) {
+				code = CodeUpstreamTimeout
+			}
+			return nil, newProxyError(code, "failed to read response: %v", err)
 		}
+		defer putBuffer(respBuf)
 
 		var openaiResp types.OpenAIResponse
-		if err := json.Unmarshal(respBody, &openaiResp); err != nil {
-			return nil, fmt.Errorf("failed to parse response: %v", err)
+		if err := json.Unmarshal(respBuf.Bytes(), &openaiResp); err != nil {
+			return nil, newProxyError(CodeUpstreamInvalidResponse, "failed to parse response: %v", err)
 		}
 
 		logger.LogNonStreamingResponse(ctx, proxyLogger, len(openaiResp.Choices))
-		// Record endpoint success for circuit breaker (skip for big models)
-		if !h.isBigModelEndpoint(endpoint) {
-			h.config.HealthManager.RecordSuccess(endpoint)
+		// Record endpoint success for circuit breaker (skipped for big models unless BIG_MODEL_CIRCUIT_BREAKER)
+		if h.tracksHealth(endpoint) {
+			h.config.HealthManager.RecordSuccessWithLatency(endpoint, responseLatency)
+		}
+		if hasProfile {
+			applyProviderResponseQuirks(&openaiResp, profile, proxyLogger)
 		}
 		return &openaiResp, nil
 	}
@@ -541,12 +1033,13 @@ func (h *Handler) proxyToProviderEndpoint(ctx context.Context, req types.OpenAIR
 // proxyWithImmediateFailover attempts immediate failover to healthy small model endpoints within same request
 func (h *Handler) proxyWithImmediateFailover(ctx context.Context, req types.OpenAIRequest, originalModel string, loggerInstance logger.Logger) (*types.OpenAIResponse, error) {
 	const maxAttempts = 3 // Limit attempts to prevent infinite loops
+	var lastErr error
 
 	for attempt := 1; attempt <= maxAttempts; attempt++ {
 		// Get the next healthy endpoint
 		endpoint := h.config.GetSmallModelEndpoint()
 		if endpoint == "" {
-			return nil, fmt.Errorf("no small model endpoints available")
+			return nil, newProxyError(CodeAllEndpointsDown, "no small model endpoints available")
 		}
 
 		apiKey := h.config.SmallModelAPIKey
@@ -558,6 +1051,7 @@ func (h *Handler) proxyWithImmediateFailover(ctx context.Context, req types.Open
 		response, err := h.proxyToProviderEndpoint(ctx, req, endpoint, apiKey, originalModel)
 		if err != nil {
 			// This endpoint failed - circuit breaker recording already handled in proxyToProviderEndpoint
+			lastErr = err
 			loggerInstance.Warn("⚠️ Endpoint failed, trying next: %s (attempt %d/%d)", endpoint, attempt, maxAttempts)
 			continue
 		}
@@ -569,7 +1063,7 @@ func (h *Handler) proxyWithImmediateFailover(ctx context.Context, req types.Open
 		return response, nil
 	}
 
-	return nil, fmt.Errorf("all %d failover attempts exhausted", maxAttempts)
+	return nil, newProxyError(CodeAllEndpointsDown, "all %d failover attempts exhausted, last error: %v", maxAttempts, lastErr)
 }
 
 // NOTE: isSmallModel and shouldLogForModel functions removed
@@ -593,7 +1087,21 @@ func boolToYesNo(b bool) string {
 }
 
 // sendStreamingResponse sends an Anthropic response as SSE streaming format
-func (h *Handler) sendStreamingResponse(w http.ResponseWriter, resp *types.AnthropicResponse, logger logger.Logger) {
+func (h *Handler) sendStreamingResponse(ctx context.Context, w http.ResponseWriter, resp *types.AnthropicResponse, limiter *streamRateLimiter, logger logger.Logger) {
+	stream := &sseStreamWriter{
+		ResponseWriter: w,
+		flushInterval:  time.Duration(h.config.SSECoalesceIntervalMs) * time.Millisecond,
+		flushBytes:     h.config.SSECoalesceBytes,
+		lastFlush:      time.Now(),
+		limiter:        limiter,
+	}
+	w = stream
+
+	// Debug mode: check our own event ordering, violations are logged at the end
+	if h.config.SSEVerifyEnabled {
+		stream.verifier = NewSSEVerifier()
+	}
+
 	// Set SSE headers
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	w.Header().Set("Cache-Control", "no-cache")
@@ -623,7 +1131,7 @@ func (h *Handler) sendStreamingResponse(w http.ResponseWriter, resp *types.Anthr
 		},
 	}
 
-	h.writeSSEEvent(w, "message_start", messageStartEvent)
+	h.writeSSEEvent(ctx, w, "message_start", messageStartEvent)
 
 	// Send content blocks
 	for index, content := range resp.Content {
@@ -635,6 +1143,13 @@ func (h *Handler) sendStreamingResponse(w http.ResponseWriter, resp *types.Anthr
 				"type": "text",
 				"text": "",
 			}
+		} else if content.Type == "thinking" {
+			// Harmony analysis channel: a native thinking block renders in Claude Code's collapsed thinking UI
+			contentBlock = map[string]interface{}{
+				"type":      "thinking",
+				"thinking":  "",
+				"signature": "",
+			}
 		} else if content.Type == "tool_use" {
 			contentBlock = map[string]interface{}{
 				"type":  "tool_use",
@@ -650,7 +1165,7 @@ func (h *Handler) sendStreamingResponse(w http.ResponseWriter, resp *types.Anthr
 			"content_block": contentBlock,
 		}
 
-		h.writeSSEEvent(w, "content_block_start", contentBlockStartEvent)
+		h.writeSSEEvent(ctx, w, "content_block_start", contentBlockStartEvent)
 
 		// Send content_block_delta events
 		if content.Type == "text" && content.Text != "" {
@@ -668,8 +1183,33 @@ func (h *Handler) sendStreamingResponse(w http.ResponseWriter, resp *types.Anthr
 					"delta": delta,
 				}
 
-				h.writeSSEEvent(w, "content_block_delta", deltaEvent)
+				h.writeSSEEvent(ctx, w, "content_block_delta", deltaEvent)
 			}
+		} else if content.Type == "thinking" {
+			for _, chunk := range h.splitTextForStreaming(content.Thinking) {
+				deltaEvent := map[string]interface{}{
+					"type":  "content_block_delta",
+					"index": index,
+					"delta": map[string]interface{}{
+						"type":     "thinking_delta",
+						"thinking": chunk,
+					},
+				}
+
+				h.writeSSEEvent(ctx, w, "content_block_delta", deltaEvent)
+			}
+
+			// Anthropic clients expect a signature_delta to close every thinking block;
+			// backend reasoning is unsigned, so it carries whatever signature the block has
+			signatureEvent := map[string]interface{}{
+				"type":  "content_block_delta",
+				"index": index,
+				"delta": map[string]interface{}{
+					"type":      "signature_delta",
+					"signature": content.Signature,
+				},
+			}
+			h.writeSSEEvent(ctx, w, "content_block_delta", signatureEvent)
 		} else if content.Type == "tool_use" {
 			// Stream tool input JSON
 			if inputJSON, err := json.Marshal(content.Input); err == nil {
@@ -687,7 +1227,7 @@ func (h *Handler) sendStreamingResponse(w http.ResponseWriter, resp *types.Anthr
 						"delta": delta,
 					}
 
-					h.writeSSEEvent(w, "content_block_delta", deltaEvent)
+					h.writeSSEEvent(ctx, w, "content_block_delta", deltaEvent)
 				}
 			}
 		}
@@ -698,7 +1238,7 @@ func (h *Handler) sendStreamingResponse(w http.ResponseWriter, resp *types.Anthr
 			"index": index,
 		}
 
-		h.writeSSEEvent(w, "content_block_stop", contentBlockStopEvent)
+		h.writeSSEEvent(ctx, w, "content_block_stop", contentBlockStopEvent)
 	}
 
 	// Send message_delta event with final usage and stop_reason
@@ -713,29 +1253,56 @@ func (h *Handler) sendStreamingResponse(w http.ResponseWriter, resp *types.Anthr
 		},
 	}
 
-	h.writeSSEEvent(w, "message_delta", messageDeltaEvent)
+	h.writeSSEEvent(ctx, w, "message_delta", messageDeltaEvent)
 
 	// Send message_stop event
 	messageStopEvent := map[string]interface{}{
 		"type": "message_stop",
 	}
 
-	h.writeSSEEvent(w, "message_stop", messageStopEvent)
+	h.writeSSEEvent(ctx, w, "message_stop", messageStopEvent)
+
+	// Debug trace goes in an SSE comment so protocol parsers ignore it
+	if trace := debugTraceFrom(ctx); trace.isReturned() {
+		traceJSON, _ := json.Marshal(trace.finish())
+		n, _ := fmt.Fprintf(w, ": proxy_debug %s\n\n", traceJSON)
+		stream.eventWritten(n)
+	}
+	if stream.pending > 0 {
+		stream.Flush() // Deliver events still held back by coalescing
+	}
+
+	if stream.verifier != nil {
+		for _, violation := range stream.verifier.Finish() {
+			logger.Warn("⚠️ SSE protocol violation: %s", violation)
+		}
+	}
 
 	logger.Info("🌊 Sent streaming response with %d content blocks", len(resp.Content))
 }
 
 // writeSSEEvent writes a single SSE event
-func (h *Handler) writeSSEEvent(w http.ResponseWriter, eventType string, data interface{}) {
-	fmt.Fprintf(w, "event: %s\n", eventType)
+func (h *Handler) writeSSEEvent(ctx context.Context, w http.ResponseWriter, eventType string, data interface{}) {
+	stream, isStream := w.(*sseStreamWriter)
+	if isStream && stream.verifier != nil {
+		stream.verifier.Observe(eventType, data)
+	}
 
-	dataJSON, err := json.Marshal(data)
-	if err != nil {
-		// Fallback to empty object if marshaling fails
-		dataJSON = []byte("{}")
+	written := 0
+	if versionSupports(ctx, FeatureNamedSSEEvents) {
+		n, _ := fmt.Fprintf(w, "event: %s\n", eventType)
+		written += n
 	}
 
-	fmt.Fprintf(w, "data: %s\n\n", string(dataJSON))
+	n, _ := writeSSEData(w, data)
+	written += n
+
+	// Coalesce flushes across whole events when configured, then keep to the client's rate limit
+	if isStream {
+		stream.eventWritten(written)
+		stream.pace(ctx, data, written)
+		return
+	}
 
 	// Flush to ensure immediate delivery
 	if flusher, ok := w.(http.Flusher); ok {
@@ -0,0 +1,57 @@
+package proxy
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"claude-proxy/types"
+)
+
+// harmonyPreamble builds the system message gpt-oss models are trained on.
+// Without it some servers' chat templates leave out the channel listing and
+// the model emits malformed or missing channels the parser cannot consume.
+func harmonyPreamble(reasoningLevel, knowledgeCutoff string, now time.Time, tools bool) string {
+	var preamble strings.Builder
+	preamble.WriteString("You are ChatGPT, a large language model trained by OpenAI.\n")
+	fmt.Fprintf(&preamble, "Knowledge cutoff: %s\nCurrent date: %s\n\n", knowledgeCutoff, now.Format("2006-01-02"))
+	fmt.Fprintf(&preamble, "Reasoning: %s\n\n", reasoningLevel)
+	preamble.WriteString("# Valid channels: analysis, commentary, final. Channel must be included for every message.")
+	if tools {
+		preamble.WriteString("\nCalls to these tools must go to the commentary channel: 'functions'.")
+	}
+	return preamble.String()
+}
+
+// injectHarmonyPreamble puts the preamble at the start of the system message,
+// adding one ahead of the developer message when there is none
+func injectHarmonyPreamble(openaiReq *types.OpenAIRequest, preamble string) {
+	if len(openaiReq.Messages) > 0 && openaiReq.Messages[0].Role == "system" {
+		openaiReq.Messages[0].Content = preamble + "\n\n" + openaiReq.Messages[0].Content
+		return
+	}
+	openaiReq.Messages = append([]types.OpenAIMessage{{Role: "system", Content: preamble}}, openaiReq.Messages...)
+}
+
+// useDeveloperRole moves the system prompt, including tool definitions inlined
+// into it, into the single leading developer message Harmony models take
+// instructions from; the system role is reserved for the Harmony preamble.
+// Reports whether there was a system prompt.
+func useDeveloperRole(openaiReq *types.OpenAIRequest) bool {
+	var instructions []string
+	messages := openaiReq.Messages[:0:0]
+	for _, msg := range openaiReq.Messages {
+		if msg.Role == "system" || msg.Role == "developer" {
+			instructions = append(instructions, msg.Content)
+			continue
+		}
+		messages = append(messages, msg)
+	}
+	if len(instructions) == 0 {
+		return false
+	}
+
+	developer := types.OpenAIMessage{Role: "developer", Content: strings.Join(instructions, "\n\n")}
+	openaiReq.Messages = append([]types.OpenAIMessage{developer}, messages...)
+	return true
+}
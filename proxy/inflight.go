@@ -0,0 +1,58 @@
+package proxy
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// InFlightRequest describes a /v1/messages request that is still being served
+type InFlightRequest struct {
+	RequestID  string    `json:"request_id"`
+	Model      string    `json:"model"`
+	Stream     bool      `json:"stream"`
+	Tools      int       `json:"tools"`
+	RemoteAddr string    `json:"remote_addr"`
+	StartedAt  time.Time `json:"started_at"`
+}
+
+// inFlightRequests tracks requests between decoding and the final response
+// byte, for the control plane's live request list
+type inFlightRequests struct {
+	mu       sync.Mutex
+	requests map[string]InFlightRequest
+}
+
+func newInFlightRequests() *inFlightRequests {
+	return &inFlightRequests{requests: make(map[string]InFlightRequest)}
+}
+
+// add registers a request and returns the function that removes it
+func (f *inFlightRequests) add(req InFlightRequest) func() {
+	f.mu.Lock()
+	f.requests[req.RequestID] = req
+	f.mu.Unlock()
+	return func() {
+		f.mu.Lock()
+		delete(f.requests, req.RequestID)
+		f.mu.Unlock()
+	}
+}
+
+// list returns the in-flight requests, oldest first
+func (f *inFlightRequests) list() []InFlightRequest {
+	f.mu.Lock()
+	list := make([]InFlightRequest, 0, len(f.requests))
+	for _, req := range f.requests {
+		list = append(list, req)
+	}
+	f.mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].StartedAt.Before(list[j].StartedAt) })
+	return list
+}
+
+// InFlight returns the requests currently being served, oldest first
+func (h *Handler) InFlight() []InFlightRequest {
+	return h.inFlight.list()
+}
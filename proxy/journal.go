@@ -0,0 +1,188 @@
+package proxy
+
+import (
+	"bytes"
+	"claude-proxy/internal"
+	"claude-proxy/journal"
+	"claude-proxy/logger"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const journalPath = "/admin/journal"
+
+// SetJournal records every accepted /v1/messages request in j until it is answered
+func (h *Handler) SetJournal(j *journal.Journal) {
+	h.journal = j
+}
+
+// journalAccept records a decoded request before any upstream call and
+// returns the journal ID journalFinish must be called with, "" when the
+// request is not journaled. Batch entries are left out: the batch store
+// already resumes them after a restart.
+func (h *Handler) journalAccept(requestID, path, model, client string, stream bool, body []byte, acceptedAt time.Time, log logger.Logger) string {
+	if h.journal == nil || strings.HasPrefix(path, batchesPath) {
+		return ""
+	}
+	id, err := h.journal.Accept(journal.Entry{
+		RequestID:  requestID,
+		AcceptedAt: acceptedAt,
+		Model:      model,
+		Stream:     stream,
+		Request:    body,
+		Path:       path,
+		ClientKey:  client,
+	})
+	if err != nil {
+		log.Warn("Failed to journal request: %v", err)
+		return ""
+	}
+	return id
+}
+
+// journalFinish records that a journaled request was answered
+func (h *Handler) journalFinish(id string, status int, log logger.Logger) {
+	if err := h.journal.Finish(id, status); err != nil {
+		log.Warn("Failed to journal request completion: %v", err)
+	}
+}
+
+// journalSummary lists an interrupted request without its body
+type journalSummary struct {
+	ID         string    `json:"id"` // Used in /admin/journal/{id}
+	RequestID  string    `json:"request_id"`
+	AcceptedAt time.Time `json:"accepted_at"`
+	Model      string    `json:"model"`
+	Stream     bool      `json:"stream"`
+	Replayable bool      `json:"replayable"` // Non-streaming; POST .../replay runs it again
+	Bytes      int       `json:"bytes"`
+}
+
+// journalListResponse is the JSON body served by GET /admin/journal
+type journalListResponse struct {
+	InFlight    int              `json:"in_flight"`   // Journaled requests of this process still being served
+	Interrupted []journalSummary `json:"interrupted"` // Left unanswered by an earlier process, oldest first
+}
+
+// HandleJournal serves the request journal:
+//
+//	GET    /admin/journal              interrupted requests and the in-flight count
+//	GET    /admin/journal/{id}         an interrupted request with its body
+//	POST   /admin/journal/{id}/replay  runs a non-streaming one through the pipeline again
+//	DELETE /admin/journal/{id}         dismisses one as accounted for
+//
+// Replayed and dismissed requests are no longer listed.
+func (h *Handler) HandleJournal(w http.ResponseWriter, r *http.Request) {
+	if h.journal == nil {
+		http.Error(w, "Request journal is disabled", http.StatusNotFound)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, journalPath+"/")
+	if id == r.URL.Path || id == "" {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		resp := journalListResponse{InFlight: h.journal.InFlight(), Interrupted: []journalSummary{}}
+		for _, entry := range h.journal.Interrupted() {
+			resp.Interrupted = append(resp.Interrupted, journalSummary{
+				ID:         entry.ID,
+				RequestID:  entry.RequestID,
+				AcceptedAt: entry.AcceptedAt,
+				Model:      entry.Model,
+				Stream:     entry.Stream,
+				Replayable: !entry.Stream,
+				Bytes:      len(entry.Request),
+			})
+		}
+		writeJournalJSON(w, resp)
+		return
+	}
+
+	if replayID := strings.TrimSuffix(id, "/replay"); replayID != id {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.replayJournalEntry(w, replayID)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		entry, ok := h.journal.Get(id)
+		if !ok {
+			http.Error(w, "Interrupted request "+id+" not found", http.StatusNotFound)
+			return
+		}
+		writeJournalJSON(w, entry)
+	case http.MethodDelete:
+		found, err := h.journal.Resolve(id, 0)
+		if err != nil {
+			http.Error(w, "Failed to update request journal: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !found {
+			http.Error(w, "Interrupted request "+id+" not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, DELETE")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// replayJournalEntry sends an interrupted non-streaming request through the
+// pipeline again and returns its response as is
+func (h *Handler) replayJournalEntry(w http.ResponseWriter, id string) {
+	entry, ok := h.journal.Get(id)
+	if !ok {
+		http.Error(w, "Interrupted request "+id+" not found", http.StatusNotFound)
+		return
+	}
+	if entry.Stream {
+		http.Error(w, "Streaming requests cannot be replayed; dismiss it with DELETE once accounted for", http.StatusConflict)
+		return
+	}
+
+	path := entry.Path
+	if path == "" {
+		path = "/v1/messages"
+	}
+	// The replay is charged to and limited like the client key that sent it
+	req, err := http.NewRequestWithContext(withClientKeyID(context.Background(), entry.ClientKey), http.MethodPost, path, bytes.NewReader(entry.Request))
+	if err != nil {
+		http.Error(w, "Failed to replay request: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(internal.RequestIDHeader, entry.RequestID+"_replay")
+
+	// A panic answers the replay with an api_error so the entry is still resolved
+	rec := &batchResponseWriter{header: make(http.Header), status: http.StatusOK}
+	WithPanicRecovery(h.obsLogger, h.stats, http.HandlerFunc(h.HandleAnthropicRequest)).ServeHTTP(rec, req)
+	if _, err := h.journal.Resolve(id, rec.status); err != nil {
+		http.Error(w, "Failed to update request journal: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", rec.header.Get("Content-Type"))
+	w.Header().Set(internal.RequestIDHeader, rec.header.Get(internal.RequestIDHeader))
+	w.WriteHeader(rec.status)
+	w.Write(rec.body.Bytes())
+}
+
+// writeJournalJSON encodes a journal response
+func writeJournalJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		http.Error(w, "Failed to encode request journal", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,80 @@
+package proxy
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"claude-proxy/types"
+)
+
+// maxPropertyDescriptionChars bounds parameter descriptions in minified schemas
+const maxPropertyDescriptionChars = 120
+
+var (
+	exampleBlockPattern = regexp.MustCompile(`(?s)<example>.*?</example>|<good-example>.*?</good-example>|<bad-example>.*?</bad-example>`)
+	codeFencePattern    = regexp.MustCompile("(?s)```.*?```")
+	blankLinesPattern   = regexp.MustCompile(`\n{3,}`)
+)
+
+// MinifyToolDescription strips example blocks and fenced code from a tool
+// description and truncates it to limit characters at a sentence or word
+// boundary. Short descriptions are returned unchanged.
+func MinifyToolDescription(description string, limit int) string {
+	if limit <= 0 || len(description) <= limit {
+		return description
+	}
+
+	minified := exampleBlockPattern.ReplaceAllString(description, "")
+	minified = codeFencePattern.ReplaceAllString(minified, "")
+	minified = blankLinesPattern.ReplaceAllString(minified, "\n\n")
+	minified = strings.TrimSpace(minified)
+
+	return truncateAtBoundary(minified, limit)
+}
+
+// truncateAtBoundary cuts text to at most limit bytes, preferring the end of
+// the last complete sentence, then the last word. It never splits a
+// multi-byte character.
+func truncateAtBoundary(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	cut := text[:runeBoundary(text, limit)]
+	if idx := strings.LastIndexAny(cut, ".!?\n"); idx >= limit/2 {
+		return strings.TrimSpace(cut[:idx+1])
+	}
+	if limit <= len("...") {
+		return cut
+	}
+	cut = cut[:runeBoundary(cut, limit-len("..."))]
+	if idx := strings.LastIndex(cut, " "); idx > 0 {
+		cut = cut[:idx]
+	}
+	return strings.TrimSpace(cut) + "..."
+}
+
+// runeBoundary backs a byte offset into text up to the start of the rune it
+// falls in, so text[:n] stays valid UTF-8
+func runeBoundary(text string, n int) int {
+	for n > 0 && n < len(text) && !utf8.RuneStart(text[n]) {
+		n--
+	}
+	return n
+}
+
+// minifyToolSchema returns a copy of schema with shortened parameter
+// descriptions. The original schema is left untouched because proxy-side
+// validation and correction use the full definitions.
+func minifyToolSchema(schema types.ToolSchema) types.ToolSchema {
+	if len(schema.Properties) == 0 {
+		return schema
+	}
+	minified := schema
+	minified.Properties = make(map[string]types.ToolProperty, len(schema.Properties))
+	for name, prop := range schema.Properties {
+		prop.Description = truncateAtBoundary(strings.TrimSpace(prop.Description), maxPropertyDescriptionChars)
+		minified.Properties[name] = prop
+	}
+	return minified
+}
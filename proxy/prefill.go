@@ -0,0 +1,118 @@
+package proxy
+
+import (
+	"context"
+	"strings"
+
+	"claude-proxy/config"
+	"claude-proxy/logger"
+	"claude-proxy/types"
+)
+
+// ContinueFinalMessageParam is the passthrough parameter that marks a backend
+// (vLLM) as needing explicit continue_final_message/add_generation_prompt flags
+// to extend a trailing assistant message. llama.cpp continues one by default.
+const ContinueFinalMessageParam = "continue_final_message"
+
+// prefillKey stores the request's assistant prefill in its context
+type prefillKey struct{}
+
+// withAssistantPrefill records the prefill so the response transform can drop
+// it if the backend echoes it back
+func withAssistantPrefill(ctx context.Context, prefill string) context.Context {
+	if prefill == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, prefillKey{}, prefill)
+}
+
+// assistantPrefillFrom returns the request's prefill, or "" when there is none
+func assistantPrefillFrom(ctx context.Context) string {
+	prefill, _ := ctx.Value(prefillKey{}).(string)
+	return prefill
+}
+
+// assistantPrefill returns the text of a trailing assistant message, which
+// Anthropic treats as the beginning of the response to continue. Requests that
+// end with a user turn, or with an assistant tool call, have no prefill.
+func assistantPrefill(req types.AnthropicRequest) string {
+	if len(req.Messages) == 0 {
+		return ""
+	}
+	last := req.Messages[len(req.Messages)-1]
+	if last.Role != "assistant" {
+		return ""
+	}
+
+	var text []string
+	switch content := last.Content.(type) {
+	case string:
+		text = append(text, content)
+	case []interface{}:
+		for _, item := range content {
+			contentMap, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			switch contentMap["type"] {
+			case "text":
+				if t, ok := contentMap["text"].(string); ok {
+					text = append(text, t)
+				}
+			case "tool_use":
+				return ""
+			}
+		}
+	}
+	return strings.TrimRightFunc(strings.Join(text, "\n"), isPrefillSpace)
+}
+
+// isPrefillSpace reports trailing characters Anthropic rejects on a prefill;
+// they would also leave the backend continuing mid-token
+func isPrefillSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r'
+}
+
+// applyAssistantPrefill keeps a trailing assistant message as a partial turn:
+// trailing whitespace is trimmed and backends that need it are told to
+// continue the message rather than start a new one after it
+func applyAssistantPrefill(req types.AnthropicRequest, openaiReq *types.OpenAIRequest, cfg *config.Config, loggerInstance logger.Logger) {
+	prefill := assistantPrefill(req)
+	if prefill == "" || len(openaiReq.Messages) == 0 {
+		return
+	}
+	last := &openaiReq.Messages[len(openaiReq.Messages)-1]
+	if last.Role != "assistant" || len(last.ToolCalls) > 0 {
+		return
+	}
+	last.Content = prefill
+
+	profile, _ := cfg.GetModelProfile(req.Model)
+	if profile.Passes(ContinueFinalMessageParam) {
+		addGenerationPrompt := false
+		openaiReq.ContinueFinalMessage = true
+		openaiReq.AddGenerationPrompt = &addGenerationPrompt
+	}
+	loggerInstance.Debug("✍️ Continuing assistant prefill (%d chars)", len(prefill))
+}
+
+// trimEchoedPrefill removes the prefill from the start of the response text.
+// Anthropic responses contain only the continuation, but some backends repeat
+// the partial assistant turn before extending it.
+func trimEchoedPrefill(ctx context.Context, content []types.Content, loggerInstance logger.Logger) []types.Content {
+	prefill := assistantPrefillFrom(ctx)
+	if prefill == "" {
+		return content
+	}
+	for i := range content {
+		if content[i].Type != "text" {
+			continue
+		}
+		if strings.HasPrefix(content[i].Text, prefill) {
+			content[i].Text = strings.TrimPrefix(content[i].Text, prefill)
+			loggerInstance.Debug("✍️ Removed echoed assistant prefill from response")
+		}
+		break
+	}
+	return content
+}
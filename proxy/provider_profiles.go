@@ -0,0 +1,99 @@
+package proxy
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/logger"
+	"claude-proxy/types"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+)
+
+// applyProviderRequestQuirks adjusts a request for the provider profile of the
+// endpoint it is sent to. req is a copy owned by the caller.
+func applyProviderRequestQuirks(req *types.OpenAIRequest, profile config.ProviderProfile) {
+	if profile.MaxParallelToolCalls == 1 && len(req.Tools) > 0 {
+		parallel := false
+		req.ParallelToolCalls = &parallel
+	}
+	// The complete response is still streamed to a streaming client
+	if profile.NoToolStreaming && req.Stream && len(req.Tools) > 0 {
+		req.Stream = false
+		req.StreamOptions = nil
+	}
+}
+
+// stripProviderParams removes the top-level fields a provider rejects from a
+// serialized request
+func stripProviderParams(body []byte, params []string) ([]byte, error) {
+	if len(params) == 0 {
+		return body, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, err
+	}
+	stripped := false
+	for _, param := range params {
+		if _, exists := fields[param]; exists {
+			delete(fields, param)
+			stripped = true
+		}
+	}
+	if !stripped {
+		return body, nil
+	}
+	return json.Marshal(fields)
+}
+
+// applyProviderResponseQuirks normalizes the tool calls of a provider response
+// so the rest of the pipeline sees the standard OpenAI format
+func applyProviderResponseQuirks(resp *types.OpenAIResponse, profile config.ProviderProfile, proxyLogger logger.Logger) {
+	for i := range resp.Choices {
+		message := &resp.Choices[i].Message
+		if len(profile.TrimStopTokens) > 0 {
+			message.Content = trimStopTokens(message.Content, profile.TrimStopTokens)
+		}
+		if profile.FillToolCallIDs {
+			for j := range message.ToolCalls {
+				if message.ToolCalls[j].ID == "" {
+					message.ToolCalls[j].ID = newToolCallID()
+					proxyLogger.Debug("🔧 Assigned id %s to tool call %s returned without one", message.ToolCalls[j].ID, message.ToolCalls[j].Function.Name)
+				}
+			}
+		}
+		if max := profile.MaxParallelToolCalls; max > 0 && len(message.ToolCalls) > max {
+			proxyLogger.Warn("⚠️ Provider returned %d tool calls, keeping the first %d", len(message.ToolCalls), max)
+			message.ToolCalls = message.ToolCalls[:max]
+		}
+	}
+}
+
+// trimStopTokens removes end-of-turn tokens, in any order, from the end of
+// text; text without a trailing token is returned unchanged
+func trimStopTokens(text string, tokens []string) string {
+	result := text
+	for trimmed := true; trimmed; {
+		trimmed = false
+		stripped := strings.TrimRight(result, " \t\r\n")
+		for _, token := range tokens {
+			if token != "" && strings.HasSuffix(stripped, token) {
+				result = strings.TrimSuffix(stripped, token)
+				trimmed = true
+				break
+			}
+		}
+	}
+	if result == text {
+		return text
+	}
+	return strings.TrimRight(result, " \t\r\n")
+}
+
+// newToolCallID returns an identifier in the call_ format used by OpenAI
+func newToolCallID() string {
+	buf := make([]byte, 12)
+	rand.Read(buf)
+	return "call_" + hex.EncodeToString(buf)
+}
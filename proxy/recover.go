@@ -0,0 +1,106 @@
+package proxy
+
+import (
+	"claude-proxy/internal"
+	"claude-proxy/logger"
+	"claude-proxy/stats"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strings"
+)
+
+// panicRecorder notes whether the response has started, which decides how a
+// recovered panic can still be reported to the client
+type panicRecorder struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (r *panicRecorder) WriteHeader(status int) {
+	r.started = true
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *panicRecorder) Write(p []byte) (int, error) {
+	r.started = true
+	return r.ResponseWriter.Write(p)
+}
+
+// Flush forwards to the underlying writer so SSE streaming keeps working
+func (r *panicRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// WithPanicRecovery recovers panics in next instead of letting net/http drop
+// the connection. The panic is logged with its stack and request ID, counted
+// in collector (may be nil) and answered with an Anthropic api_error; once a
+// stream has started the error is sent as an SSE error event instead.
+func WithPanicRecovery(obsLogger *logger.ObservabilityLogger, collector *stats.Collector, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &panicRecorder{ResponseWriter: w}
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			// Deliberate aborts keep net/http's behavior of silently closing the connection
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			requestID := w.Header().Get(internal.RequestIDHeader)
+			if requestID == "" {
+				requestID = requestIDFromHeader(r.Header)
+				w.Header().Set(internal.RequestIDHeader, requestID)
+			}
+			stack := string(debug.Stack())
+			if obsLogger != nil {
+				obsLogger.Error(logger.ComponentProxy, logger.CategoryError, requestID, "Recovered panic in request handler", map[string]interface{}{
+					"panic":            fmt.Sprint(recovered),
+					"stack":            stack,
+					"method":           r.Method,
+					"path":             r.URL.Path,
+					"response_started": recorder.started,
+					"error_code":       CodeInternal,
+				})
+			}
+			if collector != nil {
+				collector.RecordPanic()
+				collector.RecordErrorEvent(stats.Event{
+					RequestID: requestID,
+					Kind:      string(CodeInternal),
+					Status:    http.StatusInternalServerError,
+					Detail:    fmt.Sprintf("panic: %v", recovered),
+				})
+			}
+
+			message := fmt.Sprintf("Internal proxy error (request %s)", requestID)
+			if !recorder.started {
+				writeProxyError(w, http.StatusInternalServerError, CodeInternal, message)
+				return
+			}
+			if strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream") {
+				writeSSEError(recorder, CodeInternal, message)
+			}
+		}()
+		next.ServeHTTP(recorder, r)
+	})
+}
+
+// writeSSEError ends a started stream with an Anthropic error event
+func writeSSEError(w http.ResponseWriter, code ErrorCode, message string) {
+	var resp errorResponse
+	resp.Type = "error"
+	resp.Error.Type = anthropicErrorType(http.StatusInternalServerError)
+	resp.Error.Code = code
+	resp.Error.Message = message
+	data, _ := json.Marshal(resp)
+	fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,286 @@
+package proxy
+
+import (
+	"claude-proxy/types"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// requestHistoryPath serves the recent request list; /{id} serves one request
+const requestHistoryPath = "/admin/requests"
+
+// RequestRecord is what GET /admin/requests reports about one handled request.
+// It holds shapes, counts and tool names but never message text, so it is safe
+// to expose on the admin surface without masking.
+//
+// All methods are nil-safe so the handler can record unconditionally; they
+// are no-ops when the history is off. Records are only added to the history
+// once the request is finished, so they are not changed after that.
+type RequestRecord struct {
+	RequestID  string           `json:"request_id"`
+	ReceivedAt time.Time        `json:"received_at"`
+	DurationMs float64          `json:"duration_ms"`
+	Status     int              `json:"status"`
+	ErrorCode  ErrorCode        `json:"error_code,omitempty"`
+	Error      string           `json:"error,omitempty"`
+	Model      string           `json:"model,omitempty"`    // Model the client asked for
+	Request    *RequestSummary  `json:"request,omitempty"`  // What the client sent
+	Upstream   *UpstreamSummary `json:"upstream,omitempty"` // What the proxy sent to the backend
+	Response   *ResponseSummary `json:"response,omitempty"` // What the client received
+	Trace      *DebugTrace      `json:"trace,omitempty"`    // Transformation audit trail, detail view only
+}
+
+// RequestSummary describes the client's Anthropic request
+type RequestSummary struct {
+	Messages  int      `json:"messages"`
+	Roles     []string `json:"roles"`
+	System    int      `json:"system_blocks"`
+	Tools     []string `json:"tools,omitempty"`
+	MaxTokens int      `json:"max_tokens"`
+	Stream    bool     `json:"stream"`
+}
+
+// UpstreamSummary describes the OpenAI request sent to the backend
+type UpstreamSummary struct {
+	Model          string   `json:"model"`
+	Messages       int      `json:"messages"`
+	Roles          []string `json:"roles"`
+	Tools          []string `json:"tools,omitempty"`
+	ToolChoice     string   `json:"tool_choice,omitempty"`
+	MaxTokens      int      `json:"max_tokens,omitempty"`
+	Temperature    float64  `json:"temperature,omitempty"`
+	ResponseFormat string   `json:"response_format,omitempty"`
+}
+
+// ResponseSummary describes the Anthropic response returned to the client
+type ResponseSummary struct {
+	StopReason   string   `json:"stop_reason"`
+	ContentTypes []string `json:"content_types"`
+	ToolCalls    []string `json:"tool_calls,omitempty"`
+	InputTokens  int      `json:"input_tokens"`
+	OutputTokens int      `json:"output_tokens"`
+}
+
+// SetRequest records the client's request
+func (r *RequestRecord) SetRequest(req types.AnthropicRequest) {
+	if r == nil {
+		return
+	}
+	summary := &RequestSummary{
+		Messages:  len(req.Messages),
+		Roles:     make([]string, 0, len(req.Messages)),
+		System:    len(req.System),
+		MaxTokens: req.MaxTokens,
+		Stream:    req.Stream,
+	}
+	for _, message := range req.Messages {
+		summary.Roles = append(summary.Roles, message.Role)
+	}
+	for _, tool := range req.Tools {
+		summary.Tools = append(summary.Tools, tool.Name)
+	}
+
+	r.Model = req.Model
+	r.Request = summary
+}
+
+// SetUpstream records the request sent to the backend
+func (r *RequestRecord) SetUpstream(req types.OpenAIRequest) {
+	if r == nil {
+		return
+	}
+	summary := &UpstreamSummary{
+		Model:       req.Model,
+		Messages:    len(req.Messages),
+		Roles:       make([]string, 0, len(req.Messages)),
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+	}
+	if req.MaxCompletionTokens > 0 {
+		summary.MaxTokens = req.MaxCompletionTokens
+	}
+	for _, message := range req.Messages {
+		summary.Roles = append(summary.Roles, message.Role)
+	}
+	for _, tool := range req.Tools {
+		summary.Tools = append(summary.Tools, tool.Function.Name)
+	}
+	if req.ToolChoice != nil {
+		if choice, err := json.Marshal(req.ToolChoice); err == nil {
+			summary.ToolChoice = strings.Trim(string(choice), `"`)
+		}
+	}
+	if req.ResponseFormat != nil {
+		summary.ResponseFormat = req.ResponseFormat.Type
+	}
+
+	r.Upstream = summary
+}
+
+// SetResponse records the response returned to the client
+func (r *RequestRecord) SetResponse(resp *types.AnthropicResponse) {
+	if r == nil || resp == nil {
+		return
+	}
+	summary := &ResponseSummary{
+		StopReason:   resp.StopReason,
+		ContentTypes: make([]string, 0, len(resp.Content)),
+		InputTokens:  resp.Usage.InputTokens,
+		OutputTokens: resp.Usage.OutputTokens,
+	}
+	for _, content := range resp.Content {
+		summary.ContentTypes = append(summary.ContentTypes, content.Type)
+		if content.Type == "tool_use" {
+			summary.ToolCalls = append(summary.ToolCalls, content.Name)
+		}
+	}
+
+	r.Response = summary
+}
+
+// SetTrace attaches the request's transformation audit trail
+func (r *RequestRecord) SetTrace(trace *DebugTrace) {
+	if r == nil {
+		return
+	}
+	r.Trace = trace
+}
+
+// finish stamps the outcome once the response has been written
+func (r *RequestRecord) finish(status int, code ErrorCode, message string) {
+	r.DurationMs = durationMs(time.Since(r.ReceivedAt))
+	r.Status = status
+	r.ErrorCode = code
+	r.Error = message
+}
+
+// summary returns a copy without the audit trail, for the list view
+func (r *RequestRecord) summary() *RequestRecord {
+	return &RequestRecord{
+		RequestID:  r.RequestID,
+		ReceivedAt: r.ReceivedAt,
+		DurationMs: r.DurationMs,
+		Status:     r.Status,
+		ErrorCode:  r.ErrorCode,
+		Error:      r.Error,
+		Model:      r.Model,
+		Request:    r.Request,
+		Upstream:   r.Upstream,
+		Response:   r.Response,
+	}
+}
+
+// requestHistory is a fixed-size ring of the most recent requests
+type requestHistory struct {
+	mu      sync.Mutex
+	records []*RequestRecord
+	next    int
+	size    int
+}
+
+// newRequestHistory keeps the last size requests; nil when size is 0
+func newRequestHistory(size int) *requestHistory {
+	if size <= 0 {
+		return nil
+	}
+	return &requestHistory{size: size}
+}
+
+// begin starts a record for a request received at receivedAt. It returns nil
+// when the history is off.
+func (h *requestHistory) begin(requestID string, receivedAt time.Time) *RequestRecord {
+	if h == nil {
+		return nil
+	}
+	return &RequestRecord{RequestID: requestID, ReceivedAt: receivedAt}
+}
+
+// add stores a finished record, overwriting the oldest once full
+func (h *requestHistory) add(record *RequestRecord) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.records) < h.size {
+		h.records = append(h.records, record)
+		return
+	}
+	h.records[h.next] = record
+	h.next = (h.next + 1) % h.size
+}
+
+// list returns up to limit records, newest first
+func (h *requestHistory) list(limit int) []*RequestRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if limit <= 0 || limit > len(h.records) {
+		limit = len(h.records)
+	}
+	records := make([]*RequestRecord, 0, limit)
+	for i := len(h.records) - 1; i >= 0 && len(records) < limit; i-- {
+		records = append(records, h.records[(h.next+i)%len(h.records)].summary())
+	}
+	return records
+}
+
+// get returns the newest record with the request ID
+func (h *requestHistory) get(requestID string) (*RequestRecord, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i := len(h.records) - 1; i >= 0; i-- {
+		if record := h.records[(h.next+i)%len(h.records)]; record.RequestID == requestID {
+			return record, true
+		}
+	}
+	return nil, false
+}
+
+// requestHistoryResponse is the JSON body served by GET /admin/requests
+type requestHistoryResponse struct {
+	Requests []*RequestRecord `json:"requests"`
+}
+
+// HandleRequestHistory serves GET /admin/requests?limit=... (newest first) and
+// GET /admin/requests/{id}, which adds the transformation audit trail
+func (h *Handler) HandleRequestHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.history == nil {
+		http.Error(w, "Request history is disabled", http.StatusNotFound)
+		return
+	}
+
+	if id := strings.TrimPrefix(r.URL.Path, requestHistoryPath+"/"); id != r.URL.Path {
+		record, ok := h.history.get(id)
+		if !ok || id == "" {
+			http.Error(w, "Request "+id+" not found", http.StatusNotFound)
+			return
+		}
+		writeRequestHistoryJSON(w, record)
+		return
+	}
+
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	writeRequestHistoryJSON(w, requestHistoryResponse{Requests: h.history.list(limit)})
+}
+
+// writeRequestHistoryJSON encodes a history response
+func writeRequestHistoryJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		http.Error(w, "Failed to encode request history", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,206 @@
+package proxy
+
+import (
+	"claude-proxy/batch"
+	"claude-proxy/config"
+	"claude-proxy/conversation"
+	"claude-proxy/journal"
+	"claude-proxy/stats"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// PurgeResult reports what a retention run or purge request deleted
+type PurgeResult struct {
+	Conversations int  `json:"conversations"`
+	Batches       int  `json:"batches"`
+	Journal       int  `json:"journal"`
+	StatsReset    bool `json:"stats_reset"`
+}
+
+// RetentionManager deletes stored conversations, ended message batches,
+// interrupted journal entries and cumulative stats once they outlive the
+// configured retention. Stores that are not in use are nil and skipped.
+type RetentionManager struct {
+	config        *config.Config
+	conversations *conversation.Store
+	batches       *batch.Store
+	journal       *journal.Journal
+	stats         *stats.Collector
+}
+
+// NewRetentionManager creates a retention manager for the given stores
+func NewRetentionManager(cfg *config.Config, conversations *conversation.Store, batches *batch.Store, requestJournal *journal.Journal, collector *stats.Collector) *RetentionManager {
+	return &RetentionManager{config: cfg, conversations: conversations, batches: batches, journal: requestJournal, stats: collector}
+}
+
+// days returns the duration of n days
+func days(n int) time.Duration {
+	return time.Duration(n) * 24 * time.Hour
+}
+
+// Enforce applies CONVERSATION_RETENTION_DAYS, CONVERSATION_MAX_MB,
+// BATCH_RETENTION_DAYS, JOURNAL_RETENTION_DAYS and STATS_RETENTION_DAYS as of now
+func (m *RetentionManager) Enforce(now time.Time) (PurgeResult, error) {
+	var result PurgeResult
+	if m.conversations != nil && (m.config.ConversationRetentionDays > 0 || m.config.ConversationMaxMB > 0) {
+		var before time.Time
+		if m.config.ConversationRetentionDays > 0 {
+			before = now.Add(-days(m.config.ConversationRetentionDays))
+		}
+		deleted, err := m.conversations.Purge(before, int64(m.config.ConversationMaxMB)<<20)
+		if err != nil {
+			return result, err
+		}
+		result.Conversations = deleted
+	}
+	if m.batches != nil && m.config.BatchRetentionDays > 0 {
+		deleted, err := m.batches.PurgeEnded(now.Add(-days(m.config.BatchRetentionDays)))
+		if err != nil {
+			return result, err
+		}
+		result.Batches = deleted
+	}
+	if m.journal != nil && m.config.JournalRetentionDays > 0 {
+		dismissed, err := m.journal.Purge(now.Add(-days(m.config.JournalRetentionDays)))
+		if err != nil {
+			return result, err
+		}
+		result.Journal = dismissed
+	}
+	if m.stats != nil && m.config.StatsRetentionDays > 0 && m.stats.Totals().Since.Before(now.Add(-days(m.config.StatsRetentionDays))) {
+		m.stats.ResetTotals()
+		result.StatsReset = true
+	}
+	return result, nil
+}
+
+// Start enforces retention now and every interval until the returned function
+// is called. onPurge is called after runs that deleted something.
+func (m *RetentionManager) Start(interval time.Duration, onPurge func(PurgeResult), onError func(error)) func() {
+	run := func() {
+		result, err := m.Enforce(time.Now())
+		if err != nil {
+			if onError != nil {
+				onError(err)
+			}
+			return
+		}
+		if (result.Conversations > 0 || result.Batches > 0 || result.Journal > 0 || result.StatsReset) && onPurge != nil {
+			onPurge(result)
+		}
+	}
+	run()
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				run()
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-done
+	}
+}
+
+// HandlePurge serves POST /admin/purge?target=conversations|batches|journal|stats.
+// Conversations are selected with before=<RFC 3339 time>, older_than=<duration>,
+// session=<id> and/or request_id=<id>; batches that ended before the given
+// time are deleted; interrupted journal entries are selected by request_id or
+// age; stats resets the cumulative counters.
+func (m *RetentionManager) HandlePurge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := r.URL.Query()
+	var before time.Time
+	if value := params.Get("before"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			http.Error(w, "before must be an RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+		before = parsed
+	}
+	if value := params.Get("older_than"); value != "" {
+		age, err := time.ParseDuration(value)
+		if err != nil || age < 0 {
+			http.Error(w, "older_than must be a duration such as 720h", http.StatusBadRequest)
+			return
+		}
+		before = time.Now().Add(-age)
+	}
+	sessionID, requestID := params.Get("session"), params.Get("request_id")
+
+	var result PurgeResult
+	var err error
+	switch params.Get("target") {
+	case "conversations":
+		if m.conversations == nil {
+			http.Error(w, "Conversation search is disabled", http.StatusNotFound)
+			return
+		}
+		switch {
+		case sessionID != "" || requestID != "":
+			result.Conversations, err = m.conversations.Delete(sessionID, requestID)
+		case !before.IsZero():
+			result.Conversations, err = m.conversations.Purge(before, 0)
+		default:
+			http.Error(w, "conversations need before, older_than, session or request_id", http.StatusBadRequest)
+			return
+		}
+	case "batches":
+		if m.batches == nil {
+			http.Error(w, "Message batches are disabled", http.StatusNotFound)
+			return
+		}
+		if before.IsZero() {
+			http.Error(w, "batches need before or older_than", http.StatusBadRequest)
+			return
+		}
+		result.Batches, err = m.batches.PurgeEnded(before)
+	case "journal":
+		if m.journal == nil {
+			http.Error(w, "Request journal is disabled", http.StatusNotFound)
+			return
+		}
+		switch {
+		case requestID != "":
+			result.Journal, err = m.journal.Delete(requestID)
+		case !before.IsZero():
+			result.Journal, err = m.journal.Purge(before)
+		default:
+			http.Error(w, "journal needs before, older_than or request_id", http.StatusBadRequest)
+			return
+		}
+	case "stats":
+		m.stats.ResetTotals()
+		result.StatsReset = true
+	default:
+		http.Error(w, "target must be conversations, batches, journal or stats", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		http.Error(w, "Failed to encode purge result", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,134 @@
+package proxy
+
+import (
+	"fmt"
+	"strings"
+
+	"claude-proxy/config"
+	"claude-proxy/logger"
+	"claude-proxy/types"
+)
+
+// applySamplingParams translates Anthropic sampling parameters to the OpenAI
+// request, applying the backend's model profile: rejected parameters are
+// dropped, missing ones receive the profile default and out-of-range values
+// are clamped.
+func applySamplingParams(req types.AnthropicRequest, openaiReq *types.OpenAIRequest, cfg *config.Config, loggerInstance logger.Logger) {
+	profile, hasProfile := cfg.GetModelProfile(req.Model)
+
+	temperature := req.Temperature
+	topP := req.TopP
+	if hasProfile {
+		temperature = applyParamProfile("temperature", temperature, profile.Temperature, profile, loggerInstance)
+		topP = applyParamProfile("top_p", topP, profile.TopP, profile, loggerInstance)
+	}
+
+	if temperature != nil {
+		openaiReq.Temperature = *temperature
+	}
+	openaiReq.TopP = topP
+}
+
+// applyStreamOptions asks streaming backends to append a usage chunk so the
+// Anthropic message_delta reports real token counts
+func applyStreamOptions(req types.AnthropicRequest, openaiReq *types.OpenAIRequest, cfg *config.Config) {
+	if !openaiReq.Stream || !cfg.StreamIncludeUsage {
+		return
+	}
+	if profile, exists := cfg.GetModelProfile(req.Model); exists && profile.Drops("stream_options") {
+		return
+	}
+	openaiReq.StreamOptions = &types.StreamOptions{IncludeUsage: true}
+}
+
+// maxStopSequences is the OpenAI limit on the number of stop sequences
+const maxStopSequences = 4
+
+// applyAnthropicOnlyParams translates sampling fields that have no standard
+// OpenAI equivalent. stop_sequences maps to stop; top_k and n are forwarded
+// only to backends whose profile lists them in passthroughParams. Anything that cannot
+// be sent is reported in a structured warning instead of being dropped silently.
+func applyAnthropicOnlyParams(req types.AnthropicRequest, openaiReq *types.OpenAIRequest, cfg *config.Config, loggerInstance logger.Logger) {
+	profile, _ := cfg.GetModelProfile(req.Model)
+	var dropped []string
+
+	if req.TopK != nil {
+		if profile.Passes("top_k") {
+			openaiReq.TopK = req.TopK
+		} else {
+			dropped = append(dropped, fmt.Sprintf("top_k=%d", *req.TopK))
+		}
+	}
+
+	if req.N != nil && *req.N != 1 {
+		if profile.Passes("n") {
+			openaiReq.N = req.N
+		} else {
+			dropped = append(dropped, fmt.Sprintf("n=%d", *req.N))
+		}
+	}
+
+	if len(req.StopSequences) > 0 {
+		switch {
+		case profile.Drops("stop"):
+			dropped = append(dropped, fmt.Sprintf("stop_sequences(%d)", len(req.StopSequences)))
+		case len(req.StopSequences) > maxStopSequences:
+			openaiReq.Stop = req.StopSequences[:maxStopSequences]
+			dropped = append(dropped, fmt.Sprintf("stop_sequences(%d beyond limit of %d)", len(req.StopSequences)-maxStopSequences, maxStopSequences))
+		default:
+			openaiReq.Stop = req.StopSequences
+		}
+	}
+
+	if len(dropped) > 0 {
+		loggerInstance.WithField("dropped_params", strings.Join(dropped, ",")).
+			Warn("⚠️ Dropped Anthropic sampling parameters not supported by backend %s: %s", req.Model, strings.Join(dropped, ", "))
+	}
+}
+
+// applyMaxTokens clamps max_tokens to the backend's output limit and moves it
+// to max_completion_tokens for backends that reject max_tokens
+func applyMaxTokens(req types.AnthropicRequest, openaiReq *types.OpenAIRequest, cfg *config.Config, loggerInstance logger.Logger) {
+	profile, hasProfile := cfg.GetModelProfile(req.Model)
+	if !hasProfile {
+		return
+	}
+
+	maxTokens := openaiReq.MaxTokens
+	if profile.MaxOutputTokens > 0 && maxTokens > profile.MaxOutputTokens {
+		loggerInstance.Info("🎛️ Clamped max_tokens %d -> %d for backend model", maxTokens, profile.MaxOutputTokens)
+		maxTokens = profile.MaxOutputTokens
+	}
+
+	switch {
+	case profile.Drops(config.MaxTokensFieldDefault):
+		if maxTokens > 0 {
+			loggerInstance.Debug("🎛️ Dropped max_tokens=%d (not supported by backend)", maxTokens)
+		}
+		openaiReq.MaxTokens = 0
+	case profile.MaxTokensField == config.MaxTokensFieldCompletion:
+		openaiReq.MaxTokens = 0
+		openaiReq.MaxCompletionTokens = maxTokens
+	default:
+		openaiReq.MaxTokens = maxTokens
+	}
+}
+
+// applyParamProfile applies drop/default/clamp rules for a single parameter
+func applyParamProfile(name string, value *float64, paramRange *config.ParamRange, profile config.ModelProfile, loggerInstance logger.Logger) *float64 {
+	if profile.Drops(name) {
+		if value != nil {
+			loggerInstance.Debug("🎛️ Dropped %s=%v (not supported by backend)", name, *value)
+		}
+		return nil
+	}
+
+	adjusted := paramRange.Apply(value)
+	switch {
+	case value == nil && adjusted != nil:
+		loggerInstance.Debug("🎛️ Using default %s=%v from model profile", name, *adjusted)
+	case value != nil && adjusted != nil && *value != *adjusted:
+		loggerInstance.Info("🎛️ Clamped %s %v -> %v for backend model", name, *value, *adjusted)
+	}
+	return adjusted
+}
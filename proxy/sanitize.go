@@ -0,0 +1,163 @@
+package proxy
+
+import (
+	"claude-proxy/logger"
+	"claude-proxy/types"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Field names the typed request structs understand, derived from their json
+// tags so new struct fields are recognized automatically
+var (
+	knownRequestFields = jsonFieldNames(reflect.TypeOf(types.AnthropicRequest{}))
+	knownToolFields    = jsonFieldNames(reflect.TypeOf(types.Tool{}))
+	knownSystemFields  = jsonFieldNames(reflect.TypeOf(types.SystemContent{}))
+)
+
+// reportedUnknownFields remembers which unknown fields were already logged so
+// each new field name is reported once per process rather than per request
+var reportedUnknownFields sync.Map
+
+// sanitizeRequestBody prepares a raw Anthropic request for decoding into
+// types.AnthropicRequest. It collects fields the proxy does not model (e.g.
+// "thinking", "tools[].cache_control") and normalizes valid Anthropic shapes
+// the typed structs would otherwise reject:
+//   - "system" given as a plain string
+//   - JSON Schema "type" given as an array (e.g. ["string", "null"]) on tool
+//     properties and array items
+//
+// Bodies that are not JSON objects are returned unchanged so decoding reports
+// the error as before.
+func sanitizeRequestBody(body []byte) ([]byte, []string) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(body, &raw); err != nil || raw == nil {
+		return body, nil
+	}
+
+	unknown := make(map[string]bool)
+	collectUnknown(raw, knownRequestFields, "", unknown)
+
+	normalized := false
+	if system, ok := raw["system"].(string); ok {
+		raw["system"] = []interface{}{map[string]interface{}{"type": "text", "text": system}}
+		normalized = true
+	}
+	if systemBlocks, ok := raw["system"].([]interface{}); ok {
+		for _, block := range systemBlocks {
+			if blockMap, ok := block.(map[string]interface{}); ok {
+				collectUnknown(blockMap, knownSystemFields, "system[].", unknown)
+			}
+		}
+	}
+	if tools, ok := raw["tools"].([]interface{}); ok {
+		for _, tool := range tools {
+			toolMap, ok := tool.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			collectUnknown(toolMap, knownToolFields, "tools[].", unknown)
+			if normalizeToolSchemaTypes(toolMap) {
+				normalized = true
+			}
+		}
+	}
+
+	fields := make([]string, 0, len(unknown))
+	for field := range unknown {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	if !normalized {
+		return body, fields
+	}
+	sanitized, err := json.Marshal(raw)
+	if err != nil {
+		return body, fields
+	}
+	return sanitized, fields
+}
+
+// normalizeToolSchemaTypes collapses array-valued "type" entries in a tool's
+// properties (and their items) to the first non-null type
+func normalizeToolSchemaTypes(tool map[string]interface{}) bool {
+	schema, ok := tool["input_schema"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	properties, ok := schema["properties"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	changed := false
+	for _, property := range properties {
+		propertyMap, ok := property.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if collapseSchemaType(propertyMap) {
+			changed = true
+		}
+		if items, ok := propertyMap["items"].(map[string]interface{}); ok && collapseSchemaType(items) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// collapseSchemaType replaces a "type" array with its first non-null entry
+func collapseSchemaType(schema map[string]interface{}) bool {
+	typeList, ok := schema["type"].([]interface{})
+	if !ok {
+		return false
+	}
+	collapsed := "string"
+	for _, entry := range typeList {
+		if typeName, ok := entry.(string); ok && typeName != "null" {
+			collapsed = typeName
+			break
+		}
+	}
+	schema["type"] = collapsed
+	return true
+}
+
+// collectUnknown records keys of object missing from known, prefixed with path
+func collectUnknown(object map[string]interface{}, known map[string]bool, path string, unknown map[string]bool) {
+	for field := range object {
+		if !known[field] {
+			unknown[path+field] = true
+		}
+	}
+}
+
+// jsonFieldNames returns the json names of a struct's fields
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("json")
+		name := strings.Split(tag, ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = t.Field(i).Name
+		}
+		names[name] = true
+	}
+	return names
+}
+
+// logUnknownFields reports unknown request fields the first time each is seen
+func logUnknownFields(fields []string, loggerInstance logger.Logger) {
+	for _, field := range fields {
+		if _, seen := reportedUnknownFields.LoadOrStore(field, true); !seen {
+			loggerInstance.Info("🆕 Ignoring unknown request field %q (not forwarded upstream)", field)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package proxy
+
+import (
+	"claude-proxy/types"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// TestSanitizeRequestBody tests unknown field collection and shape normalization
+func TestSanitizeRequestBody(t *testing.T) {
+	body := []byte(`{
+		"model": "claude-sonnet-4-20250514",
+		"max_tokens": 100,
+		"system": "You are helpful",
+		"metadata": {"user_id": "abc"},
+		"thinking": {"type": "enabled", "budget_tokens": 1024},
+		"messages": [{"role": "user", "content": "hi"}],
+		"tools": [{
+			"name": "Read",
+			"description": "Read a file",
+			"cache_control": {"type": "ephemeral"},
+			"input_schema": {"type": "object", "properties": {
+				"file_path": {"type": "string"},
+				"limit": {"type": ["integer", "null"]},
+				"paths": {"type": "array", "items": {"type": ["null", "string"]}}
+			}}
+		}]
+	}`)
+
+	sanitized, unknown := sanitizeRequestBody(body)
+
+	expectedUnknown := []string{"thinking", "tools[].cache_control"}
+	if !reflect.DeepEqual(unknown, expectedUnknown) {
+		t.Errorf("Expected unknown fields %v, got %v", expectedUnknown, unknown)
+	}
+
+	var req types.AnthropicRequest
+	if err := json.Unmarshal(sanitized, &req); err != nil {
+		t.Fatalf("Sanitized body should decode, got: %v", err)
+	}
+	if len(req.System) != 1 || req.System[0].Text != "You are helpful" {
+		t.Errorf("Expected string system prompt to become a text block, got %+v", req.System)
+	}
+	properties := req.Tools[0].InputSchema.Properties
+	if properties["limit"].Type != "integer" {
+		t.Errorf("Expected limit type integer, got %q", properties["limit"].Type)
+	}
+	if properties["paths"].Items == nil || properties["paths"].Items.Type != "string" {
+		t.Errorf("Expected paths items type string, got %+v", properties["paths"].Items)
+	}
+}
+
+// TestSanitizeRequestBodyPassthrough tests that bodies needing no changes are returned as-is
+func TestSanitizeRequestBodyPassthrough(t *testing.T) {
+	body := []byte(`{"model": "m", "messages": [{"role": "user", "content": "hi"}], "stream": true}`)
+	sanitized, unknown := sanitizeRequestBody(body)
+	if string(sanitized) != string(body) || len(unknown) != 0 {
+		t.Errorf("Expected unchanged body and no unknown fields, got %s %v", sanitized, unknown)
+	}
+
+	invalid := []byte(`{not json`)
+	if sanitized, _ := sanitizeRequestBody(invalid); string(sanitized) != string(invalid) {
+		t.Errorf("Expected invalid body to be returned unchanged")
+	}
+}
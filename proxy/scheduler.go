@@ -0,0 +1,136 @@
+package proxy
+
+import (
+	"claude-proxy/config"
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// PriorityHeader lets clients choose the priority class of a request
+const PriorityHeader = "X-Proxy-Priority"
+
+// Request priority classes, in the order queued requests are served
+const (
+	PriorityInteractive = "interactive" // Someone is waiting on the answer
+	PriorityBackground  = "background"  // Titles, summaries and other side work
+)
+
+// requestPriority returns the priority class of a request: the one named by
+// PriorityHeader, else background for SMALL_MODEL (Claude Code's Haiku side
+// calls) and interactive for everything else
+func requestPriority(header http.Header, mappedModel string, cfg *config.Config) string {
+	switch strings.ToLower(strings.TrimSpace(header.Get(PriorityHeader))) {
+	case PriorityInteractive:
+		return PriorityInteractive
+	case PriorityBackground:
+		return PriorityBackground
+	}
+	if mappedModel == cfg.SmallModel {
+		return PriorityBackground
+	}
+	return PriorityInteractive
+}
+
+// requestScheduler bounds the upstream calls in progress to
+// MAX_CONCURRENT_REQUESTS. Once saturated, calls wait in a queue per priority
+// class; a freed slot goes to the oldest interactive call before any
+// background call.
+type requestScheduler struct {
+	mu          sync.Mutex
+	limit       int
+	active      int
+	interactive []chan struct{}
+	background  []chan struct{}
+}
+
+// newRequestScheduler creates a scheduler; a limit of 0 or less is unlimited
+func newRequestScheduler(limit int) *requestScheduler {
+	return &requestScheduler{limit: limit}
+}
+
+// acquire waits for a slot and returns the function that frees it. Returns
+// the context's error when it ends first.
+func (s *requestScheduler) acquire(ctx context.Context, priority string) (func(), error) {
+	if s.limit <= 0 {
+		return func() {}, nil
+	}
+
+	s.mu.Lock()
+	ahead := len(s.interactive)
+	if priority != PriorityInteractive {
+		ahead += len(s.background)
+	}
+	if s.active < s.limit && ahead == 0 {
+		s.active++
+		s.mu.Unlock()
+		return s.release, nil
+	}
+	ready := make(chan struct{})
+	if priority == PriorityInteractive {
+		s.interactive = append(s.interactive, ready)
+	} else {
+		s.background = append(s.background, ready)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-ready:
+		return s.release, nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		removed := removeWaiter(&s.interactive, ready) || removeWaiter(&s.background, ready)
+		s.mu.Unlock()
+		if !removed {
+			s.release() // The slot was handed over while the context ended
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// release hands the slot to the next queued call, or frees it
+func (s *requestScheduler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, queue := range []*[]chan struct{}{&s.interactive, &s.background} {
+		if len(*queue) > 0 {
+			next := (*queue)[0]
+			*queue = (*queue)[1:]
+			close(next)
+			return
+		}
+	}
+	s.active--
+}
+
+// SchedulerSnapshot is the JSON view of the upstream call scheduler
+type SchedulerSnapshot struct {
+	Limit             int `json:"limit"` // MAX_CONCURRENT_REQUESTS, 0 = unlimited
+	Active            int `json:"active"`
+	QueuedInteractive int `json:"queued_interactive"`
+	QueuedBackground  int `json:"queued_background"`
+}
+
+// snapshot returns the slots in use and the calls waiting per priority class
+func (s *requestScheduler) snapshot() SchedulerSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return SchedulerSnapshot{
+		Limit:             s.limit,
+		Active:            s.active,
+		QueuedInteractive: len(s.interactive),
+		QueuedBackground:  len(s.background),
+	}
+}
+
+// removeWaiter removes ready from queue, reporting whether it was queued
+func removeWaiter(queue *[]chan struct{}, ready chan struct{}) bool {
+	for i, waiter := range *queue {
+		if waiter == ready {
+			*queue = append((*queue)[:i], (*queue)[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,270 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"claude-proxy/logger"
+	"claude-proxy/types"
+)
+
+// clientReleasePattern extracts the Claude Code version from its User-Agent,
+// e.g. "claude-cli/1.0.83 (external, cli)"
+var clientReleasePattern = regexp.MustCompile(`claude-cli/([0-9][0-9A-Za-z.\-]*)`)
+
+// ToolSchemaFingerprint is the last known input_schema of a tool
+type ToolSchemaFingerprint struct {
+	Hash       string            `json:"hash"`
+	Release    string            `json:"release,omitempty"` // Client release that sent this schema first
+	Properties map[string]string `json:"properties"`        // Parameter name -> type
+	Required   []string          `json:"required"`
+	FirstSeen  time.Time         `json:"first_seen"`
+}
+
+// SchemaDrift describes how a known tool's schema changed
+type SchemaDrift struct {
+	Tool            string
+	PreviousRelease string
+	Release         string
+	AddedParams     []string
+	RemovedParams   []string
+	AddedRequired   []string
+	RemovedRequired []string
+	TypeChanges     []string // "param: old -> new"
+}
+
+// String summarizes the drift for logs
+func (d SchemaDrift) String() string {
+	var parts []string
+	add := func(label string, values []string) {
+		if len(values) > 0 {
+			parts = append(parts, fmt.Sprintf("%s [%s]", label, strings.Join(values, ", ")))
+		}
+	}
+	add("added params", d.AddedParams)
+	add("removed params", d.RemovedParams)
+	add("newly required", d.AddedRequired)
+	add("no longer required", d.RemovedRequired)
+	add("type changes", d.TypeChanges)
+	if len(parts) == 0 {
+		parts = append(parts, "nested schema details changed")
+	}
+	return strings.Join(parts, "; ")
+}
+
+// ToolSchemaRegistry persists a fingerprint per tool name so schema changes
+// between Claude Code releases are noticed. Correction rules and tool
+// description overrides are written against specific schemas and silently go
+// stale when Anthropic updates a tool definition.
+//
+// Thread Safety: All methods are safe for concurrent use.
+type ToolSchemaRegistry struct {
+	mu    sync.Mutex
+	path  string
+	tools map[string]ToolSchemaFingerprint
+}
+
+var (
+	toolSchemaRegistriesMu sync.Mutex
+	toolSchemaRegistries   = make(map[string]*ToolSchemaRegistry)
+)
+
+// NewToolSchemaRegistry opens the registry stored at path; a missing file yields an empty registry
+func NewToolSchemaRegistry(path string) (*ToolSchemaRegistry, error) {
+	registry := &ToolSchemaRegistry{path: path, tools: make(map[string]ToolSchemaFingerprint)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return registry, nil
+		}
+		return nil, fmt.Errorf("failed to read tool schema registry %s: %v", path, err)
+	}
+	if err := json.Unmarshal(data, &registry.tools); err != nil {
+		return nil, fmt.Errorf("failed to parse tool schema registry %s: %v", path, err)
+	}
+	return registry, nil
+}
+
+// toolSchemaRegistryFor returns the process-wide registry for path, opening it on first use
+func toolSchemaRegistryFor(path string) (*ToolSchemaRegistry, error) {
+	toolSchemaRegistriesMu.Lock()
+	defer toolSchemaRegistriesMu.Unlock()
+	if registry, exists := toolSchemaRegistries[path]; exists {
+		return registry, nil
+	}
+	registry, err := NewToolSchemaRegistry(path)
+	if err != nil {
+		return nil, err
+	}
+	toolSchemaRegistries[path] = registry
+	return registry, nil
+}
+
+// fingerprintToolSchema hashes the canonical JSON of a schema. encoding/json
+// sorts map keys, so equal schemas always produce the same hash.
+func fingerprintToolSchema(schema types.ToolSchema, release string) ToolSchemaFingerprint {
+	data, _ := json.Marshal(schema)
+	sum := sha256.Sum256(data)
+
+	properties := make(map[string]string, len(schema.Properties))
+	for name, property := range schema.Properties {
+		properties[name] = property.Type
+	}
+	required := append([]string{}, schema.Required...)
+	sort.Strings(required)
+
+	return ToolSchemaFingerprint{
+		Hash:       hex.EncodeToString(sum[:]),
+		Release:    release,
+		Properties: properties,
+		Required:   required,
+		FirstSeen:  time.Now().UTC(),
+	}
+}
+
+// Observe records the schemas of a request's tools and returns the drift for
+// every known tool whose schema changed. New and changed schemas become the
+// baseline, so each change is reported once.
+func (r *ToolSchemaRegistry) Observe(tools []types.Tool, release string) ([]SchemaDrift, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var drifts []SchemaDrift
+	changed := false
+	for _, tool := range tools {
+		current := fingerprintToolSchema(tool.InputSchema, release)
+		previous, known := r.tools[tool.Name]
+		if known && previous.Hash == current.Hash {
+			continue
+		}
+		if known {
+			drifts = append(drifts, diffToolSchemas(tool.Name, previous, current))
+		}
+		r.tools[tool.Name] = current
+		changed = true
+	}
+
+	if !changed {
+		return drifts, nil
+	}
+	return drifts, r.saveLocked()
+}
+
+// Get returns the stored fingerprint of a tool
+func (r *ToolSchemaRegistry) Get(toolName string) (ToolSchemaFingerprint, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fingerprint, exists := r.tools[toolName]
+	return fingerprint, exists
+}
+
+// diffToolSchemas compares two fingerprints of the same tool
+func diffToolSchemas(toolName string, previous, current ToolSchemaFingerprint) SchemaDrift {
+	drift := SchemaDrift{Tool: toolName, PreviousRelease: previous.Release, Release: current.Release}
+	for name, currentType := range current.Properties {
+		previousType, existed := previous.Properties[name]
+		switch {
+		case !existed:
+			drift.AddedParams = append(drift.AddedParams, name)
+		case previousType != currentType:
+			drift.TypeChanges = append(drift.TypeChanges, fmt.Sprintf("%s: %s -> %s", name, previousType, currentType))
+		}
+	}
+	for name := range previous.Properties {
+		if _, exists := current.Properties[name]; !exists {
+			drift.RemovedParams = append(drift.RemovedParams, name)
+		}
+	}
+	drift.AddedRequired = missingFrom(current.Required, previous.Required)
+	drift.RemovedRequired = missingFrom(previous.Required, current.Required)
+
+	sort.Strings(drift.AddedParams)
+	sort.Strings(drift.RemovedParams)
+	sort.Strings(drift.TypeChanges)
+	return drift
+}
+
+// missingFrom returns values of a that are not in b
+func missingFrom(a, b []string) []string {
+	var missing []string
+	for _, value := range a {
+		found := false
+		for _, other := range b {
+			if other == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, value)
+		}
+	}
+	return missing
+}
+
+// saveLocked writes the registry atomically via a temp file; the caller holds r.mu
+func (r *ToolSchemaRegistry) saveLocked() error {
+	data, err := json.MarshalIndent(r.tools, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode tool schema registry: %v", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), ".tool_schemas-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write tool schema registry: %v", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write tool schema registry: %v", err)
+	}
+	tmp.Close()
+	if err := os.Rename(tmp.Name(), r.path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write tool schema registry: %v", err)
+	}
+	return nil
+}
+
+// clientRelease returns the Claude Code version from a User-Agent, or "" when unknown
+func clientRelease(userAgent string) string {
+	if match := clientReleasePattern.FindStringSubmatch(userAgent); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// checkToolSchemaDrift warns when a known tool's schema changed since it was last seen
+func checkToolSchemaDrift(path string, tools []types.Tool, release string, loggerInstance logger.Logger) {
+	if len(tools) == 0 {
+		return
+	}
+	registry, err := toolSchemaRegistryFor(path)
+	if err != nil {
+		loggerInstance.Warn("⚠️ Tool schema drift detection unavailable: %v", err)
+		return
+	}
+	drifts, err := registry.Observe(tools, release)
+	if err != nil {
+		loggerInstance.Warn("⚠️ Failed to persist tool schemas: %v", err)
+	}
+	for _, drift := range drifts {
+		loggerInstance.Warn("🧬 Tool schema drift for %s (release %s -> %s): %s; review correction rules and overrides for this tool",
+			drift.Tool, releaseLabel(drift.PreviousRelease), releaseLabel(drift.Release), drift)
+	}
+}
+
+func releaseLabel(release string) string {
+	if release == "" {
+		return "unknown"
+	}
+	return release
+}
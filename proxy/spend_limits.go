@@ -0,0 +1,52 @@
+package proxy
+
+import (
+	"claude-proxy/config"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// spendLimitSet holds the client spend limits in force, replaced on reload
+type spendLimitSet struct {
+	mu     sync.RWMutex
+	limits config.SpendLimits
+}
+
+// get returns the limits in force
+func (s *spendLimitSet) get() config.SpendLimits {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.limits
+}
+
+// SetSpendLimits replaces the daily and monthly client spend limits; requests
+// already past the check keep the limits they were checked against
+func (h *Handler) SetSpendLimits(limits config.SpendLimits) {
+	h.spendLimits.mu.Lock()
+	defer h.spendLimits.mu.Unlock()
+	h.spendLimits.limits = limits
+}
+
+// spendLimitExceeded returns why a client key may not send more requests and
+// how long until its limit resets, "" when it may
+func (h *Handler) spendLimitExceeded(client string, now time.Time) (string, time.Duration) {
+	limit := h.spendLimits.get().For(client)
+	if client == "" || limit == (config.SpendLimit{}) {
+		return "", 0
+	}
+	day, month := h.stats.ClientSpend(client, now)
+
+	now = now.UTC()
+	if limit.MonthlyUSD > 0 && month >= limit.MonthlyUSD {
+		reset := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		return fmt.Sprintf("monthly spend limit of $%.2f reached for API key %s ($%.2f spent this month); resets %s",
+			limit.MonthlyUSD, client, month, reset.Format(time.RFC3339)), reset.Sub(now)
+	}
+	if limit.DailyUSD > 0 && day >= limit.DailyUSD {
+		reset := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+		return fmt.Sprintf("daily spend limit of $%.2f reached for API key %s ($%.2f spent today); resets %s",
+			limit.DailyUSD, client, day, reset.Format(time.RFC3339)), reset.Sub(now)
+	}
+	return "", 0
+}
@@ -0,0 +1,116 @@
+package proxy
+
+import "fmt"
+
+// SSEVerifier checks an emitted Anthropic SSE event sequence against the
+// protocol ordering Claude Code expects:
+//
+//	message_start → (content_block_start → content_block_delta* → content_block_stop)* → message_delta → message_stop
+//
+// Content block indices must start at 0 and increase by one. Violations are
+// collected rather than returned so the stream itself is never interrupted.
+type SSEVerifier struct {
+	started    bool
+	deltaSent  bool
+	stopped    bool
+	openBlock  int // Index of the open content block, -1 when none
+	nextIndex  int
+	violations []string
+}
+
+// NewSSEVerifier creates a verifier for a single streamed message
+func NewSSEVerifier() *SSEVerifier {
+	return &SSEVerifier{openBlock: -1}
+}
+
+// Observe records one emitted event and checks it against the expected order
+func (v *SSEVerifier) Observe(eventType string, data interface{}) {
+	if v.stopped {
+		v.violate("%s emitted after message_stop", eventType)
+		return
+	}
+	if !v.started && eventType != "message_start" {
+		v.violate("%s emitted before message_start", eventType)
+	}
+
+	switch eventType {
+	case "message_start":
+		if v.started {
+			v.violate("duplicate message_start")
+		}
+		v.started = true
+	case "content_block_start":
+		index, ok := sseEventIndex(data)
+		switch {
+		case !ok:
+			v.violate("content_block_start without index")
+		case v.deltaSent:
+			v.violate("content_block_start %d emitted after message_delta", index)
+		case v.openBlock >= 0:
+			v.violate("content_block_start %d emitted while block %d is still open", index, v.openBlock)
+		case index != v.nextIndex:
+			v.violate("content_block_start index %d, expected %d", index, v.nextIndex)
+		}
+		if ok {
+			v.openBlock = index
+			v.nextIndex = index + 1
+		}
+	case "content_block_delta", "content_block_stop":
+		index, ok := sseEventIndex(data)
+		switch {
+		case !ok:
+			v.violate("%s without index", eventType)
+		case v.openBlock < 0:
+			v.violate("%s for index %d with no open content block", eventType, index)
+		case index != v.openBlock:
+			v.violate("%s index %d does not match open block %d", eventType, index, v.openBlock)
+		}
+		if eventType == "content_block_stop" {
+			v.openBlock = -1
+		}
+	case "message_delta":
+		if v.deltaSent {
+			v.violate("duplicate message_delta")
+		}
+		if v.openBlock >= 0 {
+			v.violate("message_delta emitted while block %d is still open", v.openBlock)
+		}
+		v.deltaSent = true
+	case "message_stop":
+		if !v.deltaSent {
+			v.violate("message_stop emitted without message_delta")
+		}
+		v.stopped = true
+	case "ping":
+	default:
+		v.violate("unknown event type %q", eventType)
+	}
+}
+
+// Finish checks that the stream was terminated and returns all violations
+func (v *SSEVerifier) Finish() []string {
+	if !v.stopped {
+		v.violate("stream ended without message_stop")
+	}
+	return v.violations
+}
+
+func (v *SSEVerifier) violate(format string, args ...interface{}) {
+	v.violations = append(v.violations, fmt.Sprintf(format, args...))
+}
+
+// sseEventIndex extracts the content block index from an event payload
+func sseEventIndex(data interface{}) (int, bool) {
+	event, ok := data.(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+	switch index := event["index"].(type) {
+	case int:
+		return index, true
+	case float64:
+		return int(index), true
+	default:
+		return 0, false
+	}
+}
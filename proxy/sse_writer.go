@@ -0,0 +1,57 @@
+package proxy
+
+import (
+	"net/http"
+	"time"
+)
+
+// sseStreamWriter wraps the client connection for one streamed message. It
+// feeds emitted events to an optional SSEVerifier and coalesces flushes so
+// slow client links are not flooded with tiny writes.
+//
+// Flushes only ever happen after complete events, so event boundaries are
+// preserved. With coalescing enabled, buffered events are flushed once
+// flushBytes have accumulated or flushInterval has passed since the last
+// flush; the final flush is forced by the caller after message_stop.
+//
+// With a limiter, every event is followed by the wait the client's streaming
+// rate limit asks for, so one client's long generation cannot take the link.
+type sseStreamWriter struct {
+	http.ResponseWriter
+	verifier      *SSEVerifier
+	flushInterval time.Duration      // 0 disables time-based coalescing
+	flushBytes    int                // 0 disables size-based coalescing
+	limiter       *streamRateLimiter // nil when the client is not throttled
+	pending       int
+	lastFlush     time.Time
+}
+
+// coalescing reports whether flushes are batched rather than sent per event
+func (w *sseStreamWriter) coalescing() bool {
+	return w.flushInterval > 0 || w.flushBytes > 0
+}
+
+// eventWritten records a complete event of n bytes and flushes when due
+func (w *sseStreamWriter) eventWritten(n int) {
+	w.pending += n
+	if !w.coalescing() {
+		w.Flush()
+		return
+	}
+	if w.flushBytes > 0 && w.pending >= w.flushBytes {
+		w.Flush()
+		return
+	}
+	if w.flushInterval > 0 && time.Since(w.lastFlush) >= w.flushInterval {
+		w.Flush()
+	}
+}
+
+// Flush sends buffered events to the client immediately
+func (w *sseStreamWriter) Flush() {
+	w.pending = 0
+	w.lastFlush = time.Now()
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,78 @@
+package proxy
+
+import (
+	"claude-proxy/circuitbreaker"
+	"claude-proxy/correction"
+	"claude-proxy/stats"
+	"encoding/json"
+	"net/http"
+)
+
+// statsResponse is the JSON body served by HandleStats
+type statsResponse struct {
+	stats.Snapshot
+	Circuits []circuitbreaker.EndpointHealth `json:"circuits"`
+
+	// Parameter renames learned from LLM corrections; promoted ones are applied by rule
+	LearnedRules []correction.LearnedMapping `json:"learned_rules"`
+
+	// Upstream calls in progress and waiting for MAX_CONCURRENT_REQUESTS
+	Scheduler SchedulerSnapshot `json:"scheduler"`
+}
+
+// HandleStats serves aggregate request statistics and current circuit states as JSON
+func (h *Handler) HandleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	resp := statsResponse{
+		Snapshot:     h.stats.Snapshot(),
+		Circuits:     []circuitbreaker.EndpointHealth{},
+		LearnedRules: h.correctionService.RuleLearner().Learned(),
+		Scheduler:    h.scheduler.snapshot(),
+	}
+	if h.config.HealthManager != nil {
+		resp.Circuits = h.config.HealthManager.Snapshot()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		http.Error(w, "Failed to encode stats", http.StatusInternalServerError)
+	}
+}
+
+// Stats returns the handler's statistics collector
+func (h *Handler) Stats() *stats.Collector {
+	return h.stats
+}
+
+// statusRecorder captures the response status code for statistics while
+// preserving streaming support
+type statusRecorder struct {
+	http.ResponseWriter
+	status       int
+	errorCode    ErrorCode // Set by writeProxyError for the dashboard's recent errors
+	errorMessage string
+}
+
+// WriteHeader records the status code before delegating
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// recordError notes a proxy error written through the recorder
+func (r *statusRecorder) recordError(code ErrorCode, message string) {
+	r.errorCode = code
+	r.errorMessage = message
+}
+
+// Flush forwards to the underlying writer so SSE streaming keeps working
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
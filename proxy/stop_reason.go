@@ -0,0 +1,45 @@
+package proxy
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/logger"
+	"claude-proxy/types"
+)
+
+// resolveStopReason maps the upstream finish_reason to an Anthropic
+// stop_reason through the operator table when cfg provides one, falling back
+// to config.DefaultStopReasons, then reconciles it with the returned content
+func resolveStopReason(cfg ResponseConfig, finishReason *string, content []types.Content, loggerInstance logger.Logger) string {
+	stopReason := config.StopReasonEndTurn
+	if finishReason != nil && *finishReason != "" {
+		var mapped string
+		var ok bool
+		if stopReasons, isMapper := cfg.(config.StopReasonConfig); isMapper {
+			mapped, ok = stopReasons.StopReasonFor(*finishReason)
+		} else {
+			mapped, ok = config.DefaultStopReasons[*finishReason]
+		}
+		if ok {
+			stopReason = mapped
+		} else {
+			loggerInstance.Warn("⚠️ Unknown finish_reason %q, using %s (map it with STOP_REASON_MAP)", *finishReason, stopReason)
+		}
+	}
+	return reconcileStopReason(stopReason, content)
+}
+
+// reconcileStopReason keeps stop_reason consistent with the content blocks.
+// Clients only run tools when stop_reason is tool_use, yet backends such as
+// llama.cpp and Ollama report "stop" alongside tool calls, and tool correction
+// can remove every tool call from a tool_calls response. A truncated response
+// stays max_tokens so the client knows the tool input may be incomplete.
+func reconcileStopReason(stopReason string, content []types.Content) string {
+	hasToolUse := HasToolCalls(content)
+	switch {
+	case hasToolUse && stopReason == config.StopReasonEndTurn:
+		return config.StopReasonToolUse
+	case !hasToolUse && stopReason == config.StopReasonToolUse:
+		return config.StopReasonEndTurn
+	}
+	return stopReason
+}
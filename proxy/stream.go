@@ -7,12 +7,20 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 )
 
 // ProcessStreamingResponse handles streaming OpenAI responses properly
 // Reads all chunks until finish_reason != null (solving the core streaming issue)
 func (h *Handler) ProcessStreamingResponse(ctx context.Context, resp *http.Response) (*types.OpenAIResponse, error) {
+	return h.processStreamingResponse(ctx, resp, false)
+}
+
+// processStreamingResponse reads chunks until finish_reason != null. When
+// expectUsage is set (stream_options.include_usage was requested) it keeps
+// reading past the final choice chunk until the usage chunk or [DONE] arrives.
+func (h *Handler) processStreamingResponse(ctx context.Context, resp *http.Response, expectUsage bool) (*types.OpenAIResponse, error) {
 	requestID := GetRequestID(ctx)
 	if h.obsLogger != nil {
 		h.obsLogger.Info("proxy_core", "request", requestID, "Processing streaming response", map[string]interface{}{})
@@ -61,6 +69,14 @@ func (h *Handler) ProcessStreamingResponse(ctx context.Context, resp *http.Respo
 					"finish_reason": *chunk.Choices[0].FinishReason,
 				})
 			}
+			if !expectUsage || chunk.Usage != nil {
+				break
+			}
+			continue
+		}
+
+		// Usage chunk trailing the final choice chunk
+		if finalChunk != nil && chunk.Usage != nil {
 			break
 		}
 	}
@@ -71,7 +87,11 @@ func (h *Handler) ProcessStreamingResponse(ctx context.Context, resp *http.Respo
 				"error": err.Error(),
 			})
 		}
-		return nil, fmt.Errorf("error reading stream: %v", err)
+		code := CodeUpstreamInvalidResponse
+		if isTimeout(err) {
+			code = CodeUpstreamTimeout
+		}
+		return nil, newProxyError(code, "error reading stream: %v", err)
 	}
 
 	if h.obsLogger != nil {
@@ -106,90 +126,129 @@ func (h *Handler) ReconstructResponseFromChunks(ctx context.Context, chunks []ty
 		},
 	}
 
-	// Reconstruct message content and tool calls
-	var contentParts []string
-	var toolCalls []types.OpenAIToolCall
+	// Reconstruct message content and tool calls per choice index; chunks of
+	// different choices interleave when the backend returns several
+	choices := make(map[int]*streamedChoice)
+	var indices []int
+	choiceAt := func(index int) *streamedChoice {
+		choice, exists := choices[index]
+		if !exists {
+			choice = &streamedChoice{}
+			choices[index] = choice
+			indices = append(indices, index)
+		}
+		return choice
+	}
 
 	for _, chunk := range chunks {
-		if len(chunk.Choices) == 0 {
-			continue
+		// Usage is reported once, on the last chunk (stream_options.include_usage)
+		if chunk.Usage != nil {
+			response.Usage = *chunk.Usage
 		}
 
-		delta := chunk.Choices[0].Delta
+		for _, streamChoice := range chunk.Choices {
+			choice := choiceAt(streamChoice.Index)
+			choice.add(streamChoice.Delta)
+			if streamChoice.FinishReason != nil {
+				choice.finishReason = streamChoice.FinishReason
+			}
+		}
+	}
 
-		// Accumulate content
-		if delta.Content != "" {
-			contentParts = append(contentParts, delta.Content)
+	// The final chunk may be passed separately from the accumulated chunks
+	if finalChunk != nil {
+		for _, streamChoice := range finalChunk.Choices {
+			if choice := choiceAt(streamChoice.Index); choice.finishReason == nil {
+				choice.finishReason = streamChoice.FinishReason
+			}
 		}
+	}
+	if len(indices) == 0 {
+		choiceAt(0)
+	}
+	sort.Ints(indices)
 
-		// Accumulate tool calls by index (streaming chunks can have partial data)
-		for _, toolCall := range delta.ToolCalls {
-			index := toolCall.Index
+	requestID := GetRequestID(ctx)
+	for _, index := range indices {
+		choice := choices[index]
 
-			// Ensure we have enough tool calls for this index
-			for len(toolCalls) <= index {
-				toolCalls = append(toolCalls, types.OpenAIToolCall{
-					Type:     "function",
-					Function: types.OpenAIToolCallFunction{},
-				})
-			}
+		// Build final message
+		message := types.OpenAIMessage{
+			Role:    "assistant",
+			Content: strings.Join(choice.contentParts, ""),
+		}
 
-			// Accumulate fields for this tool call index
-			if toolCall.ID != "" {
-				toolCalls[index].ID = toolCall.ID
-			}
-			if toolCall.Type != "" {
-				toolCalls[index].Type = toolCall.Type
-			}
-			if toolCall.Function.Name != "" {
-				toolCalls[index].Function.Name = toolCall.Function.Name
+		if len(choice.toolCalls) > 0 {
+			message.ToolCalls = choice.toolCalls
+			if h.obsLogger != nil {
+				h.obsLogger.Info("proxy_core", "transformation", requestID, "Reconstructed tool calls", map[string]interface{}{
+					"tool_call_count": len(choice.toolCalls),
+				})
 			}
-			// Always accumulate arguments (can be spread across multiple chunks)
-			toolCalls[index].Function.Arguments += toolCall.Function.Arguments
 		}
-	}
 
-	// Build final message
-	message := types.OpenAIMessage{
-		Role:    "assistant",
-		Content: strings.Join(contentParts, ""),
-	}
+		// Add choice to response
+		response.Choices = append(response.Choices, types.OpenAIChoice{
+			Index:        index,
+			Message:      message,
+			FinishReason: choice.finishReason,
+		})
 
-	requestID := GetRequestID(ctx)
-	if len(toolCalls) > 0 {
-		message.ToolCalls = toolCalls
+		finishReasonStr := "null"
+		if choice.finishReason != nil {
+			finishReasonStr = *choice.finishReason
+		}
+		// Use structured logging for response reconstruction summary
 		if h.obsLogger != nil {
-			h.obsLogger.Info("proxy_core", "transformation", requestID, "Reconstructed tool calls", map[string]interface{}{
-				"tool_call_count": len(toolCalls),
+			h.obsLogger.Info("proxy_core", "success", requestID, "Reconstructed complete response", map[string]interface{}{
+				"choice_index":   index,
+				"content_length": len(message.Content),
+				"tool_calls":     len(choice.toolCalls),
+				"finish_reason":  finishReasonStr,
 			})
 		}
 	}
 
-	// Set finish reason
-	var finishReason *string
-	if finalChunk != nil && len(finalChunk.Choices) > 0 {
-		finishReason = finalChunk.Choices[0].FinishReason
-	}
+	return response, nil
+}
 
-	// Add choice to response
-	response.Choices = append(response.Choices, types.OpenAIChoice{
-		Index:        0,
-		Message:      message,
-		FinishReason: finishReason,
-	})
+// streamedChoice accumulates the deltas of one choice of a streamed response
+type streamedChoice struct {
+	contentParts []string
+	toolCalls    []types.OpenAIToolCall
+	finishReason *string
+}
 
-	finishReasonStr := "null"
-	if finishReason != nil {
-		finishReasonStr = *finishReason
-	}
-	// Use structured logging for response reconstruction summary
-	if h.obsLogger != nil {
-		h.obsLogger.Info("proxy_core", "success", requestID, "Reconstructed complete response", map[string]interface{}{
-			"content_length": len(message.Content),
-			"tool_calls":     len(toolCalls),
-			"finish_reason":  finishReasonStr,
-		})
+// add appends a delta's content and merges its partial tool calls
+func (c *streamedChoice) add(delta types.OpenAIStreamDelta) {
+	// Accumulate content
+	if delta.Content != "" {
+		c.contentParts = append(c.contentParts, delta.Content)
 	}
 
-	return response, nil
+	// Accumulate tool calls by index (streaming chunks can have partial data)
+	for _, toolCall := range delta.ToolCalls {
+		index := toolCall.Index
+
+		// Ensure we have enough tool calls for this index
+		for len(c.toolCalls) <= index {
+			c.toolCalls = append(c.toolCalls, types.OpenAIToolCall{
+				Type:     "function",
+				Function: types.OpenAIToolCallFunction{},
+			})
+		}
+
+		// Accumulate fields for this tool call index
+		if toolCall.ID != "" {
+			c.toolCalls[index].ID = toolCall.ID
+		}
+		if toolCall.Type != "" {
+			c.toolCalls[index].Type = toolCall.Type
+		}
+		if toolCall.Function.Name != "" {
+			c.toolCalls[index].Function.Name = toolCall.Function.Name
+		}
+		// Always accumulate arguments (can be spread across multiple chunks)
+		c.toolCalls[index].Function.Arguments += toolCall.Function.Arguments
+	}
 }
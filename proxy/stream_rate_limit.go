@@ -0,0 +1,139 @@
+package proxy
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/tokenizer"
+	"context"
+	"sync"
+	"time"
+)
+
+// streamRateLimiterIdleTimeout is how long the limiter of a client key is kept
+// after it last paced an event or was handed to a stream
+const streamRateLimiterIdleTimeout = 10 * time.Minute
+
+// streamRateLimiter paces the streamed events of one client. Every event
+// pushes back the time at which the tokens and bytes sent so far are paid for
+// at the configured rate; the writer waits until then before the next event.
+type streamRateLimiter struct {
+	mu        sync.Mutex
+	limit     config.StreamRateLimit
+	tokensDue time.Time
+	bytesDue  time.Time
+	handedOut time.Time // When get last returned it, guarded by streamRateLimiters.mu
+}
+
+// reserve records an event of tokens and bytes and returns how long the
+// writer has to wait before sending the next one
+func (l *streamRateLimiter) reserve(tokens, bytes int, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var wait time.Duration
+	if l.limit.TokensPerSecond > 0 && tokens > 0 {
+		l.tokensDue = paidAt(l.tokensDue, now, float64(tokens)/l.limit.TokensPerSecond)
+		wait = max(wait, l.tokensDue.Sub(now))
+	}
+	if l.limit.BytesPerSecond > 0 && bytes > 0 {
+		l.bytesDue = paidAt(l.bytesDue, now, float64(bytes)/l.limit.BytesPerSecond)
+		wait = max(wait, l.bytesDue.Sub(now))
+	}
+	return wait
+}
+
+// idleSince reports whether everything the limiter paced was paid for before cutoff
+func (l *streamRateLimiter) idleSince(cutoff time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.tokensDue.Before(cutoff) && l.bytesDue.Before(cutoff)
+}
+
+// paidAt returns when seconds more of sending are paid for, counting from now
+// when the client has been idle since due
+func paidAt(due, now time.Time, seconds float64) time.Time {
+	if due.Before(now) {
+		due = now
+	}
+	return due.Add(time.Duration(seconds * float64(time.Second)))
+}
+
+// streamRateLimiters holds one limiter per client key so concurrent streams
+// of a key share its rate instead of each getting the full rate. Client keys
+// are not authenticated, so limiters of idle keys are dropped.
+type streamRateLimiters struct {
+	mu      sync.Mutex
+	clients map[string]*streamRateLimiter
+}
+
+func newStreamRateLimiters() *streamRateLimiters {
+	return &streamRateLimiters{clients: make(map[string]*streamRateLimiter)}
+}
+
+// get returns the limiter of a client key, nil when its streams are not
+// throttled. Requests without a key are paced per stream.
+func (s *streamRateLimiters) get(limits config.StreamRateLimits, client string, now time.Time) *streamRateLimiter {
+	limit := limits.For(client)
+	if limit.IsZero() {
+		return nil
+	}
+	if client == "" {
+		return &streamRateLimiter{limit: limit}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := now.Add(-streamRateLimiterIdleTimeout)
+	for key, limiter := range s.clients {
+		if limiter.handedOut.Before(cutoff) && limiter.idleSince(cutoff) {
+			delete(s.clients, key)
+		}
+	}
+	limiter, exists := s.clients[client]
+	if !exists {
+		limiter = &streamRateLimiter{limit: limit}
+		s.clients[client] = limiter
+	}
+	limiter.handedOut = now
+	return limiter
+}
+
+// pace holds the stream back until the client's rate limit allows the next
+// event, delivering coalesced events first. It returns early when ctx ends.
+func (w *sseStreamWriter) pace(ctx context.Context, data interface{}, bytes int) {
+	if w.limiter == nil {
+		return
+	}
+	wait := w.limiter.reserve(deltaTokens(data), bytes, time.Now())
+	if wait <= 0 {
+		return
+	}
+	if w.pending > 0 {
+		w.Flush()
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// deltaTokens estimates the output tokens a content_block_delta event carries
+func deltaTokens(data interface{}) int {
+	event, ok := data.(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	delta, ok := event["delta"].(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	for _, field := range []string{"text", "thinking", "partial_json"} {
+		if text, ok := delta[field].(string); ok {
+			return tokenizer.Count(text)
+		}
+	}
+	return 0
+}
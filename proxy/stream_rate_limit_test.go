@@ -0,0 +1,36 @@
+package proxy
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"claude-proxy/config"
+)
+
+// TestStreamRateLimitersEvictIdle tests that limiters of client keys that
+// stopped streaming are dropped, while busy keys keep sharing theirs
+func TestStreamRateLimitersEvictIdle(t *testing.T) {
+	limits := config.StreamRateLimits{Default: config.StreamRateLimit{TokensPerSecond: 10}}
+	limiters := newStreamRateLimiters()
+	start := time.Now()
+
+	for i := 0; i < 100; i++ {
+		limiters.get(limits, fmt.Sprintf("key%02d", i), start)
+	}
+	busy := limiters.get(limits, "busy", start)
+	if len(limiters.clients) != 101 {
+		t.Fatalf("Expected 101 limiters, got %d", len(limiters.clients))
+	}
+
+	// The busy key is still paying for what it sent when the others go idle
+	busy.reserve(10*int(streamRateLimiterIdleTimeout/time.Second)+100, 0, start)
+
+	later := start.Add(streamRateLimiterIdleTimeout + time.Second)
+	if limiter := limiters.get(limits, "busy", later); limiter != busy {
+		t.Error("Expected the busy key to keep its limiter")
+	}
+	if len(limiters.clients) != 1 {
+		t.Errorf("Expected idle limiters to be evicted, %d left", len(limiters.clients))
+	}
+}
@@ -0,0 +1,385 @@
+package proxy
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/logger"
+	"claude-proxy/types"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// toolEmulationInstructions teach a model without native function calling to
+// call tools in the <tool_call> format Hermes- and Qwen-style models are
+// trained on, which parseEmulatedToolCalls turns back into tool calls
+const toolEmulationInstructions = `# Tool calling
+
+To call a tool, write a <tool_call> block holding one JSON object with the tool's "name" and its "arguments", exactly like this:
+
+<tool_call>
+{"name": "<tool name>", "arguments": {"<parameter>": "<value>"}}
+</tool_call>
+
+Rules:
+- Only call the tools listed above, with arguments matching their JSON Schema.
+- Write one <tool_call> block per call; several blocks call several tools.
+- Put nothing but the JSON object inside a block: no code fences, no comments.
+- Stop after your tool calls. Results arrive in <tool_response> blocks in the next message.
+- When no tool is needed, answer in plain text without any <tool_call> block.`
+
+// reactInstructions teach the ReAct format some agent fine-tunes produce more
+// reliably than JSON blocks, which parseReActOutput turns back into thinking
+// and tool calls
+const reactInstructions = `# Tool calling
+
+Work step by step in this format:
+
+Thought: your reasoning about what to do next
+Action: the name of the tool to call
+Action Input: the tool's arguments as one JSON object matching its JSON Schema
+
+Rules:
+- Only call the tools listed above; write "Action Input: {}" for a tool without parameters.
+- Write one Action and Action Input pair per call; several pairs call several tools.
+- Stop after your actions. Results arrive as "Observation: ..." in the next message; never write an Observation yourself.
+- When no tool is needed, finish with:
+
+Thought: your reasoning
+Final Answer: your answer to the user`
+
+// jsonToolCallInstructions describe the reply object for backends that
+// enforce it through response_format, which parseJSONToolCalls reads back
+const jsonToolCallInstructions = `# Tool calling
+
+Reply with one JSON object: "content" holds your text for the user and "tool_calls" lists the tools to call, each as {"name": "<tool name>", "arguments": {"<parameter>": "<value>"}} with arguments matching the tool's JSON Schema. Leave "tool_calls" empty when no tool is needed. Stop after your tool calls; results arrive in <tool_response> blocks in the next message.`
+
+// reactMarkerPattern matches the keyword starting a line of ReAct output,
+// bolded or not; Action Input is listed before Action so it wins
+var reactMarkerPattern = regexp.MustCompile(`(?mi)^[ \t]*(?:\*\*)?(Thought|Action Input|Action|Observation|Final Answer)[ \t]*:(?:\*\*)?`)
+
+// toolCallBlockPattern matches a <tool_call> block; a block cut off by the
+// end of the response is still matched
+var toolCallBlockPattern = regexp.MustCompile(`(?s)<tool_call>(.*?)(?:</tool_call>|\z)`)
+
+// emulatedToolCall is the JSON object inside a <tool_call> block. Models
+// trained on other formats sometimes name the arguments input or parameters.
+type emulatedToolCall struct {
+	Name       string          `json:"name"`
+	Arguments  json.RawMessage `json:"arguments"`
+	Input      json.RawMessage `json:"input"`
+	Parameters json.RawMessage `json:"parameters"`
+}
+
+// jsonToolCallReply is the reply object of the json emulation format
+type jsonToolCallReply struct {
+	Content   string             `json:"content"`
+	ToolCalls []emulatedToolCall `json:"tool_calls"`
+}
+
+// toolCallResponseFormat builds a json_schema response format for the reply
+// object, with each tool call's arguments constrained by the tool's schema
+func toolCallResponseFormat(tools []types.OpenAITool) *types.ResponseFormat {
+	calls := make([]interface{}, 0, len(tools))
+	for _, tool := range tools {
+		calls = append(calls, map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"name":      map[string]interface{}{"type": "string", "enum": []string{tool.Function.Name}},
+				"arguments": tool.Function.Parameters,
+			},
+			"required": []string{"name", "arguments"},
+		})
+	}
+	return &types.ResponseFormat{
+		Type: "json_schema",
+		JSONSchema: &types.ResponseJSONSchema{
+			Name: "tool_calls",
+			Schema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"content":    map[string]interface{}{"type": "string"},
+					"tool_calls": map[string]interface{}{"type": "array", "items": map[string]interface{}{"anyOf": calls}},
+				},
+				"required": []string{"content", "tool_calls"},
+			},
+		},
+	}
+}
+
+// renderJSONToolCalls writes an assistant turn as the reply object the model
+// is told to return
+func renderJSONToolCalls(content string, calls []types.OpenAIToolCall) string {
+	reply := struct {
+		Content   string            `json:"content"`
+		ToolCalls []json.RawMessage `json:"tool_calls"`
+	}{Content: content}
+	for _, call := range calls {
+		arguments := json.RawMessage(call.Function.Arguments)
+		if !json.Valid(arguments) {
+			arguments = json.RawMessage("{}")
+		}
+		rendered, _ := json.Marshal(map[string]interface{}{"name": call.Function.Name, "arguments": arguments})
+		reply.ToolCalls = append(reply.ToolCalls, rendered)
+	}
+	rendered, _ := json.Marshal(reply)
+	return string(rendered)
+}
+
+// renderEmulatedToolCall writes a tool call the way the model is told to, so
+// the conversation history shows it its own calls in the expected format
+func renderEmulatedToolCall(call types.OpenAIToolCall) string {
+	arguments := call.Function.Arguments
+	if strings.TrimSpace(arguments) == "" {
+		arguments = "{}"
+	}
+	return fmt.Sprintf("<tool_call>\n{\"name\": %q, \"arguments\": %s}\n</tool_call>", call.Function.Name, arguments)
+}
+
+// renderReActAction writes a tool call as the Action and Action Input lines the
+// model is told to write
+func renderReActAction(call types.OpenAIToolCall) string {
+	arguments := call.Function.Arguments
+	if strings.TrimSpace(arguments) == "" {
+		arguments = "{}"
+	}
+	return fmt.Sprintf("Action: %s\nAction Input: %s", call.Function.Name, arguments)
+}
+
+// parseEmulatedResponse turns tool calls written as text in the given format
+// back into tool calls
+func parseEmulatedResponse(resp *types.OpenAIResponse, format string, loggerInstance logger.Logger) {
+	switch format {
+	case config.ToolEmulationFormatReAct:
+		parseReActOutput(resp, loggerInstance)
+	case config.ToolEmulationFormatJSON:
+		parseJSONToolCalls(resp, loggerInstance)
+	default:
+		parseEmulatedToolCalls(resp, loggerInstance)
+	}
+}
+
+// parseEmulatedToolCalls moves the <tool_call> blocks of each choice's text
+// into tool calls, leaving the surrounding text as content. Blocks that are
+// not valid JSON stay in the text.
+func parseEmulatedToolCalls(resp *types.OpenAIResponse, loggerInstance logger.Logger) {
+	for i := range resp.Choices {
+		message := &resp.Choices[i].Message
+		if !strings.Contains(message.Content, "<tool_call>") {
+			continue
+		}
+
+		var calls []types.OpenAIToolCall
+		text := toolCallBlockPattern.ReplaceAllStringFunc(message.Content, func(block string) string {
+			body := toolCallBlockPattern.FindStringSubmatch(block)[1]
+			call, err := decodeEmulatedToolCall(body)
+			if err != nil {
+				loggerInstance.Warn("⚠️ Could not parse emulated tool call, keeping it as text: %v", err)
+				return block
+			}
+			calls = append(calls, call)
+			return ""
+		})
+		if len(calls) == 0 {
+			continue
+		}
+
+		loggerInstance.Info("🧩 Parsed %d emulated tool call(s) from the response text", len(calls))
+		message.Content = strings.TrimSpace(text)
+		message.ToolCalls = append(message.ToolCalls, calls...)
+		finishReason := "tool_calls"
+		resp.Choices[i].FinishReason = &finishReason
+	}
+}
+
+// parseJSONToolCalls reads each choice's reply object into content and tool
+// calls. Text that is not a reply object, which a backend ignoring
+// response_format may return, is left alone.
+func parseJSONToolCalls(resp *types.OpenAIResponse, loggerInstance logger.Logger) {
+	for i := range resp.Choices {
+		message := &resp.Choices[i].Message
+		body := stripCodeFence(message.Content)
+		if !strings.HasPrefix(body, "{") {
+			continue
+		}
+
+		var reply jsonToolCallReply
+		if err := json.Unmarshal([]byte(body), &reply); err != nil {
+			loggerInstance.Warn("⚠️ Could not parse JSON tool call reply, keeping it as text: %v", err)
+			continue
+		}
+		var calls []types.OpenAIToolCall
+		for _, call := range reply.ToolCalls {
+			if call.Name == "" {
+				loggerInstance.Warn("⚠️ Dropping JSON tool call without a name")
+				continue
+			}
+			calls = append(calls, emulatedToolCallFor(call.Name, call.rawArguments()))
+		}
+
+		message.Content = strings.TrimSpace(reply.Content)
+		if len(calls) > 0 {
+			loggerInstance.Info("🧩 Parsed %d tool call(s) from the JSON reply", len(calls))
+			message.ToolCalls = append(message.ToolCalls, calls...)
+			finishReason := "tool_calls"
+			resp.Choices[i].FinishReason = &finishReason
+		}
+	}
+}
+
+// parseReActOutput splits each choice's ReAct text into reasoning (Thought),
+// tool calls (Action and Action Input) and content (Final Answer). Anything
+// after an Observation the model made up itself is dropped, and text without
+// an Action or Final Answer is left alone.
+func parseReActOutput(resp *types.OpenAIResponse, loggerInstance logger.Logger) {
+	for i := range resp.Choices {
+		message := &resp.Choices[i].Message
+		markers := reactMarkerPattern.FindAllStringSubmatchIndex(message.Content, -1)
+		if len(markers) == 0 {
+			continue
+		}
+
+		var thoughts, answer []string
+		var calls []types.OpenAIToolCall
+		if preamble := strings.TrimSpace(message.Content[:markers[0][0]]); preamble != "" {
+			answer = append(answer, preamble)
+		}
+		action, pending, finalAnswer := "", false, false
+		// An Action without an Action Input calls a tool without parameters
+		flush := func() {
+			if pending {
+				calls = append(calls, emulatedToolCallFor(action, json.RawMessage("{}")))
+				pending = false
+			}
+		}
+
+	scan:
+		for j, marker := range markers {
+			end := len(message.Content)
+			if j+1 < len(markers) {
+				end = markers[j+1][0]
+			}
+			value := strings.TrimSpace(message.Content[marker[1]:end])
+
+			switch strings.ToLower(message.Content[marker[2]:marker[3]]) {
+			case "thought":
+				flush()
+				if value != "" {
+					thoughts = append(thoughts, value)
+				}
+			case "action":
+				flush()
+				action = strings.Trim(value, "`*\" ")
+				pending = action != ""
+			case "action input":
+				if !pending {
+					continue
+				}
+				pending = false
+				call, err := decodeReActAction(action, value)
+				if err != nil {
+					loggerInstance.Warn("⚠️ Could not parse ReAct action, keeping it as text: %v", err)
+					answer = append(answer, renderReActAction(types.OpenAIToolCall{Function: types.OpenAIToolCallFunction{Name: action, Arguments: value}}))
+					continue
+				}
+				calls = append(calls, call)
+			case "observation":
+				break scan
+			case "final answer":
+				flush()
+				finalAnswer = true
+				answer = append(answer, value)
+			}
+		}
+		flush()
+		if len(calls) == 0 && !finalAnswer {
+			continue
+		}
+
+		if len(thoughts) > 0 {
+			thinking := strings.Join(thoughts, "\n\n")
+			if message.ReasoningContent != "" {
+				thinking = message.ReasoningContent + "\n\n" + thinking
+			}
+			message.ReasoningContent = thinking
+		}
+		message.Content = strings.Join(answer, "\n\n")
+		if len(calls) > 0 {
+			loggerInstance.Info("🧩 Parsed %d ReAct action(s) from the response text", len(calls))
+			message.ToolCalls = append(message.ToolCalls, calls...)
+			finishReason := "tool_calls"
+			resp.Choices[i].FinishReason = &finishReason
+		}
+	}
+}
+
+// decodeReActAction parses an Action Input, which must hold a JSON object
+// (possibly string-encoded) and may be followed by stray text
+func decodeReActAction(name, input string) (types.OpenAIToolCall, error) {
+	input = stripCodeFence(input)
+	if input == "" {
+		return emulatedToolCallFor(name, json.RawMessage("{}")), nil
+	}
+
+	var arguments json.RawMessage
+	if err := json.NewDecoder(strings.NewReader(input)).Decode(&arguments); err != nil {
+		return types.OpenAIToolCall{}, fmt.Errorf("action input for %s is not JSON: %v", name, err)
+	}
+	call := emulatedToolCallFor(name, arguments)
+	if !strings.HasPrefix(strings.TrimSpace(call.Function.Arguments), "{") {
+		return types.OpenAIToolCall{}, fmt.Errorf("action input for %s is not a JSON object: %s", name, input)
+	}
+	return call, nil
+}
+
+// stripCodeFence removes a Markdown code fence around JSON
+func stripCodeFence(body string) string {
+	body = strings.TrimSpace(body)
+	body = strings.TrimPrefix(body, "```json")
+	body = strings.TrimPrefix(body, "```")
+	return strings.TrimSpace(strings.TrimSuffix(body, "```"))
+}
+
+// decodeEmulatedToolCall parses the body of a <tool_call> block
+func decodeEmulatedToolCall(body string) (types.OpenAIToolCall, error) {
+	body = stripCodeFence(body)
+
+	var parsed emulatedToolCall
+	if err := json.Unmarshal([]byte(body), &parsed); err != nil {
+		return types.OpenAIToolCall{}, err
+	}
+	if parsed.Name == "" {
+		return types.OpenAIToolCall{}, fmt.Errorf("tool call has no name: %s", body)
+	}
+
+	return emulatedToolCallFor(parsed.Name, parsed.rawArguments()), nil
+}
+
+// rawArguments returns the arguments under whichever key the model used
+func (c emulatedToolCall) rawArguments() json.RawMessage {
+	arguments := c.Arguments
+	for _, alternative := range []json.RawMessage{c.Input, c.Parameters} {
+		if len(arguments) == 0 {
+			arguments = alternative
+		}
+	}
+	return arguments
+}
+
+// emulatedToolCallFor builds a tool call from a parsed name and arguments
+func emulatedToolCallFor(name string, arguments json.RawMessage) types.OpenAIToolCall {
+	// Some models encode the arguments object as a JSON string
+	var encoded string
+	if err := json.Unmarshal(arguments, &encoded); err == nil {
+		arguments = json.RawMessage(encoded)
+	}
+	if len(arguments) == 0 || string(arguments) == "null" {
+		arguments = json.RawMessage("{}")
+	}
+
+	return types.OpenAIToolCall{
+		ID:       newToolCallID(),
+		Type:     "function",
+		Function: types.OpenAIToolCallFunction{Name: name, Arguments: string(arguments)},
+	}
+}
@@ -0,0 +1,252 @@
+package proxy
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/logger"
+	"claude-proxy/types"
+	"context"
+	"testing"
+)
+
+func emulationResponse(text string) *types.OpenAIResponse {
+	stop := "stop"
+	return &types.OpenAIResponse{Choices: []types.OpenAIChoice{{
+		Message:      types.OpenAIMessage{Role: "assistant", Content: text},
+		FinishReason: &stop,
+	}}}
+}
+
+// TestParseEmulatedToolCalls tests extraction of <tool_call> blocks in the
+// formats models actually produce
+func TestParseEmulatedToolCalls(t *testing.T) {
+	loggerInstance := logger.NewFromConfig(context.Background(), config.GetDefaultConfig())
+
+	tests := []struct {
+		name      string
+		text      string
+		calls     []string // name + arguments
+		remaining string
+	}{
+		{
+			name:      "text then call",
+			text:      "Let me read it.\n<tool_call>\n{\"name\": \"Read\", \"arguments\": {\"file_path\": \"/a.go\"}}\n</tool_call>",
+			calls:     []string{`Read {"file_path": "/a.go"}`},
+			remaining: "Let me read it.",
+		},
+		{
+			name:  "several calls, code fence, string arguments",
+			text:  "<tool_call>```json\n{\"name\": \"Read\", \"arguments\": {\"file_path\": \"/a.go\"}}\n```</tool_call>\n<tool_call>{\"name\": \"Bash\", \"arguments\": \"{\\\"command\\\": \\\"ls\\\"}\"}</tool_call>",
+			calls: []string{`Read {"file_path": "/a.go"}`, `Bash {"command": "ls"}`},
+		},
+		{
+			name:  "unterminated block and input key",
+			text:  "<tool_call>\n{\"name\": \"Glob\", \"input\": {\"pattern\": \"*.go\"}}",
+			calls: []string{`Glob {"pattern": "*.go"}`},
+		},
+		{
+			name:      "invalid JSON stays text",
+			text:      "<tool_call>{\"name\": \"Read\", \"arguments\": {</tool_call>",
+			remaining: "<tool_call>{\"name\": \"Read\", \"arguments\": {</tool_call>",
+		},
+		{
+			name:      "plain answer",
+			text:      "The file defines main.",
+			remaining: "The file defines main.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := emulationResponse(tt.text)
+			parseEmulatedToolCalls(resp, loggerInstance)
+			message := resp.Choices[0].Message
+
+			if len(message.ToolCalls) != len(tt.calls) {
+				t.Fatalf("Expected %d tool calls, got %+v", len(tt.calls), message.ToolCalls)
+			}
+			for i, call := range message.ToolCalls {
+				if got := call.Function.Name + " " + call.Function.Arguments; got != tt.calls[i] {
+					t.Errorf("Tool call %d: expected %s, got %s", i, tt.calls[i], got)
+				}
+				if call.ID == "" || call.Type != "function" {
+					t.Errorf("Tool call %d: expected an id and type function, got %+v", i, call)
+				}
+			}
+			if message.Content != tt.remaining {
+				t.Errorf("Expected remaining text %q, got %q", tt.remaining, message.Content)
+			}
+			wantFinish := "stop"
+			if len(tt.calls) > 0 {
+				wantFinish = "tool_calls"
+			}
+			if got := *resp.Choices[0].FinishReason; got != wantFinish {
+				t.Errorf("Expected finish_reason %s, got %s", wantFinish, got)
+			}
+		})
+	}
+}
+
+// TestToolHistoryAsText tests that earlier calls and results are shown to an
+// emulating model in the format it is asked to write
+func TestToolHistoryAsText(t *testing.T) {
+	messages := []types.OpenAIMessage{
+		{Role: "user", Content: "Read main.go"},
+		{Role: "assistant", ToolCalls: []types.OpenAIToolCall{{
+			ID: "toolu_1", Type: "function",
+			Function: types.OpenAIToolCallFunction{Name: "Read", Arguments: `{"file_path":"main.go"}`},
+		}}},
+		{Role: "tool", ToolCallID: "toolu_1", Content: "package main"},
+	}
+
+	converted := toolHistoryAsText(messages, config.ToolEmulationFormatToolCall)
+	if want := "<tool_call>\n{\"name\": \"Read\", \"arguments\": {\"file_path\":\"main.go\"}}\n</tool_call>"; converted[1].Content != want {
+		t.Errorf("Expected call rendered as %q, got %q", want, converted[1].Content)
+	}
+	if converted[2].Role != "user" || converted[2].Content != "<tool_response>\npackage main\n</tool_response>" {
+		t.Errorf("Unexpected tool result rendering: %+v", converted[2])
+	}
+	if len(messages[1].ToolCalls) != 1 || messages[2].Role != "tool" {
+		t.Error("Expected the original messages to be left unmodified")
+	}
+
+	react := toolHistoryAsText(messages, config.ToolEmulationFormatReAct)
+	if want := "Action: Read\nAction Input: {\"file_path\":\"main.go\"}"; react[1].Content != want {
+		t.Errorf("Expected ReAct call rendered as %q, got %q", want, react[1].Content)
+	}
+	if react[2].Role != "user" || react[2].Content != "Observation: package main" {
+		t.Errorf("Unexpected ReAct tool result rendering: %+v", react[2])
+	}
+
+	reply := toolHistoryAsText(messages, config.ToolEmulationFormatJSON)
+	if want := `{"content":"","tool_calls":[{"arguments":{"file_path":"main.go"},"name":"Read"}]}`; reply[1].Content != want {
+		t.Errorf("Expected JSON call rendered as %q, got %q", want, reply[1].Content)
+	}
+}
+
+// TestParseJSONToolCalls tests reading the reply object of the json format
+func TestParseJSONToolCalls(t *testing.T) {
+	loggerInstance := logger.NewFromConfig(context.Background(), config.GetDefaultConfig())
+
+	tests := []struct {
+		name      string
+		text      string
+		calls     []string // name + arguments
+		remaining string
+	}{
+		{
+			name:      "content and calls",
+			text:      `{"content": "Reading both.", "tool_calls": [{"name": "Read", "arguments": {"file_path": "/a.go"}}, {"name": "Glob", "input": {"pattern": "*.go"}}]}`,
+			calls:     []string{`Read {"file_path": "/a.go"}`, `Glob {"pattern": "*.go"}`},
+			remaining: "Reading both.",
+		},
+		{
+			name:      "answer without calls",
+			text:      "```json\n{\"content\": \"The file defines main.\", \"tool_calls\": []}\n```",
+			remaining: "The file defines main.",
+		},
+		{
+			name:      "backend ignored response_format",
+			text:      "The file defines main.",
+			remaining: "The file defines main.",
+		},
+		{
+			name:      "truncated object stays text",
+			text:      `{"content": "Reading", "tool_calls": [{"name": "Re`,
+			remaining: `{"content": "Reading", "tool_calls": [{"name": "Re`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := emulationResponse(tt.text)
+			parseEmulatedResponse(resp, config.ToolEmulationFormatJSON, loggerInstance)
+			message := resp.Choices[0].Message
+
+			if len(message.ToolCalls) != len(tt.calls) {
+				t.Fatalf("Expected %d tool calls, got %+v", len(tt.calls), message.ToolCalls)
+			}
+			for i, call := range message.ToolCalls {
+				if got := call.Function.Name + " " + call.Function.Arguments; got != tt.calls[i] {
+					t.Errorf("Tool call %d: expected %s, got %s", i, tt.calls[i], got)
+				}
+			}
+			if message.Content != tt.remaining {
+				t.Errorf("Expected remaining text %q, got %q", tt.remaining, message.Content)
+			}
+		})
+	}
+}
+
+// TestParseReActOutput tests splitting ReAct text into thinking, tool calls
+// and the final answer
+func TestParseReActOutput(t *testing.T) {
+	loggerInstance := logger.NewFromConfig(context.Background(), config.GetDefaultConfig())
+
+	tests := []struct {
+		name      string
+		text      string
+		thinking  string
+		calls     []string // name + arguments
+		remaining string
+	}{
+		{
+			name:     "thought and action",
+			text:     "Thought: I need the file first.\nAction: Read\nAction Input: {\"file_path\": \"/a.go\"}",
+			thinking: "I need the file first.",
+			calls:    []string{`Read {"file_path": "/a.go"}`},
+		},
+		{
+			name:     "bold markers, code fence, made-up observation",
+			text:     "**Thought:** Two lookups.\n**Action:** `Glob`\n**Action Input:** ```json\n{\"pattern\": \"*.go\"}\n```\nAction: LS\nObservation: main.go\nThought: Done.\nFinal Answer: main.go",
+			thinking: "Two lookups.",
+			calls:    []string{`Glob {"pattern": "*.go"}`, `LS {}`},
+		},
+		{
+			name:      "final answer",
+			text:      "Thought: I know this.\nFinal Answer: The file defines main.",
+			thinking:  "I know this.",
+			remaining: "The file defines main.",
+		},
+		{
+			name:      "non-object input stays text",
+			text:      "Thought: List it.\nAction: Bash\nAction Input: ls -la\nFinal Answer: Listing.",
+			thinking:  "List it.",
+			remaining: "Action: Bash\nAction Input: ls -la\n\nListing.",
+		},
+		{
+			name:      "plain answer",
+			text:      "Thought: this is just prose without an action.",
+			remaining: "Thought: this is just prose without an action.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := emulationResponse(tt.text)
+			parseEmulatedResponse(resp, config.ToolEmulationFormatReAct, loggerInstance)
+			message := resp.Choices[0].Message
+
+			if message.ReasoningContent != tt.thinking {
+				t.Errorf("Expected thinking %q, got %q", tt.thinking, message.ReasoningContent)
+			}
+			if len(message.ToolCalls) != len(tt.calls) {
+				t.Fatalf("Expected %d tool calls, got %+v", len(tt.calls), message.ToolCalls)
+			}
+			for i, call := range message.ToolCalls {
+				if got := call.Function.Name + " " + call.Function.Arguments; got != tt.calls[i] {
+					t.Errorf("Tool call %d: expected %s, got %s", i, tt.calls[i], got)
+				}
+			}
+			if message.Content != tt.remaining {
+				t.Errorf("Expected remaining text %q, got %q", tt.remaining, message.Content)
+			}
+			wantFinish := "stop"
+			if len(tt.calls) > 0 {
+				wantFinish = "tool_calls"
+			}
+			if got := *resp.Choices[0].FinishReason; got != wantFinish {
+				t.Errorf("Expected finish_reason %s, got %s", wantFinish, got)
+			}
+		})
+	}
+}
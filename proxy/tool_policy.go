@@ -0,0 +1,197 @@
+package proxy
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/correction"
+	"claude-proxy/logger"
+	"claude-proxy/types"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// PendingApproval is a tool call held by a require_approval policy until an
+// admin decides on it through /admin/approvals
+type PendingApproval struct {
+	ID        string                 `json:"id"`
+	RequestID string                 `json:"request_id"`
+	Policy    string                 `json:"policy"`
+	Tool      string                 `json:"tool"`
+	Input     map[string]interface{} `json:"input"`
+	CreatedAt time.Time              `json:"created_at"`
+
+	decision chan bool // Receives true for approve, false for deny
+}
+
+// approvalQueue holds tool calls waiting for an admin decision
+type approvalQueue struct {
+	mu      sync.Mutex
+	pending map[string]*PendingApproval
+	nextID  int
+}
+
+func newApprovalQueue() *approvalQueue {
+	return &approvalQueue{pending: make(map[string]*PendingApproval)}
+}
+
+// add queues a tool call for approval and returns the function that removes it
+func (q *approvalQueue) add(requestID, policy string, call types.Content) (*PendingApproval, func()) {
+	q.mu.Lock()
+	q.nextID++
+	approval := &PendingApproval{
+		ID:        fmt.Sprintf("approval_%d", q.nextID),
+		RequestID: requestID,
+		Policy:    policy,
+		Tool:      call.Name,
+		Input:     call.Input,
+		CreatedAt: time.Now(),
+		decision:  make(chan bool, 1),
+	}
+	q.pending[approval.ID] = approval
+	q.mu.Unlock()
+	return approval, func() {
+		q.mu.Lock()
+		delete(q.pending, approval.ID)
+		q.mu.Unlock()
+	}
+}
+
+// decide delivers an admin decision, reporting false when no call with the
+// given ID is waiting
+func (q *approvalQueue) decide(id string, approved bool) bool {
+	q.mu.Lock()
+	approval, exists := q.pending[id]
+	delete(q.pending, id)
+	q.mu.Unlock()
+	if !exists {
+		return false
+	}
+	approval.decision <- approved
+	return true
+}
+
+// list returns the calls waiting for approval, oldest first
+func (q *approvalQueue) list() []PendingApproval {
+	q.mu.Lock()
+	list := make([]PendingApproval, 0, len(q.pending))
+	for _, approval := range q.pending {
+		list = append(list, *approval)
+	}
+	q.mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list
+}
+
+// applyToolPolicies evaluates tool_policies.yaml for every tool call. Denied
+// calls are replaced with a text note; calls that require approval wait for an
+// admin decision, up to APPROVAL_TIMEOUT_SECONDS, and are denied on timeout.
+// Returns the new content and whether any call was denied.
+func (h *Handler) applyToolPolicies(ctx context.Context, content []types.Content, session, requestID string, loggerInstance logger.Logger) ([]types.Content, bool) {
+	if len(h.config.ToolPolicies) == 0 {
+		return content, false
+	}
+	trace := debugTraceFrom(ctx)
+
+	result := append([]types.Content(nil), content...)
+	held := make(map[int]*PendingApproval)
+	now := time.Now()
+	denied := false
+	for i, item := range result {
+		if item.Type != "tool_use" {
+			continue
+		}
+		policy, matched := h.config.ToolPolicyFor(item.Name, item.Input, session, now)
+		if !matched {
+			continue
+		}
+		switch policy.Action {
+		case config.ToolPolicyDeny:
+			loggerInstance.Warn("🛡️ Tool call %s denied by policy %s", item.Name, policy.Name)
+			trace.Step("tool call %s denied by policy %s", item.Name, policy.Name)
+			result[i] = correction.BlockedToolCallNotice(item.Name, "denied by policy "+policy.Name, correction.ValidationResult{})
+			denied = true
+		case config.ToolPolicyRequireApproval:
+			approval, remove := h.approvals.add(requestID, policy.Name, item)
+			defer remove()
+			held[i] = approval
+			loggerInstance.Info("🛡️ Tool call %s held for approval by policy %s (approval ID %s)", item.Name, policy.Name, approval.ID)
+			trace.Step("tool call %s held for approval %s by policy %s", item.Name, approval.ID, policy.Name)
+		}
+	}
+	if len(held) == 0 {
+		return result, denied
+	}
+
+	timeout := time.Duration(h.config.ApprovalTimeoutSeconds) * time.Second
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for i, approval := range held {
+		reason := ""
+		select {
+		case approved := <-approval.decision:
+			if approved {
+				loggerInstance.Info("🛡️ Tool call %s approved (approval ID %s)", approval.Tool, approval.ID)
+				continue
+			}
+			reason = fmt.Sprintf("denied by an administrator (policy %s)", approval.Policy)
+		case <-waitCtx.Done():
+			reason = fmt.Sprintf("no approval within %s (policy %s)", timeout, approval.Policy)
+		}
+		loggerInstance.Warn("🛡️ Tool call %s not approved (approval ID %s): %s", approval.Tool, approval.ID, reason)
+		result[i] = correction.BlockedToolCallNotice(approval.Tool, reason, correction.ValidationResult{})
+		denied = true
+	}
+	return result, denied
+}
+
+// requestSession returns the session tool policies match against, the
+// metadata.user_id Claude Code sends with every request
+func requestSession(req types.AnthropicRequest) string {
+	if req.Metadata == nil {
+		return ""
+	}
+	return req.Metadata.UserID
+}
+
+// approvalsResponse is the JSON body served by HandleApprovals
+type approvalsResponse struct {
+	Pending []PendingApproval `json:"pending"`
+}
+
+// HandleApprovals serves the tool-call approvals queue:
+// GET /admin/approvals lists calls waiting for a decision, oldest first, and
+// POST /admin/approvals?id=...&decision=approve|deny decides on one.
+func (h *Handler) HandleApprovals(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(approvalsResponse{Pending: h.approvals.list()}); err != nil {
+			http.Error(w, "Failed to encode approvals", http.StatusInternalServerError)
+		}
+	case http.MethodPost:
+		params := r.URL.Query()
+		var approved bool
+		switch params.Get("decision") {
+		case "approve":
+			approved = true
+		case "deny":
+			approved = false
+		default:
+			http.Error(w, "decision must be approve or deny", http.StatusBadRequest)
+			return
+		}
+		if !h.approvals.decide(params.Get("id"), approved) {
+			http.Error(w, "No pending approval with that id", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
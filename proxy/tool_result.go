@@ -0,0 +1,177 @@
+package proxy
+
+import (
+	"bytes"
+	"claude-proxy/config"
+	"claude-proxy/tokenizer"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Markers added to tool results changed by normalizeToolResult
+const (
+	toolResultRepairedMarker  = "\n[truncated JSON closed by proxy]"
+	toolResultTruncatedMarker = "\n... [tool result truncated by proxy] ...\n"
+)
+
+// normalizeToolResult applies TOOL_RESULT_REPAIR and TOOL_RESULT_MAX_TOKENS to
+// the text of a tool_result block before it is forwarded to the backend.
+// Returns the text and what was changed, nil when it is unchanged.
+func normalizeToolResult(cfg *config.Config, text string) (string, []string) {
+	var changes []string
+	if cfg.ToolResultRepair {
+		if repaired, change := repairToolResultJSON(text); change != "" {
+			text = repaired
+			changes = append(changes, change)
+		}
+	}
+	if cfg.ToolResultMaxTokens > 0 && tokenizer.Count(text) > cfg.ToolResultMaxTokens {
+		text = tokenizer.Truncate(text, cfg.ToolResultMaxTokens, toolResultTruncatedMarker)
+		changes = append(changes, "truncated")
+	}
+	return text, changes
+}
+
+// repairToolResultJSON rewrites tool results that hold JSON so upstream models
+// read them reliably: a result that is JSON encoded as a string is decoded,
+// truncated arrays and objects are closed after their last complete element,
+// and the result is pretty-printed. String values inside the JSON are left as
+// they are, even when they look like JSON, and numbers keep their digits.
+// Returns the text unchanged and "" when it is not JSON or already in that form.
+func repairToolResultJSON(text string) (string, string) {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" || !strings.ContainsAny(trimmed[:1], "{[\"") {
+		return text, ""
+	}
+
+	change := "reformatted"
+	value, err := decodeJSONValue(trimmed)
+	if err != nil {
+		closed, ok := closeTruncatedJSON(trimmed)
+		if !ok {
+			return text, ""
+		}
+		if value, err = decodeJSONValue(closed); err != nil {
+			return text, ""
+		}
+		change = "closed_truncated"
+	}
+
+	value, unescaped := decodeEncodedJSON(value)
+	if _, isString := value.(string); isString {
+		return text, "" // Plain text in quotes, not JSON
+	}
+	if unescaped && change == "reformatted" {
+		change = "unescaped"
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		return text, ""
+	}
+	repaired := strings.TrimSuffix(buf.String(), "\n")
+	if change == "closed_truncated" {
+		repaired += toolResultRepairedMarker
+	}
+	if repaired == text {
+		return text, ""
+	}
+	return repaired, change
+}
+
+// decodeJSONValue decodes a single JSON value, keeping numbers as
+// json.Number so integers beyond float64 precision (IDs, nanosecond
+// timestamps) are not rounded
+func decodeJSONValue(text string) (interface{}, error) {
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after JSON value")
+	}
+	return value, nil
+}
+
+// decodeEncodedJSON decodes a tool result that is a string holding a JSON
+// object or array, as often as it was encoded. Reports whether it decoded.
+func decodeEncodedJSON(value interface{}) (interface{}, bool) {
+	decoded := false
+	for {
+		text, ok := value.(string)
+		if !ok {
+			return value, decoded
+		}
+		trimmed := strings.TrimSpace(text)
+		if len(trimmed) < 2 || !strings.ContainsAny(trimmed[:1], "{[\"") {
+			return value, decoded
+		}
+		nested, err := decodeJSONValue(trimmed)
+		if err != nil {
+			return value, decoded
+		}
+		value, decoded = nested, true
+	}
+}
+
+// closeTruncatedJSON cuts truncated JSON back to the last complete element of
+// its innermost open array or object and closes every open bracket. Reports
+// false when the text is not truncated JSON or no element is complete.
+func closeTruncatedJSON(text string) (string, bool) {
+	var stack, cutStack []byte
+	cut := -1
+	complete := false
+	inString, escaped := false, false
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			stack = append(stack, c)
+			cut, cutStack = i+1, append([]byte(nil), stack...)
+		case '}', ']':
+			if len(stack) == 0 || (c == '}') != (stack[len(stack)-1] == '{') {
+				return "", false
+			}
+			stack = stack[:len(stack)-1]
+			cut, cutStack = i+1, append([]byte(nil), stack...)
+			complete = true
+		case ',':
+			cut, cutStack = i, append([]byte(nil), stack...)
+			complete = true
+		}
+	}
+	if len(stack) == 0 || !complete {
+		return "", false
+	}
+
+	var closed strings.Builder
+	closed.WriteString(text[:cut])
+	for i := len(cutStack) - 1; i >= 0; i-- {
+		if cutStack[i] == '{' {
+			closed.WriteByte('}')
+		} else {
+			closed.WriteByte(']')
+		}
+	}
+	return closed.String(), true
+}
@@ -0,0 +1,186 @@
+package proxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"claude-proxy/config"
+	"claude-proxy/correction"
+	"claude-proxy/logger"
+)
+
+// toolSummaryTimeout bounds a single background summarization request
+const toolSummaryTimeout = 2 * time.Minute
+
+// ToolSummaryCache persists LLM-generated tool description summaries on disk.
+// Entries are keyed by a hash of the full description and the character budget,
+// so a changed description (e.g. a new Claude Code release) is summarized again.
+//
+// Thread Safety: All methods are safe for concurrent use.
+type ToolSummaryCache struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]string
+	pending map[string]bool // Keys currently being summarized in the background
+}
+
+var (
+	toolSummaryCachesMu sync.Mutex
+	toolSummaryCaches   = make(map[string]*ToolSummaryCache)
+)
+
+// NewToolSummaryCache opens the cache stored at path; a missing file yields an empty cache
+func NewToolSummaryCache(path string) (*ToolSummaryCache, error) {
+	cache := &ToolSummaryCache{
+		path:    path,
+		entries: make(map[string]string),
+		pending: make(map[string]bool),
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cache, nil
+		}
+		return nil, fmt.Errorf("failed to read tool summary cache %s: %v", path, err)
+	}
+	if err := json.Unmarshal(data, &cache.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse tool summary cache %s: %v", path, err)
+	}
+	return cache, nil
+}
+
+// toolSummaryCacheFor returns the process-wide cache for path, opening it on first use
+func toolSummaryCacheFor(path string) (*ToolSummaryCache, error) {
+	toolSummaryCachesMu.Lock()
+	defer toolSummaryCachesMu.Unlock()
+	if cache, exists := toolSummaryCaches[path]; exists {
+		return cache, nil
+	}
+	cache, err := NewToolSummaryCache(path)
+	if err != nil {
+		return nil, err
+	}
+	toolSummaryCaches[path] = cache
+	return cache, nil
+}
+
+// toolSummaryKey identifies a summary by description content and budget
+func toolSummaryKey(description string, maxChars int) string {
+	sum := sha256.Sum256([]byte(description))
+	return fmt.Sprintf("%s:%d", hex.EncodeToString(sum[:]), maxChars)
+}
+
+// Get returns the cached summary for a description and budget
+func (c *ToolSummaryCache) Get(description string, maxChars int) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	summary, exists := c.entries[toolSummaryKey(description, maxChars)]
+	return summary, exists
+}
+
+// Put stores a summary and writes the cache file
+func (c *ToolSummaryCache) Put(description string, maxChars int, summary string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[toolSummaryKey(description, maxChars)] = summary
+	return c.saveLocked()
+}
+
+// Len returns the number of cached summaries
+func (c *ToolSummaryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// claim marks a key as being summarized; false if it is cached or already in progress
+func (c *ToolSummaryCache) claim(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, cached := c.entries[key]; cached || c.pending[key] {
+		return false
+	}
+	c.pending[key] = true
+	return true
+}
+
+// release clears the in-progress marker for a key
+func (c *ToolSummaryCache) release(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, key)
+}
+
+// saveLocked writes the cache atomically via a temp file; the caller holds c.mu
+func (c *ToolSummaryCache) saveLocked() error {
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode tool summary cache: %v", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), ".tool_summaries-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write tool summary cache: %v", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write tool summary cache: %v", err)
+	}
+	tmp.Close()
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write tool summary cache: %v", err)
+	}
+	return nil
+}
+
+// summarizedToolDescription returns the cached LLM summary of a description when
+// available. On a cache miss the description is summarized in the background
+// and the truncating minifier is used for the current request, so no request
+// ever waits on the correction model.
+func summarizedToolDescription(ctx context.Context, cfg *config.Config, toolName, description string, limit int, loggerInstance logger.Logger) string {
+	if len(description) <= limit {
+		return description
+	}
+
+	cache, err := toolSummaryCacheFor(cfg.ToolSummaryCachePath)
+	if err != nil {
+		loggerInstance.Warn("⚠️ Tool summary cache unavailable, using truncation: %v", err)
+		return MinifyToolDescription(description, limit)
+	}
+
+	if summary, exists := cache.Get(description, limit); exists {
+		return truncateAtBoundary(summary, limit)
+	}
+
+	key := toolSummaryKey(description, limit)
+	if cache.claim(key) {
+		summaryCtx := withRequestID(context.Background(), GetRequestID(ctx))
+		go func() {
+			defer cache.release(key)
+			summaryCtx, cancel := context.WithTimeout(summaryCtx, toolSummaryTimeout)
+			defer cancel()
+
+			service := correction.NewService(cfg, cfg.ToolCorrectionAPIKey, true, cfg.CorrectionModel, cfg.DisableToolCorrectionLogging, nil)
+			summary, err := service.SummarizeToolDescription(summaryCtx, toolName, description, limit)
+			if err != nil {
+				loggerInstance.Warn("⚠️ Failed to summarize tool description for %s: %v", toolName, err)
+				return
+			}
+			if err := cache.Put(description, limit, summary); err != nil {
+				loggerInstance.Warn("⚠️ Failed to persist tool summary for %s: %v", toolName, err)
+				return
+			}
+			loggerInstance.Info("🗜️ Cached summary for tool %s (%d -> %d chars)", toolName, len(description), len(summary))
+		}()
+	}
+
+	return MinifyToolDescription(description, limit)
+}
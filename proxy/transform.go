@@ -10,6 +10,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 )
 
 // NOTE: isSmallModel and shouldLogForModel functions removed
@@ -127,22 +128,26 @@ func TransformAnthropicToOpenAI(ctx context.Context, req types.AnthropicRequest,
 	loggerConfig := logger.NewConfigAdapter(cfg)
 	loggerInstance := logger.FromContext(ctx, loggerConfig)
 
+	// PASSTHROUGH_MODELS get the system prompt and tools exactly as the client sent them
+	passthrough := cfg.IsPassthroughModel(req.Model)
+
 	// HARMONY DETECTION AND PROCESSING - Chain of responsibility pattern
-	// Check for Harmony format in messages and process if enabled
-	if cfg.IsHarmonyParsingEnabled() {
+	// Check for Harmony format in messages and process if enabled for the model
+	if cfg.IsHarmonyEnabledForModel(req.Model) {
 		harmonyProcessed, err := processHarmonyMessages(ctx, &req, cfg, loggerInstance)
 		if err != nil {
 			if cfg.IsHarmonyStrictModeEnabled() {
 				// In strict mode, fail the request on Harmony parsing errors
-				return types.OpenAIRequest{}, fmt.Errorf("harmony parsing error: %v", err)
+				return types.OpenAIRequest{}, newProxyError(CodeHarmonyStrictReject, "harmony parsing error: %v", err)
 			} else {
 				// In lenient mode, log warning and continue with fallback
-				loggerInstance.Warn("⚠️ Harmony parsing failed, falling back to standard processing: %v", err)
+				loggerInstance.WithComponent(logger.ComponentHarmony).Warn("⚠️ Harmony parsing failed, falling back to standard processing: %v", err)
 			}
 		} else if harmonyProcessed {
+			debugTraceFrom(ctx).Step("harmony format processed in request messages")
 			// Harmony was successfully processed, request has been modified
 			if cfg.IsHarmonyDebugEnabled() {
-				loggerInstance.Debug("✅ Harmony format detected and processed successfully")
+				loggerInstance.WithComponent(logger.ComponentHarmony).Debug("✅ Harmony format detected and processed successfully")
 			}
 		}
 		// If not Harmony format or processing failed in lenient mode, continue with standard transformation
@@ -156,6 +161,12 @@ func TransformAnthropicToOpenAI(ctx context.Context, req types.AnthropicRequest,
 		Messages:    []types.OpenAIMessage{},
 	}
 
+	// Translate sampling parameters and output limits within the backend's supported ranges
+	applySamplingParams(req, &openaiReq, cfg, loggerInstance)
+	applyAnthropicOnlyParams(req, &openaiReq, cfg, loggerInstance)
+	applyMaxTokens(req, &openaiReq, cfg, loggerInstance)
+	applyStreamOptions(req, &openaiReq, cfg)
+
 	// Handle system messages - convert from Anthropic array to OpenAI string
 	if len(req.System) > 0 {
 		var systemParts []string
@@ -169,14 +180,19 @@ func TransformAnthropicToOpenAI(ctx context.Context, req types.AnthropicRequest,
 			systemContent := strings.Join(systemParts, "\n")
 
 			// Apply system message overrides if any are configured
-			if len(cfg.SystemMessageOverrides.RemovePatterns) > 0 ||
-				len(cfg.SystemMessageOverrides.Replacements) > 0 ||
-				cfg.SystemMessageOverrides.Prepend != "" ||
-				cfg.SystemMessageOverrides.Append != "" {
+			if !cfg.SystemMessageOverrides.IsEmpty() && !passthrough {
 				originalContent := systemContent
-				systemContent = config.ApplySystemMessageOverrides(systemContent, cfg.SystemMessageOverrides)
+				systemContent = cfg.ApplySystemOverrides(GetRequestID(ctx), systemContent)
 
 				logger.LogSystemOverride(ctx, loggerInstance, len(originalContent), len(systemContent))
+				debugTraceFrom(ctx).Step("system overrides applied: %d -> %d chars", len(originalContent), len(systemContent))
+			}
+
+			// Inject per-model instructions after the (overridden) Claude Code system prompt
+			if modelPrompt := cfg.GetModelSystemPrompt(req.Model); modelPrompt != "" && !passthrough {
+				systemContent = config.InjectModelSystemPrompt(systemContent, modelPrompt)
+				debugTraceFrom(ctx).Step("model system prompt injected for %s", req.Model)
+				loggerInstance.Info("➕ Injected system prompt for model %s (%d chars)", req.Model, len(modelPrompt))
 			}
 
 			// Print system message if enabled
@@ -191,6 +207,17 @@ func TransformAnthropicToOpenAI(ctx context.Context, req types.AnthropicRequest,
 		}
 	}
 
+	// Requests without a system prompt still receive the per-model instructions
+	if len(openaiReq.Messages) == 0 {
+		if modelPrompt := cfg.GetModelSystemPrompt(req.Model); modelPrompt != "" {
+			openaiReq.Messages = append(openaiReq.Messages, types.OpenAIMessage{
+				Role:    "system",
+				Content: config.InjectModelSystemPrompt("", modelPrompt),
+			})
+			loggerInstance.Info("➕ Injected system prompt for model %s (%d chars)", req.Model, len(modelPrompt))
+		}
+	}
+
 	// Transform messages
 	for i, msg := range req.Messages {
 		openaiMsg := types.OpenAIMessage{
@@ -201,6 +228,7 @@ func TransformAnthropicToOpenAI(ctx context.Context, req types.AnthropicRequest,
 		if msg.Role == "assistant" {
 			hasText := false
 			hasToolUse := false
+			hasThinking := false
 			contentCount := 0
 
 			switch content := msg.Content.(type) {
@@ -214,6 +242,8 @@ func TransformAnthropicToOpenAI(ctx context.Context, req types.AnthropicRequest,
 							}
 						} else if contentType == "tool_use" {
 							hasToolUse = true
+						} else if contentType == "thinking" || contentType == "redacted_thinking" {
+							hasThinking = true
 						}
 					}
 				}
@@ -224,7 +254,7 @@ func TransformAnthropicToOpenAI(ctx context.Context, req types.AnthropicRequest,
 				}
 			}
 
-			if !hasText && !hasToolUse {
+			if !hasText && !hasToolUse && !hasThinking {
 				loggerInstance.Warn("⚠️ Assistant message %d has no text or tool_use content (%d content items)",
 					i, contentCount)
 				// Log the actual content structure for debugging
@@ -264,6 +294,8 @@ func TransformAnthropicToOpenAI(ctx context.Context, req types.AnthropicRequest,
 			// Array format, need to convert to []Content
 			var textParts []string
 			var toolCalls []types.OpenAIToolCall
+			var thinkingParts []string
+			strippedThinking := 0
 
 			for _, item := range content {
 				if contentMap, ok := item.(map[string]interface{}); ok {
@@ -301,15 +333,16 @@ func TransformAnthropicToOpenAI(ctx context.Context, req types.AnthropicRequest,
 							} else {
 								// Apply system message overrides to tool result content
 								processedText := text
-								if len(cfg.SystemMessageOverrides.RemovePatterns) > 0 ||
-									len(cfg.SystemMessageOverrides.Replacements) > 0 ||
-									cfg.SystemMessageOverrides.Prepend != "" ||
-									cfg.SystemMessageOverrides.Append != "" {
-									processedText = config.ApplySystemMessageOverrides(text, cfg.SystemMessageOverrides)
+								if !cfg.SystemMessageOverrides.IsEmpty() && !passthrough {
+									processedText = cfg.ApplyToolResultOverrides(GetRequestID(ctx), text)
 									if processedText != text {
 										logger.LogSystemOverride(ctx, loggerInstance, len(text), len(processedText))
 									}
 								}
+								if normalized, changes := normalizeToolResult(cfg, processedText); len(changes) > 0 {
+									loggerInstance.Debug("🧰 Normalized tool result (%s): %d -> %d bytes", strings.Join(changes, ", "), len(processedText), len(normalized))
+									processedText = normalized
+								}
 								openaiMsg.Content = processedText
 							}
 						} else if cfg.HandleEmptyToolResults {
@@ -320,6 +353,17 @@ func TransformAnthropicToOpenAI(ctx context.Context, req types.AnthropicRequest,
 						if toolUseID, ok := contentMap["tool_use_id"].(string); ok {
 							openaiMsg.ToolCallID = toolUseID
 						}
+					case "thinking":
+						// Signatures only verify against Anthropic, so the text is either
+						// forwarded as reasoning_content or dropped
+						if thinking, ok := contentMap["thinking"].(string); ok && thinking != "" && cfg.ForwardThinkingBlocks {
+							thinkingParts = append(thinkingParts, thinking)
+						} else {
+							strippedThinking++
+						}
+					case "redacted_thinking":
+						// Encrypted by Anthropic - no OpenAI-compatible backend can read it
+						strippedThinking++
 					}
 				}
 			}
@@ -333,6 +377,20 @@ func TransformAnthropicToOpenAI(ctx context.Context, req types.AnthropicRequest,
 			if len(toolCalls) > 0 {
 				openaiMsg.ToolCalls = toolCalls
 			}
+
+			// Set prior-turn reasoning
+			if len(thinkingParts) > 0 {
+				openaiMsg.ReasoningContent = strings.Join(thinkingParts, "\n")
+			}
+
+			if strippedThinking > 0 {
+				loggerInstance.Debug("💭 Stripped %d thinking block(s) from message %d", strippedThinking, i)
+				// A turn that only carried thinking would reach the backend as an empty assistant message
+				if openaiMsg.Content == "" && len(openaiMsg.ToolCalls) == 0 && openaiMsg.ReasoningContent == "" {
+					loggerInstance.Debug("💭 Dropped thinking-only assistant message %d", i)
+					continue
+				}
+			}
 		default:
 			// Fallback for unexpected format
 			loggerInstance.Warn("⚠️ Unexpected content format: %T", content)
@@ -380,6 +438,9 @@ func TransformAnthropicToOpenAI(ctx context.Context, req types.AnthropicRequest,
 		openaiReq.Messages = append(openaiReq.Messages, openaiMsg)
 	}
 
+	// Keep a trailing assistant message as a partial turn to continue
+	applyAssistantPrefill(req, &openaiReq, cfg, loggerInstance)
+
 	// Debug logging: print all messages being sent
 	modelLogger := loggerInstance.WithModel(req.Model)
 	modelLogger.Debug("🔍 Final message list (%d messages):", len(openaiReq.Messages))
@@ -539,11 +600,13 @@ func TransformAnthropicToOpenAI(ctx context.Context, req types.AnthropicRequest,
 	// Transform tools
 	if len(req.Tools) > 0 {
 		// Context-aware tool filtering: Analyze conversation to determine appropriate tools
-		contextBasedSkipTools := make([]string, len(cfg.SkipTools))
-		copy(contextBasedSkipTools, cfg.SkipTools)
+		var contextBasedSkipTools []string
+		if !passthrough {
+			contextBasedSkipTools = append(contextBasedSkipTools, cfg.SkipTools...)
+		}
 
 		// Check if conversation suggests research/analysis rather than planning
-		if shouldSkipExitPlanMode(ctx, req.Messages, cfg) {
+		if !passthrough && !correctionBypassedIn(ctx) && shouldSkipExitPlanMode(ctx, req.Messages, cfg) {
 			contextBasedSkipTools = append(contextBasedSkipTools, "ExitPlanMode")
 			loggerInstance.Info("🔍 Context analysis: ExitPlanMode filtered out (research/analysis detected)")
 		}
@@ -569,6 +632,7 @@ func TransformAnthropicToOpenAI(ctx context.Context, req types.AnthropicRequest,
 		// Log skipped tools if any
 		if len(skippedTools) > 0 {
 			logger.LogToolsSkipped(ctx, loggerInstance, len(skippedTools), skippedTools)
+			debugTraceFrom(ctx).Step("tools skipped: %s", strings.Join(skippedTools, ", "))
 		}
 
 		// Print tool schemas if enabled (before transformation to see original Claude Code schemas)
@@ -578,6 +642,7 @@ func TransformAnthropicToOpenAI(ctx context.Context, req types.AnthropicRequest,
 
 		// Transform filtered tools
 		if len(filteredTools) > 0 {
+			minified := false
 			openaiReq.Tools = make([]types.OpenAITool, len(filteredTools))
 			for i, tool := range filteredTools {
 				// Attempt to restore corrupted tool schemas before processing
@@ -598,13 +663,25 @@ func TransformAnthropicToOpenAI(ctx context.Context, req types.AnthropicRequest,
 
 				// Use YAML override description if available, otherwise use original
 				description := cfg.GetToolDescription(tool.Name, tool.Description)
+				parameters := tool.InputSchema
+
+				// Minify what is sent upstream; req.Tools keeps the full schema for validation
+				if limit, ok := toolMinifyLimit(ctx, cfg, tool.Name); ok {
+					minified = true
+					if cfg.ToolSchemaMinifyMode == config.ToolMinifySummarize {
+						description = summarizedToolDescription(ctx, cfg, tool.Name, description, limit, loggerInstance)
+					} else {
+						description = MinifyToolDescription(description, limit)
+					}
+					parameters = minifyToolSchema(parameters)
+				}
 
 				openaiReq.Tools[i] = types.OpenAITool{
 					Type: "function",
 					Function: types.OpenAIToolFunction{
 						Name:        tool.Name,
 						Description: description,
-						Parameters:  tool.InputSchema,
+						Parameters:  parameters,
 					},
 				}
 
@@ -617,6 +694,16 @@ func TransformAnthropicToOpenAI(ctx context.Context, req types.AnthropicRequest,
 
 			logger.LogToolsTransformed(ctx, modelLogger, len(openaiReq.Tools), len(req.Tools))
 
+			if minified {
+				originalChars, minifiedChars := 0, 0
+				for i, tool := range filteredTools {
+					originalChars += len(cfg.GetToolDescription(tool.Name, tool.Description))
+					minifiedChars += len(openaiReq.Tools[i].Function.Description)
+				}
+				modelLogger.Info("🗜️ Minified tool descriptions: %d -> %d chars", originalChars, minifiedChars)
+				debugTraceFrom(ctx).Step("tool descriptions minified: %d -> %d chars", originalChars, minifiedChars)
+			}
+
 			// Log first few tool names for debugging
 			toolNames := make([]string, len(openaiReq.Tools))
 			for i, tool := range openaiReq.Tools {
@@ -628,11 +715,72 @@ func TransformAnthropicToOpenAI(ctx context.Context, req types.AnthropicRequest,
 		}
 	}
 
+	// Degrade features the backend model lacks (native tools, system role)
+	capabilities := cfg.GetModelCapabilities(req.Model)
+	hasTools := len(openaiReq.Tools) > 0
+	applyModelCapabilities(&openaiReq, capabilities, loggerInstance)
+
+	if cfg.IsHarmonyModel(req.Model) {
+		harmonyLogger := loggerInstance.WithComponent(logger.ComponentHarmony)
+		// Harmony models read instructions and tool guidance from a developer message
+		if cfg.HarmonyDeveloperRole && useDeveloperRole(&openaiReq) {
+			harmonyLogger.Debug("🧭 Sent the system prompt as a developer message to %s", req.Model)
+		}
+		if cfg.HarmonySystemPreamble && capabilities.SupportsSystemRole() {
+			injectHarmonyPreamble(&openaiReq, harmonyPreamble(cfg.HarmonyReasoningLevel, cfg.HarmonyKnowledgeCutoff, time.Now(), hasTools))
+			harmonyLogger.Debug("🧭 Injected the Harmony system preamble for %s (reasoning: %s)", req.Model, cfg.HarmonyReasoningLevel)
+		}
+	}
+
 	return openaiReq, nil
 }
 
+// ResponseConfig is the configuration response transformation reads
+type ResponseConfig interface {
+	config.HarmonyConfig
+	config.LoggingConfig
+}
+
+// backendModelKey stores the mapped backend model in the request context
+type backendModelKey struct{}
+
+// withBackendModel records the mapped model so the response transform can
+// apply per-model settings
+func withBackendModel(ctx context.Context, model string) context.Context {
+	return context.WithValue(ctx, backendModelKey{}, model)
+}
+
+// harmonyEnabledFor reports whether responses are parsed for Harmony, per
+// backend model (HARMONY_MODELS) when the context names one
+func harmonyEnabledFor(ctx context.Context, cfg config.HarmonyConfig) bool {
+	if model, _ := ctx.Value(backendModelKey{}).(string); model != "" {
+		return cfg.IsHarmonyEnabledForModel(model)
+	}
+	return cfg.IsHarmonyParsingEnabled()
+}
+
+type tokenRecognizerKey struct{}
+
+// withTokenRecognizer records the recognizer built from the configured
+// Harmony tokens for the response transform
+func withTokenRecognizer(ctx context.Context, tr *parser.TokenRecognizer) context.Context {
+	if tr == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, tokenRecognizerKey{}, tr)
+}
+
+// tokenRecognizerFor returns the recognizer recorded in the context, or the
+// parser's default one
+func tokenRecognizerFor(ctx context.Context) (*parser.TokenRecognizer, error) {
+	if tr, ok := ctx.Value(tokenRecognizerKey{}).(*parser.TokenRecognizer); ok {
+		return tr, nil
+	}
+	return parser.DefaultTokenRecognizer()
+}
+
 // TransformOpenAIToAnthropic converts OpenAI response format to Anthropic format
-func TransformOpenAIToAnthropic(ctx context.Context, resp *types.OpenAIResponse, model string, cfg *config.Config) (*types.AnthropicResponse, error) {
+func TransformOpenAIToAnthropic(ctx context.Context, resp *types.OpenAIResponse, model string, cfg ResponseConfig) (*types.AnthropicResponse, error) {
 	// Set up logger for this function
 	loggerConfig := logger.NewConfigAdapter(cfg)
 	loggerInstance := logger.FromContext(ctx, loggerConfig)
@@ -646,21 +794,37 @@ func TransformOpenAIToAnthropic(ctx context.Context, resp *types.OpenAIResponse,
 	// Convert content
 	var content []types.Content
 	var harmonyChannels []parser.Channel
+	harmonyLogger := loggerInstance.WithComponent(logger.ComponentHarmony)
+	harmonyEnabled := harmonyEnabledFor(ctx, cfg)
+	recognizer, err := tokenRecognizerFor(ctx)
+	if err != nil && harmonyEnabled {
+		harmonyLogger.Warn("⚠️ Harmony token recognizer unavailable, skipping Harmony parsing: %v", err)
+		harmonyEnabled = false
+	}
+
+	// Reasoning the backend returned separately (parsed ReAct thoughts, reasoning
+	// parsers) comes first, like Harmony thinking
+	if choice.Message.ReasoningContent != "" {
+		content = append(content, types.Content{
+			Type:     "thinking",
+			Thinking: choice.Message.ReasoningContent,
+		})
+	}
 
 	// Add text content if present
 	if choice.Message.Content != "" {
 		// Check for Harmony format and process if enabled
-		if cfg.IsHarmonyParsingEnabled() && parser.IsHarmonyFormat(choice.Message.Content) {
-			loggerInstance.Debug("🔍 Harmony tokens detected, performing full extraction")
+		if harmonyEnabled && recognizer.HasHarmonyTokens(choice.Message.Content) {
+			harmonyLogger.Debug("🔍 Harmony tokens detected, performing full extraction")
 
-			harmonyMsg, err := parser.ParseHarmonyMessage(choice.Message.Content)
+			harmonyMsg, err := recognizer.ParseHarmonyMessage(choice.Message.Content)
 			channelCount := 0
 			if harmonyMsg != nil {
 				channelCount = len(harmonyMsg.Channels)
 			}
-			loggerInstance.Debug("🔍 ParseHarmonyMessage result: err=%v, channels=%d", err, channelCount)
+			harmonyLogger.Debug("🔍 ParseHarmonyMessage result: err=%v, channels=%d", err, channelCount)
 			if err == nil && len(harmonyMsg.Channels) > 0 {
-				loggerInstance.Debug("✅ Successfully extracted %d Harmony channels", len(harmonyMsg.Channels))
+				harmonyLogger.Debug("✅ Successfully extracted %d Harmony channels", len(harmonyMsg.Channels))
 
 				// Handle both complete and partial Harmony sequences
 				var responseText string
@@ -668,7 +832,7 @@ func TransformOpenAIToAnthropic(ctx context.Context, resp *types.OpenAIResponse,
 				// First, try to extract content after the last Harmony sequence (for partial sequences)
 				originalContent := choice.Message.Content
 				cleanContent := ""
-				tokens := parser.FindHarmonyTokens(originalContent)
+				tokens := recognizer.FindHarmonyTokens(originalContent)
 				if len(tokens) > 0 {
 					// Find the position of the last <|end|> token
 					lastEndPos := -1
@@ -688,24 +852,24 @@ func TransformOpenAIToAnthropic(ctx context.Context, resp *types.OpenAIResponse,
 				if cleanContent != "" {
 					// Content after Harmony sequences (partial sequences like Issue #8)
 					responseText = cleanContent
-					loggerInstance.Debug("✅ Using content after Harmony sequences")
+					harmonyLogger.Debug("✅ Using content after Harmony sequences")
 				} else if harmonyMsg.ResponseText != "" {
 					// Response text from final channels (complete sequences)
 					responseText = harmonyMsg.ResponseText
-					loggerInstance.Debug("✅ Using ResponseText from Harmony channels")
+					harmonyLogger.Debug("✅ Using ResponseText from Harmony channels")
 				} else {
 					// No response channels found - set responseText to empty since we only have thinking content
 					responseText = ""
-					loggerInstance.Debug("⚠️ No response content found, only thinking content available")
+					harmonyLogger.Debug("⚠️ No response content found, only thinking content available")
 				}
 
 				// Add thinking content first (if present) for Claude Code UI compatibility
 				if harmonyMsg.ThinkingText != "" {
 					content = append(content, types.Content{
-						Type: "thinking",
-						Text: harmonyMsg.ThinkingText,
+						Type:     "thinking",
+						Thinking: harmonyMsg.ThinkingText,
 					})
-					loggerInstance.Debug("💭 Added thinking content block: %d characters", len(harmonyMsg.ThinkingText))
+					harmonyLogger.Debug("💭 Added thinking content block: %d characters", len(harmonyMsg.ThinkingText))
 				}
 
 				// Add main response content only if we have actual response text (not raw Harmony tokens)
@@ -714,14 +878,14 @@ func TransformOpenAIToAnthropic(ctx context.Context, resp *types.OpenAIResponse,
 						Type: "text",
 						Text: responseText,
 					})
-					loggerInstance.Debug("✅ Added response text block: %d characters", len(responseText))
+					harmonyLogger.Debug("✅ Added response text block: %d characters", len(responseText))
 				}
 
 				// Store harmony channels for debugging
 				harmonyChannels = harmonyMsg.Channels
 
 			} else {
-				loggerInstance.Debug("🔍 Harmony tokens found but no channels extracted - treating as non-Harmony")
+				harmonyLogger.Debug("🔍 Harmony tokens found but no channels extracted - treating as non-Harmony")
 				// Fallback to original content
 				content = append(content, types.Content{
 					Type: "text",
@@ -729,8 +893,8 @@ func TransformOpenAIToAnthropic(ctx context.Context, resp *types.OpenAIResponse,
 				})
 			}
 		} else {
-			if cfg.IsHarmonyParsingEnabled() {
-				loggerInstance.Debug("🔍 No Harmony tokens detected in content")
+			if harmonyEnabled {
+				harmonyLogger.Debug("🔍 No Harmony tokens detected in content")
 			}
 			// Regular non-Harmony content
 			content = append(content, types.Content{
@@ -761,18 +925,11 @@ func TransformOpenAIToAnthropic(ctx context.Context, resp *types.OpenAIResponse,
 			toolCall.Function.Name, toolCall.ID, args)
 	}
 
+	// Response text continues the prefill rather than repeating it
+	content = trimEchoedPrefill(ctx, content, loggerInstance)
+
 	// Determine stop reason
-	stopReason := "end_turn"
-	if choice.FinishReason != nil {
-		switch *choice.FinishReason {
-		case "tool_calls":
-			stopReason = "tool_use"
-		case "stop":
-			stopReason = "end_turn"
-		case "length":
-			stopReason = "max_tokens"
-		}
-	}
+	stopReason := resolveStopReason(cfg, choice.FinishReason, content, loggerInstance)
 
 	// Create Anthropic response
 	anthropicResp := &types.AnthropicResponse{
@@ -2,8 +2,11 @@ package proxy
 
 import (
 	"claude-proxy/config"
+	"claude-proxy/logger"
 	"claude-proxy/types"
 	"context"
+	"encoding/json"
+	"fmt"
 	"strings"
 	"testing"
 )
@@ -249,8 +252,8 @@ func TestTransformOpenAIToAnthropic_HarmonyThinkingContent(t *testing.T) {
 	if thinkingBlock.Type != "thinking" {
 		t.Errorf("Expected first content block type to be 'thinking', got: %q", thinkingBlock.Type)
 	}
-	if thinkingBlock.Text != expectedThinking {
-		t.Errorf("Expected thinking content: %q, got: %q", expectedThinking, thinkingBlock.Text)
+	if thinkingBlock.Thinking != expectedThinking {
+		t.Errorf("Expected thinking content: %q, got: %q", expectedThinking, thinkingBlock.Thinking)
 	}
 	
 	// Second content block should be main response
@@ -269,3 +272,99 @@ func TestTransformOpenAIToAnthropic_HarmonyThinkingContent(t *testing.T) {
 		}
 	}
 }
+
+// agentConversation builds a Claude Code style request: a long system prompt,
+// tool definitions and turns of tool calls with their results. It is decoded
+// from JSON so content blocks have the shape the handler sees.
+func agentConversation(turns, tools int) types.AnthropicRequest {
+	definitions := make([]map[string]interface{}, tools)
+	for i := range definitions {
+		definitions[i] = map[string]interface{}{
+			"name":        fmt.Sprintf("Tool%d", i),
+			"description": strings.Repeat("Performs one step of the agent's work. ", 20),
+			"input_schema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"file_path": map[string]interface{}{"type": "string", "description": "Absolute path"},
+					"limit":     map[string]interface{}{"type": "integer"},
+				},
+				"required": []string{"file_path"},
+			},
+		}
+	}
+
+	messages := []map[string]interface{}{{"role": "user", "content": "Refactor the config loader and run the tests"}}
+	for i := 0; i < turns; i++ {
+		id := fmt.Sprintf("toolu_%d", i)
+		messages = append(messages,
+			map[string]interface{}{"role": "assistant", "content": []map[string]interface{}{
+				{"type": "text", "text": "Reading the next file."},
+				{"type": "tool_use", "id": id, "name": fmt.Sprintf("Tool%d", i%tools), "input": map[string]interface{}{"file_path": fmt.Sprintf("/src/file%d.go", i)}},
+			}},
+			map[string]interface{}{"role": "user", "content": []map[string]interface{}{
+				{"type": "tool_result", "tool_use_id": id, "content": strings.Repeat("package config // file contents\n", 40)},
+			}},
+		)
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":      "kimi-k2",
+		"max_tokens": 4096,
+		"system":     []map[string]interface{}{{"type": "text", "text": strings.Repeat("You are an interactive CLI tool. ", 300)}},
+		"messages":   messages,
+		"tools":      definitions,
+	})
+	var req types.AnthropicRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		panic(err)
+	}
+	return req
+}
+
+// BenchmarkTransformRoundTrip measures a request and its response through both
+// transforms as the handler runs them, without the correction model's
+// ExitPlanMode analysis
+func BenchmarkTransformRoundTrip(b *testing.B) {
+	// Request logging is not what is measured
+	defer logger.Levels().SetDefaultLevel(logger.Levels().DefaultLevel())
+	logger.Levels().SetDefaultLevel(logger.ERROR)
+
+	cfg := config.GetDefaultConfig()
+	finishReason := "tool_calls"
+	toolCalls := make([]types.OpenAIToolCall, 5)
+	for i := range toolCalls {
+		toolCalls[i] = types.OpenAIToolCall{
+			ID:   fmt.Sprintf("call_%d", i),
+			Type: "function",
+			Function: types.OpenAIToolCallFunction{
+				Name:      fmt.Sprintf("Tool%d", i),
+				Arguments: fmt.Sprintf(`{"file_path": "/src/file%d.go", "limit": 200}`, i),
+			},
+		}
+	}
+
+	for _, turns := range []int{10, 50, 200} {
+		req := agentConversation(turns, 20)
+		b.Run(fmt.Sprintf("%dTurns", turns), func(b *testing.B) {
+			ctx := withoutCorrection(withRequestID(context.Background(), "bench_round_trip"))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				openaiReq, err := TransformAnthropicToOpenAI(ctx, req, cfg)
+				if err != nil {
+					b.Fatal(err)
+				}
+				resp := &types.OpenAIResponse{
+					ID:    "bench",
+					Model: openaiReq.Model,
+					Choices: []types.OpenAIChoice{{
+						Message:      types.OpenAIMessage{Role: "assistant", Content: "Reading the remaining files.", ToolCalls: toolCalls},
+						FinishReason: &finishReason,
+					}},
+				}
+				if _, err := TransformOpenAIToAnthropic(ctx, resp, req.Model, cfg); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package proxy
+
+import (
+	"claude-proxy/correction"
+	"claude-proxy/logger"
+	"claude-proxy/types"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// upstreamSender sends a follow-up request to the endpoint that served the original one
+type upstreamSender func(ctx context.Context, req types.OpenAIRequest) (*types.OpenAIResponse, error)
+
+// validationFeedbackText describes a schema violation the way Claude Code
+// reports a tool input error, so the model treats it as a failed tool call
+func validationFeedbackText(call types.Content, validation correction.ValidationResult) string {
+	var issues []string
+	for _, param := range validation.MissingParams {
+		issues = append(issues, fmt.Sprintf("The required parameter `%s` is missing", param))
+	}
+	for _, param := range validation.InvalidParams {
+		issues = append(issues, fmt.Sprintf("An unexpected parameter `%s` was provided", param))
+	}
+	if len(issues) == 0 {
+		issues = append(issues, "The input does not match the tool's schema")
+	}
+	return fmt.Sprintf("<tool_use_error>InputValidationError: %s failed due to the following issues:\n%s</tool_use_error>",
+		call.Name, strings.Join(issues, "\n"))
+}
+
+// needsValidationFeedback reports whether a call is invalid in a way only the
+// model can fix; tool name case issues are left to the correction rules
+func needsValidationFeedback(validation correction.ValidationResult) bool {
+	return !validation.IsValid && !validation.HasCaseIssue && !validation.HasToolNameIssue
+}
+
+// applyValidationFeedback returns invalid tool calls to the model as failed
+// tool_results, up to VALIDATION_FEEDBACK_ROUNDS times, and replaces them with
+// the calls the model makes in reply. Valid calls and text are kept. Calls the
+// model does not fix are left in place for tool correction.
+func (h *Handler) applyValidationFeedback(ctx context.Context, openaiReq types.OpenAIRequest, resp *types.AnthropicResponse, availableTools []types.Tool,
+	originalModel string, send upstreamSender, loggerInstance logger.Logger) *types.AnthropicResponse {
+	trace := debugTraceFrom(ctx)
+	messages := append([]types.OpenAIMessage(nil), openaiReq.Messages...)
+
+	for round := 1; round <= h.config.ValidationFeedbackRounds; round++ {
+		var invalidCalls []types.OpenAIToolCall
+		var feedback []types.OpenAIMessage
+		for _, item := range resp.Content {
+			if item.Type != "tool_use" {
+				continue
+			}
+			validation := h.correctionService.ValidateToolCall(ctx, item, availableTools)
+			if !needsValidationFeedback(validation) {
+				continue
+			}
+			arguments, _ := json.Marshal(item.Input)
+			invalidCalls = append(invalidCalls, types.OpenAIToolCall{
+				ID:       item.ID,
+				Type:     "function",
+				Function: types.OpenAIToolCallFunction{Name: item.Name, Arguments: string(arguments)},
+			})
+			feedback = append(feedback, types.OpenAIMessage{
+				Role:       "tool",
+				ToolCallID: item.ID,
+				Content:    validationFeedbackText(item, validation),
+			})
+		}
+		if len(invalidCalls) == 0 {
+			return resp
+		}
+
+		loggerInstance.Info("🔁 Validation feedback round %d: returning %d invalid tool call(s) to the model", round, len(invalidCalls))
+		trace.Step("validation feedback round %d: %d invalid tool calls returned to the model", round, len(invalidCalls))
+
+		messages = append(messages, types.OpenAIMessage{Role: "assistant", ToolCalls: invalidCalls})
+		messages = append(messages, feedback...)
+		followUpReq := openaiReq
+		followUpReq.Messages = messages
+
+		followUp, err := send(ctx, followUpReq)
+		if err == nil {
+			followUp, err = applyChoicePolicy(followUp, h.config.MultiChoicePolicy, loggerInstance)
+		}
+		var followUpResp *types.AnthropicResponse
+		if err == nil {
+			followUpResp, err = TransformOpenAIToAnthropic(ctx, followUp, originalModel, h.config)
+		}
+		if err != nil {
+			loggerInstance.Warn("⚠️ Validation feedback request failed, falling back to tool correction: %v", err)
+			return resp
+		}
+		h.stats.RecordUsage(originalModel, followUpResp.Usage.InputTokens, followUpResp.Usage.OutputTokens,
+			h.config.EstimateCost(followUpReq.Model, followUpResp.Usage.InputTokens, followUpResp.Usage.OutputTokens))
+		resp.Usage.InputTokens += followUpResp.Usage.InputTokens
+		resp.Usage.OutputTokens += followUpResp.Usage.OutputTokens
+
+		if !HasToolCalls(followUpResp.Content) {
+			loggerInstance.Warn("⚠️ Model answered validation feedback without tool calls, falling back to tool correction")
+			return resp
+		}
+		resp.Content = replaceToolCalls(resp.Content, invalidCalls, followUpResp.Content)
+	}
+	return resp
+}
+
+// replaceToolCalls swaps the replaced tool calls in content for the tool
+// calls of replacement, keeping everything else in order
+func replaceToolCalls(content []types.Content, replaced []types.OpenAIToolCall, replacement []types.Content) []types.Content {
+	replacedIDs := make(map[string]bool, len(replaced))
+	for _, call := range replaced {
+		replacedIDs[call.ID] = true
+	}
+	result := make([]types.Content, 0, len(content)+len(replacement))
+	inserted := false
+	for _, item := range content {
+		if item.Type == "tool_use" && replacedIDs[item.ID] {
+			if !inserted {
+				for _, retry := range replacement {
+					if retry.Type == "tool_use" {
+						result = append(result, retry)
+					}
+				}
+				inserted = true
+			}
+			continue
+		}
+		result = append(result, item)
+	}
+	return result
+}
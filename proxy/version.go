@@ -0,0 +1,75 @@
+package proxy
+
+import (
+	"claude-proxy/internal"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Known anthropic-version values. Versions are dates, so they compare lexically.
+const (
+	AnthropicVersion20230101 = "2023-01-01"
+	AnthropicVersion20230601 = "2023-06-01"
+
+	// DefaultAnthropicVersion is assumed when a client omits the header
+	DefaultAnthropicVersion = AnthropicVersion20230601
+)
+
+// knownAnthropicVersions lists the versions the proxy has explicit behavior for
+var knownAnthropicVersions = map[string]bool{
+	AnthropicVersion20230101: true,
+	AnthropicVersion20230601: true,
+}
+
+// Version-gated behaviors. Each maps to the first anthropic-version that has it,
+// so transformer stages can switch with versionSupports(ctx, feature).
+const (
+	// FeatureNamedSSEEvents emits "event: <type>" lines before each SSE data line
+	FeatureNamedSSEEvents = "named_sse_events"
+)
+
+var anthropicVersionFeatures = map[string]string{
+	FeatureNamedSSEEvents: AnthropicVersion20230601,
+}
+
+// anthropicVersionFromHeader validates the anthropic-version header. A missing
+// header falls back to DefaultAnthropicVersion; a value that is not a date is
+// rejected. Well-formed but unknown versions are accepted and reported via the
+// known flag so the caller can log them.
+func anthropicVersionFromHeader(header http.Header) (version string, known bool, err error) {
+	version = strings.TrimSpace(header.Get(internal.AnthropicVersionHeader))
+	if version == "" {
+		return DefaultAnthropicVersion, true, nil
+	}
+	if _, parseErr := time.Parse("2006-01-02", version); parseErr != nil {
+		return "", false, fmt.Errorf("invalid %s header %q: expected a date such as %s",
+			internal.AnthropicVersionHeader, truncateForError(version), DefaultAnthropicVersion)
+	}
+	return version, knownAnthropicVersions[version], nil
+}
+
+// versionSupports reports whether the request's anthropic-version includes a
+// version-gated feature. Requests without a recorded version use the default.
+func versionSupports(ctx context.Context, feature string) bool {
+	since, exists := anthropicVersionFeatures[feature]
+	if !exists {
+		return false
+	}
+	version := internal.GetAnthropicVersion(ctx)
+	if version == "" {
+		version = DefaultAnthropicVersion
+	}
+	return version >= since
+}
+
+// truncateForError keeps untrusted header values short in error messages
+func truncateForError(value string) string {
+	const maxLen = 32
+	if len(value) > maxLen {
+		return value[:maxLen] + "..."
+	}
+	return value
+}
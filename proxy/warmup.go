@@ -0,0 +1,143 @@
+package proxy
+
+import (
+	"bytes"
+	"claude-proxy/config"
+	"claude-proxy/logger"
+	"claude-proxy/types"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// warmupTarget is one model to load on one endpoint
+type warmupTarget struct {
+	endpoint string
+	model    string
+	apiKey   string
+}
+
+// WarmupResult reports how loading a model on an endpoint went
+type WarmupResult struct {
+	Endpoint string
+	Model    string
+	Duration time.Duration
+	Err      error
+}
+
+// warmupTargets lists every endpoint/model pair currently configured,
+// optionally restricted to a single endpoint
+func (h *Handler) warmupTargets(only string) []warmupTarget {
+	models := map[string][2]string{
+		config.EndpointRoleBig:            {h.config.BigModel, h.config.BigModelAPIKey},
+		config.EndpointRoleSmall:          {h.config.SmallModel, h.config.SmallModelAPIKey},
+		config.EndpointRoleToolCorrection: {h.config.CorrectionModel, h.config.ToolCorrectionAPIKey},
+	}
+
+	var targets []warmupTarget
+	seen := make(map[warmupTarget]bool)
+	for _, role := range config.EndpointRoles {
+		model := models[role]
+		if model[0] == "" {
+			continue
+		}
+		for _, endpoint := range h.config.Endpoints(role) {
+			target := warmupTarget{endpoint: endpoint, model: model[0], apiKey: model[1]}
+			if (only != "" && endpoint != only) || seen[target] {
+				continue
+			}
+			seen[target] = true
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// WarmUp sends a one-token generation to every configured endpoint in parallel
+// so backends that load models lazily (Ollama, llama.cpp) pay the cold start
+// before the first real request does. Results do not feed the circuit breaker.
+func (h *Handler) WarmUp(ctx context.Context) []WarmupResult {
+	return h.warmUp(ctx, h.warmupTargets(""))
+}
+
+// WarmUpOnRecovery returns a circuit state listener that reloads the models of
+// an endpoint whose circuit has just closed, since a backend that was down has
+// usually been restarted with nothing in memory
+func (h *Handler) WarmUpOnRecovery() func(endpoint string, open bool) {
+	return func(endpoint string, open bool) {
+		if open {
+			return
+		}
+		// Listeners must not block the circuit breaker
+		go h.warmUp(context.Background(), h.warmupTargets(endpoint))
+	}
+}
+
+// warmUp loads each target and logs the outcome
+func (h *Handler) warmUp(ctx context.Context, targets []warmupTarget) []WarmupResult {
+	results := make([]WarmupResult, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target warmupTarget) {
+			defer wg.Done()
+			start := time.Now()
+			err := h.warmUpEndpoint(ctx, target)
+			results[i] = WarmupResult{Endpoint: target.endpoint, Model: target.model, Duration: time.Since(start), Err: err}
+		}(i, target)
+	}
+	wg.Wait()
+
+	if h.obsLogger != nil {
+		for _, result := range results {
+			fields := map[string]interface{}{
+				"endpoint":    result.Endpoint,
+				"model":       result.Model,
+				"duration_ms": result.Duration.Milliseconds(),
+			}
+			if result.Err != nil {
+				fields["error"] = result.Err.Error()
+				h.obsLogger.Warn(logger.ComponentProxy, logger.CategoryWarning, "", "Endpoint warm-up failed", fields)
+				continue
+			}
+			h.obsLogger.Info(logger.ComponentProxy, logger.CategoryRequest, "", "Endpoint warmed up", fields)
+		}
+	}
+	return results
+}
+
+// warmUpEndpoint sends the smallest possible generation to one endpoint
+func (h *Handler) warmUpEndpoint(ctx context.Context, target warmupTarget) error {
+	reqBody, err := json.Marshal(types.OpenAIRequest{
+		Model:     target.model,
+		Messages:  []types.OpenAIMessage{{Role: "user", Content: "Hi"}},
+		MaxTokens: 1,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal warm-up request: %v", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", target.endpoint, bytes.NewReader(reqBody))
+	if err != nil {
+		return fmt.Errorf("failed to create warm-up request: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+target.apiKey)
+
+	// Loading a large model can take minutes, so use the endpoint's full request timeout
+	resp, err := h.config.HTTPClient(target.endpoint, h.getRequestTimeout(target.endpoint)).Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
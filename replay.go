@@ -0,0 +1,90 @@
+package main
+
+import (
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// replayExport is a ConversationExport in the anthropic format, decoded with
+// typed requests and responses
+type replayExport struct {
+	Format    string `json:"format"`
+	Exchanges []struct {
+		RequestID string                   `json:"request_id"`
+		Request   types.AnthropicRequest   `json:"request"`
+		Response  *types.AnthropicResponse `json:"response"`
+	} `json:"exchanges"`
+}
+
+// runReplay implements "simple-proxy replay [-url URL] [-api-key KEY] EXPORT_FILE":
+// it sends every request of a conversation exported with
+// GET /admin/conversations/export?format=anthropic to a running proxy and
+// compares stop reasons and tool calls with the recorded responses. Returns 1
+// when a request fails.
+func runReplay(args []string) int {
+	flags := newFlagSet("replay", "replay [-url URL] [-api-key KEY] [-timeout D] EXPORT_FILE")
+	url := flags.String("url", defaultProxyURL, "Messages endpoint of the proxy to replay against")
+	apiKey := flags.String("api-key", "", "Sent as x-api-key")
+	timeout := flags.Duration("timeout", 10*time.Minute, "Time allowed for each request")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	data, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read export: %v\n", err)
+		return 1
+	}
+	var export replayExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to parse export: %v\n", err)
+		return 1
+	}
+	if export.Format != proxy.ExportFormatAnthropic {
+		fmt.Fprintf(os.Stderr, "Export format is %q, replay needs format=%s\n", export.Format, proxy.ExportFormatAnthropic)
+		return 1
+	}
+
+	client := &http.Client{Timeout: *timeout}
+	failed, differed := 0, 0
+	for i, exchange := range export.Exchanges {
+		result := postMessage(client, *url, *apiKey, exchange.Request)
+		prefix := fmt.Sprintf("[%d/%d] %s", i+1, len(export.Exchanges), exchange.RequestID)
+		if result.Response == nil {
+			failed++
+			fmt.Printf("❌ %s: HTTP %d after %s: %s\n", prefix, result.Status, result.Latency.Round(time.Millisecond), result.Error)
+			continue
+		}
+
+		tools := toolCallNames(result.Response)
+		line := fmt.Sprintf("%s: %s, stop_reason=%s, tool_calls=[%s]", prefix, result.Latency.Round(time.Millisecond),
+			result.Response.StopReason, strings.Join(tools, ","))
+		if exchange.Response == nil {
+			fmt.Printf("✅ %s\n", line)
+			continue
+		}
+		recordedTools := toolCallNames(exchange.Response)
+		if exchange.Response.StopReason != result.Response.StopReason || strings.Join(recordedTools, ",") != strings.Join(tools, ",") {
+			differed++
+			fmt.Printf("⚠️  %s (recorded stop_reason=%s, tool_calls=[%s])\n", line, exchange.Response.StopReason, strings.Join(recordedTools, ","))
+			continue
+		}
+		fmt.Printf("✅ %s (matches recording)\n", line)
+	}
+
+	fmt.Printf("Replayed %d requests: %d failed, %d differ from the recording\n", len(export.Exchanges), failed, differed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"claude-proxy/discovery"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"time"
+)
+
+// selftestModels are the client model names that map to each model role
+var selftestModels = []struct {
+	role  string
+	model string
+}{
+	{"BIG_MODEL", "claude-sonnet-4-20250514"},
+	{"SMALL_MODEL", "claude-3-5-haiku-20241022"},
+}
+
+// runSelftest implements "simple-proxy selftest [-config-dir DIR] [-timeout D]":
+// it sends a short request for each model role through the same handler serve
+// uses, without opening a listener, and fails if any of them does
+func runSelftest(args []string) int {
+	flags := newFlagSet("selftest", "selftest [-config-dir DIR] [-timeout D] [-prompt TEXT]")
+	configDir := configDirFlag(flags)
+	timeout := flags.Duration("timeout", 2*time.Minute, "Time allowed for each request")
+	prompt := flags.String("prompt", "Reply with the single word OK.", "Prompt sent to each model")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := loadConfig(*configDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration: %v\n", err)
+		return 1
+	}
+	if len(cfg.EndpointSpecs) > 0 {
+		resolver := discovery.NewResolver(cfg, nil)
+		if cfg.UsesKubernetesDiscovery() {
+			kube, err := discovery.NewKubernetesClient(cfg.KubernetesAPIURL)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to set up Kubernetes discovery: %v\n", err)
+				return 1
+			}
+			resolver.SetKubernetesClient(kube)
+		}
+		resolveCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		_, err := resolver.Refresh(resolveCtx)
+		cancel()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to resolve endpoints: %v\n", err)
+			return 1
+		}
+	}
+	handler := proxy.NewHandler(cfg, nil, "")
+
+	failed := 0
+	for _, target := range selftestModels {
+		body, _ := json.Marshal(types.AnthropicRequest{
+			Model:     target.model,
+			MaxTokens: 32,
+			Messages:  []types.Message{{Role: "user", Content: *prompt}},
+		})
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		req := httptest.NewRequest(http.MethodPost, "/v1/messages", bytes.NewReader(body)).WithContext(ctx)
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		start := time.Now()
+		handler.HandleAnthropicRequest(rec, req)
+		latency := time.Since(start).Round(time.Millisecond)
+		cancel()
+
+		mapped := cfg.MapModelName(context.Background(), target.model)
+		if rec.Code != http.StatusOK {
+			failed++
+			fmt.Printf("❌ %s (%s): HTTP %d after %s: %s\n", target.role, mapped, rec.Code, latency, strings.TrimSpace(rec.Body.String()))
+			continue
+		}
+		var resp types.AnthropicResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			failed++
+			fmt.Printf("❌ %s (%s): invalid response after %s: %v\n", target.role, mapped, latency, err)
+			continue
+		}
+		fmt.Printf("✅ %s (%s): %s, stop_reason=%s, %d output tokens\n", target.role, mapped, latency, resp.StopReason, resp.Usage.OutputTokens)
+	}
+
+	if failed > 0 {
+		fmt.Printf("%d of %d model roles failed\n", failed, len(selftestModels))
+		return 1
+	}
+	return 0
+}
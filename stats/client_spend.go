@@ -0,0 +1,48 @@
+package stats
+
+import "time"
+
+// clientSpendDayFormat keys client spend by UTC day
+const clientSpendDayFormat = "2006-01-02"
+
+// RecordClientSpend adds the estimated cost of a response to a client key's
+// spend on the UTC day of at. Days before the previous calendar month are
+// no longer needed by any limit and are dropped.
+func (c *Collector) RecordClientSpend(client string, costUSD float64, at time.Time) {
+	at = at.UTC()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	days := c.totals.ClientSpend[client]
+	if days == nil {
+		days = make(map[string]float64)
+		c.totals.ClientSpend[client] = days
+	}
+	days[at.Format(clientSpendDayFormat)] += costUSD
+
+	oldest := time.Date(at.Year(), at.Month()-1, 1, 0, 0, 0, 0, time.UTC).Format(clientSpendDayFormat)
+	for day := range days {
+		if day < oldest {
+			delete(days, day)
+		}
+	}
+}
+
+// ClientSpend returns a client key's spend on the UTC day and calendar month of at
+func (c *Collector) ClientSpend(client string, at time.Time) (dayUSD, monthUSD float64) {
+	at = at.UTC()
+	today := at.Format(clientSpendDayFormat)
+	month := today[:len("2006-01")]
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for day, cost := range c.totals.ClientSpend[client] {
+		if day == today {
+			dayUSD += cost
+		}
+		if day[:len(month)] == month {
+			monthUSD += cost
+		}
+	}
+	return dayUSD, monthUSD
+}
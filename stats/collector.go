@@ -0,0 +1,364 @@
+package stats
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencySampleSize bounds the per-model latency reservoir used for percentiles
+const latencySampleSize = 1000
+
+// Correction outcomes recorded by RecordCorrection
+const (
+	CorrectionApplied   = "applied"   // Correction ran and changed at least one tool call
+	CorrectionUnchanged = "unchanged" // Correction ran but nothing needed changing
+	CorrectionFailed    = "failed"    // Correction returned an error; original content was used
+
+	CorrectionBudgetExceeded = "budget_exceeded" // CORRECTION_BUDGET_MS ran out; remaining calls were forwarded or blocked
+)
+
+// Collector aggregates in-process request statistics. It is a lightweight
+// alternative to Prometheus for operators who only want a JSON summary.
+type Collector struct {
+	mu                sync.Mutex
+	startedAt         time.Time
+	models            map[string]*modelStats
+	corrections       map[string]int64
+	harmonyDetections int64
+	panics            int64
+	totals            Totals  // Cumulative counters, persisted across restarts when a Store is attached
+	emitter           Emitter // Optional external metrics sink (e.g. StatsD)
+	throughput        throughputWindow
+	recentCorrections eventRing
+	recentErrors      eventRing
+}
+
+// modelStats tracks counts and a ring buffer of recent latencies for one model
+type modelStats struct {
+	requests     int64
+	errors       int64
+	inputTokens  int64
+	outputTokens int64
+	costUSD      float64
+	totalLatency time.Duration
+	samples      []time.Duration
+	next         int
+}
+
+// ModelSnapshot is the JSON view of a single model's statistics
+type ModelSnapshot struct {
+	Requests     int64   `json:"requests"`
+	Errors       int64   `json:"errors"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+	P50LatencyMs float64 `json:"p50_latency_ms"`
+	P95LatencyMs float64 `json:"p95_latency_ms"`
+	P99LatencyMs float64 `json:"p99_latency_ms"`
+}
+
+// ModelTotals are the cumulative counters kept for one model
+type ModelTotals struct {
+	Requests     int64   `json:"requests"`
+	Errors       int64   `json:"errors"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+}
+
+// Totals are cumulative statistics since the store was created (or since
+// process start when persistence is disabled)
+type Totals struct {
+	Models            map[string]ModelTotals        `json:"models"`
+	Corrections       map[string]int64              `json:"corrections"`
+	HarmonyDetections int64                         `json:"harmony_detections"`
+	ClientSpend       map[string]map[string]float64 `json:"client_spend"` // USD per client key ID and UTC day, kept by ResetTotals
+	Since             time.Time                     `json:"since"`        // Start of the counted period, moved by ResetTotals
+	UpdatedAt         time.Time                     `json:"updated_at"`
+}
+
+// Snapshot is a point-in-time copy of all collected statistics
+type Snapshot struct {
+	StartedAt         time.Time                `json:"started_at"`
+	UptimeSeconds     int64                    `json:"uptime_seconds"`
+	TotalRequests     int64                    `json:"total_requests"`
+	TotalErrors       int64                    `json:"total_errors"`
+	Models            map[string]ModelSnapshot `json:"models"`
+	Corrections       map[string]int64         `json:"corrections"`
+	HarmonyDetections int64                    `json:"harmony_detections"`
+	Panics            int64                    `json:"panics"` // Handler panics recovered since process start
+	Cumulative        Totals                   `json:"cumulative"`
+	RequestsPerMinute []int64                  `json:"requests_per_minute"` // Last hour, oldest first
+	RecentCorrections []Event                  `json:"recent_corrections"`  // Newest first
+	RecentErrors      []Event                  `json:"recent_errors"`       // Newest first
+}
+
+// NewCollector creates an empty collector
+func NewCollector() *Collector {
+	c := &Collector{
+		startedAt:   time.Now(),
+		models:      make(map[string]*modelStats),
+		corrections: make(map[string]int64),
+		totals:      newTotals(),
+	}
+	c.totals.Since = c.startedAt
+	return c
+}
+
+// newTotals creates empty cumulative totals
+func newTotals() Totals {
+	return Totals{
+		Models:      make(map[string]ModelTotals),
+		Corrections: make(map[string]int64),
+		ClientSpend: make(map[string]map[string]float64),
+	}
+}
+
+// modelLocked returns the session stats for a model, creating them if needed.
+// Caller must hold mu.
+func (c *Collector) modelLocked(model string) *modelStats {
+	ms, exists := c.models[model]
+	if !exists {
+		ms = &modelStats{}
+		c.models[model] = ms
+	}
+	return ms
+}
+
+// SetEmitter forwards every recorded metric to an external emitter (nil disables)
+func (c *Collector) SetEmitter(emitter Emitter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.emitter = emitter
+}
+
+// RecordRequest records a completed request for a model
+func (c *Collector) RecordRequest(model string, latency time.Duration, failed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.emitter != nil {
+		c.emitter.RequestCompleted(model, latency, failed)
+	}
+
+	c.throughput.add(time.Now())
+
+	ms := c.modelLocked(model)
+	ms.requests++
+	if failed {
+		ms.errors++
+	}
+	ms.totalLatency += latency
+
+	totals := c.totals.Models[model]
+	totals.Requests++
+	if failed {
+		totals.Errors++
+	}
+	c.totals.Models[model] = totals
+
+	if len(ms.samples) < latencySampleSize {
+		ms.samples = append(ms.samples, latency)
+	} else {
+		ms.samples[ms.next] = latency
+		ms.next = (ms.next + 1) % latencySampleSize
+	}
+}
+
+// RecordUsage records token usage and its estimated cost for a model
+func (c *Collector) RecordUsage(model string, inputTokens, outputTokens int, costUSD float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.emitter != nil {
+		c.emitter.TokensUsed(model, inputTokens, outputTokens)
+	}
+
+	ms := c.modelLocked(model)
+	ms.inputTokens += int64(inputTokens)
+	ms.outputTokens += int64(outputTokens)
+	ms.costUSD += costUSD
+
+	totals := c.totals.Models[model]
+	totals.InputTokens += int64(inputTokens)
+	totals.OutputTokens += int64(outputTokens)
+	totals.CostUSD += costUSD
+	c.totals.Models[model] = totals
+}
+
+// RecordCorrection records the outcome of a tool correction pass
+func (c *Collector) RecordCorrection(outcome string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.emitter != nil {
+		c.emitter.CorrectionCompleted(outcome)
+	}
+	c.corrections[outcome]++
+	c.totals.Corrections[outcome]++
+}
+
+// RecordHarmonyDetection records a response that was parsed as Harmony format
+func (c *Collector) RecordHarmonyDetection() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.emitter != nil {
+		c.emitter.HarmonyDetected()
+	}
+	c.harmonyDetections++
+	c.totals.HarmonyDetections++
+}
+
+// RecordPanic records a handler panic recovered by the proxy
+func (c *Collector) RecordPanic() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.emitter != nil {
+		c.emitter.PanicRecovered()
+	}
+	c.panics++
+}
+
+// LoadTotals seeds the cumulative counters, typically from a Store at startup.
+// Anything recorded before the call is added on top of the loaded totals.
+func (c *Collector) LoadTotals(loaded Totals) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	merged := copyTotals(loaded)
+	for model, t := range c.totals.Models {
+		m := merged.Models[model]
+		m.Requests += t.Requests
+		m.Errors += t.Errors
+		m.InputTokens += t.InputTokens
+		m.OutputTokens += t.OutputTokens
+		m.CostUSD += t.CostUSD
+		merged.Models[model] = m
+	}
+	for outcome, count := range c.totals.Corrections {
+		merged.Corrections[outcome] += count
+	}
+	merged.HarmonyDetections += c.totals.HarmonyDetections
+	for client, days := range c.totals.ClientSpend {
+		if merged.ClientSpend[client] == nil {
+			merged.ClientSpend[client] = make(map[string]float64)
+		}
+		for day, cost := range days {
+			merged.ClientSpend[client][day] += cost
+		}
+	}
+	if merged.Since.IsZero() {
+		// Stores written before Since was recorded count from this start
+		merged.Since = c.totals.Since
+	}
+	c.totals = merged
+}
+
+// ResetTotals clears the cumulative counters and starts a new counted period.
+// Session statistics (latency percentiles, per-session counts) and client
+// spend, which spend limits are enforced against, are kept.
+func (c *Collector) ResetTotals() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clientSpend := c.totals.ClientSpend
+	c.totals = newTotals()
+	c.totals.ClientSpend = clientSpend
+	c.totals.Since = time.Now()
+}
+
+// Totals returns a copy of the cumulative counters
+func (c *Collector) Totals() Totals {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return copyTotals(c.totals)
+}
+
+// copyTotals deep-copies totals, tolerating nil maps from older stores
+func copyTotals(t Totals) Totals {
+	copied := newTotals()
+	for model, m := range t.Models {
+		copied.Models[model] = m
+	}
+	for outcome, count := range t.Corrections {
+		copied.Corrections[outcome] = count
+	}
+	copied.HarmonyDetections = t.HarmonyDetections
+	for client, days := range t.ClientSpend {
+		copied.ClientSpend[client] = make(map[string]float64, len(days))
+		for day, cost := range days {
+			copied.ClientSpend[client][day] = cost
+		}
+	}
+	copied.Since = t.Since
+	copied.UpdatedAt = t.UpdatedAt
+	return copied
+}
+
+// Snapshot returns a copy of the current statistics
+func (c *Collector) Snapshot() Snapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snapshot := Snapshot{
+		StartedAt:         c.startedAt,
+		UptimeSeconds:     int64(time.Since(c.startedAt).Seconds()),
+		Models:            make(map[string]ModelSnapshot, len(c.models)),
+		Corrections:       make(map[string]int64, len(c.corrections)),
+		HarmonyDetections: c.harmonyDetections,
+		Panics:            c.panics,
+		Cumulative:        copyTotals(c.totals),
+		RequestsPerMinute: c.throughput.perMinute(time.Now()),
+		RecentCorrections: c.recentCorrections.newestFirst(),
+		RecentErrors:      c.recentErrors.newestFirst(),
+	}
+
+	for model, ms := range c.models {
+		snapshot.TotalRequests += ms.requests
+		snapshot.TotalErrors += ms.errors
+
+		sorted := append([]time.Duration(nil), ms.samples...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+		modelSnapshot := ModelSnapshot{
+			Requests:     ms.requests,
+			Errors:       ms.errors,
+			InputTokens:  ms.inputTokens,
+			OutputTokens: ms.outputTokens,
+			CostUSD:      ms.costUSD,
+			P50LatencyMs: percentileMs(sorted, 0.50),
+			P95LatencyMs: percentileMs(sorted, 0.95),
+			P99LatencyMs: percentileMs(sorted, 0.99),
+		}
+		if ms.requests > 0 {
+			modelSnapshot.AvgLatencyMs = durationMs(ms.totalLatency) / float64(ms.requests)
+		}
+		snapshot.Models[model] = modelSnapshot
+	}
+
+	for outcome, count := range c.corrections {
+		snapshot.Corrections[outcome] = count
+	}
+
+	return snapshot
+}
+
+// percentileMs returns the nearest-rank percentile of sorted latencies in milliseconds
+func percentileMs(sorted []time.Duration, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(float64(len(sorted))*p+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return durationMs(sorted[rank])
+}
+
+// durationMs converts a duration to fractional milliseconds
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,96 @@
+package stats
+
+import "time"
+
+// recentEventLimit bounds the recent corrections and errors kept for the dashboard
+const recentEventLimit = 50
+
+// throughputMinutes is how many one-minute request buckets are kept
+const throughputMinutes = 60
+
+// Event is a recent correction or error shown on the admin dashboard
+type Event struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id,omitempty"`
+	Model     string    `json:"model,omitempty"`
+	Kind      string    `json:"kind"` // Correction outcome or proxy error code
+	Status    int       `json:"status,omitempty"`
+	Detail    string    `json:"detail,omitempty"`
+}
+
+// eventRing keeps the newest recentEventLimit events
+type eventRing struct {
+	events []Event
+	next   int
+}
+
+// add stores an event, overwriting the oldest once full
+func (r *eventRing) add(event Event) {
+	if len(r.events) < recentEventLimit {
+		r.events = append(r.events, event)
+		return
+	}
+	r.events[r.next] = event
+	r.next = (r.next + 1) % recentEventLimit
+}
+
+// newestFirst returns a copy of the events, most recent first
+func (r *eventRing) newestFirst() []Event {
+	events := make([]Event, 0, len(r.events))
+	for i := len(r.events) - 1; i >= 0; i-- {
+		events = append(events, r.events[(r.next+i)%len(r.events)])
+	}
+	return events
+}
+
+// throughputWindow counts requests per wall-clock minute for the last hour
+type throughputWindow struct {
+	counts  [throughputMinutes]int64
+	minutes [throughputMinutes]int64 // Unix minute each bucket counts, stale buckets are reused
+}
+
+// add counts one request at now
+func (w *throughputWindow) add(now time.Time) {
+	minute := now.Unix() / 60
+	i := minute % throughputMinutes
+	if w.minutes[i] != minute {
+		w.minutes[i] = minute
+		w.counts[i] = 0
+	}
+	w.counts[i]++
+}
+
+// perMinute returns requests per minute for the last hour, oldest first and
+// ending with the current, partial minute
+func (w *throughputWindow) perMinute(now time.Time) []int64 {
+	current := now.Unix() / 60
+	counts := make([]int64, throughputMinutes)
+	for k := range counts {
+		minute := current - int64(throughputMinutes-1-k)
+		if i := minute % throughputMinutes; w.minutes[i] == minute {
+			counts[k] = w.counts[i]
+		}
+	}
+	return counts
+}
+
+// RecordCorrectionEvent keeps a correction for the dashboard's recent list.
+// The outcome counters are kept by RecordCorrection.
+func (c *Collector) RecordCorrectionEvent(event Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	c.recentCorrections.add(event)
+}
+
+// RecordErrorEvent keeps a failed request for the dashboard's recent list
+func (c *Collector) RecordErrorEvent(event Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	c.recentErrors.add(event)
+}
@@ -0,0 +1,105 @@
+package stats
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// Emitter receives the same metric set the Collector aggregates so it can be
+// forwarded to an external metrics system
+type Emitter interface {
+	RequestCompleted(model string, latency time.Duration, failed bool)
+	TokensUsed(model string, inputTokens, outputTokens int)
+	CorrectionCompleted(outcome string)
+	HarmonyDetected()
+	PanicRecovered()
+	CircuitStateChanged(endpoint string, open bool)
+}
+
+// StatsDEmitter sends metrics over UDP in StatsD format with Datadog-style
+// tags (|#key:value), which plain StatsD servers ignore
+type StatsDEmitter struct {
+	conn   net.Conn
+	prefix string
+}
+
+// NewStatsDEmitter creates an emitter sending to addr (host:port). UDP is
+// connectionless, so an unreachable agent never blocks or fails requests.
+func NewStatsDEmitter(addr, prefix string) (*StatsDEmitter, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve StatsD address %s: %v", addr, err)
+	}
+	if prefix != "" && !strings.HasSuffix(prefix, ".") {
+		prefix += "."
+	}
+	return &StatsDEmitter{conn: conn, prefix: prefix}, nil
+}
+
+// Close releases the UDP socket
+func (e *StatsDEmitter) Close() error {
+	return e.conn.Close()
+}
+
+// RequestCompleted emits request count, error count and latency timing
+func (e *StatsDEmitter) RequestCompleted(model string, latency time.Duration, failed bool) {
+	tags := []string{"model:" + model}
+	e.send("request.count", "1", "c", tags)
+	if failed {
+		e.send("request.errors", "1", "c", tags)
+	}
+	e.send("request.latency", fmt.Sprintf("%d", latency.Milliseconds()), "ms", tags)
+}
+
+// TokensUsed emits input and output token counters
+func (e *StatsDEmitter) TokensUsed(model string, inputTokens, outputTokens int) {
+	tags := []string{"model:" + model}
+	e.send("tokens.input", fmt.Sprintf("%d", inputTokens), "c", tags)
+	e.send("tokens.output", fmt.Sprintf("%d", outputTokens), "c", tags)
+}
+
+// CorrectionCompleted emits a correction counter tagged with its outcome
+func (e *StatsDEmitter) CorrectionCompleted(outcome string) {
+	e.send("corrections", "1", "c", []string{"outcome:" + outcome})
+}
+
+// HarmonyDetected emits a Harmony detection counter
+func (e *StatsDEmitter) HarmonyDetected() {
+	e.send("harmony.detections", "1", "c", nil)
+}
+
+// PanicRecovered emits a recovered handler panic counter
+func (e *StatsDEmitter) PanicRecovered() {
+	e.send("panics", "1", "c", nil)
+}
+
+// CircuitStateChanged emits a transition counter and the current state gauge (1 = open)
+func (e *StatsDEmitter) CircuitStateChanged(endpoint string, open bool) {
+	state, gauge := "closed", "0"
+	if open {
+		state, gauge = "open", "1"
+	}
+	tags := []string{"endpoint:" + endpoint}
+	e.send("circuit.transitions", "1", "c", append(tags, "state:"+state))
+	e.send("circuit.open", gauge, "g", tags)
+}
+
+// send writes a single metric line; errors are ignored as StatsD is best-effort
+func (e *StatsDEmitter) send(name, value, metricType string, tags []string) {
+	line := e.prefix + name + ":" + value + "|" + metricType
+	if len(tags) > 0 {
+		sanitized := make([]string, len(tags))
+		for i, tag := range tags {
+			sanitized[i] = sanitizeTag(tag)
+		}
+		line += "|#" + strings.Join(sanitized, ",")
+	}
+	e.conn.Write([]byte(line))
+}
+
+// sanitizeTag replaces characters that would break the StatsD line protocol
+func sanitizeTag(tag string) string {
+	return strings.NewReplacer("|", "_", ",", "_", "#", "_", "\n", "_").Replace(tag)
+}
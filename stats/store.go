@@ -0,0 +1,112 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	statsBucket = []byte("stats")
+	totalsKey   = []byte("totals")
+)
+
+// Store persists cumulative statistics in an embedded bbolt database so usage,
+// cost and correction counters survive restarts
+type Store struct {
+	db *bolt.DB
+}
+
+// OpenStore opens (or creates) the statistics database at path
+func OpenStore(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 2 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stats store %s: %v", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(statsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize stats store: %v", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Load reads the persisted totals; an empty store yields empty totals
+func (s *Store) Load() (Totals, error) {
+	totals := newTotals()
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(statsBucket).Get(totalsKey)
+		if data == nil {
+			return nil
+		}
+		return json.Unmarshal(data, &totals)
+	})
+	if err != nil {
+		return newTotals(), fmt.Errorf("failed to load stats: %v", err)
+	}
+	return copyTotals(totals), nil
+}
+
+// Save overwrites the persisted totals
+func (s *Store) Save(totals Totals) error {
+	totals.UpdatedAt = time.Now()
+	data, err := json.Marshal(totals)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stats: %v", err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(statsBucket).Put(totalsKey, data)
+	})
+}
+
+// Close closes the underlying database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// StartPersistence loads the stored totals into the collector and saves them
+// back every interval. The returned function stops the loop, performs a final
+// save and closes the store.
+func (c *Collector) StartPersistence(store *Store, interval time.Duration, onError func(error)) (func() error, error) {
+	loaded, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	c.LoadTotals(loaded)
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := store.Save(c.Totals()); err != nil && onError != nil {
+					onError(err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return func() error {
+		close(stop)
+		<-done
+		saveErr := store.Save(c.Totals())
+		closeErr := store.Close()
+		if saveErr != nil {
+			return saveErr
+		}
+		return closeErr
+	}, nil
+}
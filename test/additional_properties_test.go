@@ -0,0 +1,117 @@
+package test
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/correction"
+	"claude-proxy/internal"
+	"claude-proxy/types"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// additionalPropertiesSchema has one top-level parameter and an array of
+// objects with one field
+var additionalPropertiesSchema = types.ToolSchema{
+	Type: "object",
+	Properties: map[string]types.ToolProperty{
+		"query": {Type: "string"},
+		"filters": {Type: "array", Items: &types.ToolPropertyItems{
+			Type:       "object",
+			Properties: map[string]types.ToolProperty{"field": {Type: "string"}},
+		}},
+	},
+	Required: []string{"query"},
+}
+
+// additionalPropertiesCall has an undefined parameter at both levels
+func additionalPropertiesCall(name string) types.Content {
+	return types.Content{Type: "tool_use", ID: "toolu_extra", Name: name, Input: map[string]interface{}{
+		"query":   "open issues",
+		"verbose": true,
+		"filters": []interface{}{map[string]interface{}{"field": "state", "note": "extra"}},
+	}}
+}
+
+// TestAdditionalPropertiesPolicies tests that the validator applies the
+// global and per-tool policy to top-level and nested undefined parameters
+func TestAdditionalPropertiesPolicies(t *testing.T) {
+	validator := types.NewStandardToolValidator()
+	require.NoError(t, validator.SetAdditionalPropertiesPolicy(types.AdditionalPropertiesPolicy{
+		Default: types.AdditionalPropertiesError,
+		Tools:   map[string]string{"Lenient": types.AdditionalPropertiesAllow, "Tidy": types.AdditionalPropertiesStrip},
+	}))
+
+	strict := additionalPropertiesCall("Strict")
+	assert.Equal(t, []string{"verbose"}, validator.ValidateParameters(context.Background(), strict, additionalPropertiesSchema).InvalidParams)
+	assert.Equal(t, []string{"filters[0].note: unknown"}, validator.ValidateStructure(strict, additionalPropertiesSchema))
+	_, stripped := validator.StripAdditionalProperties(strict, additionalPropertiesSchema)
+	assert.Empty(t, stripped, "only the strip policy removes parameters")
+
+	lenient := additionalPropertiesCall("Lenient")
+	assert.True(t, validator.ValidateParameters(context.Background(), lenient, additionalPropertiesSchema).IsValid)
+	assert.Empty(t, validator.ValidateStructure(lenient, additionalPropertiesSchema))
+
+	tidy := additionalPropertiesCall("Tidy")
+	assert.False(t, validator.ValidateParameters(context.Background(), tidy, additionalPropertiesSchema).IsValid)
+	cleaned, stripped := validator.StripAdditionalProperties(tidy, additionalPropertiesSchema)
+	assert.Equal(t, []string{"filters[0].note", "verbose"}, stripped)
+	assert.Equal(t, map[string]interface{}{
+		"query":   "open issues",
+		"filters": []interface{}{map[string]interface{}{"field": "state"}},
+	}, cleaned.Input)
+	assert.Contains(t, tidy.Input, "verbose", "the original call is left unchanged")
+	assert.True(t, validator.ValidateParameters(context.Background(), cleaned, additionalPropertiesSchema).IsValid)
+	assert.Empty(t, validator.ValidateStructure(cleaned, additionalPropertiesSchema))
+
+	assert.Error(t, validator.SetAdditionalPropertiesPolicy(types.AdditionalPropertiesPolicy{Default: "ignore"}))
+}
+
+// TestAdditionalPropertiesDefaultAllowsTask tests that the default policy
+// keeps the extra parameters of Task calls converted from slash commands
+func TestAdditionalPropertiesDefaultAllowsTask(t *testing.T) {
+	validator := types.NewStandardToolValidator()
+	task := types.GetFallbackToolSchema("Task")
+	require.NotNil(t, task)
+
+	call := types.Content{Type: "tool_use", Name: "Task", Input: map[string]interface{}{
+		"description": "Review",
+		"prompt":      "Review the diff",
+		"command":     "/review",
+	}}
+	assert.True(t, validator.ValidateParameters(context.Background(), call, task.InputSchema).IsValid)
+
+	call.Name = "Read"
+	assert.ElementsMatch(t, []string{"command"}, validator.ValidateParameters(context.Background(), call, task.InputSchema).InvalidParams)
+}
+
+// TestAdditionalPropertiesStripSkipsLLM tests that the strip policy fixes
+// calls without the correction model
+func TestAdditionalPropertiesStripSkipsLLM(t *testing.T) {
+	var llmCalls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&llmCalls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cfg := config.GetDefaultConfig()
+	cfg.ToolCorrectionEndpoints = []string{server.URL}
+	cfg.ToolCorrectionAPIKey = "test-key"
+	service := correction.NewService(cfg, "test-key", true, "test-model", true, nil)
+	require.NoError(t, service.SetAdditionalPropertiesPolicy(types.AdditionalPropertiesPolicy{Default: types.AdditionalPropertiesStrip}))
+	ctx := internal.WithRequestID(context.Background(), "additional_properties_test")
+
+	corrected, err := service.CorrectToolCalls(ctx, []types.Content{additionalPropertiesCall("Search")},
+		[]types.Tool{{Name: "Search", InputSchema: additionalPropertiesSchema}})
+	require.NoError(t, err)
+	require.Len(t, corrected, 1)
+	assert.NotContains(t, corrected[0].Input, "verbose")
+	assert.Equal(t, []interface{}{map[string]interface{}{"field": "state"}}, corrected[0].Input["filters"])
+	assert.Equal(t, int32(0), atomic.LoadInt32(&llmCalls))
+}
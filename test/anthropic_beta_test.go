@@ -0,0 +1,92 @@
+package test
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/internal"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAnthropicBetaToolMinification tests that the token-efficient-tools beta
+// opts a request into tool schema minification when allowed by config
+func TestAnthropicBetaToolMinification(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.BetaMinifyTools = true
+	req := types.AnthropicRequest{
+		Model:    "kimi-k2",
+		Messages: []types.Message{{Role: "user", Content: "run the tests"}},
+		Tools:    []types.Tool{verboseToolForMinify("Bash")},
+	}
+	fullLength := len(req.Tools[0].Description)
+
+	transform := func(betas ...string) int {
+		ctx := internal.WithRequestID(context.Background(), "beta_minify_test")
+		ctx = internal.WithAnthropicBetas(ctx, betas)
+		openaiReq, err := proxy.TransformAnthropicToOpenAI(ctx, req, cfg)
+		require.NoError(t, err)
+		require.Len(t, openaiReq.Tools, 1)
+		return len(openaiReq.Tools[0].Function.Description)
+	}
+
+	assert.Equal(t, fullLength, transform(), "no beta keeps full descriptions")
+	assert.Equal(t, fullLength, transform(proxy.BetaClaudeCode), "unrelated beta keeps full descriptions")
+	assert.LessOrEqual(t, transform(proxy.BetaClaudeCode, proxy.BetaTokenEfficientTools), config.DefaultToolDescriptionMaxChars)
+
+	cfg.BetaMinifyTools = false
+	assert.Equal(t, fullLength, transform(proxy.BetaTokenEfficientTools), "beta is ignored unless enabled")
+}
+
+// TestAnthropicBetaHeaderParsing tests that comma-separated and repeated
+// anthropic-beta headers reach transformer stages through the request context
+func TestAnthropicBetaHeaderParsing(t *testing.T) {
+	var toolDescription string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("anthropic-beta"), "beta header must not be forwarded upstream")
+		body, _ := io.ReadAll(r.Body)
+		var upstreamReq types.OpenAIRequest
+		json.Unmarshal(body, &upstreamReq)
+		if len(upstreamReq.Tools) > 0 {
+			toolDescription = upstreamReq.Tools[0].Function.Description
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":    "resp_1",
+			"model": "kimi-k2",
+			"choices": []map[string]interface{}{{
+				"index":         0,
+				"message":       map[string]interface{}{"role": "assistant", "content": "ok"},
+				"finish_reason": "stop",
+			}},
+		})
+	}))
+	defer server.Close()
+
+	cfg := newErrorCodeTestConfig("http://127.0.0.1:1")
+	cfg.BigModelEndpoints = []string{server.URL}
+	cfg.BetaMinifyTools = true
+	handler := proxy.NewHandler(cfg, nil, "")
+
+	tools, err := json.Marshal([]types.Tool{verboseToolForMinify("Bash")})
+	require.NoError(t, err)
+	body := `{"model": "claude-sonnet-4-20250514", "max_tokens": 100, "messages": [{"role": "user", "content": "hi"}], "tools": ` + string(tools) + `}`
+
+	httpReq := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(body))
+	httpReq.Header.Add("anthropic-beta", "claude-code-20250219, interleaved-thinking-2025-05-14")
+	httpReq.Header.Add("anthropic-beta", "Token-Efficient-Tools-2025-02-19")
+	rr := httptest.NewRecorder()
+	handler.HandleAnthropicRequest(rr, httpReq)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	assert.NotEmpty(t, toolDescription)
+	assert.LessOrEqual(t, len(toolDescription), config.DefaultToolDescriptionMaxChars)
+}
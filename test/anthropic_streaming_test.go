@@ -354,4 +354,101 @@ func parseSSEEvents(body string) []map[string]interface{} {
 	}
 	
 	return events
-}
\ No newline at end of file
+}
+// TestAnthropicStreamingThinkingBlock verifies that Harmony analysis content is
+// streamed as a native thinking block with thinking_delta events
+func TestAnthropicStreamingThinkingBlock(t *testing.T) {
+	harmony := "<|start|>assistant<|channel|>analysis<|message|>Checking the request<|end|><|start|>assistant<|channel|>final<|message|>Done<|return|>"
+	chunk, _ := json.Marshal(map[string]interface{}{
+		"id":      "chatcmpl-test",
+		"object":  "chat.completion.chunk",
+		"created": 1234567890,
+		"model":   "test-model",
+		"choices": []map[string]interface{}{
+			{"index": 0, "delta": map[string]interface{}{"content": harmony}, "finish_reason": nil},
+		},
+	})
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintf(w, "data: %s\n\n", chunk)
+		fmt.Fprint(w, "data: {\"id\":\"chatcmpl-test\",\"object\":\"chat.completion.chunk\",\"created\":1234567890,\"model\":\"test-model\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer mockServer.Close()
+
+	cfg := config.GetDefaultConfig()
+	cfg.BigModel = "test-model"
+	cfg.BigModelEndpoints = []string{mockServer.URL}
+	cfg.BigModelAPIKey = "test-key"
+
+	handler := proxy.NewHandler(cfg, nil, "")
+
+	reqJSON, _ := json.Marshal(map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"max_tokens": 100,
+		"stream":     true,
+		"messages": []map[string]interface{}{
+			{"role": "user", "content": "Say hello"},
+		},
+	})
+	req := httptest.NewRequest("POST", "/v1/messages", bytes.NewReader(reqJSON))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	handler.HandleAnthropicRequest(rr, req)
+
+	var blockTypes []string
+	var thinking, text strings.Builder
+	startSigned, signed := false, false
+	for _, line := range strings.Split(rr.Body.String(), "\n") {
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var event struct {
+			Type         string                 `json:"type"`
+			Index        int                    `json:"index"`
+			ContentBlock map[string]interface{} `json:"content_block"`
+			Delta        map[string]interface{} `json:"delta"`
+		}
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil {
+			continue
+		}
+		switch event.Type {
+		case "content_block_start":
+			blockTypes = append(blockTypes, fmt.Sprint(event.ContentBlock["type"]))
+			if event.Index == 0 {
+				_, startSigned = event.ContentBlock["signature"]
+			}
+		case "content_block_stop":
+			if event.Index == 0 && !signed {
+				t.Error("Expected a signature_delta before the thinking block stops")
+			}
+		case "content_block_delta":
+			switch event.Delta["type"] {
+			case "thinking_delta":
+				if signed {
+					t.Error("Expected thinking deltas before the signature_delta")
+				}
+				thinking.WriteString(fmt.Sprint(event.Delta["thinking"]))
+			case "signature_delta":
+				signed = event.Index == 0
+			case "text_delta":
+				text.WriteString(fmt.Sprint(event.Delta["text"]))
+			}
+		}
+	}
+
+	if len(blockTypes) != 2 || blockTypes[0] != "thinking" || blockTypes[1] != "text" {
+		t.Fatalf("Expected thinking then text content blocks, got: %v\n%s", blockTypes, rr.Body.String())
+	}
+	if !startSigned {
+		t.Error("Expected the thinking content_block_start to carry a signature field")
+	}
+	if thinking.String() != "Checking the request" {
+		t.Errorf("Expected thinking deltas to carry analysis content, got: %q", thinking.String())
+	}
+	if text.String() != "Done" {
+		t.Errorf("Expected text deltas to carry final content, got: %q", text.String())
+	}
+}
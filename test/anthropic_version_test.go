@@ -0,0 +1,65 @@
+package test
+
+import (
+	"claude-proxy/proxy"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAnthropicVersionHeader tests validation of anthropic-version and the
+// version-gated SSE event naming
+func TestAnthropicVersionHeader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"id":"s1","model":"kimi-k2","choices":[{"index":0,"delta":{"content":"hi"},"finish_reason":null}]}`+"\n\n")
+		fmt.Fprint(w, `data: {"id":"s1","model":"kimi-k2","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`+"\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	cfg := newErrorCodeTestConfig("http://127.0.0.1:1")
+	cfg.BigModelEndpoints = []string{server.URL}
+	handler := proxy.NewHandler(cfg, nil, "")
+
+	send := func(version string) *httptest.ResponseRecorder {
+		body := `{"model": "claude-sonnet-4-20250514", "max_tokens": 100, "stream": true, "messages": [{"role": "user", "content": "hi"}]}`
+		req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(body))
+		if version != "" {
+			req.Header.Set("anthropic-version", version)
+		}
+		rr := httptest.NewRecorder()
+		handler.HandleAnthropicRequest(rr, req)
+		return rr
+	}
+
+	t.Run("MalformedVersionRejected", func(t *testing.T) {
+		rr := send("latest")
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		var body proxyErrorBody
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, string(proxy.CodeInvalidRequest), body.Error.Code)
+		assert.Contains(t, body.Error.Message, "anthropic-version")
+	})
+
+	t.Run("CurrentVersionUsesNamedEvents", func(t *testing.T) {
+		for _, version := range []string{"", proxy.AnthropicVersion20230601, "2099-01-01"} {
+			rr := send(version)
+			require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+			assert.Contains(t, rr.Body.String(), "event: message_start\n", "version %q", version)
+		}
+	})
+
+	t.Run("LegacyVersionOmitsEventNames", func(t *testing.T) {
+		rr := send(proxy.AnthropicVersion20230101)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		assert.NotContains(t, rr.Body.String(), "event: ")
+		assert.Contains(t, rr.Body.String(), `"type":"message_start"`)
+	})
+}
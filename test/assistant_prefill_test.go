@@ -0,0 +1,116 @@
+package test
+
+import (
+	"bytes"
+	"claude-proxy/config"
+	"claude-proxy/internal"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// prefillRequest ends with a partial assistant turn the model should continue
+func prefillRequest(model string, prefill interface{}) types.AnthropicRequest {
+	return types.AnthropicRequest{
+		Model:     model,
+		MaxTokens: 100,
+		Messages: []types.Message{
+			{Role: "user", Content: "Describe the file as JSON"},
+			{Role: "assistant", Content: prefill},
+		},
+	}
+}
+
+// TestAssistantPrefillTransform tests that a trailing assistant message stays
+// the last upstream message, without trailing whitespace, and that only
+// backends configured for it receive the continuation flags
+func TestAssistantPrefillTransform(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.ModelProfiles = map[string]config.ModelProfile{
+		"vllm-model": {PassthroughParams: []string{proxy.ContinueFinalMessageParam}},
+	}
+	ctx := internal.WithRequestID(context.Background(), "prefill_transform_test")
+
+	openaiReq, err := proxy.TransformAnthropicToOpenAI(ctx, prefillRequest("llama-model", "{\"name\": \n"), cfg)
+	require.NoError(t, err)
+	last := openaiReq.Messages[len(openaiReq.Messages)-1]
+	assert.Equal(t, "assistant", last.Role)
+	assert.Equal(t, "{\"name\":", last.Content)
+	assert.False(t, openaiReq.ContinueFinalMessage)
+	assert.Nil(t, openaiReq.AddGenerationPrompt)
+
+	blocks := []interface{}{map[string]interface{}{"type": "text", "text": "{\"name\":"}}
+	openaiReq, err = proxy.TransformAnthropicToOpenAI(ctx, prefillRequest("vllm-model", blocks), cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"name\":", openaiReq.Messages[len(openaiReq.Messages)-1].Content)
+	assert.True(t, openaiReq.ContinueFinalMessage)
+	require.NotNil(t, openaiReq.AddGenerationPrompt)
+	assert.False(t, *openaiReq.AddGenerationPrompt)
+
+	// A conversation ending with a user turn is not a prefill
+	openaiReq, err = proxy.TransformAnthropicToOpenAI(ctx, types.AnthropicRequest{
+		Model:    "vllm-model",
+		Messages: []types.Message{{Role: "user", Content: "Hello"}},
+	}, cfg)
+	require.NoError(t, err)
+	assert.False(t, openaiReq.ContinueFinalMessage)
+}
+
+// TestAssistantPrefillResponse tests that the response contains only the
+// continuation, whether or not the backend repeats the prefill
+func TestAssistantPrefillResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend string
+	}{
+		{name: "backend returns continuation", backend: " \"main.go\"}"},
+		{name: "backend echoes prefill", backend: "{\"name\": \"main.go\"}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var upstream types.OpenAIRequest
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewDecoder(r.Body).Decode(&upstream)
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(types.OpenAIResponse{
+					ID: "chatcmpl-prefill",
+					Choices: []types.OpenAIChoice{{
+						Message:      types.OpenAIMessage{Role: "assistant", Content: tt.backend},
+						FinishReason: stringPtr("stop"),
+					}},
+				})
+			}))
+			defer mockServer.Close()
+
+			cfg := config.GetDefaultConfig()
+			cfg.BigModel = "test-model"
+			cfg.BigModelEndpoints = []string{mockServer.URL}
+			cfg.BigModelAPIKey = "test-key"
+			handler := proxy.NewHandler(cfg, nil, "")
+
+			reqJSON, _ := json.Marshal(prefillRequest("claude-sonnet-4-20250514", "{\"name\":"))
+			req := httptest.NewRequest("POST", "/v1/messages", bytes.NewReader(reqJSON))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+
+			handler.HandleAnthropicRequest(rr, req)
+			require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+			require.NotEmpty(t, upstream.Messages)
+			assert.Equal(t, "assistant", upstream.Messages[len(upstream.Messages)-1].Role)
+
+			var resp types.AnthropicResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Len(t, resp.Content, 1)
+			assert.Equal(t, " \"main.go\"}", resp.Content[0].Text)
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"claude-proxy/batch"
+	"claude-proxy/proxy"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBatchTestProcessor wires a processor to a handler whose big model upstream
+// answers "ok", or fails with 500 when the prompt contains "fail"
+func newBatchTestProcessor(t *testing.T, store *batch.Store) (*proxy.BatchProcessor, *int32) {
+	t.Helper()
+	var streamRequests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if bytes.Contains(body, []byte(`"stream":true`)) {
+			atomic.AddInt32(&streamRequests, 1)
+		}
+		if bytes.Contains(body, []byte("fail")) {
+			http.Error(w, "backend exploded", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":    "resp_1",
+			"model": "kimi-k2",
+			"choices": []map[string]interface{}{{
+				"index":         0,
+				"message":       map[string]interface{}{"role": "assistant", "content": "ok"},
+				"finish_reason": "stop",
+			}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := newErrorCodeTestConfig("http://127.0.0.1:1")
+	cfg.BigModelEndpoints = []string{server.URL}
+	handler := proxy.NewHandler(cfg, nil, "")
+	return proxy.NewBatchProcessor(store, http.HandlerFunc(handler.HandleAnthropicRequest), 2), &streamRequests
+}
+
+func batchEntry(customID, prompt string) batch.Entry {
+	params := `{"model": "claude-sonnet-4-20250514", "max_tokens": 100, "stream": true, "messages": [{"role": "user", "content": "` + prompt + `"}]}`
+	return batch.Entry{CustomID: customID, Params: json.RawMessage(params)}
+}
+
+// TestMessageBatches tests create, get and results through the existing pipeline
+func TestMessageBatches(t *testing.T) {
+	store, err := batch.OpenStore(filepath.Join(t.TempDir(), "batches.db"))
+	require.NoError(t, err)
+	defer store.Close()
+	processor, streamRequests := newBatchTestProcessor(t, store)
+
+	entries := []batch.Entry{batchEntry("first", "hello"), batchEntry("second", "please fail"), batchEntry("third", "hi")}
+	body, _ := json.Marshal(map[string]interface{}{"requests": entries})
+	rr := httptest.NewRecorder()
+	processor.HandleBatches(rr, httptest.NewRequest(http.MethodPost, "/v1/messages/batches", bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var created batch.Batch
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+	assert.True(t, strings.HasPrefix(created.ID, "msgbatch_"))
+	assert.Equal(t, "message_batch", created.Type)
+	assert.Equal(t, batch.StatusInProgress, created.ProcessingStatus)
+	assert.Equal(t, 3, created.RequestCounts.Processing)
+
+	processor.Wait()
+
+	rr = httptest.NewRecorder()
+	processor.HandleBatch(rr, httptest.NewRequest(http.MethodGet, "/v1/messages/batches/"+created.ID, nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	var ended batch.Batch
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &ended))
+	assert.Equal(t, batch.StatusEnded, ended.ProcessingStatus)
+	assert.Equal(t, batch.RequestCounts{Succeeded: 2, Errored: 1}, ended.RequestCounts)
+	require.NotNil(t, ended.ResultsURL)
+	assert.Equal(t, "/v1/messages/batches/"+created.ID+"/results", *ended.ResultsURL)
+
+	rr = httptest.NewRecorder()
+	processor.HandleBatch(rr, httptest.NewRequest(http.MethodGet, *ended.ResultsURL, nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var results []batch.Result
+	scanner := bufio.NewScanner(rr.Body)
+	for scanner.Scan() {
+		var result batch.Result
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &result))
+		results = append(results, result)
+	}
+	require.Len(t, results, 3)
+	assert.Equal(t, "first", results[0].CustomID)
+	assert.Equal(t, batch.ResultSucceeded, results[0].Result.Type)
+	assert.Contains(t, string(results[0].Result.Message), `"text":"ok"`)
+	assert.Equal(t, "second", results[1].CustomID)
+	assert.Equal(t, batch.ResultErrored, results[1].Result.Type)
+	assert.Contains(t, string(results[1].Result.Error), "UPSTREAM_ERROR_STATUS")
+	assert.Equal(t, int32(0), atomic.LoadInt32(streamRequests), "batch entries must not stream")
+
+	rr = httptest.NewRecorder()
+	processor.HandleBatch(rr, httptest.NewRequest(http.MethodGet, "/v1/messages/batches/msgbatch_missing", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+// TestMessageBatchValidation tests rejection of malformed batches
+func TestMessageBatchValidation(t *testing.T) {
+	store, err := batch.OpenStore(filepath.Join(t.TempDir(), "batches.db"))
+	require.NoError(t, err)
+	defer store.Close()
+	processor, _ := newBatchTestProcessor(t, store)
+
+	for name, entries := range map[string][]batch.Entry{
+		"Empty":           {},
+		"DuplicateID":     {batchEntry("same", "a"), batchEntry("same", "b")},
+		"InvalidCustomID": {batchEntry("has spaces", "a")},
+		"ParamsNotObject": {{CustomID: "x", Params: json.RawMessage(`"text"`)}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]interface{}{"requests": entries})
+			rr := httptest.NewRecorder()
+			processor.HandleBatches(rr, httptest.NewRequest(http.MethodPost, "/v1/messages/batches", bytes.NewReader(body)))
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+		})
+	}
+}
+
+// TestMessageBatchResume tests that a batch interrupted by a restart is completed
+func TestMessageBatchResume(t *testing.T) {
+	store, err := batch.OpenStore(filepath.Join(t.TempDir(), "batches.db"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	// Simulate a restart: one entry already has a result, the other never ran
+	record := batch.Record{Batch: batch.Batch{
+		ID:               "msgbatch_resume",
+		Type:             "message_batch",
+		ProcessingStatus: batch.StatusInProgress,
+		RequestCounts:    batch.RequestCounts{Processing: 1, Succeeded: 1},
+		CreatedAt:        time.Now(),
+	}}
+	require.NoError(t, store.Create(record, []batch.Entry{batchEntry("done", "hello"), batchEntry("pending", "hi")}))
+	require.NoError(t, store.PutResult(record.ID, 0, batch.Result{
+		CustomID: "done",
+		Result:   batch.EntryResult{Type: batch.ResultSucceeded, Message: json.RawMessage(`{}`)},
+	}))
+
+	processor, _ := newBatchTestProcessor(t, store)
+	require.NoError(t, processor.Resume())
+	processor.Wait()
+
+	resumed, ok, err := store.Get(record.ID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, batch.StatusEnded, resumed.ProcessingStatus)
+	assert.Equal(t, batch.RequestCounts{Succeeded: 2}, resumed.RequestCounts)
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -136,4 +137,61 @@ func TestBigModelCircuitBreakerBypass(t *testing.T) {
 
 		t.Logf("✅ Small model endpoints correctly use circuit breaker - failover working")
 	})
-}
\ No newline at end of file
+
+	t.Run("BigModelCircuitBreakerEnabled", func(t *testing.T) {
+		var deadHits int32
+		deadServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&deadHits, 1)
+			http.Error(w, "Simulated dead big model", http.StatusInternalServerError)
+		}))
+		defer deadServer.Close()
+
+		workingServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id":"ok","object":"chat.completion","model":"kimi-k2","choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`))
+		}))
+		defer workingServer.Close()
+
+		healthManager := circuitbreaker.NewHealthManager(circuitbreaker.DefaultConfig())
+		healthManager.RegisterRole(config.EndpointRoleBig, []string{deadServer.URL, workingServer.URL})
+		bigModelBreaker := circuitbreaker.DefaultConfig()
+		bigModelBreaker.FailureThreshold = 2
+		healthManager.SetRoleConfig(config.EndpointRoleBig, bigModelBreaker)
+
+		cfg := &config.Config{
+			BigModelEndpoints:        []string{deadServer.URL, workingServer.URL},
+			BigModelAPIKey:           "test-key",
+			BigModel:                 "kimi-k2",
+			SmallModelEndpoints:      []string{"http://localhost:8080"},
+			SmallModelAPIKey:         "test-key",
+			SmallModel:               "qwen2.5-coder:latest",
+			SkipTools:                []string{},
+			BigModelCircuitBreaker:   true,
+			BigModelFailureThreshold: 2,
+			HealthManager:            healthManager,
+		}
+		handler := proxy.NewHandler(cfg, nil, "")
+
+		reqBody := `{"model":"claude-3-5-sonnet-20241022","max_tokens":100,"messages":[{"role":"user","content":"Test"}]}`
+		send := func() int {
+			req := httptest.NewRequest("POST", "/v1/messages", strings.NewReader(reqBody))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+			handler.HandleAnthropicRequest(rr, req)
+			return rr.Code
+		}
+
+		// Round-robin reaches the dead endpoint until its circuit opens at the threshold
+		assert.Equal(t, http.StatusBadGateway, send())
+		assert.Equal(t, http.StatusOK, send())
+		assert.True(t, cfg.IsEndpointHealthy(deadServer.URL), "one failure is below the big model threshold")
+		assert.Equal(t, http.StatusBadGateway, send())
+		assert.False(t, cfg.IsEndpointHealthy(deadServer.URL))
+
+		// The open circuit keeps every following request on the working endpoint
+		for i := 0; i < 4; i++ {
+			assert.Equal(t, http.StatusOK, send(), "request %d should skip the dead endpoint", i+1)
+		}
+		assert.Equal(t, int32(2), atomic.LoadInt32(&deadHits))
+	})
+}
@@ -0,0 +1,156 @@
+package test
+
+import (
+	"claude-proxy/circuitbreaker"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingAlertSink captures alerts for assertions
+type recordingAlertSink struct {
+	mu     sync.Mutex
+	alerts []circuitbreaker.Alert
+}
+
+func (s *recordingAlertSink) SendAlert(alert circuitbreaker.Alert) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.alerts = append(s.alerts, alert)
+	return nil
+}
+
+func (s *recordingAlertSink) waitFor(t *testing.T, count int) []circuitbreaker.Alert {
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		s.mu.Lock()
+		if len(s.alerts) >= count {
+			alerts := append([]circuitbreaker.Alert(nil), s.alerts...)
+			s.mu.Unlock()
+			return alerts
+		}
+		s.mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for %d alerts", count)
+	return nil
+}
+
+// TestCircuitBreakerAlertOnOpen tests that opening a circuit fires exactly one alert
+func TestCircuitBreakerAlertOnOpen(t *testing.T) {
+	hm := circuitbreaker.NewHealthManager(circuitbreaker.DefaultConfig())
+	endpoints := []string{"http://endpoint-a/v1/chat/completions", "http://endpoint-b/v1/chat/completions"}
+	hm.InitializeEndpoints(endpoints)
+	hm.RegisterRole("small_model", endpoints)
+
+	sink := &recordingAlertSink{}
+	hm.SetAlertSink(sink)
+
+	hm.RecordFailureWithError(endpoints[0], errors.New("connection refused"))
+	hm.RecordFailureWithError(endpoints[0], errors.New("connection refused"))
+	// Further failures while open must not re-alert
+	hm.RecordFailureWithError(endpoints[0], errors.New("connection refused"))
+
+	sink.waitFor(t, 1)
+	time.Sleep(50 * time.Millisecond)
+	alerts := sink.waitFor(t, 1)
+	if len(alerts) != 1 {
+		t.Fatalf("Expected 1 alert, got %d", len(alerts))
+	}
+
+	alert := alerts[0]
+	if alert.Type != circuitbreaker.AlertCircuitOpened {
+		t.Errorf("Expected %s alert, got %s", circuitbreaker.AlertCircuitOpened, alert.Type)
+	}
+	if alert.Endpoint != endpoints[0] || alert.Role != "small_model" {
+		t.Errorf("Unexpected alert target: endpoint=%s role=%s", alert.Endpoint, alert.Role)
+	}
+	if alert.FailureCount != 2 || alert.LastError != "connection refused" {
+		t.Errorf("Unexpected alert details: failures=%d last_error=%q", alert.FailureCount, alert.LastError)
+	}
+}
+
+// TestCircuitBreakerAlertAllEndpointsFailed tests the role-wide alert and its re-arming
+func TestCircuitBreakerAlertAllEndpointsFailed(t *testing.T) {
+	hm := circuitbreaker.NewHealthManager(circuitbreaker.DefaultConfig())
+	endpoints := []string{"http://endpoint-a/v1/chat/completions", "http://endpoint-b/v1/chat/completions"}
+	hm.InitializeEndpoints(endpoints)
+	hm.RegisterRole("tool_correction", endpoints)
+
+	sink := &recordingAlertSink{}
+	hm.SetAlertSink(sink)
+
+	for _, endpoint := range endpoints {
+		hm.RecordFailure(endpoint)
+		hm.RecordFailure(endpoint)
+	}
+
+	// Alerts are delivered asynchronously, so only their multiset is deterministic
+	alerts := sink.waitFor(t, 3)
+	roleAlerts := filterAlerts(alerts, circuitbreaker.AlertAllEndpointsFailed)
+	if len(roleAlerts) != 1 || roleAlerts[0].Role != "tool_correction" {
+		t.Fatalf("Expected one all-endpoints alert for tool_correction, got %+v", roleAlerts)
+	}
+	if len(roleAlerts[0].UnhealthyEndpoints) != 2 {
+		t.Errorf("Expected 2 unhealthy endpoints, got %v", roleAlerts[0].UnhealthyEndpoints)
+	}
+
+	// Recovery re-arms the role alert
+	hm.RecordSuccess(endpoints[0])
+	hm.RecordFailure(endpoints[0])
+	hm.RecordFailure(endpoints[0])
+
+	alerts = sink.waitFor(t, 5)
+	if len(filterAlerts(alerts, circuitbreaker.AlertAllEndpointsFailed)) != 2 {
+		t.Errorf("Expected role alert to fire again after recovery, got %+v", alerts)
+	}
+}
+
+func filterAlerts(alerts []circuitbreaker.Alert, alertType string) []circuitbreaker.Alert {
+	var filtered []circuitbreaker.Alert
+	for _, alert := range alerts {
+		if alert.Type == alertType {
+			filtered = append(filtered, alert)
+		}
+	}
+	return filtered
+}
+
+// TestWebhookAlertSinkPayload tests the webhook payload carries structured and Slack fields
+func TestWebhookAlertSinkPayload(t *testing.T) {
+	received := make(chan map[string]interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("Failed to decode payload: %v", err)
+		}
+		received <- payload
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	sink := circuitbreaker.NewWebhookAlertSink(server.URL)
+	err := sink.SendAlert(circuitbreaker.Alert{
+		Type:         circuitbreaker.AlertCircuitOpened,
+		Role:         "small_model",
+		Endpoint:     "http://endpoint-a/v1/chat/completions",
+		FailureCount: 2,
+		LastError:    "timeout",
+		Timestamp:    time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("SendAlert failed: %v", err)
+	}
+
+	payload := <-received
+	if payload["text"] == "" || payload["text"] == nil {
+		t.Error("Expected Slack-compatible text field")
+	}
+	if payload["endpoint"] != "http://endpoint-a/v1/chat/completions" || payload["last_error"] != "timeout" {
+		t.Errorf("Unexpected structured payload: %v", payload)
+	}
+}
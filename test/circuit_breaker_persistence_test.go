@@ -0,0 +1,88 @@
+package test
+
+import (
+	"claude-proxy/circuitbreaker"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCircuitBreakerStatePersistence tests that open circuits survive a restart
+func TestCircuitBreakerStatePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "circuit_state.json")
+	dead := "http://dead:8080/v1/chat/completions"
+	alive := "http://alive:8080/v1/chat/completions"
+
+	before := circuitbreaker.NewHealthManager(circuitbreaker.DefaultConfig())
+	before.InitializeEndpoints([]string{dead, alive, "http://removed:8080/v1/chat/completions"})
+	before.RecordFailure(dead)
+	before.RecordFailure(dead)
+	before.RecordSuccess(alive)
+	require.False(t, before.IsHealthy(dead))
+	require.NoError(t, before.SaveState(path))
+
+	t.Run("RestoresConfiguredEndpoints", func(t *testing.T) {
+		after := circuitbreaker.NewHealthManager(circuitbreaker.DefaultConfig())
+		after.InitializeEndpoints([]string{dead, alive})
+
+		restored, err := after.LoadState(path, time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, 2, restored, "removed endpoints are not restored")
+		assert.False(t, after.IsHealthy(dead))
+		assert.True(t, after.IsHealthy(alive))
+
+		failureCount, circuitOpen, _, _ := after.GetHealthDebug(dead)
+		assert.Equal(t, 2, failureCount)
+		assert.True(t, circuitOpen)
+		assert.Len(t, after.Snapshot(), 2)
+	})
+
+	t.Run("IgnoresExpiredState", func(t *testing.T) {
+		after := circuitbreaker.NewHealthManager(circuitbreaker.DefaultConfig())
+		after.InitializeEndpoints([]string{dead})
+
+		restored, err := after.LoadState(path, -time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, 0, restored)
+		assert.True(t, after.IsHealthy(dead))
+	})
+
+	t.Run("MissingFile", func(t *testing.T) {
+		after := circuitbreaker.NewHealthManager(circuitbreaker.DefaultConfig())
+		restored, err := after.LoadState(filepath.Join(t.TempDir(), "missing.json"), time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, restored)
+	})
+
+	t.Run("CorruptFile", func(t *testing.T) {
+		corrupt := filepath.Join(t.TempDir(), "corrupt.json")
+		require.NoError(t, os.WriteFile(corrupt, []byte("{"), 0644))
+		after := circuitbreaker.NewHealthManager(circuitbreaker.DefaultConfig())
+		_, err := after.LoadState(corrupt, time.Minute)
+		assert.Error(t, err)
+	})
+
+	t.Run("StartPersistenceSavesOnStop", func(t *testing.T) {
+		statePath := filepath.Join(t.TempDir(), "circuit_state.json")
+		hm := circuitbreaker.NewHealthManager(circuitbreaker.DefaultConfig())
+		hm.InitializeEndpoints([]string{dead})
+
+		stop, restored, err := hm.StartPersistence(statePath, time.Minute, time.Hour, nil)
+		require.NoError(t, err)
+		assert.Equal(t, 0, restored)
+		hm.RecordFailure(dead)
+		hm.RecordFailure(dead)
+		require.NoError(t, stop())
+
+		after := circuitbreaker.NewHealthManager(circuitbreaker.DefaultConfig())
+		after.InitializeEndpoints([]string{dead})
+		restored, err = after.LoadState(statePath, time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, 1, restored)
+		assert.False(t, after.IsHealthy(dead))
+	})
+}
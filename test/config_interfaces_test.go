@@ -0,0 +1,62 @@
+package test
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/logger"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeResponseConfig supplies only the sections response transformation reads
+type fakeResponseConfig struct {
+	harmony           bool
+	smallModelLogging bool
+}
+
+func (f fakeResponseConfig) IsHarmonyParsingEnabled() bool        { return f.harmony }
+func (f fakeResponseConfig) IsHarmonyEnabledForModel(string) bool { return f.harmony }
+func (f fakeResponseConfig) IsHarmonyDebugEnabled() bool          { return false }
+func (f fakeResponseConfig) IsHarmonyStrictModeEnabled() bool     { return false }
+func (f fakeResponseConfig) GetHarmonyConfiguration() config.HarmonyConfiguration {
+	return config.HarmonyConfiguration{ParsingEnabled: f.harmony}
+}
+func (f fakeResponseConfig) IsSmallModelLoggingDisabled() bool { return !f.smallModelLogging }
+func (f fakeResponseConfig) GetSmallModelName() string         { return "small-model" }
+
+// TestTransformOpenAIToAnthropicWithFakeConfig tests response transformation against a small fake config
+func TestTransformOpenAIToAnthropicWithFakeConfig(t *testing.T) {
+	content := "<|start|>assistant<|channel|>analysis<|message|>Thinking<|end|><|start|>assistant<|channel|>final<|message|>Answer<|return|>"
+	resp := &types.OpenAIResponse{
+		Model: "gpt-oss",
+		Choices: []types.OpenAIChoice{{
+			Message:      types.OpenAIMessage{Role: "assistant", Content: content},
+			FinishReason: stringPtr("stop"),
+		}},
+	}
+
+	parsed, err := proxy.TransformOpenAIToAnthropic(context.Background(), resp, "claude-sonnet-4-20250514", fakeResponseConfig{harmony: true})
+	require.NoError(t, err)
+	require.NotEmpty(t, parsed.Content)
+	assert.NotContains(t, parsed.Content[len(parsed.Content)-1].Text, "<|channel|>")
+
+	raw, err := proxy.TransformOpenAIToAnthropic(context.Background(), resp, "claude-sonnet-4-20250514", fakeResponseConfig{harmony: false})
+	require.NoError(t, err)
+	require.Len(t, raw.Content, 1)
+	assert.Equal(t, content, raw.Content[0].Text)
+}
+
+// TestLoggerConfigAdapterWithFakeConfig tests the logger adapter against a small fake config
+func TestLoggerConfigAdapterWithFakeConfig(t *testing.T) {
+	disabled := logger.NewConfigAdapter(fakeResponseConfig{smallModelLogging: false})
+	assert.False(t, disabled.ShouldLogForModel("small-model"))
+	assert.False(t, disabled.ShouldLogForModel("claude-3-5-haiku-20241022"))
+	assert.True(t, disabled.ShouldLogForModel("big-model"))
+
+	enabled := logger.NewConfigAdapter(fakeResponseConfig{smallModelLogging: true})
+	assert.True(t, enabled.ShouldLogForModel("small-model"))
+}
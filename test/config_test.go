@@ -860,3 +860,106 @@ func TestSystemMessageOverrideLogging(t *testing.T) {
 	// Test logs are written (we can't easily capture them in unit tests, but the function should not error)
 	assert.NotEmpty(t, result)
 }
+
+// TestSystemMessageOverrideInvalidPatternRejected tests that an invalid removePattern fails config load
+func TestSystemMessageOverrideInvalidPatternRejected(t *testing.T) {
+	tempDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tempDir)
+	defer os.Chdir(originalWd)
+
+	envContent := `BIG_MODEL=test-big
+BIG_MODEL_ENDPOINT=http://test:8080/v1/chat/completions
+BIG_MODEL_API_KEY=test-key
+SMALL_MODEL=test-small
+SMALL_MODEL_ENDPOINT=http://test:11434/v1/chat/completions
+SMALL_MODEL_API_KEY=test-key
+TOOL_CORRECTION_ENDPOINT=http://test:11434/v1/chat/completions
+TOOL_CORRECTION_API_KEY=test-key
+CORRECTION_MODEL=test-correction
+PRINT_SYSTEM_MESSAGE=true
+LOG_FULL_TOOLS=false
+CONVERSATION_TRUNCATION=false
+`
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, ".env"), []byte(envContent), 0644))
+
+	yamlContent := `systemMessageOverrides:
+  removePatterns:
+    - "valid pattern"
+    - "unclosed (group"
+`
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "system_overrides.yaml"), []byte(yamlContent), 0644))
+
+	cfg, err := config.LoadConfigWithEnv()
+	require.Error(t, err)
+	assert.Nil(t, cfg)
+	assert.Contains(t, err.Error(), "system_overrides.yaml")
+	assert.Contains(t, err.Error(), `removePatterns[1] "unclosed (group"`)
+}
+
+// TestSystemMessageOverrideCompiledPatterns tests that compiled and uncompiled overrides behave the same
+func TestSystemMessageOverrideCompiledPatterns(t *testing.T) {
+	overrides := config.SystemMessageOverrides{
+		RemovePatterns: []string{`IMPORTANT:[^.]*\.\s*`, `\s+$`},
+	}
+	message := "You are helpful. IMPORTANT: Never do X. Help users.   "
+	uncompiled := config.ApplySystemMessageOverrides(message, overrides)
+
+	require.NoError(t, overrides.CompilePatterns())
+	assert.Equal(t, uncompiled, config.ApplySystemMessageOverrides(message, overrides))
+	assert.Equal(t, "You are helpful. Help users.", uncompiled)
+
+	cfg := config.GetDefaultConfig()
+	cfg.SystemMessageOverrides = overrides
+	assert.Equal(t, uncompiled, cfg.ApplySystemOverrides("req-1", message))
+
+	invalid := config.SystemMessageOverrides{RemovePatterns: []string{"[unterminated"}}
+	assert.Error(t, invalid.CompilePatterns())
+}
+
+// TestServerTimeoutsConfig tests SERVER_*_TIMEOUT parsing, defaults and validation
+func TestServerTimeoutsConfig(t *testing.T) {
+	baseEnv := `BIG_MODEL=kimi-k2
+BIG_MODEL_ENDPOINT=http://192.168.0.24:8080/v1/chat/completions
+BIG_MODEL_API_KEY=sk-12345
+SMALL_MODEL=qwen2.5-coder:latest
+SMALL_MODEL_ENDPOINT=http://192.168.0.46:11434/v1/chat/completions
+SMALL_MODEL_API_KEY=ollama
+TOOL_CORRECTION_ENDPOINT=http://192.168.0.46:11434/v1/chat/completions
+TOOL_CORRECTION_API_KEY=ollama
+CORRECTION_MODEL=qwen2.5-coder:latest
+LOG_FULL_TOOLS=false
+CONVERSATION_TRUNCATION=false
+`
+	load := func(t *testing.T, extra string) (*config.Config, error) {
+		tempDir := t.TempDir()
+		originalWd, _ := os.Getwd()
+		require.NoError(t, os.Chdir(tempDir))
+		t.Cleanup(func() { os.Chdir(originalWd) })
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, ".env"), []byte(baseEnv+extra), 0644))
+		return config.LoadConfigWithEnv()
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		cfg, err := load(t, "")
+		require.NoError(t, err)
+		assert.Equal(t, 30, cfg.ServerReadTimeout)
+		assert.Equal(t, 300, cfg.ServerWriteTimeout)
+		assert.Equal(t, 60, cfg.ServerIdleTimeout)
+	})
+
+	t.Run("configured", func(t *testing.T) {
+		cfg, err := load(t, "SERVER_READ_TIMEOUT=120\nSERVER_WRITE_TIMEOUT=0\nSERVER_IDLE_TIMEOUT=90\n")
+		require.NoError(t, err)
+		assert.Equal(t, 120, cfg.ServerReadTimeout)
+		assert.Equal(t, 0, cfg.ServerWriteTimeout, "0 disables the write timeout for long streams")
+		assert.Equal(t, 90, cfg.ServerIdleTimeout)
+	})
+
+	for _, invalid := range []string{"SERVER_READ_TIMEOUT=-1\n", "SERVER_WRITE_TIMEOUT=5m\n", "SERVER_IDLE_TIMEOUT=abc\n"} {
+		t.Run("invalid "+invalid, func(t *testing.T) {
+			_, err := load(t, invalid)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package test
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/controlplane"
+	"claude-proxy/logger"
+	"claude-proxy/proxy"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// fakeRequestLister returns a fixed in-flight request list
+type fakeRequestLister []proxy.InFlightRequest
+
+func (f fakeRequestLister) InFlight() []proxy.InFlightRequest { return f }
+
+// dialControlPlane serves the control plane over an in-memory listener
+func dialControlPlane(t *testing.T, server *controlplane.Server, token string) *grpc.ClientConn {
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := controlplane.NewGRPCServer(server, token)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func invokeControlPlane(ctx context.Context, conn *grpc.ClientConn, method string) (*structpb.Struct, error) {
+	out := new(structpb.Struct)
+	err := conn.Invoke(ctx, "/"+controlplane.ServiceName+"/"+method, &emptypb.Empty{}, out)
+	return out, err
+}
+
+// TestControlPlaneQueries tests the config, endpoint health and request list RPCs
+func TestControlPlaneQueries(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.BigModel = "big-model"
+	cfg.BigModelEndpoints = []string{"http://big:8080/v1/chat/completions"}
+	cfg.BigModelAPIKey = "sk-secret-big-key"
+	cfg.RecordEndpointFailure("http://big:8080/v1/chat/completions")
+
+	requests := fakeRequestLister{{RequestID: "req_1", Model: "claude-sonnet-4-20250514", Stream: true, StartedAt: time.Now()}}
+	conn := dialControlPlane(t, controlplane.NewServer(cfg, requests, nil), "")
+	ctx := context.Background()
+
+	dump, err := invokeControlPlane(ctx, conn, "GetConfig")
+	require.NoError(t, err)
+	assert.Equal(t, "big-model", dump.Fields["models"].GetStructValue().Fields["big"].GetStringValue())
+	assert.NotContains(t, dump.String(), "sk-secret-big-key")
+
+	health, err := invokeControlPlane(ctx, conn, "GetEndpointHealth")
+	require.NoError(t, err)
+	big := health.Fields["endpoints"].GetStructValue().Fields["big"].GetListValue().Values
+	require.Len(t, big, 1)
+	assert.Equal(t, float64(1), big[0].GetStructValue().Fields["failure_count"].GetNumberValue())
+	assert.NotEmpty(t, health.Fields["circuits"].GetListValue().Values)
+
+	list, err := invokeControlPlane(ctx, conn, "ListRequests")
+	require.NoError(t, err)
+	live := list.Fields["requests"].GetListValue().Values
+	require.Len(t, live, 1)
+	assert.Equal(t, "req_1", live[0].GetStructValue().Fields["request_id"].GetStringValue())
+	assert.True(t, live[0].GetStructValue().Fields["stream"].GetBoolValue())
+
+	_, err = invokeControlPlane(ctx, conn, "Reload")
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
+
+// TestControlPlaneToken tests that a configured token is required on every call
+func TestControlPlaneToken(t *testing.T) {
+	conn := dialControlPlane(t, controlplane.NewServer(config.GetDefaultConfig(), fakeRequestLister{}, nil), "fleet-token")
+
+	_, err := invokeControlPlane(context.Background(), conn, "ListRequests")
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer wrong")
+	_, err = invokeControlPlane(ctx, conn, "ListRequests")
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx = metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer fleet-token")
+	_, err = invokeControlPlane(ctx, conn, "ListRequests")
+	assert.NoError(t, err)
+}
+
+// TestControlPlaneEnvReload tests that reload applies log levels and reports restart-only changes
+func TestControlPlaneEnvReload(t *testing.T) {
+	env := `BIG_MODEL=big-model
+BIG_MODEL_ENDPOINT=http://big:8080/v1
+BIG_MODEL_API_KEY=big-key
+SMALL_MODEL=small-model
+SMALL_MODEL_ENDPOINT=http://small:8080/v1
+SMALL_MODEL_API_KEY=small-key
+CORRECTION_MODEL=correction-model
+TOOL_CORRECTION_ENDPOINT=http://correction:8080/v1
+TOOL_CORRECTION_API_KEY=correction-key
+PRINT_SYSTEM_MESSAGE=true
+LOG_FULL_TOOLS=false
+CONVERSATION_TRUNCATION=false
+`
+	tempDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	require.NoError(t, os.Chdir(tempDir))
+	defer os.Chdir(originalWd)
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, ".env"), []byte(env), 0644))
+
+	cfg, err := config.LoadConfigWithEnv()
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, ".env"),
+		[]byte(env+"LOG_LEVEL=WARN\nLOG_LEVELS=correction=DEBUG\nBIG_MODEL_ENDPOINT=http://big2:8080/v1\n"), 0644))
+
+	levels := logger.NewLevelController(logger.INFO)
+	result, err := controlplane.EnvReloader(cfg, levels, nil)()
+	require.NoError(t, err)
+	assert.Equal(t, logger.WARN, levels.DefaultLevel())
+	assert.Equal(t, logger.DEBUG, levels.ComponentLevel(logger.ComponentToolCorrection))
+	assert.Equal(t, "WARN", result.LogLevel)
+	assert.Equal(t, []string{"endpoints"}, result.RestartRequired)
+
+	// A broken .env is rejected without touching the levels
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, ".env"), []byte(env+"LOG_LEVELS=correction\n"), 0644))
+	_, err = controlplane.EnvReloader(cfg, levels, nil)()
+	assert.Error(t, err)
+	assert.Equal(t, logger.WARN, levels.DefaultLevel())
+}
+
+// recordingSpendLimits records the spend limits a reload applies
+type recordingSpendLimits struct {
+	limits *config.SpendLimits
+}
+
+func (r *recordingSpendLimits) SetSpendLimits(limits config.SpendLimits) {
+	r.limits = &limits
+}
+
+// TestControlPlaneReloadSpendLimits tests that reload applies client spend
+// limits instead of reporting them as restart-only
+func TestControlPlaneReloadSpendLimits(t *testing.T) {
+	env := `BIG_MODEL=big-model
+BIG_MODEL_ENDPOINT=http://big:8080/v1
+BIG_MODEL_API_KEY=big-key
+SMALL_MODEL=small-model
+SMALL_MODEL_ENDPOINT=http://small:8080/v1
+SMALL_MODEL_API_KEY=small-key
+CORRECTION_MODEL=correction-model
+TOOL_CORRECTION_ENDPOINT=http://correction:8080/v1
+TOOL_CORRECTION_API_KEY=correction-key
+PRINT_SYSTEM_MESSAGE=true
+LOG_FULL_TOOLS=false
+CONVERSATION_TRUNCATION=false
+CLIENT_DAILY_LIMIT_USD=5
+`
+	tempDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	require.NoError(t, os.Chdir(tempDir))
+	defer os.Chdir(originalWd)
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, ".env"), []byte(env), 0644))
+
+	cfg, err := config.LoadConfigWithEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 5.0, cfg.SpendLimits.Default.DailyUSD)
+
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, ".env"),
+		[]byte(env+"CLIENT_MONTHLY_LIMIT_USD=40\nCLIENT_SPEND_LIMITS=0011223344556677=1/10\n"), 0644))
+
+	setter := &recordingSpendLimits{}
+	result, err := controlplane.EnvReloader(cfg, logger.NewLevelController(logger.INFO), setter)()
+	require.NoError(t, err)
+	require.NotNil(t, setter.limits)
+	assert.Equal(t, config.SpendLimit{DailyUSD: 5, MonthlyUSD: 40}, setter.limits.Default)
+	assert.Equal(t, config.SpendLimit{DailyUSD: 1, MonthlyUSD: 10}, setter.limits.For("0011223344556677"))
+	assert.Equal(t, *setter.limits, result.SpendLimits)
+	assert.Empty(t, result.RestartRequired)
+
+	// Without a handler to apply them, changed limits need a restart
+	result, err = controlplane.EnvReloader(cfg, logger.NewLevelController(logger.INFO), nil)()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"spend_limits"}, result.RestartRequired)
+}
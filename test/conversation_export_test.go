@@ -0,0 +1,99 @@
+package test
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newExportTestHandler(t *testing.T) *proxy.Handler {
+	t.Helper()
+	cfg := config.GetDefaultConfig()
+	cfg.BigModel = "kimi-k2"
+	handler := proxy.NewHandler(cfg, nil, "")
+	store := newConversationTestStore(t)
+	handler.SetConversationStore(store)
+
+	addTestExchange(t, store, "req_1", "session_a", "List the files", &types.AnthropicResponse{
+		Content: []types.Content{{Type: "tool_use", ID: "toolu_1", Name: "LS", Input: map[string]interface{}{"path": "/src"}}},
+	})
+	addTestExchange(t, store, "req_2", "session_a", "Thanks", &types.AnthropicResponse{
+		Content: []types.Content{{Type: "text", Text: "You're welcome"}},
+	})
+	addTestExchange(t, store, "req_3", "session_b", "Unrelated", nil)
+	return handler
+}
+
+func exportConversation(t *testing.T, handler *proxy.Handler, query string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.HandleConversationExport(rec, httptest.NewRequest(http.MethodGet, "/admin/conversations/export?"+query, nil))
+	var body map[string]interface{}
+	if rec.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	}
+	return rec, body
+}
+
+// TestConversationExportAnthropic tests replaying a session as /v1/messages requests
+func TestConversationExportAnthropic(t *testing.T) {
+	handler := newExportTestHandler(t)
+
+	rec, body := exportConversation(t, handler, "session=session_a")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Disposition"), "session_a.anthropic.json")
+	assert.Equal(t, "anthropic", body["format"])
+
+	exchanges := body["exchanges"].([]interface{})
+	require.Len(t, exchanges, 2)
+	first := exchanges[0].(map[string]interface{})
+	assert.Equal(t, "req_1", first["request_id"])
+	request := first["request"].(map[string]interface{})
+	assert.Equal(t, "claude-sonnet-4-20250514", request["model"])
+	assert.Len(t, request["messages"], 3)
+}
+
+// TestConversationExportOpenAI tests rendering exchanges as chat completions bodies
+func TestConversationExportOpenAI(t *testing.T) {
+	handler := newExportTestHandler(t)
+
+	rec, body := exportConversation(t, handler, "request_id=req_1&format=openai")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	exchanges := body["exchanges"].([]interface{})
+	require.Len(t, exchanges, 1, "request_id exports a single exchange")
+	exchange := exchanges[0].(map[string]interface{})
+
+	var request types.OpenAIRequest
+	data, _ := json.Marshal(exchange["request"])
+	require.NoError(t, json.Unmarshal(data, &request))
+	assert.Equal(t, "kimi-k2", request.Model, "the model is mapped as it was when served")
+	require.NotEmpty(t, request.Messages)
+	assert.Equal(t, "user", request.Messages[len(request.Messages)-1].Role)
+
+	var assistant types.OpenAIMessage
+	data, _ = json.Marshal(exchange["response"])
+	require.NoError(t, json.Unmarshal(data, &assistant))
+	assert.Equal(t, "assistant", assistant.Role)
+	require.Len(t, assistant.ToolCalls, 1)
+	assert.Equal(t, "LS", assistant.ToolCalls[0].Function.Name)
+}
+
+// TestConversationExportErrors tests parameter validation
+func TestConversationExportErrors(t *testing.T) {
+	handler := newExportTestHandler(t)
+
+	rec, _ := exportConversation(t, handler, "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec, _ = exportConversation(t, handler, "session=session_a&format=xml")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec, _ = exportConversation(t, handler, "session=missing")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,116 @@
+package test
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/conversation"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newConversationTestStore(t *testing.T) *conversation.Store {
+	t.Helper()
+	store, err := conversation.OpenStore(filepath.Join(t.TempDir(), "conversations.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func addTestExchange(t *testing.T, store *conversation.Store, requestID, sessionID, prompt string, resp *types.AnthropicResponse) {
+	t.Helper()
+	req := types.AnthropicRequest{
+		Model: "claude-sonnet-4-20250514",
+		Messages: []types.Message{
+			{Role: "user", Content: "an older turn about kubernetes"},
+			{Role: "assistant", Content: "done"},
+			{Role: "user", Content: []interface{}{map[string]interface{}{"type": "text", "text": prompt}}},
+		},
+	}
+	exchange, err := conversation.NewExchange(requestID, sessionID, req.Model, req, resp)
+	require.NoError(t, err)
+	require.NoError(t, store.Add(exchange))
+}
+
+// TestConversationStoreSearch tests content, prefix, tool and request ID lookups
+func TestConversationStoreSearch(t *testing.T) {
+	store := newConversationTestStore(t)
+	addTestExchange(t, store, "req_1", "session_a", "Why does the parser panic on empty input?", &types.AnthropicResponse{
+		Content: []types.Content{{Type: "tool_use", ID: "toolu_1", Name: "Read", Input: map[string]interface{}{"file_path": "/src/parser.go"}}},
+	})
+	addTestExchange(t, store, "req_2", "session_b", "Rename the config loader", &types.AnthropicResponse{
+		Content: []types.Content{{Type: "text", Text: "Renamed the loader in config.go"}},
+	})
+
+	results, err := store.Search(conversation.Query{Text: "parser PANIC"})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "req_1", results[0].RequestID)
+	assert.Equal(t, []string{"Read"}, results[0].ToolNames)
+	assert.Contains(t, results[0].Snippet, "parser panic")
+
+	// Only the new turn is indexed, not the repeated history
+	results, err = store.Search(conversation.Query{Text: "kubernetes"})
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	results, err = store.Search(conversation.Query{Text: "load*"})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "req_2", results[0].RequestID)
+
+	results, err = store.Search(conversation.Query{Tool: "read"})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "req_1", results[0].RequestID)
+
+	results, err = store.Search(conversation.Query{RequestID: "req_2"})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "session_b", results[0].SessionID)
+
+	results, err = store.Search(conversation.Query{})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "req_2", results[0].RequestID, "newest first")
+
+	exchange, ok, err := store.Get("req_1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	var stored types.AnthropicRequest
+	require.NoError(t, json.Unmarshal(exchange.Request, &stored))
+	assert.Len(t, stored.Messages, 3, "the full request is kept for replay")
+}
+
+// TestHandleConversations tests the admin search endpoint
+func TestHandleConversations(t *testing.T) {
+	handler := proxy.NewHandler(config.GetDefaultConfig(), nil, "")
+
+	rec := httptest.NewRecorder()
+	handler.HandleConversations(rec, httptest.NewRequest(http.MethodGet, "/admin/conversations?q=x", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code, "search is off without a store")
+
+	store := newConversationTestStore(t)
+	addTestExchange(t, store, "req_1", "session_a", "flaky websocket test", nil)
+	handler.SetConversationStore(store)
+
+	rec = httptest.NewRecorder()
+	handler.HandleConversations(rec, httptest.NewRequest(http.MethodGet, "/admin/conversations?q=websocket&limit=10", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var body struct {
+		Results []conversation.SearchResult `json:"results"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Len(t, body.Results, 1)
+	assert.Equal(t, "req_1", body.Results[0].RequestID)
+
+	rec = httptest.NewRecorder()
+	handler.HandleConversations(rec, httptest.NewRequest(http.MethodGet, "/admin/conversations?limit=0", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,113 @@
+package test
+
+import (
+	"claude-proxy/correction"
+	"claude-proxy/internal"
+	"claude-proxy/types"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func backendTestTools() []types.Tool {
+	return []types.Tool{{
+		Name: "Read",
+		InputSchema: types.ToolSchema{
+			Type:       "object",
+			Properties: map[string]types.ToolProperty{"file_path": {Type: "string"}},
+			Required:   []string{"file_path"},
+		},
+	}}
+}
+
+// TestRulesCorrectorNeverCallsModel tests that the rules backend fixes what rules can and leaves the rest
+func TestRulesCorrectorNeverCallsModel(t *testing.T) {
+	var modelCalls int32
+	model := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&modelCalls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer model.Close()
+
+	service := correction.NewService(NewMockConfigProvider(model.URL), "test-key", true, "test-model", true, nil)
+	corrector, err := correction.NewCorrector(correction.BackendRules, service, "", "", time.Second)
+	require.NoError(t, err)
+
+	calls := []types.Content{
+		{Type: "tool_use", ID: "toolu_1", Name: "read", Input: map[string]interface{}{"path": "/tmp/a"}},
+		{Type: "tool_use", ID: "toolu_2", Name: "Read", Input: map[string]interface{}{"unrelated": "x"}},
+	}
+	ctx := context.WithValue(context.Background(), internal.RequestIDKey, "test-req")
+	corrected, err := corrector.CorrectToolCalls(ctx, calls, backendTestTools())
+	require.NoError(t, err)
+	require.Len(t, corrected, 2)
+
+	assert.Equal(t, "Read", corrected[0].Name)
+	assert.Equal(t, map[string]interface{}{"file_path": "/tmp/a"}, corrected[0].Input)
+	assert.Equal(t, calls[1].Input, corrected[1].Input, "unfixable calls are returned unchanged")
+	assert.Zero(t, atomic.LoadInt32(&modelCalls))
+}
+
+// TestRemoteCorrector tests the remote correction protocol and its failure handling
+func TestRemoteCorrector(t *testing.T) {
+	calls := []types.Content{{Type: "tool_use", ID: "toolu_1", Name: "Read", Input: map[string]interface{}{"path": "/tmp/a"}}}
+
+	remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer remote-key", r.Header.Get("Authorization"))
+		assert.Equal(t, "test-req", r.Header.Get(internal.RequestIDHeader))
+
+		var body struct {
+			ToolCalls []types.Content `json:"tool_calls"`
+			Tools     []types.Tool    `json:"tools"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.Len(t, body.ToolCalls, 1)
+		require.Len(t, body.Tools, 1)
+
+		fixed := body.ToolCalls[0]
+		fixed.Input = map[string]interface{}{"file_path": fixed.Input["path"]}
+		json.NewEncoder(w).Encode(map[string]interface{}{"tool_calls": []types.Content{fixed}})
+	}))
+	defer remote.Close()
+
+	corrector, err := correction.NewCorrector(correction.BackendRemote, nil, remote.URL, "remote-key", time.Second)
+	require.NoError(t, err)
+
+	ctx := context.WithValue(context.Background(), internal.RequestIDKey, "test-req")
+	corrected, err := corrector.CorrectToolCalls(ctx, calls, backendTestTools())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"file_path": "/tmp/a"}, corrected[0].Input)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	corrector = correction.NewRemoteCorrector(failing.URL, "", time.Second)
+	corrected, err = corrector.CorrectToolCalls(ctx, calls, backendTestTools())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "503")
+	assert.Equal(t, calls, corrected, "original calls are returned on failure")
+}
+
+// TestNewCorrectorValidation tests backend selection errors
+func TestNewCorrectorValidation(t *testing.T) {
+	service := correction.NewService(NewMockConfigProvider("http://test:8080"), "test-key", true, "test-model", true, nil)
+
+	llm, err := correction.NewCorrector("", service, "", "", time.Second)
+	require.NoError(t, err)
+	assert.Same(t, service, llm)
+
+	_, err = correction.NewCorrector(correction.BackendRemote, service, "", "", time.Second)
+	assert.Error(t, err)
+
+	_, err = correction.NewCorrector("magic", service, "", "", time.Second)
+	assert.Error(t, err)
+}
@@ -0,0 +1,91 @@
+package test
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/correction"
+	"claude-proxy/internal"
+	"claude-proxy/logger"
+	"claude-proxy/types"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// benchmarkToolCalls returns n tool calls cycling through a valid call and the
+// mistakes fixed without the correction model: a wrong parameter name, a
+// mistyped value, a near-miss enum value and a malformed TodoWrite item
+func benchmarkToolCalls(n int) []types.Content {
+	calls := make([]types.Content, n)
+	for i := range calls {
+		id := fmt.Sprintf("toolu_%d", i)
+		switch i % 5 {
+		case 0:
+			calls[i] = types.Content{Type: "tool_use", ID: id, Name: "Read", Input: map[string]interface{}{"file_path": "/src/main.go"}}
+		case 1:
+			calls[i] = types.Content{Type: "tool_use", ID: id, Name: "Read", Input: map[string]interface{}{"path": "/src/main.go"}}
+		case 2:
+			calls[i] = types.Content{Type: "tool_use", ID: id, Name: "Read", Input: map[string]interface{}{"file_path": "/src/main.go", "limit": "50"}}
+		case 3:
+			calls[i] = types.Content{Type: "tool_use", ID: id, Name: "Grep", Input: map[string]interface{}{"pattern": "TODO", "output_mode": "files-with-matches"}}
+		case 4:
+			calls[i] = types.Content{Type: "tool_use", ID: id, Name: "TodoWrite", Input: map[string]interface{}{"todos": []interface{}{
+				map[string]interface{}{"id": "1", "content": "Write tests", "status": "done", "priority": "high"},
+				map[string]interface{}{"id": "2", "content": "Ship it", "status": "in-progress", "priority": "normal"},
+			}}}
+		}
+	}
+	return calls
+}
+
+// BenchmarkCorrectToolCalls measures correcting responses with many tool
+// calls through validation and the rule-based stages
+func BenchmarkCorrectToolCalls(b *testing.B) {
+	// Correction logging is not what is measured
+	defer logger.Levels().SetDefaultLevel(logger.Levels().DefaultLevel())
+	logger.Levels().SetDefaultLevel(logger.ERROR)
+
+	var llmCalls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&llmCalls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cfg := config.GetDefaultConfig()
+	cfg.ToolCorrectionEndpoints = []string{server.URL}
+	cfg.ToolCorrectionAPIKey = "test-key"
+	service := correction.NewService(cfg, "test-key", true, "test-model", true, nil)
+
+	var tools []types.Tool
+	for _, name := range []string{"Read", "Grep", "TodoWrite"} {
+		tools = append(tools, *types.GetFallbackToolSchema(name))
+	}
+
+	for _, n := range []int{10, 50, 200} {
+		calls := benchmarkToolCalls(n)
+		b.Run(fmt.Sprintf("%dCalls", n), func(b *testing.B) {
+			ctx := correction.WithToolIndex(internal.WithRequestID(context.Background(), "bench_correction"), correction.NewToolIndex(tools))
+			corrected, err := service.CorrectToolCalls(ctx, calls, tools)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, fixed := corrected[1].Input["file_path"]; !fixed {
+				b.Fatalf("expected path to be corrected to file_path, got %v", corrected[1].Input)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := service.CorrectToolCalls(ctx, calls, tools); err != nil {
+					b.Fatal(err)
+				}
+			}
+			if calls := atomic.LoadInt32(&llmCalls); calls > 0 {
+				b.Fatalf("correction model called %d times; the benchmark must only take rule-based paths", calls)
+			}
+		})
+	}
+}
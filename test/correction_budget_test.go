@@ -0,0 +1,110 @@
+package test
+
+import (
+	"bytes"
+	"claude-proxy/config"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCorrectionBudget tests that an exhausted correction budget returns
+// promptly and forwards or blocks the calls left invalid
+func TestCorrectionBudget(t *testing.T) {
+	// Correction model that answers request analysis at once but never
+	// finishes a tool call correction within the budget
+	slowCorrector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "tool filtering") {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"choices": []map[string]interface{}{{"message": map[string]interface{}{"role": "assistant", "content": "KEEP"}}},
+			})
+			return
+		}
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer slowCorrector.Close()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":    "chatcmpl-budget",
+			"model": "test-model",
+			"choices": []map[string]interface{}{{
+				"index": 0,
+				"message": map[string]interface{}{
+					"role": "assistant",
+					"tool_calls": []map[string]interface{}{{
+						"id":       "call_1",
+						"type":     "function",
+						"function": map[string]interface{}{"name": "Read", "arguments": `{"unrelated":"x"}`},
+					}},
+				},
+				"finish_reason": "tool_calls",
+			}},
+		})
+	}))
+	defer upstream.Close()
+
+	tests := []struct {
+		policy       string
+		expectedType string
+		expectedStop string
+	}{
+		{config.CorrectionBudgetForward, "tool_use", "tool_use"},
+		{config.CorrectionBudgetBlock, "text", "end_turn"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			cfg := config.GetDefaultConfig()
+			cfg.BigModel = "test-model"
+			cfg.BigModelEndpoints = []string{upstream.URL}
+			cfg.BigModelAPIKey = "test-key"
+			cfg.CorrectionModel = "test-model"
+			cfg.ToolCorrectionEndpoints = []string{slowCorrector.URL}
+			cfg.ToolCorrectionAPIKey = "test-key"
+			cfg.CorrectionBudgetMs = 200
+			cfg.CorrectionBudgetPolicy = tt.policy
+			handler := proxy.NewHandler(cfg, nil, "")
+
+			reqJSON, _ := json.Marshal(map[string]interface{}{
+				"model":      "claude-sonnet-4-20250514",
+				"max_tokens": 100,
+				"messages":   []map[string]interface{}{{"role": "user", "content": "Read the file"}},
+				"tools":      backendTestTools(),
+			})
+			req := httptest.NewRequest("POST", "/v1/messages", bytes.NewReader(reqJSON))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+
+			start := time.Now()
+			handler.HandleAnthropicRequest(rr, req)
+			assert.Less(t, time.Since(start), 3*time.Second, "correction must stop at the budget")
+			require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+			var resp types.AnthropicResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Len(t, resp.Content, 1)
+			assert.Equal(t, tt.expectedType, resp.Content[0].Type)
+			assert.Equal(t, tt.expectedStop, resp.StopReason)
+			if tt.policy == config.CorrectionBudgetBlock {
+				assert.Contains(t, resp.Content[0].Text, "missing parameters: file_path")
+			}
+		})
+	}
+}
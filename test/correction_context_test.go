@@ -0,0 +1,70 @@
+package test
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/correction"
+	"claude-proxy/internal"
+	"claude-proxy/types"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCorrectionPromptConversation tests the conversation tail in correction prompts
+func TestCorrectionPromptConversation(t *testing.T) {
+	var prompt string
+	model := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req types.OpenAIRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		prompt = req.Messages[len(req.Messages)-1].Content
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{
+				"message": map[string]interface{}{"role": "assistant", "content": `{"name": "Read", "input": {"file_path": "/src/handler.go"}}`},
+			}},
+		})
+	}))
+	defer model.Close()
+
+	conversation := []types.OpenAIMessage{
+		{Role: "system", Content: "You are a coding assistant"},
+		{Role: "user", Content: "Hello there"},
+		{Role: "assistant", Content: "Hi, what should I look at?"},
+		{Role: "user", Content: "Open the request handler in /src/handler.go " + strings.Repeat("and explain it ", 200)},
+	}
+	calls := []types.Content{{Type: "tool_use", ID: "toolu_1", Name: "Read", Input: map[string]interface{}{"file": "handler"}}}
+
+	correct := func(cfg *config.Config) {
+		prompt = ""
+		cfg.ToolCorrectionEndpoints = []string{model.URL}
+		service := correction.NewService(cfg, "test-key", true, "test-model", true, nil)
+		ctx := context.WithValue(context.Background(), internal.RequestIDKey, "test-req")
+		ctx = correction.WithConversation(ctx, conversation)
+		corrected, err := service.CorrectToolCalls(ctx, calls, backendTestTools())
+		require.NoError(t, err)
+		require.Len(t, corrected, 1)
+		assert.Equal(t, "/src/handler.go", corrected[0].Input["file_path"])
+	}
+
+	correct(config.GetDefaultConfig())
+	require.NotEmpty(t, prompt)
+	assert.NotContains(t, prompt, "RECENT CONVERSATION", "the conversation is left out by default")
+
+	cfg := config.GetDefaultConfig()
+	cfg.CorrectionContextMessages = 2
+	cfg.CorrectionContextTokens = 100
+	correct(cfg)
+	assert.Contains(t, prompt, "RECENT CONVERSATION")
+	assert.Contains(t, prompt, "ASSISTANT: Hi, what should I look at?")
+	assert.Contains(t, prompt, "USER: Open the request handler in /src/handler.go")
+	assert.Contains(t, prompt, "[truncated]", "long messages are cut to the token budget")
+	assert.NotContains(t, prompt, "Hello there", "only the last two messages are included")
+	assert.NotContains(t, prompt, "coding assistant", "system messages are skipped")
+	assert.Less(t, strings.Count(prompt, "and explain it"), 30)
+}
@@ -0,0 +1,72 @@
+package test
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/correction"
+	"claude-proxy/internal"
+	"claude-proxy/types"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCorrectionExhaustedPolicy tests the configured retry count and what
+// happens to a tool call the correction model never fixes
+func TestCorrectionExhaustedPolicy(t *testing.T) {
+	var modelCalls int32
+	model := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&modelCalls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{
+				"message": map[string]interface{}{"role": "assistant", "content": `{"name": "Read", "input": {"unrelated": "y"}}`},
+			}},
+		})
+	}))
+	defer model.Close()
+
+	calls := []types.Content{
+		{Type: "text", Text: "Reading the file"},
+		{Type: "tool_use", ID: "toolu_1", Name: "Read", Input: map[string]interface{}{"unrelated": "x"}},
+	}
+
+	tests := []struct {
+		policy   string
+		expected []string // Content types returned
+	}{
+		{config.CorrectionExhaustedOriginal, []string{"text", "tool_use"}},
+		{config.CorrectionExhaustedDrop, []string{"text"}},
+		{config.CorrectionExhaustedBlock, []string{"text", "text"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			atomic.StoreInt32(&modelCalls, 0)
+			cfg := config.GetDefaultConfig()
+			cfg.ToolCorrectionEndpoints = []string{model.URL}
+			cfg.CorrectionMaxRetries = 2
+			cfg.CorrectionExhaustedPolicy = tt.policy
+			service := correction.NewService(cfg, "test-key", true, "test-model", true, nil)
+
+			ctx := context.WithValue(context.Background(), internal.RequestIDKey, "test-req")
+			corrected, err := service.CorrectToolCalls(ctx, calls, backendTestTools())
+			require.NoError(t, err)
+
+			var contentTypes []string
+			for _, item := range corrected {
+				contentTypes = append(contentTypes, item.Type)
+			}
+			assert.Equal(t, tt.expected, contentTypes)
+			assert.Equal(t, int32(2), atomic.LoadInt32(&modelCalls), "one model call per attempt")
+			if tt.policy == config.CorrectionExhaustedBlock {
+				assert.Contains(t, corrected[1].Text, "Read blocked: it was still invalid after 2 correction attempts")
+			}
+		})
+	}
+}
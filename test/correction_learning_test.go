@@ -0,0 +1,93 @@
+package test
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/correction"
+	"claude-proxy/internal"
+	"claude-proxy/types"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRuleLearnerObserve tests which parameter changes count as renames
+func TestRuleLearnerObserve(t *testing.T) {
+	learner := correction.NewRuleLearner(2, true)
+	now := time.Now()
+	call := func(input map[string]interface{}) types.Content {
+		return types.Content{Type: "tool_use", Name: "Read", Input: input}
+	}
+
+	promoted := learner.Observe(call(map[string]interface{}{"source": "a.go", "limit": 10}),
+		call(map[string]interface{}{"file_path": "a.go", "limit": 10}), now)
+	assert.Empty(t, promoted, "one observation is below the threshold")
+	assert.Empty(t, learner.Learned())
+
+	// Ambiguous: two removed parameters share the value
+	learner.Observe(call(map[string]interface{}{"a": "x", "b": "x"}), call(map[string]interface{}{"c": "x"}), now)
+	// Different tool names are not renames
+	learner.Observe(call(map[string]interface{}{"source": "a.go"}), types.Content{Name: "Write", Input: map[string]interface{}{"file_path": "a.go"}}, now)
+
+	promoted = learner.Observe(call(map[string]interface{}{"source": "b.go"}), call(map[string]interface{}{"file_path": "b.go"}), now)
+	require.Len(t, promoted, 1)
+	assert.Equal(t, "source", promoted[0].From)
+	assert.Equal(t, "file_path", promoted[0].To)
+	assert.Equal(t, map[string]string{"source": "file_path"}, learner.Promoted("Read"))
+	assert.Nil(t, learner.Promoted("Write"))
+
+	learned := learner.Learned()
+	require.Len(t, learned, 1)
+	assert.Equal(t, 2, learned[0].Count)
+	assert.True(t, learned[0].Promoted)
+}
+
+// TestCorrectionLearning tests that promoted renames replace the correction model
+func TestCorrectionLearning(t *testing.T) {
+	var modelCalls int32
+	model := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&modelCalls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{
+				"message": map[string]interface{}{"role": "assistant", "content": `{"name": "Read", "input": {"file_path": "main.go"}}`},
+			}},
+		})
+	}))
+	defer model.Close()
+
+	calls := []types.Content{{Type: "tool_use", ID: "toolu_1", Name: "Read", Input: map[string]interface{}{"source": "main.go"}}}
+
+	for _, autoPromote := range []bool{false, true} {
+		atomic.StoreInt32(&modelCalls, 0)
+		cfg := config.GetDefaultConfig()
+		cfg.ToolCorrectionEndpoints = []string{model.URL}
+		service := correction.NewService(cfg, "test-key", true, "test-model", true, nil)
+		service.SetRuleLearner(correction.NewRuleLearner(2, autoPromote))
+
+		ctx := context.WithValue(context.Background(), internal.RequestIDKey, "test-req")
+		for i := 0; i < 3; i++ {
+			corrected, err := service.CorrectToolCalls(ctx, calls, backendTestTools())
+			require.NoError(t, err)
+			require.Len(t, corrected, 1)
+			assert.Equal(t, map[string]interface{}{"file_path": "main.go"}, corrected[0].Input)
+		}
+
+		learned := service.RuleLearner().Learned()
+		require.Len(t, learned, 1)
+		assert.Equal(t, autoPromote, learned[0].Promoted)
+		if autoPromote {
+			assert.Equal(t, int32(2), atomic.LoadInt32(&modelCalls), "the third call is fixed by the promoted rule")
+			assert.Equal(t, 2, learned[0].Count)
+		} else {
+			assert.Equal(t, int32(3), atomic.LoadInt32(&modelCalls))
+			assert.Equal(t, 3, learned[0].Count)
+		}
+	}
+}
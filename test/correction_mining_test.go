@@ -0,0 +1,91 @@
+package test
+
+import (
+	"bytes"
+	"claude-proxy/correction"
+	"claude-proxy/types"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// correctionLogLine builds the structured part of a LogCorrection entry
+func correctionLogLine(t *testing.T, original, corrected types.Content) string {
+	originalJSON, err := json.Marshal([]types.Content{{Type: "text", Text: "Working on it"}, original})
+	require.NoError(t, err)
+	correctedJSON, err := json.Marshal([]types.Content{{Type: "text", Text: "Working on it"}, corrected})
+	require.NoError(t, err)
+	fields, err := json.Marshal(map[string]interface{}{
+		"event":             "correction",
+		"correction_method": "tool_correction",
+		"original_data":     string(originalJSON),
+		"corrected_data":    string(correctedJSON),
+	})
+	require.NoError(t, err)
+	return "[12:00:00.000] [INFO] 🔧 Tool correction applied\n" + string(fields)
+}
+
+// TestMineCorrections tests ranking of invalid patterns from exported correction logs
+func TestMineCorrections(t *testing.T) {
+	readFix := correctionLogLine(t,
+		types.Content{Type: "tool_use", ID: "toolu_1", Name: "read_file", Input: map[string]interface{}{"path": "a.go", "verbose": true}},
+		types.Content{Type: "tool_use", ID: "toolu_1", Name: "Read", Input: map[string]interface{}{"file_path": "a.go"}})
+	grepFix := correctionLogLine(t,
+		types.Content{Type: "tool_use", ID: "toolu_2", Name: "Grep", Input: map[string]interface{}{"query": "TODO"}},
+		types.Content{Type: "tool_use", ID: "toolu_2", Name: "Grep", Input: map[string]interface{}{"pattern": "TODO", "output_mode": "content"}})
+
+	// The same entries in each export format, plus unrelated lines
+	logcli, err := json.Marshal(map[string]interface{}{"labels": map[string]string{"job": "simple-proxy"}, "line": readFix})
+	require.NoError(t, err)
+	push, err := json.Marshal(map[string]interface{}{"streams": []interface{}{
+		map[string]interface{}{"stream": map[string]string{}, "values": [][]string{{"1700000000000000000", readFix}, {"1700000000000000001", grepFix}}},
+	}})
+	require.NoError(t, err)
+	input := strings.Join([]string{
+		readFix,
+		string(logcli),
+		"Loki unavailable (connection refused), logging to stdout: " + string(push),
+		"[12:00:01.000] [INFO] Request received",
+		`{"event": "request", "model": "big-model"}`,
+	}, "\n")
+
+	report, err := correction.MineCorrections(strings.NewReader(input))
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.Entries)
+	require.Len(t, report.Tools, 2)
+
+	read := report.Tools[0]
+	assert.Equal(t, "Read", read.Tool)
+	assert.Equal(t, 3, read.Corrections)
+	assert.ElementsMatch(t, []correction.MinedPattern{
+		{Kind: correction.PatternToolName, From: "read_file", To: "Read", Count: 3},
+		{Kind: correction.PatternRenamedParam, From: "path", To: "file_path", Count: 3},
+		{Kind: correction.PatternUnknownParam, From: "verbose", Count: 3},
+	}, read.Patterns)
+
+	grep := report.Tools[1]
+	assert.Equal(t, "Grep", grep.Tool)
+	assert.ElementsMatch(t, []correction.MinedPattern{
+		{Kind: correction.PatternRenamedParam, From: "query", To: "pattern", Count: 1},
+		{Kind: correction.PatternMissingParam, From: "output_mode", Count: 1},
+	}, grep.Patterns)
+
+	rules, err := report.SuggestedRules(2)
+	require.NoError(t, err)
+	assert.Equal(t, "customTools:\n    - name: Read\n      aliases:\n        - read_file\n      parameterAliases:\n        path: file_path\n", string(rules))
+
+	var text bytes.Buffer
+	require.NoError(t, report.WriteText(&text, 2))
+	assert.Contains(t, text.String(), "Read (3 corrected calls)")
+	assert.Contains(t, text.String(), "renamed_param  path -> file_path")
+	assert.Contains(t, text.String(), "Suggested tool_validators.yaml rules")
+
+	empty, err := correction.MineCorrections(strings.NewReader("no json here\n"))
+	require.NoError(t, err)
+	rules, err = empty.SuggestedRules(1)
+	require.NoError(t, err)
+	assert.Nil(t, rules)
+}
@@ -0,0 +1,103 @@
+package test
+
+import (
+	"claude-proxy/correction"
+	"claude-proxy/internal"
+	"claude-proxy/types"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func customIssueTool() types.CustomTool {
+	return types.CustomTool{
+		Name:             "mcp__github__create_issue",
+		Aliases:          []string{"create_issue"},
+		ParameterAliases: map[string]string{"repository": "repo"},
+		Normalizers:      map[string]string{"labels": "array", "title": "trim", "draft": "boolean"},
+	}
+}
+
+// TestStandardToolValidatorCustomTools tests name and parameter normalization of registered tools
+func TestStandardToolValidatorCustomTools(t *testing.T) {
+	validator := types.NewStandardToolValidator()
+	require.NoError(t, validator.RegisterTool(customIssueTool()))
+
+	for _, name := range []string{"mcp__github__create_issue", "MCP__GitHub__Create_Issue", "Create_Issue"} {
+		normalized, found := validator.NormalizeToolName(name)
+		assert.True(t, found, name)
+		assert.Equal(t, "mcp__github__create_issue", normalized, name)
+	}
+	normalized, found := validator.NormalizeToolName("READ")
+	assert.True(t, found)
+	assert.Equal(t, "Read", normalized, "built-in tools keep working")
+
+	call := types.Content{Type: "tool_use", Name: "mcp__github__create_issue", Input: map[string]interface{}{
+		"repository": "owner/repo",
+		"title":      "  Crash on start  ",
+		"labels":     "bug",
+		"draft":      "false",
+	}}
+	fixed, changed := validator.NormalizeParameters(call)
+	require.True(t, changed)
+	assert.Equal(t, map[string]interface{}{
+		"repo":   "owner/repo",
+		"title":  "Crash on start",
+		"labels": []interface{}{"bug"},
+		"draft":  false,
+	}, fixed.Input)
+	assert.Contains(t, call.Input, "repository", "the original call is not modified")
+
+	_, changed = validator.NormalizeParameters(fixed)
+	assert.False(t, changed, "normalized calls are left alone")
+
+	assert.Error(t, validator.RegisterTool(types.CustomTool{Name: "x", Normalizers: map[string]string{"a": "reverse"}}))
+}
+
+// TestStandardToolValidatorRegisteredValidator tests per-tool validator delegation
+func TestStandardToolValidatorRegisteredValidator(t *testing.T) {
+	validator := types.NewStandardToolValidator()
+	require.NoError(t, validator.RegisterValidator("Strict", &MockToolValidator{
+		validateFunc: func(ctx context.Context, call types.Content, schema types.ToolSchema) types.ValidationResult {
+			return types.ValidationResult{IsValid: false, InvalidParams: []string{"custom_rule"}}
+		},
+	}))
+
+	schema := types.ToolSchema{Type: "object", Properties: map[string]types.ToolProperty{"a": {Type: "string"}}}
+	strict := validator.ValidateParameters(context.Background(), types.Content{Name: "Strict", Input: map[string]interface{}{"a": "x"}}, schema)
+	assert.Equal(t, []string{"custom_rule"}, strict.InvalidParams)
+
+	other := validator.ValidateParameters(context.Background(), types.Content{Name: "Other", Input: map[string]interface{}{"a": "x"}}, schema)
+	assert.True(t, other.IsValid)
+}
+
+// TestCorrectionUsesCustomToolNormalization tests that correction fixes custom tool calls without an LLM
+func TestCorrectionUsesCustomToolNormalization(t *testing.T) {
+	service := correction.NewService(NewMockConfigProvider("http://127.0.0.1:1"), "test-key", true, "test-model", true, nil)
+	require.NoError(t, service.RegisterCustomTools([]types.CustomTool{customIssueTool()}))
+
+	tools := []types.Tool{{
+		Name: "mcp__github__create_issue",
+		InputSchema: types.ToolSchema{
+			Type: "object",
+			Properties: map[string]types.ToolProperty{
+				"repo":  {Type: "string"},
+				"title": {Type: "string"},
+			},
+			Required: []string{"repo", "title"},
+		},
+	}}
+	call := types.Content{Type: "tool_use", ID: "toolu_1", Name: "create_issue", Input: map[string]interface{}{
+		"repository": "owner/repo",
+		"title":      "Crash",
+	}}
+
+	ctx := context.WithValue(context.Background(), internal.RequestIDKey, "test-req")
+	corrected, err := service.CorrectToolCalls(ctx, []types.Content{call}, tools)
+	require.NoError(t, err)
+	require.Len(t, corrected, 1)
+	assert.Equal(t, "mcp__github__create_issue", corrected[0].Name)
+	assert.Equal(t, map[string]interface{}{"repo": "owner/repo", "title": "Crash"}, corrected[0].Input)
+}
@@ -0,0 +1,82 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"claude-proxy/config"
+	"claude-proxy/proxy"
+	"claude-proxy/stats"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDashboardServesPage tests that the embedded dashboard is served as HTML
+// and reads /stats
+func TestDashboardServesPage(t *testing.T) {
+	rec := httptest.NewRecorder()
+	proxy.HandleDashboard(rec, httptest.NewRequest(http.MethodGet, "/admin/dashboard", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, rec.Body.String(), `fetch("/stats"`)
+
+	rec = httptest.NewRecorder()
+	proxy.HandleDashboard(rec, httptest.NewRequest(http.MethodPost, "/admin/dashboard", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+// TestStatsRecentEvents tests the throughput window and that recent events are
+// reported newest first and bounded
+func TestStatsRecentEvents(t *testing.T) {
+	collector := stats.NewCollector()
+	collector.RecordRequest("big-model", time.Millisecond, false)
+	collector.RecordRequest("big-model", time.Millisecond, true)
+	for i := 0; i < 60; i++ {
+		collector.RecordCorrectionEvent(stats.Event{RequestID: fmt.Sprintf("req-%d", i), Kind: stats.CorrectionApplied})
+	}
+	collector.RecordErrorEvent(stats.Event{RequestID: "req-error", Kind: "upstream_error", Status: http.StatusBadGateway})
+
+	snapshot := collector.Snapshot()
+	require.Len(t, snapshot.RequestsPerMinute, 60)
+	var lastTwoMinutes int64 // Recording may straddle a minute boundary
+	for _, count := range snapshot.RequestsPerMinute[58:] {
+		lastTwoMinutes += count
+	}
+	assert.Equal(t, int64(2), lastTwoMinutes, "the current minute is last")
+
+	require.Len(t, snapshot.RecentCorrections, 50)
+	assert.Equal(t, "req-59", snapshot.RecentCorrections[0].RequestID)
+	assert.Equal(t, "req-10", snapshot.RecentCorrections[49].RequestID)
+	assert.False(t, snapshot.RecentCorrections[0].Time.IsZero())
+
+	require.Len(t, snapshot.RecentErrors, 1)
+	assert.Equal(t, http.StatusBadGateway, snapshot.RecentErrors[0].Status)
+}
+
+// TestHandlerRecordsRecentErrors tests that a rejected request appears in the
+// recent errors with its status and proxy error code
+func TestHandlerRecordsRecentErrors(t *testing.T) {
+	handler := proxy.NewHandler(config.GetDefaultConfig(), nil, "")
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader("{not json"))
+	req.Header.Set("X-Request-ID", "dashboard-error-test")
+	handler.HandleAnthropicRequest(httptest.NewRecorder(), req)
+
+	rec := httptest.NewRecorder()
+	handler.HandleStats(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	var body struct {
+		RecentErrors []stats.Event `json:"recent_errors"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Len(t, body.RecentErrors, 1)
+	assert.Equal(t, "dashboard-error-test", body.RecentErrors[0].RequestID)
+	assert.Equal(t, http.StatusBadRequest, body.RecentErrors[0].Status)
+	assert.NotEmpty(t, body.RecentErrors[0].Kind)
+	assert.NotEmpty(t, body.RecentErrors[0].Detail)
+}
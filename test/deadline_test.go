@@ -0,0 +1,174 @@
+package test
+
+import (
+	"bytes"
+	"claude-proxy/config"
+	"claude-proxy/proxy"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// deadlineErrorResponse mirrors the DEADLINE_EXCEEDED body the tests read
+type deadlineErrorResponse struct {
+	Error struct {
+		Type string `json:"type"`
+		Code string `json:"code"`
+	} `json:"error"`
+	Accounting struct {
+		DeadlineMs int64    `json:"deadline_ms"`
+		ElapsedMs  int64    `json:"elapsed_ms"`
+		Stage      string   `json:"stage"`
+		Completed  []string `json:"completed"`
+		Usage      *struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	} `json:"accounting"`
+}
+
+// waitForCancel blocks a test server until the proxy gives up on the request.
+// The body is read first so the server notices the connection closing.
+func waitForCancel(r *http.Request) {
+	io.Copy(io.Discard, r.Body)
+	select {
+	case <-r.Context().Done():
+	case <-time.After(5 * time.Second):
+	}
+}
+
+// sendWithDeadline posts a tool-enabled request with X-Proxy-Deadline-Ms set
+func sendWithDeadline(t *testing.T, handler *proxy.Handler, deadline string) (*httptest.ResponseRecorder, time.Duration) {
+	reqJSON, _ := json.Marshal(map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"max_tokens": 100,
+		"messages":   []map[string]interface{}{{"role": "user", "content": "Read the file"}},
+		"tools":      backendTestTools(),
+	})
+	req := httptest.NewRequest("POST", "/v1/messages", bytes.NewReader(reqJSON))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(proxy.DeadlineHeader, deadline)
+	rr := httptest.NewRecorder()
+
+	start := time.Now()
+	handler.HandleAnthropicRequest(rr, req)
+	return rr, time.Since(start)
+}
+
+// TestRequestDeadline tests that X-Proxy-Deadline-Ms bounds the upstream call
+// and reports how far the pipeline got
+func TestRequestDeadline(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		waitForCancel(r)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer upstream.Close()
+
+	cfg := config.GetDefaultConfig()
+	cfg.BigModel = "test-model"
+	cfg.BigModelEndpoints = []string{upstream.URL}
+	cfg.BigModelAPIKey = "test-key"
+	cfg.ToolCorrectionEnabled = false
+	handler := proxy.NewHandler(cfg, nil, "")
+
+	rr, elapsed := sendWithDeadline(t, handler, "100")
+	assert.Less(t, elapsed, 3*time.Second, "the deadline must stop the upstream call")
+	require.Equal(t, http.StatusGatewayTimeout, rr.Code, rr.Body.String())
+
+	var resp deadlineErrorResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, "api_error", resp.Error.Type)
+	assert.Equal(t, "DEADLINE_EXCEEDED", resp.Error.Code)
+	assert.Equal(t, int64(100), resp.Accounting.DeadlineMs)
+	assert.GreaterOrEqual(t, resp.Accounting.ElapsedMs, int64(100))
+	assert.Equal(t, "upstream", resp.Accounting.Stage)
+	assert.Equal(t, []string{"request_transform"}, resp.Accounting.Completed)
+	assert.Nil(t, resp.Accounting.Usage, "no upstream response means no usage")
+}
+
+// TestRequestDeadlineDuringCorrection tests that a deadline passing during
+// tool correction returns the error with the tokens already used upstream
+func TestRequestDeadlineDuringCorrection(t *testing.T) {
+	slowCorrector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "tool filtering") {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"choices": []map[string]interface{}{{"message": map[string]interface{}{"role": "assistant", "content": "KEEP"}}},
+			})
+			return
+		}
+		waitForCancel(r)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer slowCorrector.Close()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":    "chatcmpl-deadline",
+			"model": "test-model",
+			"choices": []map[string]interface{}{{
+				"index": 0,
+				"message": map[string]interface{}{
+					"role": "assistant",
+					"tool_calls": []map[string]interface{}{{
+						"id":       "call_1",
+						"type":     "function",
+						"function": map[string]interface{}{"name": "Read", "arguments": `{"unrelated":"x"}`},
+					}},
+				},
+				"finish_reason": "tool_calls",
+			}},
+			"usage": map[string]interface{}{"prompt_tokens": 42, "completion_tokens": 7, "total_tokens": 49},
+		})
+	}))
+	defer upstream.Close()
+
+	cfg := config.GetDefaultConfig()
+	cfg.BigModel = "test-model"
+	cfg.BigModelEndpoints = []string{upstream.URL}
+	cfg.BigModelAPIKey = "test-key"
+	cfg.CorrectionModel = "test-model"
+	cfg.ToolCorrectionEndpoints = []string{slowCorrector.URL}
+	cfg.ToolCorrectionAPIKey = "test-key"
+	handler := proxy.NewHandler(cfg, nil, "")
+
+	rr, elapsed := sendWithDeadline(t, handler, "300")
+	assert.Less(t, elapsed, 3*time.Second, "the deadline must stop tool correction")
+	require.Equal(t, http.StatusGatewayTimeout, rr.Code, rr.Body.String())
+
+	var resp deadlineErrorResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, "DEADLINE_EXCEEDED", resp.Error.Code)
+	assert.Equal(t, "correction", resp.Accounting.Stage)
+	assert.Equal(t, []string{"request_transform", "upstream"}, resp.Accounting.Completed)
+	require.NotNil(t, resp.Accounting.Usage)
+	assert.Equal(t, 42, resp.Accounting.Usage.InputTokens)
+	assert.Equal(t, 7, resp.Accounting.Usage.OutputTokens)
+}
+
+// TestRequestDeadlineInvalid tests that a malformed deadline is rejected
+// before any work is done
+func TestRequestDeadlineInvalid(t *testing.T) {
+	handler := proxy.NewHandler(config.GetDefaultConfig(), nil, "")
+	for _, value := range []string{"soon", "0", "-5"} {
+		rr, _ := sendWithDeadline(t, handler, value)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, value)
+		assert.Contains(t, rr.Body.String(), "X-Proxy-Deadline-Ms must be a positive number of milliseconds", value)
+	}
+
+	// Values a time.Duration cannot hold would overflow into an expired deadline
+	for _, value := range []string{"9223372036855", "9223372036854775807"} {
+		rr, _ := sendWithDeadline(t, handler, value)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, value)
+		assert.Contains(t, rr.Body.String(), "X-Proxy-Deadline-Ms must be at most 9223372036854 milliseconds", value)
+	}
+}
@@ -0,0 +1,78 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"claude-proxy/config"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDumpDiagnostics tests that the SIGUSR1 dump appended to
+// DIAGNOSTIC_DUMP_FILE lists a request stuck upstream, the queues and the
+// goroutine stacks
+func TestDumpDiagnostics(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(types.OpenAIResponse{
+			ID:    "chatcmpl-diagnostics",
+			Model: "backend-model",
+			Choices: []types.OpenAIChoice{{
+				Message:      types.OpenAIMessage{Role: "assistant", Content: "Done"},
+				FinishReason: stringPtr("stop"),
+			}},
+		})
+	}))
+	defer server.Close()
+
+	cfg := config.GetDefaultConfig()
+	cfg.BigModel = "backend-model"
+	cfg.BigModelEndpoints = []string{server.URL}
+	cfg.DiagnosticDumpFile = filepath.Join(t.TempDir(), "diagnostics.log")
+	handler := proxy.NewHandler(cfg, nil, "")
+
+	body, _ := json.Marshal(types.AnthropicRequest{
+		Model:     "claude-sonnet-4-20250514",
+		MaxTokens: 100,
+		Messages:  []types.Message{{Role: "user", Content: "Hello"}},
+	})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		req := httptest.NewRequest(http.MethodPost, "/v1/messages", bytes.NewReader(body))
+		req.Header.Set("X-Request-ID", "req-stuck")
+		handler.HandleAnthropicRequest(httptest.NewRecorder(), req)
+	}()
+	require.Eventually(t, func() bool { return len(handler.InFlight()) == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, handler.DumpDiagnostics())
+	close(release)
+	<-done
+	require.NoError(t, handler.DumpDiagnostics())
+
+	data, err := os.ReadFile(cfg.DiagnosticDumpFile)
+	require.NoError(t, err)
+	dump := string(data)
+
+	assert.Equal(t, 2, strings.Count(dump, "=== simple-proxy diagnostic dump"), "each signal should append a dump")
+	first := dump[:strings.LastIndex(dump, "=== simple-proxy diagnostic dump")]
+	assert.Contains(t, first, "--- in-flight requests (1) ---")
+	assert.Contains(t, first, "req-stuck model=claude-sonnet-4-20250514 stream=false")
+	assert.Contains(t, first, "scheduler: active=0 limit=0")
+	assert.Contains(t, first, "pending approvals: 0")
+	assert.Contains(t, first, "goroutine ")
+	assert.Contains(t, first, "TestDumpDiagnostics", "the stacks of every goroutine should be included")
+}
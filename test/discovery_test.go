@@ -0,0 +1,148 @@
+package test
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/discovery"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLookup serves DNS answers from maps that tests can change between refreshes
+type fakeLookup struct {
+	mu    sync.Mutex
+	hosts map[string][]string
+	srv   map[string][]*net.SRV
+}
+
+func (f *fakeLookup) LookupHost(ctx context.Context, host string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if addresses, exists := f.hosts[host]; exists {
+		return addresses, nil
+	}
+	return nil, fmt.Errorf("no such host %s", host)
+}
+
+func (f *fakeLookup) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if records, exists := f.srv[name]; exists {
+		return name, records, nil
+	}
+	return "", nil, fmt.Errorf("no SRV records for %s", name)
+}
+
+func (f *fakeLookup) setHost(host string, addresses ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.hosts[host] = addresses
+}
+
+// TestDiscoveryExpand tests dns+ and srv+ expansion into one endpoint per address
+func TestDiscoveryExpand(t *testing.T) {
+	lookup := &fakeLookup{
+		hosts: map[string][]string{"llm.internal": {"10.0.0.1", "fd00::2"}},
+		srv: map[string][]*net.SRV{"_llm._tcp.internal": {
+			{Target: "gpu-a.internal.", Port: 8000},
+			{Target: "gpu-b.internal.", Port: 8001},
+		}},
+	}
+	ctx := context.Background()
+
+	endpoints, err := discovery.Expand(ctx, lookup, "dns+http://llm.internal:8000/v1/chat/completions")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"http://10.0.0.1:8000/v1/chat/completions",
+		"http://[fd00::2]:8000/v1/chat/completions",
+	}, endpoints)
+
+	endpoints, err = discovery.Expand(ctx, lookup, "srv+https://_llm._tcp.internal/v1/chat/completions")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"https://gpu-a.internal:8000/v1/chat/completions",
+		"https://gpu-b.internal:8001/v1/chat/completions",
+	}, endpoints)
+
+	endpoints, err = discovery.Expand(ctx, lookup, "http://static:8000/v1/chat/completions")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"http://static:8000/v1/chat/completions"}, endpoints)
+
+	_, err = discovery.Expand(ctx, lookup, "dns+http://unknown.internal/v1")
+	assert.Error(t, err)
+}
+
+// TestDiscoveryRefresh tests that re-resolution replaces a role's endpoints,
+// gives new addresses their own circuit breaker state and keeps the previous
+// endpoints when resolution fails
+func TestDiscoveryRefresh(t *testing.T) {
+	env := `BIG_MODEL=big-model
+BIG_MODEL_ENDPOINT=http://big:8080/v1
+BIG_MODEL_API_KEY=big-key
+SMALL_MODEL=small-model
+SMALL_MODEL_ENDPOINT=dns+http://small.internal:8080/v1/chat/completions,http://static:8080/v1/chat/completions
+SMALL_MODEL_API_KEY=small-key
+CORRECTION_MODEL=correction-model
+TOOL_CORRECTION_ENDPOINT=http://correction:8080/v1
+TOOL_CORRECTION_API_KEY=correction-key
+LOG_FULL_TOOLS=false
+CONVERSATION_TRUNCATION=false
+ENDPOINT_RESOLVE_INTERVAL_SECONDS=5
+`
+	tempDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	require.NoError(t, os.Chdir(tempDir))
+	defer os.Chdir(originalWd)
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, ".env"), []byte(env), 0644))
+
+	cfg, err := config.LoadConfigWithEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 5, cfg.EndpointResolveIntervalSeconds)
+	require.Contains(t, cfg.EndpointSpecs, config.EndpointRoleSmall)
+	assert.NotContains(t, cfg.EndpointSpecs, config.EndpointRoleBig)
+
+	lookup := &fakeLookup{hosts: map[string][]string{"small.internal": {"10.0.0.1"}}}
+	resolver := discovery.NewResolver(cfg, lookup)
+
+	changed, err := resolver.Refresh(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{config.EndpointRoleSmall}, changed)
+	assert.ElementsMatch(t, []string{
+		"http://10.0.0.1:8080/v1/chat/completions",
+		"http://static:8080/v1/chat/completions",
+	}, cfg.Endpoints(config.EndpointRoleSmall))
+
+	// Trip the breaker of the first address, then move the backend
+	cfg.RecordEndpointFailure("http://10.0.0.1:8080/v1/chat/completions")
+	cfg.RecordEndpointFailure("http://10.0.0.1:8080/v1/chat/completions")
+	assert.False(t, cfg.IsEndpointHealthy("http://10.0.0.1:8080/v1/chat/completions"))
+
+	lookup.setHost("small.internal", "10.0.0.2", "10.0.0.3")
+	changed, err = resolver.Refresh(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{config.EndpointRoleSmall}, changed)
+	assert.ElementsMatch(t, []string{
+		"http://10.0.0.2:8080/v1/chat/completions",
+		"http://10.0.0.3:8080/v1/chat/completions",
+		"http://static:8080/v1/chat/completions",
+	}, cfg.Endpoints(config.EndpointRoleSmall))
+	assert.True(t, cfg.IsEndpointHealthy("http://10.0.0.2:8080/v1/chat/completions"))
+
+	// Unchanged answers report no change
+	changed, err = resolver.Refresh(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+
+	// A failed lookup keeps the last resolved endpoints
+	lookup.setHost("small.internal")
+	_, err = resolver.Refresh(context.Background())
+	assert.Error(t, err)
+	assert.Len(t, cfg.Endpoints(config.EndpointRoleSmall), 3)
+}
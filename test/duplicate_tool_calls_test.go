@@ -0,0 +1,81 @@
+package test
+
+import (
+	"bytes"
+	"claude-proxy/config"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDuplicateToolCallPolicy tests the drop, warn and keep policies against
+// an upstream that repeats a tool call with its arguments in another key order
+func TestDuplicateToolCallPolicy(t *testing.T) {
+	tests := []struct {
+		policy        string
+		expectedCalls int
+	}{
+		{policy: config.DuplicateToolCallDrop, expectedCalls: 2},
+		{policy: config.DuplicateToolCallWarn, expectedCalls: 3},
+		{policy: config.DuplicateToolCallKeep, expectedCalls: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(types.OpenAIResponse{
+					ID: "chatcmpl-duplicate",
+					Choices: []types.OpenAIChoice{{
+						Index: 0,
+						Message: types.OpenAIMessage{Role: "assistant", ToolCalls: []types.OpenAIToolCall{
+							{ID: "call_1", Type: "function", Function: types.OpenAIToolCallFunction{Name: "Read", Arguments: `{"file_path":"/tmp/a.go","limit":10}`}},
+							{ID: "call_2", Type: "function", Function: types.OpenAIToolCallFunction{Name: "Read", Arguments: `{"limit":10,"file_path":"/tmp/a.go"}`}},
+							{ID: "call_3", Type: "function", Function: types.OpenAIToolCallFunction{Name: "Read", Arguments: `{"file_path":"/tmp/b.go","limit":10}`}},
+						}},
+						FinishReason: stringPtr("tool_calls"),
+					}},
+				})
+			}))
+			defer mockServer.Close()
+
+			cfg := config.GetDefaultConfig()
+			cfg.BigModel = "test-model"
+			cfg.BigModelEndpoints = []string{mockServer.URL}
+			cfg.BigModelAPIKey = "test-key"
+			cfg.DuplicateToolCallPolicy = tt.policy
+			handler := proxy.NewHandler(cfg, nil, "")
+
+			reqJSON, _ := json.Marshal(map[string]interface{}{
+				"model":      "claude-sonnet-4-20250514",
+				"max_tokens": 100,
+				"messages":   []map[string]interface{}{{"role": "user", "content": "Read both files"}},
+			})
+			req := httptest.NewRequest("POST", "/v1/messages", bytes.NewReader(reqJSON))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+
+			handler.HandleAnthropicRequest(rr, req)
+			require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+			var resp types.AnthropicResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			var ids []string
+			for _, item := range resp.Content {
+				if item.Type == "tool_use" {
+					ids = append(ids, item.ID)
+				}
+			}
+			assert.Len(t, ids, tt.expectedCalls)
+			assert.Equal(t, "call_1", ids[0])
+			assert.Equal(t, "call_3", ids[len(ids)-1])
+			assert.Equal(t, "tool_use", resp.StopReason)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package test
+
+import (
+	"claude-proxy/circuitbreaker"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEndpointEWMAScore tests that scores follow recent outcomes and latency
+func TestEndpointEWMAScore(t *testing.T) {
+	hm := circuitbreaker.NewHealthManager(circuitbreaker.DefaultConfig())
+	endpoint := "http://a:8080/v1/chat/completions"
+	hm.InitializeEndpoints([]string{endpoint})
+
+	assert.Equal(t, 0.5, hm.Score(endpoint), "new endpoints score neutral")
+
+	// A long success history is outweighed by a few recent failures
+	for i := 0; i < 50; i++ {
+		hm.RecordSuccess(endpoint)
+	}
+	assert.InDelta(t, 1.0, hm.CalculateSuccessRate(endpoint), 0.0001)
+	for i := 0; i < 3; i++ {
+		hm.RecordFailure(endpoint)
+	}
+	assert.InDelta(t, 0.512, hm.CalculateSuccessRate(endpoint), 0.0001) // 0.8^3
+
+	// Latency at the reference halves the score
+	fresh := "http://b:8080/v1/chat/completions"
+	hm.RecordSuccessWithLatency(fresh, 5*time.Second)
+	assert.InDelta(t, 0.5, hm.Score(fresh), 0.0001)
+	hm.RecordSuccessWithLatency(fresh, 0) // Unmeasured latency keeps the average
+	assert.InDelta(t, 0.5, hm.Score(fresh), 0.0001)
+
+	snapshot := hm.Snapshot()
+	require.Len(t, snapshot, 2)
+	assert.Equal(t, endpoint, snapshot[0].URL)
+	assert.InDelta(t, 0.512, snapshot[0].Score, 0.0001)
+	assert.InDelta(t, 5000, snapshot[1].LatencyEWMAMs, 0.0001)
+	assert.InDelta(t, 0.5, snapshot[1].Score, 0.0001)
+}
+
+// TestReorderByScore tests that faster endpoints move ahead of slower ones
+// with the same success rate, and healthy ones ahead of open circuits
+func TestReorderByScore(t *testing.T) {
+	hm := circuitbreaker.NewHealthManager(circuitbreaker.DefaultConfig())
+	slow := "http://slow:8080/v1/chat/completions"
+	fast := "http://fast:8080/v1/chat/completions"
+	broken := "http://broken:8080/v1/chat/completions"
+	endpoints := []string{broken, slow, fast}
+	hm.InitializeEndpoints(endpoints)
+
+	for i := 0; i < 5; i++ {
+		hm.RecordSuccessWithLatency(slow, 8*time.Second)
+		hm.RecordSuccessWithLatency(fast, 500*time.Millisecond)
+	}
+	hm.RecordFailure(broken)
+	hm.RecordFailure(broken)
+	require.False(t, hm.IsHealthy(broken))
+
+	assert.True(t, hm.ReorderBySuccess(endpoints, "SmallModel"))
+	assert.Equal(t, []string{fast, slow, broken}, endpoints)
+
+	// Reordering waits for the reorder interval
+	hm.RecordFailure(fast)
+	assert.False(t, hm.ReorderBySuccess(endpoints, "SmallModel"))
+}
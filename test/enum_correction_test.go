@@ -0,0 +1,134 @@
+package test
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/correction"
+	"claude-proxy/internal"
+	"claude-proxy/types"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNearestEnumValue tests which near-misses are mapped to an allowed value
+func TestNearestEnumValue(t *testing.T) {
+	statuses := []interface{}{"pending", "in_progress", "completed"}
+	modes := []interface{}{"content", "files_with_matches", "count"}
+
+	tests := []struct {
+		value    string
+		enum     []interface{}
+		expected string
+		found    bool
+	}{
+		{"in-progress", statuses, "in_progress", true},
+		{"In Progress", statuses, "in_progress", true},
+		{"inprogress", statuses, "in_progress", true},
+		{"done", statuses, "completed", true},
+		{"complete", statuses, "completed", true},
+		{"todo", statuses, "pending", true},
+		{"pendng", statuses, "pending", true},
+		{"files-with-matches", modes, "files_with_matches", true},
+		{"files_with_match", modes, "files_with_matches", true},
+		{"counts", modes, "count", true},
+		{"blocked", statuses, "", false},
+		{"done", modes, "", false},
+		{"", statuses, "", false},
+	}
+	for _, tt := range tests {
+		nearest, found := types.NearestEnumValue(tt.value, tt.enum)
+		assert.Equal(t, tt.found, found, tt.value)
+		assert.Equal(t, tt.expected, nearest, tt.value)
+	}
+
+	_, found := types.NearestEnumValue("cat", []interface{}{"bat", "cap"})
+	assert.False(t, found, "equally close values are ambiguous")
+}
+
+// TestValidatorFlagsEnumViolations tests that values outside a parameter's
+// enum, or an array's item enum, are invalid parameters
+func TestValidatorFlagsEnumViolations(t *testing.T) {
+	validator := types.NewStandardToolValidator()
+	schema := types.ToolSchema{
+		Type: "object",
+		Properties: map[string]types.ToolProperty{
+			"output_mode": {Type: "string", Enum: []interface{}{"content", "files_with_matches", "count"}},
+			"levels":      {Type: "array", Items: &types.ToolPropertyItems{Type: "string", Enum: []interface{}{"low", "high"}}},
+		},
+	}
+
+	valid := validator.ValidateParameters(context.Background(), types.Content{Name: "Probe", Input: map[string]interface{}{
+		"output_mode": "count",
+		"levels":      []interface{}{"low", "high"},
+	}}, schema)
+	assert.True(t, valid.IsValid)
+
+	call := types.Content{Name: "Probe", Input: map[string]interface{}{
+		"output_mode": "files-with-matches",
+		"levels":      []interface{}{"low", "hig"},
+	}}
+	invalid := validator.ValidateParameters(context.Background(), call, schema)
+	assert.ElementsMatch(t, []string{"output_mode", "levels"}, invalid.InvalidParams)
+
+	corrected, params := validator.CorrectEnums(call, schema)
+	assert.Equal(t, []string{"levels", "output_mode"}, params)
+	assert.Equal(t, "files_with_matches", corrected.Input["output_mode"])
+	assert.Equal(t, []interface{}{"low", "high"}, corrected.Input["levels"])
+	assert.Equal(t, []interface{}{"low", "hig"}, call.Input["levels"], "the original call is left unchanged")
+	assert.True(t, validator.ValidateParameters(context.Background(), corrected, schema).IsValid)
+}
+
+// TestEnumCorrectionSkipsLLM tests that near-miss enum values are corrected
+// without the correction model, including TodoWrite item statuses
+func TestEnumCorrectionSkipsLLM(t *testing.T) {
+	var llmCalls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&llmCalls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cfg := config.GetDefaultConfig()
+	cfg.ToolCorrectionEndpoints = []string{server.URL}
+	cfg.ToolCorrectionAPIKey = "test-key"
+	service := correction.NewService(cfg, "test-key", true, "test-model", true, nil)
+	ctx := internal.WithRequestID(context.Background(), "enum_correction_test")
+	grep := types.GetFallbackToolSchema("Grep")
+	todoWrite := types.GetFallbackToolSchema("TodoWrite")
+	require.NotNil(t, grep)
+	require.NotNil(t, todoWrite)
+
+	corrected, err := service.CorrectToolCalls(ctx, []types.Content{
+		{
+			Type:  "tool_use",
+			ID:    "toolu_grep",
+			Name:  "Grep",
+			Input: map[string]interface{}{"pattern": "TODO", "output_mode": "files-with-matches"},
+		},
+		{
+			Type: "tool_use",
+			ID:   "toolu_todo",
+			Name: "TodoWrite",
+			Input: map[string]interface{}{"todos": []interface{}{
+				map[string]interface{}{"id": "1", "content": "Write tests", "status": "done", "priority": "high"},
+				map[string]interface{}{"id": "2", "content": "Ship it", "status": "in-progress", "priority": "normal"},
+			}},
+		},
+	}, []types.Tool{*grep, *todoWrite})
+	require.NoError(t, err)
+	require.Len(t, corrected, 2)
+	assert.Equal(t, "files_with_matches", corrected[0].Input["output_mode"])
+
+	todos, ok := corrected[1].Input["todos"].([]interface{})
+	require.True(t, ok)
+	require.Len(t, todos, 2)
+	assert.Equal(t, "completed", todos[0].(map[string]interface{})["status"])
+	assert.Equal(t, "in_progress", todos[1].(map[string]interface{})["status"])
+	assert.Equal(t, "medium", todos[1].(map[string]interface{})["priority"])
+	assert.Equal(t, int32(0), atomic.LoadInt32(&llmCalls), "near-miss enum values must not need the correction model")
+}
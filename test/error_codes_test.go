@@ -0,0 +1,120 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"claude-proxy/circuitbreaker"
+	"claude-proxy/config"
+	"claude-proxy/proxy"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// proxyErrorBody is the Anthropic-style error envelope returned by the proxy
+type proxyErrorBody struct {
+	Type  string `json:"type"`
+	Error struct {
+		Type    string `json:"type"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func newErrorCodeTestConfig(smallEndpoints ...string) *config.Config {
+	return &config.Config{
+		BigModelEndpoints:        []string{"http://127.0.0.1:1"},
+		BigModelAPIKey:           "test-key",
+		BigModel:                 "kimi-k2",
+		SmallModelEndpoints:      smallEndpoints,
+		SmallModelAPIKey:         "test-key",
+		SmallModel:               "qwen2.5-coder:latest",
+		SkipTools:                []string{},
+		DefaultConnectionTimeout: 5,
+		HealthManager: circuitbreaker.NewHealthManager(circuitbreaker.Config{
+			FailureThreshold:   1,
+			BackoffDuration:    time.Minute,
+			MaxBackoffDuration: time.Minute,
+			ResetTimeout:       time.Minute,
+		}),
+	}
+}
+
+func doErrorCodeRequest(t *testing.T, handler *proxy.Handler, ctx context.Context, model, body string) (int, proxyErrorBody) {
+	t.Helper()
+	if body == "" {
+		body = `{"model": "` + model + `", "max_tokens": 100, "messages": [{"role": "user", "content": "hi"}]}`
+	}
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(body)).WithContext(ctx)
+	rr := httptest.NewRecorder()
+	handler.HandleAnthropicRequest(rr, req)
+
+	var resp proxyErrorBody
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp), "error body should be JSON: %s", rr.Body.String())
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	return rr.Code, resp
+}
+
+// TestProxyErrorCodes tests that each failure class surfaces its stable error code
+func TestProxyErrorCodes(t *testing.T) {
+	t.Run("InvalidRequest", func(t *testing.T) {
+		handler := proxy.NewHandler(newErrorCodeTestConfig("http://127.0.0.1:1"), nil, "")
+		status, resp := doErrorCodeRequest(t, handler, context.Background(), "", "{not json")
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, "error", resp.Type)
+		assert.Equal(t, "invalid_request_error", resp.Error.Type)
+		assert.Equal(t, string(proxy.CodeInvalidRequest), resp.Error.Code)
+	})
+
+	t.Run("UpstreamErrorStatus", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "boom", http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		cfg := newErrorCodeTestConfig("http://127.0.0.1:1")
+		cfg.BigModelEndpoints = []string{server.URL}
+		handler := proxy.NewHandler(cfg, nil, "")
+		status, resp := doErrorCodeRequest(t, handler, context.Background(), "claude-3-5-sonnet-20241022", "")
+		assert.Equal(t, http.StatusBadGateway, status)
+		assert.Equal(t, "api_error", resp.Error.Type)
+		assert.Equal(t, string(proxy.CodeUpstreamStatus), resp.Error.Code)
+	})
+
+	t.Run("UpstreamTimeout", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(500 * time.Millisecond):
+			}
+		}))
+		defer server.Close()
+
+		cfg := newErrorCodeTestConfig("http://127.0.0.1:1")
+		cfg.BigModelEndpoints = []string{server.URL}
+		handler := proxy.NewHandler(cfg, nil, "")
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		status, resp := doErrorCodeRequest(t, handler, ctx, "claude-3-5-sonnet-20241022", "")
+		assert.Equal(t, http.StatusBadGateway, status)
+		assert.Equal(t, string(proxy.CodeUpstreamTimeout), resp.Error.Code)
+	})
+
+	t.Run("AllEndpointsDown", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		handler := proxy.NewHandler(newErrorCodeTestConfig(server.URL, server.URL+"/v2"), nil, "")
+		status, resp := doErrorCodeRequest(t, handler, context.Background(), "claude-3-5-haiku-20241022", "")
+		assert.Equal(t, http.StatusBadGateway, status)
+		assert.Equal(t, string(proxy.CodeAllEndpointsDown), resp.Error.Code)
+	})
+}
@@ -0,0 +1,121 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"claude-proxy/config"
+	"claude-proxy/internal"
+	"claude-proxy/loop"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHarmonyDeveloperRole tests that HARMONY_DEVELOPER_ROLE sends the system
+// prompt and inlined tool definitions to listed Harmony models as a single
+// developer message, and leaves other models alone
+func TestHarmonyDeveloperRole(t *testing.T) {
+	no := false
+	cfg := config.GetDefaultConfig()
+	cfg.HarmonyModels = []string{"gpt-oss*"}
+	cfg.HarmonyDeveloperRole = true
+	cfg.ModelProfiles = map[string]config.ModelProfile{
+		"gpt-oss:20b-notools": {Capabilities: config.ModelCapabilities{Tools: &no}},
+	}
+	ctx := internal.WithRequestID(context.Background(), "harmony_developer_role_test")
+
+	request := func(model string) types.AnthropicRequest {
+		return types.AnthropicRequest{
+			Model:     model,
+			MaxTokens: 100,
+			System: []types.SystemContent{
+				{Type: "text", Text: "You are a coding assistant."},
+				{Type: "text", Text: "Answer briefly."},
+			},
+			Messages: []types.Message{{Role: "user", Content: "Read main.go"}},
+			Tools:    backendTestTools(),
+		}
+	}
+
+	openaiReq, err := proxy.TransformAnthropicToOpenAI(ctx, request("gpt-oss:20b"), cfg)
+	require.NoError(t, err)
+	require.Len(t, openaiReq.Messages, 2)
+	assert.Equal(t, "developer", openaiReq.Messages[0].Role)
+	assert.Contains(t, openaiReq.Messages[0].Content, "You are a coding assistant.")
+	assert.Equal(t, "user", openaiReq.Messages[1].Role)
+	assert.NotEmpty(t, openaiReq.Tools, "native tools stay in the tools field")
+
+	inlined, err := proxy.TransformAnthropicToOpenAI(ctx, request("gpt-oss:20b-notools"), cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "developer", inlined.Messages[0].Role)
+	assert.Contains(t, inlined.Messages[0].Content, "## Read")
+	assert.Empty(t, inlined.Tools)
+
+	other, err := proxy.TransformAnthropicToOpenAI(ctx, request("qwen3:32b"), cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "system", other.Messages[0].Role)
+
+	cfg.HarmonyDeveloperRole = false
+	disabled, err := proxy.TransformAnthropicToOpenAI(ctx, request("gpt-oss:20b"), cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "system", disabled.Messages[0].Role)
+}
+
+// TestLoopNudgeDeveloperMessage tests that the loop warning joins a leading
+// developer message instead of adding a system message before it
+func TestLoopNudgeDeveloperMessage(t *testing.T) {
+	messages := []types.OpenAIMessage{
+		{Role: "developer", Content: "You are a coding assistant."},
+		{Role: "user", Content: "Read main.go"},
+	}
+	nudged := loop.Nudge(messages, &loop.LoopDetection{Recommendation: "Stop reading main.go."})
+	require.Len(t, nudged, 2)
+	assert.Equal(t, "developer", nudged[0].Role)
+	assert.Contains(t, nudged[0].Content, "[Loop Guard]")
+}
+
+// TestHarmonySystemPreamble tests that HARMONY_SYSTEM_PREAMBLE gives listed
+// Harmony models the system message gpt-oss expects, ahead of the developer
+// message when both are enabled
+func TestHarmonySystemPreamble(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.HarmonyModels = []string{"gpt-oss*"}
+	cfg.HarmonySystemPreamble = true
+	cfg.HarmonyReasoningLevel = config.HarmonyReasoningHigh
+	ctx := internal.WithRequestID(context.Background(), "harmony_preamble_test")
+
+	req := types.AnthropicRequest{
+		Model:     "gpt-oss:120b",
+		MaxTokens: 100,
+		System:    []types.SystemContent{{Type: "text", Text: "You are a coding assistant."}},
+		Messages:  []types.Message{{Role: "user", Content: "Read main.go"}},
+		Tools:     backendTestTools(),
+	}
+
+	openaiReq, err := proxy.TransformAnthropicToOpenAI(ctx, req, cfg)
+	require.NoError(t, err)
+	system := openaiReq.Messages[0]
+	assert.Equal(t, "system", system.Role)
+	assert.Contains(t, system.Content, "Knowledge cutoff: 2024-06\nCurrent date: ")
+	assert.Contains(t, system.Content, "Reasoning: high")
+	assert.Contains(t, system.Content, "# Valid channels: analysis, commentary, final.")
+	assert.Contains(t, system.Content, "commentary channel: 'functions'")
+	assert.Contains(t, system.Content, "\n\nYou are a coding assistant.")
+
+	cfg.HarmonyDeveloperRole = true
+	split, err := proxy.TransformAnthropicToOpenAI(ctx, req, cfg)
+	require.NoError(t, err)
+	require.Len(t, split.Messages, 3)
+	assert.Equal(t, "system", split.Messages[0].Role)
+	assert.NotContains(t, split.Messages[0].Content, "coding assistant")
+	assert.Equal(t, "developer", split.Messages[1].Role)
+	assert.Equal(t, "You are a coding assistant.", split.Messages[1].Content)
+
+	req.Model = "qwen3:32b"
+	other, err := proxy.TransformAnthropicToOpenAI(ctx, req, cfg)
+	require.NoError(t, err)
+	assert.NotContains(t, other.Messages[0].Content, "Valid channels")
+}
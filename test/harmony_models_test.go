@@ -0,0 +1,77 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"claude-proxy/config"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHarmonyModelsRestrictParsing tests that with HARMONY_MODELS set only
+// matching backend models have their responses parsed for Harmony channels
+func TestHarmonyModelsRestrictParsing(t *testing.T) {
+	content := "<|start|>assistant<|channel|>analysis<|message|>Thinking<|end|><|start|>assistant<|channel|>final<|message|>Answer<|return|>"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req types.OpenAIRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(types.OpenAIResponse{
+			ID:    "chatcmpl-harmony",
+			Model: req.Model,
+			Choices: []types.OpenAIChoice{{
+				Message:      types.OpenAIMessage{Role: "assistant", Content: content},
+				FinishReason: stringPtr("stop"),
+			}},
+		})
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		model   string
+		harmony bool
+	}{
+		{model: "gpt-oss:20b", harmony: true},
+		{model: "qwen3:32b", harmony: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			cfg := config.GetDefaultConfig()
+			cfg.BigModel = tt.model
+			cfg.BigModelEndpoints = []string{server.URL}
+			cfg.BigModelAPIKey = "test-key"
+			cfg.HarmonyModels = []string{"gpt-oss*"}
+			handler := proxy.NewHandler(cfg, nil, "")
+
+			body, _ := json.Marshal(types.AnthropicRequest{
+				Model:     "claude-sonnet-4-20250514",
+				MaxTokens: 100,
+				Messages:  []types.Message{{Role: "user", Content: "Hello"}},
+			})
+			req := httptest.NewRequest("POST", "/v1/messages", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+			handler.HandleAnthropicRequest(rr, req)
+			require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+			var resp types.AnthropicResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			if tt.harmony {
+				require.Len(t, resp.Content, 2)
+				assert.Equal(t, "thinking", resp.Content[0].Type)
+				assert.Equal(t, "Answer", resp.Content[1].Text)
+				return
+			}
+			require.Len(t, resp.Content, 1)
+			assert.Equal(t, content, resp.Content[0].Text)
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package test
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/discovery"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEndpointSlices serves an EndpointSliceList that tests can change between refreshes
+type fakeEndpointSlices struct {
+	mu        sync.Mutex
+	body      string
+	selectors []string
+}
+
+func (f *fakeEndpointSlices) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.URL.Path != "/apis/discovery.k8s.io/v1/namespaces/llm/endpointslices" {
+		http.NotFound(w, r)
+		return
+	}
+	f.selectors = append(f.selectors, r.URL.Query().Get("labelSelector"))
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(f.body))
+}
+
+func (f *fakeEndpointSlices) set(body string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.body = body
+}
+
+// TestParseKubernetesTarget tests the Service and label selector forms of k8s+ specs
+func TestParseKubernetesTarget(t *testing.T) {
+	target, err := discovery.ParseKubernetesTarget("k8s+http://vllm.llm:8000/v1/chat/completions")
+	require.NoError(t, err)
+	assert.Equal(t, "llm", target.Namespace)
+	assert.Equal(t, "kubernetes.io/service-name=vllm", target.LabelSelector)
+	assert.Equal(t, 8000, target.Port)
+
+	target, err = discovery.ParseKubernetesTarget("k8s+http://llm/v1/chat/completions?selector=app=vllm,tier=gpu")
+	require.NoError(t, err)
+	assert.Equal(t, "llm", target.Namespace)
+	assert.Equal(t, "app=vllm,tier=gpu", target.LabelSelector)
+	assert.Equal(t, 0, target.Port)
+
+	_, err = discovery.ParseKubernetesTarget("k8s+http://vllm:8000/v1/chat/completions")
+	assert.Error(t, err, "a Service needs a namespace")
+}
+
+// TestKubernetesDiscovery tests that k8s+ specs follow the ready pods of an
+// EndpointSlice and that new pods start with closed circuit breakers
+func TestKubernetesDiscovery(t *testing.T) {
+	slices := &fakeEndpointSlices{body: `{
+  "metadata": {"resourceVersion": "100"},
+  "items": [{
+    "endpoints": [
+      {"addresses": ["10.1.0.1"], "conditions": {"ready": true}},
+      {"addresses": ["10.1.0.2"], "conditions": {"ready": false}},
+      {"addresses": ["10.1.0.3"], "conditions": {}}
+    ],
+    "ports": [{"port": 8000}]
+  }]
+}`}
+	server := httptest.NewServer(slices)
+	defer server.Close()
+
+	env := `BIG_MODEL=big-model
+BIG_MODEL_ENDPOINT=k8s+http://llm/v1/chat/completions?selector=app=vllm
+BIG_MODEL_API_KEY=big-key
+SMALL_MODEL=small-model
+SMALL_MODEL_ENDPOINT=http://small:8080/v1
+SMALL_MODEL_API_KEY=small-key
+CORRECTION_MODEL=correction-model
+TOOL_CORRECTION_ENDPOINT=http://correction:8080/v1
+TOOL_CORRECTION_API_KEY=correction-key
+LOG_FULL_TOOLS=false
+CONVERSATION_TRUNCATION=false
+KUBERNETES_API_URL=` + server.URL + `
+`
+	tempDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	require.NoError(t, os.Chdir(tempDir))
+	defer os.Chdir(originalWd)
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, ".env"), []byte(env), 0644))
+
+	cfg, err := config.LoadConfigWithEnv()
+	require.NoError(t, err)
+	assert.True(t, cfg.UsesKubernetesDiscovery())
+	assert.Equal(t, server.URL, cfg.KubernetesAPIURL)
+
+	resolver := discovery.NewResolver(cfg, nil)
+
+	// Without a client k8s+ specs cannot be resolved
+	_, err = resolver.Refresh(context.Background())
+	assert.Error(t, err)
+
+	kube, err := discovery.NewKubernetesClient(cfg.KubernetesAPIURL)
+	require.NoError(t, err)
+	resolver.SetKubernetesClient(kube)
+
+	changed, err := resolver.Refresh(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{config.EndpointRoleBig}, changed)
+	assert.ElementsMatch(t, []string{
+		"http://10.1.0.1:8000/v1/chat/completions",
+		"http://10.1.0.3:8000/v1/chat/completions",
+	}, cfg.Endpoints(config.EndpointRoleBig))
+	assert.Contains(t, slices.selectors, "app=vllm")
+
+	// Trip a breaker, then scale up: the new pod starts healthy
+	cfg.RecordEndpointFailure("http://10.1.0.1:8000/v1/chat/completions")
+	cfg.RecordEndpointFailure("http://10.1.0.1:8000/v1/chat/completions")
+	assert.False(t, cfg.IsEndpointHealthy("http://10.1.0.1:8000/v1/chat/completions"))
+
+	slices.set(`{"metadata": {"resourceVersion": "101"}, "items": [{
+  "endpoints": [
+    {"addresses": ["10.1.0.1"], "conditions": {"ready": true}},
+    {"addresses": ["10.1.0.4"], "conditions": {"ready": true}}
+  ],
+  "ports": [{"port": 8000}]
+}]}`)
+	changed, err = resolver.Refresh(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{config.EndpointRoleBig}, changed)
+	assert.ElementsMatch(t, []string{
+		"http://10.1.0.1:8000/v1/chat/completions",
+		"http://10.1.0.4:8000/v1/chat/completions",
+	}, cfg.Endpoints(config.EndpointRoleBig))
+	assert.True(t, cfg.IsEndpointHealthy("http://10.1.0.4:8000/v1/chat/completions"))
+
+	// No ready pods keeps the previous endpoints
+	slices.set(`{"metadata": {"resourceVersion": "102"}, "items": []}`)
+	_, err = resolver.Refresh(context.Background())
+	assert.Error(t, err)
+	assert.Len(t, cfg.Endpoints(config.EndpointRoleBig), 2)
+}
@@ -0,0 +1,149 @@
+package test
+
+import (
+	"bytes"
+	"claude-proxy/config"
+	"claude-proxy/loop"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readLoopMessages returns a conversation where Read was called with the same
+// arguments count times in a row
+func readLoopMessages(count int) []types.OpenAIMessage {
+	messages := []types.OpenAIMessage{
+		{Role: "system", Content: "You are a coding assistant."},
+		{Role: "user", Content: "Look at main.go"},
+	}
+	for i := 0; i < count; i++ {
+		id := "call_" + string(rune('a'+i))
+		messages = append(messages,
+			types.OpenAIMessage{Role: "assistant", ToolCalls: []types.OpenAIToolCall{
+				{ID: id, Type: "function", Function: types.OpenAIToolCallFunction{Name: "Read", Arguments: `{"file_path":"/main.go"}`}},
+			}},
+			types.OpenAIMessage{Role: "tool", ToolCallID: id, Content: "package main"},
+		)
+	}
+	return messages
+}
+
+func TestLoopDetector_Threshold(t *testing.T) {
+	detector := loop.NewLoopDetectorWithThreshold(4)
+	assert.False(t, detector.DetectLoop(context.Background(), readLoopMessages(3)).HasLoop)
+	assert.True(t, detector.DetectLoop(context.Background(), readLoopMessages(4)).HasLoop)
+
+	// Invalid thresholds keep the default of 3
+	assert.True(t, loop.NewLoopDetectorWithThreshold(0).DetectLoop(context.Background(), readLoopMessages(3)).HasLoop)
+}
+
+func TestLoopNudge(t *testing.T) {
+	messages := readLoopMessages(3)
+	detection := loop.NewLoopDetector().DetectLoop(context.Background(), messages)
+	require.True(t, detection.HasLoop)
+
+	nudged := loop.Nudge(messages, detection)
+	require.Len(t, nudged, len(messages))
+	assert.True(t, strings.HasPrefix(nudged[0].Content, "You are a coding assistant.\n\n[Loop Guard]"))
+	assert.Contains(t, nudged[0].Content, "Read called 3 times")
+	assert.Equal(t, "You are a coding assistant.", messages[0].Content, "input messages must not change")
+
+	// Conversations without a system message get one
+	nudged = loop.Nudge(messages[1:], detection)
+	require.Len(t, nudged, len(messages))
+	assert.Equal(t, "system", nudged[0].Role)
+	assert.Equal(t, "user", nudged[1].Role)
+}
+
+func TestLoopReplaceToolResult(t *testing.T) {
+	messages := readLoopMessages(3)
+	detection := loop.NewLoopDetector().DetectLoop(context.Background(), messages)
+	require.True(t, detection.HasLoop)
+
+	replaced, ok := loop.ReplaceToolResult(messages, detection)
+	require.True(t, ok)
+	last := replaced[len(replaced)-1]
+	assert.Equal(t, "call_c", last.ToolCallID)
+	assert.True(t, strings.HasPrefix(last.Content, "[Loop Guard]"))
+	assert.Equal(t, "package main", replaced[len(replaced)-3].Content, "only the latest result is replaced")
+	assert.Equal(t, "package main", messages[len(messages)-1].Content, "input messages must not change")
+
+	// Requests ending with a user message have no result to replace
+	withUser := append(append([]types.OpenAIMessage(nil), messages...), types.OpenAIMessage{Role: "user", Content: "go on"})
+	_, ok = loop.ReplaceToolResult(withUser, detection)
+	assert.False(t, ok)
+}
+
+// TestLoopDetectionAction tests that the nudge and tool_result actions still
+// call the backend, with the loop warning in the request
+func TestLoopDetectionAction(t *testing.T) {
+	tests := []struct {
+		action   string
+		expected func(t *testing.T, messages []types.OpenAIMessage)
+	}{
+		{action: config.LoopActionNudge, expected: func(t *testing.T, messages []types.OpenAIMessage) {
+			assert.Contains(t, messages[0].Content, "[Loop Guard]")
+			assert.Equal(t, "package main", messages[len(messages)-1].Content)
+		}},
+		{action: config.LoopActionToolResult, expected: func(t *testing.T, messages []types.OpenAIMessage) {
+			assert.NotContains(t, messages[0].Content, "[Loop Guard]")
+			assert.Contains(t, messages[len(messages)-1].Content, "[Loop Guard]")
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			var upstream types.OpenAIRequest
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewDecoder(r.Body).Decode(&upstream)
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(types.OpenAIResponse{
+					ID: "chatcmpl-loop",
+					Choices: []types.OpenAIChoice{
+						{Index: 0, Message: types.OpenAIMessage{Role: "assistant", Content: "main.go declares package main."}, FinishReason: stringPtr("stop")},
+					},
+				})
+			}))
+			defer mockServer.Close()
+
+			cfg := config.GetDefaultConfig()
+			cfg.BigModel = "test-model"
+			cfg.BigModelEndpoints = []string{mockServer.URL}
+			cfg.BigModelAPIKey = "test-key"
+			cfg.ToolCorrectionEnabled = false
+			cfg.LoopDetectionAction = tt.action
+			handler := proxy.NewHandler(cfg, nil, "")
+
+			messages := []types.Message{{Role: "user", Content: "Look at main.go"}}
+			for _, id := range []string{"1", "2", "3"} {
+				messages = append(messages,
+					types.Message{Role: "assistant", Content: []types.Content{{Type: "tool_use", ID: id, Name: "Read", Input: map[string]interface{}{"file_path": "/main.go"}}}},
+					types.Message{Role: "user", Content: []map[string]interface{}{{"type": "tool_result", "tool_use_id": id, "content": "package main"}}},
+				)
+			}
+			reqJSON, _ := json.Marshal(types.AnthropicRequest{
+				Model:     "claude-sonnet-4-20250514",
+				MaxTokens: 100,
+				System:    []types.SystemContent{{Type: "text", Text: "You are a coding assistant."}},
+				Messages:  messages,
+			})
+			req := httptest.NewRequest("POST", "/v1/messages", bytes.NewReader(reqJSON))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+
+			handler.HandleAnthropicRequest(rr, req)
+			require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+			assert.Contains(t, rr.Body.String(), "declares package main")
+			require.NotEmpty(t, upstream.Messages)
+			tt.expected(t, upstream.Messages)
+		})
+	}
+}
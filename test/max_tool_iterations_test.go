@@ -0,0 +1,106 @@
+package test
+
+import (
+	"bytes"
+	"claude-proxy/config"
+	"claude-proxy/loop"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsecutiveToolTurns(t *testing.T) {
+	toolTurn := func(id string) []types.OpenAIMessage {
+		return []types.OpenAIMessage{
+			{Role: "assistant", ToolCalls: []types.OpenAIToolCall{{ID: id, Type: "function", Function: types.OpenAIToolCallFunction{Name: "Read"}}}},
+			{Role: "tool", ToolCallID: id, Content: "ok"},
+		}
+	}
+
+	messages := []types.OpenAIMessage{{Role: "user", Content: "Fix the bug"}}
+	assert.Equal(t, 0, loop.ConsecutiveToolTurns(messages))
+
+	messages = append(messages, toolTurn("1")...)
+	messages = append(messages, toolTurn("2")...)
+	assert.Equal(t, 2, loop.ConsecutiveToolTurns(messages))
+
+	// A turn with text ends the streak
+	messages = append(messages, types.OpenAIMessage{Role: "assistant", Content: "Found it, fixing now.",
+		ToolCalls: []types.OpenAIToolCall{{ID: "3", Type: "function", Function: types.OpenAIToolCallFunction{Name: "Edit"}}}})
+	messages = append(messages, types.OpenAIMessage{Role: "tool", ToolCallID: "3", Content: "ok"})
+	messages = append(messages, toolTurn("4")...)
+	assert.Equal(t, 1, loop.ConsecutiveToolTurns(messages))
+
+	// So does a user message
+	messages = append(messages, types.OpenAIMessage{Role: "user", Content: "Keep going"})
+	assert.Equal(t, 0, loop.ConsecutiveToolTurns(messages))
+}
+
+// TestMaxToolIterations tests that tool_choice is forced to none once the
+// conversation reaches MAX_TOOL_ITERATIONS tool-only turns
+func TestMaxToolIterations(t *testing.T) {
+	tests := []struct {
+		name               string
+		maxToolIterations  int
+		expectedToolChoice interface{}
+	}{
+		{name: "below limit", maxToolIterations: 4, expectedToolChoice: nil},
+		{name: "limit reached", maxToolIterations: 3, expectedToolChoice: "none"},
+		{name: "unlimited", maxToolIterations: 0, expectedToolChoice: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var upstream map[string]interface{}
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewDecoder(r.Body).Decode(&upstream)
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(types.OpenAIResponse{
+					ID: "chatcmpl-iterations",
+					Choices: []types.OpenAIChoice{
+						{Index: 0, Message: types.OpenAIMessage{Role: "assistant", Content: "Done."}, FinishReason: stringPtr("stop")},
+					},
+				})
+			}))
+			defer mockServer.Close()
+
+			cfg := config.GetDefaultConfig()
+			cfg.BigModel = "test-model"
+			cfg.BigModelEndpoints = []string{mockServer.URL}
+			cfg.BigModelAPIKey = "test-key"
+			cfg.ToolCorrectionEnabled = false
+			cfg.MaxToolIterations = tt.maxToolIterations
+			handler := proxy.NewHandler(cfg, nil, "")
+
+			messages := []types.Message{{Role: "user", Content: "Read the sources"}}
+			for i := 1; i <= 3; i++ {
+				id := fmt.Sprintf("toolu_%d", i)
+				messages = append(messages,
+					types.Message{Role: "assistant", Content: []types.Content{{Type: "tool_use", ID: id, Name: "Read", Input: map[string]interface{}{"file_path": fmt.Sprintf("/src/%d.go", i)}}}},
+					types.Message{Role: "user", Content: []map[string]interface{}{{"type": "tool_result", "tool_use_id": id, "content": "package src"}}},
+				)
+			}
+			reqJSON, _ := json.Marshal(types.AnthropicRequest{
+				Model:     "claude-sonnet-4-20250514",
+				MaxTokens: 100,
+				Messages:  messages,
+				Tools:     backendTestTools(),
+			})
+			req := httptest.NewRequest("POST", "/v1/messages", bytes.NewReader(reqJSON))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+
+			handler.HandleAnthropicRequest(rr, req)
+			require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+			require.NotNil(t, upstream)
+			assert.Equal(t, tt.expectedToolChoice, upstream["tool_choice"])
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package test
+
+import (
+	"bytes"
+	"claude-proxy/batch"
+	"claude-proxy/config"
+	"claude-proxy/journal"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClientModelAccess tests that a key limited to SMALL_MODEL is refused
+// the big model with a permission error, while other keys are not affected
+func TestClientModelAccess(t *testing.T) {
+	upstream := budgetTestServer()
+	defer upstream.Close()
+
+	cfg := config.GetDefaultConfig()
+	cfg.BigModel = "big-model"
+	cfg.BigModelEndpoints = []string{upstream.URL}
+	cfg.BigModelAPIKey = "test-key"
+	cfg.SmallModel = "small-model"
+	cfg.SmallModelEndpoints = []string{upstream.URL}
+	cfg.SmallModelAPIKey = "test-key"
+	handler := proxy.NewHandler(cfg, nil, "")
+
+	// Learn the key ID of the intern key, as an operator would from /stats
+	cfg.ModelPricing = map[string]config.ModelPrice{"small-model": {InputPerMTok: 1, OutputPerMTok: 1}}
+	require.Equal(t, http.StatusOK, sendModelRequest(handler, "claude-3-5-haiku-20241022", "sk-intern").Code)
+	var internID string
+	for id := range handler.Stats().Totals().ClientSpend {
+		internID = id
+	}
+	require.NotEmpty(t, internID)
+	cfg.ClientModelAccess = config.ModelAccess{internID: {config.ModelAccessSmallModel}}
+
+	rr := sendModelRequest(handler, "claude-sonnet-4-20250514", "sk-intern")
+	require.Equal(t, http.StatusForbidden, rr.Code)
+	var errResp struct {
+		Error struct {
+			Type    string `json:"type"`
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errResp))
+	assert.Equal(t, "permission_error", errResp.Error.Type)
+	assert.Equal(t, string(proxy.CodeModelNotAllowed), errResp.Error.Code)
+	assert.Contains(t, errResp.Error.Message, "big-model")
+
+	assert.Equal(t, http.StatusOK, sendModelRequest(handler, "claude-3-5-haiku-20241022", "sk-intern").Code)
+	assert.Equal(t, http.StatusOK, sendModelRequest(handler, "claude-sonnet-4-20250514", "sk-lead").Code)
+
+	// A "*" entry also covers requests without a key
+	cfg.ClientModelAccess[config.ModelAccessOtherKeys] = []string{config.ModelAccessSmallModel}
+	assert.Equal(t, http.StatusForbidden, sendModelRequest(handler, "claude-sonnet-4-20250514", "sk-lead").Code)
+	assert.Equal(t, http.StatusForbidden, sendModelRequest(handler, "claude-sonnet-4-20250514", "").Code)
+}
+
+// sendModelRequest sends a minimal request for a model with an optional API key
+func sendModelRequest(handler *proxy.Handler, model, apiKey string) *httptest.ResponseRecorder {
+	reqJSON, _ := json.Marshal(types.AnthropicRequest{
+		Model:     model,
+		MaxTokens: 100,
+		Messages:  []types.Message{{Role: "user", Content: "Hello"}},
+	})
+	req := httptest.NewRequest("POST", "/v1/messages", bytes.NewReader(reqJSON))
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("X-Api-Key", apiKey)
+	}
+	rr := httptest.NewRecorder()
+	handler.HandleAnthropicRequest(rr, req)
+	return rr
+}
+
+// TestClientModelAccessBeforeCorrection tests that a refused key is turned
+// away during routing, before tool necessity detection calls the correction model
+func TestClientModelAccessBeforeCorrection(t *testing.T) {
+	upstream := budgetTestServer()
+	defer upstream.Close()
+
+	var correctionCalls int32
+	correctionServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&correctionCalls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer correctionServer.Close()
+
+	cfg := config.GetDefaultConfig()
+	cfg.BigModel = "big-model"
+	cfg.BigModelEndpoints = []string{upstream.URL}
+	cfg.BigModelAPIKey = "test-key"
+	cfg.ToolCorrectionEnabled = true
+	cfg.ToolCorrectionEndpoints = []string{correctionServer.URL}
+	cfg.ToolCorrectionAPIKey = "test-key"
+	cfg.ClientModelAccess = config.ModelAccess{config.ModelAccessOtherKeys: {config.ModelAccessSmallModel}}
+	handler := proxy.NewHandler(cfg, nil, "")
+
+	body := `{"model": "claude-sonnet-4-20250514", "max_tokens": 100, "messages": [{"role": "user", "content": "read main.go"}],
+		"tools": [{"name": "Read", "description": "Read a file", "input_schema": {"type": "object", "properties": {"file_path": {"type": "string"}}, "required": ["file_path"]}}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(body))
+	req.Header.Set("X-Api-Key", "sk-intern")
+	rr := httptest.NewRecorder()
+	handler.HandleAnthropicRequest(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&correctionCalls), "refused requests never reach the correction model")
+}
+
+// TestClientModelAccessBatchesAndReplays tests that batch entries and journal
+// replays, which carry no API key headers, are limited as the key that sent them
+func TestClientModelAccessBatchesAndReplays(t *testing.T) {
+	upstream := budgetTestServer()
+	defer upstream.Close()
+
+	sum := sha256.Sum256([]byte("sk-intern"))
+	internID := hex.EncodeToString(sum[:8])
+
+	cfg := config.GetDefaultConfig()
+	cfg.BigModel = "big-model"
+	cfg.BigModelEndpoints = []string{upstream.URL}
+	cfg.BigModelAPIKey = "test-key"
+	cfg.SmallModel = "small-model"
+	cfg.SmallModelEndpoints = []string{upstream.URL}
+	cfg.SmallModelAPIKey = "test-key"
+	cfg.ClientModelAccess = config.ModelAccess{internID: {config.ModelAccessSmallModel}}
+	handler := proxy.NewHandler(cfg, nil, "")
+
+	t.Run("Batch", func(t *testing.T) {
+		store, err := batch.OpenStore(filepath.Join(t.TempDir(), "batches.db"))
+		require.NoError(t, err)
+		defer store.Close()
+		processor := proxy.NewBatchProcessor(store, http.HandlerFunc(handler.HandleAnthropicRequest), 1)
+
+		params := `{"model": "claude-sonnet-4-20250514", "max_tokens": 100, "messages": [{"role": "user", "content": "Hello"}]}`
+		body, _ := json.Marshal(map[string]interface{}{"requests": []batch.Entry{{CustomID: "big", Params: json.RawMessage(params)}}})
+		req := httptest.NewRequest(http.MethodPost, "/v1/messages/batches", bytes.NewReader(body))
+		req.Header.Set("X-Api-Key", "sk-intern")
+		rr := httptest.NewRecorder()
+		processor.HandleBatches(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var created batch.Batch
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+		processor.Wait()
+
+		var result batch.Result
+		require.NoError(t, store.Results(created.ID, func(line []byte) error {
+			return json.Unmarshal(line, &result)
+		}))
+		assert.Equal(t, batch.ResultErrored, result.Result.Type)
+		assert.Contains(t, string(result.Result.Error), string(proxy.CodeModelNotAllowed))
+	})
+
+	t.Run("JournalReplay", func(t *testing.T) {
+		// An earlier run accepted the request from the intern key and crashed
+		body := `{"model": "claude-sonnet-4-20250514", "max_tokens": 100, "messages": [{"role": "user", "content": "Hello"}]}`
+		path := filepath.Join(t.TempDir(), "journal.jsonl")
+		earlier, err := journal.Open(path)
+		require.NoError(t, err)
+		id, err := earlier.Accept(journal.Entry{RequestID: "req-big", Model: "claude-sonnet-4-20250514", Request: json.RawMessage(body), Path: "/v1/messages", ClientKey: internID})
+		require.NoError(t, err)
+		require.NoError(t, earlier.Close())
+		j, err := journal.Open(path)
+		require.NoError(t, err)
+		defer j.Close()
+		handler.SetJournal(j)
+
+		rr := httptest.NewRecorder()
+		handler.HandleJournal(rr, httptest.NewRequest(http.MethodPost, "/admin/journal/"+id+"/replay", nil))
+		assert.Equal(t, http.StatusForbidden, rr.Code, rr.Body.String())
+	})
+}
@@ -0,0 +1,164 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"claude-proxy/config"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// capabilityTestHandler returns a handler whose big model has caps, and a
+// function returning the last request the backend received
+func capabilityTestHandler(t *testing.T, caps config.ModelCapabilities) (*proxy.Handler, func() *types.OpenAIRequest) {
+	var received *types.OpenAIRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = &types.OpenAIRequest{}
+		json.NewDecoder(r.Body).Decode(received)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(types.OpenAIResponse{
+			ID:    "chatcmpl-caps",
+			Model: "gemma-2-9b-it",
+			Choices: []types.OpenAIChoice{{
+				Message:      types.OpenAIMessage{Role: "assistant", Content: "ok"},
+				FinishReason: stringPtr("stop"),
+			}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := config.GetDefaultConfig()
+	cfg.BigModel = "gemma-2-9b-it"
+	cfg.BigModelEndpoints = []string{server.URL}
+	cfg.BigModelAPIKey = "test-key"
+	cfg.ToolCorrectionEnabled = false
+	cfg.ModelProfiles = map[string]config.ModelProfile{"gemma-2-9b-it": {Capabilities: caps}}
+	return proxy.NewHandler(cfg, nil, ""), func() *types.OpenAIRequest { return received }
+}
+
+func sendCapabilityRequest(handler *proxy.Handler, req types.AnthropicRequest) *httptest.ResponseRecorder {
+	req.Model = "claude-sonnet-4-20250514"
+	req.MaxTokens = 100
+	body, _ := json.Marshal(req)
+	httpReq := httptest.NewRequest("POST", "/v1/messages", bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	handler.HandleAnthropicRequest(rr, httpReq)
+	return rr
+}
+
+func TestCapabilitiesRejectImagesWithoutVision(t *testing.T) {
+	no := false
+	handler, received := capabilityTestHandler(t, config.ModelCapabilities{Vision: &no})
+
+	rr := sendCapabilityRequest(handler, types.AnthropicRequest{
+		Messages: []types.Message{{Role: "user", Content: []interface{}{
+			map[string]interface{}{"type": "text", "text": "What is in this screenshot?"},
+			map[string]interface{}{"type": "image", "source": map[string]interface{}{"type": "base64", "media_type": "image/png", "data": "iVBORw0KGgo="}},
+		}}},
+	})
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "UNSUPPORTED_CONTENT")
+	assert.Contains(t, rr.Body.String(), "does not accept image input")
+	assert.Nil(t, received(), "the request never reaches the backend")
+}
+
+func TestCapabilitiesRejectImagesInToolResults(t *testing.T) {
+	no := false
+	handler, _ := capabilityTestHandler(t, config.ModelCapabilities{Vision: &no})
+
+	rr := sendCapabilityRequest(handler, types.AnthropicRequest{
+		Messages: []types.Message{
+			{Role: "user", Content: "Look at the screenshot"},
+			{Role: "assistant", Content: []interface{}{
+				map[string]interface{}{"type": "tool_use", "id": "toolu_1", "name": "Read", "input": map[string]interface{}{"file_path": "/tmp/shot.png"}},
+			}},
+			{Role: "user", Content: []interface{}{
+				map[string]interface{}{"type": "tool_result", "tool_use_id": "toolu_1", "content": []interface{}{
+					map[string]interface{}{"type": "image", "source": map[string]interface{}{"type": "base64", "media_type": "image/png", "data": "iVBORw0KGgo="}},
+				}},
+			}},
+		},
+		Tools: backendTestTools(),
+	})
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "UNSUPPORTED_CONTENT")
+}
+
+func TestCapabilitiesInlineToolsAndMergeSystem(t *testing.T) {
+	no := false
+	handler, received := capabilityTestHandler(t, config.ModelCapabilities{Tools: &no, SystemRole: &no})
+
+	rr := sendCapabilityRequest(handler, types.AnthropicRequest{
+		System: []types.SystemContent{{Type: "text", Text: "You are a coding assistant."}},
+		Messages: []types.Message{
+			{Role: "user", Content: "Read main.go"},
+			{Role: "assistant", Content: []interface{}{
+				map[string]interface{}{"type": "tool_use", "id": "toolu_1", "name": "Read", "input": map[string]interface{}{"file_path": "main.go"}},
+			}},
+			{Role: "user", Content: []interface{}{
+				map[string]interface{}{"type": "tool_result", "tool_use_id": "toolu_1", "content": "package main"},
+			}},
+		},
+		Tools: backendTestTools(),
+	})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	upstream := received()
+	require.NotNil(t, upstream)
+	assert.Empty(t, upstream.Tools, "native tool definitions are not sent")
+	assert.Nil(t, upstream.ToolChoice)
+	for _, msg := range upstream.Messages {
+		assert.NotEqual(t, "system", msg.Role)
+		assert.NotEqual(t, "tool", msg.Role)
+		assert.Empty(t, msg.ToolCalls)
+	}
+	first := upstream.Messages[0]
+	assert.Equal(t, "user", first.Role)
+	assert.True(t, strings.HasPrefix(first.Content, "You are a coding assistant."), first.Content)
+	assert.Contains(t, first.Content, "## Read")
+	assert.Contains(t, first.Content, `"file_path"`)
+	assert.True(t, strings.HasSuffix(first.Content, "Read main.go"))
+	assert.Contains(t, upstream.Messages[1].Content, `[Called tool Read with {"file_path":"main.go"}]`)
+	assert.Contains(t, upstream.Messages[2].Content, "package main")
+}
+
+func TestCapabilitiesRejectPromptOverContext(t *testing.T) {
+	handler, received := capabilityTestHandler(t, config.ModelCapabilities{MaxContextTokens: 50})
+
+	rr := sendCapabilityRequest(handler, types.AnthropicRequest{
+		Messages: []types.Message{{Role: "user", Content: strings.Repeat("explain this code please ", 40)}},
+	})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "prompt is too long")
+	assert.Contains(t, rr.Body.String(), "invalid_request_error")
+	assert.Nil(t, received())
+
+	rr = sendCapabilityRequest(handler, types.AnthropicRequest{
+		Messages: []types.Message{{Role: "user", Content: "Hi"}},
+	})
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+}
+
+func TestCapabilitiesUnsetSendRequestUnchanged(t *testing.T) {
+	handler, received := capabilityTestHandler(t, config.ModelCapabilities{})
+
+	rr := sendCapabilityRequest(handler, types.AnthropicRequest{
+		System:   []types.SystemContent{{Type: "text", Text: "You are a coding assistant."}},
+		Messages: []types.Message{{Role: "user", Content: "Read main.go"}},
+		Tools:    backendTestTools(),
+	})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Len(t, received().Tools, 1)
+	assert.Equal(t, "system", received().Messages[0].Role)
+}
@@ -0,0 +1,61 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"claude-proxy/config"
+	"claude-proxy/internal"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestModelSystemPromptInjection tests that per-model instructions are appended
+// after the overridden Claude Code system prompt only for the configured model
+func TestModelSystemPromptInjection(t *testing.T) {
+	cfg := &config.Config{
+		SystemMessageOverrides: config.SystemMessageOverrides{
+			Replacements: []config.SystemMessageReplacement{{Find: "Claude Code", Replace: "AI Assistant"}},
+		},
+		ModelSystemPrompts: map[string]string{
+			"gpt-oss:20b": "Reasoning: high\n",
+		},
+	}
+	ctx := internal.WithRequestID(context.Background(), "model_system_prompt_test")
+
+	newRequest := func(model string, withSystem bool) types.AnthropicRequest {
+		req := types.AnthropicRequest{
+			Model:    model,
+			Messages: []types.Message{{Role: "user", Content: "Hello"}},
+		}
+		if withSystem {
+			req.System = []types.SystemContent{{Type: "text", Text: "You are Claude Code."}}
+		}
+		return req
+	}
+
+	t.Run("InjectedAfterOverriddenSystemPrompt", func(t *testing.T) {
+		result, err := proxy.TransformAnthropicToOpenAI(ctx, newRequest("gpt-oss:20b", true), cfg)
+		require.NoError(t, err)
+		require.Equal(t, "system", result.Messages[0].Role)
+		assert.Equal(t, "You are AI Assistant.\n\nReasoning: high", result.Messages[0].Content)
+	})
+
+	t.Run("OtherModelsUnaffected", func(t *testing.T) {
+		result, err := proxy.TransformAnthropicToOpenAI(ctx, newRequest("qwen3-coder", true), cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "You are AI Assistant.", result.Messages[0].Content)
+	})
+
+	t.Run("SystemMessageCreatedWhenMissing", func(t *testing.T) {
+		result, err := proxy.TransformAnthropicToOpenAI(ctx, newRequest("gpt-oss:20b", false), cfg)
+		require.NoError(t, err)
+		require.Len(t, result.Messages, 2)
+		assert.Equal(t, "system", result.Messages[0].Role)
+		assert.Equal(t, "Reasoning: high", result.Messages[0].Content)
+		assert.Equal(t, "user", result.Messages[1].Role)
+	})
+}
@@ -0,0 +1,127 @@
+package test
+
+import (
+	"bytes"
+	"claude-proxy/config"
+	"claude-proxy/internal"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMultiChoicePolicy tests the first, merge and error policies against an
+// upstream that ignores n and returns two choices out of index order
+func TestMultiChoicePolicy(t *testing.T) {
+	tests := []struct {
+		policy       string
+		expectedCode int
+		expectedText string
+	}{
+		{policy: config.MultiChoiceFirst, expectedCode: http.StatusOK, expectedText: "first answer"},
+		{policy: config.MultiChoiceMerge, expectedCode: http.StatusOK, expectedText: "first answer\n\nsecond answer"},
+		{policy: config.MultiChoiceError, expectedCode: http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(types.OpenAIResponse{
+					ID: "chatcmpl-multi",
+					Choices: []types.OpenAIChoice{
+						{Index: 1, Message: types.OpenAIMessage{Role: "assistant", Content: "second answer"}, FinishReason: stringPtr("stop")},
+						{Index: 0, Message: types.OpenAIMessage{Role: "assistant", Content: "first answer"}, FinishReason: stringPtr("stop")},
+					},
+				})
+			}))
+			defer mockServer.Close()
+
+			cfg := config.GetDefaultConfig()
+			cfg.BigModel = "test-model"
+			cfg.BigModelEndpoints = []string{mockServer.URL}
+			cfg.BigModelAPIKey = "test-key"
+			cfg.MultiChoicePolicy = tt.policy
+			handler := proxy.NewHandler(cfg, nil, "")
+
+			reqJSON, _ := json.Marshal(map[string]interface{}{
+				"model":      "claude-sonnet-4-20250514",
+				"max_tokens": 100,
+				"messages":   []map[string]interface{}{{"role": "user", "content": "Hello"}},
+			})
+			req := httptest.NewRequest("POST", "/v1/messages", bytes.NewReader(reqJSON))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+
+			handler.HandleAnthropicRequest(rr, req)
+			require.Equal(t, tt.expectedCode, rr.Code, rr.Body.String())
+			if tt.expectedCode != http.StatusOK {
+				assert.Contains(t, rr.Body.String(), string(proxy.CodeUpstreamInvalidResponse))
+				return
+			}
+
+			var resp types.AnthropicResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Len(t, resp.Content, 1)
+			assert.Equal(t, tt.expectedText, resp.Content[0].Text)
+		})
+	}
+}
+
+// TestMultiChoiceNStripped tests that n reaches the backend only when its
+// model profile passes it through
+func TestMultiChoiceNStripped(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.ModelProfiles = map[string]config.ModelProfile{
+		"vllm-model": {PassthroughParams: []string{"n"}},
+	}
+	ctx := internal.WithRequestID(context.Background(), "multi_choice_n_test")
+	n := 3
+
+	for model, expected := range map[string]*int{"llama-model": nil, "vllm-model": &n} {
+		openaiReq, err := proxy.TransformAnthropicToOpenAI(ctx, types.AnthropicRequest{
+			Model:    model,
+			N:        &n,
+			Messages: []types.Message{{Role: "user", Content: "Hello"}},
+		}, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, expected, openaiReq.N, model)
+	}
+}
+
+// TestStreamReconstructionMultipleChoices tests that interleaved chunks of
+// different choices are reconstructed separately instead of concatenated
+func TestStreamReconstructionMultipleChoices(t *testing.T) {
+	chunk := func(index int, content string, finishReason *string) types.OpenAIStreamChunk {
+		return types.OpenAIStreamChunk{
+			ID:      "chatcmpl-stream",
+			Choices: []types.OpenAIStreamChoice{{Index: index, Delta: types.OpenAIStreamDelta{Content: content}, FinishReason: finishReason}},
+		}
+	}
+	chunks := []types.OpenAIStreamChunk{
+		chunk(0, "Hello", nil),
+		chunk(1, "Good", nil),
+		chunk(0, " world", nil),
+		chunk(1, "bye", stringPtr("stop")),
+		chunk(0, "", stringPtr("length")),
+	}
+
+	handler := proxy.NewHandler(getTestConfig(), nil, "")
+	ctx := internal.WithRequestID(context.Background(), "stream_multi_choice_test")
+	response, err := handler.ReconstructResponseFromChunks(ctx, chunks, &chunks[len(chunks)-1])
+	require.NoError(t, err)
+
+	require.Len(t, response.Choices, 2)
+	assert.Equal(t, 0, response.Choices[0].Index)
+	assert.Equal(t, "Hello world", response.Choices[0].Message.Content)
+	assert.Equal(t, "length", *response.Choices[0].FinishReason)
+	assert.Equal(t, 1, response.Choices[1].Index)
+	assert.Equal(t, "Goodbye", response.Choices[1].Message.Content)
+	assert.Equal(t, "stop", *response.Choices[1].FinishReason)
+}
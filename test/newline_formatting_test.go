@@ -349,6 +349,9 @@ This is the user-facing response with:
 			for _, content := range result.Content {
 				if content.Type == tt.expectedType {
 					actualText = content.Text
+					if content.Type == "thinking" {
+						actualText = content.Thinking
+					}
 					break
 				}
 			}
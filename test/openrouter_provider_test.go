@@ -0,0 +1,75 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"claude-proxy/config"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOpenRouterProviderPreferences tests that the provider block configured
+// for an endpoint is forwarded upstream, and only to that endpoint
+func TestOpenRouterProviderPreferences(t *testing.T) {
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = nil
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(types.OpenAIResponse{
+			ID:    "chatcmpl-provider",
+			Model: "deepseek/deepseek-chat",
+			Choices: []types.OpenAIChoice{{
+				Message:      types.OpenAIMessage{Role: "assistant", Content: "ok"},
+				FinishReason: stringPtr("stop"),
+			}},
+		})
+	}))
+	defer server.Close()
+
+	allowFallbacks := false
+	cfg := config.GetDefaultConfig()
+	cfg.BigModel = "deepseek/deepseek-chat"
+	cfg.BigModelEndpoints = []string{server.URL + "/api/v1/chat/completions"}
+	cfg.BigModelAPIKey = "sk-or-test"
+	cfg.EndpointSettings = map[string]config.EndpointSettings{
+		server.URL + "/api/v1": {Provider: &types.OpenRouterProvider{
+			Order:          []string{"deepinfra", "together"},
+			AllowFallbacks: &allowFallbacks,
+			Quantizations:  []string{"fp8"},
+		}},
+	}
+	handler := proxy.NewHandler(cfg, nil, "")
+
+	send := func() {
+		body, _ := json.Marshal(types.AnthropicRequest{
+			Model:     "claude-sonnet-4-20250514",
+			MaxTokens: 100,
+			Messages:  []types.Message{{Role: "user", Content: "Hello"}},
+		})
+		req := httptest.NewRequest("POST", "/v1/messages", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		handler.HandleAnthropicRequest(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	send()
+	provider, ok := received["provider"].(map[string]interface{})
+	require.True(t, ok, "expected a provider block, got %v", received)
+	assert.Equal(t, []interface{}{"deepinfra", "together"}, provider["order"])
+	assert.Equal(t, false, provider["allow_fallbacks"])
+	assert.Equal(t, []interface{}{"fp8"}, provider["quantizations"])
+	assert.NotContains(t, provider, "sort")
+
+	cfg.EndpointSettings = nil
+	send()
+	assert.NotContains(t, received, "provider", "endpoints without settings get no provider block")
+}
@@ -0,0 +1,115 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"claude-proxy/batch"
+	"claude-proxy/proxy"
+	"claude-proxy/stats"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPanicRecovery tests that a panicking handler yields an Anthropic
+// api_error carrying the request ID and is counted in the stats
+func TestPanicRecovery(t *testing.T) {
+	collector := stats.NewCollector()
+	handler := proxy.WithPanicRecovery(nil, collector, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var tools map[string]int
+		tools["Read"]++ // nil map write
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader("{}"))
+	req.Header.Set("X-Request-ID", "req-panic")
+	rec := httptest.NewRecorder()
+	require.NotPanics(t, func() { handler.ServeHTTP(rec, req) })
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, "req-panic", rec.Header().Get("X-Request-ID"))
+	var resp struct {
+		Type  string `json:"type"`
+		Error struct {
+			Type    string `json:"type"`
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "error", resp.Type)
+	assert.Equal(t, "api_error", resp.Error.Type)
+	assert.Equal(t, "INTERNAL_ERROR", resp.Error.Code)
+	assert.Contains(t, resp.Error.Message, "req-panic")
+
+	snapshot := collector.Snapshot()
+	assert.Equal(t, int64(1), snapshot.Panics)
+	require.Len(t, snapshot.RecentErrors, 1)
+	assert.Equal(t, "req-panic", snapshot.RecentErrors[0].RequestID)
+	assert.Contains(t, snapshot.RecentErrors[0].Detail, "assignment to entry in nil map")
+}
+
+// TestPanicRecoveryMidStream tests that a panic after an SSE stream started
+// ends the stream with an error event instead of a second status line
+func TestPanicRecoveryMidStream(t *testing.T) {
+	handler := proxy.WithPanicRecovery(nil, nil, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("event: message_start\ndata: {\"type\":\"message_start\"}\n\n"))
+		panic("transformer failed")
+	}))
+
+	rec := httptest.NewRecorder()
+	require.NotPanics(t, func() { handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/messages", nil)) })
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.True(t, strings.HasPrefix(body, "event: message_start\n"))
+	assert.Contains(t, body, "event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"api_error\",\"code\":\"INTERNAL_ERROR\"")
+}
+
+// TestPanicRecoveryAbortHandler tests that http.ErrAbortHandler is passed on
+// so net/http still aborts the response silently
+func TestPanicRecoveryAbortHandler(t *testing.T) {
+	collector := stats.NewCollector()
+	handler := proxy.WithPanicRecovery(nil, collector, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+	assert.Equal(t, int64(0), collector.Snapshot().Panics)
+}
+
+// TestPanicRecoveryBatchEntry tests that a panic in a batch entry, which runs
+// on a background goroutine, errors that entry and the batch still ends
+func TestPanicRecoveryBatchEntry(t *testing.T) {
+	store, err := batch.OpenStore(filepath.Join(t.TempDir(), "batches.db"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	pipeline := proxy.WithPanicRecovery(nil, nil, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("transformer failed")
+	}))
+	processor := proxy.NewBatchProcessor(store, pipeline, 1)
+
+	body, _ := json.Marshal(map[string]interface{}{"requests": []batch.Entry{batchEntry("boom", "hello")}})
+	rec := httptest.NewRecorder()
+	processor.HandleBatches(rec, httptest.NewRequest(http.MethodPost, "/v1/messages/batches", bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var created batch.Batch
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	processor.Wait()
+
+	ended, ok, err := store.Get(created.ID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, batch.StatusEnded, ended.ProcessingStatus)
+	assert.Equal(t, batch.RequestCounts{Errored: 1}, ended.RequestCounts)
+}
@@ -0,0 +1,122 @@
+package test
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPassthroughModels tests that requests mapped to PASSTHROUGH_MODELS skip
+// system overrides, tool filtering, Harmony parsing and tool correction
+func TestPassthroughModels(t *testing.T) {
+	const harmonyText = "<|channel|>analysis<|message|>Thinking it over<|end|><|start|>assistant<|channel|>final<|message|>Reading it now<|return|>"
+
+	var upstream types.OpenAIRequest
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&upstream))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":    "resp_1",
+			"model": "gpt-oss:120b",
+			"choices": []map[string]interface{}{{
+				"index": 0,
+				"message": map[string]interface{}{
+					"role":    "assistant",
+					"content": harmonyText,
+					"tool_calls": []map[string]interface{}{{
+						"id":       "call_1",
+						"type":     "function",
+						"function": map[string]interface{}{"name": "Read", "arguments": `{"path": "/tmp/main.go"}`},
+					}},
+				},
+				"finish_reason": "tool_calls",
+			}},
+		})
+	}))
+	defer backend.Close()
+
+	var correctionCalls int32
+	correctionServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&correctionCalls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer correctionServer.Close()
+
+	type result struct {
+		Content []struct {
+			Type  string                 `json:"type"`
+			Text  string                 `json:"text"`
+			Input map[string]interface{} `json:"input"`
+		} `json:"content"`
+	}
+	send := func(passthroughModels []string) result {
+		cfg := newErrorCodeTestConfig("http://127.0.0.1:1")
+		cfg.BigModel = "gpt-oss:120b"
+		cfg.BigModelEndpoints = []string{backend.URL}
+		cfg.HarmonyParsingEnabled = true
+		cfg.ToolCorrectionEnabled = true
+		cfg.ToolCorrectionEndpoints = []string{correctionServer.URL}
+		cfg.ToolCorrectionAPIKey = "test-key"
+		cfg.SkipTools = []string{"WebSearch"}
+		cfg.SystemMessageOverrides = config.SystemMessageOverrides{Prepend: "Overridden."}
+		cfg.PassthroughModels = passthroughModels
+		handler := proxy.NewHandler(cfg, nil, "")
+
+		body := `{"model": "claude-sonnet-4-20250514", "max_tokens": 100, "system": [{"type": "text", "text": "You are Claude Code."}],
+			"messages": [{"role": "user", "content": "read main.go"}],
+			"tools": [
+				{"name": "Read", "description": "Read a file", "input_schema": {"type": "object", "properties": {"file_path": {"type": "string"}}, "required": ["file_path"]}},
+				{"name": "WebSearch", "description": "Search the web", "input_schema": {"type": "object", "properties": {"query": {"type": "string"}}, "required": ["query"]}}
+			]}`
+		req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		handler.HandleAnthropicRequest(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		var resp result
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		return resp
+	}
+
+	t.Run("Passthrough", func(t *testing.T) {
+		atomic.StoreInt32(&correctionCalls, 0)
+		resp := send([]string{"gpt-oss*"})
+
+		require.NotEmpty(t, upstream.Messages)
+		assert.Equal(t, "You are Claude Code.", upstream.Messages[0].Content)
+		require.Len(t, upstream.Tools, 2)
+		assert.Equal(t, "WebSearch", upstream.Tools[1].Function.Name)
+
+		require.Len(t, resp.Content, 2)
+		assert.Equal(t, harmonyText, resp.Content[0].Text)
+		assert.Equal(t, map[string]interface{}{"path": "/tmp/main.go"}, resp.Content[1].Input)
+		assert.Equal(t, int32(0), atomic.LoadInt32(&correctionCalls), "passthrough requests never reach the correction model")
+	})
+
+	t.Run("OtherModels", func(t *testing.T) {
+		resp := send([]string{"qwen*"})
+
+		require.NotEmpty(t, upstream.Messages)
+		assert.True(t, strings.HasPrefix(upstream.Messages[0].Content, "Overridden."))
+		require.Len(t, upstream.Tools, 1)
+		assert.Equal(t, "Read", upstream.Tools[0].Function.Name)
+
+		var texts []string
+		for _, item := range resp.Content {
+			if item.Type == "tool_use" {
+				assert.Equal(t, map[string]interface{}{"file_path": "/tmp/main.go"}, item.Input)
+			}
+			texts = append(texts, item.Text)
+		}
+		assert.NotContains(t, strings.Join(texts, ""), "<|channel|>")
+	})
+}
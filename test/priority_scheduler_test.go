@@ -0,0 +1,123 @@
+package test
+
+import (
+	"bytes"
+	"claude-proxy/config"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// schedulerTest runs requests through a handler limited to one upstream call
+// whose first call blocks until release, recording the order models arrive in
+type schedulerTest struct {
+	t       *testing.T
+	handler *proxy.Handler
+	release chan struct{}
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	order   []string
+}
+
+func newSchedulerTest(t *testing.T) *schedulerTest {
+	st := &schedulerTest{t: t, release: make(chan struct{})}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req types.OpenAIRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		st.mu.Lock()
+		st.order = append(st.order, req.Model)
+		first := len(st.order) == 1
+		st.mu.Unlock()
+		if first {
+			<-st.release
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(types.OpenAIResponse{
+			ID: "chatcmpl-priority",
+			Choices: []types.OpenAIChoice{
+				{Index: 0, Message: types.OpenAIMessage{Role: "assistant", Content: "ok"}, FinishReason: stringPtr("stop")},
+			},
+		})
+	}))
+	t.Cleanup(upstream.Close)
+
+	cfg := config.GetDefaultConfig()
+	cfg.BigModel = "big-model"
+	cfg.BigModelEndpoints = []string{upstream.URL}
+	cfg.BigModelAPIKey = "test-key"
+	cfg.SmallModel = "small-model"
+	cfg.SmallModelEndpoints = []string{upstream.URL}
+	cfg.SmallModelAPIKey = "test-key"
+	cfg.MaxConcurrentRequests = 1
+	st.handler = proxy.NewHandler(cfg, nil, "")
+	return st
+}
+
+// send starts a request and waits until the scheduler has taken it in
+func (st *schedulerTest) send(model, priority string, taken func(proxy.SchedulerSnapshot) bool) {
+	st.wg.Add(1)
+	go func() {
+		defer st.wg.Done()
+		reqJSON, _ := json.Marshal(types.AnthropicRequest{
+			Model:     model,
+			MaxTokens: 100,
+			Messages:  []types.Message{{Role: "user", Content: "Hello"}},
+		})
+		req := httptest.NewRequest("POST", "/v1/messages", bytes.NewReader(reqJSON))
+		req.Header.Set("Content-Type", "application/json")
+		if priority != "" {
+			req.Header.Set(proxy.PriorityHeader, priority)
+		}
+		rr := httptest.NewRecorder()
+		st.handler.HandleAnthropicRequest(rr, req)
+		assert.Equal(st.t, http.StatusOK, rr.Code)
+	}()
+	require.Eventually(st.t, func() bool { return taken(st.snapshot()) }, 5*time.Second, 5*time.Millisecond)
+}
+
+// snapshot reads the scheduler state from /stats
+func (st *schedulerTest) snapshot() proxy.SchedulerSnapshot {
+	rr := httptest.NewRecorder()
+	st.handler.HandleStats(rr, httptest.NewRequest("GET", "/stats", nil))
+	var resp struct {
+		Scheduler proxy.SchedulerSnapshot `json:"scheduler"`
+	}
+	require.NoError(st.t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	return resp.Scheduler
+}
+
+// finish releases the blocked call and returns the order models reached upstream
+func (st *schedulerTest) finish() []string {
+	close(st.release)
+	st.wg.Wait()
+	assert.Equal(st.t, proxy.SchedulerSnapshot{Limit: 1}, st.snapshot())
+	return st.order
+}
+
+// TestPriorityScheduler tests that with MAX_CONCURRENT_REQUESTS saturated an
+// interactive Sonnet request overtakes a Haiku request queued before it
+func TestPriorityScheduler(t *testing.T) {
+	st := newSchedulerTest(t)
+	st.send("claude-sonnet-4-20250514", "", func(s proxy.SchedulerSnapshot) bool { return s.Active == 1 })
+	st.send("claude-3-5-haiku-20241022", "", func(s proxy.SchedulerSnapshot) bool { return s.QueuedBackground == 1 })
+	st.send("claude-sonnet-4-20250514", "", func(s proxy.SchedulerSnapshot) bool { return s.QueuedInteractive == 1 })
+	assert.Equal(t, []string{"big-model", "big-model", "small-model"}, st.finish())
+}
+
+// TestPriorityHeader tests that X-Proxy-Priority overrides the class inferred
+// from the model
+func TestPriorityHeader(t *testing.T) {
+	st := newSchedulerTest(t)
+	st.send("claude-sonnet-4-20250514", "", func(s proxy.SchedulerSnapshot) bool { return s.Active == 1 })
+	st.send("claude-sonnet-4-20250514", proxy.PriorityBackground, func(s proxy.SchedulerSnapshot) bool { return s.QueuedBackground == 1 })
+	st.send("claude-3-5-haiku-20241022", proxy.PriorityInteractive, func(s proxy.SchedulerSnapshot) bool { return s.QueuedInteractive == 1 })
+	assert.Equal(t, []string{"big-model", "small-model", "big-model"}, st.finish())
+}
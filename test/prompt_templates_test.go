@@ -0,0 +1,57 @@
+package test
+
+import (
+	"claude-proxy/correction"
+	"claude-proxy/types"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPromptTemplates tests loading operator prompt templates over the built-in ones
+func TestPromptTemplates(t *testing.T) {
+	service := correction.NewService(NewMockConfigProvider("http://localhost:0"), "test-key", true, "test-model", true, nil)
+	messages := []types.OpenAIMessage{{Role: "user", Content: "Implement the plan"}}
+	builtIn := service.BuildExitPlanModeValidationPrompt("My plan", messages)
+
+	t.Run("MissingDirectoryUsesBuiltIn", func(t *testing.T) {
+		prompts, err := correction.LoadPrompts(filepath.Join(t.TempDir(), "missing"))
+		require.NoError(t, err)
+		service.SetPrompts(prompts)
+		assert.Equal(t, builtIn, service.BuildExitPlanModeValidationPrompt("My plan", messages))
+	})
+
+	t.Run("OverridesSingleTemplate", func(t *testing.T) {
+		dir := t.TempDir()
+		custom := "Plan: {{.Plan}}\nMessages: {{.MessageCount}}\nAnswer BLOCK or ALLOW.\n"
+		require.NoError(t, os.WriteFile(filepath.Join(dir, correction.PromptExitPlanModeValidation+".tmpl"), []byte(custom), 0644))
+
+		prompts, err := correction.LoadPrompts(dir)
+		require.NoError(t, err)
+		service.SetPrompts(prompts)
+		assert.Equal(t, "Plan: My plan\nMessages: 1\nAnswer BLOCK or ALLOW.", service.BuildExitPlanModeValidationPrompt("My plan", messages))
+
+		// Templates not in the directory keep the built-in text
+		filter, err := prompts.Render(correction.PromptExitPlanModeFilter, struct{ Request string }{"read main.go"})
+		require.NoError(t, err)
+		builtInFilter, err := correction.DefaultPrompts().Render(correction.PromptExitPlanModeFilter, struct{ Request string }{"read main.go"})
+		require.NoError(t, err)
+		assert.Equal(t, builtInFilter, filter)
+	})
+
+	invalidTemplates := map[string]string{
+		"syntax":        "Plan: {{.Plan",
+		"unknown field": "Plan: {{.PlanText}}",
+	}
+	for name, invalid := range invalidTemplates {
+		t.Run("Invalid "+name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, correction.PromptExitPlanModeValidation+".tmpl"), []byte(invalid), 0644))
+			_, err := correction.LoadPrompts(dir)
+			assert.Error(t, err)
+		})
+	}
+}
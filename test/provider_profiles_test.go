@@ -0,0 +1,174 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"claude-proxy/config"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// providerProfileTest runs one request through an endpoint using profile and
+// returns the upstream request body and the client response
+func providerProfileTest(t *testing.T, profile string, toolCalls []types.OpenAIToolCall) (map[string]interface{}, types.AnthropicResponse) {
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(types.OpenAIResponse{
+			ID:    "chatcmpl-profile",
+			Model: "llama-3.3-70b",
+			Choices: []types.OpenAIChoice{{
+				Message:      types.OpenAIMessage{Role: "assistant", ToolCalls: toolCalls},
+				FinishReason: stringPtr("tool_calls"),
+			}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := config.GetDefaultConfig()
+	cfg.BigModel = "llama-3.3-70b"
+	cfg.BigModelEndpoints = []string{server.URL + "/openai/v1/chat/completions"}
+	cfg.BigModelAPIKey = "test-key"
+	cfg.ToolCorrectionEnabled = false
+	cfg.EndpointSettings = map[string]config.EndpointSettings{server.URL: {Profile: profile}}
+	handler := proxy.NewHandler(cfg, nil, "")
+
+	body, _ := json.Marshal(types.AnthropicRequest{
+		Model:     "claude-sonnet-4-20250514",
+		MaxTokens: 100,
+		Messages:  []types.Message{{Role: "user", Content: "Read both files"}},
+		Tools:     backendTestTools(),
+	})
+	req := httptest.NewRequest("POST", "/v1/messages", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	handler.HandleAnthropicRequest(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var resp types.AnthropicResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	return received, resp
+}
+
+func readToolCall(id, path string) types.OpenAIToolCall {
+	return types.OpenAIToolCall{
+		ID:       id,
+		Type:     "function",
+		Function: types.OpenAIToolCallFunction{Name: "Read", Arguments: `{"file_path":"` + path + `"}`},
+	}
+}
+
+func TestProviderProfileStripsRejectedParams(t *testing.T) {
+	received, _ := providerProfileTest(t, config.ProviderGroq, []types.OpenAIToolCall{readToolCall("call_1", "/a.go")})
+
+	assert.NotContains(t, received, "cache_prompt", "groq rejects llama.cpp extensions")
+	assert.NotContains(t, received, "parallel_tool_calls")
+	assert.Contains(t, received, "tools")
+}
+
+func TestProviderProfileLimitsParallelToolCalls(t *testing.T) {
+	received, resp := providerProfileTest(t, config.ProviderFireworks, []types.OpenAIToolCall{
+		readToolCall("call_1", "/a.go"),
+		readToolCall("call_2", "/b.go"),
+	})
+
+	assert.Equal(t, false, received["parallel_tool_calls"])
+	var toolUses []types.Content
+	for _, content := range resp.Content {
+		if content.Type == "tool_use" {
+			toolUses = append(toolUses, content)
+		}
+	}
+	require.Len(t, toolUses, 1, "calls beyond the profile's limit are dropped")
+	assert.Equal(t, "call_1", toolUses[0].ID)
+}
+
+func TestProviderProfileFillsToolCallIDs(t *testing.T) {
+	_, resp := providerProfileTest(t, config.ProviderTogether, []types.OpenAIToolCall{
+		readToolCall("", "/a.go"),
+		readToolCall("", "/b.go"),
+	})
+
+	ids := map[string]bool{}
+	for _, content := range resp.Content {
+		if content.Type == "tool_use" {
+			assert.NotEmpty(t, content.ID)
+			ids[content.ID] = true
+		}
+	}
+	assert.Len(t, ids, 2, "each tool call gets its own id")
+}
+
+func TestUnprofiledEndpointSentUnchanged(t *testing.T) {
+	received, resp := providerProfileTest(t, "", []types.OpenAIToolCall{
+		readToolCall("call_1", "/a.go"),
+		readToolCall("call_2", "/b.go"),
+	})
+
+	assert.Equal(t, true, received["cache_prompt"])
+	assert.NotContains(t, received, "parallel_tool_calls")
+	assert.Len(t, resp.Content, 2)
+}
+
+func TestLMStudioProfile(t *testing.T) {
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = nil
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(types.OpenAIResponse{
+			ID:    "chatcmpl-lmstudio",
+			Model: "qwen2.5-coder-7b-instruct",
+			Choices: []types.OpenAIChoice{{
+				Message:      types.OpenAIMessage{Role: "assistant", Content: "All files read.<|im_end|>\n"},
+				FinishReason: stringPtr("stop"),
+			}},
+		})
+	}))
+	defer server.Close()
+
+	cfg := config.GetDefaultConfig()
+	cfg.SmallModel = "qwen2.5-coder-7b-instruct"
+	cfg.SmallModelEndpoints = []string{server.URL + "/v1/chat/completions"}
+	cfg.SmallModelAPIKey = "lm-studio"
+	cfg.ToolCorrectionEnabled = false
+	cfg.EndpointSettings = map[string]config.EndpointSettings{server.URL: {Profile: config.ProviderLMStudio}}
+	handler := proxy.NewHandler(cfg, nil, "")
+
+	send := func(stream bool) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(types.AnthropicRequest{
+			Model:     "claude-3-5-haiku-20241022",
+			MaxTokens: 100,
+			Stream:    stream,
+			Messages:  []types.Message{{Role: "user", Content: "Read the files"}},
+			Tools:     backendTestTools(),
+		})
+		req := httptest.NewRequest("POST", "/v1/messages", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		handler.HandleAnthropicRequest(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		return rr
+	}
+
+	rr := send(false)
+	var resp types.AnthropicResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Len(t, resp.Content, 1)
+	assert.Equal(t, "All files read.", resp.Content[0].Text, "leaked end-of-turn token is trimmed")
+	assert.NotContains(t, received, "cache_prompt")
+
+	rr = send(true)
+	assert.NotContains(t, received, "stream", "tool-enabled requests are not streamed from LM Studio")
+	assert.NotContains(t, received, "stream_options")
+	assert.Contains(t, rr.Body.String(), "event: message_stop", "the client still receives a stream")
+	assert.NotContains(t, rr.Body.String(), "im_end")
+}
@@ -0,0 +1,99 @@
+package test
+
+import (
+	"claude-proxy/proxy"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestProxyDebugHeader tests the x-proxy-debug trace for JSON and SSE responses
+func TestProxyDebugHeader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["stream"] == true {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, `data: {"id":"s1","model":"kimi-k2","choices":[{"index":0,"delta":{"content":"hi"},"finish_reason":"stop"}]}`+"\n\n")
+			fmt.Fprint(w, "data: [DONE]\n\n")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":    "resp_1",
+			"model": "kimi-k2",
+			"choices": []map[string]interface{}{{
+				"index":         0,
+				"message":       map[string]interface{}{"role": "assistant", "content": "hi"},
+				"finish_reason": "stop",
+			}},
+		})
+	}))
+	defer server.Close()
+
+	send := func(enabled bool, header string, stream bool) *httptest.ResponseRecorder {
+		cfg := newErrorCodeTestConfig("http://127.0.0.1:1")
+		cfg.BigModelEndpoints = []string{server.URL}
+		cfg.ProxyDebugHeaderEnabled = enabled
+		handler := proxy.NewHandler(cfg, nil, "")
+
+		body := fmt.Sprintf(`{"model": "claude-sonnet-4-20250514", "max_tokens": 100, "stream": %t, "messages": [{"role": "user", "content": "hi"}]}`, stream)
+		req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(body))
+		if header != "" {
+			req.Header.Set("x-proxy-debug", header)
+		}
+		rr := httptest.NewRecorder()
+		handler.HandleAnthropicRequest(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		return rr
+	}
+
+	t.Run("JSONResponseIncludesTrace", func(t *testing.T) {
+		rr := send(true, "true", false)
+		var resp struct {
+			Content    []map[string]interface{} `json:"content"`
+			ProxyDebug *proxy.DebugTrace        `json:"proxy_debug"`
+		}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		require.NotEmpty(t, resp.Content)
+		require.NotNil(t, resp.ProxyDebug)
+		assert.Equal(t, server.URL, resp.ProxyDebug.Endpoint)
+		require.Len(t, resp.ProxyDebug.Attempts, 1)
+		assert.Empty(t, resp.ProxyDebug.Attempts[0].Error)
+		assert.Contains(t, resp.ProxyDebug.Steps, "model mapped: claude-sonnet-4-20250514 -> kimi-k2")
+		for _, stage := range []string{"request_transform", "upstream", "response_transform", "total"} {
+			assert.Contains(t, resp.ProxyDebug.TimingsMs, stage)
+		}
+	})
+
+	t.Run("DisabledByConfig", func(t *testing.T) {
+		rr := send(false, "true", false)
+		assert.NotContains(t, rr.Body.String(), "proxy_debug")
+	})
+
+	t.Run("NotRequested", func(t *testing.T) {
+		rr := send(true, "", false)
+		assert.NotContains(t, rr.Body.String(), "proxy_debug")
+	})
+
+	t.Run("StreamEndsWithTraceComment", func(t *testing.T) {
+		rr := send(true, "true", true)
+		output := rr.Body.String()
+		stopIndex := strings.Index(output, "event: message_stop")
+		traceIndex := strings.Index(output, "\n: proxy_debug {")
+		require.NotEqual(t, -1, traceIndex)
+		assert.Greater(t, traceIndex, stopIndex, "trace comment follows message_stop")
+
+		line := output[traceIndex+len("\n: proxy_debug "):]
+		line = line[:strings.Index(line, "\n")]
+		var trace proxy.DebugTrace
+		require.NoError(t, json.Unmarshal([]byte(line), &trace))
+		assert.Equal(t, server.URL, trace.Endpoint)
+	})
+}
@@ -0,0 +1,132 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"claude-proxy/config"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// historyRecord mirrors the fields of GET /admin/requests the tests read
+type historyRecord struct {
+	RequestID string `json:"request_id"`
+	Status    int    `json:"status"`
+	ErrorCode string `json:"error_code"`
+	Model     string `json:"model"`
+	Request   *struct {
+		Messages int      `json:"messages"`
+		Tools    []string `json:"tools"`
+	} `json:"request"`
+	Upstream *struct {
+		Model string   `json:"model"`
+		Roles []string `json:"roles"`
+	} `json:"upstream"`
+	Response *struct {
+		StopReason string   `json:"stop_reason"`
+		ToolCalls  []string `json:"tool_calls"`
+	} `json:"response"`
+	Trace *struct {
+		Steps []string `json:"steps"`
+	} `json:"trace"`
+}
+
+// TestRequestHistory tests that handled requests are listed newest first as
+// summaries without message text, and that the detail view adds the audit trail
+func TestRequestHistory(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(types.OpenAIResponse{
+			ID:    "chatcmpl-history",
+			Model: "backend-model",
+			Choices: []types.OpenAIChoice{{
+				Message:      types.OpenAIMessage{Role: "assistant", Content: "Done"},
+				FinishReason: stringPtr("stop"),
+			}},
+		})
+	}))
+	defer server.Close()
+
+	cfg := config.GetDefaultConfig()
+	cfg.BigModel = "backend-model"
+	cfg.BigModelEndpoints = []string{server.URL}
+	cfg.BigModelAPIKey = "test-key"
+	cfg.RequestHistorySize = 2
+	handler := proxy.NewHandler(cfg, nil, "")
+
+	send := func(requestID string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/messages", bytes.NewReader(body))
+		req.Header.Set("X-Request-ID", requestID)
+		rr := httptest.NewRecorder()
+		handler.HandleAnthropicRequest(rr, req)
+		return rr
+	}
+	valid, _ := json.Marshal(types.AnthropicRequest{
+		Model:     "claude-sonnet-4-20250514",
+		MaxTokens: 100,
+		Messages:  []types.Message{{Role: "user", Content: "secret prompt text"}},
+		Tools:     backendTestTools(),
+	})
+	for i := 1; i <= 2; i++ {
+		rr := send(fmt.Sprintf("history-%d", i), valid)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		assert.NotContains(t, rr.Body.String(), "proxy_debug", "history traces are not returned to the client")
+	}
+	send("history-bad", []byte("{not json"))
+
+	rr := httptest.NewRecorder()
+	handler.HandleRequestHistory(rr, httptest.NewRequest(http.MethodGet, "/admin/requests", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "secret prompt text")
+	var list struct {
+		Requests []historyRecord `json:"requests"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &list))
+	require.Len(t, list.Requests, 2, "the oldest request is dropped")
+	assert.Equal(t, "history-bad", list.Requests[0].RequestID)
+	assert.Equal(t, http.StatusBadRequest, list.Requests[0].Status)
+	assert.Equal(t, string(proxy.CodeInvalidRequest), list.Requests[0].ErrorCode)
+
+	ok := list.Requests[1]
+	assert.Equal(t, "history-2", ok.RequestID)
+	assert.Equal(t, http.StatusOK, ok.Status)
+	assert.Equal(t, "claude-sonnet-4-20250514", ok.Model)
+	require.NotNil(t, ok.Request)
+	assert.Equal(t, 1, ok.Request.Messages)
+	assert.NotEmpty(t, ok.Request.Tools)
+	require.NotNil(t, ok.Upstream)
+	assert.Equal(t, "backend-model", ok.Upstream.Model)
+	assert.Contains(t, ok.Upstream.Roles, "user")
+	require.NotNil(t, ok.Response)
+	assert.Equal(t, "end_turn", ok.Response.StopReason)
+	assert.Nil(t, ok.Trace, "the list leaves out the audit trail")
+
+	rr = httptest.NewRecorder()
+	handler.HandleRequestHistory(rr, httptest.NewRequest(http.MethodGet, "/admin/requests/history-2", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	var detail historyRecord
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &detail))
+	require.NotNil(t, detail.Trace)
+	assert.Contains(t, strings.Join(detail.Trace.Steps, "\n"), "model mapped: claude-sonnet-4-20250514 -> backend-model")
+
+	rr = httptest.NewRecorder()
+	handler.HandleRequestHistory(rr, httptest.NewRequest(http.MethodGet, "/admin/requests/history-1", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	rr = httptest.NewRecorder()
+	handler.HandleRequestHistory(rr, httptest.NewRequest(http.MethodGet, "/admin/requests?limit=0", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	cfg.RequestHistorySize = 0
+	rr = httptest.NewRecorder()
+	proxy.NewHandler(cfg, nil, "").HandleRequestHistory(rr, httptest.NewRequest(http.MethodGet, "/admin/requests", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,79 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"claude-proxy/proxy"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRequestIDPassthrough tests that X-Request-ID is honored, echoed and forwarded upstream
+func TestRequestIDPassthrough(t *testing.T) {
+	var upstreamRequestID string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamRequestID = r.Header.Get("X-Request-ID")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":    "resp_1",
+			"model": "kimi-k2",
+			"choices": []map[string]interface{}{{
+				"index":         0,
+				"message":       map[string]interface{}{"role": "assistant", "content": "hello"},
+				"finish_reason": "stop",
+			}},
+		})
+	}))
+	defer server.Close()
+
+	cfg := newErrorCodeTestConfig("http://127.0.0.1:1")
+	cfg.BigModelEndpoints = []string{server.URL}
+	handler := proxy.NewHandler(cfg, nil, "")
+
+	send := func(requestID string) *httptest.ResponseRecorder {
+		body := `{"model": "claude-3-5-sonnet-20241022", "max_tokens": 100, "messages": [{"role": "user", "content": "hi"}]}`
+		req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(body))
+		if requestID != "" {
+			req.Header.Set("X-Request-ID", requestID)
+		}
+		rr := httptest.NewRecorder()
+		handler.HandleAnthropicRequest(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		return rr
+	}
+
+	t.Run("IncomingIDIsEchoedAndForwarded", func(t *testing.T) {
+		rr := send("wrapper-7f3a9c")
+		assert.Equal(t, "wrapper-7f3a9c", rr.Header().Get("X-Request-ID"))
+		assert.Equal(t, "wrapper-7f3a9c", upstreamRequestID)
+	})
+
+	t.Run("MissingIDIsGenerated", func(t *testing.T) {
+		rr := send("")
+		generated := rr.Header().Get("X-Request-ID")
+		assert.True(t, strings.HasPrefix(generated, "req_"), "expected generated ID, got %q", generated)
+		assert.Equal(t, generated, upstreamRequestID)
+	})
+
+	t.Run("UnsafeIDIsReplaced", func(t *testing.T) {
+		rr := send("bad id\twith spaces")
+		assert.True(t, strings.HasPrefix(rr.Header().Get("X-Request-ID"), "req_"))
+
+		rr = send(strings.Repeat("a", 200))
+		assert.True(t, strings.HasPrefix(rr.Header().Get("X-Request-ID"), "req_"))
+	})
+
+	t.Run("ErrorResponsesEchoID", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader("{not json"))
+		req.Header.Set("X-Request-ID", "wrapper-err")
+		rr := httptest.NewRecorder()
+		handler.HandleAnthropicRequest(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, "wrapper-err", rr.Header().Get("X-Request-ID"))
+	})
+}
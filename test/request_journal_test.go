@@ -0,0 +1,190 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"claude-proxy/config"
+	"claude-proxy/journal"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRequestJournalInterrupted tests that requests accepted but never
+// finished before the journal was reopened are reported as interrupted, and
+// that reopening compacts the file down to them
+func TestRequestJournalInterrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+
+	j, err := journal.Open(path)
+	require.NoError(t, err)
+	doneID, err := j.Accept(journal.Entry{RequestID: "req-done", Model: "m", Request: json.RawMessage(`{"n":1}`)})
+	require.NoError(t, err)
+	cutID, err := j.Accept(journal.Entry{RequestID: "req-cut", Model: "m", Request: json.RawMessage(`{"n":2}`)})
+	require.NoError(t, err)
+	require.NoError(t, j.Finish(doneID, http.StatusOK))
+	assert.Equal(t, 1, j.InFlight())
+	assert.Empty(t, j.Interrupted(), "open requests of this process are not interrupted")
+	require.NoError(t, j.Close())
+
+	// A crash mid-write leaves a torn last line
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	file.WriteString(`{"op":"accepted","request_id":"req-torn","requ`)
+	file.Close()
+
+	j, err = journal.Open(path)
+	require.NoError(t, err)
+	defer j.Close()
+	interrupted := j.Interrupted()
+	require.Len(t, interrupted, 1)
+	assert.Equal(t, cutID, interrupted[0].ID)
+	assert.Equal(t, "req-cut", interrupted[0].RequestID)
+	assert.JSONEq(t, `{"n":2}`, string(interrupted[0].Request))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 1, "compaction keeps only the interrupted request")
+	assert.Contains(t, lines[0], `"request_id":"req-cut"`)
+
+	found, err := j.Resolve(cutID, 0)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Empty(t, j.Interrupted())
+	found, err = j.Resolve(cutID, 0)
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+// TestRequestJournalAdmin tests journaling through the handler and listing,
+// replaying and dismissing interrupted requests via /admin/journal
+func TestRequestJournalAdmin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(types.OpenAIResponse{
+			ID:    "chatcmpl-journal",
+			Model: "backend-model",
+			Choices: []types.OpenAIChoice{{
+				Message:      types.OpenAIMessage{Role: "assistant", Content: "Replayed"},
+				FinishReason: stringPtr("stop"),
+			}},
+		})
+	}))
+	defer server.Close()
+
+	// An earlier run accepted two requests and crashed before answering them
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	earlier, err := journal.Open(path)
+	require.NoError(t, err)
+	body, _ := json.Marshal(types.AnthropicRequest{
+		Model:     "claude-sonnet-4-20250514",
+		MaxTokens: 100,
+		Messages:  []types.Message{{Role: "user", Content: "Hello"}},
+	})
+	plainID, err := earlier.Accept(journal.Entry{RequestID: "req-plain", Model: "claude-sonnet-4-20250514", Request: body, Path: "/v1/messages"})
+	require.NoError(t, err)
+	streamID, err := earlier.Accept(journal.Entry{RequestID: "req-stream", Model: "claude-sonnet-4-20250514", Stream: true, Request: body, Path: "/v1/messages"})
+	require.NoError(t, err)
+	require.NoError(t, earlier.Close())
+
+	j, err := journal.Open(path)
+	require.NoError(t, err)
+	defer j.Close()
+
+	cfg := config.GetDefaultConfig()
+	cfg.BigModel = "backend-model"
+	cfg.BigModelEndpoints = []string{server.URL}
+	cfg.ToolCorrectionEnabled = false
+	handler := proxy.NewHandler(cfg, nil, "")
+	handler.SetJournal(j)
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.HandleJournal(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	var list struct {
+		InFlight    int `json:"in_flight"`
+		Interrupted []struct {
+			ID         string `json:"id"`
+			RequestID  string `json:"request_id"`
+			Replayable bool   `json:"replayable"`
+		} `json:"interrupted"`
+	}
+	rec := serve(http.MethodGet, "/admin/journal")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Len(t, list.Interrupted, 2)
+	assert.Equal(t, plainID, list.Interrupted[0].ID)
+	assert.Equal(t, "req-plain", list.Interrupted[0].RequestID)
+	assert.True(t, list.Interrupted[0].Replayable)
+	assert.False(t, list.Interrupted[1].Replayable)
+	assert.NotContains(t, rec.Body.String(), "Hello", "the listing leaves out request bodies")
+
+	rec = serve(http.MethodGet, "/admin/journal/"+plainID)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Hello")
+
+	rec = serve(http.MethodPost, "/admin/journal/"+streamID+"/replay")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = serve(http.MethodPost, "/admin/journal/"+plainID+"/replay")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "req-plain_replay", rec.Header().Get("X-Request-ID"))
+	var resp types.AnthropicResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Content, 1)
+	assert.Equal(t, "Replayed", resp.Content[0].Text)
+
+	assert.Equal(t, http.StatusNoContent, serve(http.MethodDelete, "/admin/journal/"+streamID).Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodDelete, "/admin/journal/"+streamID).Code)
+	assert.Empty(t, j.Interrupted())
+
+	// Requests served while the journal is set are accepted and finished
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", bytes.NewReader(body))
+	handler.HandleAnthropicRequest(httptest.NewRecorder(), req)
+	assert.Equal(t, 0, j.InFlight())
+	require.NoError(t, j.Close())
+	reopened, err := journal.Open(path)
+	require.NoError(t, err)
+	defer reopened.Close()
+	assert.Empty(t, reopened.Interrupted(), "answered and resolved requests are not interrupted")
+}
+
+// TestRequestJournalRepeatedRequestID tests that requests sharing a request ID,
+// e.g. a client reusing X-Request-ID, are journaled as separate entries
+func TestRequestJournalRepeatedRequestID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+
+	j, err := journal.Open(path)
+	require.NoError(t, err)
+	firstID, err := j.Accept(journal.Entry{RequestID: "req-same", Model: "m", Request: json.RawMessage(`{"n":1}`)})
+	require.NoError(t, err)
+	secondID, err := j.Accept(journal.Entry{RequestID: "req-same", Model: "m", Request: json.RawMessage(`{"n":2}`)})
+	require.NoError(t, err)
+	assert.NotEqual(t, firstID, secondID)
+	assert.Equal(t, 2, j.InFlight())
+	require.NoError(t, j.Close())
+
+	j, err = journal.Open(path)
+	require.NoError(t, err)
+	defer j.Close()
+	require.Len(t, j.Interrupted(), 2)
+	first, ok := j.Get(firstID)
+	require.True(t, ok)
+	assert.JSONEq(t, `{"n":1}`, string(first.Request))
+	second, ok := j.Get(secondID)
+	require.True(t, ok)
+	assert.JSONEq(t, `{"n":2}`, string(second.Request))
+}
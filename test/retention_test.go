@@ -0,0 +1,175 @@
+package test
+
+import (
+	"claude-proxy/batch"
+	"claude-proxy/config"
+	"claude-proxy/conversation"
+	"claude-proxy/journal"
+	"claude-proxy/proxy"
+	"claude-proxy/stats"
+	"claude-proxy/types"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestConversationStorePurge tests age, size and session based deletion
+func TestConversationStorePurge(t *testing.T) {
+	store := newConversationTestStore(t)
+	for _, id := range []string{"req_1", "req_2", "req_3"} {
+		addTestExchange(t, store, id, "session_"+id, "Explain the retry loop", &types.AnthropicResponse{
+			Content: []types.Content{{Type: "text", Text: "It retries three times"}},
+		})
+	}
+	count := func() int {
+		results, err := store.Search(conversation.Query{Text: "retry"})
+		require.NoError(t, err)
+		return len(results)
+	}
+
+	deleted, err := store.Purge(time.Now().Add(-time.Hour), 0)
+	require.NoError(t, err)
+	assert.Zero(t, deleted, "nothing is older than an hour")
+
+	deleted, err = store.Delete("session_req_2", "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	_, ok, err := store.Get("req_2")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 2, count())
+
+	// A tiny size limit deletes the oldest exchanges first
+	deleted, err = store.Purge(time.Time{}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+	assert.Zero(t, count())
+
+	_, err = store.Delete("", "")
+	assert.Error(t, err, "deleting needs a session or request ID")
+}
+
+// TestRetentionManager tests scheduled retention and the purge endpoint
+func TestRetentionManager(t *testing.T) {
+	conversations := newConversationTestStore(t)
+	addTestExchange(t, conversations, "req_1", "session_a", "Summarize the log", &types.AnthropicResponse{})
+	addTestExchange(t, conversations, "req_2", "session_b", "Summarize the log", &types.AnthropicResponse{})
+
+	batches, err := batch.OpenStore(filepath.Join(t.TempDir(), "batches.db"))
+	require.NoError(t, err)
+	defer batches.Close()
+	endedAt := time.Now().Add(-48 * time.Hour)
+	for _, record := range []batch.Record{
+		{Batch: batch.Batch{ID: "msgbatch_old", ProcessingStatus: batch.StatusEnded, CreatedAt: endedAt, EndedAt: &endedAt}},
+		{Batch: batch.Batch{ID: "msgbatch_running", ProcessingStatus: batch.StatusInProgress, CreatedAt: endedAt}},
+	} {
+		require.NoError(t, batches.Create(record, []batch.Entry{batchEntry("a", "hello")}))
+	}
+
+	collector := stats.NewCollector()
+	collector.RecordUsage("test-model", 10, 5, 0)
+
+	cfg := config.GetDefaultConfig()
+	cfg.BatchRetentionDays = 1
+	cfg.StatsRetentionDays = 1
+	retention := proxy.NewRetentionManager(cfg, conversations, batches, nil, collector)
+
+	result, err := retention.Enforce(time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, proxy.PurgeResult{Batches: 1}, result, "stats span less than a day")
+	_, ok, err := batches.Get("msgbatch_old")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	entries, err := batches.Entries("msgbatch_old")
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	_, ok, err = batches.Get("msgbatch_running")
+	require.NoError(t, err)
+	assert.True(t, ok, "batches still processing are kept")
+
+	result, err = retention.Enforce(time.Now().Add(48 * time.Hour))
+	require.NoError(t, err)
+	assert.True(t, result.StatsReset)
+	assert.Empty(t, collector.Totals().Models)
+
+	purge := func(query string) (int, proxy.PurgeResult) {
+		rr := httptest.NewRecorder()
+		retention.HandlePurge(rr, httptest.NewRequest("POST", "/admin/purge?"+query, nil))
+		var result proxy.PurgeResult
+		if rr.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+		}
+		return rr.Code, result
+	}
+
+	code, result := purge("target=conversations&request_id=req_1")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 1, result.Conversations)
+
+	code, result = purge("target=conversations&older_than=0s")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 1, result.Conversations)
+
+	code, _ = purge("target=conversations")
+	assert.Equal(t, http.StatusBadRequest, code, "purging everything needs a selector")
+	code, _ = purge("target=batches&before=yesterday")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = purge("target=logs")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+// TestRetentionManagerJournal tests that interrupted journal entries, which
+// hold raw request bodies, are dismissed by retention and purge requests
+func TestRetentionManagerJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	earlier, err := journal.Open(path)
+	require.NoError(t, err)
+	acceptedAt := time.Now().Add(-48 * time.Hour)
+	for _, entry := range []journal.Entry{
+		{RequestID: "req_old", AcceptedAt: acceptedAt, Request: json.RawMessage(`{"secret":"old"}`)},
+		{RequestID: "req_new", Request: json.RawMessage(`{"secret":"new"}`)},
+		{RequestID: "req_erase", Request: json.RawMessage(`{"secret":"erase"}`)},
+	} {
+		_, err := earlier.Accept(entry)
+		require.NoError(t, err)
+	}
+	require.NoError(t, earlier.Close())
+
+	j, err := journal.Open(path)
+	require.NoError(t, err)
+	defer j.Close()
+
+	cfg := config.GetDefaultConfig()
+	cfg.JournalRetentionDays = 1
+	retention := proxy.NewRetentionManager(cfg, nil, nil, j, nil)
+
+	result, err := retention.Enforce(time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, proxy.PurgeResult{Journal: 1}, result)
+	require.Len(t, j.Interrupted(), 2)
+
+	rr := httptest.NewRecorder()
+	retention.HandlePurge(rr, httptest.NewRequest("POST", "/admin/purge?target=journal&request_id=req_erase", nil))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+	assert.Equal(t, 1, result.Journal)
+
+	interrupted := j.Interrupted()
+	require.Len(t, interrupted, 1)
+	assert.Equal(t, "req_new", interrupted[0].RequestID)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), `"old"`, "dismissed bodies are removed from the file")
+	assert.NotContains(t, string(data), `"erase"`)
+
+	rr = httptest.NewRecorder()
+	retention.HandlePurge(rr, httptest.NewRequest("POST", "/admin/purge?target=journal", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "purging the journal needs a selector")
+}
@@ -0,0 +1,166 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"claude-proxy/config"
+	"claude-proxy/internal"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func float64Ptr(v float64) *float64 {
+	return &v
+}
+
+// TestSamplingParamsPerBackend tests that sampling parameters are translated,
+// clamped, defaulted or dropped according to the backend model profile
+func TestSamplingParamsPerBackend(t *testing.T) {
+	cfg := &config.Config{
+		ModelProfiles: map[string]config.ModelProfile{
+			"gpt-oss:20b": {
+				Temperature: &config.ParamRange{Min: float64Ptr(0), Max: float64Ptr(0.8), Default: float64Ptr(0.6)},
+			},
+			"o3-mini": {DropParams: []string{"temperature", "top_p"}},
+		},
+	}
+	ctx := internal.WithRequestID(context.Background(), "sampling_params_test")
+
+	transform := func(model string, temperature, topP *float64) types.OpenAIRequest {
+		req := types.AnthropicRequest{
+			Model:       model,
+			Messages:    []types.Message{{Role: "user", Content: "hi"}},
+			Temperature: temperature,
+			TopP:        topP,
+		}
+		result, err := proxy.TransformAnthropicToOpenAI(ctx, req, cfg)
+		require.NoError(t, err)
+		return result
+	}
+
+	t.Run("PassthroughWithoutProfile", func(t *testing.T) {
+		result := transform("qwen3-coder", float64Ptr(0.3), float64Ptr(0.9))
+		assert.Equal(t, 0.3, result.Temperature)
+		require.NotNil(t, result.TopP)
+		assert.Equal(t, 0.9, *result.TopP)
+	})
+
+	t.Run("ClampedToRange", func(t *testing.T) {
+		result := transform("gpt-oss:20b", float64Ptr(1.0), nil)
+		assert.Equal(t, 0.8, result.Temperature)
+		assert.Nil(t, result.TopP)
+	})
+
+	t.Run("DefaultWhenMissing", func(t *testing.T) {
+		result := transform("gpt-oss:20b", nil, nil)
+		assert.Equal(t, 0.6, result.Temperature)
+	})
+
+	t.Run("DroppedForRejectingBackend", func(t *testing.T) {
+		result := transform("o3-mini", float64Ptr(1.0), float64Ptr(0.9))
+		body, err := json.Marshal(result)
+		require.NoError(t, err)
+		assert.NotContains(t, string(body), "temperature")
+		assert.NotContains(t, string(body), "top_p")
+	})
+}
+
+// TestMaxTokensMapping tests max_tokens clamping and max_completion_tokens translation
+func TestMaxTokensMapping(t *testing.T) {
+	cfg := &config.Config{
+		ModelProfiles: map[string]config.ModelProfile{
+			"qwen3-coder": {MaxOutputTokens: 8192},
+			"o3-mini":     {MaxOutputTokens: 16000, MaxTokensField: config.MaxTokensFieldCompletion},
+			"no-limit":    {DropParams: []string{"max_tokens"}},
+		},
+	}
+	ctx := internal.WithRequestID(context.Background(), "max_tokens_test")
+
+	transform := func(model string, maxTokens int) types.OpenAIRequest {
+		req := types.AnthropicRequest{
+			Model:     model,
+			Messages:  []types.Message{{Role: "user", Content: "hi"}},
+			MaxTokens: maxTokens,
+		}
+		result, err := proxy.TransformAnthropicToOpenAI(ctx, req, cfg)
+		require.NoError(t, err)
+		return result
+	}
+
+	t.Run("PassthroughWithoutProfile", func(t *testing.T) {
+		result := transform("kimi-k2", 32000)
+		assert.Equal(t, 32000, result.MaxTokens)
+		assert.Zero(t, result.MaxCompletionTokens)
+	})
+
+	t.Run("ClampedToBackendLimit", func(t *testing.T) {
+		assert.Equal(t, 8192, transform("qwen3-coder", 32000).MaxTokens)
+		assert.Equal(t, 1024, transform("qwen3-coder", 1024).MaxTokens)
+	})
+
+	t.Run("TranslatedToMaxCompletionTokens", func(t *testing.T) {
+		result := transform("o3-mini", 32000)
+		assert.Zero(t, result.MaxTokens)
+		assert.Equal(t, 16000, result.MaxCompletionTokens)
+
+		body, err := json.Marshal(result)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), `"max_completion_tokens":16000`)
+		assert.NotContains(t, string(body), `"max_tokens"`)
+	})
+
+	t.Run("Dropped", func(t *testing.T) {
+		result := transform("no-limit", 32000)
+		assert.Zero(t, result.MaxTokens)
+		assert.Zero(t, result.MaxCompletionTokens)
+	})
+}
+
+// TestAnthropicOnlySamplingParams tests top_k passthrough and stop_sequences translation
+func TestAnthropicOnlySamplingParams(t *testing.T) {
+	cfg := &config.Config{
+		ModelProfiles: map[string]config.ModelProfile{
+			"qwen3-coder": {PassthroughParams: []string{"top_k"}},
+			"strict-api":  {DropParams: []string{"stop"}},
+		},
+	}
+	ctx := internal.WithRequestID(context.Background(), "anthropic_only_params_test")
+	topK := 40
+
+	transform := func(model string, stop []string) types.OpenAIRequest {
+		req := types.AnthropicRequest{
+			Model:         model,
+			Messages:      []types.Message{{Role: "user", Content: "hi"}},
+			TopK:          &topK,
+			StopSequences: stop,
+		}
+		result, err := proxy.TransformAnthropicToOpenAI(ctx, req, cfg)
+		require.NoError(t, err)
+		return result
+	}
+
+	t.Run("TopKForwardedWhenSupported", func(t *testing.T) {
+		result := transform("qwen3-coder", nil)
+		require.NotNil(t, result.TopK)
+		assert.Equal(t, 40, *result.TopK)
+	})
+
+	t.Run("TopKDroppedOtherwise", func(t *testing.T) {
+		result := transform("gpt-4o", nil)
+		assert.Nil(t, result.TopK)
+		body, err := json.Marshal(result)
+		require.NoError(t, err)
+		assert.NotContains(t, string(body), "top_k")
+	})
+
+	t.Run("StopSequencesTranslated", func(t *testing.T) {
+		assert.Equal(t, []string{"\n\nHuman:"}, transform("gpt-4o", []string{"\n\nHuman:"}).Stop)
+		assert.Len(t, transform("gpt-4o", []string{"a", "b", "c", "d", "e"}).Stop, 4)
+		assert.Nil(t, transform("strict-api", []string{"a"}).Stop)
+	})
+}
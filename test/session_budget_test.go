@@ -0,0 +1,138 @@
+package test
+
+import (
+	"bytes"
+	"claude-proxy/circuitbreaker"
+	"claude-proxy/config"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// budgetTestServer answers every request with 100 prompt and 60 completion tokens
+func budgetTestServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(types.OpenAIResponse{
+			ID: "chatcmpl-budget",
+			Choices: []types.OpenAIChoice{
+				{Index: 0, Message: types.OpenAIMessage{Role: "assistant", Content: "ok"}, FinishReason: stringPtr("stop")},
+			},
+			Usage: types.OpenAIUsage{PromptTokens: 100, CompletionTokens: 60, TotalTokens: 160},
+		})
+	}))
+}
+
+// sendBudgetRequest sends a request for a session, or with only an API key
+// when session is empty
+func sendBudgetRequest(handler *proxy.Handler, session, apiKey string) *httptest.ResponseRecorder {
+	anthropicReq := types.AnthropicRequest{
+		Model:     "claude-sonnet-4-20250514",
+		MaxTokens: 100,
+		Messages:  []types.Message{{Role: "user", Content: "Hello"}},
+	}
+	if session != "" {
+		anthropicReq.Metadata = &types.RequestMetadata{UserID: session}
+	}
+	reqJSON, _ := json.Marshal(anthropicReq)
+	req := httptest.NewRequest("POST", "/v1/messages", bytes.NewReader(reqJSON))
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("X-Api-Key", apiKey)
+	}
+	rr := httptest.NewRecorder()
+	handler.HandleAnthropicRequest(rr, req)
+	return rr
+}
+
+func TestSessionTokenBudget(t *testing.T) {
+	alerts := make(chan circuitbreaker.Alert, 4)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert circuitbreaker.Alert
+		json.NewDecoder(r.Body).Decode(&alert)
+		alerts <- alert
+	}))
+	defer webhook.Close()
+	upstream := budgetTestServer()
+	defer upstream.Close()
+
+	cfg := config.GetDefaultConfig()
+	cfg.BigModel = "test-model"
+	cfg.BigModelEndpoints = []string{upstream.URL}
+	cfg.BigModelAPIKey = "test-key"
+	cfg.SessionBudgetTokens = 300
+	cfg.AlertWebhookURL = webhook.URL
+	handler := proxy.NewHandler(cfg, nil, "")
+
+	// 160 tokens, then 320: the request that crosses the budget still completes
+	require.Equal(t, http.StatusOK, sendBudgetRequest(handler, "session-a", "").Code)
+	require.Equal(t, http.StatusOK, sendBudgetRequest(handler, "session-a", "").Code)
+
+	select {
+	case alert := <-alerts:
+		assert.Equal(t, circuitbreaker.AlertBudgetExceeded, alert.Type)
+		assert.Equal(t, "session-a", alert.Session)
+		assert.Contains(t, alert.Message, "320 of 300 tokens")
+	case <-time.After(2 * time.Second):
+		t.Fatal("no budget alert was sent")
+	}
+
+	rr := sendBudgetRequest(handler, "session-a", "")
+	require.Equal(t, http.StatusPaymentRequired, rr.Code)
+	var errResp struct {
+		Error struct {
+			Type    string `json:"type"`
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errResp))
+	assert.Equal(t, "billing_error", errResp.Error.Type)
+	assert.Equal(t, string(proxy.CodeBudgetExceeded), errResp.Error.Code)
+	assert.Contains(t, errResp.Error.Message, "session token budget exhausted")
+
+	// Other sessions keep their own budget
+	assert.Equal(t, http.StatusOK, sendBudgetRequest(handler, "session-b", "").Code)
+
+	select {
+	case alert := <-alerts:
+		t.Fatalf("unexpected second alert: %+v", alert)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestSessionCostBudgetByClientKey(t *testing.T) {
+	upstream := budgetTestServer()
+	defer upstream.Close()
+
+	cfg := config.GetDefaultConfig()
+	cfg.BigModel = "test-model"
+	cfg.BigModelEndpoints = []string{upstream.URL}
+	cfg.BigModelAPIKey = "test-key"
+	cfg.ModelPricing = map[string]config.ModelPrice{"test-model": {InputPerMTok: 1000, OutputPerMTok: 1000}}
+	cfg.SessionBudgetUSD = 0.1 // 160 tokens cost $0.16
+	handler := proxy.NewHandler(cfg, nil, "")
+
+	// Requests without metadata.user_id are charged to their API key
+	require.Equal(t, http.StatusOK, sendBudgetRequest(handler, "", "sk-client-one").Code)
+	rr := sendBudgetRequest(handler, "", "sk-client-one")
+	assert.Equal(t, http.StatusPaymentRequired, rr.Code)
+	assert.Contains(t, rr.Body.String(), "session cost budget exhausted")
+	assert.Equal(t, http.StatusOK, sendBudgetRequest(handler, "", "sk-client-two").Code)
+
+	// Sessions are scoped to the key: another key sending the same user_id has its own budget
+	require.Equal(t, http.StatusOK, sendBudgetRequest(handler, "session-shared", "sk-client-three").Code)
+	assert.Equal(t, http.StatusPaymentRequired, sendBudgetRequest(handler, "session-shared", "sk-client-three").Code)
+	assert.Equal(t, http.StatusOK, sendBudgetRequest(handler, "session-shared", "sk-client-four").Code)
+
+	// Requests that identify no session are not limited
+	assert.Equal(t, http.StatusOK, sendBudgetRequest(handler, "", "").Code)
+	assert.Equal(t, http.StatusOK, sendBudgetRequest(handler, "", "").Code)
+}
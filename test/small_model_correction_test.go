@@ -0,0 +1,91 @@
+package test
+
+import (
+	"claude-proxy/proxy"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDisableSmallModelCorrection tests that DISABLE_SMALL_MODEL_CORRECTION
+// forwards SMALL_MODEL tool calls without necessity detection or correction
+func TestDisableSmallModelCorrection(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":    "resp_1",
+			"model": "qwen2.5-coder:latest",
+			"choices": []map[string]interface{}{{
+				"index": 0,
+				"message": map[string]interface{}{
+					"role": "assistant",
+					"tool_calls": []map[string]interface{}{{
+						"id":       "call_1",
+						"type":     "function",
+						"function": map[string]interface{}{"name": "Read", "arguments": `{"path": "/tmp/main.go"}`},
+					}},
+				},
+				"finish_reason": "tool_calls",
+			}},
+		})
+	}))
+	defer backend.Close()
+
+	var correctionCalls int32
+	correctionServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&correctionCalls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer correctionServer.Close()
+
+	send := func(disabled bool, model string) map[string]interface{} {
+		cfg := newErrorCodeTestConfig(backend.URL)
+		cfg.BigModelEndpoints = []string{backend.URL}
+		cfg.ToolCorrectionEnabled = true
+		cfg.ToolCorrectionEndpoints = []string{correctionServer.URL}
+		cfg.ToolCorrectionAPIKey = "test-key"
+		cfg.DisableSmallModelCorrection = disabled
+		handler := proxy.NewHandler(cfg, nil, "")
+
+		body := `{"model": "` + model + `", "max_tokens": 100, "messages": [{"role": "user", "content": "read main.go"}],
+			"tools": [{"name": "Read", "description": "Read a file", "input_schema": {"type": "object", "properties": {"file_path": {"type": "string"}}, "required": ["file_path"]}}]}`
+		req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		handler.HandleAnthropicRequest(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		var resp struct {
+			Content []struct {
+				Type  string                 `json:"type"`
+				Input map[string]interface{} `json:"input"`
+			} `json:"content"`
+		}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		require.Len(t, resp.Content, 1)
+		require.Equal(t, "tool_use", resp.Content[0].Type)
+		return resp.Content[0].Input
+	}
+
+	t.Run("SmallModelSkipsCorrection", func(t *testing.T) {
+		atomic.StoreInt32(&correctionCalls, 0)
+		input := send(true, "claude-3-5-haiku-20241022")
+		assert.Equal(t, map[string]interface{}{"path": "/tmp/main.go"}, input)
+		assert.Equal(t, int32(0), atomic.LoadInt32(&correctionCalls), "small model requests never reach the correction model")
+	})
+
+	t.Run("BigModelStillCorrected", func(t *testing.T) {
+		input := send(true, "claude-sonnet-4-20250514")
+		assert.Equal(t, map[string]interface{}{"file_path": "/tmp/main.go"}, input)
+	})
+
+	t.Run("DisabledByDefault", func(t *testing.T) {
+		input := send(false, "claude-3-5-haiku-20241022")
+		assert.Equal(t, map[string]interface{}{"file_path": "/tmp/main.go"}, input)
+	})
+}
@@ -0,0 +1,136 @@
+package test
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/proxy"
+	"claude-proxy/stats"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClientSpendAccounting tests daily and monthly spend per client key,
+// pruning of old days and that spend survives ResetTotals and restarts
+func TestClientSpendAccounting(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "stats.db")
+	store, err := stats.OpenStore(dbPath)
+	require.NoError(t, err)
+	collector := stats.NewCollector()
+	stop, err := collector.StartPersistence(store, time.Hour, nil)
+	require.NoError(t, err)
+
+	march := time.Date(2026, time.March, 31, 23, 0, 0, 0, time.UTC)
+	collector.RecordClientSpend("client-a", 1.00, time.Date(2026, time.January, 15, 0, 0, 0, 0, time.UTC))
+	collector.RecordClientSpend("client-a", 2.00, time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC))
+	collector.RecordClientSpend("client-a", 0.50, march)
+	collector.RecordClientSpend("client-a", 0.25, march.Add(30*time.Minute))
+	collector.RecordClientSpend("client-b", 9.00, march)
+
+	day, month := collector.ClientSpend("client-a", march)
+	assert.InDelta(t, 0.75, day, 0.0001)
+	assert.InDelta(t, 2.75, month, 0.0001)
+
+	// January is dropped once March spend is recorded
+	assert.NotContains(t, collector.Totals().ClientSpend["client-a"], "2026-01-15")
+
+	// The next UTC day starts a new day, April a new month
+	april := time.Date(2026, time.April, 1, 0, 30, 0, 0, time.UTC)
+	day, month = collector.ClientSpend("client-a", april)
+	assert.Zero(t, day)
+	assert.Zero(t, month)
+
+	collector.ResetTotals()
+	require.NoError(t, stop())
+
+	store, err = stats.OpenStore(dbPath)
+	require.NoError(t, err)
+	restarted := stats.NewCollector()
+	stop, err = restarted.StartPersistence(store, time.Hour, nil)
+	require.NoError(t, err)
+	defer stop()
+	day, month = restarted.ClientSpend("client-a", march)
+	assert.InDelta(t, 0.75, day, 0.0001)
+	assert.InDelta(t, 2.75, month, 0.0001)
+}
+
+func TestClientSpendLimits(t *testing.T) {
+	upstream := budgetTestServer()
+	defer upstream.Close()
+
+	cfg := config.GetDefaultConfig()
+	cfg.BigModel = "test-model"
+	cfg.BigModelEndpoints = []string{upstream.URL}
+	cfg.BigModelAPIKey = "test-key"
+	cfg.ModelPricing = map[string]config.ModelPrice{"test-model": {InputPerMTok: 1000, OutputPerMTok: 1000}}
+	cfg.SpendLimits = config.SpendLimits{Default: config.SpendLimit{DailyUSD: 0.3}} // $0.16 per request
+	handler := proxy.NewHandler(cfg, nil, "")
+
+	require.Equal(t, http.StatusOK, sendBudgetRequest(handler, "", "sk-client-one").Code)
+	require.Equal(t, http.StatusOK, sendBudgetRequest(handler, "", "sk-client-one").Code)
+
+	rr := sendBudgetRequest(handler, "", "sk-client-one")
+	require.Equal(t, http.StatusTooManyRequests, rr.Code)
+	var errResp struct {
+		Error struct {
+			Type    string `json:"type"`
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errResp))
+	assert.Equal(t, "rate_limit_error", errResp.Error.Type)
+	assert.Equal(t, string(proxy.CodeSpendLimitExceeded), errResp.Error.Code)
+	assert.Contains(t, errResp.Error.Message, "daily spend limit of $0.30 reached")
+	retryAfter, err := strconv.Atoi(rr.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	assert.True(t, retryAfter > 0 && retryAfter <= 24*60*60+1, "Retry-After %d", retryAfter)
+
+	// Other keys have their own spend
+	assert.Equal(t, http.StatusOK, sendBudgetRequest(handler, "", "sk-client-two").Code)
+
+	// Limits reloaded at runtime apply to the next request
+	handler.SetSpendLimits(config.SpendLimits{Default: config.SpendLimit{DailyUSD: 1, MonthlyUSD: 0.4}})
+	assert.Equal(t, http.StatusOK, sendBudgetRequest(handler, "", "sk-client-one").Code)
+	rr = sendBudgetRequest(handler, "", "sk-client-one")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Contains(t, rr.Body.String(), "monthly spend limit of $0.40 reached")
+
+	// Requests without an API key are not limited
+	assert.Equal(t, http.StatusOK, sendBudgetRequest(handler, "", "").Code)
+}
+
+// TestClientSpendLimitOverride tests that CLIENT_SPEND_LIMITS overrides the
+// default limit for one key ID, the ID /stats reports spend under
+func TestClientSpendLimitOverride(t *testing.T) {
+	upstream := budgetTestServer()
+	defer upstream.Close()
+
+	cfg := config.GetDefaultConfig()
+	cfg.BigModel = "test-model"
+	cfg.BigModelEndpoints = []string{upstream.URL}
+	cfg.BigModelAPIKey = "test-key"
+	cfg.ModelPricing = map[string]config.ModelPrice{"test-model": {InputPerMTok: 1000, OutputPerMTok: 1000}}
+	handler := proxy.NewHandler(cfg, nil, "")
+
+	require.Equal(t, http.StatusOK, sendBudgetRequest(handler, "", "sk-limited").Code)
+	clientSpend := handler.Stats().Totals().ClientSpend
+	require.Len(t, clientSpend, 1)
+	var keyID string
+	for id := range clientSpend {
+		keyID = id
+	}
+
+	handler.SetSpendLimits(config.SpendLimits{Keys: map[string]config.SpendLimit{keyID: {DailyUSD: 0.1}}})
+	assert.Equal(t, http.StatusTooManyRequests, sendBudgetRequest(handler, "", "sk-limited").Code)
+	assert.Equal(t, http.StatusOK, sendBudgetRequest(handler, "", "sk-unlimited").Code)
+
+	// The key itself never appears in the stats
+	body, _ := json.Marshal(handler.Stats().Totals())
+	assert.NotContains(t, string(body), "sk-limited")
+}
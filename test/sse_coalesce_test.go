@@ -0,0 +1,70 @@
+package test
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/proxy"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flushCountingRecorder counts flushes and checks each one lands on an event boundary
+type flushCountingRecorder struct {
+	*httptest.ResponseRecorder
+	flushes       int
+	midEventFlush bool
+}
+
+func (r *flushCountingRecorder) Flush() {
+	r.flushes++
+	if !strings.HasSuffix(r.Body.String(), "\n\n") {
+		r.midEventFlush = true
+	}
+	r.ResponseRecorder.Flush()
+}
+
+// TestSSEChunkCoalescing tests that coalescing reduces flushes without splitting events
+func TestSSEChunkCoalescing(t *testing.T) {
+	longText := strings.Repeat("token ", 300)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, _ := json.Marshal(longText)
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, `data: {"id":"s1","model":"kimi-k2","choices":[{"index":0,"delta":{"content":%s},"finish_reason":null}]}`+"\n\n", content)
+		fmt.Fprint(w, `data: {"id":"s1","model":"kimi-k2","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`+"\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	send := func(configure func(cfg *config.Config)) *flushCountingRecorder {
+		cfg := newErrorCodeTestConfig("http://127.0.0.1:1")
+		cfg.BigModelEndpoints = []string{server.URL}
+		configure(cfg)
+		handler := proxy.NewHandler(cfg, nil, "")
+
+		body := `{"model": "claude-sonnet-4-20250514", "max_tokens": 100, "stream": true, "messages": [{"role": "user", "content": "hi"}]}`
+		rec := &flushCountingRecorder{ResponseRecorder: httptest.NewRecorder()}
+		handler.HandleAnthropicRequest(rec, httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(body)))
+		require.Equal(t, http.StatusOK, rec.Code)
+		return rec
+	}
+
+	perEvent := send(func(cfg *config.Config) {})
+	events := strings.Count(perEvent.Body.String(), "data: ")
+	assert.Equal(t, events, perEvent.flushes, "every event is flushed without coalescing")
+
+	bySize := send(func(cfg *config.Config) { cfg.SSECoalesceBytes = 2048 })
+	assert.Less(t, bySize.flushes, events/5)
+	assert.GreaterOrEqual(t, bySize.flushes, 2)
+	assert.False(t, bySize.midEventFlush, "flushes must preserve event boundaries")
+	assert.Equal(t, perEvent.Body.String(), bySize.Body.String())
+
+	byTime := send(func(cfg *config.Config) { cfg.SSECoalesceIntervalMs = 60000 })
+	assert.Equal(t, 1, byTime.flushes, "only the final flush after message_stop")
+	assert.True(t, strings.HasSuffix(byTime.Body.String(), "event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"))
+}
@@ -0,0 +1,98 @@
+package test
+
+import (
+	"claude-proxy/proxy"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSSEVerifierOrdering tests detection of malformed event sequences
+func TestSSEVerifierOrdering(t *testing.T) {
+	block := func(index int) map[string]interface{} {
+		return map[string]interface{}{"index": index}
+	}
+
+	t.Run("ValidSequence", func(t *testing.T) {
+		v := proxy.NewSSEVerifier()
+		v.Observe("message_start", map[string]interface{}{})
+		for i := 0; i < 2; i++ {
+			v.Observe("content_block_start", block(i))
+			v.Observe("content_block_delta", block(i))
+			v.Observe("content_block_stop", block(i))
+		}
+		v.Observe("message_delta", map[string]interface{}{})
+		v.Observe("message_stop", map[string]interface{}{})
+		assert.Empty(t, v.Finish())
+	})
+
+	tests := []struct {
+		name     string
+		events   []string
+		indices  []int
+		contains string
+	}{
+		{"DeltaBeforeStart", []string{"content_block_delta"}, []int{0}, "before message_start"},
+		{"SkippedIndex", []string{"message_start", "content_block_start"}, []int{0, 1}, "index 1, expected 0"},
+		{"DeltaWrongBlock", []string{"message_start", "content_block_start", "content_block_delta"}, []int{0, 0, 1}, "does not match open block 0"},
+		{"UnclosedBlock", []string{"message_start", "content_block_start", "message_delta"}, []int{0, 0, 0}, "block 0 is still open"},
+		{"MissingStop", []string{"message_start", "message_delta"}, []int{0, 0}, "without message_stop"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := proxy.NewSSEVerifier()
+			for i, event := range tt.events {
+				v.Observe(event, block(tt.indices[i]))
+			}
+			violations := v.Finish()
+			require.NotEmpty(t, violations)
+			assert.Contains(t, strings.Join(violations, "; "), tt.contains)
+		})
+	}
+}
+
+// TestEmittedSSESequenceIsValid tests that the handler's streamed output passes verification
+func TestEmittedSSESequenceIsValid(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"id":"s1","model":"kimi-k2","choices":[{"index":0,"delta":{"content":"Running the tests now"},"finish_reason":null}]}`+"\n\n")
+		fmt.Fprint(w, `data: {"id":"s1","model":"kimi-k2","choices":[{"index":0,"delta":{"tool_calls":[{"id":"call_1","type":"function","function":{"name":"Bash","arguments":"{\"command\":\"go test ./...\"}"}}]},"finish_reason":null}]}`+"\n\n")
+		fmt.Fprint(w, `data: {"id":"s1","model":"kimi-k2","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`+"\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	cfg := newErrorCodeTestConfig("http://127.0.0.1:1")
+	cfg.BigModelEndpoints = []string{server.URL}
+	cfg.SSEVerifyEnabled = true
+	handler := proxy.NewHandler(cfg, nil, "")
+
+	body := `{"model": "claude-sonnet-4-20250514", "max_tokens": 100, "stream": true, "messages": [{"role": "user", "content": "run tests"}],
+		"tools": [{"name": "Bash", "description": "Run a command", "input_schema": {"type": "object", "properties": {"command": {"type": "string"}}, "required": ["command"]}}]}`
+	rr := httptest.NewRecorder()
+	handler.HandleAnthropicRequest(rr, httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	// Replay the recorded stream through an independent verifier
+	verifier := proxy.NewSSEVerifier()
+	var eventType string
+	for _, line := range strings.Split(rr.Body.String(), "\n") {
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			eventType = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			var data map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &data))
+			verifier.Observe(eventType, data)
+		}
+	}
+	assert.Empty(t, verifier.Finish())
+	assert.Contains(t, rr.Body.String(), `"name":"Bash"`)
+}
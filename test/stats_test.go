@@ -0,0 +1,118 @@
+package test
+
+import (
+	"claude-proxy/circuitbreaker"
+	"claude-proxy/config"
+	"claude-proxy/correction"
+	"claude-proxy/proxy"
+	"claude-proxy/stats"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStatsCollectorSnapshot tests per-model counts, latency percentiles and counters
+func TestStatsCollectorSnapshot(t *testing.T) {
+	collector := stats.NewCollector()
+
+	for i := 1; i <= 100; i++ {
+		collector.RecordRequest("small-model", time.Duration(i)*time.Millisecond, i%10 == 0)
+	}
+	collector.RecordRequest("big-model", 2*time.Second, false)
+	collector.RecordCorrection(stats.CorrectionApplied)
+	collector.RecordCorrection(stats.CorrectionApplied)
+	collector.RecordCorrection(stats.CorrectionFailed)
+	collector.RecordHarmonyDetection()
+
+	snapshot := collector.Snapshot()
+	assert.Equal(t, int64(101), snapshot.TotalRequests)
+	assert.Equal(t, int64(10), snapshot.TotalErrors)
+	assert.Equal(t, int64(1), snapshot.HarmonyDetections)
+	assert.Equal(t, int64(2), snapshot.Corrections[stats.CorrectionApplied])
+	assert.Equal(t, int64(1), snapshot.Corrections[stats.CorrectionFailed])
+
+	small := snapshot.Models["small-model"]
+	assert.Equal(t, int64(100), small.Requests)
+	assert.Equal(t, int64(10), small.Errors)
+	assert.InDelta(t, 50.5, small.AvgLatencyMs, 0.001)
+	assert.InDelta(t, 50, small.P50LatencyMs, 0.001)
+	assert.InDelta(t, 95, small.P95LatencyMs, 0.001)
+	assert.InDelta(t, 99, small.P99LatencyMs, 0.001)
+
+	assert.InDelta(t, 2000, snapshot.Models["big-model"].P99LatencyMs, 0.001)
+}
+
+// TestHandleStatsEndpoint tests that /stats records proxied requests and reports circuits
+func TestHandleStatsEndpoint(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.BigModel = "big-model"
+	cfg.BigModelEndpoints = []string{"http://127.0.0.1:1/v1/chat/completions"}
+	cfg.HealthManager = circuitbreaker.NewHealthManager(circuitbreaker.DefaultConfig())
+	cfg.HealthManager.InitializeEndpoints(cfg.BigModelEndpoints)
+	handler := proxy.NewHandler(cfg, nil, "")
+
+	// Malformed request is counted as an error under the unknown model
+	rec := httptest.NewRecorder()
+	handler.HandleAnthropicRequest(rec, httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader("{not json")))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.HandleStats(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var body struct {
+		TotalRequests int64                           `json:"total_requests"`
+		TotalErrors   int64                           `json:"total_errors"`
+		Models        map[string]stats.ModelSnapshot  `json:"models"`
+		Circuits      []circuitbreaker.EndpointHealth `json:"circuits"`
+		LearnedRules  []correction.LearnedMapping     `json:"learned_rules"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, int64(1), body.TotalRequests)
+	assert.Equal(t, int64(1), body.TotalErrors)
+	assert.Equal(t, int64(1), body.Models["unknown"].Requests)
+	require.Len(t, body.Circuits, 1)
+	assert.Equal(t, cfg.BigModelEndpoints[0], body.Circuits[0].URL)
+	assert.NotNil(t, body.LearnedRules, "learned rules are reported even when empty")
+}
+
+// TestStatsPersistenceAcrossRestarts tests that cumulative totals survive a collector restart
+func TestStatsPersistenceAcrossRestarts(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "stats.db")
+
+	store, err := stats.OpenStore(dbPath)
+	require.NoError(t, err)
+	first := stats.NewCollector()
+	stop, err := first.StartPersistence(store, time.Hour, nil)
+	require.NoError(t, err)
+
+	first.RecordRequest("small-model", 10*time.Millisecond, false)
+	first.RecordUsage("small-model", 1000, 500, 0.25)
+	first.RecordCorrection(stats.CorrectionApplied)
+	require.NoError(t, stop())
+
+	// Simulated restart: session counters reset, cumulative totals are restored
+	store, err = stats.OpenStore(dbPath)
+	require.NoError(t, err)
+	second := stats.NewCollector()
+	stop, err = second.StartPersistence(store, time.Hour, nil)
+	require.NoError(t, err)
+	second.RecordRequest("small-model", 20*time.Millisecond, true)
+
+	snapshot := second.Snapshot()
+	assert.Equal(t, int64(1), snapshot.TotalRequests)
+	cumulative := snapshot.Cumulative.Models["small-model"]
+	assert.Equal(t, int64(2), cumulative.Requests)
+	assert.Equal(t, int64(1), cumulative.Errors)
+	assert.Equal(t, int64(1000), cumulative.InputTokens)
+	assert.InDelta(t, 0.25, cumulative.CostUSD, 0.0001)
+	assert.Equal(t, int64(1), snapshot.Cumulative.Corrections[stats.CorrectionApplied])
+	require.NoError(t, stop())
+}
@@ -0,0 +1,88 @@
+package test
+
+import (
+	"claude-proxy/circuitbreaker"
+	"claude-proxy/stats"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readStatsDLines collects metric lines from a UDP listener until no more arrive
+func readStatsDLines(t *testing.T, conn net.PacketConn) []string {
+	t.Helper()
+	var lines []string
+	buf := make([]byte, 2048)
+	for {
+		conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return lines
+		}
+		lines = append(lines, string(buf[:n]))
+	}
+}
+
+// TestStatsDEmitterMetricSet tests that collector and circuit events are emitted in StatsD format
+func TestStatsDEmitterMetricSet(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	emitter, err := stats.NewStatsDEmitter(listener.LocalAddr().String(), "simple_proxy")
+	require.NoError(t, err)
+	defer emitter.Close()
+
+	collector := stats.NewCollector()
+	collector.SetEmitter(emitter)
+	collector.RecordRequest("qwen3", 42*time.Millisecond, true)
+	collector.RecordUsage("qwen3", 100, 20, 0)
+	collector.RecordCorrection(stats.CorrectionApplied)
+
+	hm := circuitbreaker.NewHealthManager(circuitbreaker.Config{
+		FailureThreshold:   1,
+		BackoffDuration:    time.Second,
+		MaxBackoffDuration: time.Second,
+	})
+	hm.AddStateChangeListener(emitter.CircuitStateChanged)
+	hm.RecordFailureWithError("http://endpoint-a", errors.New("timeout"))
+	hm.RecordFailureWithError("http://endpoint-a", errors.New("timeout")) // Already open, no second transition
+	hm.RecordSuccess("http://endpoint-a")
+
+	lines := readStatsDLines(t, listener)
+	assert.Equal(t, []string{
+		"simple_proxy.request.count:1|c|#model:qwen3",
+		"simple_proxy.request.errors:1|c|#model:qwen3",
+		"simple_proxy.request.latency:42|ms|#model:qwen3",
+		"simple_proxy.tokens.input:100|c|#model:qwen3",
+		"simple_proxy.tokens.output:20|c|#model:qwen3",
+		"simple_proxy.corrections:1|c|#outcome:applied",
+		"simple_proxy.circuit.transitions:1|c|#endpoint:http://endpoint-a,state:open",
+		"simple_proxy.circuit.open:1|g|#endpoint:http://endpoint-a",
+		"simple_proxy.circuit.transitions:1|c|#endpoint:http://endpoint-a,state:closed",
+		"simple_proxy.circuit.open:0|g|#endpoint:http://endpoint-a",
+	}, lines)
+}
+
+// TestStatsDEmitterSanitizesTags tests that tag values cannot break the line protocol
+func TestStatsDEmitterSanitizesTags(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	emitter, err := stats.NewStatsDEmitter(listener.LocalAddr().String(), "")
+	require.NoError(t, err)
+	defer emitter.Close()
+
+	emitter.CorrectionCompleted("bad|value,#x")
+
+	lines := readStatsDLines(t, listener)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "corrections:1|c|#outcome:bad_value__x", lines[0])
+	assert.False(t, strings.Contains(lines[0], "\n"))
+}
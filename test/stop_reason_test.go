@@ -0,0 +1,148 @@
+package test
+
+import (
+	"bytes"
+	"claude-proxy/config"
+	"claude-proxy/internal"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFinishReasonMapping tests finish_reason to stop_reason mapping,
+// including provider-specific values, operator overrides and reconciliation
+// with the returned tool calls
+func TestFinishReasonMapping(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.StopReasons = map[string]string{"recitation": config.StopReasonRefusal}
+	ctx := internal.WithRequestID(context.Background(), "stop_reason_test")
+
+	toolCall := []types.OpenAIToolCall{{
+		ID:       "call_1",
+		Type:     "function",
+		Function: types.OpenAIToolCallFunction{Name: "Bash", Arguments: `{"command":"ls"}`},
+	}}
+
+	tests := []struct {
+		finishReason *string
+		toolCalls    []types.OpenAIToolCall
+		expected     string
+	}{
+		{stringPtr("stop"), nil, "end_turn"},
+		{stringPtr("length"), nil, "max_tokens"},
+		{stringPtr("tool_calls"), toolCall, "tool_use"},
+		{stringPtr("function_call"), toolCall, "tool_use"},
+		{stringPtr("content_filter"), nil, "refusal"},
+		{stringPtr("eos_token"), nil, "end_turn"},
+		{stringPtr("recitation"), nil, "refusal"},
+		{stringPtr("provider_hiccup"), nil, "end_turn"},
+		{nil, nil, "end_turn"},
+		// llama.cpp and Ollama report "stop" with tool calls
+		{stringPtr("stop"), toolCall, "tool_use"},
+		{nil, toolCall, "tool_use"},
+		// Truncated tool call stays max_tokens
+		{stringPtr("length"), toolCall, "max_tokens"},
+		// tool_calls without any tool call is a plain end of turn
+		{stringPtr("tool_calls"), nil, "end_turn"},
+	}
+
+	for _, tt := range tests {
+		name := "nil"
+		if tt.finishReason != nil {
+			name = *tt.finishReason
+		}
+		t.Run(fmt.Sprintf("%s/tools=%d", name, len(tt.toolCalls)), func(t *testing.T) {
+			resp := &types.OpenAIResponse{
+				ID: "chatcmpl-stop",
+				Choices: []types.OpenAIChoice{{
+					Message:      types.OpenAIMessage{Role: "assistant", Content: "Working on it", ToolCalls: tt.toolCalls},
+					FinishReason: tt.finishReason,
+				}},
+			}
+			result, err := proxy.TransformOpenAIToAnthropic(ctx, resp, "claude-sonnet-4-20250514", cfg)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result.StopReason)
+		})
+	}
+}
+
+// TestStreamingMessageDeltaStopReason tests that streamed responses report
+// max_tokens and tool_use in the message_delta event
+func TestStreamingMessageDeltaStopReason(t *testing.T) {
+	tests := []struct {
+		name     string
+		chunks   []string
+		expected string
+	}{
+		{
+			name: "length",
+			chunks: []string{
+				`{"id":"chatcmpl-test","model":"test-model","choices":[{"index":0,"delta":{"content":"Truncated"},"finish_reason":null}]}`,
+				`{"id":"chatcmpl-test","model":"test-model","choices":[{"index":0,"delta":{},"finish_reason":"length"}]}`,
+			},
+			expected: "max_tokens",
+		},
+		{
+			name: "tool_calls",
+			chunks: []string{
+				`{"id":"chatcmpl-test","model":"test-model","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"Bash","arguments":"{\"command\":\"ls\"}"}}]},"finish_reason":null}]}`,
+				`{"id":"chatcmpl-test","model":"test-model","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+			},
+			expected: "tool_use",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				for _, chunk := range tt.chunks {
+					fmt.Fprintf(w, "data: %s\n\n", chunk)
+				}
+				fmt.Fprint(w, "data: [DONE]\n\n")
+			}))
+			defer mockServer.Close()
+
+			cfg := config.GetDefaultConfig()
+			cfg.BigModel = "test-model"
+			cfg.BigModelEndpoints = []string{mockServer.URL}
+			cfg.BigModelAPIKey = "test-key"
+			cfg.ToolCorrectionEnabled = false
+			handler := proxy.NewHandler(cfg, nil, "")
+
+			reqJSON, _ := json.Marshal(map[string]interface{}{
+				"model":      "claude-sonnet-4-20250514",
+				"max_tokens": 100,
+				"stream":     true,
+				"messages":   []map[string]interface{}{{"role": "user", "content": "List files"}},
+			})
+			req := httptest.NewRequest("POST", "/v1/messages", bytes.NewReader(reqJSON))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+
+			handler.HandleAnthropicRequest(rr, req)
+
+			var stopReason interface{}
+			for _, line := range strings.Split(rr.Body.String(), "\n") {
+				if !strings.HasPrefix(line, "data: ") {
+					continue
+				}
+				var event map[string]interface{}
+				if json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event) != nil || event["type"] != "message_delta" {
+					continue
+				}
+				stopReason = event["delta"].(map[string]interface{})["stop_reason"]
+			}
+			assert.Equal(t, tt.expected, stopReason, rr.Body.String())
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package test
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/proxy"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStreamRateLimits tests that streamed responses are paced to the
+// client's token or byte rate, and that per-key overrides apply
+func TestStreamRateLimits(t *testing.T) {
+	text := strings.TrimSpace(strings.Repeat("word ", 100))
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chunk, _ := json.Marshal(map[string]interface{}{
+			"id":    "s1",
+			"model": "kimi-k2",
+			"choices": []map[string]interface{}{{
+				"index":         0,
+				"delta":         map[string]interface{}{"content": text},
+				"finish_reason": "stop",
+			}},
+		})
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: %s\n\ndata: [DONE]\n\n", chunk)
+	}))
+	defer backend.Close()
+
+	const apiKey = "sk-throttled"
+	sum := sha256.Sum256([]byte(apiKey))
+	keyID := hex.EncodeToString(sum[:8])
+
+	send := func(limits config.StreamRateLimits, key string) (time.Duration, int) {
+		cfg := newErrorCodeTestConfig("http://127.0.0.1:1")
+		cfg.BigModelEndpoints = []string{backend.URL}
+		cfg.StreamRateLimits = limits
+		handler := proxy.NewHandler(cfg, nil, "")
+
+		body := `{"model": "claude-sonnet-4-20250514", "max_tokens": 100, "stream": true, "messages": [{"role": "user", "content": "hi"}]}`
+		req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("X-Api-Key", key)
+		}
+		rr := httptest.NewRecorder()
+		start := time.Now()
+		handler.HandleAnthropicRequest(rr, req)
+		elapsed := time.Since(start)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		assert.Contains(t, rr.Body.String(), "message_stop")
+		return elapsed, rr.Body.Len()
+	}
+
+	unthrottled, streamBytes := send(config.StreamRateLimits{}, "")
+
+	t.Run("TokensPerSecond", func(t *testing.T) {
+		// 100 words are at least 100 tokens: a second at 500 tokens/s is 200ms
+		elapsed, _ := send(config.StreamRateLimits{Default: config.StreamRateLimit{TokensPerSecond: 500}}, "")
+		assert.GreaterOrEqual(t, elapsed, 150*time.Millisecond)
+	})
+
+	t.Run("BytesPerSecond", func(t *testing.T) {
+		// The whole stream at 5x its size per second takes 200ms
+		elapsed, _ := send(config.StreamRateLimits{Default: config.StreamRateLimit{BytesPerSecond: float64(streamBytes * 5)}}, "")
+		assert.GreaterOrEqual(t, elapsed, 150*time.Millisecond)
+	})
+
+	t.Run("PerKeyOverride", func(t *testing.T) {
+		limits := config.StreamRateLimits{
+			Default: config.StreamRateLimit{TokensPerSecond: 10},
+			Keys:    map[string]config.StreamRateLimit{keyID: {}},
+		}
+		elapsed, _ := send(limits, apiKey)
+		assert.Less(t, elapsed, unthrottled+time.Second, "a key without limits is not throttled by the default")
+	})
+}
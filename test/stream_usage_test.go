@@ -0,0 +1,71 @@
+package test
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStreamIncludeUsage tests that stream_options.include_usage is requested and
+// the trailing usage chunk reaches the Anthropic message_delta event
+func TestStreamIncludeUsage(t *testing.T) {
+	var upstreamReq types.OpenAIRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		upstreamReq = types.OpenAIRequest{}
+		json.Unmarshal(body, &upstreamReq)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"id":"stream_1","model":"kimi-k2","choices":[{"index":0,"delta":{"content":"Hello"},"finish_reason":null}]}`+"\n\n")
+		fmt.Fprint(w, `data: {"id":"stream_1","model":"kimi-k2","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`+"\n\n")
+		if upstreamReq.StreamOptions != nil && upstreamReq.StreamOptions.IncludeUsage {
+			fmt.Fprint(w, `data: {"id":"stream_1","model":"kimi-k2","choices":[],"usage":{"prompt_tokens":42,"completion_tokens":7,"total_tokens":49}}`+"\n\n")
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	send := func(cfg *config.Config) (string, types.OpenAIRequest) {
+		cfg.BigModelEndpoints = []string{server.URL}
+		handler := proxy.NewHandler(cfg, nil, "")
+		body := `{"model": "claude-sonnet-4-20250514", "max_tokens": 100, "stream": true, "messages": [{"role": "user", "content": "hi"}]}`
+		rr := httptest.NewRecorder()
+		handler.HandleAnthropicRequest(rr, httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(body)))
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		return rr.Body.String(), upstreamReq
+	}
+
+	t.Run("UsageChunkPropagated", func(t *testing.T) {
+		cfg := newErrorCodeTestConfig("http://127.0.0.1:1")
+		cfg.StreamIncludeUsage = true
+
+		output, sent := send(cfg)
+		require.NotNil(t, sent.StreamOptions)
+		assert.True(t, sent.StreamOptions.IncludeUsage)
+		assert.Contains(t, output, `"input_tokens":42`)
+		assert.Contains(t, output, `"usage":{"output_tokens":7}`)
+		assert.Contains(t, output, "Hello")
+	})
+
+	t.Run("ProfileOptOut", func(t *testing.T) {
+		cfg := newErrorCodeTestConfig("http://127.0.0.1:1")
+		cfg.StreamIncludeUsage = true
+		cfg.ModelProfiles = map[string]config.ModelProfile{
+			"kimi-k2": {DropParams: []string{"stream_options"}},
+		}
+
+		output, sent := send(cfg)
+		assert.Nil(t, sent.StreamOptions)
+		assert.Contains(t, output, `"usage":{"output_tokens":0}`)
+	})
+}
@@ -0,0 +1,129 @@
+package test
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/correction"
+	"claude-proxy/internal"
+	"claude-proxy/types"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestValidateStructure tests that array items and nested objects are checked
+// against the items and properties of the schema, with a path per violation
+func TestValidateStructure(t *testing.T) {
+	validator := types.NewStandardToolValidator()
+	todoWrite := types.GetFallbackToolSchema("TodoWrite")
+	require.NotNil(t, todoWrite)
+
+	valid := types.Content{Name: "TodoWrite", Input: map[string]interface{}{"todos": []interface{}{
+		map[string]interface{}{"id": "1", "content": "Write tests", "status": "pending", "priority": "high"},
+	}}}
+	assert.Empty(t, validator.ValidateStructure(valid, todoWrite.InputSchema))
+
+	malformed := types.Content{Name: "TodoWrite", Input: map[string]interface{}{"todos": []interface{}{
+		map[string]interface{}{"id": "1", "task": "Write tests", "status": "pending", "priority": "high"},
+		map[string]interface{}{"id": "2", "content": "Ship it", "status": "blocked", "priority": 1},
+		"just a string",
+	}}}
+	assert.Equal(t, []string{
+		"todos[0].content: missing",
+		"todos[0].task: unknown",
+		"todos[1].priority: expected string",
+		"todos[1].status: not one of pending, in_progress, completed",
+		"todos[2]: expected object",
+	}, validator.ValidateStructure(malformed, todoWrite.InputSchema))
+
+	// Nested arrays and objects without a schema for their contents are not checked
+	schema := types.ToolSchema{
+		Type: "object",
+		Properties: map[string]types.ToolProperty{
+			"matrix": {Type: "array", Items: &types.ToolPropertyItems{Type: "array", Items: &types.ToolPropertyItems{Type: "number"}}},
+			"config": {Type: "object", Properties: map[string]types.ToolProperty{
+				"retries": {Type: "integer"},
+			}},
+			"free": {Type: "object"},
+		},
+	}
+	call := types.Content{Name: "Probe", Input: map[string]interface{}{
+		"matrix": []interface{}{[]interface{}{1.0, 2.0}, []interface{}{3.0, "four"}},
+		"config": map[string]interface{}{"retries": 1.5},
+		"free":   map[string]interface{}{"anything": "goes"},
+	}}
+	assert.Equal(t, []string{
+		"config.retries: expected integer",
+		"matrix[1][1]: expected number",
+	}, validator.ValidateStructure(call, schema))
+
+	coerced, params := validator.CoerceTypes(types.Content{Name: "Probe", Input: map[string]interface{}{
+		"matrix": []interface{}{[]interface{}{"1", 2.0}},
+	}}, schema)
+	assert.Equal(t, []string{"matrix"}, params)
+	assert.Equal(t, []interface{}{[]interface{}{1.0, 2.0}}, coerced.Input["matrix"])
+}
+
+// TestStructuralMismatchShallowSchema tests that tools sent with a schema that
+// does not describe their array items are checked against the built-in one
+func TestStructuralMismatchShallowSchema(t *testing.T) {
+	service := correction.NewService(NewMockConfigProvider("http://test.com"), "test-key", true, "test-model", true, nil)
+	shallow := []types.Tool{{
+		Name: "TodoWrite",
+		InputSchema: types.ToolSchema{
+			Type: "object",
+			Properties: map[string]types.ToolProperty{
+				"todos": {Type: "array", Items: &types.ToolPropertyItems{Type: "object"}},
+			},
+			Required: []string{"todos"},
+		},
+	}}
+
+	assert.True(t, service.HasStructuralMismatch(types.Content{Type: "tool_use", Name: "TodoWrite", Input: map[string]interface{}{
+		"todos": []interface{}{map[string]interface{}{"id": "1", "description": "Write tests", "status": "pending", "priority": "high"}},
+	}}, shallow))
+	assert.False(t, service.HasStructuralMismatch(types.Content{Type: "tool_use", Name: "TodoWrite", Input: map[string]interface{}{
+		"todos": []interface{}{map[string]interface{}{"id": "1", "content": "Write tests", "status": "pending", "priority": "high"}},
+	}}, shallow))
+}
+
+// TestNestedCorrectionSkipsLLM tests that mistyped values inside array items
+// are fixed without the correction model
+func TestNestedCorrectionSkipsLLM(t *testing.T) {
+	var llmCalls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&llmCalls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cfg := config.GetDefaultConfig()
+	cfg.ToolCorrectionEndpoints = []string{server.URL}
+	cfg.ToolCorrectionAPIKey = "test-key"
+	service := correction.NewService(cfg, "test-key", true, "test-model", true, nil)
+	ctx := internal.WithRequestID(context.Background(), "nested_correction_test")
+	multiEdit := types.GetFallbackToolSchema("MultiEdit")
+	require.NotNil(t, multiEdit)
+
+	corrected, err := service.CorrectToolCalls(ctx, []types.Content{{
+		Type: "tool_use",
+		ID:   "toolu_multi",
+		Name: "MultiEdit",
+		Input: map[string]interface{}{
+			"file_path": "/tmp/main.go",
+			"edits": []interface{}{
+				map[string]interface{}{"old_string": "a", "new_string": "b"},
+				map[string]interface{}{"old_string": "c", "new_string": "d", "replace_all": "true"},
+			},
+		},
+	}}, []types.Tool{*multiEdit})
+	require.NoError(t, err)
+	require.Len(t, corrected, 1)
+	edits := corrected[0].Input["edits"].([]interface{})
+	assert.Equal(t, true, edits[1].(map[string]interface{})["replace_all"])
+	assert.Equal(t, int32(0), atomic.LoadInt32(&llmCalls))
+}
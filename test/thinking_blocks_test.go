@@ -0,0 +1,119 @@
+package test
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/internal"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// multiTurnThinkingRequest decodes a request the way Claude Code sends it
+// after an extended-thinking turn: signed thinking and redacted_thinking
+// blocks precede the prior tool call
+func multiTurnThinkingRequest(t *testing.T) types.AnthropicRequest {
+	body := `{
+		"model": "claude-sonnet-4-20250514",
+		"max_tokens": 1024,
+		"thinking": {"type": "enabled", "budget_tokens": 1024},
+		"messages": [
+			{"role": "user", "content": "List the files"},
+			{"role": "assistant", "content": [
+				{"type": "thinking", "thinking": "I should run ls.", "signature": "EqQBCgIYAhIM"},
+				{"type": "redacted_thinking", "data": "EmwKAhgBEgy3va3pzix"},
+				{"type": "tool_use", "id": "toolu_01", "name": "Bash", "input": {"command": "ls"}}
+			]},
+			{"role": "user", "content": [
+				{"type": "tool_result", "tool_use_id": "toolu_01", "content": "main.go"}
+			]},
+			{"role": "assistant", "content": [
+				{"type": "thinking", "thinking": "Only one file.", "signature": "EqQBCgIYAhIN"}
+			]},
+			{"role": "user", "content": "Thanks"}
+		]
+	}`
+	var req types.AnthropicRequest
+	require.NoError(t, json.Unmarshal([]byte(body), &req))
+	return req
+}
+
+// TestThinkingBlocksStripped tests that prior-turn thinking and
+// redacted_thinking blocks never reach OpenAI-compatible backends by default
+func TestThinkingBlocksStripped(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	ctx := internal.WithRequestID(context.Background(), "thinking_strip_test")
+
+	openaiReq, err := proxy.TransformAnthropicToOpenAI(ctx, multiTurnThinkingRequest(t), cfg)
+	require.NoError(t, err)
+
+	var roles []string
+	for _, msg := range openaiReq.Messages {
+		roles = append(roles, msg.Role)
+		assert.Empty(t, msg.ReasoningContent)
+		assert.NotContains(t, msg.Content, "I should run ls.")
+		assert.NotContains(t, msg.Content, "EmwKAhgBEgy3va3pzix")
+	}
+	// The thinking-only assistant turn is dropped rather than sent empty
+	assert.Equal(t, []string{"user", "assistant", "tool", "user"}, roles)
+	require.Len(t, openaiReq.Messages[1].ToolCalls, 1)
+	assert.Equal(t, "toolu_01", openaiReq.Messages[1].ToolCalls[0].ID)
+
+	upstream, err := json.Marshal(openaiReq)
+	require.NoError(t, err)
+	assert.NotContains(t, string(upstream), "signature")
+	assert.NotContains(t, string(upstream), "reasoning_content")
+}
+
+// TestThinkingBlocksForwarded tests that FORWARD_THINKING_BLOCKS sends prior
+// thinking text as reasoning_content while still dropping redacted blocks
+func TestThinkingBlocksForwarded(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.ForwardThinkingBlocks = true
+	ctx := internal.WithRequestID(context.Background(), "thinking_forward_test")
+
+	openaiReq, err := proxy.TransformAnthropicToOpenAI(ctx, multiTurnThinkingRequest(t), cfg)
+	require.NoError(t, err)
+	require.Len(t, openaiReq.Messages, 5)
+
+	assert.Equal(t, "I should run ls.", openaiReq.Messages[1].ReasoningContent)
+	require.Len(t, openaiReq.Messages[1].ToolCalls, 1)
+	assert.Equal(t, "assistant", openaiReq.Messages[3].Role)
+	assert.Equal(t, "Only one file.", openaiReq.Messages[3].ReasoningContent)
+
+	upstream, err := json.Marshal(openaiReq)
+	require.NoError(t, err)
+	assert.NotContains(t, string(upstream), "EmwKAhgBEgy3va3pzix")
+}
+
+// TestThinkingBlockResponseFormat tests that Harmony analysis content is
+// returned in the Anthropic thinking block shape clients send back next turn
+func TestThinkingBlockResponseFormat(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	ctx := internal.WithRequestID(context.Background(), "thinking_response_test")
+	resp := &types.OpenAIResponse{
+		ID: "chatcmpl-thinking",
+		Choices: []types.OpenAIChoice{{
+			Message: types.OpenAIMessage{
+				Role:    "assistant",
+				Content: "<|start|>assistant<|channel|>analysis<|message|>Reasoning here<|end|><|start|>assistant<|channel|>final<|message|>Answer<|return|>",
+			},
+			FinishReason: stringPtr("stop"),
+		}},
+	}
+
+	result, err := proxy.TransformOpenAIToAnthropic(ctx, resp, "claude-sonnet-4-20250514", cfg)
+	require.NoError(t, err)
+
+	encoded, err := json.Marshal(result.Content)
+	require.NoError(t, err)
+	var blocks []map[string]interface{}
+	require.NoError(t, json.Unmarshal(encoded, &blocks))
+	require.Len(t, blocks, 2)
+	assert.Equal(t, map[string]interface{}{"type": "thinking", "thinking": "Reasoning here"}, blocks[0])
+	assert.Equal(t, map[string]interface{}{"type": "text", "text": "Answer"}, blocks[1])
+}
@@ -0,0 +1,222 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"claude-proxy/config"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestToolEmulationRoundTrip tests that a model without native tools is told
+// how to call them and that its <tool_call> text reaches the client as tool_use
+func TestToolEmulationRoundTrip(t *testing.T) {
+	var received types.OpenAIRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(types.OpenAIResponse{
+			ID:    "chatcmpl-emulated",
+			Model: "mistral-7b-instruct",
+			Choices: []types.OpenAIChoice{{
+				Message: types.OpenAIMessage{
+					Role:    "assistant",
+					Content: "I'll read the file.\n<tool_call>\n{\"name\": \"Read\", \"arguments\": {\"file_path\": \"/src/main.go\"}}\n</tool_call>",
+				},
+				FinishReason: stringPtr("stop"),
+			}},
+		})
+	}))
+	defer server.Close()
+
+	cfg := config.GetDefaultConfig()
+	cfg.BigModel = "mistral-7b-instruct"
+	cfg.BigModelEndpoints = []string{server.URL}
+	cfg.BigModelAPIKey = "test-key"
+	cfg.ToolCorrectionEnabled = false
+	cfg.ModelProfiles = map[string]config.ModelProfile{
+		"mistral-7b-instruct": {Capabilities: config.ModelCapabilities{ToolEmulation: true}},
+	}
+	handler := proxy.NewHandler(cfg, nil, "")
+
+	body, _ := json.Marshal(types.AnthropicRequest{
+		Model:     "claude-sonnet-4-20250514",
+		MaxTokens: 100,
+		System:    []types.SystemContent{{Type: "text", Text: "You are a coding assistant."}},
+		Messages: []types.Message{
+			{Role: "user", Content: "List the files, then read main.go"},
+			{Role: "assistant", Content: []interface{}{
+				map[string]interface{}{"type": "tool_use", "id": "toolu_1", "name": "Read", "input": map[string]interface{}{"file_path": "/src"}},
+			}},
+			{Role: "user", Content: []interface{}{
+				map[string]interface{}{"type": "tool_result", "tool_use_id": "toolu_1", "content": "main.go"},
+			}},
+		},
+		Tools: backendTestTools(),
+	})
+	req := httptest.NewRequest("POST", "/v1/messages", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	handler.HandleAnthropicRequest(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	// The backend sees tools only as prompt text
+	assert.Empty(t, received.Tools)
+	require.Equal(t, "system", received.Messages[0].Role)
+	assert.Contains(t, received.Messages[0].Content, "## Read")
+	assert.Contains(t, received.Messages[0].Content, "<tool_call>")
+	assert.Contains(t, received.Messages[2].Content, `<tool_call>`)
+	assert.Contains(t, received.Messages[2].Content, `"name": "Read"`)
+	assert.Equal(t, "user", received.Messages[3].Role)
+	assert.Equal(t, "<tool_response>\nmain.go\n</tool_response>", received.Messages[3].Content)
+
+	var resp types.AnthropicResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Len(t, resp.Content, 2)
+	assert.Equal(t, "text", resp.Content[0].Type)
+	assert.Equal(t, "I'll read the file.", resp.Content[0].Text)
+	assert.Equal(t, "tool_use", resp.Content[1].Type)
+	assert.Equal(t, "Read", resp.Content[1].Name)
+	assert.Equal(t, "/src/main.go", resp.Content[1].Input["file_path"])
+	assert.NotEmpty(t, resp.Content[1].ID)
+	assert.Equal(t, "tool_use", resp.StopReason)
+}
+
+// TestReActEmulationRoundTrip tests that a model emulating tools in the ReAct
+// format gets Observation lines and that its Thought and Action reach the
+// client as thinking and tool_use
+func TestReActEmulationRoundTrip(t *testing.T) {
+	var received types.OpenAIRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(types.OpenAIResponse{
+			ID:    "chatcmpl-react",
+			Model: "react-agent-7b",
+			Choices: []types.OpenAIChoice{{
+				Message: types.OpenAIMessage{
+					Role:    "assistant",
+					Content: "Thought: main.go is there, read it.\nAction: Read\nAction Input: {\"file_path\": \"/src/main.go\"}",
+				},
+				FinishReason: stringPtr("stop"),
+			}},
+		})
+	}))
+	defer server.Close()
+
+	cfg := config.GetDefaultConfig()
+	cfg.BigModel = "react-agent-7b"
+	cfg.BigModelEndpoints = []string{server.URL}
+	cfg.BigModelAPIKey = "test-key"
+	cfg.ToolCorrectionEnabled = false
+	cfg.ModelProfiles = map[string]config.ModelProfile{
+		"react-agent-7b": {Capabilities: config.ModelCapabilities{ToolEmulation: true, ToolEmulationFormat: config.ToolEmulationFormatReAct}},
+	}
+	handler := proxy.NewHandler(cfg, nil, "")
+
+	body, _ := json.Marshal(types.AnthropicRequest{
+		Model:     "claude-sonnet-4-20250514",
+		MaxTokens: 100,
+		Messages: []types.Message{
+			{Role: "user", Content: "List the files, then read main.go"},
+			{Role: "assistant", Content: []interface{}{
+				map[string]interface{}{"type": "tool_use", "id": "toolu_1", "name": "Read", "input": map[string]interface{}{"file_path": "/src"}},
+			}},
+			{Role: "user", Content: []interface{}{
+				map[string]interface{}{"type": "tool_result", "tool_use_id": "toolu_1", "content": "main.go"},
+			}},
+		},
+		Tools: backendTestTools(),
+	})
+	req := httptest.NewRequest("POST", "/v1/messages", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	handler.HandleAnthropicRequest(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	assert.Empty(t, received.Tools)
+	require.Equal(t, "system", received.Messages[0].Role)
+	assert.Contains(t, received.Messages[0].Content, "Action Input:")
+	assert.NotContains(t, received.Messages[0].Content, "<tool_call>")
+	assert.Equal(t, `Action: Read`+"\n"+`Action Input: {"file_path":"/src"}`, received.Messages[2].Content)
+	assert.Equal(t, "Observation: main.go", received.Messages[3].Content)
+
+	var resp types.AnthropicResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Len(t, resp.Content, 2)
+	assert.Equal(t, "thinking", resp.Content[0].Type)
+	assert.Equal(t, "main.go is there, read it.", resp.Content[0].Thinking)
+	assert.Equal(t, "tool_use", resp.Content[1].Type)
+	assert.Equal(t, "Read", resp.Content[1].Name)
+	assert.Equal(t, "/src/main.go", resp.Content[1].Input["file_path"])
+	assert.Equal(t, "tool_use", resp.StopReason)
+}
+
+// TestJSONModeToolEmulation tests that a model declaring JSON mode but no
+// native tools is sent a schema built from the tools and that its reply object
+// reaches the client as text and tool_use
+func TestJSONModeToolEmulation(t *testing.T) {
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(types.OpenAIResponse{
+			ID:    "chatcmpl-json",
+			Model: "phi-3-mini",
+			Choices: []types.OpenAIChoice{{
+				Message: types.OpenAIMessage{
+					Role:    "assistant",
+					Content: `{"content": "I'll read the file.", "tool_calls": [{"name": "Read", "arguments": {"file_path": "/src/main.go"}}]}`,
+				},
+				FinishReason: stringPtr("stop"),
+			}},
+		})
+	}))
+	defer server.Close()
+
+	yes := true
+	cfg := config.GetDefaultConfig()
+	cfg.BigModel = "phi-3-mini"
+	cfg.BigModelEndpoints = []string{server.URL}
+	cfg.BigModelAPIKey = "test-key"
+	cfg.ToolCorrectionEnabled = false
+	cfg.ModelProfiles = map[string]config.ModelProfile{
+		"phi-3-mini": {Capabilities: config.ModelCapabilities{ToolEmulation: true, JSONMode: &yes}},
+	}
+	handler := proxy.NewHandler(cfg, nil, "")
+
+	body, _ := json.Marshal(types.AnthropicRequest{
+		Model:     "claude-sonnet-4-20250514",
+		MaxTokens: 100,
+		Messages:  []types.Message{{Role: "user", Content: "Read main.go"}},
+		Tools:     backendTestTools(),
+	})
+	req := httptest.NewRequest("POST", "/v1/messages", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	handler.HandleAnthropicRequest(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	assert.NotContains(t, received, "tools")
+	format, ok := received["response_format"].(map[string]interface{})
+	require.True(t, ok, "expected response_format in %v", received)
+	assert.Equal(t, "json_schema", format["type"])
+	schema, _ := json.Marshal(format["json_schema"])
+	assert.Contains(t, string(schema), `"enum":["Read"]`)
+	assert.Contains(t, string(schema), `"file_path"`)
+
+	var resp types.AnthropicResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Len(t, resp.Content, 2)
+	assert.Equal(t, "I'll read the file.", resp.Content[0].Text)
+	assert.Equal(t, "tool_use", resp.Content[1].Type)
+	assert.Equal(t, "/src/main.go", resp.Content[1].Input["file_path"])
+	assert.Equal(t, "tool_use", resp.StopReason)
+}
@@ -0,0 +1,53 @@
+package test
+
+import (
+	"claude-proxy/correction"
+	"claude-proxy/internal"
+	"claude-proxy/types"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestToolIndexLookup tests exact and case-insensitive lookups
+func TestToolIndexLookup(t *testing.T) {
+	tools := []types.Tool{{Name: "Read"}, {Name: "mcp__github__create_issue"}, {Name: "read"}}
+	index := correction.NewToolIndex(tools)
+
+	require.NotNil(t, index.Get("Read"))
+	assert.Equal(t, "read", index.Get("read").Name, "exact lookup must not fold")
+	assert.Nil(t, index.Get("READ"))
+
+	assert.Equal(t, "Read", index.GetFold("READ").Name, "first tool wins on case collisions")
+	assert.Equal(t, "mcp__github__create_issue", index.GetFold("MCP__GitHub__Create_Issue").Name)
+	assert.Nil(t, index.GetFold("Write"))
+}
+
+// TestValidateToolCallUsesRequestToolsForCaseFixes tests that tools unknown to the
+// validator (e.g. MCP tools) still get case corrections from the request's tool list
+func TestValidateToolCallUsesRequestToolsForCaseFixes(t *testing.T) {
+	service := correction.NewService(NewMockConfigProvider("http://test:8080"), "test-key", true, "test-model", true, nil)
+	tools := []types.Tool{{
+		Name: "mcp__github__create_issue",
+		InputSchema: types.ToolSchema{
+			Type:       "object",
+			Properties: map[string]types.ToolProperty{"title": {Type: "string"}},
+			Required:   []string{"title"},
+		},
+	}}
+	call := types.Content{Type: "tool_use", Name: "MCP__GitHub__Create_Issue", Input: map[string]interface{}{"title": "bug"}}
+
+	ctx := context.WithValue(context.Background(), internal.RequestIDKey, "test-req")
+	for _, ctx := range []context.Context{ctx, correction.WithToolIndex(ctx, correction.NewToolIndex(tools))} {
+		result := service.ValidateToolCall(ctx, call, tools)
+		assert.True(t, result.HasCaseIssue)
+		assert.Equal(t, "mcp__github__create_issue", result.CorrectToolName)
+	}
+
+	// An index built for other tools is ignored
+	stale := correction.WithToolIndex(ctx, correction.NewToolIndex([]types.Tool{{Name: "Write"}}))
+	result := service.ValidateToolCall(stale, call, tools)
+	assert.Equal(t, "mcp__github__create_issue", result.CorrectToolName)
+}
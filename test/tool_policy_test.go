@@ -0,0 +1,158 @@
+package test
+
+import (
+	"bytes"
+	"claude-proxy/config"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestToolPolicies tests that tool_policies.yaml denies tool calls and holds
+// others until they are decided through /admin/approvals
+func TestToolPolicies(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req types.OpenAIRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		filePath := strings.TrimPrefix(req.Messages[len(req.Messages)-1].Content, "Read ")
+		arguments, _ := json.Marshal(map[string]string{"file_path": filePath})
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":    "chatcmpl-policy",
+			"model": "test-model",
+			"choices": []map[string]interface{}{{
+				"index": 0,
+				"message": map[string]interface{}{
+					"role": "assistant",
+					"tool_calls": []map[string]interface{}{{
+						"id":       "call_1",
+						"type":     "function",
+						"function": map[string]interface{}{"name": "Read", "arguments": string(arguments)},
+					}},
+				},
+				"finish_reason": "tool_calls",
+			}},
+			"usage": map[string]interface{}{"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15},
+		})
+	}))
+	defer upstream.Close()
+
+	corrector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]interface{}{"role": "assistant", "content": "KEEP"}}},
+		})
+	}))
+	defer corrector.Close()
+
+	policies := `policies:
+  - name: no-secrets
+    tool: Read
+    params: {file_path: '^/etc/'}
+    action: deny
+  - name: review-home
+    tool: Read
+    params: {file_path: '^/home/'}
+    action: require_approval
+`
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tool_policies.yaml"), []byte(policies), 0644))
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	loaded, err := config.LoadToolPolicies()
+	require.NoError(t, os.Chdir(wd))
+	require.NoError(t, err)
+
+	cfg := config.GetDefaultConfig()
+	cfg.BigModel = "test-model"
+	cfg.BigModelEndpoints = []string{upstream.URL}
+	cfg.BigModelAPIKey = "test-key"
+	cfg.CorrectionModel = "test-model"
+	cfg.ToolCorrectionEndpoints = []string{corrector.URL}
+	cfg.ToolPolicies = loaded
+	handler := proxy.NewHandler(cfg, nil, "")
+
+	send := func(filePath string) types.AnthropicResponse {
+		reqJSON, _ := json.Marshal(map[string]interface{}{
+			"model":      "claude-sonnet-4-20250514",
+			"max_tokens": 100,
+			"messages":   []map[string]interface{}{{"role": "user", "content": "Read " + filePath}},
+			"tools":      backendTestTools(),
+			"metadata":   map[string]string{"user_id": "user_1_session_abc"},
+		})
+		req := httptest.NewRequest("POST", "/v1/messages", bytes.NewReader(reqJSON))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		handler.HandleAnthropicRequest(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var resp types.AnthropicResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		return resp
+	}
+
+	// decideNext waits for the next held call and sends the decision for it
+	decideNext := func(decision string) {
+		for i := 0; i < 200; i++ {
+			rr := httptest.NewRecorder()
+			handler.HandleApprovals(rr, httptest.NewRequest("GET", "/admin/approvals", nil))
+			var pending struct {
+				Pending []proxy.PendingApproval `json:"pending"`
+			}
+			json.Unmarshal(rr.Body.Bytes(), &pending)
+			if len(pending.Pending) > 0 {
+				assert.Equal(t, "review-home", pending.Pending[0].Policy)
+				rr = httptest.NewRecorder()
+				handler.HandleApprovals(rr, httptest.NewRequest("POST", "/admin/approvals?id="+pending.Pending[0].ID+"&decision="+decision, nil))
+				assert.Equal(t, http.StatusNoContent, rr.Code)
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Error("No tool call was held for approval")
+	}
+
+	t.Run("allowed", func(t *testing.T) {
+		resp := send("/tmp/a")
+		require.Len(t, resp.Content, 1)
+		assert.Equal(t, "tool_use", resp.Content[0].Type)
+		assert.Equal(t, "tool_use", resp.StopReason)
+	})
+
+	t.Run("denied", func(t *testing.T) {
+		resp := send("/etc/shadow")
+		require.Len(t, resp.Content, 1)
+		assert.Equal(t, "[Tool call Read blocked: denied by policy no-secrets]", resp.Content[0].Text)
+		assert.Equal(t, "end_turn", resp.StopReason)
+	})
+
+	t.Run("approved", func(t *testing.T) {
+		go decideNext("approve")
+		resp := send("/home/user/notes")
+		require.Len(t, resp.Content, 1)
+		assert.Equal(t, "tool_use", resp.Content[0].Type)
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		go decideNext("deny")
+		resp := send("/home/user/notes")
+		require.Len(t, resp.Content, 1)
+		assert.Contains(t, resp.Content[0].Text, "denied by an administrator (policy review-home)")
+	})
+
+	t.Run("unknown approval", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.HandleApprovals(rr, httptest.NewRequest("POST", "/admin/approvals?id=approval_99&decision=approve", nil))
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
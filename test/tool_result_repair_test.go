@@ -0,0 +1,87 @@
+package test
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/internal"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// toolResultContent transforms a request with one tool_result and returns
+// what the backend receives
+func toolResultContent(t *testing.T, cfg *config.Config, content string) string {
+	req := types.AnthropicRequest{
+		Model: "test-model",
+		Messages: []types.Message{{
+			Role: "user",
+			Content: []interface{}{
+				map[string]interface{}{"type": "tool_result", "tool_use_id": "toolu_1", "content": content},
+			},
+		}},
+	}
+	ctx := internal.WithRequestID(context.Background(), "tool_result_repair_test")
+	openaiReq, err := proxy.TransformAnthropicToOpenAI(ctx, req, cfg)
+	require.NoError(t, err)
+	require.Len(t, openaiReq.Messages, 1)
+	return openaiReq.Messages[0].Content
+}
+
+// TestToolResultRepair tests the JSON normalization of tool results
+func TestToolResultRepair(t *testing.T) {
+	cfg := &config.Config{ToolResultRepair: true}
+
+	t.Run("JSON is pretty-printed with string values kept", func(t *testing.T) {
+		result := toolResultContent(t, cfg, `{"status":"ok","body":"{\"items\":[1,2]}"}`)
+		assert.Equal(t, "{\n  \"body\": \"{\\\"items\\\":[1,2]}\",\n  \"status\": \"ok\"\n}", result,
+			"a string that looks like JSON, e.g. file contents, stays a string")
+	})
+
+	t.Run("large integers keep their digits", func(t *testing.T) {
+		result := toolResultContent(t, cfg, `{"id":12345678901234567890,"created_ns":1735689600123456789,"ratio":0.25}`)
+		assert.Equal(t, "{\n  \"created_ns\": 1735689600123456789,\n  \"id\": 12345678901234567890,\n  \"ratio\": 0.25\n}", result)
+	})
+
+	t.Run("JSON encoded as a string is decoded", func(t *testing.T) {
+		encoded, err := json.Marshal(`[{"name":"a.go"}]`)
+		require.NoError(t, err)
+		assert.Equal(t, "[\n  {\n    \"name\": \"a.go\"\n  }\n]", toolResultContent(t, cfg, string(encoded)))
+	})
+
+	t.Run("truncated arrays are closed after the last complete element", func(t *testing.T) {
+		result := toolResultContent(t, cfg, `{"files":[{"name":"a.go","size":10},{"name":"b.go","si`)
+		assert.True(t, strings.HasSuffix(result, "[truncated JSON closed by proxy]"))
+		var decoded map[string][]map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimSuffix(result, "\n[truncated JSON closed by proxy]")), &decoded))
+		assert.Equal(t, []map[string]interface{}{{"name": "a.go", "size": 10.0}, {"name": "b.go"}}, decoded["files"],
+			"the cut element keeps its complete fields")
+	})
+
+	t.Run("plain text is unchanged", func(t *testing.T) {
+		for _, text := range []string{"File written successfully", `"quoted text"`, "{not json at all", "[1, 2]] extra"} {
+			assert.Equal(t, text, toolResultContent(t, cfg, text))
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		assert.Equal(t, `{"a":"{\"b\":1}"}`, toolResultContent(t, &config.Config{}, `{"a":"{\"b\":1}"}`))
+	})
+}
+
+// TestToolResultMaxTokens tests truncation of long tool results
+func TestToolResultMaxTokens(t *testing.T) {
+	long := "first line\n" + strings.Repeat("lorem ipsum dolor sit amet ", 500) + "\nlast line"
+	result := toolResultContent(t, &config.Config{ToolResultMaxTokens: 100}, long)
+	assert.True(t, strings.HasPrefix(result, "first line"))
+	assert.True(t, strings.HasSuffix(result, "last line"))
+	assert.Contains(t, result, "[tool result truncated by proxy]")
+	assert.Less(t, len(result), 600)
+
+	assert.Equal(t, "short result", toolResultContent(t, &config.Config{ToolResultMaxTokens: 100}, "short result"))
+}
@@ -0,0 +1,89 @@
+package test
+
+import (
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func driftTestTool(required []string, properties map[string]types.ToolProperty) types.Tool {
+	return types.Tool{
+		Name: "Read",
+		InputSchema: types.ToolSchema{
+			Type:       "object",
+			Properties: properties,
+			Required:   required,
+		},
+	}
+}
+
+// TestToolSchemaRegistryDetectsDrift tests that schema changes of known tools are reported once
+func TestToolSchemaRegistryDetectsDrift(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tool_schemas.json")
+	registry, err := proxy.NewToolSchemaRegistry(path)
+	require.NoError(t, err)
+
+	original := driftTestTool([]string{"file_path"}, map[string]types.ToolProperty{
+		"file_path": {Type: "string"},
+		"limit":     {Type: "number"},
+	})
+
+	drifts, err := registry.Observe([]types.Tool{original}, "1.0.80")
+	require.NoError(t, err)
+	assert.Empty(t, drifts, "first sighting is the baseline")
+
+	drifts, err = registry.Observe([]types.Tool{original}, "1.0.81")
+	require.NoError(t, err)
+	assert.Empty(t, drifts, "unchanged schema must not drift")
+
+	updated := driftTestTool([]string{"path", "encoding"}, map[string]types.ToolProperty{
+		"path":     {Type: "string"},
+		"limit":    {Type: "integer"},
+		"encoding": {Type: "string"},
+	})
+	drifts, err = registry.Observe([]types.Tool{updated}, "1.0.90")
+	require.NoError(t, err)
+	require.Len(t, drifts, 1)
+
+	drift := drifts[0]
+	assert.Equal(t, "Read", drift.Tool)
+	assert.Equal(t, "1.0.80", drift.PreviousRelease)
+	assert.Equal(t, "1.0.90", drift.Release)
+	assert.Equal(t, []string{"encoding", "path"}, drift.AddedParams)
+	assert.Equal(t, []string{"file_path"}, drift.RemovedParams)
+	assert.ElementsMatch(t, []string{"path", "encoding"}, drift.AddedRequired)
+	assert.Equal(t, []string{"file_path"}, drift.RemovedRequired)
+	assert.Equal(t, []string{"limit: number -> integer"}, drift.TypeChanges)
+	assert.Contains(t, drift.String(), "newly required")
+
+	drifts, err = registry.Observe([]types.Tool{updated}, "1.0.90")
+	require.NoError(t, err)
+	assert.Empty(t, drifts, "a change is reported only once")
+
+	// The baseline survives a restart
+	reopened, err := proxy.NewToolSchemaRegistry(path)
+	require.NoError(t, err)
+	fingerprint, exists := reopened.Get("Read")
+	require.True(t, exists)
+	assert.Equal(t, "1.0.90", fingerprint.Release)
+	assert.Equal(t, []string{"encoding", "path"}, fingerprint.Required)
+}
+
+// TestToolSchemaRegistryIgnoresDescriptionOnlyChanges tests that tool descriptions do not affect the fingerprint
+func TestToolSchemaRegistryIgnoresDescriptionOnlyChanges(t *testing.T) {
+	registry, err := proxy.NewToolSchemaRegistry(filepath.Join(t.TempDir(), "tool_schemas.json"))
+	require.NoError(t, err)
+
+	tool := driftTestTool([]string{"command"}, map[string]types.ToolProperty{"command": {Type: "string"}})
+	_, err = registry.Observe([]types.Tool{tool}, "")
+	require.NoError(t, err)
+
+	tool.Description = "Runs a shell command, now with a longer description"
+	drifts, err := registry.Observe([]types.Tool{tool}, "")
+	require.NoError(t, err)
+	assert.Empty(t, drifts)
+}
@@ -0,0 +1,103 @@
+package test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"claude-proxy/config"
+	"claude-proxy/internal"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func verboseToolForMinify(name string) types.Tool {
+	description := "Executes a given command in a persistent shell session. " +
+		strings.Repeat("Follow the usage notes carefully before running anything. ", 20) +
+		"\n<example>\nuser: run the tests\nassistant: I'll run go test ./...\n</example>\n"
+	return types.Tool{
+		Name:        name,
+		Description: description,
+		InputSchema: types.ToolSchema{
+			Type: "object",
+			Properties: map[string]types.ToolProperty{
+				"command": {
+					Type:        "string",
+					Description: "The command to execute. " + strings.Repeat("It runs in the project root with the user's environment. ", 5),
+				},
+			},
+			Required: []string{"command"},
+		},
+	}
+}
+
+// TestToolSchemaMinification tests that upstream tool definitions are shrunk
+// while the request's own tool schemas stay intact for validation
+func TestToolSchemaMinification(t *testing.T) {
+	ctx := internal.WithRequestID(context.Background(), "tool_schema_minify_test")
+	newRequest := func() types.AnthropicRequest {
+		return types.AnthropicRequest{
+			Model:    "qwen3-coder",
+			Messages: []types.Message{{Role: "user", Content: "run the tests"}},
+			Tools:    []types.Tool{verboseToolForMinify("Bash"), verboseToolForMinify("Task")},
+		}
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		req := newRequest()
+		result, err := proxy.TransformAnthropicToOpenAI(ctx, req, &config.Config{})
+		require.NoError(t, err)
+		assert.Equal(t, req.Tools[0].Description, result.Tools[0].Function.Description)
+	})
+
+	t.Run("Enabled", func(t *testing.T) {
+		cfg := &config.Config{
+			ToolSchemaMinifyEnabled:  true,
+			ToolSchemaMinifyMaxChars: 200,
+			ToolSchemaMinifyLimits:   map[string]int{"Task": 0},
+		}
+		req := newRequest()
+		originalPropertyDescription := req.Tools[0].InputSchema.Properties["command"].Description
+
+		result, err := proxy.TransformAnthropicToOpenAI(ctx, req, cfg)
+		require.NoError(t, err)
+		require.Len(t, result.Tools, 2)
+
+		bash := result.Tools[0].Function
+		assert.LessOrEqual(t, len(bash.Description), 200)
+		assert.NotContains(t, bash.Description, "<example>")
+		assert.True(t, strings.HasPrefix(bash.Description, "Executes a given command"))
+		assert.LessOrEqual(t, len(bash.Parameters.Properties["command"].Description), 120)
+		assert.Equal(t, []string{"command"}, bash.Parameters.Required)
+
+		// Task is excluded via per-tool limit 0
+		assert.Equal(t, req.Tools[1].Description, result.Tools[1].Function.Description)
+
+		// Full schema is preserved on the request for proxy-side validation
+		assert.Equal(t, originalPropertyDescription, req.Tools[0].InputSchema.Properties["command"].Description)
+	})
+}
+
+// TestMinifyToolDescription tests sentence-boundary truncation and short passthrough
+func TestMinifyToolDescription(t *testing.T) {
+	assert.Equal(t, "Short description.", proxy.MinifyToolDescription("Short description.", 100))
+	long := "First sentence here. Second sentence is much longer than the budget."
+	assert.Equal(t, "First sentence here.", proxy.MinifyToolDescription(long, 36))
+	truncated := proxy.MinifyToolDescription("alpha beta gamma delta epsilon zeta", 20)
+	assert.Equal(t, "alpha beta gamma...", truncated)
+	assert.LessOrEqual(t, len(truncated), 20)
+	assert.Equal(t, "nospaces", proxy.MinifyToolDescription("<example>x</example>nospaces", 10))
+
+	// Byte limits falling inside a multi-byte character cut before it
+	for limit := 5; limit <= 16; limit++ {
+		for _, text := range []string{"日本語の説明文です", "Lire le fichier été"} {
+			truncated := proxy.MinifyToolDescription(text, limit)
+			assert.True(t, utf8.ValidString(truncated), "limit %d cut %q into %q", limit, text, truncated)
+			assert.LessOrEqual(t, len(truncated), limit)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"claude-proxy/circuitbreaker"
+	"claude-proxy/config"
+	"claude-proxy/internal"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestToolSummaryCacheRoundTrip tests that summaries persist across cache instances
+func TestToolSummaryCacheRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tool_summaries.json")
+
+	cache, err := proxy.NewToolSummaryCache(path)
+	require.NoError(t, err)
+	require.NoError(t, cache.Put("long description", 200, "short"))
+
+	reopened, err := proxy.NewToolSummaryCache(path)
+	require.NoError(t, err)
+	summary, exists := reopened.Get("long description", 200)
+	assert.True(t, exists)
+	assert.Equal(t, "short", summary)
+
+	// Budget is part of the key
+	_, exists = reopened.Get("long description", 100)
+	assert.False(t, exists)
+}
+
+// TestToolDescriptionSummarization tests that a cache miss falls back to truncation
+// while the correction model summarizes once in the background
+func TestToolDescriptionSummarization(t *testing.T) {
+	var summaryCalls int32
+	correctionServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "Summarize the description") {
+			atomic.AddInt32(&summaryCalls, 1)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":    "summary",
+			"model": "correction-model",
+			"choices": []map[string]interface{}{{
+				"index":         0,
+				"message":       map[string]interface{}{"role": "assistant", "content": "Runs shell commands. Quote paths with spaces."},
+				"finish_reason": "stop",
+			}},
+		})
+	}))
+	defer correctionServer.Close()
+
+	cfg := &config.Config{
+		ToolCorrectionEndpoints:  []string{correctionServer.URL},
+		ToolCorrectionAPIKey:     "test-key",
+		CorrectionModel:          "correction-model",
+		ToolSchemaMinifyEnabled:  true,
+		ToolSchemaMinifyMaxChars: 200,
+		ToolSchemaMinifyMode:     config.ToolMinifySummarize,
+		ToolSummaryCachePath:     filepath.Join(t.TempDir(), "tool_summaries.json"),
+		HealthManager:            circuitbreaker.NewHealthManager(circuitbreaker.DefaultConfig()),
+	}
+	ctx := internal.WithRequestID(context.Background(), "tool_summary_test")
+	req := types.AnthropicRequest{
+		Model:    "qwen3-coder",
+		Messages: []types.Message{{Role: "user", Content: "run the tests"}},
+		Tools:    []types.Tool{verboseToolForMinify("Bash")},
+	}
+
+	first, err := proxy.TransformAnthropicToOpenAI(ctx, req, cfg)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(first.Tools[0].Function.Description, "Executes a given command"),
+		"cache miss should use truncation")
+
+	require.Eventually(t, func() bool {
+		result, err := proxy.TransformAnthropicToOpenAI(ctx, req, cfg)
+		return err == nil && result.Tools[0].Function.Description == "Runs shell commands. Quote paths with spaces."
+	}, 5*time.Second, 20*time.Millisecond)
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&summaryCalls), "description should be summarized only once")
+}
@@ -0,0 +1,140 @@
+package test
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/correction"
+	"claude-proxy/internal"
+	"claude-proxy/types"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// coercionTestSchema declares one parameter of every JSON type
+var coercionTestSchema = types.ToolSchema{
+	Type: "object",
+	Properties: map[string]types.ToolProperty{
+		"file_path":   {Type: "string"},
+		"limit":       {Type: "integer"},
+		"ratio":       {Type: "number"},
+		"replace_all": {Type: "boolean"},
+		"paths":       {Type: "array", Items: &types.ToolPropertyItems{Type: "string"}},
+		"options":     {Type: "object"},
+	},
+	Required: []string{"file_path"},
+}
+
+// TestValidatorFlagsTypeMismatches tests that values of the wrong JSON type
+// are reported as invalid parameters
+func TestValidatorFlagsTypeMismatches(t *testing.T) {
+	validator := types.NewStandardToolValidator()
+
+	valid := validator.ValidateParameters(context.Background(), types.Content{Name: "Probe", Input: map[string]interface{}{
+		"file_path":   "/tmp/a",
+		"limit":       float64(50),
+		"ratio":       0.5,
+		"replace_all": true,
+		"paths":       []interface{}{"a"},
+		"options":     map[string]interface{}{"k": "v"},
+	}}, coercionTestSchema)
+	assert.True(t, valid.IsValid, "invalid params: %v", valid.InvalidParams)
+
+	invalid := validator.ValidateParameters(context.Background(), types.Content{Name: "Probe", Input: map[string]interface{}{
+		"file_path":   "/tmp/a",
+		"limit":       "50",
+		"ratio":       1.5,
+		"replace_all": "true",
+	}}, coercionTestSchema)
+	assert.False(t, invalid.IsValid)
+	assert.ElementsMatch(t, []string{"limit", "replace_all"}, invalid.InvalidParams)
+
+	fractional := validator.ValidateParameters(context.Background(), types.Content{Name: "Probe", Input: map[string]interface{}{
+		"file_path": "/tmp/a",
+		"limit":     2.5,
+	}}, coercionTestSchema)
+	assert.Equal(t, []string{"limit"}, fractional.InvalidParams, "integers must be whole numbers")
+}
+
+// TestCoerceTypes tests which mistyped values are converted and which are left
+// for the correction model
+func TestCoerceTypes(t *testing.T) {
+	validator := types.NewStandardToolValidator()
+
+	call := types.Content{Name: "Probe", Input: map[string]interface{}{
+		"file_path":   float64(42),
+		"limit":       " 50 ",
+		"ratio":       "0.25",
+		"replace_all": "TRUE",
+		"paths":       `["a", "b"]`,
+		"options":     `{"depth": 2}`,
+	}}
+	coerced, params := validator.CoerceTypes(call, coercionTestSchema)
+	assert.Equal(t, []string{"file_path", "limit", "options", "paths", "ratio", "replace_all"}, params)
+	assert.Equal(t, "42", coerced.Input["file_path"])
+	assert.Equal(t, float64(50), coerced.Input["limit"])
+	assert.Equal(t, 0.25, coerced.Input["ratio"])
+	assert.Equal(t, true, coerced.Input["replace_all"])
+	assert.Equal(t, []interface{}{"a", "b"}, coerced.Input["paths"])
+	assert.Equal(t, map[string]interface{}{"depth": float64(2)}, coerced.Input["options"])
+	assert.Equal(t, "TRUE", call.Input["replace_all"], "the original call is left unchanged")
+
+	unchanged, params := validator.CoerceTypes(types.Content{Name: "Probe", Input: map[string]interface{}{
+		"file_path":   "/tmp/a",
+		"limit":       "fifty",
+		"ratio":       "NaN",
+		"replace_all": "yes please",
+		"paths":       "a, b",
+		"options":     `["not", "an", "object"]`,
+		"unknown":     "1",
+	}}, coercionTestSchema)
+	assert.Empty(t, params)
+	assert.Equal(t, "fifty", unchanged.Input["limit"])
+
+	_, params = validator.CoerceTypes(types.Content{Name: "Probe", Input: map[string]interface{}{"file_path": "/tmp/a", "limit": "2.5"}}, coercionTestSchema)
+	assert.Empty(t, params, "fractional strings are not integers")
+}
+
+// TestTypeCoercionSkipsLLM tests that tool calls fixed by coercion never reach
+// the correction model, while uncoercible values still do
+func TestTypeCoercionSkipsLLM(t *testing.T) {
+	var llmCalls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&llmCalls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cfg := config.GetDefaultConfig()
+	cfg.ToolCorrectionEndpoints = []string{server.URL}
+	cfg.ToolCorrectionAPIKey = "test-key"
+	cfg.CorrectionMaxRetries = 1
+	service := correction.NewService(cfg, "test-key", true, "test-model", true, nil)
+	ctx := internal.WithRequestID(context.Background(), "type_coercion_test")
+	tools := []types.Tool{{Name: "Probe", InputSchema: coercionTestSchema}}
+
+	corrected, err := service.CorrectToolCalls(ctx, []types.Content{{
+		Type:  "tool_use",
+		ID:    "toolu_1",
+		Name:  "Probe",
+		Input: map[string]interface{}{"file_path": "/tmp/a", "limit": "50", "replace_all": "true"},
+	}}, tools)
+	require.NoError(t, err)
+	require.Len(t, corrected, 1)
+	assert.Equal(t, float64(50), corrected[0].Input["limit"])
+	assert.Equal(t, true, corrected[0].Input["replace_all"])
+	assert.Equal(t, int32(0), atomic.LoadInt32(&llmCalls), "coercible values must not need the correction model")
+
+	_, err = service.CorrectToolCalls(ctx, []types.Content{{
+		Type:  "tool_use",
+		ID:    "toolu_2",
+		Name:  "Probe",
+		Input: map[string]interface{}{"file_path": "/tmp/a", "limit": "fifty"},
+	}}, tools)
+	require.NoError(t, err)
+	assert.Greater(t, atomic.LoadInt32(&llmCalls), int32(0), "uncoercible values are still corrected by the model")
+}
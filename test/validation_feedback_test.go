@@ -0,0 +1,105 @@
+package test
+
+import (
+	"bytes"
+	"claude-proxy/config"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestValidationFeedback tests that invalid tool calls are returned to the
+// model as failed tool results and replaced by the calls it makes in reply
+func TestValidationFeedback(t *testing.T) {
+	var upstreamCalls int32
+	var feedback string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req types.OpenAIRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		call := atomic.AddInt32(&upstreamCalls, 1)
+		arguments := `{"unrelated":"x"}`
+		if call > 1 {
+			last := req.Messages[len(req.Messages)-1]
+			feedback = last.Role + ": " + last.Content
+			arguments = `{"file_path":"/tmp/a"}`
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":    "chatcmpl-feedback",
+			"model": "test-model",
+			"choices": []map[string]interface{}{{
+				"index": 0,
+				"message": map[string]interface{}{
+					"role":    "assistant",
+					"content": "Reading it",
+					"tool_calls": []map[string]interface{}{{
+						"id":       fmt.Sprintf("call_%d", call),
+						"type":     "function",
+						"function": map[string]interface{}{"name": "Read", "arguments": arguments},
+					}},
+				},
+				"finish_reason": "tool_calls",
+			}},
+			"usage": map[string]interface{}{"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15},
+		})
+	}))
+	defer upstream.Close()
+
+	// Correction model that only answers the ExitPlanMode request analysis
+	var correctionCalls int32
+	corrector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req types.OpenAIRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if !strings.Contains(req.Messages[0].Content, "tool filtering") {
+			atomic.AddInt32(&correctionCalls, 1)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]interface{}{"role": "assistant", "content": "KEEP"}}},
+		})
+	}))
+	defer corrector.Close()
+
+	cfg := config.GetDefaultConfig()
+	cfg.BigModel = "test-model"
+	cfg.BigModelEndpoints = []string{upstream.URL}
+	cfg.BigModelAPIKey = "test-key"
+	cfg.CorrectionModel = "test-model"
+	cfg.ToolCorrectionEndpoints = []string{corrector.URL}
+	cfg.ValidationFeedbackRounds = 1
+	handler := proxy.NewHandler(cfg, nil, "")
+
+	reqJSON, _ := json.Marshal(map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"max_tokens": 100,
+		"messages":   []map[string]interface{}{{"role": "user", "content": "Read the file"}},
+		"tools":      backendTestTools(),
+	})
+	req := httptest.NewRequest("POST", "/v1/messages", bytes.NewReader(reqJSON))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	handler.HandleAnthropicRequest(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&upstreamCalls))
+	assert.Contains(t, feedback, "tool: <tool_use_error>InputValidationError: Read failed")
+	assert.Contains(t, feedback, "The required parameter `file_path` is missing")
+	assert.Zero(t, atomic.LoadInt32(&correctionCalls), "fixed calls need no correction")
+
+	var resp types.AnthropicResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Len(t, resp.Content, 2)
+	assert.Equal(t, "Reading it", resp.Content[0].Text)
+	assert.Equal(t, map[string]interface{}{"file_path": "/tmp/a"}, resp.Content[1].Input)
+	assert.Equal(t, "tool_use", resp.StopReason)
+	assert.Equal(t, 20, resp.Usage.InputTokens, "usage covers both upstream requests")
+}
@@ -0,0 +1,63 @@
+package test
+
+import (
+	"claude-proxy/config"
+	"claude-proxy/correction"
+	"claude-proxy/internal"
+	"claude-proxy/types"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestValidationSeverity tests that rules set to warn pass calls through,
+// rules set to block replace them, and the strictest broken rule wins
+func TestValidationSeverity(t *testing.T) {
+	var modelCalls int32
+	model := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&modelCalls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer model.Close()
+
+	cfg := config.GetDefaultConfig()
+	cfg.ToolCorrectionEndpoints = []string{model.URL}
+	cfg.ValidationSeverity = map[string]string{
+		config.ValidationRuleMissingParam: config.ValidationSeverityWarn,
+		config.ValidationRuleSemantic:     config.ValidationSeverityBlock,
+	}
+	service := correction.NewService(cfg, "test-key", true, "test-model", true, nil)
+
+	tools := append(backendTestTools(), types.Tool{
+		Name: "WebFetch",
+		InputSchema: types.ToolSchema{
+			Type:       "object",
+			Properties: map[string]types.ToolProperty{"url": {Type: "string"}, "prompt": {Type: "string"}},
+			Required:   []string{"url", "prompt"},
+		},
+	})
+	calls := []types.Content{
+		// Only missing_param (warn): passed through uncorrected
+		{Type: "tool_use", ID: "toolu_1", Name: "Read", Input: map[string]interface{}{}},
+		// semantic (block)
+		{Type: "tool_use", ID: "toolu_2", Name: "WebFetch", Input: map[string]interface{}{"url": "file:///etc/hosts", "prompt": "show"}},
+		// missing_param (warn) and invalid_param (fix): fixed
+		{Type: "tool_use", ID: "toolu_3", Name: "Read", Input: map[string]interface{}{"path": "/tmp/a"}},
+	}
+
+	ctx := context.WithValue(context.Background(), internal.RequestIDKey, "test-req")
+	corrected, err := service.CorrectToolCalls(ctx, calls, tools)
+	require.NoError(t, err)
+	require.Len(t, corrected, 3)
+
+	assert.Equal(t, calls[0], corrected[0])
+	assert.Equal(t, "text", corrected[1].Type)
+	assert.Contains(t, corrected[1].Text, "WebFetch blocked: it violates the semantic validation rule")
+	assert.Equal(t, map[string]interface{}{"file_path": "/tmp/a"}, corrected[2].Input)
+	assert.Zero(t, atomic.LoadInt32(&modelCalls))
+}
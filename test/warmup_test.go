@@ -0,0 +1,128 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"claude-proxy/config"
+	"claude-proxy/proxy"
+	"claude-proxy/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// warmupTestServer records the model of every request it receives
+func warmupTestServer(status int) (*httptest.Server, func() []types.OpenAIRequest) {
+	var mu sync.Mutex
+	var received []types.OpenAIRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req types.OpenAIRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		received = append(received, req)
+		mu.Unlock()
+		w.WriteHeader(status)
+		w.Write([]byte(`{"id":"warm","choices":[{"message":{"role":"assistant","content":"H"},"finish_reason":"length"}]}`))
+	}))
+	return server, func() []types.OpenAIRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]types.OpenAIRequest(nil), received...)
+	}
+}
+
+func warmupTestConfig(bigURL, smallURL string) *config.Config {
+	cfg := config.GetDefaultConfig()
+	cfg.BigModel = "big-local"
+	cfg.SmallModel = "small-local"
+	cfg.CorrectionModel = "small-local"
+	cfg.BigModelEndpoints = []string{bigURL}
+	cfg.SmallModelEndpoints = []string{smallURL}
+	cfg.ToolCorrectionEndpoints = []string{smallURL}
+	return cfg
+}
+
+func TestWarmUpLoadsEveryEndpointModel(t *testing.T) {
+	big, bigRequests := warmupTestServer(http.StatusOK)
+	defer big.Close()
+	small, smallRequests := warmupTestServer(http.StatusOK)
+	defer small.Close()
+
+	handler := proxy.NewHandler(warmupTestConfig(big.URL, small.URL), nil, "")
+	results := handler.WarmUp(context.Background())
+
+	require.Len(t, results, 2, "small and correction roles share an endpoint and model")
+	for _, result := range results {
+		assert.NoError(t, result.Err)
+	}
+	require.Len(t, bigRequests(), 1)
+	assert.Equal(t, "big-local", bigRequests()[0].Model)
+	assert.Equal(t, 1, bigRequests()[0].MaxTokens)
+	assert.False(t, bigRequests()[0].Stream)
+	require.Len(t, smallRequests(), 1)
+	assert.Equal(t, "small-local", smallRequests()[0].Model)
+}
+
+func TestWarmUpReportsFailuresWithoutTrippingCircuit(t *testing.T) {
+	big, _ := warmupTestServer(http.StatusOK)
+	defer big.Close()
+	small, _ := warmupTestServer(http.StatusServiceUnavailable)
+	defer small.Close()
+
+	cfg := warmupTestConfig(big.URL, small.URL)
+	cfg.CorrectionModel = "corrector"
+	handler := proxy.NewHandler(cfg, nil, "")
+	results := handler.WarmUp(context.Background())
+
+	require.Len(t, results, 3)
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			assert.Equal(t, small.URL, result.Endpoint)
+		}
+	}
+	assert.Equal(t, 2, failed)
+	assert.True(t, cfg.HealthManager.IsHealthy(small.URL), "warm-up must not feed the circuit breaker")
+}
+
+func TestWarmUpOnCircuitRecovery(t *testing.T) {
+	big, bigRequests := warmupTestServer(http.StatusOK)
+	defer big.Close()
+	small, smallRequests := warmupTestServer(http.StatusOK)
+	defer small.Close()
+
+	cfg := warmupTestConfig(big.URL, small.URL)
+	handler := proxy.NewHandler(cfg, nil, "")
+
+	var transitions []bool
+	var mu sync.Mutex
+	cfg.HealthManager.AddStateChangeListener(func(endpoint string, open bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		transitions = append(transitions, open)
+	})
+	cfg.HealthManager.AddStateChangeListener(handler.WarmUpOnRecovery())
+
+	for i := 0; i < 10; i++ {
+		cfg.HealthManager.RecordFailure(small.URL)
+	}
+	_, open, _, _ := cfg.HealthManager.GetHealthDebug(small.URL)
+	require.True(t, open)
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, smallRequests(), "opening a circuit does not warm up")
+
+	cfg.HealthManager.RecordSuccess(small.URL)
+	assert.Eventually(t, func() bool { return len(smallRequests()) == 1 }, 2*time.Second, 10*time.Millisecond)
+	assert.Empty(t, bigRequests(), "only the recovered endpoint is warmed up")
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []bool{true, false}, transitions, "existing listener keeps receiving transitions")
+}
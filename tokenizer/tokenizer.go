@@ -0,0 +1,65 @@
+// Package tokenizer estimates how many tokens a text takes up in a model's
+// context. Backends use different vocabularies and the proxy has none of them,
+// so the estimate follows the shape common BPE tokenizers share: a leading
+// space joins the word after it, words split into chunks of about four
+// characters, and punctuation and non-ASCII characters take a token each.
+package tokenizer
+
+import "unicode/utf8"
+
+// charsPerToken is the length of the chunks long words are split into
+const charsPerToken = 4
+
+// Boundaries returns the byte offset where each estimated token of s starts.
+// Offsets always fall on rune boundaries.
+func Boundaries(s string) []int {
+	var bounds []int
+	for i := 0; i < len(s); {
+		bounds = append(bounds, i)
+		for i < len(s) && s[i] == ' ' {
+			i++
+		}
+		switch {
+		case i >= len(s):
+		case isWordByte(s[i]):
+			start := i
+			for i < len(s) && isWordByte(s[i]) && i-start < charsPerToken {
+				i++
+			}
+		case s[i] < utf8.RuneSelf:
+			i++
+		default:
+			_, size := utf8.DecodeRuneInString(s[i:])
+			i += size
+		}
+	}
+	return bounds
+}
+
+// Count returns the estimated number of tokens in s
+func Count(s string) int {
+	return len(Boundaries(s))
+}
+
+// Truncate shortens s to about maxTokens tokens by cutting out its middle,
+// keeping the beginning and the end around marker. Strings within the limit
+// and limits of 0 or less return s unchanged.
+func Truncate(s string, maxTokens int, marker string) string {
+	if maxTokens <= 0 {
+		return s
+	}
+	bounds := Boundaries(s)
+	if len(bounds) <= maxTokens {
+		return s
+	}
+	keep := (maxTokens - Count(marker)) / 2
+	if keep < 1 {
+		keep = 1
+	}
+	return s[:bounds[keep]] + marker + s[bounds[len(bounds)-keep]:]
+}
+
+// isWordByte reports whether b is an ASCII letter, digit or underscore
+func isWordByte(b byte) bool {
+	return b == '_' || ('0' <= b && b <= '9') || ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z')
+}
@@ -0,0 +1,47 @@
+package tokenizer
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestCount(t *testing.T) {
+	tests := map[string]int{
+		"":                       0,
+		"hello world":            4, // "hell" "o" " worl" "d"
+		"Read the file.":         4, // "Read" " the" " file" "."
+		"file_path":              3,
+		"日本語":                    3,
+		"  leading spaces":       4, // "  lead" "ing" " spac" "es"
+		`{"file_path":"a.go"}`:   13,
+		"emoji 🙂 and ümlauts ok": 8,
+	}
+	for input, expected := range tests {
+		if got := Count(input); got != expected {
+			t.Errorf("Count(%q) = %d, expected %d", input, got, expected)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	short := "short text"
+	if got := Truncate(short, 10, " ... "); got != short {
+		t.Errorf("Expected text within the limit unchanged, got %q", got)
+	}
+	if got := Truncate(short, 0, " ... "); got != short {
+		t.Errorf("Expected a limit of 0 to disable truncation, got %q", got)
+	}
+
+	long := strings.Repeat("ü日", 200) + " tail"
+	got := Truncate(long, 20, " ... ")
+	if !utf8.ValidString(got) {
+		t.Fatalf("Truncated text is not valid UTF-8: %q", got)
+	}
+	if !strings.HasPrefix(got, "ü日") || !strings.HasSuffix(got, " tail") || !strings.Contains(got, " ... ") {
+		t.Errorf("Expected beginning and end around the marker, got %q", got)
+	}
+	if count := Count(got); count > 20 {
+		t.Errorf("Expected at most 20 tokens, got %d in %q", count, got)
+	}
+}
@@ -0,0 +1,165 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// What tool validation does with parameters a tool's schema does not define
+const (
+	AdditionalPropertiesError = "error" // The call is invalid and goes to correction (default)
+	AdditionalPropertiesAllow = "allow" // The parameters are kept and the call stays valid
+	AdditionalPropertiesStrip = "strip" // The parameters are removed without an LLM round trip
+)
+
+// AdditionalPropertiesPolicies lists the accepted policies
+var AdditionalPropertiesPolicies = []string{AdditionalPropertiesError, AdditionalPropertiesAllow, AdditionalPropertiesStrip}
+
+// AdditionalPropertiesPolicy is the policy for undefined parameters, with
+// per-tool overrides keyed by tool name. It applies to the top-level
+// parameters and to the fields of nested objects whose properties the schema
+// declares.
+type AdditionalPropertiesPolicy struct {
+	Default string            `json:"default"`
+	Tools   map[string]string `json:"tools,omitempty"`
+}
+
+// DefaultAdditionalPropertiesPolicy rejects undefined parameters except for
+// Task, whose calls converted from slash commands carry extra ones
+func DefaultAdditionalPropertiesPolicy() AdditionalPropertiesPolicy {
+	return AdditionalPropertiesPolicy{
+		Default: AdditionalPropertiesError,
+		Tools:   map[string]string{"Task": AdditionalPropertiesAllow},
+	}
+}
+
+// For returns the policy of a tool
+func (p AdditionalPropertiesPolicy) For(toolName string) string {
+	if policy, exists := p.Tools[toolName]; exists {
+		return policy
+	}
+	if p.Default == "" {
+		return AdditionalPropertiesError
+	}
+	return p.Default
+}
+
+// Validate checks that every policy is a known one
+func (p AdditionalPropertiesPolicy) Validate() error {
+	if p.Default != "" && !isAdditionalPropertiesPolicy(p.Default) {
+		return fmt.Errorf("unknown additional properties policy %q, must be one of: %s", p.Default, strings.Join(AdditionalPropertiesPolicies, ", "))
+	}
+	for tool, policy := range p.Tools {
+		if tool == "" {
+			return fmt.Errorf("additional properties policies need a tool name")
+		}
+		if !isAdditionalPropertiesPolicy(policy) {
+			return fmt.Errorf("unknown additional properties policy %q for %s, must be one of: %s", policy, tool, strings.Join(AdditionalPropertiesPolicies, ", "))
+		}
+	}
+	return nil
+}
+
+// isAdditionalPropertiesPolicy reports whether policy is a known policy
+func isAdditionalPropertiesPolicy(policy string) bool {
+	for _, known := range AdditionalPropertiesPolicies {
+		if policy == known {
+			return true
+		}
+	}
+	return false
+}
+
+// AdditionalPropertiesHandler is implemented by validators that apply an
+// AdditionalPropertiesPolicy and can strip the parameters it removes
+type AdditionalPropertiesHandler interface {
+	SetAdditionalPropertiesPolicy(policy AdditionalPropertiesPolicy) error
+	StripAdditionalProperties(call Content, schema ToolSchema) (Content, []string)
+}
+
+// SetAdditionalPropertiesPolicy replaces the policy for undefined parameters
+func (v *StandardToolValidator) SetAdditionalPropertiesPolicy(policy AdditionalPropertiesPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.additionalProperties = policy
+	return nil
+}
+
+// additionalPropertiesFor returns the policy of a tool
+func (v *StandardToolValidator) additionalPropertiesFor(toolName string) string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.additionalProperties.For(toolName)
+}
+
+// StripAdditionalProperties removes the parameters, and the fields of nested
+// objects, that the schema does not define from a call whose tool has the
+// strip policy, and returns the paths it removed
+func (v *StandardToolValidator) StripAdditionalProperties(call Content, schema ToolSchema) (Content, []string) {
+	if v.additionalPropertiesFor(call.Name) != AdditionalPropertiesStrip {
+		return call, nil
+	}
+
+	var stripped []string
+	input := make(map[string]interface{}, len(call.Input))
+	for param, value := range call.Input {
+		property, exists := schema.Properties[param]
+		if !exists {
+			stripped = append(stripped, param)
+			continue
+		}
+		input[param] = stripNested(param, value, property, &stripped)
+	}
+
+	if len(stripped) == 0 {
+		return call, nil
+	}
+	sort.Strings(stripped)
+	call.Input = input
+	return call, stripped
+}
+
+// stripNested returns value without the object fields its property does not
+// define, copying only the arrays and objects that change
+func stripNested(path string, value interface{}, property ToolProperty, stripped *[]string) interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		if property.Items == nil {
+			return value
+		}
+		item := property.Items.property()
+		before := len(*stripped)
+		elements := make([]interface{}, len(v))
+		for i, element := range v {
+			elements[i] = stripNested(fmt.Sprintf("%s[%d]", path, i), element, item, stripped)
+		}
+		if len(*stripped) == before {
+			return value
+		}
+		return elements
+	case map[string]interface{}:
+		if len(property.Properties) == 0 {
+			return value
+		}
+		before := len(*stripped)
+		fields := make(map[string]interface{}, len(v))
+		for field, fieldValue := range v {
+			fieldProperty, exists := property.Properties[field]
+			if !exists {
+				*stripped = append(*stripped, path+"."+field)
+				continue
+			}
+			fields[field] = stripNested(path+"."+field, fieldValue, fieldProperty, stripped)
+		}
+		if len(*stripped) == before {
+			return value
+		}
+		return fields
+	}
+	return value
+}
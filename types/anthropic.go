@@ -28,6 +28,24 @@ type AnthropicRequest struct {
 	Tools     []Tool          `json:"tools,omitempty"`
 	MaxTokens int             `json:"max_tokens,omitempty"`
 	Stream    bool            `json:"stream,omitempty"`
+
+	// Sampling parameters, nil when the client did not send them
+	Temperature   *float64 `json:"temperature,omitempty"`
+	TopP          *float64 `json:"top_p,omitempty"`
+	TopK          *int     `json:"top_k,omitempty"`
+	StopSequences []string `json:"stop_sequences,omitempty"`
+
+	// Number of choices, a non-standard extension some OpenAI-style clients
+	// send; forwarded only to backends whose profile passes "n"
+	N *int `json:"n,omitempty"`
+
+	Metadata *RequestMetadata `json:"metadata,omitempty"`
+}
+
+// RequestMetadata carries the client's request metadata. Claude Code puts its
+// account and session ID in UserID.
+type RequestMetadata struct {
+	UserID string `json:"user_id,omitempty"`
 }
 
 // AnthropicResponse represents a complete response from the proxy service back to
@@ -117,6 +135,7 @@ type SystemContent struct {
 //   - Text field contains human-readable content
 //   - Tool use fields (ID, Name, Input) specify function calls
 //   - Tool result field (ToolUseID) links results to requests
+//   - Thinking fields (Thinking, Signature, Data) hold extended-thinking blocks
 //
 // This structure enables complex multi-turn tool interactions while maintaining
 // compatibility with simple text-only conversations.
@@ -131,6 +150,12 @@ type Content struct {
 
 	// Tool result fields
 	ToolUseID string `json:"tool_use_id,omitempty"`
+
+	// Thinking fields: "thinking" blocks carry Thinking and an optional
+	// Signature, "redacted_thinking" blocks carry opaque Data
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"`
 }
 
 // Tool represents a complete tool/function definition in Anthropic format,
@@ -182,14 +207,19 @@ type ToolSchema struct {
 // Each property defines:
 //   - Type: Data type (string, number, boolean, array, object)
 //   - Description: Human-readable parameter explanation
+//   - Enum: Allowed values, when the parameter takes one of a fixed set
+//   - Properties/Required: Fields of a nested object (when Type is "object")
 //   - Items: Schema for array element types (when Type is "array")
 //
 // ToolProperty enables rich parameter definitions that support complex data types
 // and nested structures while providing clear documentation for tool usage.
 type ToolProperty struct {
-	Type        string               `json:"type"`
-	Description string               `json:"description,omitempty"`
-	Items       *ToolPropertyItems   `json:"items,omitempty"`
+	Type        string                  `json:"type"`
+	Description string                  `json:"description,omitempty"`
+	Enum        []interface{}           `json:"enum,omitempty"`
+	Properties  map[string]ToolProperty `json:"properties,omitempty"`
+	Required    []string                `json:"required,omitempty"`
+	Items       *ToolPropertyItems      `json:"items,omitempty"`
 }
 
 // ToolPropertyItems represents the schema definition for elements within
@@ -200,9 +230,15 @@ type ToolProperty struct {
 //
 // The Type field specifies the data type for all elements in the array,
 // supporting primitive types (string, number, boolean) and complex types
-// (object, array) for nested data structures.
+// (object, array) for nested data structures. Enum restricts the elements
+// to a fixed set of values; Properties and Required describe object elements
+// and Items the elements of nested arrays.
 type ToolPropertyItems struct {
-	Type string `json:"type"`
+	Type       string                  `json:"type"`
+	Enum       []interface{}           `json:"enum,omitempty"`
+	Properties map[string]ToolProperty `json:"properties,omitempty"`
+	Required   []string                `json:"required,omitempty"`
+	Items      *ToolPropertyItems      `json:"items,omitempty"`
 }
 
 // Usage represents detailed token consumption statistics for a request/response
@@ -415,6 +451,7 @@ func GetFallbackToolSchema(toolName string) *Tool {
 					"output_mode": {
 						Type:        "string",
 						Description: "Output mode: content, files_with_matches, count",
+						Enum:        []interface{}{"content", "files_with_matches", "count"},
 					},
 				},
 				Required: []string{"pattern"},
@@ -488,7 +525,16 @@ func GetFallbackToolSchema(toolName string) *Tool {
 					"todos": {
 						Type:        "array",
 						Description: "The updated todo list",
-						Items:       &ToolPropertyItems{Type: "object"},
+						Items: &ToolPropertyItems{
+							Type: "object",
+							Properties: map[string]ToolProperty{
+								"content":  {Type: "string"},
+								"status":   {Type: "string", Enum: []interface{}{"pending", "in_progress", "completed"}},
+								"priority": {Type: "string", Enum: []interface{}{"high", "medium", "low"}},
+								"id":       {Type: "string"},
+							},
+							Required: []string{"content", "status", "priority", "id"},
+						},
 					},
 				},
 				Required: []string{"todos"},
@@ -523,7 +569,15 @@ func GetFallbackToolSchema(toolName string) *Tool {
 					"edits": {
 						Type:        "array",
 						Description: "Array of edit operations to perform sequentially on the file",
-						Items:       &ToolPropertyItems{Type: "object"},
+						Items: &ToolPropertyItems{
+							Type: "object",
+							Properties: map[string]ToolProperty{
+								"old_string":  {Type: "string", Description: "The text to replace"},
+								"new_string":  {Type: "string", Description: "The text to replace it with"},
+								"replace_all": {Type: "boolean", Description: "Replace all occurences of old_string (default false)"},
+							},
+							Required: []string{"old_string", "new_string"},
+						},
 					},
 					"file_path": {
 						Type:        "string",
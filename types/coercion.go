@@ -0,0 +1,103 @@
+package types
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// TypeCoercer is implemented by validators that can convert parameter values
+// sent with the wrong JSON type (e.g. "50" for a number) to the type the
+// schema declares without an LLM round trip
+type TypeCoercer interface {
+	CoerceTypes(call Content, schema ToolSchema) (Content, []string)
+}
+
+// CoerceTypes converts every parameter value, including the values nested in
+// array and object parameters, that does not match its schema type but
+// converts losslessly to it, and returns the call with the converted input and
+// the names of the parameters it changed
+func (v *StandardToolValidator) CoerceTypes(call Content, schema ToolSchema) (Content, []string) {
+	return rewriteInput(call, schema, func(value interface{}, property ToolProperty) (interface{}, bool) {
+		if matchesType(value, property.Type) {
+			return value, false
+		}
+		return coerceValue(value, property.Type)
+	})
+}
+
+// matchesType reports whether value has the JSON type a schema declares.
+// Values built in Go as well as decoded from JSON are accepted; unset, null
+// and unrecognized schema types are not checked.
+func matchesType(value interface{}, schemaType string) bool {
+	if value == nil {
+		return true
+	}
+	kind := reflect.TypeOf(value).Kind()
+	switch schemaType {
+	case "string":
+		return kind == reflect.String
+	case "boolean":
+		return kind == reflect.Bool
+	case "number":
+		return isNumberKind(kind)
+	case "integer":
+		if !isNumberKind(kind) {
+			return false
+		}
+		n := reflect.ValueOf(value)
+		if kind == reflect.Float32 || kind == reflect.Float64 {
+			return n.Float() == math.Trunc(n.Float())
+		}
+		return true
+	case "array":
+		return kind == reflect.Slice || kind == reflect.Array
+	case "object":
+		return kind == reflect.Map || kind == reflect.Struct
+	}
+	return true
+}
+
+// isNumberKind reports whether kind holds a JSON number
+func isNumberKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// coerceValue converts value to schemaType when nothing is lost doing so:
+// numeric and boolean strings, numbers and booleans for strings, and strings
+// holding a JSON array or object
+func coerceValue(value interface{}, schemaType string) (interface{}, bool) {
+	switch schemaType {
+	case "string", "boolean":
+		return parameterNormalizers[schemaType](value)
+	case "number", "integer":
+		n, ok := parameterNormalizers["number"](value)
+		if !ok {
+			return value, false
+		}
+		// "NaN" and "Inf" parse but cannot be sent back as JSON
+		f := n.(float64)
+		if math.IsNaN(f) || math.IsInf(f, 0) || (schemaType == "integer" && f != math.Trunc(f)) {
+			return value, false
+		}
+		return f, true
+	case "array", "object":
+		s, ok := value.(string)
+		if !ok {
+			return value, false
+		}
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(s)), &decoded); err != nil || !matchesType(decoded, schemaType) || decoded == nil {
+			return value, false
+		}
+		return decoded, true
+	}
+	return value, false
+}
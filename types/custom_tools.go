@@ -0,0 +1,200 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CustomTool gives a tool the standard validator does not know (e.g. an MCP
+// tool) the same name and parameter normalization as the built-in tools.
+// Definitions come from tool_validators.yaml or are registered in code.
+type CustomTool struct {
+	Name             string            `yaml:"name" json:"name"`
+	Aliases          []string          `yaml:"aliases" json:"aliases,omitempty"`                    // Alternative names resolved to Name, case-insensitive
+	ParameterAliases map[string]string `yaml:"parameterAliases" json:"parameter_aliases,omitempty"` // Wrong parameter name -> schema parameter name
+	Normalizers      map[string]string `yaml:"normalizers" json:"normalizers,omitempty"`            // Schema parameter -> normalizer name
+}
+
+// ToolRegistrar is implemented by validators that accept custom tool definitions
+type ToolRegistrar interface {
+	RegisterTool(tool CustomTool) error
+	RegisterValidator(toolName string, validator ToolValidator) error
+}
+
+// ParameterNormalizer is implemented by validators that can rewrite a call's
+// parameters into the shape its schema expects without an LLM round trip
+type ParameterNormalizer interface {
+	NormalizeParameters(call Content) (Content, bool)
+}
+
+// parameterNormalizers are the value normalizers a CustomTool can name.
+// Each returns the normalized value and whether it differs from the input.
+var parameterNormalizers = map[string]func(value interface{}) (interface{}, bool){
+	"trim": func(value interface{}) (interface{}, bool) {
+		s, ok := value.(string)
+		return strings.TrimSpace(s), ok && strings.TrimSpace(s) != s
+	},
+	"lowercase": func(value interface{}) (interface{}, bool) {
+		s, ok := value.(string)
+		return strings.ToLower(s), ok && strings.ToLower(s) != s
+	},
+	"uppercase": func(value interface{}) (interface{}, bool) {
+		s, ok := value.(string)
+		return strings.ToUpper(s), ok && strings.ToUpper(s) != s
+	},
+	"string": func(value interface{}) (interface{}, bool) {
+		switch v := value.(type) {
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), true
+		case bool:
+			return strconv.FormatBool(v), true
+		}
+		return value, false
+	},
+	"number": func(value interface{}) (interface{}, bool) {
+		s, ok := value.(string)
+		if !ok {
+			return value, false
+		}
+		n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		return n, err == nil
+	},
+	"boolean": func(value interface{}) (interface{}, bool) {
+		s, ok := value.(string)
+		if !ok {
+			return value, false
+		}
+		b, err := strconv.ParseBool(strings.TrimSpace(s))
+		return b, err == nil
+	},
+	"array": func(value interface{}) (interface{}, bool) {
+		if _, ok := value.([]interface{}); ok || value == nil {
+			return value, false
+		}
+		return []interface{}{value}, true
+	},
+}
+
+// ParameterNormalizerNames lists the normalizers a CustomTool can use
+func ParameterNormalizerNames() []string {
+	names := make([]string, 0, len(parameterNormalizers))
+	for name := range parameterNormalizers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate checks that the definition names a tool and only known normalizers
+func (t CustomTool) Validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("custom tool name must not be empty")
+	}
+	for param, normalizer := range t.Normalizers {
+		if _, exists := parameterNormalizers[normalizer]; !exists {
+			return fmt.Errorf("unknown normalizer %q for %s.%s, must be one of: %s",
+				normalizer, t.Name, param, strings.Join(ParameterNormalizerNames(), ", "))
+		}
+	}
+	for alias, target := range t.ParameterAliases {
+		if alias == "" || target == "" {
+			return fmt.Errorf("parameter aliases for %s must not be empty", t.Name)
+		}
+	}
+	return nil
+}
+
+// normalize applies the tool's parameter aliases, then its value normalizers
+func (t CustomTool) normalize(call Content) (Content, bool) {
+	if len(t.ParameterAliases) == 0 && len(t.Normalizers) == 0 {
+		return call, false
+	}
+
+	input := make(map[string]interface{}, len(call.Input))
+	for key, value := range call.Input {
+		input[key] = value
+	}
+
+	changed := false
+	for alias, target := range t.ParameterAliases {
+		value, exists := input[alias]
+		if !exists {
+			continue
+		}
+		// Never overwrite a value the model already sent under the right name
+		if _, hasTarget := input[target]; hasTarget {
+			continue
+		}
+		delete(input, alias)
+		input[target] = value
+		changed = true
+	}
+	for param, name := range t.Normalizers {
+		value, exists := input[param]
+		if !exists {
+			continue
+		}
+		if normalized, ok := parameterNormalizers[name](value); ok {
+			input[param] = normalized
+			changed = true
+		}
+	}
+
+	if !changed {
+		return call, false
+	}
+	call.Input = input
+	return call, true
+}
+
+// RegisterTool adds a custom tool's name, aliases and parameter normalization.
+// Registering a name again replaces the earlier definition.
+func (v *StandardToolValidator) RegisterTool(tool CustomTool) error {
+	if err := tool.Validate(); err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.toolNameMappings[strings.ToLower(tool.Name)] = tool.Name
+	for _, alias := range tool.Aliases {
+		v.toolNameMappings[strings.ToLower(alias)] = tool.Name
+	}
+	v.customTools[tool.Name] = tool
+	return nil
+}
+
+// RegisterValidator routes ValidateParameters for toolName to validator,
+// for tools whose rules go beyond required and known parameters
+func (v *StandardToolValidator) RegisterValidator(toolName string, validator ToolValidator) error {
+	if toolName == "" || validator == nil {
+		return fmt.Errorf("validator registration needs a tool name and a validator")
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.validators[toolName] = validator
+	return nil
+}
+
+// NormalizeParameters applies the registered parameter aliases and
+// normalizers of a custom tool to the call
+func (v *StandardToolValidator) NormalizeParameters(call Content) (Content, bool) {
+	v.mu.RLock()
+	tool, exists := v.customTools[call.Name]
+	v.mu.RUnlock()
+	if !exists {
+		return call, false
+	}
+	return tool.normalize(call)
+}
+
+// registeredValidator returns the validator registered for a tool, if any
+func (v *StandardToolValidator) registeredValidator(toolName string) (ToolValidator, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	validator, exists := v.validators[toolName]
+	return validator, exists
+}
@@ -0,0 +1,163 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EnumCorrector is implemented by validators that can replace near-miss enum
+// values ("in-progress" for "in_progress") with the allowed value they were
+// meant to be without an LLM round trip
+type EnumCorrector interface {
+	CorrectEnums(call Content, schema ToolSchema) (Content, []string)
+}
+
+// enumSynonyms are words models use for common enum values that are too far
+// from them to be found by edit distance. A synonym only applies when its
+// value is allowed by the schema.
+var enumSynonyms = map[string][]string{
+	"completed":   {"done", "finished", "resolved", "closed"},
+	"in_progress": {"started", "doing", "active", "ongoing", "working", "wip"},
+	"pending":     {"todo", "not_started", "open", "queued", "waiting"},
+	"medium":      {"normal", "med", "moderate"},
+}
+
+// CorrectEnums replaces every string value outside its schema enum, including
+// the values nested in array and object parameters, with the allowed value it
+// most likely meant, and returns the call with the corrected input and the
+// names of the parameters it changed
+func (v *StandardToolValidator) CorrectEnums(call Content, schema ToolSchema) (Content, []string) {
+	return rewriteInput(call, schema, func(value interface{}, property ToolProperty) (interface{}, bool) {
+		return correctEnumValue(value, property.Enum)
+	})
+}
+
+// NearestEnumValue returns the string in enum that value most likely meant:
+// the same value in another case or with "-" or " " for "_", a known synonym,
+// or the only closest value within a small edit distance
+func NearestEnumValue(value string, enum []interface{}) (string, bool) {
+	normalized := normalizeEnumValue(value)
+	if normalized == "" {
+		return "", false
+	}
+
+	var candidates []string
+	for _, member := range enum {
+		if s, ok := member.(string); ok {
+			candidates = append(candidates, s)
+		}
+	}
+	for _, candidate := range candidates {
+		if normalizeEnumValue(candidate) == normalized {
+			return candidate, true
+		}
+	}
+	for _, candidate := range candidates {
+		for _, synonym := range enumSynonyms[candidate] {
+			if synonym == normalized {
+				return candidate, true
+			}
+		}
+	}
+
+	best, bestDistance, tied := "", -1, false
+	for _, candidate := range candidates {
+		distance := editDistance(normalized, normalizeEnumValue(candidate))
+		if distance > maxEnumDistance(candidate) {
+			continue
+		}
+		switch {
+		case bestDistance < 0 || distance < bestDistance:
+			best, bestDistance, tied = candidate, distance, false
+		case distance == bestDistance:
+			tied = true
+		}
+	}
+	if bestDistance < 0 || tied {
+		return "", false
+	}
+	return best, true
+}
+
+// matchesEnum reports whether value is one of the allowed values; an empty
+// enum allows any value
+func matchesEnum(value interface{}, enum []interface{}) bool {
+	if len(enum) == 0 || value == nil {
+		return true
+	}
+	for _, member := range enum {
+		if fmt.Sprint(member) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesPropertyEnum checks a parameter value, and the elements of an array
+// value, against the property's enums
+func matchesPropertyEnum(value interface{}, property ToolProperty) bool {
+	if !matchesEnum(value, property.Enum) {
+		return false
+	}
+	if property.Items == nil || len(property.Items.Enum) == 0 {
+		return true
+	}
+	elements, ok := value.([]interface{})
+	if !ok {
+		return true
+	}
+	for _, element := range elements {
+		if !matchesEnum(element, property.Items.Enum) {
+			return false
+		}
+	}
+	return true
+}
+
+// correctEnumValue replaces a string outside enum with its nearest allowed value
+func correctEnumValue(value interface{}, enum []interface{}) (interface{}, bool) {
+	s, ok := value.(string)
+	if !ok || matchesEnum(value, enum) {
+		return value, false
+	}
+	if nearest, found := NearestEnumValue(s, enum); found {
+		return nearest, true
+	}
+	return value, false
+}
+
+// normalizeEnumValue lowercases a value and writes word separators as "_"
+func normalizeEnumValue(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	return strings.NewReplacer("-", "_", " ", "_").Replace(value)
+}
+
+// maxEnumDistance is the largest edit distance still taken as a typo of
+// value: one edit per four characters, at least one
+func maxEnumDistance(value string) int {
+	if len(value) < 8 {
+		return 1
+	}
+	return len(value) / 4
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -18,14 +18,57 @@ package types
 // OpenAIRequest is designed to be compatible with the OpenAI Chat Completions API
 // while supporting various OpenAI-compatible providers configured via environment variables.
 type OpenAIRequest struct {
-	Model       string          `json:"model"`
-	Messages    []OpenAIMessage `json:"messages"`
-	Tools       []OpenAITool    `json:"tools,omitempty"`
-	ToolChoice  interface{}     `json:"tool_choice,omitempty"`
-	MaxTokens   int             `json:"max_tokens,omitempty"`
-	Temperature float64         `json:"temperature,omitempty"`
-	Stream      bool            `json:"stream,omitempty"`
-	CachePrompt bool            `json:"cache_prompt,omitempty"`
+	Model               string          `json:"model"`
+	Messages            []OpenAIMessage `json:"messages"`
+	Tools               []OpenAITool    `json:"tools,omitempty"`
+	ToolChoice          interface{}     `json:"tool_choice,omitempty"`
+	ParallelToolCalls   *bool           `json:"parallel_tool_calls,omitempty"` // Set by provider profiles limited to one call per turn
+	MaxTokens           int             `json:"max_tokens,omitempty"`
+	MaxCompletionTokens int             `json:"max_completion_tokens,omitempty"` // Used instead of MaxTokens by backends that require it
+	Temperature         float64         `json:"temperature,omitempty"`
+	TopP                *float64        `json:"top_p,omitempty"`
+	TopK                *int            `json:"top_k,omitempty"` // Non-standard extension accepted by Ollama, vLLM and llama.cpp
+	N                   *int            `json:"n,omitempty"`     // Choices to generate; see MULTI_CHOICE_POLICY
+	Stop                []string        `json:"stop,omitempty"`
+	Stream              bool            `json:"stream,omitempty"`
+	StreamOptions       *StreamOptions  `json:"stream_options,omitempty"`
+	CachePrompt         bool            `json:"cache_prompt,omitempty"`
+
+	// Assistant prefill continuation, sent only to backends that accept it (vLLM)
+	ContinueFinalMessage bool  `json:"continue_final_message,omitempty"`
+	AddGenerationPrompt  *bool `json:"add_generation_prompt,omitempty"`
+
+	// OpenRouter provider routing, set from the endpoint's settings in endpoints.yaml
+	Provider *OpenRouterProvider `json:"provider,omitempty"`
+
+	// Structured output, set when tool calls are emulated through JSON mode
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+}
+
+// ResponseFormat constrains the response to JSON, optionally matching a schema
+type ResponseFormat struct {
+	Type       string              `json:"type"` // "json_object" or "json_schema"
+	JSONSchema *ResponseJSONSchema `json:"json_schema,omitempty"`
+}
+
+// ResponseJSONSchema is the schema a json_schema response must match
+type ResponseJSONSchema struct {
+	Name   string      `json:"name"`
+	Schema interface{} `json:"schema"`
+}
+
+// OpenRouterProvider controls which underlying providers OpenRouter may route
+// a request to. Field names follow OpenRouter's API so blocks can be copied
+// from its documentation into endpoints.yaml.
+type OpenRouterProvider struct {
+	Order             []string `yaml:"order" json:"order,omitempty"`                           // Providers to try first, in order
+	AllowFallbacks    *bool    `yaml:"allow_fallbacks" json:"allow_fallbacks,omitempty"`       // Whether providers outside Order may serve the request
+	RequireParameters bool     `yaml:"require_parameters" json:"require_parameters,omitempty"` // Only use providers supporting every request parameter (e.g. tools)
+	DataCollection    string   `yaml:"data_collection" json:"data_collection,omitempty"`       // "allow" or "deny" providers that store prompts
+	Only              []string `yaml:"only" json:"only,omitempty"`                             // Providers allowed at all
+	Ignore            []string `yaml:"ignore" json:"ignore,omitempty"`                         // Providers never used
+	Quantizations     []string `yaml:"quantizations" json:"quantizations,omitempty"`           // Accepted quantizations, e.g. fp8, bf16
+	Sort              string   `yaml:"sort" json:"sort,omitempty"`                             // "price", "throughput" or "latency"
 }
 
 // OpenAIResponse represents a complete response from OpenAI-compatible providers,
@@ -74,6 +117,12 @@ type OpenAIStreamChunk struct {
 	Created int64                `json:"created"`
 	Model   string               `json:"model"`
 	Choices []OpenAIStreamChoice `json:"choices"`
+	Usage   *OpenAIUsage         `json:"usage,omitempty"` // Sent after the final choice chunk when stream_options.include_usage is set
+}
+
+// StreamOptions controls optional streaming behavior on OpenAI-compatible backends
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 // OpenAIMessage represents a single message within an OpenAI-format conversation,
@@ -98,6 +147,10 @@ type OpenAIMessage struct {
 	Name       string           `json:"name,omitempty"`
 	ToolCalls  []OpenAIToolCall `json:"tool_calls,omitempty"`
 	ToolCallID string           `json:"tool_call_id,omitempty"`
+
+	// Prior-turn reasoning for backends that accept it (llama.cpp, vLLM, DeepSeek);
+	// in responses, reasoning returned apart from the content
+	ReasoningContent string `json:"reasoning_content,omitempty"`
 }
 
 // OpenAIChoice represents a single response alternative from an OpenAI-compatible
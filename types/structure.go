@@ -0,0 +1,191 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// StructureValidator is implemented by validators that check the values
+// nested in array and object parameters against the items and properties
+// their schema declares
+type StructureValidator interface {
+	ValidateStructure(call Content, schema ToolSchema) []string
+}
+
+// ValidateStructure returns the violations found inside the call's array and
+// object parameters, e.g. "todos[1].priority: missing" or "edits[0].path:
+// unknown". Top-level parameters are left to ValidateParameters; values whose
+// schema does not describe their elements or fields are not checked, and
+// undefined fields are not reported for tools whose policy allows them.
+func (v *StandardToolValidator) ValidateStructure(call Content, schema ToolSchema) []string {
+	check := structureCheck{allowUnknown: v.additionalPropertiesFor(call.Name) == AdditionalPropertiesAllow}
+	for _, param := range sortedKeys(call.Input) {
+		if property, exists := schema.Properties[param]; exists {
+			check.nested(param, call.Input[param], property)
+		}
+	}
+	return check.violations
+}
+
+// structureCheck collects the violations of one ValidateStructure call
+type structureCheck struct {
+	allowUnknown bool // Undefined object fields are allowed by the tool's policy
+	violations   []string
+}
+
+// property returns the items schema in the form of a property, so elements
+// are checked like any other value
+func (items *ToolPropertyItems) property() ToolProperty {
+	return ToolProperty{
+		Type:       items.Type,
+		Enum:       items.Enum,
+		Properties: items.Properties,
+		Required:   items.Required,
+		Items:      items.Items,
+	}
+}
+
+// nested checks the elements of an array value and the fields of an object
+// value against property
+func (c *structureCheck) nested(path string, value interface{}, property ToolProperty) {
+	switch v := value.(type) {
+	case []interface{}:
+		if property.Items == nil {
+			return
+		}
+		item := property.Items.property()
+		for i, element := range v {
+			c.value(fmt.Sprintf("%s[%d]", path, i), element, item)
+		}
+	case map[string]interface{}:
+		if len(property.Properties) == 0 {
+			return
+		}
+		for _, field := range property.Required {
+			if _, exists := v[field]; !exists {
+				c.violations = append(c.violations, path+"."+field+": missing")
+			}
+		}
+		for _, field := range sortedKeys(v) {
+			fieldProperty, exists := property.Properties[field]
+			if !exists {
+				if !c.allowUnknown {
+					c.violations = append(c.violations, path+"."+field+": unknown")
+				}
+				continue
+			}
+			c.value(path+"."+field, v[field], fieldProperty)
+		}
+	}
+}
+
+// value checks a nested value's type and enum, then what it contains
+func (c *structureCheck) value(path string, value interface{}, property ToolProperty) {
+	if !matchesType(value, property.Type) {
+		c.violations = append(c.violations, fmt.Sprintf("%s: expected %s", path, property.Type))
+		return
+	}
+	if !matchesEnum(value, property.Enum) {
+		allowed := make([]string, len(property.Enum))
+		for i, member := range property.Enum {
+			allowed[i] = fmt.Sprint(member)
+		}
+		c.violations = append(c.violations, fmt.Sprintf("%s: not one of %s", path, strings.Join(allowed, ", ")))
+		return
+	}
+	c.nested(path, value, property)
+}
+
+// rewriteValue applies fix to value, then to the elements and fields its
+// property describes, copying only the arrays and objects that change
+func rewriteValue(value interface{}, property ToolProperty, fix func(value interface{}, property ToolProperty) (interface{}, bool)) (interface{}, bool) {
+	value, changed := fix(value, property)
+
+	switch v := value.(type) {
+	case []interface{}:
+		if property.Items == nil {
+			break
+		}
+		item := property.Items.property()
+		var elements []interface{}
+		for i, element := range v {
+			rewritten, elementChanged := rewriteValue(element, item, fix)
+			if !elementChanged {
+				continue
+			}
+			if elements == nil {
+				elements = append([]interface{}(nil), v...)
+			}
+			elements[i] = rewritten
+		}
+		if elements != nil {
+			value, changed = elements, true
+		}
+	case map[string]interface{}:
+		var fields map[string]interface{}
+		for field, fieldValue := range v {
+			fieldProperty, exists := property.Properties[field]
+			if !exists {
+				continue
+			}
+			rewritten, fieldChanged := rewriteValue(fieldValue, fieldProperty, fix)
+			if !fieldChanged {
+				continue
+			}
+			if fields == nil {
+				fields = make(map[string]interface{}, len(v))
+				for key, original := range v {
+					fields[key] = original
+				}
+			}
+			fields[field] = rewritten
+		}
+		if fields != nil {
+			value, changed = fields, true
+		}
+	}
+	return value, changed
+}
+
+// rewriteInput applies rewriteValue to every parameter the schema declares
+// and returns the call with the rewritten input and the changed parameters
+func rewriteInput(call Content, schema ToolSchema, fix func(value interface{}, property ToolProperty) (interface{}, bool)) (Content, []string) {
+	var changed []string
+	var input map[string]interface{}
+	for param, value := range call.Input {
+		property, exists := schema.Properties[param]
+		if !exists {
+			continue
+		}
+		rewritten, ok := rewriteValue(value, property, fix)
+		if !ok {
+			continue
+		}
+		if input == nil {
+			input = make(map[string]interface{}, len(call.Input))
+			for key, original := range call.Input {
+				input[key] = original
+			}
+		}
+		input[param] = rewritten
+		changed = append(changed, param)
+	}
+
+	if input == nil {
+		return call, nil
+	}
+	sort.Strings(changed)
+	call.Input = input
+	return call, changed
+}
+
+// sortedKeys returns the keys of an object in a stable order
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
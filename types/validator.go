@@ -3,6 +3,7 @@ package types
 import (
 	"context"
 	"strings"
+	"sync"
 )
 
 // ToolValidator interface for tool call validation
@@ -24,10 +25,21 @@ type ValidationResult struct {
 	CorrectToolName string
 }
 
-// StandardToolValidator is the default implementation of ToolValidator
+// StandardToolValidator is the default implementation of ToolValidator.
+// Custom tools and per-tool validators can be added with RegisterTool and
+// RegisterValidator; registration is safe alongside concurrent validation.
 type StandardToolValidator struct {
+	mu sync.RWMutex
+
 	// Tool name mappings for case-insensitive and alias resolution
 	toolNameMappings map[string]string
+
+	// Registered custom tools and per-tool validators, keyed by canonical name
+	customTools map[string]CustomTool
+	validators  map[string]ToolValidator
+
+	// What happens to parameters a tool's schema does not define
+	additionalProperties AdditionalPropertiesPolicy
 }
 
 // NewStandardToolValidator creates a new StandardToolValidator with default mappings
@@ -56,11 +68,18 @@ func NewStandardToolValidator() *StandardToolValidator {
 			"bash_command": "Bash",
 			"grep_search": "Grep",
 		},
+		customTools:          make(map[string]CustomTool),
+		validators:           make(map[string]ToolValidator),
+		additionalProperties: DefaultAdditionalPropertiesPolicy(),
 	}
 }
 
 // ValidateParameters performs comprehensive parameter validation
 func (v *StandardToolValidator) ValidateParameters(ctx context.Context, call Content, schema ToolSchema) ValidationResult {
+	if validator, exists := v.registeredValidator(call.Name); exists {
+		return validator.ValidateParameters(ctx, call, schema)
+	}
+
 	result := ValidationResult{
 		IsValid:       false,
 		MissingParams: []string{},
@@ -74,9 +93,18 @@ func (v *StandardToolValidator) ValidateParameters(ctx context.Context, call Con
 		}
 	}
 	
-	// Check for invalid parameters
-	for param := range call.Input {
-		if _, exists := schema.Properties[param]; !exists {
+	// Check for invalid parameters, unless the tool's policy allows undefined ones
+	if v.additionalPropertiesFor(call.Name) != AdditionalPropertiesAllow {
+		for param := range call.Input {
+			if _, exists := schema.Properties[param]; !exists {
+				result.InvalidParams = append(result.InvalidParams, param)
+			}
+		}
+	}
+
+	// Check parameter values against their declared types and allowed values
+	for param, value := range call.Input {
+		if property, exists := schema.Properties[param]; exists && (!matchesType(value, property.Type) || !matchesPropertyEnum(value, property)) {
 			result.InvalidParams = append(result.InvalidParams, param)
 		}
 	}
@@ -89,6 +117,9 @@ func (v *StandardToolValidator) ValidateParameters(ctx context.Context, call Con
 
 // NormalizeToolName resolves tool names to their canonical form
 func (v *StandardToolValidator) NormalizeToolName(toolName string) (normalized string, found bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
 	// Try exact match first
 	if canonical, exists := v.toolNameMappings[toolName]; exists {
 		return canonical, true
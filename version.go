@@ -1,8 +1,12 @@
 package main
 
 import (
+	"claude-proxy/config"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"os/exec"
+	"runtime"
 	"strings"
 	"time"
 )
@@ -44,4 +48,45 @@ func GetBuildInfo() string {
 	
 	return fmt.Sprintf("Simple Proxy v%s\nCommit: %s\nBuild Time: %s\nHarmony Fix: v2.0 (Issue #8 resolved)", 
 		Version, commit, buildTime)
-}
\ No newline at end of file
+}
+
+// versionResponse is the JSON body served by GET /version
+type versionResponse struct {
+	Version       string    `json:"version"`
+	GitCommit     string    `json:"git_commit"`
+	BuildTime     string    `json:"build_time"`
+	GoVersion     string    `json:"go_version"`
+	Summary       string    `json:"summary"`     // GetVersionInfo
+	ConfigHash    string    `json:"config_hash"` // Sanitized configuration, see Config.Hash
+	StartedAt     time.Time `json:"started_at"`
+	UptimeSeconds int64     `json:"uptime_seconds"`
+}
+
+// handleVersion reports which build is serving traffic, with what configuration
+// and since when. The commit is resolved once, as GetGitCommit may run git.
+func handleVersion(cfg *config.Config, startedAt time.Time) http.HandlerFunc {
+	commit := GetGitCommit()
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(versionResponse{
+			Version:       Version,
+			GitCommit:     commit,
+			BuildTime:     BuildTime,
+			GoVersion:     runtime.Version(),
+			Summary:       GetVersionInfo(),
+			ConfigHash:    cfg.Hash(),
+			StartedAt:     startedAt,
+			UptimeSeconds: int64(time.Since(startedAt).Seconds()),
+		}); err != nil {
+			http.Error(w, "Failed to encode version", http.StatusInternalServerError)
+		}
+	}
+}